@@ -41,13 +41,19 @@ func TestMainIntegration(t *testing.T) {
 			expectExitCode: 1,
 			expectError:    "Error: could not determine if this is a GitHub or GitLab repository",
 		},
+		{
+			name:           "tig blame arguments",
+			args:           []string{"-M", "-C", "-C", "-w", "--encoding=UTF-8", "--incremental", "HEAD", "--", "main.go"},
+			expectExitCode: 1,
+			expectError:    "Error: could not determine if this is a GitHub or GitLab repository",
+		},
 		{
 			name: "GitHub repo with GitHub token", 
 			args: []string{"/tmp/nonexistent.go"},
 			env: map[string]string{
 				"GITHUB_TOKEN": "dummy-token",
 			},
-			expectExitCode: 1,
+			expectExitCode: 3,
 			expectError:    "Error: this directory is not part of a Git repository",
 		},
 		{
@@ -56,9 +62,15 @@ func TestMainIntegration(t *testing.T) {
 			env: map[string]string{
 				"GITLAB_TOKEN": "dummy-token",
 			},
-			expectExitCode: 1,
+			expectExitCode: 3,
 			expectError:    "Error: this directory is not part of a Git repository",
 		},
+		{
+			name:           "JSON errors",
+			args:           []string{"-json-errors", "/tmp/nonexistent.go"},
+			expectExitCode: 3,
+			expectError:    `{"code":"not_git_repository","message":"this directory is not part of a Git repository.`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -132,4 +144,22 @@ func TestMainFlags(t *testing.T) {
 	if strings.Contains(outputStr, "flag provided but not defined") {
 		t.Errorf("Flag parsing failed: %s", outputStr)
 	}
-}
\ No newline at end of file
+}
+
+func TestVersionLDFlags(t *testing.T) {
+	// The version is set in the package that holds it, not in package main
+	ldflags := "-X git-blame-reviewer/internal/reviewblame.Version=v9.8.7"
+	buildCmd := exec.Command("go", "build", "-ldflags", ldflags, "-o", "test-git-review-blame-version", ".")
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build binary: %v\n%s", err, output)
+	}
+	defer os.Remove("test-git-review-blame-version")
+
+	output, err := exec.Command("./test-git-review-blame-version", "version").Output()
+	if err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+	if !strings.HasPrefix(string(output), "git-review-blame v9.8.7 ") {
+		t.Errorf("expected the version set with -ldflags, got %q", output)
+	}
+}
@@ -0,0 +1,138 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Annotation severities, from least to most attention-worthy
+const (
+	AnnotationSeverityHint    = "hint"
+	AnnotationSeverityInfo    = "info"
+	AnnotationSeverityWarning = "warning"
+)
+
+// EditorAnnotation is one line of the "annotations" output format, meant to
+// be shown as virtual text or a gutter marker by editor plugins. Its fields
+// are stable and independent of the human format.
+type EditorAnnotation struct {
+	// File is the repository-relative path, slash separated
+	File string `json:"file"`
+	// Line is the 1-based line number
+	Line int `json:"line"`
+	// Text is a short single-line label for virtual text
+	Text string `json:"text"`
+	// Hover is a longer, possibly multi-line description for hover popups
+	Hover string `json:"hover"`
+	// Severity is "info" for approved lines, "warning" for unreviewed and
+	// self-approved lines and "hint" for uncommitted lines
+	Severity string `json:"severity"`
+	// State is the line's ReviewState, e.g. "no-pr" for a direct push
+	State string `json:"state"`
+}
+
+// isUncommitted reports whether a blame line is a local change not yet committed
+func isUncommitted(line BlameLine) bool {
+	return strings.Trim(line.CommitHash, "0") == ""
+}
+
+// NewEditorAnnotation builds the editor annotation of a line
+func NewEditorAnnotation(line BlameLineWithApproval) EditorAnnotation {
+	annotation := EditorAnnotation{File: line.Filename, Line: line.LineNumber, State: line.ReviewState()}
+
+	if isUncommitted(line.BlameLine) {
+		annotation.Text = "not committed yet"
+		annotation.Hover = "Local change, not committed yet"
+		annotation.Severity = AnnotationSeverityHint
+		return annotation
+	}
+
+	pr := "no PR"
+	if line.PRNumber > 0 {
+		pr = fmt.Sprintf("#%d", line.PRNumber)
+	}
+
+	var hover []string
+	if line.Approver != "" {
+		annotation.Text = fmt.Sprintf("%s (%s)", line.Approver, pr)
+		annotation.Severity = AnnotationSeverityInfo
+		approved := "Approved by " + line.Approver
+		if line.ApproverEmail != "" {
+			approved += " <" + line.ApproverEmail + ">"
+		}
+		if line.ApprovalTime != nil {
+			approved += " on " + line.ApprovalTime.Format("2006-01-02")
+		}
+		hover = append(hover, approved)
+		if line.SelfApproved() {
+			annotation.Severity = AnnotationSeverityWarning
+			hover = append(hover, "Approved only by an author of the change")
+		}
+		if line.ApprovalRules != nil && !line.ApprovalRules.Satisfied() {
+			annotation.Severity = AnnotationSeverityWarning
+			hover = append(hover, "Approval rules unmet: "+strings.Join(line.ApprovalRules.Unmet, ", "))
+		}
+	} else if line.LookupFailed {
+		annotation.Text = fmt.Sprintf("review unknown (%s)", pr)
+		annotation.Severity = AnnotationSeverityWarning
+		hover = append(hover, "The pull request or its approvals could not be looked up")
+	} else {
+		annotation.Text = fmt.Sprintf("unreviewed (%s)", pr)
+		annotation.Severity = AnnotationSeverityWarning
+		hover = append(hover, "No approval found")
+	}
+
+	if line.PRNumber > 0 {
+		pull := fmt.Sprintf("Pull request %s", pr)
+		if line.PRTitle != "" {
+			pull += ": " + line.PRTitle
+		}
+		hover = append(hover, pull)
+		if len(line.LinkedIssues) > 0 {
+			hover = append(hover, "Closes "+strings.Join(line.LinkedIssues, ", "))
+		}
+		if line.TrackerURL != "" {
+			hover = append(hover, fmt.Sprintf("Tracker %s: %s", line.TrackerKey, line.TrackerURL))
+		} else if line.TrackerKey != "" {
+			hover = append(hover, "Tracker "+line.TrackerKey)
+		}
+		if origin := line.Backport; origin != nil {
+			backport := "Backport of an unknown pull request"
+			if origin.PRNumber > 0 {
+				backport = fmt.Sprintf("Backport of #%d", origin.PRNumber)
+				if origin.Approver != "" {
+					backport += ", originally approved by " + origin.Approver
+				}
+			}
+			hover = append(hover, backport)
+		}
+	} else {
+		hover = append(hover, "No pull request found for this commit")
+	}
+
+	committed := fmt.Sprintf("Commit %s by %s", shortCommit(line.CommitHash), line.Author)
+	if timestamp, err := strconv.ParseInt(line.Date, 10, 64); err == nil {
+		committed += " on " + time.Unix(timestamp, 0).Format("2006-01-02")
+	}
+	hover = append(hover, committed)
+
+	annotation.Hover = strings.Join(hover, "\n")
+	return annotation
+}
+
+// formatAnnotations renders one JSON object per line (JSON Lines)
+func formatAnnotations(lines []BlameLineWithApproval, opts FormatOptions) string {
+	var result strings.Builder
+	for _, line := range lines {
+		data, err := json.Marshal(NewEditorAnnotation(line))
+		if err != nil {
+			continue
+		}
+		result.Write(data)
+		result.WriteString("\n")
+	}
+	return result.String()
+}
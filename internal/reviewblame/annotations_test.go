@@ -0,0 +1,101 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEditorAnnotation(t *testing.T) {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	base := BlameLine{
+		CommitHash: "a1b2c3d4e5f6a7b8c9d0",
+		Author:     "John Doe",
+		Date:       "1609459200",
+		Filename:   "src/main.go",
+		LineNumber: 12,
+	}
+
+	tests := []struct {
+		name        string
+		line        BlameLineWithApproval
+		expectText  string
+		expectHover string
+		expectLevel string
+	}{
+		{
+			name:        "approved",
+			line:        BlameLineWithApproval{BlameLine: base, PRNumber: 42, Approver: "alice", ApproverEmail: "alice@example.com", ApprovalTime: &approvalTime},
+			expectText:  "alice (#42)",
+			expectHover: "Approved by alice <alice@example.com> on 2024-05-02\nPull request #42\nCommit a1b2c3d4 by John Doe on 2021-01-01",
+			expectLevel: AnnotationSeverityInfo,
+		},
+		{
+			name:        "unreviewed PR",
+			line:        BlameLineWithApproval{BlameLine: base, PRNumber: 7},
+			expectText:  "unreviewed (#7)",
+			expectHover: "No approval found\nPull request #7\nCommit a1b2c3d4 by John Doe on 2021-01-01",
+			expectLevel: AnnotationSeverityWarning,
+		},
+		{
+			name:        "no PR",
+			line:        BlameLineWithApproval{BlameLine: base},
+			expectText:  "unreviewed (no PR)",
+			expectLevel: AnnotationSeverityWarning,
+		},
+		{
+			name:        "uncommitted",
+			line:        BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "0000000000000000000000000000000000000000", Filename: "src/main.go", LineNumber: 3}},
+			expectText:  "not committed yet",
+			expectLevel: AnnotationSeverityHint,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotation := NewEditorAnnotation(tt.line)
+			if annotation.File != tt.line.Filename || annotation.Line != tt.line.LineNumber {
+				t.Errorf("unexpected position %s:%d", annotation.File, annotation.Line)
+			}
+			if annotation.Text != tt.expectText {
+				t.Errorf("expected text %q, got %q", tt.expectText, annotation.Text)
+			}
+			if tt.expectHover != "" && annotation.Hover != tt.expectHover {
+				t.Errorf("expected hover %q, got %q", tt.expectHover, annotation.Hover)
+			}
+			if annotation.Severity != tt.expectLevel {
+				t.Errorf("expected severity %s, got %s", tt.expectLevel, annotation.Severity)
+			}
+		})
+	}
+}
+
+func TestFormatAnnotations(t *testing.T) {
+	formatter, err := NewFormatterRegistry().Lookup("annotations")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "a.go", LineNumber: 1}},
+		{BlameLine: BlameLine{CommitHash: "bbbb", Filename: "a.go", LineNumber: 2}, Approver: "alice"},
+	}
+	output := formatter.Format(lines, FormatOptions{})
+
+	records := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(records) != 2 {
+		t.Fatalf("expected one JSON object per line, got %q", output)
+	}
+	for i, record := range records {
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(record), &decoded); err != nil {
+			t.Fatalf("record %d is not valid JSON: %v", i, err)
+		}
+		for _, field := range []string{"file", "line", "text", "hover", "severity"} {
+			if _, ok := decoded[field]; !ok {
+				t.Errorf("record %d is missing field %s", i, field)
+			}
+		}
+	}
+}
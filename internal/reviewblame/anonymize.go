@@ -0,0 +1,115 @@
+package reviewblame
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// AnonymizeKeyEnv names the environment variable holding the secret key for
+// pseudonyms. Without a key, pseudonyms are plain hashes that anyone can
+// recompute for a guessed login or email.
+const AnonymizeKeyEnv = "GIT_REVIEW_BLAME_ANONYMIZE_KEY"
+
+// anonymizedEmailDomain is the domain of pseudonymous emails; .invalid is reserved
+const anonymizedEmailDomain = "anonymized.invalid"
+
+// Anonymizer replaces people with stable pseudonyms, so reports can be shared
+// outside the organization while the same person keeps the same pseudonym
+// across lines, files and runs
+type Anonymizer struct {
+	key []byte
+}
+
+// NewAnonymizer creates an anonymizer keyed with key, which may be empty
+func NewAnonymizer(key string) *Anonymizer {
+	return &Anonymizer{key: []byte(key)}
+}
+
+// pseudonymID returns the hash identifying a login, name or email
+func (a *Anonymizer) pseudonymID(value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+// Pseudonym returns the pseudonymous name and email of a person. Both are
+// derived from the email when it is known, so they stay consistent; empty
+// values stay empty.
+func (a *Anonymizer) Pseudonym(name, email string) (string, string) {
+	identity := email
+	if identity == "" {
+		identity = name
+	}
+	if identity == "" {
+		return "", ""
+	}
+
+	id := a.pseudonymID(identity)
+	if name != "" {
+		name = "user-" + id
+	}
+	if email != "" {
+		email = id + "@" + anonymizedEmailDomain
+	}
+	return name, email
+}
+
+// AnonymizeEnricher replaces commit authors, approvers and PR/MR authors with
+// pseudonyms. It must be the last stage, after identities are folded.
+type AnonymizeEnricher struct {
+	anonymizer *Anonymizer
+}
+
+// NewAnonymizeEnricher creates the anonymization stage, keyed from AnonymizeKeyEnv
+func NewAnonymizeEnricher() *AnonymizeEnricher {
+	return &AnonymizeEnricher{anonymizer: NewAnonymizer(os.Getenv(AnonymizeKeyEnv))}
+}
+
+// Name implements Enricher
+func (e *AnonymizeEnricher) Name() string {
+	return "anonymize"
+}
+
+// Enrich implements Enricher
+func (e *AnonymizeEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		line := &lines[i]
+		// Uncommitted lines carry git's "Not Committed Yet" placeholder, not a person
+		if !isUncommitted(line.BlameLine) {
+			line.Author, line.AuthorEmail = e.anonymizer.Pseudonym(line.Author, line.AuthorEmail)
+		}
+		line.Approver, line.ApproverEmail = e.anonymizer.Pseudonym(line.Approver, line.ApproverEmail)
+		line.PRAuthor, _ = e.anonymizer.Pseudonym(line.PRAuthor, "")
+		if len(line.Approvers) > 0 {
+			// Approver lists are shared between lines, so copy before changing
+			approvers := make([]LineApprover, len(line.Approvers))
+			for j, approver := range line.Approvers {
+				approver.Name, approver.Email = e.anonymizer.Pseudonym(approver.Name, approver.Email)
+				// Avatar URLs name the account, e.g. by user ID
+				approver.AvatarURL = ""
+				approvers[j] = approver
+			}
+			line.Approvers = approvers
+		}
+		if len(line.Objections) > 0 {
+			// Objection lists are shared between lines, so copy before changing
+			objections := make([]Objection, len(line.Objections))
+			for j, objection := range line.Objections {
+				objection.Name, _ = e.anonymizer.Pseudonym(objection.Name, "")
+				objections[j] = objection
+			}
+			line.Objections = objections
+		}
+		if line.Backport != nil {
+			// Backport origins may be shared between lines, so copy before changing
+			origin := *line.Backport
+			origin.Approver, origin.ApproverEmail = e.anonymizer.Pseudonym(origin.Approver, origin.ApproverEmail)
+			line.Backport = &origin
+		}
+	}
+	return nil
+}
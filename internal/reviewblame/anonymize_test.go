@@ -0,0 +1,64 @@
+package reviewblame
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizerPseudonym(t *testing.T) {
+	anonymizer := NewAnonymizer("secret")
+
+	name, email := anonymizer.Pseudonym("Alice", "alice@example.com")
+	if !strings.HasPrefix(name, "user-") || !strings.HasSuffix(email, "@"+anonymizedEmailDomain) {
+		t.Fatalf("unexpected pseudonym %q <%s>", name, email)
+	}
+	if strings.TrimPrefix(name, "user-") != strings.TrimSuffix(email, "@"+anonymizedEmailDomain) {
+		t.Errorf("name and email pseudonyms should share the same ID: %q <%s>", name, email)
+	}
+
+	// Pseudonyms are stable and keyed on the email, ignoring case
+	if again, _ := anonymizer.Pseudonym("Alice Smith", "Alice@Example.com"); again != name {
+		t.Errorf("expected stable pseudonym %q, got %q", name, again)
+	}
+	if login, _ := anonymizer.Pseudonym("alice", ""); login == name || !strings.HasPrefix(login, "user-") {
+		t.Errorf("unexpected login pseudonym %q", login)
+	}
+	if other, _ := NewAnonymizer("other").Pseudonym("Alice", "alice@example.com"); other == name {
+		t.Error("pseudonyms should depend on the key")
+	}
+	if name, email := anonymizer.Pseudonym("", ""); name != "" || email != "" {
+		t.Errorf("empty identity should stay empty, got %q <%s>", name, email)
+	}
+}
+
+func TestAnonymizeEnricher(t *testing.T) {
+	t.Setenv(AnonymizeKeyEnv, "secret")
+
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine: BlameLine{CommitHash: "abc123", Author: "Alice", AuthorEmail: "alice@example.com"},
+			Approver:  "bob", PRAuthor: "alice",
+			Backport: &BackportOrigin{PRNumber: 2, Approver: "carol"},
+		},
+		{BlameLine: BlameLine{CommitHash: strings.Repeat("0", 40), Author: "Not Committed Yet", AuthorEmail: "not.committed.yet"}},
+	}
+	if err := NewAnonymizeEnricher().Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := (&OutputFormatter{Porcelain: true}).FormatOutput(lines[:1]) + NewEditorAnnotation(lines[0]).Hover
+	for _, person := range []string{"Alice", "alice@example.com", "bob", "carol"} {
+		if strings.Contains(output, person) {
+			t.Errorf("output still contains %q:\n%s", person, output)
+		}
+	}
+
+	anonymizer := NewAnonymizer("secret")
+	if want, _ := anonymizer.Pseudonym("bob", ""); lines[0].Approver != want {
+		t.Errorf("expected approver %q, got %q", want, lines[0].Approver)
+	}
+	if lines[1].Author != "Not Committed Yet" {
+		t.Errorf("uncommitted placeholder changed to %q", lines[1].Author)
+	}
+}
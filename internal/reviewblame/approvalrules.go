@@ -0,0 +1,209 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ApprovalRule is an approval rule of a PR/MR: the approvals it requires,
+// who may give them and who did
+type ApprovalRule struct {
+	Name string `json:"name"`
+	// Type is GitLab's rule type: regular, code_owner, any_approver or
+	// report_approver
+	Type     string `json:"type"`
+	Required int    `json:"required"`
+	// EligibleApprovers are the logins allowed to approve; empty when anyone
+	// may
+	EligibleApprovers []string `json:"eligible_approvers,omitempty"`
+	// ApprovedBy are the logins whose approval counted for the rule
+	ApprovedBy []string `json:"approved_by,omitempty"`
+}
+
+// label names the rule in messages
+func (r ApprovalRule) label() string {
+	if r.Type == "code_owner" {
+		return "code owners of " + r.Name
+	}
+	return r.Name
+}
+
+// eligible reports whether login may approve for the rule
+func (r ApprovalRule) eligible(login string) bool {
+	if len(r.EligibleApprovers) == 0 {
+		return true
+	}
+	for _, approver := range r.EligibleApprovers {
+		if strings.EqualFold(approver, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// approvals counts the distinct eligible approvers among the rule's own
+// approvers and approvers, the PR/MR's approvals as the approvals stage
+// found them, which may have been recovered after GitLab reset them
+func (r ApprovalRule) approvals(approvers []string) int {
+	seen := make(map[string]bool)
+	for _, login := range append(append([]string(nil), r.ApprovedBy...), approvers...) {
+		if key := strings.ToLower(login); !seen[key] && r.eligible(login) {
+			seen[key] = true
+		}
+	}
+	return len(seen)
+}
+
+// ApprovalRuleStatus is whether a PR/MR satisfied its approval rules
+type ApprovalRuleStatus struct {
+	Rules []ApprovalRule
+	// Unmet describes each rule with fewer eligible approvals than it
+	// requires, e.g. "Backend (1 of 2 approvals)"
+	Unmet []string
+}
+
+// Satisfied reports whether every rule had its required approvals
+func (s ApprovalRuleStatus) Satisfied() bool {
+	return len(s.Unmet) == 0
+}
+
+// evaluateApprovalRules checks rules against the approvers of a PR/MR
+func evaluateApprovalRules(rules []ApprovalRule, approvers []string) *ApprovalRuleStatus {
+	status := &ApprovalRuleStatus{Rules: rules}
+	for _, rule := range rules {
+		if approvals := rule.approvals(approvers); approvals < rule.Required {
+			status.Unmet = append(status.Unmet, fmt.Sprintf("%s (%d of %d approvals)", rule.label(), approvals, rule.Required))
+		}
+	}
+	return status
+}
+
+// ApprovalRuleProvider is implemented by review clients that can report the
+// approval rules of a PR/MR
+type ApprovalRuleProvider interface {
+	GetApprovalRules(ctx context.Context, owner, repo string, prNumber int) ([]ApprovalRule, error)
+}
+
+// GetApprovalRules gets the approval rules of a merge request, including
+// Code Owner rules, from its approval state
+func (c *GitLabClient) GetApprovalRules(ctx context.Context, owner, repo string, mrIID int) ([]ApprovalRule, error) {
+	var state struct {
+		Rules []struct {
+			Name              string       `json:"name"`
+			RuleType          string       `json:"rule_type"`
+			ApprovalsRequired int          `json:"approvals_required"`
+			EligibleApprovers []GitLabUser `json:"eligible_approvers"`
+			ApprovedBy        []GitLabUser `json:"approved_by"`
+		} `json:"rules"`
+	}
+	apiURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/approval_state", mrIID))
+	if err := c.doJSON(ctx, "GET", apiURL, nil, &state, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	rules := make([]ApprovalRule, 0, len(state.Rules))
+	for _, r := range state.Rules {
+		rule := ApprovalRule{Name: r.Name, Type: r.RuleType, Required: r.ApprovalsRequired}
+		for _, user := range r.EligibleApprovers {
+			rule.EligibleApprovers = append(rule.EligibleApprovers, user.Username)
+		}
+		for _, user := range r.ApprovedBy {
+			rule.ApprovedBy = append(rule.ApprovedBy, user.Username)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ApprovalRuleEnricher checks each line's PR/MR against its approval rules.
+// It must run right after the approvals stage, before trailers or identity
+// mapping change the approvers, and is a no-op for clients that do not
+// implement ApprovalRuleProvider.
+type ApprovalRuleEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// cache maps PR to its rule status (nil when the lookup failed)
+	cache map[prKey]*ApprovalRuleStatus
+}
+
+// NewApprovalRuleEnricher creates the approval rules stage
+func NewApprovalRuleEnricher(client ReviewClient, repoInfo *RepoInfo) *ApprovalRuleEnricher {
+	return &ApprovalRuleEnricher{
+		client:   client,
+		repoInfo: repoInfo,
+		cache:    make(map[prKey]*ApprovalRuleStatus),
+	}
+}
+
+// Name implements Enricher
+func (e *ApprovalRuleEnricher) Name() string {
+	return "approval-rules"
+}
+
+// Enrich implements Enricher
+func (e *ApprovalRuleEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	provider, ok := e.client.(ApprovalRuleProvider)
+	if !ok {
+		return nil
+	}
+
+	for i := range lines {
+		prNumber := lines[i].PRNumber
+		if prNumber == 0 {
+			continue
+		}
+
+		key := prKey{lines[i].Repository, prNumber}
+		status, exists := e.cache[key]
+		if !exists {
+			owner, name := lineRepository(e.repoInfo, lines[i])
+			rules, err := provider.GetApprovalRules(ctx, owner, name, prNumber)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				var approvers []string
+				for _, approver := range distinctApprovers(lines[i]) {
+					approvers = append(approvers, approver.Name)
+				}
+				status = evaluateApprovalRules(rules, approvers)
+			} else {
+				recordLookupFailure(e.Name(), prSubject(lines[i].Repository, prNumber), err)
+			}
+			e.cache[key] = status
+		}
+		lines[i].ApprovalRules = status
+	}
+	return nil
+}
+
+// UnmetApprovalRuleWarnings returns one message per approved PR/MR of the
+// lines that did not satisfy its approval rules, ordered by PR number
+func UnmetApprovalRuleWarnings(lines []BlameLineWithApproval) []string {
+	var keys []prKey
+	statuses := make(map[prKey]*ApprovalRuleStatus)
+	for _, line := range lines {
+		key := prKey{line.Repository, line.PRNumber}
+		if line.ReviewState() != ReviewStateRulesUnmet || statuses[key] != nil {
+			continue
+		}
+		keys = append(keys, key)
+		statuses[key] = line.ApprovalRules
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].repository != keys[j].repository {
+			return keys[i].repository < keys[j].repository
+		}
+		return keys[i].number < keys[j].number
+	})
+
+	warnings := make([]string, 0, len(keys))
+	for _, key := range keys {
+		warnings = append(warnings, fmt.Sprintf("%s#%d was approved without meeting its approval rules: %s",
+			key.repository, key.number, strings.Join(statuses[key].Unmet, ", ")))
+	}
+	return warnings
+}
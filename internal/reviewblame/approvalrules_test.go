@@ -0,0 +1,144 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitLabGetApprovalRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/owner%2Frepo/merge_requests/4/approval_state" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"approval_rules_overwritten": false,
+			"rules": []map[string]interface{}{
+				{
+					"name": "Backend", "rule_type": "regular", "approvals_required": 2, "approved": false,
+					"eligible_approvers": []map[string]string{{"username": "alice"}, {"username": "bob"}},
+					"approved_by":        []map[string]string{{"username": "alice"}},
+				},
+				{"name": "*.go", "rule_type": "code_owner", "approvals_required": 1, "approved": true},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	rules, err := client.GetApprovalRules(context.Background(), "owner", "repo", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ApprovalRule{
+		{Name: "Backend", Type: "regular", Required: 2, EligibleApprovers: []string{"alice", "bob"}, ApprovedBy: []string{"alice"}},
+		{Name: "*.go", Type: "code_owner", Required: 1},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("expected %+v, got %+v", want, rules)
+	}
+}
+
+func TestEvaluateApprovalRules(t *testing.T) {
+	rules := []ApprovalRule{
+		{Name: "Backend", Type: "regular", Required: 2, EligibleApprovers: []string{"alice", "bob"}, ApprovedBy: []string{"alice"}},
+		{Name: "*.go", Type: "code_owner", Required: 1, EligibleApprovers: []string{"carol"}},
+		{Name: "All members", Type: "any_approver", Required: 1},
+	}
+	tests := []struct {
+		name      string
+		approvers []string
+		want      []string
+	}{
+		{"rules met", []string{"Bob", "carol"}, nil},
+		{"ineligible approver", []string{"alice", "mallory"}, []string{"Backend (1 of 2 approvals)", "code owners of *.go (0 of 1 approvals)"}},
+		{"approval counted once", []string{"alice", "carol"}, []string{"Backend (1 of 2 approvals)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := evaluateApprovalRules(rules, tt.approvers)
+			if !reflect.DeepEqual(status.Unmet, tt.want) {
+				t.Errorf("expected unmet rules %q, got %q", tt.want, status.Unmet)
+			}
+			if status.Satisfied() != (tt.want == nil) {
+				t.Errorf("expected Satisfied() to be %v", tt.want == nil)
+			}
+		})
+	}
+}
+
+// fakeRuleClient is a fakeReviewClient that also reports approval rules
+type fakeRuleClient struct {
+	fakeReviewClient
+	rules     map[int][]ApprovalRule
+	ruleCalls int
+}
+
+func (c *fakeRuleClient) GetApprovalRules(ctx context.Context, owner, repo string, prNumber int) ([]ApprovalRule, error) {
+	c.ruleCalls++
+	return c.rules[prNumber], nil
+}
+
+func TestApprovalRuleEnricher(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	backend := []ApprovalRule{{Name: "Backend", Required: 2, EligibleApprovers: []string{"alice", "bob"}}}
+	client := &fakeRuleClient{
+		fakeReviewClient: fakeReviewClient{
+			prs: map[string]int{"aaa": 1, "bbb": 2, "ccc": 3},
+			approvals: map[int][]Review{
+				1: {newTestReview("alice", now), newTestReview("bob", now)},
+				2: {newTestReview("alice", now), newTestReview("mallory", now)},
+			},
+		},
+		rules: map[int][]ApprovalRule{1: backend, 2: backend, 3: backend},
+	}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	pipeline := NewDefaultEnrichmentPipeline(client, repoInfo)
+	pipeline.Use(NewApprovalRuleEnricher(client, repoInfo))
+	lines, err := pipeline.Run(context.Background(), []BlameLine{
+		{CommitHash: "aaa", LineNumber: 1},
+		{CommitHash: "bbb", LineNumber: 2},
+		{CommitHash: "bbb", LineNumber: 3},
+		{CommitHash: "ccc", LineNumber: 4},
+		{CommitHash: "ddd", LineNumber: 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if client.ruleCalls != 3 {
+		t.Errorf("expected 3 rule lookups (cached per MR), got %d", client.ruleCalls)
+	}
+	want := []string{ReviewStateApproved, ReviewStateRulesUnmet, ReviewStateRulesUnmet, ReviewStateLookupFailed, ReviewStateNoPR}
+	for i, line := range lines {
+		if line.ReviewState() != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i+1, want[i], line.ReviewState())
+		}
+	}
+
+	warnings := UnmetApprovalRuleWarnings(lines)
+	expected := "#2 was approved without meeting its approval rules: Backend (1 of 2 approvals)"
+	if len(warnings) != 1 || warnings[0] != expected {
+		t.Errorf("expected [%q], got %q", expected, warnings)
+	}
+	if err := reportFailures(Options{RequireApproval: true}, lines[:3], nil); err == nil || !strings.Contains(err.Error(), "2 line(s) were approved without meeting their approval rules") {
+		t.Errorf("expected -require-approval to fail on unmet rules, got %v", err)
+	}
+
+	if record := NewAnnotationRecord(lines[1]); !reflect.DeepEqual(record.UnmetApprovalRules, []string{"Backend (1 of 2 approvals)"}) {
+		t.Errorf("expected the unmet rules in the annotation record, got %q", record.UnmetApprovalRules)
+	}
+	if annotation := NewEditorAnnotation(lines[1]); annotation.Severity != AnnotationSeverityWarning || !strings.Contains(annotation.Hover, "Approval rules unmet: Backend") {
+		t.Errorf("expected a warning annotation, got %+v", annotation)
+	}
+}
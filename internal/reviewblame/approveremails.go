@@ -0,0 +1,137 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UserProfile is the public profile of a user account
+type UserProfile struct {
+	Login string `json:"login"`
+	ID    int64  `json:"id"`
+	// Email is the public email address, empty when the user keeps it
+	// private
+	Email string `json:"email"`
+}
+
+// UserProfileProvider is implemented by review clients that can look up
+// the profile of a user by login
+type UserProfileProvider interface {
+	GetUserProfile(ctx context.Context, login string) (*UserProfile, error)
+}
+
+// GetUserProfile returns the public profile of a GitHub user
+func (c *GitHubClient) GetUserProfile(ctx context.Context, login string) (*UserProfile, error) {
+	apiURL := fmt.Sprintf("%s/users/%s", c.baseURL, url.PathEscape(login))
+	resp, err := c.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+	var profile UserProfile
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetUserProfile implements UserProfileProvider
+func (a *GitHubClientAdapter) GetUserProfile(ctx context.Context, login string) (*UserProfile, error) {
+	return a.client.GetUserProfile(ctx, login)
+}
+
+// noreplyEmail returns the noreply address GitHub attributes to a user who
+// keeps their email private, e.g. "123+octocat@users.noreply.github.com"
+func noreplyEmail(host string, profile *UserProfile) string {
+	if host == "" {
+		host = "github.com"
+	}
+	return fmt.Sprintf("%d+%s@users.noreply.%s", profile.ID, profile.Login, host)
+}
+
+// ApproverEmailEnricher fills in the emails of approvers from their user
+// profiles, since GitHub's reviews API leaves them out. Approvers who keep
+// their email private get their noreply address. It is a no-op for clients
+// that do not implement UserProfileProvider, and it must run before mailmap
+// and identity mapping, while approvers are still logins.
+type ApproverEmailEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// cache maps lower-case login to its email ("" when the lookup failed)
+	cache map[string]string
+}
+
+// NewApproverEmailEnricher creates the approver email stage
+func NewApproverEmailEnricher(client ReviewClient, repoInfo *RepoInfo) *ApproverEmailEnricher {
+	return &ApproverEmailEnricher{
+		client:   client,
+		repoInfo: repoInfo,
+		cache:    make(map[string]string),
+	}
+}
+
+// Name implements Enricher
+func (e *ApproverEmailEnricher) Name() string {
+	return "approver-emails"
+}
+
+// Enrich implements Enricher
+func (e *ApproverEmailEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	provider, ok := e.client.(UserProfileProvider)
+	if !ok {
+		return nil
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		if line.Approver != "" && line.ApproverEmail == "" {
+			email, err := e.email(ctx, provider, line.Approver)
+			if err != nil {
+				return err
+			}
+			line.ApproverEmail = email
+		}
+		for j := range line.Approvers {
+			if line.Approvers[j].Email != "" {
+				continue
+			}
+			email, err := e.email(ctx, provider, line.Approvers[j].Name)
+			if err != nil {
+				return err
+			}
+			line.Approvers[j].Email = email
+		}
+	}
+	return nil
+}
+
+// email returns the email of login, looking it up once. Failed lookups
+// leave the email empty rather than failing the run.
+func (e *ApproverEmailEnricher) email(ctx context.Context, provider UserProfileProvider, login string) (string, error) {
+	key := strings.ToLower(login)
+	if email, exists := e.cache[key]; exists {
+		return email, nil
+	}
+
+	var email string
+	profile, err := provider.GetUserProfile(ctx, login)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err == nil {
+		email = profile.Email
+		if email == "" {
+			email = noreplyEmail(e.repoInfo.Host, profile)
+		}
+	}
+	e.cache[key] = email
+	return email, nil
+}
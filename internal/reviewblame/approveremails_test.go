@@ -0,0 +1,91 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubGetUserProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/octocat":
+			json.NewEncoder(w).Encode(map[string]interface{}{"login": "octocat", "id": 583231, "email": "octocat@github.com"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+	profile, err := client.GetUserProfile(context.Background(), "octocat")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *profile != (UserProfile{Login: "octocat", ID: 583231, Email: "octocat@github.com"}) {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+	if _, err := client.GetUserProfile(context.Background(), "ghost"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
+
+// fakeProfileClient is a fakeReviewClient that also reports user profiles
+type fakeProfileClient struct {
+	fakeReviewClient
+	profiles map[string]*UserProfile
+	calls    int
+}
+
+func (c *fakeProfileClient) GetUserProfile(ctx context.Context, login string) (*UserProfile, error) {
+	c.calls++
+	if profile, exists := c.profiles[login]; exists {
+		return profile, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func TestApproverEmailEnricher(t *testing.T) {
+	client := &fakeProfileClient{profiles: map[string]*UserProfile{
+		"alice": {Login: "alice", ID: 1, Email: "alice@example.com"},
+		"bob":   {Login: "bob", ID: 2},
+	}}
+	repoInfo := &RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com"}
+
+	tests := []struct {
+		name     string
+		approver string
+		email    string
+		want     string
+	}{
+		{"public email", "alice", "", "alice@example.com"},
+		{"private email", "bob", "", "2+bob@users.noreply.github.com"},
+		{"known email", "alice", "a@corp.example", "a@corp.example"},
+		{"lookup failed", "ghost", "", ""},
+		{"cached", "Alice", "", "alice@example.com"},
+	}
+	enricher := NewApproverEmailEnricher(client, repoInfo)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []BlameLineWithApproval{{
+				BlameLine:     BlameLine{CommitHash: "aaaa"},
+				Approver:      tt.approver,
+				ApproverEmail: tt.email,
+				Approvers:     []LineApprover{{Name: tt.approver, Email: tt.email}},
+			}}
+			if err := enricher.Enrich(context.Background(), lines); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lines[0].ApproverEmail != tt.want || lines[0].Approvers[0].Email != tt.want {
+				t.Errorf("expected %q, got %q and %q", tt.want, lines[0].ApproverEmail, lines[0].Approvers[0].Email)
+			}
+		})
+	}
+	if client.calls != 3 {
+		t.Errorf("expected one lookup per login, got %d", client.calls)
+	}
+}
@@ -0,0 +1,147 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Approver policies of -approver-policy, deciding which approval of a
+// line's PR/MR is shown as its approver
+const (
+	ApproverPolicyFirst = "first"
+	// ApproverPolicyLast shows the most recent approval, the default
+	ApproverPolicyLast = "last"
+	// ApproverPolicyLastBeforeMerge shows the most recent approval given
+	// before the PR/MR was merged and drops later ones
+	ApproverPolicyLastBeforeMerge = "last-before-merge"
+	// ApproverPolicyAll shows every approver, like -all-approvers
+	ApproverPolicyAll = "all"
+	// ApproverPolicyCodeOwnerPreferred shows the most recent approval by a
+	// code owner of the line's file, falling back to the most recent one
+	ApproverPolicyCodeOwnerPreferred = "codeowner-preferred"
+)
+
+// approverPolicies are the policies -approver-policy accepts
+var approverPolicies = []string{
+	ApproverPolicyFirst,
+	ApproverPolicyLast,
+	ApproverPolicyLastBeforeMerge,
+	ApproverPolicyAll,
+	ApproverPolicyCodeOwnerPreferred,
+}
+
+// ParseApproverPolicy checks an -approver-policy value
+func ParseApproverPolicy(policy string) (string, error) {
+	for _, known := range approverPolicies {
+		if policy == known {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown approver policy %q (expected %s)", policy, strings.Join(approverPolicies, ", "))
+}
+
+// ApproverPolicyEnricher picks the approver of each line from the approvals
+// of its PR/MR by policy, instead of the most recent one the approvals stage
+// shows. It must run right after the approvals stage, while approvers are
+// still the logins CODEOWNERS names.
+type ApproverPolicyEnricher struct {
+	client ReviewClient
+	policy string
+	// codeOwners finds the code owners of files for codeowner-preferred
+	codeOwners *codeOwnersResolver
+	// beforeMerge maps PR to its approvals given before it was merged, shared
+	// by its lines
+	beforeMerge map[prKey][]LineApprover
+}
+
+// NewApproverPolicyEnricher creates the approver policy stage
+func NewApproverPolicyEnricher(client ReviewClient, repoRoot, policy string) *ApproverPolicyEnricher {
+	return &ApproverPolicyEnricher{
+		client:      client,
+		policy:      policy,
+		codeOwners:  newCodeOwnersResolver(repoRoot),
+		beforeMerge: make(map[prKey][]LineApprover),
+	}
+}
+
+// Name implements Enricher
+func (e *ApproverPolicyEnricher) Name() string {
+	return "approver-policy"
+}
+
+// Enrich implements Enricher
+func (e *ApproverPolicyEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	lister, _ := e.client.(TeamMemberLister)
+	for i := range lines {
+		line := &lines[i]
+		if len(line.Approvers) == 0 {
+			continue
+		}
+
+		switch e.policy {
+		case ApproverPolicyFirst:
+			setApprover(line, line.Approvers[0])
+		case ApproverPolicyLastBeforeMerge:
+			approvers := e.approvalsBeforeMerge(*line)
+			if len(approvers) == 0 {
+				line.Approver, line.ApproverEmail, line.ApprovalTime = "", "", nil
+				line.Approvers = nil
+				continue
+			}
+			line.Approvers = approvers
+			setApprover(line, approvers[len(approvers)-1])
+		case ApproverPolicyCodeOwnerPreferred:
+			_, owners, err := e.codeOwners.Owners(ctx, lister, line.Filename)
+			if err != nil {
+				return err
+			}
+			for j := len(line.Approvers) - 1; j >= 0; j-- {
+				if containsFold(owners, line.Approvers[j].Name) {
+					setApprover(line, line.Approvers[j])
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// approvalsBeforeMerge returns the approvals of line's PR/MR given no later
+// than its merge. Approvals without a time, and all approvals of PRs/MRs
+// whose merge time is unknown, are kept.
+func (e *ApproverPolicyEnricher) approvalsBeforeMerge(line BlameLineWithApproval) []LineApprover {
+	if line.PRMergedAt == nil {
+		return line.Approvers
+	}
+	key := prKey{line.Repository, line.PRNumber}
+	if approvers, exists := e.beforeMerge[key]; exists {
+		return approvers
+	}
+
+	var approvers []LineApprover
+	for _, approver := range line.Approvers {
+		if approver.Time == nil || !approver.Time.After(*line.PRMergedAt) {
+			approvers = append(approvers, approver)
+		}
+	}
+	e.beforeMerge[key] = approvers
+	return approvers
+}
+
+// setApprover shows approver as the approver of line
+func setApprover(line *BlameLineWithApproval, approver LineApprover) {
+	line.Approver = approver.Name
+	line.ApproverEmail = approver.Email
+	line.ApprovalTime = approver.Time
+}
+
+// containsFold reports whether names contains name, ignoring case
+func containsFold(names []string, name string) bool {
+	for _, candidate := range names {
+		if strings.EqualFold(candidate, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseApproverPolicy(t *testing.T) {
+	for _, policy := range approverPolicies {
+		if _, err := ParseApproverPolicy(policy); err != nil {
+			t.Errorf("expected %q to be accepted, got %v", policy, err)
+		}
+	}
+	if _, err := ParseApproverPolicy("newest"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestApproverPolicyEnricher(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "CODEOWNERS"), []byte("*.go @bob\n/api/ @acme/api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "Add CODEOWNERS")
+
+	merged := time.Unix(1700000000, 0)
+	before, after := merged.Add(-time.Hour), merged.Add(time.Hour)
+	approvers := []LineApprover{
+		{Name: "alice", Time: &before},
+		{Name: "bob", Time: &before},
+		{Name: "carol", Time: &after},
+	}
+	newLine := func(filename string, mergedAt *time.Time) BlameLineWithApproval {
+		return BlameLineWithApproval{
+			BlameLine:    BlameLine{CommitHash: "aaaa", Filename: filename},
+			PRNumber:     1,
+			PRMergedAt:   mergedAt,
+			Approver:     "carol",
+			ApprovalTime: &after,
+			Approvers:    approvers,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		line   BlameLineWithApproval
+		want   string
+		count  int
+	}{
+		{"first", ApproverPolicyFirst, newLine("main.go", &merged), "alice", 3},
+		{"last before merge", ApproverPolicyLastBeforeMerge, newLine("main.go", &merged), "bob", 2},
+		{"merge time unknown", ApproverPolicyLastBeforeMerge, newLine("main.go", nil), "carol", 3},
+		{"code owner", ApproverPolicyCodeOwnerPreferred, newLine("main.go", &merged), "bob", 3},
+		{"no code owner approved", ApproverPolicyCodeOwnerPreferred, newLine("README.md", &merged), "carol", 3},
+		{"team owner", ApproverPolicyCodeOwnerPreferred, newLine("api/handler.go", &merged), "alice", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeProtectionClient{teams: map[string][]string{"acme/api": {"Alice"}}}
+			lines := []BlameLineWithApproval{tt.line}
+			if err := NewApproverPolicyEnricher(client, dir, tt.policy).Enrich(context.Background(), lines); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lines[0].Approver != tt.want || len(lines[0].Approvers) != tt.count {
+				t.Errorf("expected approver %q of %d, got %q of %d", tt.want, tt.count, lines[0].Approver, len(lines[0].Approvers))
+			}
+		})
+	}
+
+	// Lines approved only after the merge lose their approver
+	early := before.Add(-time.Hour)
+	late := []BlameLineWithApproval{newLine("main.go", &early)}
+	if err := NewApproverPolicyEnricher(&fakeReviewClient{}, dir, ApproverPolicyLastBeforeMerge).Enrich(context.Background(), late); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if late[0].Approver != "" || late[0].Approvers != nil || late[0].ReviewState() != ReviewStateUnapproved {
+		t.Errorf("expected an unapproved line, got %+v", late[0])
+	}
+}
+
+func TestPRLookupResultMergedAt(t *testing.T) {
+	merged := time.Unix(1700000000, 0)
+	var line BlameLineWithApproval
+	newPRLookupResult(&PullRequest{Number: 4, MergedAt: &merged}).apply(&line)
+	if line.PRMergedAt == nil || !line.PRMergedAt.Equal(merged) {
+		t.Errorf("expected the merge time to be set, got %v", line.PRMergedAt)
+	}
+}
@@ -0,0 +1,174 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ListOrgTeams returns the slugs of all teams of an organization
+func (c *GitHubClient) ListOrgTeams(ctx context.Context, org string) ([]string, error) {
+	var slugs []string
+	url := fmt.Sprintf("%s/orgs/%s/teams", c.baseURL, org)
+	err := c.listPages(ctx, url, func(dec *json.Decoder) error {
+		var team struct {
+			Slug string `json:"slug"`
+		}
+		if err := dec.Decode(&team); err != nil {
+			return err
+		}
+		slugs = append(slugs, team.Slug)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slugs, nil
+}
+
+// ListDescendantGroups returns the full paths of all subgroups of a group,
+// at any depth
+func (c *GitLabClient) ListDescendantGroups(ctx context.Context, group string) ([]string, error) {
+	var paths []string
+	apiURL := fmt.Sprintf("%s/groups/%s/descendant_groups", c.baseURL, url.PathEscape(group))
+	err := c.listPages(ctx, apiURL, func(dec *json.Decoder) error {
+		var subgroup struct {
+			FullPath string `json:"full_path"`
+		}
+		if err := dec.Decode(&subgroup); err != nil {
+			return err
+		}
+		paths = append(paths, subgroup.FullPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// DiscoverTeams lists the teams of the organization owning a GitHub
+// repository, named "@org/slug", or the group owning a GitLab project and
+// its subgroups, named "@group/path"
+func DiscoverTeams(ctx context.Context, repoInfo *RepoInfo, githubToken, gitlabToken string) ([]TeamConfig, error) {
+	var teams []TeamConfig
+	switch repoInfo.Type {
+	case RepositoryTypeGitHub:
+		if githubToken == "" {
+			return nil, ErrMissingGitHubToken
+		}
+		slugs, err := newGitHubClientForRepo(githubToken, repoInfo).ListOrgTeams(ctx, repoInfo.Owner)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the teams of %s: %w", repoInfo.Owner, err)
+		}
+		for _, slug := range slugs {
+			team := repoInfo.Owner + "/" + slug
+			teams = append(teams, TeamConfig{Name: "@" + team, GitHubTeam: team})
+		}
+	case RepositoryTypeGitLab:
+		if gitlabToken == "" {
+			return nil, ErrMissingGitLabToken
+		}
+		paths, err := newGitLabClientForRepo(gitlabToken, repoInfo).ListDescendantGroups(ctx, repoInfo.Owner)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the subgroups of %s: %w", repoInfo.Owner, err)
+		}
+		for _, path := range append([]string{repoInfo.Owner}, paths...) {
+			teams = append(teams, TeamConfig{Name: "@" + path, GitLabGroup: path})
+		}
+	default:
+		return nil, fmt.Errorf("teams of %s repositories cannot be listed; configure them under \"teams\" in the config file", repoInfo.Type)
+	}
+	return teams, nil
+}
+
+// ApproverTeamEnricher sets the team of each line's approver, for reports by
+// team rather than by person (-show-team). Teams are the configured ones,
+// or those of the repository's organization or group when none are. An
+// approver in several teams gets the smallest one, the most specific for
+// nested teams, which list the members of their child teams too. It must
+// run before identity mapping, while approvers are still logins.
+type ApproverTeamEnricher struct {
+	repoInfo    *RepoInfo
+	githubToken string
+	gitlabToken string
+	teams       []TeamConfig
+	// memberTeams maps lower-case login to its team, resolved on first use
+	memberTeams map[string]string
+}
+
+// NewApproverTeamEnricher creates the approver team stage
+func NewApproverTeamEnricher(teams []TeamConfig, repoInfo *RepoInfo, githubToken, gitlabToken string) *ApproverTeamEnricher {
+	return &ApproverTeamEnricher{
+		repoInfo:    repoInfo,
+		githubToken: githubToken,
+		gitlabToken: gitlabToken,
+		teams:       teams,
+	}
+}
+
+// Name implements Enricher
+func (e *ApproverTeamEnricher) Name() string {
+	return "approver-teams"
+}
+
+// Enrich implements Enricher
+func (e *ApproverTeamEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	if e.memberTeams == nil {
+		memberTeams, err := e.resolveTeams(ctx)
+		if err != nil {
+			return err
+		}
+		e.memberTeams = memberTeams
+	}
+
+	for i := range lines {
+		if lines[i].Approver != "" {
+			lines[i].ApproverTeam = e.memberTeams[strings.ToLower(lines[i].Approver)]
+		}
+	}
+	return nil
+}
+
+// resolveTeams lists the members of every team and maps each member to the
+// smallest team they are in; ties go to the team listed first
+func (e *ApproverTeamEnricher) resolveTeams(ctx context.Context) (map[string]string, error) {
+	teams := e.teams
+	if len(teams) == 0 {
+		discovered, err := DiscoverTeams(ctx, e.repoInfo, e.githubToken, e.gitlabToken)
+		if err != nil {
+			return nil, err
+		}
+		teams = discovered
+	}
+
+	type resolvedTeam struct {
+		name    string
+		members []string
+	}
+	resolved := make([]resolvedTeam, 0, len(teams))
+	for _, team := range teams {
+		members, err := ResolveTeamMembers(ctx, team, e.repoInfo, e.githubToken, e.gitlabToken)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, resolvedTeam{team.Name, members})
+	}
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return len(resolved[i].members) < len(resolved[j].members)
+	})
+
+	memberTeams := make(map[string]string)
+	for _, team := range resolved {
+		for _, member := range team.members {
+			key := strings.ToLower(member)
+			if _, exists := memberTeams[key]; !exists {
+				memberTeams[key] = team.name
+			}
+		}
+	}
+	return memberTeams, nil
+}
@@ -0,0 +1,102 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListDescendantGroups(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/groups/company%2Feng/descendant_groups" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+		}
+		json.NewEncoder(w).Encode([]map[string]string{
+			{"full_path": "company/eng/payments"},
+			{"full_path": "company/eng/payments/api"},
+		})
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+	paths, err := client.ListDescendantGroups(context.Background(), "company/eng")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"company/eng/payments", "company/eng/payments/api"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected %q, got %q", want, paths)
+	}
+}
+
+func TestApproverTeamEnricher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/acme/teams":
+			json.NewEncoder(w).Encode([]map[string]string{{"slug": "platform"}, {"slug": "payments"}})
+		case "/orgs/acme/teams/platform/members":
+			// Parent teams list the members of their child teams
+			json.NewEncoder(w).Encode([]map[string]string{{"login": "alice"}, {"login": "bob"}, {"login": "carol"}})
+		case "/orgs/acme/teams/payments/members":
+			json.NewEncoder(w).Encode([]map[string]string{{"login": "bob"}})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	repoInfo := &RepoInfo{Type: RepositoryTypeGitHub, Owner: "acme", Name: "repo", APIURL: server.URL}
+
+	tests := []struct {
+		name  string
+		teams []TeamConfig
+		want  []string
+	}{
+		{"organization teams", nil, []string{"@acme/platform", "@acme/payments", "", ""}},
+		{"configured teams", []TeamConfig{{Name: "core", Members: []string{"alice", "dave"}}}, []string{"core", "", "", "core"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []BlameLineWithApproval{
+				{BlameLine: BlameLine{CommitHash: "aaaa", LineNumber: 1}, Approver: "alice"},
+				{BlameLine: BlameLine{CommitHash: "aaaa", LineNumber: 2}, Approver: "Bob"},
+				{BlameLine: BlameLine{CommitHash: "aaaa", LineNumber: 3, Author: "erin"}},
+				{BlameLine: BlameLine{CommitHash: "aaaa", LineNumber: 4}, Approver: "dave"},
+			}
+			enricher := NewApproverTeamEnricher(tt.teams, repoInfo, "test-token", "")
+			if err := enricher.Enrich(context.Background(), lines); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, line := range lines {
+				if line.ApproverTeam != tt.want[i] {
+					t.Errorf("line %d: expected team %q, got %q", i+1, tt.want[i], line.ApproverTeam)
+				}
+			}
+		})
+	}
+
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaa", LineNumber: 1, Filename: "a.go"}, Approver: "alice", ApproverTeam: "@acme/platform"},
+		{BlameLine: BlameLine{CommitHash: "bbbb", LineNumber: 2, Filename: "a.go"}, Approver: "carol", ApproverTeam: "@acme/platform"},
+		{BlameLine: BlameLine{CommitHash: "cccc", LineNumber: 3, Filename: "a.go"}, Approver: "dave"},
+	}
+	formatter := NewOutputFormatter(false, false, true)
+	if name := formatter.getAuthorName(lines[0]); name != "@acme/platform" {
+		t.Errorf("expected the team in the author column, got %q", name)
+	}
+	if porcelain := formatter.formatPorcelain(lines[:1]); !strings.Contains(porcelain, "approver-team @acme/platform\n") {
+		t.Errorf("expected an approver-team field, got:\n%s", porcelain)
+	}
+	report := BuildOwnershipReport("a.go", lines, StatsByTotal, 0)
+	want := []ApproverShare{
+		{Approver: "@acme/platform", Lines: 2, Percent: percentOf(2, 3)},
+		{Approver: "dave", Lines: 1, Percent: percentOf(1, 3)},
+	}
+	if !reflect.DeepEqual(report.Total.Approvers, want) {
+		t.Errorf("expected shares %+v, got %+v", want, report.Total.Approvers)
+	}
+}
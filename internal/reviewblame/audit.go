@@ -0,0 +1,517 @@
+package reviewblame
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// auditSnapshotFormat is the version of the audit snapshot layout
+const auditSnapshotFormat = 1
+
+// DefaultAuditSnapshotFile is the artifact written by the snapshot command
+const DefaultAuditSnapshotFile = "review-audit.json.gz"
+
+// AuditSettings are the options that affect annotations, recorded so that
+// verify can re-run with exactly the same settings
+type AuditSettings struct {
+	Offline         bool `json:"offline,omitempty"`
+	TrailersOnly    bool `json:"trailers_only,omitempty"`
+	AllApprovers    bool `json:"all_approvers,omitempty"`
+	Threads         bool `json:"threads,omitempty"`
+	Rounds          bool `json:"rounds,omitempty"`
+	Owners          bool `json:"owners,omitempty"`
+	Backports       bool `json:"backports,omitempty"`
+	IncludeVendored bool `json:"include_vendored,omitempty"`
+}
+
+// options converts the settings to blame options for annotating commit
+func (s AuditSettings) options(commit string) Options {
+	return Options{
+		Revision:        commit,
+		Offline:         s.Offline,
+		TrailersOnly:    s.TrailersOnly,
+		AllApprovers:    s.AllApprovers,
+		Threads:         s.Threads,
+		Rounds:          s.Rounds,
+		Owners:          s.Owners,
+		Backports:       s.Backports,
+		IncludeVendored: s.IncludeVendored,
+	}
+}
+
+// AuditSnapshot is the full annotation state of paths at a commit, kept as
+// repeatable compliance evidence
+type AuditSnapshot struct {
+	Format      int                `json:"format"`
+	ToolVersion string             `json:"tool_version"`
+	Repository  string             `json:"repository"`
+	Commit      string             `json:"commit"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Paths       []string           `json:"paths"`
+	Settings    AuditSettings      `json:"settings"`
+	Config      *Config            `json:"config"`
+	Records     []AnnotationRecord `json:"records"`
+}
+
+// auditArtifact is the on-disk form: the snapshot and the SHA-256 digest of
+// its JSON encoding, gzip compressed
+type auditArtifact struct {
+	Digest   string          `json:"digest"`
+	Snapshot json.RawMessage `json:"snapshot"`
+}
+
+// snapshotDigest returns the content digest of an encoded snapshot
+func snapshotDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// auditConfig returns the settings of a repository's config file that
+// affect annotations. The artifact is shared, so webhook URLs, which may
+// hold secrets, and plugins, which name local programs, are left out; the
+// user config file is never recorded.
+func auditConfig(config *Config) *Config {
+	recorded := &Config{
+		Migrations: config.Migrations,
+		Trackers:   config.Trackers,
+		Identities: config.Identities,
+		Teams:      config.Teams,
+		Remote:     config.Remote,
+	}
+	for _, host := range config.Hosts {
+		host.APIURL, host.Plugin = "", ""
+		recorded.Hosts = append(recorded.Hosts, host)
+	}
+	return recorded
+}
+
+// loadAuditConfig loads the repository's config file, without the user
+// config file, and returns its recorded settings (see auditConfig)
+func loadAuditConfig(repoRoot, configPath string) (*Config, error) {
+	config, err := loadRepositoryConfig(repoRoot, configPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load config file: %w", err)
+	}
+	return auditConfig(config), nil
+}
+
+// auditConfigDifferences returns the settings, by their config file key,
+// whose recorded and current values differ
+func auditConfigDifferences(recorded, current *Config) []string {
+	if recorded == nil {
+		recorded = &Config{}
+	}
+	var recordedFields, currentFields map[string]json.RawMessage
+	data, _ := json.Marshal(recorded)
+	json.Unmarshal(data, &recordedFields)
+	data, _ = json.Marshal(current)
+	json.Unmarshal(data, &currentFields)
+
+	var differences []string
+	for key, value := range currentFields {
+		if string(recordedFields[key]) != string(value) {
+			differences = append(differences, key)
+		}
+	}
+	sort.Strings(differences)
+	return differences
+}
+
+// WriteAuditSnapshot writes the snapshot as a compressed artifact and returns its digest
+func WriteAuditSnapshot(path string, snapshot *AuditSnapshot) (string, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	digest := snapshotDigest(data)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	if err := json.NewEncoder(writer).Encode(auditArtifact{Digest: digest, Snapshot: data}); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return digest, file.Close()
+}
+
+// ReadAuditSnapshot reads an artifact written by WriteAuditSnapshot and
+// checks its content digest
+func ReadAuditSnapshot(path string) (*AuditSnapshot, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid audit snapshot %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	var artifact auditArtifact
+	if err := json.NewDecoder(reader).Decode(&artifact); err != nil {
+		return nil, "", fmt.Errorf("invalid audit snapshot %s: %w", path, err)
+	}
+	if digest := snapshotDigest(artifact.Snapshot); digest != artifact.Digest {
+		return nil, "", fmt.Errorf("audit snapshot %s is corrupted or was modified: digest %s does not match recorded %s", path, digest, artifact.Digest)
+	}
+
+	var snapshot AuditSnapshot
+	if err := json.Unmarshal(artifact.Snapshot, &snapshot); err != nil {
+		return nil, "", fmt.Errorf("invalid audit snapshot %s: %w", path, err)
+	}
+	if snapshot.Format != auditSnapshotFormat {
+		return nil, "", fmt.Errorf("audit snapshot %s has unsupported format %d", path, snapshot.Format)
+	}
+	return &snapshot, artifact.Digest, nil
+}
+
+// annotateAtCommit annotates every file under paths as of commit, so the
+// result does not depend on the working tree. Files with an entry in reused
+// (keyed by repository-relative path) take those records instead of being
+// blamed again.
+func annotateAtCommit(ctx context.Context, repoRoot, commit string, paths []string, pipeline *EnrichmentPipeline, settings AuditSettings, reused map[string][]AnnotationRecord) ([]AnnotationRecord, error) {
+	files, err := ListTrackedFilesAt(repoRoot, commit, paths)
+	if err != nil {
+		return nil, fmt.Errorf("could not list files: %w", err)
+	}
+	if !settings.IncludeVendored {
+		if files, _, err = FilterVendoredFiles(repoRoot, files); err != nil {
+			return nil, err
+		}
+	}
+
+	var records []AnnotationRecord
+	for _, file := range files {
+		if relPath, err := filepath.Rel(repoRoot, file); err == nil {
+			if previous, ok := reused[filepath.ToSlash(relPath)]; ok {
+				records = append(records, previous...)
+				continue
+			}
+		}
+
+		blameLines, err := ExecuteGitBlameAt(ctx, repoRoot, file, commit, BlameOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not analyze file history for %s: %w", file, err)
+		}
+		lines, err := pipeline.Run(ctx, blameLines)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			record := NewAnnotationRecord(line)
+			// Snapshots taken without -all-approvers keep only the last one,
+			// so they verify the same as before approver lists were recorded
+			if !settings.AllApprovers {
+				record.Approvers = nil
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// reusableRecords groups the records of a previous snapshot by file, leaving
+// out files changed since its commit. A previous snapshot taken with other
+// paths, settings or recorded config cannot be reused and yields an error.
+func reusableRecords(previous *AuditSnapshot, repository string, paths []string, settings AuditSettings, recorded *Config, changed map[string]bool) (map[string][]AnnotationRecord, error) {
+	if previous.Repository != repository {
+		return nil, fmt.Errorf("it is for %s, not %s", previous.Repository, repository)
+	}
+	if strings.Join(previous.Paths, "\x00") != strings.Join(paths, "\x00") {
+		return nil, fmt.Errorf("it covers %s, not %s", strings.Join(previous.Paths, ", "), strings.Join(paths, ", "))
+	}
+	if previous.Settings != settings {
+		return nil, fmt.Errorf("it was taken with different settings")
+	}
+	if len(auditConfigDifferences(previous.Config, recorded)) > 0 {
+		return nil, fmt.Errorf("it was taken with a different config")
+	}
+
+	reused := make(map[string][]AnnotationRecord)
+	for _, record := range previous.Records {
+		if !changed[record.File] {
+			reused[record.File] = append(reused[record.File], record)
+		}
+	}
+	return reused, nil
+}
+
+// AuditDifference is one field of one line that differs from the snapshot
+type AuditDifference struct {
+	File     string
+	Line     int
+	Field    string
+	Recorded string
+	Current  string
+}
+
+// String formats the difference for display
+func (d AuditDifference) String() string {
+	return fmt.Sprintf("%s:%d: %s: recorded %s, now %s", d.File, d.Line, d.Field, d.Recorded, d.Current)
+}
+
+// recordFields returns the JSON fields of a record, with absent values as ""
+func recordFields(record AnnotationRecord) map[string]string {
+	data, _ := json.Marshal(record)
+	var raw map[string]json.RawMessage
+	json.Unmarshal(data, &raw)
+
+	fields := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if text := string(value); text != "null" {
+			fields[name] = text
+		}
+	}
+	return fields
+}
+
+// DiffAuditRecords compares current records against recorded ones line by line
+func DiffAuditRecords(recorded, current []AnnotationRecord) []AuditDifference {
+	type lineKey struct {
+		file string
+		line int
+	}
+	index := func(records []AnnotationRecord) map[lineKey]AnnotationRecord {
+		byLine := make(map[lineKey]AnnotationRecord, len(records))
+		for _, record := range records {
+			byLine[lineKey{record.File, record.Line}] = record
+		}
+		return byLine
+	}
+	recordedByLine, currentByLine := index(recorded), index(current)
+
+	keys := make(map[lineKey]bool)
+	for key := range recordedByLine {
+		keys[key] = true
+	}
+	for key := range currentByLine {
+		keys[key] = true
+	}
+	sortedKeys := make([]lineKey, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Slice(sortedKeys, func(i, j int) bool {
+		if sortedKeys[i].file != sortedKeys[j].file {
+			return sortedKeys[i].file < sortedKeys[j].file
+		}
+		return sortedKeys[i].line < sortedKeys[j].line
+	})
+
+	var differences []AuditDifference
+	for _, key := range sortedKeys {
+		before, hadBefore := recordedByLine[key]
+		after, hasAfter := currentByLine[key]
+		switch {
+		case !hadBefore:
+			differences = append(differences, AuditDifference{File: key.file, Line: key.line, Field: "line", Recorded: "missing", Current: "present"})
+		case !hasAfter:
+			differences = append(differences, AuditDifference{File: key.file, Line: key.line, Field: "line", Recorded: "present", Current: "missing"})
+		default:
+			beforeFields, afterFields := recordFields(before), recordFields(after)
+			names := make(map[string]bool)
+			for name := range beforeFields {
+				names[name] = true
+			}
+			for name := range afterFields {
+				names[name] = true
+			}
+			sortedNames := make([]string, 0, len(names))
+			for name := range names {
+				sortedNames = append(sortedNames, name)
+			}
+			sort.Strings(sortedNames)
+			for _, name := range sortedNames {
+				if beforeFields[name] != afterFields[name] {
+					differences = append(differences, AuditDifference{
+						File: key.file, Line: key.line, Field: name,
+						Recorded: orNone(beforeFields[name]), Current: orNone(afterFields[name]),
+					})
+				}
+			}
+		}
+	}
+	return differences
+}
+
+// orNone shows absent values explicitly
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// runSnapshot implements the snapshot subcommand
+func runSnapshot(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	output := flags.String("o", DefaultAuditSnapshotFile, "Artifact to write")
+	configPath := flags.String("config", "", "Path to the config file")
+	var settings AuditSettings
+	flags.BoolVar(&settings.Offline, "offline", false, "Find PR/MR numbers from local commit messages only")
+	flags.BoolVar(&settings.TrailersOnly, "trailers-only", false, "Take approvers from Reviewed-by/Acked-by trailers only")
+	flags.BoolVar(&settings.AllApprovers, "all-approvers", false, "Record every approver of each line, not only the last")
+	flags.BoolVar(&settings.Threads, "threads", false, "Record review thread resolution status")
+	flags.BoolVar(&settings.Rounds, "rounds", false, "Record review rounds")
+	flags.BoolVar(&settings.Owners, "owners", false, "Record whether approvers are OWNERS")
+	flags.BoolVar(&settings.Backports, "backports", false, "Record original PRs/MRs of backports")
+	flags.BoolVar(&settings.IncludeVendored, "include-vendored", false, "Include vendored files")
+	incremental := flags.String("incremental-update", "", "Previous artifact whose records are reused for files unchanged since its commit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(paths[0], *configPath, "")
+	if err != nil {
+		return err
+	}
+	recorded, err := loadAuditConfig(repoRoot, *configPath)
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+	commit, err := ResolveRevision(repoRoot, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	snapshot := &AuditSnapshot{
+		Format:      auditSnapshotFormat,
+		ToolVersion: Version,
+		Repository:  fmt.Sprintf("%s/%s", repoInfo.Owner, repoInfo.Name),
+		Commit:      commit,
+		CreatedAt:   time.Now().UTC(),
+		Settings:    settings,
+		Config:      recorded,
+	}
+	for _, path := range paths {
+		snapshot.Paths = append(snapshot.Paths, displayPath(repoRoot, path))
+	}
+
+	// Reuse the records of files unchanged since the previous snapshot; a
+	// previous snapshot that does not match falls back to a full run
+	var reused map[string][]AnnotationRecord
+	if *incremental != "" {
+		previous, _, err := ReadAuditSnapshot(*incremental)
+		if err != nil {
+			return err
+		}
+		changed, err := ListChangedFiles(repoRoot, previous.Commit, commit)
+		if err != nil {
+			return err
+		}
+		reused, err = reusableRecords(previous, snapshot.Repository, snapshot.Paths, settings, recorded, changed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: cannot update %s incrementally, annotating all files: %v\n", *incremental, err)
+		} else {
+			debugf("reusing records of %d file(s) unchanged since %s", len(reused), shortCommit(previous.Commit))
+		}
+	}
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, settings.options(commit), githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+	records, err := annotateAtCommit(ctx, repoRoot, commit, paths, pipeline, settings, reused)
+	if err != nil {
+		return err
+	}
+	snapshot.Records = records
+
+	digest, err := WriteAuditSnapshot(*output, snapshot)
+	if err != nil {
+		return fmt.Errorf("could not write audit snapshot: %w", err)
+	}
+	fmt.Printf("wrote %s: %d lines of %s at %s, %s\n", *output, len(records), strings.Join(snapshot.Paths, ", "), shortCommit(commit), digest)
+	return nil
+}
+
+// runVerify implements the verify subcommand
+func runVerify(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	configPath := flags.String("config", "", "Path to the config file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path := DefaultAuditSnapshotFile
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+
+	snapshot, digest, err := ReadAuditSnapshot(path)
+	if err != nil {
+		return err
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(".", *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+	if repository := fmt.Sprintf("%s/%s", repoInfo.Owner, repoInfo.Name); repository != snapshot.Repository {
+		return fmt.Errorf("audit snapshot is for %s, not %s", snapshot.Repository, repository)
+	}
+	if snapshot.ToolVersion != Version {
+		fmt.Fprintf(os.Stderr, "warning: snapshot was taken with version %s, verifying with %s\n", snapshot.ToolVersion, Version)
+	}
+
+	// The artifact is not authenticated, so its config is only compared
+	// with the current one, never run; the recorded settings only select
+	// what is fetched
+	current, err := loadAuditConfig(repoRoot, *configPath)
+	if err != nil {
+		return err
+	}
+	if differences := auditConfigDifferences(snapshot.Config, current); len(differences) > 0 {
+		return fmt.Errorf("audit snapshot was taken with a different config file: %s differ", strings.Join(differences, ", "))
+	}
+
+	// Re-run with the current config and the recorded settings at the
+	// recorded commit
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, snapshot.Settings.options(snapshot.Commit), githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, len(snapshot.Paths))
+	for i, recordedPath := range snapshot.Paths {
+		paths[i] = filepath.Join(repoRoot, filepath.FromSlash(recordedPath))
+	}
+	records, err := annotateAtCommit(ctx, repoRoot, snapshot.Commit, paths, pipeline, snapshot.Settings, nil)
+	if err != nil {
+		return err
+	}
+
+	differences := DiffAuditRecords(snapshot.Records, records)
+	if len(differences) == 0 {
+		fmt.Printf("verified %s (%s): %d lines at %s match\n", path, digest, len(records), shortCommit(snapshot.Commit))
+		return nil
+	}
+	for _, difference := range differences {
+		fmt.Println(difference)
+	}
+	return fmt.Errorf("%d difference(s) from audit snapshot %s", len(differences), path)
+}
@@ -0,0 +1,115 @@
+package reviewblame
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// authUsage is the usage of the auth subcommand
+const authUsage = "usage: git-review-blame auth login|logout|status [-host <host>]"
+
+// runAuth implements the auth subcommand
+func runAuth(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	return runAuthCommand(ctx, systemKeyring, args, os.Stdin, os.Stdout)
+}
+
+// runAuthCommand stores, removes or checks the token of a host in keyring.
+// login reads the token from input, so it can be piped in from a secrets
+// manager instead of being pasted.
+func runAuthCommand(ctx context.Context, keyring Keyring, args []string, input *os.File, output io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(authUsage)
+	}
+	flags := flag.NewFlagSet("auth "+args[0], flag.ContinueOnError)
+	hostFlag := flags.String("host", "github.com", "Host the token is for, e.g. gitlab.example.com")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	host := authHost(*hostFlag)
+
+	switch args[0] {
+	case "login":
+		token, err := readToken(input, os.Stderr, host)
+		if err != nil {
+			return err
+		}
+		if err := keyring.Set(ctx, host, token); err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "Stored the token for %s in the OS keychain\n", host)
+	case "logout":
+		err := keyring.Delete(ctx, host)
+		if errors.Is(err, ErrTokenNotStored) {
+			return fmt.Errorf("no token stored for %s", host)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "Removed the token for %s from the OS keychain\n", host)
+	case "status":
+		_, err := keyring.Get(ctx, host)
+		if errors.Is(err, ErrTokenNotStored) {
+			return fmt.Errorf("no token stored for %s; run git-review-blame auth login -host %s", host, host)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "%s: token stored in the OS keychain\n", host)
+	default:
+		return fmt.Errorf("unknown auth command %q\n%s", args[0], authUsage)
+	}
+	return nil
+}
+
+// authHost accepts a URL such as https://gitlab.example.com/ in place of a
+// host name
+func authHost(host string) string {
+	if parsed, err := url.Parse(host); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// readToken reads a token from the first line of input. On a terminal it
+// prompts on prompt and turns off echo while the token is typed.
+func readToken(input *os.File, prompt io.Writer, host string) (string, error) {
+	if info, err := input.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprintf(prompt, "Paste a token for %s: ", host)
+		if setTerminalEcho(input, false) == nil {
+			defer func() {
+				setTerminalEcho(input, true)
+				fmt.Fprintln(prompt)
+			}()
+		}
+	}
+
+	line, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("could not read the token: %w", err)
+	}
+	token := strings.TrimSpace(line)
+	if err := validateToken(token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// setTerminalEcho turns echo of a terminal on or off with stty; it fails
+// where stty is not available, e.g. on Windows
+func setTerminalEcho(terminal *os.File, on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = terminal
+	return cmd.Run()
+}
@@ -0,0 +1,88 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tokenInput returns a file holding content, standing in for piped stdin
+func tokenInput(t *testing.T, content string) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestAuthCommand(t *testing.T) {
+	ctx := context.Background()
+	keyring := memoryKeyring{}
+	var out strings.Builder
+
+	if err := runAuthCommand(ctx, keyring, []string{"login", "-host", "https://gitlab.example.com/"}, tokenInput(t, "glpat-secret\n"), &out); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if keyring["gitlab.example.com"] != "glpat-secret" {
+		t.Errorf("expected the token to be stored for gitlab.example.com, got %v", keyring)
+	}
+	if err := runAuthCommand(ctx, keyring, []string{"login"}, tokenInput(t, "ghp_secret"), &out); err != nil {
+		t.Fatalf("login without a trailing newline failed: %v", err)
+	}
+	if keyring["github.com"] != "ghp_secret" {
+		t.Errorf("expected github.com to be the default host, got %v", keyring)
+	}
+
+	if err := runAuthCommand(ctx, keyring, []string{"status", "-host", "gitlab.example.com"}, nil, &out); err != nil {
+		t.Errorf("status failed: %v", err)
+	}
+	if err := runAuthCommand(ctx, keyring, []string{"logout", "-host", "gitlab.example.com"}, nil, &out); err != nil {
+		t.Errorf("logout failed: %v", err)
+	}
+	if err := runAuthCommand(ctx, keyring, []string{"status", "-host", "gitlab.example.com"}, nil, &out); err == nil || !strings.Contains(err.Error(), "no token stored for gitlab.example.com") {
+		t.Errorf("expected no token after logout, got %v", err)
+	}
+	if err := runAuthCommand(ctx, keyring, []string{"logout", "-host", "gitlab.example.com"}, nil, &out); err == nil {
+		t.Error("expected logout without a stored token to fail")
+	}
+
+	want := "Stored the token for gitlab.example.com in the OS keychain\n" +
+		"Stored the token for github.com in the OS keychain\n" +
+		"gitlab.example.com: token stored in the OS keychain\n" +
+		"Removed the token for gitlab.example.com from the OS keychain\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestAuthCommandErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  string
+	}{
+		{name: "no command", want: "usage: git-review-blame auth"},
+		{name: "unknown command", args: []string{"whoami"}, want: `unknown auth command "whoami"`},
+		{name: "empty token", args: []string{"login"}, input: "\n", want: "no token given"},
+		{name: "pasted with spaces", args: []string{"login"}, input: "token: ghp_secret\n", want: "whitespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runAuthCommand(ctx, memoryKeyring{}, tt.args, tokenInput(t, tt.input), &strings.Builder{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package reviewblame
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackportOrigin is the mainline PR/MR a backport was made from
+type BackportOrigin struct {
+	// PRNumber is the original PR/MR, 0 when the backport was detected but
+	// its origin could not be determined
+	PRNumber      int
+	Approver      string
+	ApproverEmail string
+	ApprovalTime  *time.Time
+}
+
+var (
+	// backportTitlePattern matches release-branch prefixes such as
+	// "[release-1.5] Fix crash" or "[Backport 2.x] Fix crash"
+	backportTitlePattern = regexp.MustCompile(`(?i)^\s*\[(?:release|backport|cherry[- ]?pick|[0-9]+\.[0-9x]+)[^\]]*\]`)
+	// backportReferencePattern finds the original PR in a title or description
+	backportReferencePattern = regexp.MustCompile(`(?i)\b(?:backport|cherry[- ]?pick)(?:ed)?(?:\s+of)?\s+(?:PR\s+|MR\s+)?[#!](\d+)`)
+	// trailingReferencePattern finds "Fix crash (#123)" in a backport title
+	trailingReferencePattern = regexp.MustCompile(`\([#!](\d+)\)\s*$`)
+	// cherryPickTrailerPattern matches the trailer added by git cherry-pick -x
+	cherryPickTrailerPattern = regexp.MustCompile(`\(cherry picked from commit ([0-9a-f]{7,40})\)`)
+)
+
+// isBackportBot reports whether a PR author is a known backport bot
+func isBackportBot(login string) bool {
+	login = strings.ToLower(login)
+	return strings.Contains(login, "backport") || strings.Contains(login, "cherry-pick") ||
+		login == "k8s-ci-robot" || login == "mergify[bot]"
+}
+
+// backportReference returns the original PR number referenced by a backport
+// PR's title or description, or 0
+func backportReference(title, body string) int {
+	for _, text := range []string{title, body} {
+		if match := backportReferencePattern.FindStringSubmatch(text); match != nil {
+			number, _ := strconv.Atoi(match[1])
+			return number
+		}
+	}
+	if backportTitlePattern.MatchString(title) {
+		if match := trailingReferencePattern.FindStringSubmatch(title); match != nil {
+			number, _ := strconv.Atoi(match[1])
+			return number
+		}
+	}
+	return 0
+}
+
+// BackportEnricher detects backport PRs/MRs and sets the original mainline
+// PR/MR and its approver on their lines. A PR is a backport when its title has
+// a release-branch prefix, it references the PR it backports, it was opened by
+// a backport bot, or the commit carries a "cherry picked from" trailer.
+// It must run after the approvals stage.
+type BackportEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	repoRoot string
+	// cache maps commit hash to its backport origin (nil when not a backport)
+	cache map[string]*BackportOrigin
+	// approvals caches the approvals of original PRs
+	approvals *ApprovalEnricher
+}
+
+// NewBackportEnricher creates the backport stage
+func NewBackportEnricher(client ReviewClient, repoInfo *RepoInfo, repoRoot string) *BackportEnricher {
+	return &BackportEnricher{
+		client:    client,
+		repoInfo:  repoInfo,
+		repoRoot:  repoRoot,
+		cache:     make(map[string]*BackportOrigin),
+		approvals: NewApprovalEnricher(client, repoInfo),
+	}
+}
+
+// Name implements Enricher
+func (e *BackportEnricher) Name() string {
+	return "backports"
+}
+
+// cherryPickSource returns the commit a commit was cherry-picked from, if recorded
+func (e *BackportEnricher) cherryPickSource(commitHash string) string {
+	cmd := exec.Command("git", "log", "-1", "--format=%B", commitHash)
+	cmd.Dir = e.repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	if match := cherryPickTrailerPattern.FindStringSubmatch(string(output)); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// origin determines the backport origin of a line, or nil if it is not a backport
+func (e *BackportEnricher) origin(ctx context.Context, line BlameLineWithApproval) *BackportOrigin {
+	original := backportReference(line.PRTitle, line.PRBody)
+	detected := original > 0 || backportTitlePattern.MatchString(line.PRTitle) || isBackportBot(line.PRAuthor)
+
+	if source := e.cherryPickSource(line.CommitHash); source != "" {
+		detected = true
+		if original == 0 {
+			owner, name := lineRepository(e.repoInfo, line)
+			if pr, err := e.client.FindPRByCommit(ctx, owner, name, source); err == nil && pr != nil && pr.Number != line.PRNumber {
+				original = pr.Number
+			}
+		}
+	}
+
+	if !detected {
+		return nil
+	}
+	return &BackportOrigin{PRNumber: original}
+}
+
+// Enrich implements Enricher
+func (e *BackportEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		if lines[i].PRNumber == 0 {
+			continue
+		}
+
+		commitHash := lines[i].CommitHash
+		origin, exists := e.cache[commitHash]
+		if !exists {
+			origin = e.origin(ctx, lines[i])
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if origin != nil && origin.PRNumber > 0 {
+				// Reuse the approvals stage to resolve the original PR's approver
+				original := []BlameLineWithApproval{{PRNumber: origin.PRNumber, Repository: lines[i].Repository}}
+				if err := e.approvals.Enrich(ctx, original); err != nil {
+					return err
+				}
+				origin.Approver = original[0].Approver
+				origin.ApproverEmail = original[0].ApproverEmail
+				origin.ApprovalTime = original[0].ApprovalTime
+			}
+			e.cache[commitHash] = origin
+		}
+		lines[i].Backport = origin
+	}
+	return nil
+}
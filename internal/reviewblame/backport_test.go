@@ -0,0 +1,109 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackportReference(t *testing.T) {
+	tests := []struct {
+		title string
+		body  string
+		want  int
+	}{
+		{"[release-1.5] Fix crash (#123)", "", 123},
+		{"[Backport 2.x] Fix crash", "Backport of #45 to 2.x", 45},
+		{"Backport #77 to release-3.0", "", 77},
+		{"Fix crash", "Cherry-pick of !9", 9},
+		{"[release-1.5] Fix crash", "", 0},
+		// A trailing reference only names the original on backport titles
+		{"Fix crash (#123)", "", 0},
+		{"Fix crash", "Fixes #3", 0},
+	}
+
+	for _, tt := range tests {
+		if got := backportReference(tt.title, tt.body); got != tt.want {
+			t.Errorf("backportReference(%q, %q) = %d, want %d", tt.title, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestBackportEnricher(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "file.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Fix crash")
+	mainline := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m",
+		"Fix crash\n\n(cherry picked from commit "+mainline+")")
+	picked := gitCommand(t, dir, "rev-parse", "HEAD")
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m", "Regular change")
+	regular := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	now := time.Unix(1700000000, 0)
+	client := &fakeReviewClient{
+		prs: map[string]int{mainline: 12},
+		approvals: map[int][]Review{
+			12: {newTestReview("alice", now), newTestReview("bob", now.Add(time.Hour))},
+			30: {newTestReview("carol", now)},
+		},
+	}
+
+	lines := []BlameLineWithApproval{
+		// Title prefix with a trailing reference
+		{BlameLine: BlameLine{CommitHash: regular}, PRNumber: 40, PRTitle: "[release-1.5] Fix leak (#30)"},
+		// Cherry-pick trailer, origin found from the picked commit
+		{BlameLine: BlameLine{CommitHash: picked}, PRNumber: 41, PRTitle: "Fix crash"},
+		// Bot authorship without a resolvable origin
+		{BlameLine: BlameLine{CommitHash: "cccc"}, PRNumber: 42, PRTitle: "Fix typo", PRAuthor: "backport-bot[bot]"},
+		// Not a backport
+		{BlameLine: BlameLine{CommitHash: mainline}, PRNumber: 12, PRTitle: "Fix crash"},
+	}
+
+	enricher := NewBackportEnricher(client, &RepoInfo{Owner: "owner", Name: "repo"}, dir)
+	if err := enricher.Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if origin := lines[0].Backport; origin == nil || origin.PRNumber != 30 || origin.Approver != "carol" {
+		t.Errorf("expected backport of #30 approved by carol, got %+v", origin)
+	}
+	if origin := lines[1].Backport; origin == nil || origin.PRNumber != 12 || origin.Approver != "bob" {
+		t.Errorf("expected backport of #12 approved by bob, got %+v", origin)
+	}
+	if origin := lines[2].Backport; origin == nil || origin.PRNumber != 0 {
+		t.Errorf("expected backport with unknown origin, got %+v", origin)
+	}
+	if lines[3].Backport != nil {
+		t.Errorf("expected no backport for a mainline PR, got %+v", lines[3].Backport)
+	}
+}
+
+func TestFormatBackport(t *testing.T) {
+	line := BlameLineWithApproval{
+		BlameLine: BlameLine{CommitHash: "abcdef1234567890", LineNumber: 1, Author: "dev", Date: "1700000000", Content: "x"},
+		PRNumber:  40,
+		Approver:  "release-manager",
+		Backport:  &BackportOrigin{PRNumber: 12, Approver: "alice"},
+	}
+
+	porcelain := (&OutputFormatter{Porcelain: true}).FormatOutput([]BlameLineWithApproval{line})
+	for _, want := range []string{"pr-number 40\n", "original-pr-number 12\n", "original-approver alice\n"} {
+		if !strings.Contains(porcelain, want) {
+			t.Errorf("expected porcelain output to contain %q, got:\n%s", want, porcelain)
+		}
+	}
+
+	human := (&OutputFormatter{}).FormatOutput([]BlameLineWithApproval{line})
+	if !strings.Contains(human, "(release-manager <- alice#12 ") {
+		t.Errorf("expected human output to show the original approver, got %q", human)
+	}
+}
@@ -0,0 +1,83 @@
+package reviewblame
+
+import (
+	"fmt"
+	"math"
+)
+
+// Badge colors, matching the shields.io palette
+const (
+	badgeColorGreen  = "#4c1"
+	badgeColorYellow = "#dfb317"
+	badgeColorOrange = "#fe7d37"
+	badgeColorRed    = "#e05d44"
+	badgeColorGrey   = "#9f9f9f"
+)
+
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">
+  <title>%[3]s: %[4]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r">
+    <rect width="%[1]d" height="20" rx="3" fill="#fff"/>
+  </clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[5]d" height="20" fill="%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]d" y="14">%[3]s</text>
+    <text x="%[8]d" y="14">%[4]s</text>
+  </g>
+</svg>
+`
+
+// RenderCoverageBadge renders a flat SVG badge such as "reviewed: 97%"
+func RenderCoverageBadge(stats ReviewStats) string {
+	label := "reviewed"
+	message := "n/a"
+	color := badgeColorGrey
+
+	if stats.TotalLines > 0 {
+		coverage := stats.Coverage()
+		message = fmt.Sprintf("%d%%", int(math.Floor(coverage)))
+		color = badgeColor(coverage)
+	}
+
+	labelWidth := badgeTextWidth(label)
+	messageWidth := badgeTextWidth(message)
+	totalWidth := labelWidth + messageWidth
+
+	return fmt.Sprintf(badgeTemplate,
+		totalWidth,
+		labelWidth,
+		label,
+		message,
+		messageWidth,
+		color,
+		labelWidth/2,
+		labelWidth+messageWidth/2,
+	)
+}
+
+// badgeColor picks the badge color for a coverage percentage
+func badgeColor(coverage float64) string {
+	switch {
+	case coverage >= 90:
+		return badgeColorGreen
+	case coverage >= 75:
+		return badgeColorYellow
+	case coverage >= 50:
+		return badgeColorOrange
+	default:
+		return badgeColorRed
+	}
+}
+
+// badgeTextWidth approximates the rendered width of text in an 11px Verdana font
+func badgeTextWidth(text string) int {
+	return len(text)*7 + 10
+}
@@ -0,0 +1,62 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderCoverageBadge(t *testing.T) {
+	tests := []struct {
+		name          string
+		stats         ReviewStats
+		expectMessage string
+		expectColor   string
+	}{
+		{
+			name:          "fully reviewed",
+			stats:         ReviewStats{TotalLines: 10, ReviewedLines: 10},
+			expectMessage: "reviewed: 100%",
+			expectColor:   badgeColorGreen,
+		},
+		{
+			name:          "rounds down",
+			stats:         ReviewStats{TotalLines: 1000, ReviewedLines: 979},
+			expectMessage: "reviewed: 97%",
+			expectColor:   badgeColorGreen,
+		},
+		{
+			name:          "partially reviewed",
+			stats:         ReviewStats{TotalLines: 4, ReviewedLines: 3},
+			expectMessage: "reviewed: 75%",
+			expectColor:   badgeColorYellow,
+		},
+		{
+			name:          "mostly unreviewed",
+			stats:         ReviewStats{TotalLines: 10, ReviewedLines: 1},
+			expectMessage: "reviewed: 10%",
+			expectColor:   badgeColorRed,
+		},
+		{
+			name:          "no lines",
+			stats:         ReviewStats{},
+			expectMessage: "reviewed: n/a",
+			expectColor:   badgeColorGrey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg := RenderCoverageBadge(tt.stats)
+
+			if !strings.HasPrefix(svg, "<svg ") {
+				t.Errorf("expected SVG document, got %q", svg)
+			}
+			if !strings.Contains(svg, "<title>"+tt.expectMessage+"</title>") {
+				t.Errorf("expected title %q in badge:\n%s", tt.expectMessage, svg)
+			}
+			if !strings.Contains(svg, `fill="`+tt.expectColor+`"`) {
+				t.Errorf("expected color %s in badge:\n%s", tt.expectColor, svg)
+			}
+		})
+	}
+}
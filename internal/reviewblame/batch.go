@@ -0,0 +1,266 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CommitBatchLookup is implemented by review clients that can look up the
+// PRs of many commits, and their approvals, in a few requests. The PR
+// lookup stage prefetches through it; FindPRByCommit and GetPRApprovals then
+// answer from the prefetched results and fall back to per-commit requests
+// for anything the batch missed.
+type CommitBatchLookup interface {
+	PrefetchCommits(ctx context.Context, owner, repo string, commitHashes []string) error
+}
+
+// graphqlCommitBatchSize is the number of commits looked up per GraphQL query
+const graphqlCommitBatchSize = 50
+
+// commitPullsFragment selects the pull requests of a commit and their
+// approving reviews
+const commitPullsFragment = `fragment commitPulls on Commit {
+  associatedPullRequests(first: 5) {
+    nodes {
+      number
+      title
+      state
+      body
+      mergedAt
+      headRefName
+      author { login }
+      labels(first: 20) { nodes { name } }
+      reviews(first: 100, states: APPROVED) {
+        pageInfo { hasNextPage }
+        nodes {
+          author { login avatarUrl }
+          submittedAt
+          url
+        }
+      }
+    }
+  }
+}`
+
+// commitBatchQuery builds a query looking up count commits, passed as the
+// variables $c0, $c1, ... and returned under the aliases c0, c1, ...
+func commitBatchQuery(count int) string {
+	var query strings.Builder
+	query.WriteString("query($owner: String!, $repo: String!")
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&query, ", $c%d: GitObjectID!", i)
+	}
+	query.WriteString(") {\n  repository(owner: $owner, name: $repo) {\n")
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&query, "    c%d: object(oid: $c%d) { ...commitPulls }\n", i, i)
+	}
+	query.WriteString("  }\n}\n")
+	query.WriteString(commitPullsFragment)
+	return query.String()
+}
+
+// graphqlCommit is a commit as returned by the commit batch query
+type graphqlCommit struct {
+	AssociatedPullRequests struct {
+		Nodes []graphqlPullRequest `json:"nodes"`
+	} `json:"associatedPullRequests"`
+}
+
+// graphqlPullRequest is a pull request as returned by the commit batch query
+type graphqlPullRequest struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	State       string     `json:"state"`
+	Body        string     `json:"body"`
+	MergedAt    *time.Time `json:"mergedAt"`
+	HeadRefName string     `json:"headRefName"`
+	Author      *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Labels struct {
+		Nodes []PRLabel `json:"nodes"`
+	} `json:"labels"`
+	Reviews struct {
+		PageInfo struct {
+			HasNextPage bool `json:"hasNextPage"`
+		} `json:"pageInfo"`
+		Nodes []struct {
+			Author *struct {
+				Login     string `json:"login"`
+				AvatarURL string `json:"avatarUrl"`
+			} `json:"author"`
+			SubmittedAt *time.Time `json:"submittedAt"`
+			URL         string     `json:"url"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+}
+
+// toPullRequest converts a GraphQL pull request to the REST format. GraphQL
+// states are upper case and tell merged PRs apart; REST reports them closed.
+func (p graphqlPullRequest) toPullRequest() *PullRequest {
+	pr := &PullRequest{
+		Number:   p.Number,
+		Title:    p.Title,
+		State:    strings.ToLower(p.State),
+		Body:     p.Body,
+		MergedAt: p.MergedAt,
+		Labels:   p.Labels.Nodes,
+	}
+	if pr.State == "merged" {
+		pr.State = "closed"
+	}
+	if p.Author != nil {
+		pr.User.Login = p.Author.Login
+	}
+	pr.Head.Ref = p.HeadRefName
+	return pr
+}
+
+// CommitPRInfo is the pull request of a commit as found by a batch lookup
+type CommitPRInfo struct {
+	// PR is nil when the commit belongs to no pull request
+	PR *PullRequest
+	// Approvals are the approving reviews of PR, oldest first
+	Approvals []Review
+	// ApprovalsComplete is false when PR has more approving reviews than a
+	// single query returns
+	ApprovalsComplete bool
+}
+
+// FindPRsByCommits looks up the pull requests of many commits, and their
+// approvals, with one GraphQL query per graphqlCommitBatchSize commits.
+// Commits GitHub does not know are left out of the result; a commit in
+// several pull requests resolves as in FindPRByCommit.
+func (c *GitHubClient) FindPRsByCommits(ctx context.Context, owner, repo string, commitHashes []string) (map[string]*CommitPRInfo, error) {
+	infos := make(map[string]*CommitPRInfo)
+	for start := 0; start < len(commitHashes); start += graphqlCommitBatchSize {
+		end := start + graphqlCommitBatchSize
+		if end > len(commitHashes) {
+			end = len(commitHashes)
+		}
+		if err := c.findPRsByCommitBatch(ctx, owner, repo, commitHashes[start:end], infos); err != nil {
+			return nil, err
+		}
+	}
+	return infos, nil
+}
+
+// findPRsByCommitBatch runs one commit batch query and adds its results to infos
+func (c *GitHubClient) findPRsByCommitBatch(ctx context.Context, owner, repo string, commitHashes []string, infos map[string]*CommitPRInfo) error {
+	variables := map[string]interface{}{
+		"owner": owner,
+		"repo":  repo,
+	}
+	for i, commitHash := range commitHashes {
+		variables[fmt.Sprintf("c%d", i)] = commitHash
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"query":     commitBatchQuery(len(commitHashes)),
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.makeRequestWithBody(ctx, "POST", c.graphqlURL(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	var result struct {
+		Data struct {
+			Repository map[string]*graphqlCommit `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&result); err != nil {
+		return err
+	}
+	// Unknown commits fail only their own alias; the rest of the data is
+	// still usable
+	if result.Data.Repository == nil && len(result.Errors) > 0 {
+		return fmt.Errorf("GitHub GraphQL error: %s", result.Errors[0].Message)
+	}
+
+	for i, commitHash := range commitHashes {
+		commit := result.Data.Repository[fmt.Sprintf("c%d", i)]
+		if commit == nil {
+			continue
+		}
+		infos[commitHash] = commit.prInfo()
+	}
+	return nil
+}
+
+// prInfo picks the pull request of a commit as FindPRByCommit does (see
+// preferCommitPR)
+func (c *graphqlCommit) prInfo() *CommitPRInfo {
+	var found *graphqlPullRequest
+	var pr *PullRequest
+	for i, node := range c.AssociatedPullRequests.Nodes {
+		if candidate := node.toPullRequest(); preferCommitPR(pr, candidate) {
+			found, pr = &c.AssociatedPullRequests.Nodes[i], candidate
+		}
+	}
+	if found == nil {
+		return &CommitPRInfo{ApprovalsComplete: true}
+	}
+
+	info := &CommitPRInfo{
+		PR:                pr,
+		ApprovalsComplete: !found.Reviews.PageInfo.HasNextPage,
+	}
+	for _, node := range found.Reviews.Nodes {
+		review := Review{State: "APPROVED", SubmittedAt: node.SubmittedAt, HTMLURL: node.URL}
+		if node.Author != nil {
+			review.User.Login = node.Author.Login
+			review.User.AvatarURL = node.Author.AvatarURL
+		}
+		info.Approvals = append(info.Approvals, review)
+	}
+	return info
+}
+
+// PrefetchCommits implements CommitBatchLookup
+func (a *GitHubClientAdapter) PrefetchCommits(ctx context.Context, owner, repo string, commitHashes []string) error {
+	infos, err := a.client.FindPRsByCommits(ctx, owner, repo, commitHashes)
+	if err != nil {
+		return err
+	}
+
+	if a.commits == nil {
+		a.commits = make(map[string]*CommitPRInfo)
+		a.approvals = make(map[string][]Review)
+	}
+	for commitHash, info := range infos {
+		a.commits[owner+"/"+repo+"@"+commitHash] = info
+		if info.PR != nil && info.ApprovalsComplete {
+			a.approvals[fmt.Sprintf("%s/%s#%d", owner, repo, info.PR.Number)] = info.Approvals
+		}
+	}
+	return nil
+}
+
+// prefetchedPR returns the prefetched pull request of a commit, if any
+func (a *GitHubClientAdapter) prefetchedPR(owner, repo, commitHash string) (*CommitPRInfo, bool) {
+	info, exists := a.commits[owner+"/"+repo+"@"+commitHash]
+	return info, exists
+}
+
+// prefetchedApprovals returns the prefetched approvals of a pull request, if any
+func (a *GitHubClientAdapter) prefetchedApprovals(owner, repo string, prNumber int) ([]Review, bool) {
+	approvals, exists := a.approvals[fmt.Sprintf("%s/%s#%d", owner, repo, prNumber)]
+	return approvals, exists
+}
@@ -0,0 +1,265 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// graphqlCommitResponse builds the batch query result of a commit in one PR
+func graphqlCommitResponse(number int, merged bool, approvers ...string) map[string]interface{} {
+	var reviews []map[string]interface{}
+	for _, login := range approvers {
+		reviews = append(reviews, map[string]interface{}{
+			"author":      map[string]string{"login": login, "avatarUrl": "https://avatars.example.com/" + login},
+			"submittedAt": "2024-01-10T12:00:00Z",
+			"url":         fmt.Sprintf("https://github.com/owner/repo/pull/%d#pullrequestreview-%s", number, login),
+		})
+	}
+	pr := map[string]interface{}{
+		"number":      number,
+		"title":       fmt.Sprintf("PR %d", number),
+		"state":       "OPEN",
+		"body":        "Fixes #9",
+		"headRefName": "feature",
+		"author":      map[string]string{"login": "author"},
+		"labels":      map[string]interface{}{"nodes": []map[string]string{{"name": "security-review"}}},
+		"reviews": map[string]interface{}{
+			"pageInfo": map[string]bool{"hasNextPage": false},
+			"nodes":    reviews,
+		},
+	}
+	if merged {
+		pr["state"] = "MERGED"
+		pr["mergedAt"] = "2024-01-11T12:00:00Z"
+	}
+	return map[string]interface{}{
+		"associatedPullRequests": map[string]interface{}{"nodes": []interface{}{pr}},
+	}
+}
+
+// newGraphQLBatchServer serves commit batch queries from responses, keyed by
+// commit hash, and counts GraphQL and REST requests
+func newGraphQLBatchServer(t *testing.T, responses map[string]map[string]interface{}, graphqlRequests, restRequests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			*restRequests++
+			w.Write([]byte("[]"))
+			return
+		}
+		*graphqlRequests++
+		var payload struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Variables["owner"] != "owner" || payload.Variables["repo"] != "repo" {
+			t.Errorf("unexpected variables %v", payload.Variables)
+		}
+
+		repository := make(map[string]interface{})
+		var errors []map[string]string
+		for alias, commitHash := range payload.Variables {
+			if !strings.HasPrefix(alias, "c") || !strings.Contains(payload.Query, alias+": object(oid: $"+alias+")") {
+				continue
+			}
+			response, ok := responses[commitHash.(string)]
+			if !ok {
+				repository[alias] = nil
+				errors = append(errors, map[string]string{"message": "Could not resolve to a Commit"})
+				continue
+			}
+			repository[alias] = response
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":   map[string]interface{}{"repository": repository},
+			"errors": errors,
+		})
+	}))
+}
+
+func TestGitHubFindPRsByCommits(t *testing.T) {
+	graphqlRequests, restRequests := 0, 0
+	closed := graphqlCommitResponse(4, false)
+	merged := graphqlCommitResponse(5, true, "alice", "bob")
+	closed["associatedPullRequests"].(map[string]interface{})["nodes"] = append(
+		closed["associatedPullRequests"].(map[string]interface{})["nodes"].([]interface{}),
+		merged["associatedPullRequests"].(map[string]interface{})["nodes"].([]interface{})[0])
+	server := newGraphQLBatchServer(t, map[string]map[string]interface{}{
+		"aaaa": closed,
+		"bbbb": {"associatedPullRequests": map[string]interface{}{"nodes": []interface{}{}}},
+	}, &graphqlRequests, &restRequests)
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	infos, err := client.FindPRsByCommits(context.Background(), "owner", "repo", []string{"aaaa", "bbbb", "cccc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if graphqlRequests != 1 {
+		t.Errorf("expected 1 GraphQL request, got %d", graphqlRequests)
+	}
+
+	info := infos["aaaa"]
+	if info == nil || info.PR == nil || info.PR.Number != 5 || info.PR.State != "closed" || info.PR.Head.Ref != "feature" || info.PR.User.Login != "author" ||
+		len(info.PR.LabelNames()) != 1 || info.PR.LabelNames()[0] != "security-review" {
+		t.Fatalf("expected merged PR 5 for aaaa, got %+v", info)
+	}
+	if len(info.Approvals) != 2 || info.Approvals[1].User.Login != "bob" || info.Approvals[1].SubmittedAt == nil || !info.ApprovalsComplete ||
+		info.Approvals[1].HTMLURL != "https://github.com/owner/repo/pull/5#pullrequestreview-bob" || info.Approvals[1].User.AvatarURL != "https://avatars.example.com/bob" {
+		t.Errorf("expected approvals by alice and bob, got %+v", info.Approvals)
+	}
+	if info := infos["bbbb"]; info == nil || info.PR != nil {
+		t.Errorf("expected no PR for bbbb, got %+v", info)
+	}
+	if _, exists := infos["cccc"]; exists {
+		t.Error("expected unknown commit cccc to be left out")
+	}
+}
+
+func TestGitHubCommitPRSelectionMatchesREST(t *testing.T) {
+	// An open PR listed before the merged one that landed the commit
+	prs := []PullRequest{{Number: 4, State: "open"}, {Number: 5, State: "closed"}}
+	mergedAt := time.Date(2024, 1, 11, 12, 0, 0, 0, time.UTC)
+	prs[1].MergedAt = &mergedAt
+	open := graphqlCommitResponse(4, false)
+	merged := graphqlCommitResponse(5, true)
+	open["associatedPullRequests"].(map[string]interface{})["nodes"] = append(
+		open["associatedPullRequests"].(map[string]interface{})["nodes"].([]interface{}),
+		merged["associatedPullRequests"].(map[string]interface{})["nodes"].([]interface{})[0])
+
+	graphqlRequests, restRequests := 0, 0
+	graphql := newGraphQLBatchServer(t, map[string]map[string]interface{}{"aaaa": open}, &graphqlRequests, &restRequests)
+	defer graphql.Close()
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(prs)
+	}))
+	defer rest.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = rest.URL
+	pr, err := client.FindPRByCommit(context.Background(), "owner", "repo", "aaaa")
+	if err != nil || pr == nil || pr.Number != 5 {
+		t.Fatalf("expected REST to pick merged PR 5, got %+v, %v", pr, err)
+	}
+
+	client.baseURL = graphql.URL
+	infos, err := client.FindPRsByCommits(context.Background(), "owner", "repo", []string{"aaaa"})
+	if err != nil || infos["aaaa"] == nil || infos["aaaa"].PR.Number != pr.Number {
+		t.Errorf("expected GraphQL to pick PR %d too, got %+v, %v", pr.Number, infos["aaaa"], err)
+	}
+}
+
+func TestGitHubFindPRsByCommitsEnterprise(t *testing.T) {
+	graphqlRequests, restRequests := 0, 0
+	server := newGraphQLBatchServer(t, map[string]map[string]interface{}{"aaaa": graphqlCommitResponse(1, true)}, &graphqlRequests, &restRequests)
+	defer server.Close()
+	// GitHub Enterprise Server answers GraphQL at /api/graphql, not under
+	// the REST prefix
+	enterprise := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		r.URL.Path = "/graphql"
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer enterprise.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = enterprise.URL + "/api/v3"
+	infos, err := client.FindPRsByCommits(context.Background(), "owner", "repo", []string{"aaaa"})
+	if err != nil || infos["aaaa"] == nil || graphqlRequests != 1 {
+		t.Errorf("expected the batch query at /api/graphql, got %+v, %v", infos, err)
+	}
+}
+
+func TestGitHubFindPRsByCommitsBatches(t *testing.T) {
+	graphqlRequests, restRequests := 0, 0
+	responses := make(map[string]map[string]interface{})
+	var commits []string
+	for i := 0; i < graphqlCommitBatchSize+1; i++ {
+		commitHash := fmt.Sprintf("%040x", i+1)
+		commits = append(commits, commitHash)
+		responses[commitHash] = graphqlCommitResponse(i+1, true)
+	}
+	server := newGraphQLBatchServer(t, responses, &graphqlRequests, &restRequests)
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	infos, err := client.FindPRsByCommits(context.Background(), "owner", "repo", commits)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if graphqlRequests != 2 || len(infos) != len(commits) {
+		t.Errorf("expected %d commits in 2 requests, got %d in %d", len(commits), len(infos), graphqlRequests)
+	}
+}
+
+func TestGitHubFindPRsByCommitsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"message": "Bad credentials"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	if _, err := client.FindPRsByCommits(context.Background(), "owner", "repo", []string{"aaaa"}); err == nil {
+		t.Error("expected error for GraphQL error response")
+	}
+}
+
+func TestPRLookupPrefetchesCommits(t *testing.T) {
+	graphqlRequests, restRequests := 0, 0
+	server := newGraphQLBatchServer(t, map[string]map[string]interface{}{
+		"aaaa": graphqlCommitResponse(1, true, "alice"),
+		"bbbb": graphqlCommitResponse(1, true, "alice"),
+		"cccc": graphqlCommitResponse(2, true, "bob", "carol"),
+	}, &graphqlRequests, &restRequests)
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+	pipeline := NewDefaultEnrichmentPipeline(&GitHubClientAdapter{client: client}, &RepoInfo{Owner: "owner", Name: "repo"})
+
+	lines, err := pipeline.Run(context.Background(), []BlameLine{
+		{CommitHash: "aaaa", LineNumber: 1},
+		{CommitHash: "bbbb", LineNumber: 2},
+		{CommitHash: "cccc", LineNumber: 3},
+		{CommitHash: "aaaa", LineNumber: 4},
+		{CommitHash: strings.Repeat("0", 40), LineNumber: 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if graphqlRequests != 1 {
+		t.Errorf("expected 1 GraphQL request, got %d", graphqlRequests)
+	}
+	// The uncommitted line is not looked up at all
+	if restRequests != 0 {
+		t.Errorf("expected no REST request, got %d", restRequests)
+	}
+	if lines[0].PRNumber != 1 || lines[0].Approver != "alice" || lines[0].PRTitle != "PR 1" || len(lines[0].LinkedIssues) != 1 {
+		t.Errorf("line 1: unexpected annotation %+v", lines[0])
+	}
+	if lines[2].PRNumber != 2 || lines[2].Approver != "carol" || len(lines[2].Approvers) != 2 {
+		t.Errorf("line 3: unexpected annotation %+v", lines[2])
+	}
+	if lines[4].PRNumber != 0 {
+		t.Errorf("line 5: expected no PR, got %d", lines[4].PRNumber)
+	}
+}
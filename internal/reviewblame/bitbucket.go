@@ -0,0 +1,226 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// BitbucketTokenEnv is the environment variable holding the Bitbucket
+// Cloud access token
+const BitbucketTokenEnv = "BITBUCKET_TOKEN"
+
+// BitbucketClient handles Bitbucket Cloud 2.0 API interactions
+type BitbucketClient struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewBitbucketClient creates a new Bitbucket Cloud API client
+func NewBitbucketClient(token string) *BitbucketClient {
+	return &BitbucketClient{
+		token:      token,
+		baseURL:    "https://api.bitbucket.org/2.0",
+		httpClient: newHTTPClient(30 * time.Second),
+	}
+}
+
+// newBitbucketClientForRepo creates a client for the API host of repoInfo
+func newBitbucketClientForRepo(token string, repoInfo *RepoInfo) *BitbucketClient {
+	client := NewBitbucketClient(token)
+	if repoInfo.APIURL != "" {
+		client.baseURL = repoInfo.APIURL
+	}
+	return client
+}
+
+// makeRequest makes an authenticated request to the Bitbucket API. Access
+// tokens (repository, project or workspace) are sent as bearer tokens.
+func (c *BitbucketClient) makeRequest(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	return doRequest(c.httpClient, req)
+}
+
+// BitbucketUser is a user as returned by the Bitbucket API
+type BitbucketUser struct {
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+}
+
+// login returns the nickname, which Bitbucket uses in mentions, or the
+// display name when the nickname is private
+func (u BitbucketUser) login() string {
+	if u.Nickname != "" {
+		return u.Nickname
+	}
+	return u.DisplayName
+}
+
+// BitbucketPullRequest represents a pull request from the Bitbucket API
+type BitbucketPullRequest struct {
+	ID      int           `json:"id"`
+	Title   string        `json:"title"`
+	State   string        `json:"state"`
+	Author  BitbucketUser `json:"author"`
+	Summary struct {
+		Raw string `json:"raw"`
+	} `json:"summary"`
+	Source struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+	} `json:"source"`
+	Participants []BitbucketParticipant `json:"participants"`
+}
+
+// BitbucketParticipant is a reviewer or commenter of a pull request
+type BitbucketParticipant struct {
+	User           BitbucketUser `json:"user"`
+	Approved       bool          `json:"approved"`
+	ParticipatedOn *time.Time    `json:"participated_on"`
+}
+
+// toPullRequest converts a Bitbucket pull request to GitHub PR format
+func (pr BitbucketPullRequest) toPullRequest() *PullRequest {
+	result := &PullRequest{
+		Number: pr.ID,
+		Title:  pr.Title,
+		State:  pr.State,
+		Body:   pr.Summary.Raw,
+	}
+	result.User.Login = pr.Author.login()
+	result.Head.Ref = pr.Source.Branch.Name
+	return result
+}
+
+// FindPRByCommit finds the pull request that introduced a specific commit,
+// preferring a merged pull request over open or declined ones
+func (c *BitbucketClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s/commit/%s/pullrequests", c.baseURL, owner, repo, commitHash)
+
+	resp, err := c.makeRequest(ctx, "GET", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Bitbucket API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	var found *BitbucketPullRequest
+	_, err = decodeJSONField(json.NewDecoder(newResponseReader(resp.Body)), "values", func(dec *json.Decoder) error {
+		return decodeJSONArray(dec, func(dec *json.Decoder) error {
+			var pr BitbucketPullRequest
+			if err := dec.Decode(&pr); err != nil {
+				return err
+			}
+			if found == nil || (pr.State == "MERGED" && found.State != "MERGED") {
+				found = &pr
+			}
+			if found.State == "MERGED" {
+				return errStopDecoding
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found == nil {
+		return nil, nil
+	}
+	return found.toPullRequest(), nil
+}
+
+// getPullRequest fetches a single pull request with its participants
+func (c *BitbucketClient) getPullRequest(ctx context.Context, owner, repo string, prNumber int) (*BitbucketPullRequest, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d", c.baseURL, owner, repo, prNumber)
+
+	resp, err := c.makeRequest(ctx, "GET", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Bitbucket API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	var pr BitbucketPullRequest
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetPRByNumber fetches a pull request by its ID
+func (c *BitbucketClient) GetPRByNumber(ctx context.Context, owner, repo string, prNumber int) (*PullRequest, error) {
+	pr, err := c.getPullRequest(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	return pr.toPullRequest(), nil
+}
+
+// GetPRApprovals gets the participants who approved a pull request, ordered
+// by when they last participated, which is the closest Bitbucket records to
+// an approval time
+func (c *BitbucketClient) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	pr, err := c.getPullRequest(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var approvals []Review
+	for _, participant := range pr.Participants {
+		if !participant.Approved {
+			continue
+		}
+		review := Review{State: "APPROVED", SubmittedAt: participant.ParticipatedOn}
+		review.User.Login = participant.User.login()
+		approvals = append(approvals, review)
+	}
+	sort.SliceStable(approvals, func(i, j int) bool {
+		if approvals[i].SubmittedAt == nil || approvals[j].SubmittedAt == nil {
+			return approvals[j].SubmittedAt != nil
+		}
+		return approvals[i].SubmittedAt.Before(*approvals[j].SubmittedAt)
+	})
+
+	return approvals, nil
+}
+
+// GetPRApprovalInfo gets complete approval information for a commit
+func (c *BitbucketClient) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	pr, err := c.FindPRByCommit(ctx, owner, repo, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr == nil {
+		return nil, fmt.Errorf("no pull request found for commit %s", commitHash)
+	}
+
+	approvals, err := c.GetPRApprovals(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRApprovalInfo{
+		PR:        *pr,
+		Approvers: approvals,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBitbucketGetPRApprovalInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/repositories/workspace/repo/commit/abc123/pullrequests":
+			w.Write([]byte(`{"values": [
+				{"id": 3, "title": "Declined attempt", "state": "DECLINED"},
+				{"id": 7, "title": "Add parser", "state": "MERGED",
+				 "author": {"display_name": "Alice Smith", "nickname": "alice"},
+				 "source": {"branch": {"name": "feature/parser"}}}
+			]}`))
+		case "/repositories/workspace/repo/pullrequests/7":
+			w.Write([]byte(`{"id": 7, "participants": [
+				{"user": {"nickname": "carol"}, "approved": true, "participated_on": "2024-03-02T10:00:00Z"},
+				{"user": {"nickname": "dave"}, "approved": false, "participated_on": "2024-03-01T08:00:00Z"},
+				{"user": {"display_name": "Bob Jones"}, "approved": true, "participated_on": "2024-03-01T09:00:00Z"}
+			]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewBitbucketClient("test-token")
+	client.baseURL = server.URL
+
+	info, err := client.GetPRApprovalInfo(context.Background(), "workspace", "repo", "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.PR.Number != 7 || info.PR.User.Login != "alice" || info.PR.Head.Ref != "feature/parser" {
+		t.Errorf("expected the merged pull request, got %+v", info.PR)
+	}
+	// dave commented without approving; approvals are ordered by time
+	if len(info.Approvers) != 2 || info.Approvers[0].User.Login != "Bob Jones" || info.Approvers[1].User.Login != "carol" {
+		t.Errorf("expected approvals by Bob Jones then carol, got %+v", info.Approvers)
+	}
+}
+
+func TestBitbucketFindPRByCommitNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"values": []}`))
+	}))
+	defer server.Close()
+
+	client := NewBitbucketClient("test-token")
+	client.baseURL = server.URL
+
+	pr, err := client.FindPRByCommit(context.Background(), "workspace", "repo", "abc123")
+	if err != nil || pr != nil {
+		t.Errorf("expected no pull request, got %+v, %v", pr, err)
+	}
+}
+
+func TestClientFactoryBitbucket(t *testing.T) {
+	repoInfo := &RepoInfo{Owner: "workspace", Name: "repo", Type: RepositoryTypeBitbucket, Host: "bitbucket.org"}
+
+	if _, err := (&ClientFactory{}).CreateClient(repoInfo, "github-token", "gitlab-token"); !errors.Is(err, ErrMissingBitbucketToken) {
+		t.Errorf("expected ErrMissingBitbucketToken, got %v", err)
+	}
+
+	client, err := (&ClientFactory{bitbucketToken: "bitbucket-token"}).CreateClient(repoInfo, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := client.(*BitbucketClient); !ok {
+		t.Errorf("expected *BitbucketClient, got %T", client)
+	}
+}
@@ -0,0 +1,208 @@
+package reviewblame
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BlameStream is a running git blame --incremental, whose hunks can be
+// enriched while git is still computing the rest of the file
+type BlameStream struct {
+	// Hunks receives the lines of each entry as git reports them, which is
+	// not file order, and is closed when git exits
+	Hunks <-chan []BlameLine
+	// Lines is the number of lines of the annotated file
+	Lines int
+
+	cancel   context.CancelFunc
+	done     chan error
+	waitOnce sync.Once
+	err      error
+}
+
+// StartGitBlameStream starts git blame --incremental on filePath as of rev,
+// or on the working tree when rev is empty. git only reports where lines
+// came from, so their content is read from the annotated file.
+func StartGitBlameStream(ctx context.Context, repoRoot, filePath, rev string, opts BlameOptions) (*BlameStream, error) {
+	args, relPath, err := gitBlameArgs(repoRoot, filePath, rev, opts, "--incremental")
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the contents git annotates; stdin can only be read once, so git
+	// receives it from here
+	var content []byte
+	var stdin io.Reader
+	switch {
+	case opts.Contents == "-":
+		if content, err = io.ReadAll(os.Stdin); err != nil {
+			return nil, err
+		}
+		stdin = bytes.NewReader(content)
+	case opts.Contents != "":
+		content, err = os.ReadFile(opts.Contents)
+	case rev != "":
+		content, err = ReadFileAt(repoRoot, relPath, rev)
+	default:
+		content, err = os.ReadFile(filepath.Join(repoRoot, relPath))
+	}
+	if err != nil {
+		return nil, err
+	}
+	contentLines := strings.Split(string(content), "\n")
+	if contentLines[len(contentLines)-1] == "" {
+		contentLines = contentLines[:len(contentLines)-1]
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoRoot
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	hunks := make(chan []BlameLine, streamWindow)
+	stream := &BlameStream{Hunks: hunks, Lines: len(contentLines), cancel: cancel, done: make(chan error, 1)}
+	go func() {
+		defer close(hunks)
+		parseErr := parseGitBlameIncremental(stdout, contentLines, relPath, func(hunk []BlameLine) error {
+			select {
+			case hunks <- hunk:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if parseErr != nil {
+			// Stop git so Wait does not block on a full pipe
+			cancel()
+			io.Copy(io.Discard, stdout)
+		}
+		err := cmd.Wait()
+		if err != nil && stderr.Len() > 0 {
+			err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		if parseErr != nil {
+			err = parseErr
+		}
+		stream.done <- err
+	}()
+	return stream, nil
+}
+
+// Wait waits for git to exit, after Hunks is closed, and returns its error
+func (s *BlameStream) Wait() error {
+	s.waitOnce.Do(func() {
+		s.err = <-s.done
+		s.cancel()
+	})
+	return s.err
+}
+
+// Close stops git when the remaining hunks are not needed and waits for it
+func (s *BlameStream) Close() {
+	s.cancel()
+	for range s.Hunks {
+	}
+	s.Wait()
+}
+
+// Collect returns every line of the stream in file order
+func (s *BlameStream) Collect() ([]BlameLine, error) {
+	var lines []BlameLine
+	for hunk := range s.Hunks {
+		lines = append(lines, hunk...)
+	}
+	if err := s.Wait(); err != nil {
+		return nil, err
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].LineNumber < lines[j].LineNumber })
+	return lines, nil
+}
+
+// parseGitBlameIncremental parses git blame --incremental output, calling
+// hunk with the lines of each entry once its closing filename header is
+// read. The commit headers only come with the first entry of each commit.
+func parseGitBlameIncremental(r io.Reader, content []string, filename string, hunk func([]BlameLine) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	commits := make(map[string]BlameLine)
+	var entry BlameLine
+	var count int
+	for scanner.Scan() {
+		line := scanner.Text()
+		if entry.CommitHash == "" {
+			// Each entry starts with "<hash> <orig line> <final line> <count>"
+			parts := strings.Fields(line)
+			if len(parts) != 4 || len(parts[0]) < 40 || !isHexString(parts[0]) {
+				return fmt.Errorf("unexpected git blame --incremental line %q", line)
+			}
+			entry = commits[parts[0]]
+			entry.CommitHash = parts[0]
+			entry.OriginalLineNumber, _ = strconv.Atoi(parts[1])
+			entry.LineNumber, _ = strconv.Atoi(parts[2])
+			count, _ = strconv.Atoi(parts[3])
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			entry.Author = value
+		case "author-mail":
+			entry.AuthorEmail = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+		case "author-time":
+			entry.Date = value
+		case "author-tz":
+			entry.AuthorTimezone = value
+		case "summary":
+			entry.Summary = value
+		case "filename":
+			entry.OriginalFilename = unquoteGitPath(value)
+			entry.Filename = filename
+			commits[entry.CommitHash] = entry
+
+			lines := make([]BlameLine, 0, count)
+			for i := 0; i < count; i++ {
+				line := entry
+				line.LineNumber += i
+				line.OriginalLineNumber += i
+				if line.LineNumber >= 1 && line.LineNumber <= len(content) {
+					line.Content = content[line.LineNumber-1]
+				}
+				lines = append(lines, line)
+			}
+			if err := hunk(lines); err != nil {
+				return err
+			}
+			entry = BlameLine{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if entry.CommitHash != "" {
+		return fmt.Errorf("git blame --incremental output ended inside an entry")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitBlameIncremental(t *testing.T) {
+	// Entries arrive out of file order, and the commit headers only come
+	// with the first entry of each commit
+	output := `b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1 2 2 2
+author Jane Smith
+author-mail <jane@example.com>
+author-time 1609632000
+author-tz +0100
+committer Jane Smith
+summary Add body
+previous a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 main.go
+filename main.go
+a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 1 1 1
+author John Doe
+author-mail <john@example.com>
+author-time 1609459200
+author-tz +0000
+summary Add main
+boundary
+filename "old main.go"
+b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1 9 4 1
+filename main.go
+`
+	content := []string{"package main", "func main() {", "\tprintln()", "}"}
+
+	var hunks []string
+	err := parseGitBlameIncremental(strings.NewReader(output), content, "src/main.go", func(lines []BlameLine) error {
+		var parts []string
+		for _, line := range lines {
+			if line.Filename != "src/main.go" {
+				t.Errorf("expected the final path, got %q", line.Filename)
+			}
+			parts = append(parts, fmt.Sprintf("%d:%d:%s:%s:%s:%s", line.LineNumber, line.OriginalLineNumber, line.OriginalFilename, line.Author, line.AuthorTimezone, line.Content))
+		}
+		hunks = append(hunks, strings.Join(parts, "|"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"2:2:main.go:Jane Smith:+0100:func main() {|3:3:main.go:Jane Smith:+0100:\tprintln()",
+		"1:1:old main.go:John Doe:+0000:package main",
+		"4:9:main.go:Jane Smith:+0100:}",
+	}
+	if strings.Join(hunks, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected hunks:\n%s\nwant:\n%s", strings.Join(hunks, "\n"), strings.Join(expected, "\n"))
+	}
+
+	if err := parseGitBlameIncremental(strings.NewReader("not a header\n"), content, "main.go", func([]BlameLine) error { return nil }); err == nil {
+		t.Error("expected an error for a malformed entry")
+	}
+	truncated := "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 1 1 1\nauthor John Doe\n"
+	if err := parseGitBlameIncremental(strings.NewReader(truncated), content, "main.go", func([]BlameLine) error { return nil }); err == nil {
+		t.Error("expected an error for a truncated entry")
+	}
+}
+
+func TestGitBlameStreamMatchesExec(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main\n\nfunc main() {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "main.go")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main")
+	if err := os.WriteFile(file, []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "commit", "-q", "-am", "Print")
+	// An uncommitted line of the working tree
+	if err := os.WriteFile(file, []byte("// Package main\npackage main\n\nfunc main() {\n\tprintln()\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, opts := range []BlameOptions{{}, {LineRanges: []string{"4,5"}}} {
+		want, err := ExecuteGitBlame(ctx, dir, file, opts)
+		if err != nil {
+			t.Fatalf("exec backend failed: %v", err)
+		}
+		opts.Backend = BackendIncremental
+		got, err := ExecuteGitBlame(ctx, dir, file, opts)
+		if err != nil {
+			t.Fatalf("incremental backend failed: %v", err)
+		}
+		if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", want) {
+			t.Errorf("ranges %v: expected\n%+v\ngot\n%+v", opts.LineRanges, want, got)
+		}
+	}
+
+	stream, err := StartGitBlameStream(ctx, dir, file, "HEAD~1", BlameOptions{})
+	if err != nil {
+		t.Fatalf("StartGitBlameStream failed: %v", err)
+	}
+	lines, err := stream.Collect()
+	if err != nil || stream.Lines != 4 || len(lines) != 4 || lines[3].Content != "}" {
+		t.Errorf("expected the 4 lines of HEAD~1, got %d of %d: %+v, %v", len(lines), stream.Lines, lines, err)
+	}
+
+	if _, err := ExecuteGitBlame(ctx, dir, filepath.Join(dir, "missing.go"), BlameOptions{Backend: BackendIncremental}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "untracked.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecuteGitBlame(ctx, dir, filepath.Join(dir, "untracked.go"), BlameOptions{Backend: BackendIncremental}); err == nil || !strings.Contains(err.Error(), "no such path") {
+		t.Errorf("expected git's error for an untracked file, got %v", err)
+	}
+}
+
+func TestEnrichmentPipelineRunHunks(t *testing.T) {
+	var windows []string
+	pipeline := NewEnrichmentPipeline(windowRecorder{windows: &windows})
+
+	// Hunks in the order git might report them; all are already waiting,
+	// so they are enriched window hunks at a time
+	hunks := make(chan []BlameLine, 4)
+	hunks <- []BlameLine{{CommitHash: "bbbb", LineNumber: 3}, {CommitHash: "bbbb", LineNumber: 4}}
+	hunks <- []BlameLine{{CommitHash: "aaaa", LineNumber: 1}}
+	hunks <- []BlameLine{{CommitHash: "cccc", LineNumber: 2}}
+	hunks <- []BlameLine{{CommitHash: "aaaa", LineNumber: 7}}
+	close(hunks)
+
+	var emitted []string
+	lines, err := pipeline.RunHunks(context.Background(), hunks, 2, func(window []BlameLineWithApproval) error {
+		var numbers []string
+		for _, line := range window {
+			numbers = append(numbers, fmt.Sprint(line.LineNumber))
+		}
+		emitted = append(emitted, strings.Join(numbers, ","))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(windows, " ") != "bbbb,bbbb,aaaa cccc,aaaa" {
+		t.Errorf("unexpected enrichment windows %v", windows)
+	}
+	// Line 1 can be written after the first window, lines 2-4 after the
+	// second, and line 7, after a gap, at the end
+	if strings.Join(emitted, " ") != "1 2,3,4 7" {
+		t.Errorf("unexpected emitted windows %v", emitted)
+	}
+	var numbers []int
+	for _, line := range lines {
+		numbers = append(numbers, line.LineNumber)
+	}
+	if fmt.Sprint(numbers) != "[1 2 3 4 7]" {
+		t.Errorf("expected the lines in file order, got %v", numbers)
+	}
+}
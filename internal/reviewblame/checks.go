@@ -0,0 +1,363 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// maxAnnotationsPerRequest is the GitHub limit of annotations per check run update
+const maxAnnotationsPerRequest = 50
+
+// CheckRun is the payload for creating or updating a GitHub check run
+type CheckRun struct {
+	Name       string          `json:"name,omitempty"`
+	HeadSHA    string          `json:"head_sha,omitempty"`
+	Status     string          `json:"status,omitempty"`
+	Conclusion string          `json:"conclusion,omitempty"`
+	Output     *CheckRunOutput `json:"output,omitempty"`
+}
+
+// CheckRunOutput holds the summary and annotations shown in the PR Files view
+type CheckRunOutput struct {
+	Title       string            `json:"title"`
+	Summary     string            `json:"summary"`
+	Annotations []CheckAnnotation `json:"annotations,omitempty"`
+}
+
+// CheckAnnotation marks a line range of a file in a check run
+type CheckAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title,omitempty"`
+	Message         string `json:"message"`
+}
+
+// checkRunResponse is the subset of the check run API response we use
+type checkRunResponse struct {
+	ID      int64  `json:"id"`
+	HTMLURL string `json:"html_url"`
+}
+
+// GitHubAppCredentials identifies a GitHub App installation
+type GitHubAppCredentials struct {
+	AppID          string
+	InstallationID string
+	PrivateKey     *rsa.PrivateKey
+}
+
+// LoadGitHubAppCredentials reads GitHub App credentials from the environment.
+// It returns nil without error when no App is configured.
+func LoadGitHubAppCredentials() (*GitHubAppCredentials, error) {
+	appID := os.Getenv("GITHUB_APP_ID")
+	if appID == "" {
+		return nil, nil
+	}
+
+	installationID := os.Getenv("GITHUB_APP_INSTALLATION_ID")
+	if installationID == "" {
+		return nil, errors.New("GITHUB_APP_INSTALLATION_ID must be set together with GITHUB_APP_ID")
+	}
+
+	keyPEM := []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+	if len(keyPEM) == 0 {
+		keyPath := os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH")
+		if keyPath == "" {
+			return nil, errors.New("GITHUB_APP_PRIVATE_KEY or GITHUB_APP_PRIVATE_KEY_PATH must be set together with GITHUB_APP_ID")
+		}
+		var err error
+		keyPEM, err = os.ReadFile(keyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := parseRSAPrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitHubAppCredentials{
+		AppID:          appID,
+		InstallationID: installationID,
+		PrivateKey:     key,
+	}, nil
+}
+
+// parseRSAPrivateKey decodes a PKCS#1 or PKCS#8 PEM-encoded RSA private key
+func parseRSAPrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid GitHub App private key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid GitHub App private key: not an RSA key")
+	}
+	return key, nil
+}
+
+// createAppJWT creates the short-lived RS256 JWT used to authenticate as a GitHub App
+func createAppJWT(appID string, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+
+	claims, err := json.Marshal(map[string]interface{}{
+		// Backdate to allow for clock drift, as recommended by GitHub
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// CreateInstallationToken exchanges GitHub App credentials for an installation access token
+func (c *GitHubClient) CreateInstallationToken(ctx context.Context, creds *GitHubAppCredentials) (string, error) {
+	jwt, err := createAppJWT(creds.AppID, creds.PrivateKey, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	// The App JWT replaces the regular token for this request only
+	appClient := &GitHubClient{token: jwt, baseURL: c.baseURL, httpClient: c.httpClient}
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", c.baseURL, creds.InstallationID)
+
+	resp, err := appClient.makeRequest(ctx, "POST", url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	var tokenResp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&tokenResp); err != nil {
+		return "", err
+	}
+
+	return tokenResp.Token, nil
+}
+
+// CreateCheckRun creates a check run, sending annotations in batches of 50
+// as required by the Checks API, and returns the check run's web URL
+func (c *GitHubClient) CreateCheckRun(ctx context.Context, owner, repo string, run CheckRun) (string, error) {
+	var remaining []CheckAnnotation
+	if run.Output != nil && len(run.Output.Annotations) > maxAnnotationsPerRequest {
+		remaining = run.Output.Annotations[maxAnnotationsPerRequest:]
+		output := *run.Output
+		output.Annotations = output.Annotations[:maxAnnotationsPerRequest]
+		run.Output = &output
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/check-runs", c.baseURL, owner, repo)
+	created, err := c.sendCheckRun(ctx, "POST", url, run, http.StatusCreated)
+	if err != nil {
+		return "", err
+	}
+
+	for len(remaining) > 0 {
+		batch := remaining
+		if len(batch) > maxAnnotationsPerRequest {
+			batch = batch[:maxAnnotationsPerRequest]
+		}
+		remaining = remaining[len(batch):]
+
+		update := CheckRun{Output: &CheckRunOutput{
+			Title:       run.Output.Title,
+			Summary:     run.Output.Summary,
+			Annotations: batch,
+		}}
+		updateURL := fmt.Sprintf("%s/repos/%s/%s/check-runs/%d", c.baseURL, owner, repo, created.ID)
+		if _, err := c.sendCheckRun(ctx, "PATCH", updateURL, update, http.StatusOK); err != nil {
+			return "", err
+		}
+	}
+
+	return created.HTMLURL, nil
+}
+
+// sendCheckRun sends a check run payload and decodes the response
+func (c *GitHubClient) sendCheckRun(ctx context.Context, method, url string, run CheckRun, expectedStatus int) (*checkRunResponse, error) {
+	payload, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.makeRequestWithBody(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result checkRunResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// BuildReviewCheckRun builds a completed check run annotating unreviewed lines
+// and policy violations. Consecutive unreviewed lines of the same file and
+// commit are merged into one annotation.
+func BuildReviewCheckRun(headSHA string, lines []BlameLineWithApproval, violations []PolicyViolation) CheckRun {
+	var annotations []CheckAnnotation
+	for _, v := range violations {
+		annotations = append(annotations, CheckAnnotation{
+			Path:            v.File,
+			StartLine:       v.Line,
+			EndLine:         v.Line,
+			AnnotationLevel: "failure",
+			Title:           "Policy violation",
+			Message:         v.Message,
+		})
+	}
+	for _, r := range FindUnreviewedRanges(lines) {
+		annotations = append(annotations, CheckAnnotation{
+			Path:            r.Filename,
+			StartLine:       r.StartLine,
+			EndLine:         r.EndLine,
+			AnnotationLevel: "warning",
+			Title:           "Unreviewed code",
+			Message:         r.Message(),
+		})
+	}
+
+	stats := ComputeReviewStats(lines)
+	conclusion := "success"
+	if len(violations) > 0 {
+		conclusion = "failure"
+	} else if stats.UnreviewedLines() > 0 {
+		conclusion = "neutral"
+	}
+
+	return CheckRun{
+		Name:       "git-review-blame",
+		HeadSHA:    headSHA,
+		Status:     "completed",
+		Conclusion: conclusion,
+		Output: &CheckRunOutput{
+			Title: fmt.Sprintf("%d of %d lines reviewed (%.1f%%)", stats.ReviewedLines, stats.TotalLines, stats.Coverage()),
+			Summary: fmt.Sprintf("%d line(s) trace back to commits without an approved pull request. %d policy violation(s).",
+				stats.UnreviewedLines(), len(violations)),
+			Annotations: annotations,
+		},
+	}
+}
+
+// DetectCheckHeadSHA determines the commit a check run should be attached to.
+// In pull_request workflows GITHUB_SHA is the test merge commit, so the PR
+// head from the event payload is preferred.
+func DetectCheckHeadSHA(repoRoot string) (string, error) {
+	if eventPath := os.Getenv("GITHUB_EVENT_PATH"); eventPath != "" {
+		if data, err := os.ReadFile(eventPath); err == nil {
+			var event struct {
+				PullRequest struct {
+					Head struct {
+						SHA string `json:"sha"`
+					} `json:"head"`
+				} `json:"pull_request"`
+			}
+			if json.Unmarshal(data, &event) == nil && event.PullRequest.Head.SHA != "" {
+				return event.PullRequest.Head.SHA, nil
+			}
+		}
+	}
+
+	if sha := os.Getenv("GITHUB_SHA"); sha != "" {
+		return sha, nil
+	}
+
+	return ResolveRevision(repoRoot, "HEAD")
+}
+
+// checkRunTokenSource describes where the check run token came from, for messages
+func checkRunTokenSource(creds *GitHubAppCredentials) string {
+	if creds != nil {
+		return "GitHub App " + creds.AppID + " (installation " + creds.InstallationID + ")"
+	}
+	return "GITHUB_TOKEN"
+}
+
+// PublishCheckRun publishes annotations for unreviewed lines and policy violations
+// as a check run, authenticating as the configured GitHub App or falling back to githubToken
+func PublishCheckRun(ctx context.Context, repoRoot string, repoInfo *RepoInfo, githubToken string, lines []BlameLineWithApproval, violations []PolicyViolation) (string, error) {
+	if repoInfo.Type != RepositoryTypeGitHub {
+		return "", fmt.Errorf("check runs can only be published to GitHub repositories, not %s", repoInfo.Type)
+	}
+
+	creds, err := LoadGitHubAppCredentials()
+	if err != nil {
+		return "", err
+	}
+
+	token := githubToken
+	if creds != nil {
+		token, err = newGitHubClientForRepo("", repoInfo).CreateInstallationToken(ctx, creds)
+		if err != nil {
+			return "", fmt.Errorf("could not authenticate as %s: %w", checkRunTokenSource(creds), err)
+		}
+	}
+	if token == "" {
+		return "", ErrMissingGitHubToken
+	}
+
+	headSHA, err := DetectCheckHeadSHA(repoRoot)
+	if err != nil {
+		return "", fmt.Errorf("could not determine head commit: %w", err)
+	}
+
+	url, err := newGitHubClientForRepo(token, repoInfo).CreateCheckRun(ctx, repoInfo.Owner, repoInfo.Name, BuildReviewCheckRun(headSHA, lines, violations))
+	if err != nil {
+		return "", fmt.Errorf("could not create check run using %s: %w", checkRunTokenSource(creds), err)
+	}
+
+	return url, nil
+}
@@ -0,0 +1,214 @@
+package reviewblame
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildReviewCheckRun(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: 1, Author: "John"}},
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: 2, Author: "John"}},
+		{BlameLine: BlameLine{CommitHash: "bbbbbbbbbb", Filename: "main.go", LineNumber: 3}, Approver: "jane", PRNumber: 7},
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: 4, Author: "John"}},
+		{BlameLine: BlameLine{CommitHash: "cccccccccc", Filename: "git.go", LineNumber: 1, Author: "Bob"}, PRNumber: 9},
+	}
+
+	run := BuildReviewCheckRun("deadbeef", lines, nil)
+
+	if run.HeadSHA != "deadbeef" {
+		t.Errorf("expected head sha deadbeef, got %s", run.HeadSHA)
+	}
+	if run.Conclusion != "neutral" {
+		t.Errorf("expected neutral conclusion, got %s", run.Conclusion)
+	}
+	if run.Output.Title != "1 of 5 lines reviewed (20.0%)" {
+		t.Errorf("unexpected title %q", run.Output.Title)
+	}
+
+	annotations := run.Output.Annotations
+	if len(annotations) != 3 {
+		t.Fatalf("expected 3 annotations, got %d: %+v", len(annotations), annotations)
+	}
+	if annotations[0].StartLine != 1 || annotations[0].EndLine != 2 {
+		t.Errorf("expected first annotation to span lines 1-2, got %d-%d", annotations[0].StartLine, annotations[0].EndLine)
+	}
+	if annotations[1].StartLine != 4 || annotations[1].EndLine != 4 {
+		t.Errorf("expected second annotation on line 4, got %d-%d", annotations[1].StartLine, annotations[1].EndLine)
+	}
+	if annotations[2].Path != "git.go" || !strings.Contains(annotations[2].Message, "#9 without approvals") {
+		t.Errorf("unexpected third annotation %+v", annotations[2])
+	}
+}
+
+func TestBuildReviewCheckRunAllReviewed(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "main.go", LineNumber: 1}, Approver: "jane"},
+	}
+
+	run := BuildReviewCheckRun("deadbeef", lines, nil)
+
+	if run.Conclusion != "success" {
+		t.Errorf("expected success conclusion, got %s", run.Conclusion)
+	}
+	if len(run.Output.Annotations) != 0 {
+		t.Errorf("expected no annotations, got %d", len(run.Output.Annotations))
+	}
+}
+
+func TestBuildReviewCheckRunPolicyViolations(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "main.go", LineNumber: 1}, Approver: "jane"},
+	}
+	violations := []PolicyViolation{{File: "main.go", Line: 1, Message: "self-approved"}}
+
+	run := BuildReviewCheckRun("deadbeef", lines, violations)
+
+	if run.Conclusion != "failure" {
+		t.Errorf("expected failure conclusion, got %s", run.Conclusion)
+	}
+	if len(run.Output.Annotations) != 1 || run.Output.Annotations[0].AnnotationLevel != "failure" {
+		t.Errorf("expected one failure annotation, got %+v", run.Output.Annotations)
+	}
+}
+
+func TestCreateAppJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	jwt, err := createAppJWT("12345", key, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 JWT parts, got %d", len(parts))
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "12345" || claims.Iat != now.Unix()-60 || claims.Exp != now.Add(9*time.Minute).Unix() {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("JWT signature does not verify: %v", err)
+	}
+}
+
+func TestParseRSAPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if _, err := parseRSAPrivateKey(pkcs1); err != nil {
+		t.Errorf("unexpected error parsing PKCS#1 key: %v", err)
+	}
+
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Bytes})
+	if _, err := parseRSAPrivateKey(pkcs8); err != nil {
+		t.Errorf("unexpected error parsing PKCS#8 key: %v", err)
+	}
+
+	if _, err := parseRSAPrivateKey([]byte("not a key")); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestCreateCheckRunBatchesAnnotations(t *testing.T) {
+	var posts, patches int
+	var annotationCounts []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var run CheckRun
+		if err := json.NewDecoder(r.Body).Decode(&run); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		annotationCounts = append(annotationCounts, len(run.Output.Annotations))
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/repos/owner/repo/check-runs":
+			posts++
+			if run.HeadSHA != "deadbeef" {
+				t.Errorf("expected head_sha deadbeef, got %s", run.HeadSHA)
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "html_url": "https://github.com/owner/repo/runs/42"})
+		case r.Method == "PATCH" && r.URL.Path == "/repos/owner/repo/check-runs/42":
+			patches++
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 42})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	annotations := make([]CheckAnnotation, 120)
+	for i := range annotations {
+		annotations[i] = CheckAnnotation{Path: "main.go", StartLine: i + 1, EndLine: i + 1, AnnotationLevel: "warning", Message: "x"}
+	}
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	url, err := client.CreateCheckRun(context.Background(), "owner", "repo", CheckRun{
+		Name:    "git-review-blame",
+		HeadSHA: "deadbeef",
+		Output:  &CheckRunOutput{Title: "t", Summary: "s", Annotations: annotations},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if url != "https://github.com/owner/repo/runs/42" {
+		t.Errorf("unexpected check run URL %s", url)
+	}
+	if posts != 1 || patches != 2 {
+		t.Errorf("expected 1 POST and 2 PATCH requests, got %d and %d", posts, patches)
+	}
+	expectedCounts := []int{50, 50, 20}
+	for i, count := range expectedCounts {
+		if i >= len(annotationCounts) || annotationCounts[i] != count {
+			t.Errorf("expected annotation batches %v, got %v", expectedCounts, annotationCounts)
+			break
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package reviewblame
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// repoInfoFromCIEnvironment describes the repository a GitHub Actions or
+// GitLab CI job runs for, or returns nil outside CI. Unlike the remote URL,
+// the CI variables name the right API host on GitHub Enterprise Server and
+// self-hosted GitLab, and keep GitLab subgroups intact.
+func repoInfoFromCIEnvironment(getenv func(string) string) *RepoInfo {
+	if getenv("GITHUB_ACTIONS") == "true" && strings.Contains(getenv("GITHUB_REPOSITORY"), "/") {
+		owner, name, _ := strings.Cut(getenv("GITHUB_REPOSITORY"), "/")
+		repoInfo := &RepoInfo{Owner: owner, Name: name, Type: RepositoryTypeGitHub, Host: "github.com"}
+		if serverURL, err := url.Parse(getenv("GITHUB_SERVER_URL")); err == nil && serverURL.Host != "" {
+			repoInfo.Host = serverURL.Host
+		}
+		repoInfo.APIURL = strings.TrimRight(getenv("GITHUB_API_URL"), "/")
+		return repoInfo
+	}
+
+	if getenv("GITLAB_CI") == "true" && strings.Contains(getenv("CI_PROJECT_PATH"), "/") {
+		projectPath := getenv("CI_PROJECT_PATH")
+		separator := strings.LastIndex(projectPath, "/")
+		repoInfo := &RepoInfo{
+			Owner:  projectPath[:separator],
+			Name:   projectPath[separator+1:],
+			Type:   RepositoryTypeGitLab,
+			Host:   getenv("CI_SERVER_HOST"),
+			APIURL: strings.TrimRight(getenv("CI_API_V4_URL"), "/"),
+		}
+		if repoInfo.Host == "" {
+			repoInfo.Host = "gitlab.com"
+		}
+		return repoInfo
+	}
+
+	return nil
+}
+
+// remoteMatchesCIRepository reports whether a remote URL points at the CI
+// repository, comparing the path after the host so that any host spelling
+// (SSH, HTTPS, enterprise hostnames) matches
+func remoteMatchesCIRepository(remoteURL string, repoInfo *RepoInfo) bool {
+	remoteURL = strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(remoteURL), "/"), ".git"))
+	projectPath := strings.ToLower(repoInfo.Owner + "/" + repoInfo.Name)
+	return strings.HasSuffix(remoteURL, "/"+projectPath) || strings.HasSuffix(remoteURL, ":"+projectPath)
+}
+
+// DetectRepoInfo determines the repository and its API host from remote, or
+// from the remote DetectRemote chooses when remote is empty. Inside GitHub
+// Actions or GitLab CI the job's environment is used, unless the remote
+// points at a different repository (e.g. a workflow checking out another
+// project); otherwise the remote URL is parsed.
+func DetectRepoInfo(repoRoot, remote string) (*RepoInfo, error) {
+	ci := repoInfoFromCIEnvironment(os.Getenv)
+	if ci == nil {
+		return ExtractRepoInfo(repoRoot, remote)
+	}
+
+	if remote == "" {
+		remote = DetectRemote(repoRoot)
+	}
+	cmd := exec.Command("git", "remote", "get-url", remote)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil || remoteMatchesCIRepository(string(output), ci) {
+		return ci, nil
+	}
+	return parseRepositoryURL(strings.TrimSpace(string(output)))
+}
@@ -0,0 +1,95 @@
+package reviewblame
+
+import (
+	"testing"
+)
+
+func TestRepoInfoFromCIEnvironment(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want *RepoInfo
+	}{
+		{
+			name: "outside CI",
+			env:  map[string]string{"GITHUB_REPOSITORY": "owner/repo"},
+		},
+		{
+			name: "github.com",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_REPOSITORY": "owner/repo",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_API_URL":    "https://api.github.com",
+			},
+			want: &RepoInfo{Owner: "owner", Name: "repo", Type: RepositoryTypeGitHub, Host: "github.com", APIURL: "https://api.github.com"},
+		},
+		{
+			name: "GitHub Enterprise Server",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_REPOSITORY": "owner/repo",
+				"GITHUB_SERVER_URL": "https://ghe.example.com",
+				"GITHUB_API_URL":    "https://ghe.example.com/api/v3/",
+			},
+			want: &RepoInfo{Owner: "owner", Name: "repo", Type: RepositoryTypeGitHub, Host: "ghe.example.com", APIURL: "https://ghe.example.com/api/v3"},
+		},
+		{
+			name: "GitLab subgroup",
+			env: map[string]string{
+				"GITLAB_CI":       "true",
+				"CI_PROJECT_PATH": "group/subgroup/project",
+				"CI_SERVER_HOST":  "gitlab.example.com",
+				"CI_API_V4_URL":   "https://gitlab.example.com/api/v4",
+			},
+			want: &RepoInfo{Owner: "group/subgroup", Name: "project", Type: RepositoryTypeGitLab, Host: "gitlab.example.com", APIURL: "https://gitlab.example.com/api/v4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := repoInfoFromCIEnvironment(func(key string) string { return tt.env[key] })
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRepoInfo(t *testing.T) {
+	t.Setenv("GITLAB_CI", "")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SERVER_URL", "https://ghe.example.com")
+	t.Setenv("GITHUB_API_URL", "https://ghe.example.com/api/v3")
+
+	tests := []struct {
+		name   string
+		remote string
+		want   RepoInfo
+	}{
+		{"enterprise remote", "git@ghe.example.com:Owner/repo.git",
+			RepoInfo{Owner: "owner", Name: "repo", Type: RepositoryTypeGitHub, Host: "ghe.example.com", APIURL: "https://ghe.example.com/api/v3"}},
+		{"no remote", "",
+			RepoInfo{Owner: "owner", Name: "repo", Type: RepositoryTypeGitHub, Host: "ghe.example.com", APIURL: "https://ghe.example.com/api/v3"}},
+		{"other repository", "https://github.com/other/project.git",
+			RepoInfo{Owner: "other", Name: "project", Type: RepositoryTypeGitHub, Host: "github.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			gitCommand(t, dir, "init", "-q")
+			if tt.remote != "" {
+				gitCommand(t, dir, "remote", "add", "origin", tt.remote)
+			}
+			got, err := DetectRepoInfo(dir, "")
+			if err != nil {
+				t.Fatalf("DetectRepoInfo failed: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1639 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Main runs the git-review-blame command line with the arguments of
+// os.Args and exits with a non-zero status on failure
+func Main() {
+	// Get tokens from environment
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	gitlabToken := os.Getenv("GITLAB_TOKEN")
+
+	// Ctrl-C cancels in-flight API requests and git subprocesses; once
+	// canceled, a second Ctrl-C terminates immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Revalidate API responses of earlier runs instead of refetching them
+	enableHTTPCache()
+
+	// Dispatch subcommands; any other first argument is a path or blame
+	// option, so "git-review-blame <file>" runs blame
+	args := os.Args[1:]
+	run := runBlame
+	if len(args) > 0 {
+		if command, ok := lookupCommand(args[0]); ok {
+			run, args = command.Run, args[1:]
+		}
+	}
+	err := run(ctx, args, githubToken, gitlabToken)
+	reportWarnings()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		exitWithError(ctx, err)
+	}
+}
+
+// runBlame implements the blame subcommand, the default command
+func runBlame(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	paths, opts, err := parseBlameOptions(flag.NewFlagSet("blame", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	return runGitReviewBlame(ctx, paths, opts, githubToken, gitlabToken)
+}
+
+// parseBlameOptions defines the blame options on flags, parses args, and
+// returns the paths to annotate and the options of the run. Commands
+// sharing the blame options define their own flags on flags first. -help
+// shows the help and returns flag.ErrHelp.
+func parseBlameOptions(flags *flag.FlagSet, args []string) ([]string, Options, error) {
+	var (
+		symbol       = flags.String("symbol", "", "Annotate only the given Go function, method or type")
+		porcelain    = flags.Bool("porcelain", false, "Show in a format designed for machine consumption")
+		incremental  = flags.Bool("incremental", false, "Show in the git blame --incremental format read by tig and git gui")
+		csvOutput    = flags.Bool("csv", false, "Write one CSV row per line, for spreadsheets and audit evidence (same as -format csv)")
+		csvCols      = flags.String("csv-columns", "", "Comma-separated CSV columns (default: file,line,commit,author,pr,approvers,approval_date)")
+		markdown     = flags.Bool("markdown", false, "Write a Markdown table of each file's hunks with PR/MR links and approvers (same as -format markdown)")
+		groupBy      = flags.String("group-by", "", "Summarize the lines by pr: each PR/MR with its title, approvers and line ranges")
+		htmlFile     = flags.String("html", "", "Write an HTML report with links to each line's PR/MR and approving reviews to the file")
+		format       = flags.String("format", "", "Output format name (human, porcelain, annotations, csv, markdown, html, dot, pr-summary, or a registered custom format), or a Go template per line such as '{{.ShortHash}} {{.Approver}}'")
+		stream       = flags.Bool("stream", false, "Print lines as soon as their approvals are resolved instead of after the whole file")
+		progress     = flags.Bool("progress", false, "Show a spinner counting the resolved lines on stderr")
+		showEmail    = flags.Bool("show-email", false, "Show author email instead of author name")
+		noApprMails  = flags.Bool("no-approver-emails", false, "With -show-email, do not look up approver emails from GitHub user profiles")
+		showIssues   = flags.Bool("show-issues", false, "Show the issues closed by each line's PR/MR")
+		showLabels   = flags.Bool("show-labels", false, "Show the labels of each line's PR/MR")
+		badge        = flags.Bool("badge", false, "Render an SVG review-coverage badge instead of blame output")
+		publishCheck = flags.Bool("publish-check", false, "Publish unreviewed lines as a GitHub check run (CI mode)")
+		postDiscuss  = flags.Bool("post-discussions", false, "Post unreviewed lines as GitLab merge request discussions (CI mode)")
+		notify       = flags.Bool("notify", false, "Post a coverage summary to the webhooks configured in the config file")
+		notifyBase   = flags.String("notify-base", "", "With -notify, also count the unreviewed lines from commits not reachable from this revision")
+		configPath   = flags.String("config", "", "Path to the config file (default: .git-review-blame.json in the repository root)")
+		policyFile   = flags.String("policy", "", "Rego policy file deciding which lines are compliant (requires opa)")
+		requireAppr  = flags.Bool("require-approval", false, "Fail when any line has no PR/MR or no approval, listing those lines (CI gate)")
+		failOnError  = flags.Bool("fail-on-error", false, "Fail when any API lookup failed, instead of only warning (CI gate)")
+		forbidSelf   = flags.Bool("forbid-self-approval", false, "Fail when any line was approved only by its own commit or PR/MR author, listing those lines (CI gate)")
+		threads      = flags.Bool("threads", false, "Report review thread resolution status of each PR/MR")
+		rules        = flags.Bool("approval-rules", false, "Check each MR against its approval rules, including Code Owner rules (GitLab)")
+		objections   = flags.Bool("show-objections", false, "List the reviewers who requested changes or whose review was dismissed before each PR/MR was merged")
+		checkPolicy  = flags.Bool("check-policy", false, "Check each PR against the review requirements of its base branch's current protection rules (GitHub)")
+		searchRemote = flags.Bool("search-remotes", false, "Look up commits without a PR/MR in the repositories of the other remotes on the same host")
+		rounds       = flags.Bool("rounds", false, "Count the review rounds of each PR/MR")
+		owners       = flags.Bool("owners", false, "Check approvers against per-directory OWNERS files")
+		backports    = flags.Bool("backports", false, "Detect backport PRs/MRs and show the original mainline PR/MR and its approver")
+		offline      = flags.Bool("offline", false, "Find PR/MR numbers from local merge and squash commit messages only, without API access")
+		allApprovers = flags.Bool("all-approvers", false, "Show every approver of each line's PR/MR instead of the last one")
+		showTeam     = flags.Bool("show-team", false, "Show each approver's team (configured teams, or the organization's teams or group's subgroups) instead of the approver")
+		approverPick = flags.String("approver-policy", ApproverPolicyLast, "Approval shown as the approver: first, last, last-before-merge, all or codeowner-preferred")
+		groupHunks   = flags.Bool("group-hunks", false, "Show the commit, approver and date only on the first line of each hunk")
+		colorBy      = flags.String("color-by", "", "Color human output by approver, pr or age (default: approver when writing to a terminal)")
+		noColor      = flags.Bool("no-color", false, "Never color the output, like setting NO_COLOR")
+		noLinks      = flags.Bool("no-hyperlinks", false, "Do not make PRs/MRs and approvers clickable in the terminal")
+		trailersOnly = flags.Bool("trailers-only", false, "Take approvers from Reviewed-by/Acked-by commit trailers only, without API access")
+		inclVendored = flags.Bool("include-vendored", false, "Include vendored files when annotating a directory")
+		anonymize    = flags.Bool("anonymize", false, "Replace names, logins and emails with stable pseudonyms in all output")
+		token        = flags.String("token", "", "GitHub or GitLab API token, instead of GITHUB_TOKEN or GITLAB_TOKEN")
+		tokenSource  = flags.String("token-source", "", "Take the API token only from: env, keyring, cli (gh or glab) or git-credential (default: try each in turn)")
+		provider     = flags.String("provider", "", "Hosting service of the remote: github, gitlab, bitbucket, gitea, forgejo or gerrit (default: detected)")
+		remote       = flags.String("remote", "", "Remote whose repository holds the PRs/MRs (default: the branch's remote, upstream or origin)")
+		debug        = flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+		jsonErrs     = flags.Bool("json-errors", false, "Report a failure as a JSON object with an error code on stderr")
+		noHTTPCache  = flags.Bool("no-http-cache", false, "Do not revalidate API responses cached by earlier runs")
+		cacheBackend = flags.String("cache", "", "Keep merged PRs/MRs and their approvers in a persistent cache: sqlite")
+		cachePath    = flags.String("cache-path", "", "Database file of the persistent cache (default: in the user cache directory)")
+		retries      = flags.Int("retries", retryPolicy.Attempts, "Retry API requests failing with server errors, reset connections or timeouts this often")
+		retryBackoff = flags.Duration("retry-backoff", retryPolicy.Backoff, "Longest wait before the first retry of a failed API request; doubles with each retry")
+		timeout      = flags.Duration("timeout", 0, "Time limit of each API request (default 30s)")
+		proxy        = flags.String("proxy", "", "URL of the proxy for API requests, instead of HTTPS_PROXY/HTTP_PROXY")
+		caCert       = flags.String("ca-cert", "", "PEM file of certificate authorities to trust in addition to the system's")
+		insecure     = flags.Bool("insecure-skip-verify", false, "Accept any TLS certificate of API hosts (insecure)")
+		help         = flags.Bool("help", false, "Show help message")
+	)
+
+	var lineRanges, ignoreRevsFiles, globs, approvers, authors, labels stringsFlag
+	flags.Var(&lineRanges, "L", "Annotate only the given line range; may be repeated")
+	flags.Var(&globs, "glob", "Annotate only files matching the pattern (e.g. '**/*.go'); may be repeated")
+	flags.Var(&approvers, "approver", "Print only the lines approved by the login, name or email; may be repeated")
+	flags.Var(&authors, "author", "Print only the lines whose commit author has the name or email; may be repeated")
+	flags.Var(&labels, "label", "Print only the lines whose PR/MR has the label; may be repeated")
+	flags.Var(&ignoreRevsFiles, "ignore-revs-file", "Skip the commits listed in the file when attributing lines, besides blame.ignoreRevsFile; may be repeated")
+
+	// git blame's move and copy detection; -CC and -CCC repeat -C
+	var (
+		detectMoves = flags.Bool("M", false, "Attribute lines moved within the file to the commit that wrote them")
+		copyOnce    = flags.Bool("C", false, "Also follow lines moved or copied from files changed in the same commit")
+		copyTwice   = flags.Bool("CC", false, "Also follow lines copied from files of the commit that created the file")
+		copyThrice  = flags.Bool("CCC", false, "Also follow lines copied from files of any commit")
+	)
+
+	ignoreWhitespace := flags.Bool("w", false, "Ignore whitespace changes when attributing lines")
+
+	// Options tig blame and git gui blame pass to git blame, accepted so this
+	// tool can replace it
+	flags.String("encoding", "", "Accepted for git blame compatibility; ignored")
+	contents := flags.String("contents", "", "Annotate the contents of the file, or of stdin with -, instead of the working tree file; changed lines are not committed yet")
+	backend := flags.String("backend", BackendExec, "Blame backend: exec runs git blame, incremental enriches hunks while git blame --incremental is still running, go-git blames in-process without the git CLI")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, Options{}, err
+	}
+	if *help {
+		showHelp()
+		return nil, Options{}, flag.ErrHelp
+	}
+
+	if *debug {
+		enableDebugLogging()
+	}
+	jsonErrors = *jsonErrs
+	if *noHTTPCache {
+		httpCacheDir = ""
+	}
+	if *retries < 0 {
+		return nil, Options{}, fmt.Errorf("-retries must not be negative")
+	}
+	retryPolicy.Attempts, retryPolicy.Backoff = *retries, *retryBackoff
+	cacheSettings.Backend, cacheSettings.Path = *cacheBackend, *cachePath
+	if err := cacheSettings.validate(); err != nil {
+		return nil, Options{}, fmt.Errorf("-%v", err)
+	}
+	if *timeout < 0 {
+		return nil, Options{}, fmt.Errorf("-timeout must not be negative")
+	}
+	if *timeout > 0 {
+		httpSettings.Timeout = timeout.String()
+	}
+	httpSettings.Proxy, httpSettings.InsecureSkipVerify = *proxy, *insecure
+	if *caCert != "" {
+		httpSettings.CACerts = []string{*caCert}
+	}
+
+	// Get the revision and paths from remaining arguments; -glob alone
+	// selects files of the whole working directory
+	args = flags.Args()
+	if len(args) == 0 && len(globs) > 0 {
+		args = []string{"."}
+	}
+	if len(args) == 0 {
+		return nil, Options{}, fmt.Errorf("Please specify a file to analyze.\nUsage: git-review-blame <file>")
+	}
+	revision, paths, err := parseBlameArgs(args)
+	if err != nil {
+		return nil, Options{}, fmt.Errorf("%w\nUsage: git-review-blame [<rev>] [--] <path>...", err)
+	}
+	if *incremental {
+		*format = "incremental"
+	}
+	if *csvOutput {
+		*format = "csv"
+	}
+	if *markdown {
+		*format = "markdown"
+	}
+	if *htmlFile != "" {
+		*format = "html"
+	}
+	if *groupBy != "" {
+		groupFormat, ok := GroupByModes[*groupBy]
+		if !ok {
+			return nil, Options{}, fmt.Errorf("unknown -group-by %q (expected pr)", *groupBy)
+		}
+		*format = groupFormat
+	}
+	if *tokenSource != "" {
+		if _, err := ParseTokenSource(*tokenSource); err != nil {
+			return nil, Options{}, err
+		}
+	}
+	if *notifyBase != "" && !*notify {
+		return nil, Options{}, fmt.Errorf("-notify-base requires -notify")
+	}
+	if *colorBy != "" {
+		if _, err := ParseColorBy(*colorBy); err != nil {
+			return nil, Options{}, err
+		}
+	}
+	if _, err := ParseApproverPolicy(*approverPick); err != nil {
+		return nil, Options{}, err
+	}
+	if *backend, err = ParseBackend(*backend); err != nil {
+		return nil, Options{}, err
+	}
+	if isTemplateFormat(*format) {
+		// Report template errors before any lookups
+		if _, err := NewTemplateFormatter(*format); err != nil {
+			return nil, Options{}, err
+		}
+	}
+	var csvColumns []string
+	if *csvCols != "" {
+		if csvColumns, err = ParseCSVColumns(*csvCols); err != nil {
+			return nil, Options{}, err
+		}
+	}
+
+	opts := Options{
+		LineRanges:         lineRanges,
+		Revision:           revision,
+		Symbol:             *symbol,
+		Globs:              globs,
+		Filter:             LineFilter{Approvers: approvers, Authors: authors, Labels: labels},
+		Porcelain:          *porcelain,
+		DetectMoves:        *detectMoves,
+		CopyDetection:      copyDetection(*copyOnce, *copyTwice, *copyThrice),
+		IgnoreWhitespace:   *ignoreWhitespace,
+		IgnoreRevsFiles:    ignoreRevsFiles,
+		Contents:           *contents,
+		Backend:            *backend,
+		Format:             *format,
+		Stream:             *stream,
+		Progress:           *progress,
+		CSVColumns:         csvColumns,
+		OutputFile:         *htmlFile,
+		ShowEmail:          *showEmail,
+		NoApproverEmails:   *noApprMails,
+		ShowIssues:         *showIssues,
+		ShowLabels:         *showLabels,
+		Badge:              *badge,
+		PublishCheck:       *publishCheck,
+		PostDiscussions:    *postDiscuss,
+		Notify:             *notify,
+		NotifyBase:         *notifyBase,
+		ConfigPath:         *configPath,
+		PolicyFile:         *policyFile,
+		RequireApproval:    *requireAppr,
+		FailOnError:        *failOnError,
+		ForbidSelfApproval: *forbidSelf,
+		Threads:            *threads,
+		ApprovalRules:      *rules,
+		SearchRemotes:      *searchRemote,
+		CheckPolicy:        *checkPolicy,
+		ShowObjections:     *objections,
+		Rounds:             *rounds,
+		Offline:            *offline,
+		TrailersOnly:       *trailersOnly,
+		AllApprovers:       *allApprovers || *approverPick == ApproverPolicyAll,
+		ApproverPolicy:     *approverPick,
+		ShowTeam:           *showTeam,
+		GroupHunks:         *groupHunks,
+		ColorBy:            *colorBy,
+		NoColor:            *noColor,
+		NoHyperlinks:       *noLinks,
+		Owners:             *owners,
+		Backports:          *backports,
+		Anonymize:          *anonymize,
+		IncludeVendored:    *inclVendored,
+		Provider:           *provider,
+		Remote:             *remote,
+		Token:              *token,
+		TokenSource:        *tokenSource,
+	}
+	return paths, opts, nil
+}
+
+// reportWarnings warns on stderr when rate limits, transient errors or
+// failed lookups degraded the output
+func reportWarnings() {
+	if warning := RateLimitWarning(); warning != "" {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	if warning := TransientErrorWarning(); warning != "" {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	for i, warning := range LookupWarnings() {
+		if i == 0 {
+			warning = "warning: " + warning
+		}
+		fmt.Fprintln(os.Stderr, warning)
+	}
+}
+
+// exitWithError reports err, as a JSON object with -json-errors, and exits
+// with the exit code of its kind, e.g. the conventional status 130 when the
+// run was interrupted
+func exitWithError(ctx context.Context, err error) {
+	code, exitCode := classifyError(ctx, err)
+	switch {
+	case jsonErrors:
+		writeErrorJSON(os.Stderr, code, exitCode, err)
+	case code == ErrorCodeInterrupted:
+		fmt.Fprintln(os.Stderr, "Interrupted")
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(exitCode)
+}
+
+func showHelp() {
+	fmt.Printf(`git-review-blame - Show GitHub/GitLab PR/MR approvers for each line instead of commit authors
+
+Usage:
+  git-review-blame [blame] [<options>] [<rev-opts>] [<rev>] [--] <path>...
+  git-review-blame report [-o review.html|review.md|review.csv] [<options>] [<rev>] [--] <path>...
+  git-review-blame digest [-since 7d] [-format markdown|json] [-no-save] [<path>]
+  git-review-blame snapshot [-o review-audit.json.gz] [-incremental-update <previous>] [-threads] [-rounds]
+                            [-owners] [-backports] [<path>...]
+  git-review-blame verify [<snapshot>]
+  git-review-blame doctor [<path>]
+  git-review-blame team-coverage -team <name|org/team|group> [-format text|json] [<path>]
+  git-review-blame stats [-by total|file|dir] [-top 10] [-format text|json] [-offline] [-show-team] [<path>]
+  git-review-blame report coverage [-top 10] [-jobs N] [-format text|json] [<path>]
+  git-review-blame history -L <start>,<end> [-format text|json] [-offline] [<rev>] [--] <file>
+  git-review-blame diff [-format text|json] [-offline] [<rev> | <rev1>..<rev2> | -base <rev> < patch] [-- <path>...]
+  git-review-blame drift [-format text|json] [-offline] <rev> | <rev1>..<rev2> | <rev1>...<rev2> [--] <path>...
+  git-review-blame serve [-addr 127.0.0.1:7465] [-root <dir>] [-allow-host <name>] [-cache-ttl 10m] [-offline]
+                         [-webhook-addr <host:port>] [-cache-path <file>]
+  git-review-blame lsp [-cache-ttl 10m] [-offline]
+  git-review-blame hook [-ref <pattern>] [-offline] [<ref> <old> <new>]
+  git-review-blame auth login|logout|status [-host github.com]
+  git-review-blame cache stats|clear [-path <file>] [-repo <host>/<owner>/<name>]
+  git-review-blame version
+  git-review-blame help
+
+Without a command, blame runs; annotate a file named like a command with
+git-review-blame blame -- <file>.
+
+Options:
+  -L <start>,<end>    Show only lines in given range; repeat for several ranges,
+                      or use -L :<funcname> for a function
+  -M                  Attribute lines moved within the file to their original PR/MR
+  -C, -CC, -CCC       Also follow lines moved or copied from other files (as in git blame)
+  -w                  Ignore whitespace changes when attributing lines
+  -contents <file>    Annotate the file's contents from <file>, or from stdin with -, as git blame --contents
+                      does; lines changed since the annotated revision are marked as not committed yet
+  -backend <name>     Blame with exec (the git CLI, default), incremental, which looks up the approvals of the first
+                      hunks while git blame --incremental computes the rest of a large file, or go-git, which
+                      works without git installed but annotates the last commit rather than the working tree,
+                      without -M, -C, -w, -contents or -ignore-revs-file
+  -ignore-revs-file <file>
+                      Skip the commits listed in the file, besides those of blame.ignoreRevsFile;
+                      may be repeated
+  -glob <pattern>     Annotate only files matching the pattern ('**/*.go', 'src/*.ts'); repeat for several
+                      patterns. Several paths, directories and -glob print every selected file
+  -symbol <name>      Show only the lines of a Go function, method (Type.Method) or type
+  -approver <login>   Print only the lines approved by the login, name or email; repeat for several people
+  -author <name>      Print only the lines whose commit author has the name or email; repeat for several people
+  -label <name>       Print only the lines whose PR/MR has the label; repeat for several labels
+  -porcelain          Show in a format designed for machine consumption  
+  -incremental        Show in the git blame --incremental format read by tig blame and git gui blame
+  -csv                Write one CSV row per line (same as -format csv)
+  -csv-columns <list> Comma-separated CSV columns: file, line, commit, author, author_email, author_date,
+                      repository, pr, pr_title, pr_labels, approver, approver_email, approvers, approval_date,
+                      review_state, content, original_line, original_file
+                      (default: file,line,commit,author,pr,approvers,approval_date)
+  -markdown           Write a Markdown table per file with a row per hunk: line range, commit, PR/MR link,
+                      approvers and approval date (same as -format markdown)
+  -html <file>        Write an HTML report to the file: each line with links to its PR/MR and the approving
+                      reviews, approver avatars, and highlighted code (same as -format html, written to a file)
+  -format <name>      Output format: human, porcelain, incremental, annotations, csv, markdown, html, dot (Graphviz graph of a
+                      file or directory), pr-summary (same as -group-by pr), or a registered custom format
+  -group-by pr        Summarize the lines by PR/MR: its number and title, approvers, and the line ranges that
+                      still originate from it
+  -format '<template>'
+                      Write each line with a Go text/template over its fields, e.g.
+                      '{{.ShortHash}} {{.PRNumber}} {{.Approver}} {{.Content}}'
+  -stream             Print lines as soon as their approvals are resolved, a few hunks at a time, instead of
+                      after the whole file (human, porcelain, incremental and annotations formats)
+  -progress           Show a spinner counting the resolved lines on stderr while approvals are looked up
+  -show-email         Show author email instead of author name
+  -no-approver-emails With -show-email, do not look up each GitHub approver's email from their user profile
+                      (one request per approver; private emails show as the noreply address)
+  -show-issues        Show the issues closed by each line's PR/MR (Fixes #N, Closes #N)
+  -show-labels        Show the labels of each line's PR/MR (hashtags on Gerrit)
+  -all-approvers      Show every approver of each line's PR/MR, comma-separated, instead of the last one
+  -show-team          Show each approver's team instead of the approver: the smallest configured team
+                      they are in, or team of the organization (GitHub) or subgroup (GitLab)
+  -approver-policy <policy>
+                      Approval shown as each line's approver: first, last (default), last-before-merge
+                      (ignoring approvals after the merge), all, or codeowner-preferred (the last
+                      approval by a CODEOWNERS owner of the file)
+  -group-hunks        Show the commit, approver and date only on the first line of each run of consecutive
+                      lines from the same commit
+  -color-by <mode>    Color each line by approver, pr or age and dim unreviewed lines (default: approver
+                      when writing to a terminal)
+  -no-color           Never color the output (also set by the NO_COLOR environment variable)
+  -no-hyperlinks      Do not link commits to their PR/MR and approvers to their profile in the terminal
+  -badge              Render an SVG review-coverage badge for a file or directory
+  -publish-check      Publish unreviewed lines as a GitHub check run (CI mode)
+  -post-discussions   Post unreviewed lines as GitLab merge request discussions (CI mode)
+  -notify             Post a coverage summary to the webhooks configured in the config file
+  -notify-base <rev>  With -notify, also count the unreviewed lines from commits not reachable from <rev>
+  -config <path>      Config file (default: .git-review-blame.json in the repository root)
+  -policy <file>      Rego policy deciding which lines are compliant (evaluated with opa)
+  -require-approval   Fail and list the lines whose commit has no PR/MR or no approvals (CI gate)
+  -fail-on-error      Fail when any API lookup failed; failures are always listed on stderr (CI gate)
+  -forbid-self-approval
+                      Fail and list the lines approved only by their commit or PR/MR author (CI gate)
+  -threads            Report PRs/MRs merged with unresolved review threads
+  -approval-rules     Check each MR against its approval rules, required approvals, eligible approvers and
+                      Code Owner rules, and report approved MRs that did not meet them (GitLab)
+  -show-objections    List the reviewers who requested changes to each PR/MR, or whose review was
+                      dismissed, before it was merged
+  -check-policy       Check each PR against the required approvals, code owner reviews and stale review
+                      dismissal its base branch's protection requires now (GitHub)
+  -search-remotes     Look up commits without a PR/MR in the repositories of the other remotes on the
+                      same host, e.g. both the fork and upstream, or a mirror
+  -rounds             Count the review rounds (review, new commits, re-review) of each PR/MR
+  -owners             Check whether approvers are listed in the OWNERS files of each line's directory
+  -backports          Detect backport PRs/MRs and show the original mainline PR/MR and its approver
+  -offline            Find PR/MR numbers from local commit messages only, without API access
+  -trailers-only      Take approvers from Reviewed-by/Acked-by commit trailers only, without API access
+  -include-vendored   Include vendored files (linguist-vendored, vendor/, node_modules/, ...) in directories
+  -anonymize          Replace names, logins and emails with stable pseudonyms in all output
+  -token <token>      GitHub or GitLab API token, instead of GITHUB_TOKEN or GITLAB_TOKEN
+  -token-source <src> Take the token only from env, keyring (auth login), cli (gh auth token or glab config)
+                      or git-credential (git credential fill); by default -token and each source are tried in turn
+  -provider <name>    Hosting service of the remote: github, gitlab, bitbucket, gitea, forgejo or gerrit
+                      (default: detected from the host, see hosts in the config file)
+  -remote <name>      Remote whose repository holds the PRs/MRs, e.g. upstream for a fork (default: the
+                      remote the branch tracks if not origin, else upstream if it exists, else origin)
+  -debug              Log API requests and the run ID (sent as X-Request-ID) to stderr
+  -json-errors        Report a failure on stderr as a JSON object with an error code instead of a message
+  -no-http-cache      Send every API request in full instead of revalidating responses cached by earlier runs
+  -cache sqlite       Keep merged PRs/MRs and their approvers in a SQLite database shared by all repositories,
+                      so later runs and serve look them up without API requests (see cache stats)
+  -cache-path <file>  Database file of -cache (default: git-review-blame/cache.db in the user cache directory)
+  -retries <n>        Retry API requests failing with 500/502/503/504, reset connections or timeouts n times
+                      (default 3; 0 disables)
+  -retry-backoff <d>  Longest wait before the first retry, doubling with each retry up to 30s (default 1s)
+  -timeout <d>        Time limit of each API request, e.g. 2m (default 30s)
+  -proxy <url>        Proxy for API requests (default: HTTPS_PROXY, HTTP_PROXY and NO_PROXY)
+  -ca-cert <file>     PEM file of certificate authorities to trust besides the system's, e.g. an internal CA
+  -insecure-skip-verify
+                      Accept any TLS certificate of API hosts; prefer -ca-cert
+  -help               Show this help message
+
+Environment Variables:
+  GITHUB_TOKEN - GitHub personal access token (or log in with gh auth login, or use a git credential helper)
+  GITLAB_TOKEN - GitLab personal access token (or log in with glab auth login, or use a git credential helper)
+  BITBUCKET_TOKEN - Bitbucket Cloud access token (required for Bitbucket repositories)
+  GITEA_TOKEN - Gitea or Forgejo access token (required for Gitea, Forgejo and Codeberg repositories)
+  GERRIT_USER, GERRIT_TOKEN - Gerrit account and HTTP password (optional; changes are read anonymously otherwise)
+  GITHUB_APP_ID, GITHUB_APP_INSTALLATION_ID, GITHUB_APP_PRIVATE_KEY[_PATH]
+               - GitHub App credentials used by -publish-check (optional)
+  NO_COLOR - Disable colored output when set to any value
+  GIT_REVIEW_BLAME_NO_HTTP_CACHE - Disable the HTTP cache of API responses for all commands when set
+
+Examples:
+  git-review-blame src/main.go
+  git-review-blame -L 10,20 src/main.go  
+  git-review-blame -symbol Server.Handle src/server.go
+  git-review-blame -porcelain src/main.go
+  git-review-blame -format '{{.ShortHash}} #{{.PRNumber}} {{.Approver}} {{date .ApprovalTime}}' src/main.go
+  git-review-blame -stream -progress src/large_file.go
+  git-review-blame -csv src/main.go > review-evidence.csv
+  git-review-blame -markdown -L 10,40 src/main.go
+  git-review-blame -html review.html src/
+  git-review-blame report -o review.md -glob '**/*.go' src/
+  git-review-blame -glob '**/*.go' src/ cmd/
+  git-review-blame -approver alice src/main.go
+  git-review-blame -group-by pr src/main.go
+  git-review-blame -contents - src/main.go < edited.go
+  git-review-blame -backend incremental -stream src/large_file.go
+  git-review-blame -backend go-git src/main.go
+  git-review-blame -label security-review -show-labels src/auth/
+  git-review-blame -badge . > reviewed.svg
+  git-review-blame -format dot src/ | dot -Tsvg > ownership.svg
+  git-review-blame digest -since 7d src/
+  git-review-blame stats -by dir src/
+  git-review-blame report coverage -format json
+  git-review-blame history -L 10,40 src/main.go
+  git-review-blame diff main...feature
+  git-review-blame drift main...refactor src/server.go
+  git-review-blame serve -addr 127.0.0.1:7465
+  git-review-blame lsp -offline
+  git-review-blame hook -ref main < ref-updates
+  git-review-blame auth login -host gitlab.example.com
+
+Note: The tool automatically detects if the repository is GitHub or GitLab based on the
+remote URL and uses the appropriate token. In forks, the upstream remote is queried.
+`)
+}
+
+// stringsFlag collects the values of an option that may be repeated
+type stringsFlag []string
+
+// String implements flag.Value
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+// Set implements flag.Value
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Options holds the command-line options that control a run
+type Options struct {
+	// LineRanges are the -L ranges to annotate, all lines when empty
+	LineRanges []string
+	// Revision annotates the file as of a commit instead of the working tree
+	Revision  string
+	Porcelain bool
+	Format    string
+	ShowEmail bool
+	Badge     bool
+
+	// NoApproverEmails skips looking up approver emails for -show-email
+	NoApproverEmails bool
+
+	// Stream prints each window of lines as soon as it is enriched;
+	// Progress shows the resolved lines on stderr (see enrichLines)
+	Stream   bool
+	Progress bool
+
+	// CSVColumns are the columns of the csv format, the defaults when empty
+	CSVColumns []string
+	// OutputFile receives the formatted output instead of stdout when set
+	OutputFile string
+
+	// DetectMoves and CopyDetection are git blame's -M and -C options
+	DetectMoves   bool
+	CopyDetection int
+	// IgnoreWhitespace and IgnoreRevsFiles are git blame's -w and
+	// --ignore-revs-file options
+	IgnoreWhitespace bool
+	IgnoreRevsFiles  []string
+	// Contents is git blame's --contents option: the file, or "-" for
+	// stdin, annotated in place of the working tree file
+	Contents string
+	// Backend selects how files are blamed, BackendExec or BackendGoGit
+	Backend string
+
+	// Symbol restricts the run to the line range of a declaration
+	Symbol string
+
+	// Globs restrict the files of the annotated paths to those matching any
+	// of the patterns, relative to the repository root
+	Globs []string
+
+	// Filter restricts the printed lines to those of some approvers or
+	// authors; checks and reports still see every line
+	Filter LineFilter
+
+	// ShowIssues adds the issues closed by each line's PR/MR to the human format
+	ShowIssues bool
+
+	// ShowLabels adds the labels of each line's PR/MR to the human format
+	ShowLabels bool
+
+	// PublishCheck publishes unreviewed lines as a GitHub check run (CI mode)
+	PublishCheck bool
+
+	// PostDiscussions posts unreviewed lines as GitLab MR discussions (CI mode)
+	PostDiscussions bool
+
+	// Notify posts a run summary to the webhooks configured in the config file
+	Notify bool
+	// NotifyBase counts the new unreviewed lines of the summary against a
+	// revision, e.g. the target branch of a pull request
+	NotifyBase string
+
+	// ConfigPath overrides the default config file location
+	ConfigPath string
+
+	// PolicyFile overrides the Rego policy files of the config file
+	PolicyFile string
+
+	// RequireApproval fails the run when any line has no approved PR/MR
+	RequireApproval bool
+
+	// FailOnError fails the run when any API lookup failed
+	FailOnError bool
+
+	// ForbidSelfApproval fails the run when any line is self-approved
+	ForbidSelfApproval bool
+
+	// Threads fetches the review thread resolution status of each PR/MR
+	Threads bool
+
+	// ApprovalRules checks each PR/MR against its approval rules
+	ApprovalRules bool
+
+	// SearchRemotes looks up commits without a PR/MR in the repositories of
+	// the other remotes
+	SearchRemotes bool
+
+	// CheckPolicy checks each PR against the current branch protection of
+	// its base branch
+	CheckPolicy bool
+
+	// ShowObjections lists the reviewers who objected to each PR/MR
+	ShowObjections bool
+
+	// Rounds counts the review rounds of each PR/MR
+	Rounds bool
+
+	// Offline maps lines to PRs/MRs from local commit messages without any API access
+	Offline bool
+
+	// AllApprovers shows every approver of a line's PR/MR, not only the last
+	AllApprovers bool
+
+	// ApproverPolicy picks the approval shown as a line's approver, one of
+	// the ApproverPolicy values; empty means ApproverPolicyLast
+	ApproverPolicy string
+
+	// ShowTeam shows the team of each approver instead of the approver
+	ShowTeam bool
+
+	// GroupHunks shows the metadata of the human format once per hunk
+	GroupHunks bool
+
+	// ColorBy is the -color-by mode; NoColor turns colors off (see colorBy)
+	ColorBy string
+	NoColor bool
+
+	// NoHyperlinks turns off the terminal hyperlinks of human output
+	NoHyperlinks bool
+
+	// TrailersOnly takes approvers from Reviewed-by/Acked-by trailers only,
+	// for air-gapped environments and mailing-list workflows without PRs
+	TrailersOnly bool
+
+	// Owners checks approvers against per-directory OWNERS files
+	Owners bool
+
+	// Backports links backport PRs/MRs to their original mainline PR/MR
+	Backports bool
+
+	// Anonymize replaces people with stable pseudonyms
+	Anonymize bool
+
+	// IncludeVendored keeps vendored files when annotating a directory
+	IncludeVendored bool
+
+	// Provider overrides the hosting service detected from the remote
+	Provider string
+
+	// Remote selects the remote whose repository is queried, overriding the
+	// config file and DetectRemote
+	Remote string
+
+	// Token is the API token passed with -token; TokenSource restricts token
+	// discovery to one source (see TokenResolver)
+	Token       string
+	TokenSource string
+}
+
+// blameOptions returns the options passed through to git blame
+func (o Options) blameOptions() BlameOptions {
+	return BlameOptions{
+		LineRanges:       o.LineRanges,
+		Porcelain:        o.Porcelain,
+		DetectMoves:      o.DetectMoves,
+		CopyDetection:    o.CopyDetection,
+		IgnoreWhitespace: o.IgnoreWhitespace,
+		IgnoreRevsFiles:  o.IgnoreRevsFiles,
+		Contents:         o.Contents,
+		Backend:          o.Backend,
+	}
+}
+
+// copyDetection returns the number of -C options given as -C, -CC or -CCC
+func copyDetection(once, twice, thrice bool) int {
+	switch {
+	case thrice:
+		return 3
+	case twice:
+		return 2
+	case once:
+		return 1
+	}
+	return 0
+}
+
+// formatOptions returns the display options of the formatters
+func (o Options) formatOptions(repoInfo *RepoInfo, config *Config) FormatOptions {
+	return FormatOptions{
+		ShowEmail:    o.ShowEmail,
+		ShowIssues:   o.ShowIssues,
+		ShowLabels:   o.ShowLabels,
+		AllApprovers: o.AllApprovers,
+		GroupHunks:   o.GroupHunks,
+		ColorBy:      o.colorBy(),
+		Hyperlinks:   o.hyperlinks(),
+		Theme:        config.Colors,
+		Columns:      o.CSVColumns,
+		Repo:         repoInfo,
+	}
+}
+
+// colorBy returns the color mode of human output: none with -no-color or
+// NO_COLOR set, the -color-by mode when given, and otherwise approver when
+// the output goes to a terminal
+func (o Options) colorBy() string {
+	if o.NoColor || os.Getenv("NO_COLOR") != "" {
+		return ""
+	}
+	if o.ColorBy != "" {
+		return o.ColorBy
+	}
+	if o.OutputFile == "" && isTerminal(os.Stdout) {
+		return ColorByApprover
+	}
+	return ""
+}
+
+// hyperlinks reports whether human output gets OSC 8 hyperlinks: when it
+// goes to a terminal other than TERM=dumb, unless -no-hyperlinks is given
+func (o Options) hyperlinks() bool {
+	return !o.NoHyperlinks && o.OutputFile == "" && os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout)
+}
+
+// formatName returns the output format selected by -format or -porcelain
+func (o Options) formatName() string {
+	if o.Format != "" {
+		return o.Format
+	}
+	if o.Porcelain {
+		return "porcelain"
+	}
+	return "human"
+}
+
+// annotatesPath reports whether the run works on every tracked file under a
+// path instead of printing blame output for a single file
+func (o Options) annotatesPath() bool {
+	return o.Badge || o.PublishCheck || o.PostDiscussions || o.Notify || o.formatName() == "dot"
+}
+
+// parseBlameArgs splits the positional arguments of git blame, "[<rev>] [--]
+// <path>...", into the revision (empty for the working tree) and the paths.
+// Without "--", a first argument that is not an existing path is the revision.
+func parseBlameArgs(args []string) (revision string, paths []string, err error) {
+	for i, arg := range args {
+		if arg == "--" {
+			if i > 1 || i == len(args)-1 {
+				return "", nil, fmt.Errorf("expected [<rev>] -- <path>...")
+			}
+			if i == 1 {
+				revision = args[0]
+			}
+			return revision, args[i+1:], nil
+		}
+	}
+
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("please specify a file to analyze")
+	}
+	if _, err := os.Stat(args[0]); len(args) > 1 && err != nil {
+		return args[0], args[1:], nil
+	}
+	return "", args, nil
+}
+
+// runGitReviewBlame executes the main logic of the application
+func runGitReviewBlame(ctx context.Context, paths []string, opts Options, githubToken, gitlabToken string) error {
+	// 1-2. Find git repository root and extract repository information
+	filePath := paths[0]
+	repoRoot, err := findRepositoryRoot(filePath)
+	if err != nil {
+		return err
+	}
+	repoRoot, repoInfo, config, err := openRepositoryAt(repoRoot, opts.ConfigPath, opts.Remote)
+	if err != nil {
+		return err
+	}
+	if err := applyProvider(repoInfo, opts.Provider); err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{Explicit: opts.Token, Source: opts.TokenSource}, githubToken, gitlabToken)
+
+	multipleFiles := len(paths) > 1 || len(opts.Globs) > 0 || isDirectoryPath(repoRoot, filePath, opts.Revision)
+	if opts.annotatesPath() || multipleFiles {
+		if opts.Symbol != "" {
+			return fmt.Errorf("-symbol annotates a single file and cannot be combined with directories, several paths, -glob, -badge, -publish-check, -post-discussions, -notify or -format dot")
+		}
+		if multipleFiles && len(opts.LineRanges) > 0 {
+			return fmt.Errorf("-L annotates a single file and cannot be combined with directories, several paths or -glob")
+		}
+		if multipleFiles && opts.Contents != "" {
+			return fmt.Errorf("-contents annotates a single file and cannot be combined with directories, several paths or -glob")
+		}
+		if opts.Stream || opts.Progress {
+			return fmt.Errorf("-stream and -progress annotate a single file and cannot be combined with directories, several paths, -glob, -badge, -publish-check, -post-discussions, -notify or -format dot")
+		}
+		return runPathMode(ctx, repoRoot, paths, repoInfo, config, opts, githubToken, gitlabToken)
+	}
+
+	// 3. Execute git blame on the file at the given revision, or on its last
+	// revision if it was deleted
+	revision := opts.Revision
+	var deleted *DeletedFile
+	if _, statErr := os.Stat(filePath); revision == "" && opts.Contents == "" && os.IsNotExist(statErr) {
+		deleted, err = FindDeletedFile(repoRoot, filePath)
+		if err != nil {
+			return err
+		}
+		revision = deleted.Revision
+	}
+
+	// Resolve -symbol to the line range of its declaration
+	var symbol *SymbolRange
+	if opts.Symbol != "" {
+		if len(opts.LineRanges) > 0 {
+			return fmt.Errorf("-symbol and -L cannot be combined")
+		}
+		if opts.Contents != "" {
+			return fmt.Errorf("-symbol and -contents cannot be combined")
+		}
+		content, err := readAnnotatedFile(repoRoot, filePath, revision)
+		if err != nil {
+			return err
+		}
+		symbol, err = FindSymbol(filePath, content, opts.Symbol)
+		if err != nil {
+			return err
+		}
+		opts.LineRanges = []string{symbol.LineRange()}
+	}
+
+	// The incremental backend keeps git running while the pipeline is set
+	// up and the first hunks are enriched
+	var blameLines []BlameLine
+	var stream *BlameStream
+	if opts.Backend == BackendIncremental {
+		stream, err = StartGitBlameStream(ctx, repoRoot, filePath, revision, opts.blameOptions())
+	} else {
+		blameLines, err = ExecuteGitBlameAt(ctx, repoRoot, filePath, revision, opts.blameOptions())
+	}
+	if err != nil {
+		return &UntrackedFileError{Path: filePath, Err: err}
+	}
+	if stream != nil {
+		defer stream.Close()
+	}
+
+	// 4. Create the enrichment pipeline for the repository type
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, opts, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+
+	notebook := isNotebook(filePath) && opts.formatName() == "human"
+	formatOptions := opts.formatOptions(repoInfo, config)
+	var streamFormatter Formatter
+	if opts.Stream {
+		if notebook || !streamFormats[opts.formatName()] && !isTemplateFormat(opts.formatName()) {
+			return fmt.Errorf("-stream prints the human, porcelain, incremental and annotations formats and templates; %s output needs every line first", opts.formatName())
+		}
+		if streamFormatter, err = LookupFormatter(opts.formatName()); err != nil {
+			return err
+		}
+	}
+	if deleted != nil {
+		// Keep machine-readable output parseable by writing the header to stderr
+		if opts.formatName() == "human" {
+			fmt.Print(deleted.Header())
+		} else {
+			fmt.Fprint(os.Stderr, deleted.Header())
+		}
+	}
+
+	// 5. Process each blame line to get PR approval info; -stream writes the
+	// output while the lines are enriched
+	enrich := func(write func([]BlameLineWithApproval) error) ([]BlameLineWithApproval, error) {
+		if stream != nil {
+			return enrichStream(ctx, pipeline, stream, opts, write)
+		}
+		return enrichLines(ctx, pipeline, blameLines, opts, write)
+	}
+	var linesWithApprovals []BlameLineWithApproval
+	if streamFormatter != nil {
+		err = writeOutput(opts.OutputFile, func(w io.Writer) error {
+			var err error
+			linesWithApprovals, err = enrich(func(window []BlameLineWithApproval) error {
+				if window = opts.Filter.Apply(window); len(window) == 0 {
+					return nil
+				}
+				return WriteFormatted(w, streamFormatter, window, formatOptions)
+			})
+			return err
+		})
+	} else {
+		linesWithApprovals, err = enrich(nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 6. Format and display the output; notebooks are summarized per cell
+	// because raw JSON line numbers mean nothing to their authors
+	var notebookSummary string
+	var formatter Formatter
+	if notebook {
+		content, err := readAnnotatedFile(repoRoot, filePath, revision)
+		if err != nil {
+			return err
+		}
+		cells, err := ParseNotebookCells(content)
+		if err != nil {
+			return fmt.Errorf("could not parse notebook: %w", err)
+		}
+		notebookSummary = FormatNotebookCells(SummarizeNotebookCells(cells, opts.Filter.Apply(linesWithApprovals)))
+	} else if !opts.Stream {
+		// -stream wrote the output while the lines were enriched
+		if formatter, err = LookupFormatter(opts.formatName()); err != nil {
+			return err
+		}
+	}
+	if formatter != nil {
+		// Built-in formats stream to stdout instead of building the output in memory
+		err := writeOutput(opts.OutputFile, func(w io.Writer) error {
+			return WriteFormatted(w, formatter, opts.Filter.Apply(linesWithApprovals), formatOptions)
+		})
+		if err != nil {
+			return fmt.Errorf("could not write output: %w", err)
+		}
+	} else {
+		fmt.Print(notebookSummary)
+	}
+	if symbol != nil {
+		summary := SummarizeSymbol(*symbol, linesWithApprovals).String()
+		if opts.formatName() == "human" {
+			fmt.Print(summary)
+		} else {
+			fmt.Fprint(os.Stderr, summary)
+		}
+	}
+	reportObjections(opts, linesWithApprovals)
+	reportUnresolvedThreads(linesWithApprovals)
+	reportUnmetApprovalRules(linesWithApprovals)
+
+	// 7. Check the annotated lines against the policy, if any, and
+	// -require-approval
+	violations, err := evaluatePolicy(config, opts, linesWithApprovals)
+	if err != nil {
+		return err
+	}
+	return reportFailures(opts, linesWithApprovals, violations)
+}
+
+// readAnnotatedFile reads the annotated file at revision, or from the
+// working tree when revision is empty
+func readAnnotatedFile(repoRoot, filePath, revision string) ([]byte, error) {
+	if revision == "" {
+		return os.ReadFile(filePath)
+	}
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(repoRoot, absFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFileAt(repoRoot, filepath.ToSlash(relPath), revision)
+}
+
+// evaluatePolicy evaluates the Rego policy from -policy or the config file.
+// It returns no violations when no policy is configured.
+func evaluatePolicy(config *Config, opts Options, lines []BlameLineWithApproval) ([]PolicyViolation, error) {
+	var policyConfig PolicyConfig
+	if config.Policy != nil {
+		policyConfig = *config.Policy
+	}
+	if opts.PolicyFile != "" {
+		policyConfig.Files = []string{opts.PolicyFile}
+	}
+	if len(policyConfig.Files) == 0 {
+		return nil, nil
+	}
+
+	violations, err := NewPolicyEvaluator(policyConfig).Evaluate(lines)
+	if err != nil {
+		return nil, fmt.Errorf("could not evaluate policy: %w", err)
+	}
+	return violations, nil
+}
+
+// reportViolations prints policy violations to stderr and fails the run if there are any
+func reportViolations(violations []PolicyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "policy violation: %s\n", v)
+	}
+	return fmt.Errorf("%d line(s) violate the review policy", len(violations))
+}
+
+// reportUnapprovedLines prints the ranges of lines without an approved
+// PR/MR to stderr and fails the run if there are any
+func reportUnapprovedLines(lines []BlameLineWithApproval) error {
+	ranges := FindUnreviewedRanges(lines)
+	if len(ranges) == 0 {
+		return nil
+	}
+	count := 0
+	for _, r := range ranges {
+		location := fmt.Sprintf("%s:%d", r.Filename, r.StartLine)
+		if r.EndLine != r.StartLine {
+			location += fmt.Sprintf("-%d", r.EndLine)
+		}
+		fmt.Fprintf(os.Stderr, "unapproved: %s: %s\n", location, r.Message())
+		count += r.EndLine - r.StartLine + 1
+	}
+	return fmt.Errorf("%d line(s) were not approved in a pull/merge request", count)
+}
+
+// reportFailures reports policy violations and, with -require-approval,
+// unapproved lines and lines approved without meeting their approval rules,
+// failing the run if there are any. With -fail-on-error, failed lookups
+// fail it too; they are listed by reportWarnings.
+func reportFailures(opts Options, lines []BlameLineWithApproval, violations []PolicyViolation) error {
+	errs := []error{reportViolations(violations)}
+	if count := LookupFailureCount(); opts.FailOnError && count > 0 {
+		cause := ErrLookupFailed
+		if RateLimitWarning() != "" {
+			cause = ErrRateLimited
+		}
+		errs = append(errs, fmt.Errorf("%d API lookup(s) failed: %w", count, cause))
+	}
+	if opts.RequireApproval {
+		errs = append(errs, reportUnapprovedLines(lines), rulesUnmetError(lines))
+	}
+	if opts.ForbidSelfApproval {
+		errs = append(errs, reportSelfApprovedLines(lines))
+	}
+	return errors.Join(errs...)
+}
+
+// reportSelfApprovedLines lists the self-approved lines on stderr and fails
+// when there are any
+func reportSelfApprovedLines(lines []BlameLineWithApproval) error {
+	ranges := FindSelfApprovedRanges(lines)
+	if len(ranges) == 0 {
+		return nil
+	}
+	count := 0
+	for _, r := range ranges {
+		location := fmt.Sprintf("%s:%d", r.Filename, r.StartLine)
+		if r.EndLine != r.StartLine {
+			location += fmt.Sprintf("-%d", r.EndLine)
+		}
+		fmt.Fprintf(os.Stderr, "self-approved: %s: %s\n", location, r.Message())
+		count += r.EndLine - r.StartLine + 1
+	}
+	return fmt.Errorf("%d line(s) were approved only by their own author", count)
+}
+
+// reportObjections lists the reviewers who objected to the PRs/MRs of the
+// lines, after human output or on stderr for other formats
+func reportObjections(opts Options, lines []BlameLineWithApproval) {
+	summaries := ObjectionSummaries(lines)
+	if len(summaries) == 0 {
+		return
+	}
+	out := io.Writer(os.Stderr)
+	if opts.formatName() == "human" {
+		out = os.Stdout
+	}
+	fmt.Fprintln(out, "\nObjections before merge:")
+	for _, summary := range summaries {
+		fmt.Fprintf(out, "  %s\n", summary)
+	}
+}
+
+// reportUnresolvedThreads warns on stderr about PRs/MRs merged with unresolved review threads
+func reportUnresolvedThreads(lines []BlameLineWithApproval) {
+	for _, warning := range UnresolvedThreadWarnings(lines) {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
+
+// reportUnmetApprovalRules warns on stderr about PRs/MRs approved without
+// meeting their approval rules
+func reportUnmetApprovalRules(lines []BlameLineWithApproval) {
+	for _, warning := range UnmetApprovalRuleWarnings(lines) {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
+
+// rulesUnmetError fails the run when lines were approved without meeting
+// their approval rules, which reportUnmetApprovalRules already listed
+func rulesUnmetError(lines []BlameLineWithApproval) error {
+	count := 0
+	for _, line := range lines {
+		if line.ReviewState() == ReviewStateRulesUnmet {
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d line(s) were approved without meeting their approval rules", count)
+}
+
+// OpenRepository finds the git repository containing path, loads its
+// config file (configPath, else .git-review-blame.json at its root) and
+// detects its hosting service as openRepositoryAt does. It returns the
+// repository root, the repository and the config.
+func OpenRepository(path, configPath, remote string) (string, *RepoInfo, *Config, error) {
+	repoRoot, err := findRepositoryRoot(path)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return openRepositoryAt(repoRoot, configPath, remote)
+}
+
+// findRepositoryRoot finds the root of the git repository containing path
+func findRepositoryRoot(path string) (string, error) {
+	repoRoot, err := FindGitRoot(path)
+	if err != nil {
+		return "", fmt.Errorf("this directory is not part of a Git repository. Please run this command from within a Git repository: %w", err)
+	}
+	return repoRoot, nil
+}
+
+// openRepositoryAt opens the repository at repoRoot, which may also be the
+// directory of a bare repository, as OpenRepository does. The repository is
+// detected from remote, else from the remote of the config file, else from
+// the remote DetectRemote chooses.
+func openRepositoryAt(repoRoot, configPath, remote string) (string, *RepoInfo, *Config, error) {
+	config, err := LoadConfig(repoRoot, configPath)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("could not load config file: %w", err)
+	}
+	if err := configureHTTP(config.HTTP); err != nil {
+		return "", nil, nil, err
+	}
+	if remote == "" {
+		remote = config.Remote
+	}
+
+	repoInfo, err := DetectRepoInfo(repoRoot, remote)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("could not determine if this is a GitHub or GitLab repository. Please ensure you have a valid remote configured: %w", err)
+	}
+	if !applyHostConfig(repoInfo, config.Hosts) && usesGerrit(repoRoot, repoInfo) {
+		repoInfo.Type = RepositoryTypeGerrit
+	}
+
+	return repoRoot, repoInfo, config, nil
+}
+
+// createReviewClient creates the review client for the detected repository type
+func createReviewClient(repoRoot string, repoInfo *RepoInfo, githubToken, gitlabToken string) (ReviewClient, error) {
+	factory := NewClientFactory()
+	factory.repoRoot = repoRoot
+	client, err := factory.CreateClient(repoInfo, githubToken, gitlabToken)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required: %w", err)
+	}
+	return client, nil
+}
+
+// newEnrichmentPipeline creates the enrichment pipeline for the selected
+// options. Without an API token it falls back to offline PR detection from
+// commit messages, so PR numbers and trailer reviewers are still shown.
+func newEnrichmentPipeline(repoRoot string, repoInfo *RepoInfo, config *Config, opts Options, githubToken, gitlabToken string) (*EnrichmentPipeline, error) {
+	if opts.TrailersOnly {
+		pipeline := NewEnrichmentPipeline(NewTrailerApprovalEnricher(repoRoot))
+		if err := useIdentities(pipeline, repoRoot, config, opts); err != nil {
+			return nil, err
+		}
+		return pipeline, nil
+	}
+	if opts.Offline {
+		return newOfflinePipeline(repoRoot, config, opts)
+	}
+
+	client, err := createReviewClient(repoRoot, repoInfo, githubToken, gitlabToken)
+	if errors.Is(err, ErrMissingGitHubToken) || errors.Is(err, ErrMissingGitLabToken) || errors.Is(err, ErrMissingBitbucketToken) || errors.Is(err, ErrMissingGiteaToken) {
+		fmt.Fprintf(os.Stderr, "warning: no API token for %s; showing PR numbers and Reviewed-by/Acked-by reviewers from commit messages only\n", repoInfo.Type)
+		return newOfflinePipeline(repoRoot, config, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := openCache(config.Cache)
+	if err != nil {
+		return nil, err
+	}
+	lookup := NewPRLookupEnricher(client, repoInfo)
+	lookup.repoRoot = repoRoot
+	lookup.store = store
+	approvals := NewApprovalEnricher(client, repoInfo)
+	approvals.store = store
+	pipeline := NewEnrichmentPipeline(NewPreflightEnricher(client, repoInfo), lookup, approvals)
+	switch opts.ApproverPolicy {
+	case "", ApproverPolicyLast, ApproverPolicyAll:
+	default:
+		pipeline.Use(NewApproverPolicyEnricher(client, repoRoot, opts.ApproverPolicy))
+	}
+	if opts.ApprovalRules {
+		// Rules are checked against the approvals of the API only
+		pipeline.Use(NewApprovalRuleEnricher(client, repoInfo))
+	}
+	if opts.CheckPolicy {
+		pipeline.Use(NewBranchProtectionEnricher(client, repoInfo, repoRoot))
+	}
+	if opts.ShowObjections {
+		pipeline.Use(NewObjectionEnricher(client, repoInfo))
+	}
+	// Fill in approvers from trailers where the API had none or failed
+	pipeline.Use(NewTrailerApprovalEnricher(repoRoot))
+	if len(config.Migrations) > 0 {
+		if err := pipeline.InsertBefore("pr-lookup", NewMigrationEnricher(repoRoot, config.Migrations)); err != nil {
+			return nil, err
+		}
+	}
+	if opts.SearchRemotes {
+		if repositories := remoteRepositories(repoRoot, repoInfo); len(repositories) > 0 {
+			if err := pipeline.InsertBefore("approvals", NewRemoteSearchEnricher(client, repoInfo, repositories)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(config.Trackers) > 0 {
+		tracker, err := NewTrackerEnricher(config.Trackers)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Use(tracker)
+	}
+	if opts.Threads {
+		pipeline.Use(NewThreadEnricher(client, repoInfo))
+	}
+	if opts.Rounds {
+		pipeline.Use(NewReviewRoundEnricher(client, repoInfo))
+	}
+	if opts.Owners {
+		pipeline.Use(NewOwnersEnricher(repoRoot))
+	}
+	if opts.Backports {
+		pipeline.Use(NewBackportEnricher(client, repoInfo, repoRoot))
+	}
+	if opts.ShowEmail && !opts.NoApproverEmails {
+		pipeline.Use(NewApproverEmailEnricher(client, repoInfo))
+	}
+	if opts.ShowTeam {
+		pipeline.Use(NewApproverTeamEnricher(config.Teams, repoInfo, githubToken, gitlabToken))
+	}
+	if err := useIdentities(pipeline, repoRoot, config, opts); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// useIdentities appends the .mailmap stage when the repository has a
+// .mailmap, the identity mapping stage when identities are configured and
+// the anonymization stage for -anonymize; they must be the last stages
+func useIdentities(pipeline *EnrichmentPipeline, repoRoot string, config *Config, opts Options) error {
+	if hasMailmap(repoRoot) {
+		pipeline.Use(NewMailmapEnricher(repoRoot))
+	}
+	if len(config.Identities) > 0 {
+		identities, err := NewIdentityEnricher(config.Identities)
+		if err != nil {
+			return err
+		}
+		pipeline.Use(identities)
+	}
+	if opts.Anonymize {
+		pipeline.Use(NewAnonymizeEnricher())
+	}
+	return nil
+}
+
+// newOfflinePipeline creates a pipeline that needs no API access
+func newOfflinePipeline(repoRoot string, config *Config, opts Options) (*EnrichmentPipeline, error) {
+	lookup := NewOfflinePRLookupEnricher(repoRoot)
+	lookup.revision = opts.Revision
+	pipeline := NewEnrichmentPipeline(lookup, NewTrailerApprovalEnricher(repoRoot))
+	if len(config.Migrations) > 0 {
+		if err := pipeline.InsertBefore("offline-pr-lookup", NewMigrationEnricher(repoRoot, config.Migrations)); err != nil {
+			return nil, err
+		}
+	}
+	if err := useIdentities(pipeline, repoRoot, config, opts); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// runPathMode annotates every tracked file under path and publishes or renders
+// the aggregated results
+func runPathMode(ctx context.Context, repoRoot string, paths []string, repoInfo *RepoInfo, config *Config, opts Options, githubToken, gitlabToken string) error {
+	// Check the notification base before the lookups rather than after them
+	if opts.NotifyBase != "" {
+		if _, err := ResolveRevision(repoRoot, opts.NotifyBase); err != nil {
+			return fmt.Errorf("-notify-base: %w", err)
+		}
+	}
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, opts, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+
+	blame := opts.blameOptions()
+	blame.LineRanges = nil
+	lines, err := annotatePaths(ctx, repoRoot, paths, opts.Revision, opts.Globs, blame, pipeline, opts.IncludeVendored)
+	if err != nil {
+		return err
+	}
+
+	// Without a path-only output, print the blame output of every file
+	if !opts.annotatesPath() {
+		formatter, err := LookupFormatter(opts.formatName())
+		if err != nil {
+			return err
+		}
+		formatOptions := opts.formatOptions(repoInfo, config)
+		err = writeOutput(opts.OutputFile, func(w io.Writer) error {
+			perFile := opts.formatName() == "human" || opts.formatName() == "pr-summary"
+			return writeFiles(w, formatter, opts.Filter.Apply(lines), formatOptions, perFile)
+		})
+		if err != nil {
+			return fmt.Errorf("could not write output: %w", err)
+		}
+	}
+	reportObjections(opts, lines)
+	reportUnresolvedThreads(lines)
+	reportUnmetApprovalRules(lines)
+
+	violations, err := evaluatePolicy(config, opts, lines)
+	if err != nil {
+		return err
+	}
+
+	if opts.PublishCheck {
+		url, err := PublishCheckRun(ctx, repoRoot, repoInfo, githubToken, lines, violations)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Published check run: %s\n", url)
+	}
+
+	if opts.PostDiscussions {
+		result, err := PublishMRDiscussions(ctx, repoInfo, gitlabToken, lines)
+		if err != nil {
+			return fmt.Errorf("could not post merge request discussions: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Merge request discussions: %d created, %d updated, %d unchanged\n",
+			result.Created, result.Updated, result.Unchanged)
+	}
+
+	if opts.Notify {
+		if len(config.Notifications) == 0 {
+			return fmt.Errorf("-notify requires at least one entry under \"notifications\" in the config file")
+		}
+		displayPaths := make([]string, len(paths))
+		for i, path := range paths {
+			displayPaths[i] = displayPath(repoRoot, path)
+		}
+		summary := BuildRunSummary(repoInfo, strings.Join(displayPaths, ", "), lines)
+		if opts.NotifyBase != "" {
+			summary.CountNewUnreviewed(repoRoot, opts.NotifyBase, lines)
+		}
+		if err := NewNotifier().SendAll(config.Notifications, summary); err != nil {
+			return fmt.Errorf("could not send notification: %w", err)
+		}
+	}
+
+	if opts.Badge {
+		fmt.Print(RenderCoverageBadge(ComputeReviewStats(lines)))
+	}
+
+	if opts.formatName() == "dot" {
+		fmt.Print(formatDot(opts.Filter.Apply(lines), FormatOptions{ShowEmail: opts.ShowEmail}))
+	}
+
+	return reportFailures(opts, lines, violations)
+}
+
+// displayPath returns path relative to the repository root for messages
+func displayPath(repoRoot, path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	relPath, err := filepath.Rel(repoRoot, absPath)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// isDirectoryAt reports whether path names a directory rather than a single
+// file, in the working tree or at revision; files are the files it matched
+func isDirectoryAt(path, revision string, files []string) bool {
+	if revision == "" {
+		info, err := os.Stat(path)
+		return err == nil && info.IsDir()
+	}
+	// The path may not exist in the working tree; a file lists only itself
+	absPath, err := filepath.Abs(path)
+	return err == nil && !(len(files) == 1 && files[0] == absPath)
+}
+
+// isDirectoryPath reports whether path names a directory, in the working
+// tree or at revision
+func isDirectoryPath(repoRoot, path, revision string) bool {
+	if revision == "" {
+		return isDirectoryAt(path, "", nil)
+	}
+	files, err := ListTrackedFilesAt(repoRoot, revision, []string{path})
+	return err == nil && isDirectoryAt(path, revision, files)
+}
+
+// writeOutput calls write with the file at path, created or truncated, or
+// with stdout when path is empty
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeFiles writes lines in the given format. Human output and the PR/MR
+// summary are written per file, each after a "==> file <==" header; the
+// other formats name the file on every line and are written as one stream.
+func writeFiles(w io.Writer, formatter Formatter, lines []BlameLineWithApproval, opts FormatOptions, perFile bool) error {
+	if !perFile {
+		return WriteFormatted(w, formatter, lines, opts)
+	}
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for end < len(lines) && lines[end].Filename == lines[start].Filename {
+			end++
+		}
+		separator := "\n"
+		if start == 0 {
+			separator = ""
+		}
+		if _, err := fmt.Fprintf(w, "%s==> %s <==\n", separator, lines[start].Filename); err != nil {
+			return err
+		}
+		if err := WriteFormatted(w, formatter, lines[start:end], opts); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// annotatePath blames every tracked file under path, as of revision or in
+// the working tree when revision is empty, and enriches the lines
+func annotatePath(ctx context.Context, repoRoot, path, revision string, blame BlameOptions, pipeline *EnrichmentPipeline, includeVendored bool) ([]BlameLineWithApproval, error) {
+	return annotatePaths(ctx, repoRoot, []string{path}, revision, nil, blame, pipeline, includeVendored)
+}
+
+// annotatePaths blames every tracked file under paths that matches any of
+// globs (all files when there are none), and enriches the lines. All files
+// are blamed before the lines are enriched in one pipeline run, so lookups
+// are cached and batched across files.
+func annotatePaths(ctx context.Context, repoRoot string, paths []string, revision string, globs []string, blame BlameOptions, pipeline *EnrichmentPipeline, includeVendored bool) ([]BlameLineWithApproval, error) {
+	files, err := listAnnotatedFiles(repoRoot, paths, revision, globs, includeVendored)
+	if err != nil {
+		return nil, err
+	}
+
+	blameLines, err := blameFiles(ctx, repoRoot, files, revision, blame)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.Run(ctx, blameLines)
+}
+
+// blameFiles runs git blame on files, blame.Jobs at a time, and returns
+// their lines in the order of files
+func blameFiles(ctx context.Context, repoRoot string, files []string, revision string, blame BlameOptions) ([]BlameLine, error) {
+	jobs := blame.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]BlameLine, len(files))
+	var firstErr error
+	var once sync.Once
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				lines, err := ExecuteGitBlameAt(ctx, repoRoot, files[i], revision, blame)
+				if err != nil {
+					// Report the first failure and stop the other blames,
+					// which would only fail with it
+					once.Do(func() {
+						firstErr = fmt.Errorf("could not analyze file history for %s: %w", files[i], err)
+						cancel()
+					})
+					continue
+				}
+				results[i] = lines
+			}
+		}()
+	}
+	for i := range files {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var blameLines []BlameLine
+	for _, lines := range results {
+		blameLines = append(blameLines, lines...)
+	}
+	return blameLines, nil
+}
+
+// listAnnotatedFiles returns the tracked files under paths, as of revision or
+// in the working tree when revision is empty, in order and without
+// duplicates, keeping only those matching any of globs when given
+func listAnnotatedFiles(repoRoot string, paths []string, revision string, globs []string, includeVendored bool) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		var pathFiles []string
+		var err error
+		if revision == "" {
+			pathFiles, err = ListTrackedFiles(repoRoot, path)
+		} else {
+			pathFiles, err = ListTrackedFilesAt(repoRoot, revision, []string{path})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not list tracked files: %w", err)
+		}
+
+		// Vendored third-party code is excluded from directories by default since
+		// it would dominate the unreviewed lines; an explicitly named file is kept
+		if isDirectoryAt(path, revision, pathFiles) && !includeVendored {
+			var excluded int
+			pathFiles, excluded, err = FilterVendoredFiles(repoRoot, pathFiles)
+			if err != nil {
+				return nil, err
+			}
+			debugf("excluded %d vendored file(s) under %s", excluded, path)
+			if len(pathFiles) == 0 {
+				return nil, fmt.Errorf("all tracked files under %s are vendored; use -include-vendored to annotate them", path)
+			}
+		}
+
+		for _, file := range pathFiles {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	if len(globs) > 0 {
+		var err error
+		if files, err = FilterGlobFiles(repoRoot, files, globs); err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no tracked files under %s match %s", strings.Join(paths, ", "), strings.Join(globs, ", "))
+		}
+	}
+	return files, nil
+}
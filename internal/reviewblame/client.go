@@ -0,0 +1,124 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// ReviewClient defines the interface for both GitHub and GitLab API clients
+type ReviewClient interface {
+	// FindPRByCommit finds the pull/merge request that introduced a specific commit
+	FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error)
+	
+	// GetPRApprovals gets all approvals for a specific pull/merge request
+	GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error)
+	
+	// GetPRApprovalInfo gets complete approval information for a commit
+	GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error)
+}
+
+// UnifiedPullRequest represents a PR/MR from either GitHub or GitLab
+type UnifiedPullRequest struct {
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	State    string `json:"state"`
+	User     User   `json:"user"`
+	MergedAt *time.Time `json:"merged_at"`
+	WebURL   string `json:"web_url"` // GitLab uses web_url
+}
+
+// User represents a user from either GitHub or GitLab
+type User struct {
+	Login string `json:"login"` // GitHub uses "login"
+	Name  string `json:"name"`  // GitLab uses "name" 
+	Email string `json:"email"`
+}
+
+// UnifiedReview represents a review/approval from either GitHub or GitLab
+type UnifiedReview struct {
+	User        User       `json:"user"`
+	State       string     `json:"state"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+}
+
+// UnifiedPRApprovalInfo contains unified information about PR/MR approvals
+type UnifiedPRApprovalInfo struct {
+	PR        UnifiedPullRequest
+	Approvers []UnifiedReview
+}
+
+// ClientFactory creates the appropriate client based on repository type
+type ClientFactory struct {
+	// bitbucketToken authenticates Bitbucket Cloud requests
+	bitbucketToken string
+	// giteaToken authenticates Gitea and Forgejo requests
+	giteaToken string
+	// repoRoot is the local clone, where Gerrit Change-Ids are read from
+	repoRoot string
+}
+
+// NewClientFactory creates a new client factory, reading the Bitbucket and
+// Gitea tokens from BITBUCKET_TOKEN and GITEA_TOKEN
+func NewClientFactory() *ClientFactory {
+	return &ClientFactory{
+		bitbucketToken: os.Getenv(BitbucketTokenEnv),
+		giteaToken:     os.Getenv(GiteaTokenEnv),
+	}
+}
+
+// CreateClient creates the appropriate client based on repository type and token availability
+func (cf *ClientFactory) CreateClient(repoInfo *RepoInfo, githubToken, gitlabToken string) (ReviewClient, error) {
+	switch repoInfo.Type {
+	case RepositoryTypeGitHub:
+		if githubToken == "" {
+			return nil, ErrMissingGitHubToken
+		}
+		return &GitHubClientAdapter{client: newGitHubClientForRepo(githubToken, repoInfo)}, nil
+	case RepositoryTypeGitLab:
+		if gitlabToken == "" {
+			return nil, ErrMissingGitLabToken
+		}
+		return newGitLabClientForRepo(gitlabToken, repoInfo), nil
+	case RepositoryTypeBitbucket:
+		if cf.bitbucketToken == "" {
+			return nil, ErrMissingBitbucketToken
+		}
+		return newBitbucketClientForRepo(cf.bitbucketToken, repoInfo), nil
+	case RepositoryTypeGitea:
+		if cf.giteaToken == "" {
+			return nil, ErrMissingGiteaToken
+		}
+		return newGiteaClientForRepo(cf.giteaToken, repoInfo), nil
+	case RepositoryTypeGerrit:
+		// Gerrit changes can be read anonymously; GERRIT_USER and
+		// GERRIT_TOKEN are only needed for private projects
+		return newGerritClientForRepo(cf.repoRoot, repoInfo), nil
+	case RepositoryTypePlugin:
+		if repoInfo.Plugin == "" {
+			return nil, ErrMissingPlugin
+		}
+		return newPluginClientForRepo(cf.repoRoot, repoInfo), nil
+	default:
+		return nil, ErrUnsupportedRepositoryType
+	}
+}
+
+// Custom errors
+var (
+	ErrMissingGitHubToken        = &ClientError{Message: "GitHub authentication required. Please set the GITHUB_TOKEN environment variable with your personal access token, pass one with -token, or log in with gh auth login. You can create one at: https://github.com/settings/tokens"}
+	ErrMissingGitLabToken        = &ClientError{Message: "GitLab authentication required. Please set the GITLAB_TOKEN environment variable with your personal access token, pass one with -token, or log in with glab auth login. You can create one in your GitLab profile settings under 'Access Tokens'"}
+	ErrMissingBitbucketToken     = &ClientError{Message: "Bitbucket authentication required. Please set the BITBUCKET_TOKEN environment variable with a repository, project or workspace access token"}
+	ErrMissingGiteaToken         = &ClientError{Message: "Gitea authentication required. Please set the GITEA_TOKEN environment variable with an access token with read:repository scope, created under Settings > Applications"}
+	ErrMissingPlugin             = &ClientError{Message: "The plugin provider needs a provider plugin. Please name it in the hosts entry of the remote's host in the config file, e.g. {\"host\": \"review.example.com\", \"provider\": \"plugin\", \"plugin\": \"corp-review\"}"}
+	ErrUnsupportedRepositoryType = &ClientError{Message: "This repository type is not supported. Only GitHub, GitLab, Bitbucket Cloud, Gitea/Forgejo and Gerrit repositories and provider plugins are currently supported"}
+)
+
+// ClientError represents a client-related error
+type ClientError struct {
+	Message string
+}
+
+func (e *ClientError) Error() string {
+	return e.Message
+}
\ No newline at end of file
@@ -1,6 +1,7 @@
-package main
+package reviewblame
 
 import (
+	"context"
 	"testing"
 )
 
@@ -195,9 +196,9 @@ func TestReviewClientInterface(t *testing.T) {
 			}
 
 			// Test method signatures exist (will compile if interface is correct)
-			var _ func(string, string, string) (*PullRequest, error) = tc.client.FindPRByCommit
-			var _ func(string, string, int) ([]Review, error) = tc.client.GetPRApprovals  
-			var _ func(string, string, string) (*PRApprovalInfo, error) = tc.client.GetPRApprovalInfo
+			var _ func(context.Context, string, string, string) (*PullRequest, error) = tc.client.FindPRByCommit
+			var _ func(context.Context, string, string, int) ([]Review, error) = tc.client.GetPRApprovals  
+			var _ func(context.Context, string, string, string) (*PRApprovalInfo, error) = tc.client.GetPRApprovalInfo
 		})
 	}
 }
\ No newline at end of file
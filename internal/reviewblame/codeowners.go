@@ -0,0 +1,145 @@
+package reviewblame
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// codeOwnersPaths are the locations GitHub reads CODEOWNERS from, in order
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwnersRule is a line of a CODEOWNERS file
+type CodeOwnersRule struct {
+	Pattern string
+	// Owners are "@login", "@org/team-slug" or email addresses; empty
+	// when the pattern removes the owners of matching files
+	Owners []string
+}
+
+// ParseCodeOwners parses a CODEOWNERS file: a path pattern followed by its
+// owners on each line, "#" comments and blank lines
+func ParseCodeOwners(content string) []CodeOwnersRule {
+	var rules []CodeOwnersRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, CodeOwnersRule{Pattern: fields[0], Owners: fields[1:]})
+	}
+	return rules
+}
+
+// Matches reports whether the rule's pattern matches a repository path,
+// following gitignore rules: a pattern with a leading or inner slash is
+// anchored at the root, and a pattern matching a directory matches the
+// files below it
+func (r CodeOwnersRule) Matches(relPath string) bool {
+	pattern := strings.TrimSuffix(r.Pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return MatchGlob(pattern, relPath) || MatchGlob(pattern+"/**", relPath)
+}
+
+// codeOwnersFor returns the rule deciding the owners of relPath: the last
+// one matching it, or nil
+func codeOwnersFor(rules []CodeOwnersRule, relPath string) *CodeOwnersRule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].Matches(relPath) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// readCodeOwners reads the CODEOWNERS file of HEAD from the first location
+// GitHub looks in that has one
+func readCodeOwners(repoRoot string) []CodeOwnersRule {
+	for _, filePath := range codeOwnersPaths {
+		cmd := exec.Command("git", "show", "HEAD:"+filePath)
+		cmd.Dir = repoRoot
+		if output, err := cmd.Output(); err == nil {
+			return ParseCodeOwners(string(output))
+		}
+	}
+	return nil
+}
+
+// TeamMemberLister is implemented by review clients that can list the
+// members of an organization team
+type TeamMemberLister interface {
+	ListTeamMembers(ctx context.Context, org, slug string) ([]string, error)
+}
+
+// codeOwnersResolver finds the code owners of files from the CODEOWNERS
+// file of HEAD, expanding teams to their members
+type codeOwnersResolver struct {
+	repoRoot string
+	// rules is the parsed CODEOWNERS file, read on first use
+	rules  []CodeOwnersRule
+	loaded bool
+	// teams maps "org/slug" to its members (nil when the lookup failed)
+	teams map[string][]string
+}
+
+// newCodeOwnersResolver creates a resolver reading CODEOWNERS from repoRoot
+func newCodeOwnersResolver(repoRoot string) *codeOwnersResolver {
+	return &codeOwnersResolver{repoRoot: repoRoot, teams: make(map[string][]string)}
+}
+
+// Owners returns the rule deciding the owners of a file, nil when none
+// matches, and the logins of its owners with teams listed through lister.
+// The logins are empty when the rule has no owner with a login, or when
+// one of its teams could not be listed, since the owners are unknown then.
+// The error is only set when ctx is done.
+func (r *codeOwnersResolver) Owners(ctx context.Context, lister TeamMemberLister, filename string) (*CodeOwnersRule, []string, error) {
+	if !r.loaded {
+		r.rules = readCodeOwners(r.repoRoot)
+		r.loaded = true
+	}
+	rule := codeOwnersFor(r.rules, filename)
+	if rule == nil {
+		return nil, nil, nil
+	}
+
+	var logins []string
+	for _, owner := range rule.Owners {
+		owner, isLogin := strings.CutPrefix(owner, "@")
+		if !isLogin {
+			// Email addresses cannot be matched against review logins
+			continue
+		}
+		org, slug, isTeam := strings.Cut(owner, "/")
+		if !isTeam {
+			logins = append(logins, owner)
+			continue
+		}
+
+		members, exists := r.teams[owner]
+		if !exists {
+			if lister != nil {
+				var err error
+				members, err = lister.ListTeamMembers(ctx, org, slug)
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				if err != nil {
+					members = nil
+				} else if members == nil {
+					members = []string{}
+				}
+			}
+			r.teams[owner] = members
+		}
+		if members == nil {
+			return rule, nil, nil
+		}
+		logins = append(logins, members...)
+	}
+	return rule, logins, nil
+}
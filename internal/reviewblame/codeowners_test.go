@@ -0,0 +1,38 @@
+package reviewblame
+
+import "testing"
+
+func TestCodeOwnersFor(t *testing.T) {
+	rules := ParseCodeOwners(`# Default owners
+*           @alice
+*.sql       @dba
+/api/       @acme/api # API team
+docs/
+build/**/gen.go  ops@example.com
+`)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.go", "*"},
+		{"db/schema.sql", "*.sql"},
+		{"api/handler.go", "/api/"},
+		{"api/v1/queries.sql", "/api/"},
+		{"pkg/api/handler.go", "*"},
+		{"docs/readme.md", "docs/"},
+		{"site/docs/index.md", "docs/"},
+		{"build/x/y/gen.go", "build/**/gen.go"},
+		{"tools/build/gen.go", "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule := codeOwnersFor(rules, tt.path)
+			if rule == nil || rule.Pattern != tt.want {
+				t.Errorf("expected rule %q, got %+v", tt.want, rule)
+			}
+		})
+	}
+	if rule := codeOwnersFor(rules, "docs/readme.md"); len(rule.Owners) != 0 {
+		t.Errorf("expected docs/ to have no owners, got %q", rule.Owners)
+	}
+}
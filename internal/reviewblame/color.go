@@ -0,0 +1,146 @@
+package reviewblame
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes of -color-by
+const (
+	ColorByApprover = "approver"
+	ColorByPR       = "pr"
+	ColorByAge      = "age"
+)
+
+// colorModes are the modes -color-by accepts
+var colorModes = []string{ColorByApprover, ColorByPR, ColorByAge}
+
+// ParseColorBy checks a -color-by value
+func ParseColorBy(mode string) (string, error) {
+	for _, known := range colorModes {
+		if mode == known {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown color mode %q (expected %s)", mode, strings.Join(colorModes, ", "))
+}
+
+// ColorTheme holds the ANSI colors of the human format as SGR parameters,
+// e.g. "32" for green or "38;5;208" for orange in a 256-color terminal
+type ColorTheme struct {
+	// Palette colors approvers or PRs/MRs; each keeps its color in every run
+	Palette []string `json:"palette"`
+	// Unreviewed styles the lines without an approver
+	Unreviewed string `json:"unreviewed"`
+	// Age colors lines by the age of their approval, or of their commit when
+	// not approved, from the newest to the oldest (see ageThresholds)
+	Age []string `json:"age"`
+}
+
+// DefaultColorTheme is used for the colors the config file does not set
+var DefaultColorTheme = ColorTheme{
+	Palette:    []string{"32", "33", "34", "35", "36", "92", "93", "94", "95", "96"},
+	Unreviewed: "2",
+	Age:        []string{"91", "31", "33", "32", "36", "34", "90"},
+}
+
+// ageThresholds are the ages at which -color-by age moves to the next color
+// of ColorTheme.Age; older lines keep its last color
+var ageThresholds = []time.Duration{
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	90 * 24 * time.Hour,
+	180 * 24 * time.Hour,
+	365 * 24 * time.Hour,
+	2 * 365 * 24 * time.Hour,
+}
+
+// validate checks that every color is a list of SGR parameters
+func (t ColorTheme) validate() error {
+	colors := append(append([]string{t.Unreviewed}, t.Palette...), t.Age...)
+	for _, color := range colors {
+		if strings.Trim(color, "0123456789;") != "" {
+			return fmt.Errorf("colors: %q is not an SGR color such as \"32\" or \"38;5;208\"", color)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns the theme with DefaultColorTheme filling the unset colors
+func (t *ColorTheme) withDefaults() ColorTheme {
+	theme := DefaultColorTheme
+	if t == nil {
+		return theme
+	}
+	if len(t.Palette) > 0 {
+		theme.Palette = t.Palette
+	}
+	if t.Unreviewed != "" {
+		theme.Unreviewed = t.Unreviewed
+	}
+	if len(t.Age) > 0 {
+		theme.Age = t.Age
+	}
+	return theme
+}
+
+// lineColor returns the SGR parameters of a reviewed line in the given mode,
+// or "" when it stays uncolored
+func (t ColorTheme) lineColor(mode string, line BlameLineWithApproval, now time.Time) string {
+	switch mode {
+	case ColorByApprover:
+		return t.paletteColor(line.Approver)
+	case ColorByPR:
+		if line.PRNumber == 0 {
+			return ""
+		}
+		return t.paletteColor(fmt.Sprintf("%s#%d", line.Repository, line.PRNumber))
+	case ColorByAge:
+		when, ok := lineTime(line)
+		if !ok || len(t.Age) == 0 {
+			return ""
+		}
+		bucket := 0
+		for _, threshold := range ageThresholds {
+			if now.Sub(when) >= threshold {
+				bucket++
+			}
+		}
+		return t.Age[min(bucket, len(t.Age)-1)]
+	}
+	return ""
+}
+
+// paletteColor picks the palette color of key by its hash, so a person or
+// PR/MR has the same color in every run and in every window of -stream
+func (t ColorTheme) paletteColor(key string) string {
+	if len(t.Palette) == 0 {
+		return ""
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return t.Palette[hash.Sum32()%uint32(len(t.Palette))]
+}
+
+// lineTime returns the approval time of a line, or its commit time
+func lineTime(line BlameLineWithApproval) (time.Time, bool) {
+	if line.ApprovalTime != nil {
+		return *line.ApprovalTime, true
+	}
+	if timestamp, err := strconv.ParseInt(line.Date, 10, 64); err == nil {
+		return time.Unix(timestamp, 0), true
+	}
+	return time.Time{}, false
+}
+
+// appendColored appends text wrapped in the escape sequences of an SGR color
+func appendColored(buf []byte, color string, text []byte) []byte {
+	buf = append(buf, "\033["...)
+	buf = append(buf, color...)
+	buf = append(buf, 'm')
+	buf = append(buf, text...)
+	return append(buf, "\033[0m"...)
+}
@@ -0,0 +1,124 @@
+package reviewblame
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseColorBy(t *testing.T) {
+	for _, mode := range []string{"approver", "pr", "age"} {
+		if got, err := ParseColorBy(mode); err != nil || got != mode {
+			t.Errorf("ParseColorBy(%q) = %q, %v", mode, got, err)
+		}
+	}
+	if _, err := ParseColorBy("author"); err == nil || !strings.Contains(err.Error(), "approver, pr, age") {
+		t.Errorf("expected an error listing the modes, got %v", err)
+	}
+}
+
+func TestColorThemeLineColor(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	theme := ColorTheme{Palette: []string{"31", "32", "33"}, Age: []string{"91", "31", "90"}}
+	approved := func(approver string, age time.Duration) BlameLineWithApproval {
+		approvalTime := now.Add(-age)
+		return BlameLineWithApproval{Approver: approver, PRNumber: 7, ApprovalTime: &approvalTime}
+	}
+
+	alice := theme.lineColor(ColorByApprover, approved("alice", 0), now)
+	if alice == "" || alice != theme.lineColor(ColorByApprover, approved("alice", time.Hour), now) {
+		t.Errorf("expected an approver to keep one color, got %q", alice)
+	}
+	if got := theme.lineColor(ColorByPR, approved("bob", 0), now); got != theme.paletteColor("#7") {
+		t.Errorf("expected the color of PR #7, got %q", got)
+	}
+	if got := theme.lineColor(ColorByPR, BlameLineWithApproval{Approver: "bob"}, now); got != "" {
+		t.Errorf("expected no color for lines without a PR/MR, got %q", got)
+	}
+
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{time.Hour, "91"},
+		{10 * 24 * time.Hour, "31"},
+		{5 * 365 * 24 * time.Hour, "90"},
+	}
+	for _, tt := range tests {
+		if got := theme.lineColor(ColorByAge, approved("alice", tt.age), now); got != tt.want {
+			t.Errorf("age %v: expected color %q, got %q", tt.age, tt.want, got)
+		}
+	}
+	commitOnly := BlameLineWithApproval{BlameLine: BlameLine{Date: "1000000000"}}
+	if got := theme.lineColor(ColorByAge, commitOnly, now); got != "90" {
+		t.Errorf("expected the commit date to be used without an approval, got %q", got)
+	}
+}
+
+func TestWriteHumanColors(t *testing.T) {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine:    BlameLine{CommitHash: "a1b2c3d4e5f6", LineNumber: 1, Content: "first"},
+			Approver:     "alice",
+			ApprovalTime: &approvalTime,
+		},
+		{
+			BlameLine: BlameLine{CommitHash: "b1b2c3d4e5f6", Author: "bob", Date: "1714644000", LineNumber: 2, Content: "second"},
+		},
+	}
+	theme := &ColorTheme{Palette: []string{"36"}}
+	human, _ := NewFormatterRegistry().Lookup("human")
+
+	got := human.Format(lines, FormatOptions{ColorBy: ColorByApprover, Theme: theme})
+	want := "\033[36ma1b2c3d4 (alice       2024-05-02 10:00:00 1) \033[0mfirst\n" +
+		"\033[2mb1b2c3d4 (bob [no PR] 2024-05-02 10:00:00 2) second\033[0m\n"
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+
+	for _, opts := range []FormatOptions{{}, {ColorBy: ColorByApprover, NoColors: true}} {
+		if got := human.Format(lines, opts); strings.Contains(got, "\033[") {
+			t.Errorf("expected no colors with %+v, got %q", opts, got)
+		}
+	}
+}
+
+func TestOptionsColorBy(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if got := (Options{ColorBy: ColorByAge}).colorBy(); got != ColorByAge {
+		t.Errorf("expected -color-by to select the mode, got %q", got)
+	}
+	if got := (Options{ColorBy: ColorByAge, NoColor: true}).colorBy(); got != "" {
+		t.Errorf("expected -no-color to disable colors, got %q", got)
+	}
+	if got := (Options{OutputFile: "out.txt"}).colorBy(); got != "" {
+		t.Errorf("expected no colors in files by default, got %q", got)
+	}
+	t.Setenv("NO_COLOR", "1")
+	if got := (Options{ColorBy: ColorByAge}).colorBy(); got != "" {
+		t.Errorf("expected NO_COLOR to disable colors, got %q", got)
+	}
+}
+
+func TestLoadConfigColors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	os.WriteFile(path, []byte(`{"colors": {"palette": ["38;5;208", "32"], "unreviewed": "90"}}`), 0644)
+	config, err := LoadConfig(dir, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	theme := config.Colors.withDefaults()
+	if len(theme.Palette) != 2 || theme.Unreviewed != "90" || len(theme.Age) != len(DefaultColorTheme.Age) {
+		t.Errorf("expected configured colors with default age colors, got %+v", theme)
+	}
+
+	os.WriteFile(path, []byte(`{"colors": {"palette": ["green"]}}`), 0644)
+	if _, err := LoadConfig(dir, path); err == nil || !strings.Contains(err.Error(), `"green"`) {
+		t.Errorf("expected an invalid color error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package reviewblame
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Command is a subcommand of git-review-blame
+type Command struct {
+	Name string
+	// Run runs the command with the arguments after its name and the
+	// GitHub and GitLab tokens of the environment
+	Run func(ctx context.Context, args []string, githubToken, gitlabToken string) error
+}
+
+// commands are the subcommands, dispatched on the first argument. A first
+// argument that is not a command name runs blame, so "git-review-blame
+// <file>" keeps working; a file named like a command is annotated with
+// "git-review-blame blame -- <file>".
+var commands = []Command{
+	{Name: "blame", Run: runBlame},
+	{Name: "report", Run: runReport},
+	{Name: "digest", Run: runDigest},
+	{Name: "snapshot", Run: runSnapshot},
+	{Name: "verify", Run: runVerify},
+	{Name: "team-coverage", Run: runTeamCoverage},
+	{Name: "stats", Run: runStats},
+	{Name: "history", Run: runHistory},
+	{Name: "diff", Run: runDiff},
+	{Name: "drift", Run: runDrift},
+	{Name: "serve", Run: runServe},
+	{Name: "lsp", Run: runLSP},
+	{Name: "hook", Run: runHook},
+	{Name: "doctor", Run: runDoctor},
+	{Name: "auth", Run: runAuth},
+	{Name: "cache", Run: runCache},
+	{Name: "version", Run: runVersion},
+	{Name: "help", Run: runHelp},
+}
+
+// lookupCommand returns the command called name
+func lookupCommand(name string) (Command, bool) {
+	for _, command := range commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return Command{}, false
+}
+
+// runReport implements the report subcommand: blame in a report format,
+// written to the file given with -o, whose extension picks the format
+// unless one is selected
+func runReport(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	// "report coverage" reports on the whole repository rather than writing
+	// the lines of the given files; "report -- coverage" reports a file
+	// named coverage
+	if len(args) > 0 && args[0] == "coverage" {
+		return runCoverageReport(ctx, args[1:], githubToken, gitlabToken)
+	}
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	output := flags.String("o", "", "Write the report to the file; .html, .md and .csv pick the format (default: Markdown on stdout)")
+	paths, opts, err := parseBlameOptions(flags, args)
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		opts.OutputFile = *output
+	}
+	if opts.Format == "" && !opts.Porcelain {
+		opts.Format = reportFormat(opts.OutputFile)
+	}
+	return runGitReviewBlame(ctx, paths, opts, githubToken, gitlabToken)
+}
+
+// reportFormat returns the report format for an output file name
+func reportFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return "html"
+	case ".csv":
+		return "csv"
+	default:
+		return "markdown"
+	}
+}
+
+// runVersion implements the version subcommand
+func runVersion(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	fmt.Printf("git-review-blame %s (%s, %s/%s)\n", Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
+
+// runHelp implements the help subcommand
+func runHelp(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	showHelp()
+	return nil
+}
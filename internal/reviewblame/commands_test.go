@@ -0,0 +1,71 @@
+package reviewblame
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestLookupCommand(t *testing.T) {
+	for _, name := range []string{"blame", "report", "digest", "snapshot", "verify", "team-coverage", "stats", "history", "diff", "drift", "serve", "lsp", "hook", "doctor", "auth", "version", "help"} {
+		if command, ok := lookupCommand(name); !ok || command.Name != name || command.Run == nil {
+			t.Errorf("command %q not found", name)
+		}
+	}
+	if _, ok := lookupCommand("main.go"); ok {
+		t.Error("expected a path not to be a command")
+	}
+}
+
+func TestParseBlameOptions(t *testing.T) {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	output := flags.String("o", "", "")
+	paths, opts, err := parseBlameOptions(flags, []string{"-o", "out.md", "-L", "1,5", "-csv", "-csv-columns", "file,pr", "-token-source", "keyring", "HEAD~1", "--", "a.go", "b.go"})
+	if err != nil {
+		t.Fatalf("parseBlameOptions failed: %v", err)
+	}
+	if *output != "out.md" {
+		t.Errorf("expected the command's own flag to be parsed, got %q", *output)
+	}
+	if strings.Join(paths, " ") != "a.go b.go" || opts.Revision != "HEAD~1" {
+		t.Errorf("unexpected paths %v and revision %q", paths, opts.Revision)
+	}
+	if opts.Format != "csv" || strings.Join(opts.CSVColumns, ",") != "file,pr" || strings.Join(opts.LineRanges, " ") != "1,5" || opts.TokenSource != "keyring" {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "no path", args: nil, want: "Please specify a file to analyze"},
+		{name: "unknown flag", args: []string{"-bogus", "a.go"}, want: "flag provided but not defined"},
+		{name: "bad csv column", args: []string{"-csv-columns", "reviewer", "a.go"}, want: `unknown CSV column "reviewer"`},
+		{name: "bad token source", args: []string{"-token-source", "vault", "a.go"}, want: `unknown token source "vault"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("blame", flag.ContinueOnError)
+			flags.SetOutput(&strings.Builder{})
+			if _, _, err := parseBlameOptions(flags, tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestReportFormat(t *testing.T) {
+	tests := map[string]string{
+		"":               "markdown",
+		"review.md":      "markdown",
+		"review.HTML":    "html",
+		"out/review.htm": "html",
+		"evidence.csv":   "csv",
+	}
+	for path, want := range tests {
+		if got := reportFormat(path); got != want {
+			t.Errorf("reportFormat(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultConfigFile is the config file name looked up at the repository root
+const DefaultConfigFile = ".git-review-blame.json"
+
+// Config holds optional settings read from the config file
+type Config struct {
+	Notifications []NotificationConfig `json:"notifications"`
+	Policy        *PolicyConfig        `json:"policy"`
+	Migrations    []MigrationConfig    `json:"migrations"`
+	Trackers      []TrackerConfig      `json:"trackers"`
+	Identities    []IdentityConfig     `json:"identities"`
+	Teams         []TeamConfig         `json:"teams"`
+	Hosts         []HostConfig         `json:"hosts"`
+	Colors        *ColorTheme          `json:"colors"`
+	HTTP          *HTTPConfig          `json:"http"`
+	Cache         *CacheConfig         `json:"cache"`
+	// Remote is the remote whose repository holds the PRs/MRs, e.g.
+	// "upstream" in a fork (default: see DetectRemote)
+	Remote string `json:"remote"`
+}
+
+// NotificationConfig configures a webhook that receives run summaries
+type NotificationConfig struct {
+	// Type is the webhook flavor: "slack" or "teams"
+	Type string `json:"type"`
+	// WebhookURL is the incoming webhook URL, or a reference to an
+	// environment variable holding it, so secrets need not be committed
+	WebhookURL string `json:"webhook_url"`
+}
+
+// WebhookURLVariableSuffix ends the names of the environment variables a
+// webhook_url may reference
+const WebhookURLVariableSuffix = "_WEBHOOK_URL"
+
+// resolveWebhookURL returns the webhook URL a webhook_url value stands for.
+// A value starting with $ must be a reference to exactly one variable named
+// *_WEBHOOK_URL, such as $SLACK_WEBHOOK_URL or ${TEAMS_WEBHOOK_URL}; it is
+// never expanded inside a URL, so a config file cannot send other variables,
+// such as tokens, to a host of its choosing.
+func resolveWebhookURL(value string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+	name := strings.TrimPrefix(value, "$")
+	if strings.HasPrefix(name, "{") && strings.HasSuffix(name, "}") {
+		name = name[1 : len(name)-1]
+	}
+	if !webhookURLVariable.MatchString(name) {
+		return "", fmt.Errorf("webhook_url %q must be a URL or a reference to a single $<NAME>%s variable", value, WebhookURLVariableSuffix)
+	}
+	return os.Getenv(name), nil
+}
+
+// webhookURLVariable matches the variable names resolveWebhookURL accepts
+var webhookURLVariable = regexp.MustCompile(`^[A-Za-z0-9_]*` + WebhookURLVariableSuffix + `$`)
+
+// LoadConfig reads the config file at path, or DefaultConfigFile in repoRoot
+// when path is empty, and adds the settings of the user config file (see
+// LoadUserConfig). A missing default config file yields an empty Config.
+func LoadConfig(repoRoot, path string) (*Config, error) {
+	config, err := loadRepositoryConfig(repoRoot, path)
+	if err != nil {
+		return nil, err
+	}
+	user, err := LoadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	user.apply(config)
+	return config, nil
+}
+
+// loadRepositoryConfig reads the config file at path, or DefaultConfigFile in
+// repoRoot when path is empty. Either can be committed by a repository, so
+// settings deciding where tokens are sent are rejected (see UserConfig).
+func loadRepositoryConfig(repoRoot, path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = filepath.Join(repoRoot, DefaultConfigFile)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for _, migration := range config.Migrations {
+		if err := migration.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	for _, tracker := range config.Trackers {
+		if _, err := tracker.compile(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	if _, err := NewIdentityMap(config.Identities); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for _, team := range config.Teams {
+		if err := team.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	for _, host := range config.Hosts {
+		if err := host.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		if host.APIURL != "" {
+			return nil, fmt.Errorf("invalid config file %s: host %q: api_url decides where tokens are sent, so it is only read from the user config file", path, host.Host)
+		}
+		// A repository may name a plugin the user installed, but not run
+		// an executable of its own
+		if strings.ContainsAny(host.Plugin, `/\`) {
+			return nil, fmt.Errorf("invalid config file %s: host %q: plugin paths are only read from the user config file; name a plugin installed on PATH", path, host.Host)
+		}
+	}
+
+	if config.Colors != nil {
+		if err := config.Colors.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	if config.HTTP != nil {
+		if err := config.HTTP.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		if config.HTTP.Proxy != "" || len(config.HTTP.CACerts) > 0 || config.HTTP.InsecureSkipVerify {
+			return nil, fmt.Errorf("invalid config file %s: http: proxy, ca_certs and insecure_skip_verify decide who can read tokens in transit, so they are only read from the user config file", path)
+		}
+	}
+
+	if config.Cache != nil {
+		return nil, fmt.Errorf("invalid config file %s: cache: cached approvals are trusted without an API request, so the cache is only read from the user config file", path)
+	}
+
+	for i := range config.Notifications {
+		if err := validateNotificationType(config.Notifications[i].Type); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: notifications[%d]: %w", path, i, err)
+		}
+		url, err := resolveWebhookURL(config.Notifications[i].WebhookURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		config.Notifications[i].WebhookURL = url
+	}
+
+	// Policy files are relative to, and confined to, the directory holding
+	// the config file: the repository root for DefaultConfigFile
+	if config.Policy != nil {
+		if err := config.Policy.resolveFiles(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,134 @@
+package reviewblame
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TEST_SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/X")
+
+	content := `{
+  "notifications": [
+    {"type": "slack", "webhook_url": "$TEST_SLACK_WEBHOOK_URL"},
+    {"type": "teams", "webhook_url": "https://example.webhook.office.com/x"}
+  ]
+}`
+	if err := os.WriteFile(filepath.Join(dir, DefaultConfigFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(config.Notifications) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(config.Notifications))
+	}
+	if config.Notifications[0].WebhookURL != "https://hooks.slack.com/services/T/B/X" {
+		t.Errorf("expected expanded webhook URL, got %s", config.Notifications[0].WebhookURL)
+	}
+	if config.Notifications[1].Type != "teams" {
+		t.Errorf("expected teams notification, got %s", config.Notifications[1].Type)
+	}
+}
+
+func TestResolveWebhookURL(t *testing.T) {
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/X")
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"https://example.webhook.office.com/x", "https://example.webhook.office.com/x", false},
+		{"$SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/X", false},
+		{"${SLACK_WEBHOOK_URL}", "https://hooks.slack.com/services/T/B/X", false},
+		{"$GITHUB_TOKEN", "", true},
+		{"https://attacker.example.com/?t=$GITHUB_TOKEN", "", true},
+		{"https://attacker.example.com/?u=$SLACK_WEBHOOK_URL", "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolveWebhookURL(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveWebhookURL(%q): unexpected error %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveWebhookURL(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := LoadConfig(dir, "")
+	if err != nil {
+		t.Fatalf("expected missing default config to be ignored, got %v", err)
+	}
+	if len(config.Notifications) != 0 {
+		t.Errorf("expected empty config, got %+v", config)
+	}
+
+	if _, err := LoadConfig(dir, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for missing explicit config file")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, DefaultConfigFile), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadConfig(dir, ""); err == nil {
+		t.Error("expected error for invalid config file")
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectError bool
+	}{
+		{"valid", `{"migrations": [{"before": "abc123", "repository": "old-org/old-repo"}]}`, false},
+		{"missing before", `{"migrations": [{"repository": "old-org/old-repo"}]}`, true},
+		{"bad repository", `{"migrations": [{"before": "abc123", "repository": "old-repo"}]}`, true},
+		{"valid tracker", `{"trackers": [{"name": "jira", "pattern": "[A-Z]+-\\d+", "url_template": "https://jira/{key}"}]}`, false},
+		{"invalid tracker", `{"trackers": [{"name": "jira", "pattern": "([A-Z"}]}`, true},
+		{"valid identities", `{"identities": [{"name": "alice", "aliases": ["alice-contractor"]}]}`, false},
+		{"duplicate identity alias", `{"identities": [{"name": "alice", "aliases": ["x"]}, {"name": "bob", "aliases": ["x"]}]}`, true},
+		{"valid team", `{"teams": [{"name": "platform", "members": ["alice"], "github_team": "acme/platform"}]}`, false},
+		{"team without members", `{"teams": [{"name": "platform"}]}`, true},
+		{"valid host", `{"hosts": [{"host": "git.example.com", "provider": "forgejo"}]}`, false},
+		{"host with unknown provider", `{"hosts": [{"host": "git.example.com", "provider": "svn"}]}`, true},
+		{"plugin host", `{"hosts": [{"host": "review.example.com", "provider": "plugin", "plugin": "corp-review"}]}`, false},
+		{"plugin host without plugin", `{"hosts": [{"host": "review.example.com", "provider": "plugin"}]}`, true},
+		{"plugin of another provider", `{"hosts": [{"host": "git.example.com", "provider": "gitea", "plugin": "corp-review"}]}`, true},
+		{"repository cache", `{"cache": {"backend": "sqlite", "path": "cache.db"}}`, true},
+		{"valid notification", `{"notifications": [{"type": "teams", "webhook_url": "https://example.webhook.office.com/x"}]}`, false},
+		{"unknown notification type", `{"notifications": [{"type": "slak", "webhook_url": "https://hooks.slack.com/services/T/B/X"}]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, DefaultConfigFile), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(dir, "")
+			if tt.expectError && err == nil {
+				t.Error("expected error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
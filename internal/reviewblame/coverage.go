@@ -0,0 +1,195 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileReviewerDependency tells how much of a file's reviewed lines one
+// approver approved
+type FileReviewerDependency struct {
+	File          string  `json:"file"`
+	TotalLines    int     `json:"total_lines"`
+	ReviewedLines int     `json:"reviewed_lines"`
+	Approvers     int     `json:"approvers"`
+	TopApprover   string  `json:"top_approver"`
+	TopLines      int     `json:"top_approver_lines"`
+	Share         float64 `json:"top_approver_share"`
+}
+
+// CoverageReport is the result of the report coverage subcommand: how many lines
+// of the repository were approved, by whom, and which files depend the most
+// on a single reviewer
+type CoverageReport struct {
+	Path          string  `json:"path"`
+	Files         int     `json:"files"`
+	TotalLines    int     `json:"total_lines"`
+	ReviewedLines int     `json:"reviewed_lines"`
+	Coverage      float64 `json:"coverage"`
+	// BusFactor is the smallest number of approvers who together approved
+	// more than half of the reviewed lines
+	BusFactor       int                      `json:"bus_factor"`
+	Approvers       []ApproverShare          `json:"approvers"`
+	SingleReviewers []FileReviewerDependency `json:"single_reviewer_files"`
+}
+
+// BuildCoverageReport computes the review coverage of lines, with up to top
+// files (all when 0) most dependent on a single reviewer: those whose most
+// frequent approver approved the largest share of their reviewed lines,
+// larger files first on ties. The counts are those of the stats subcommand
+// grouped by file.
+func BuildCoverageReport(scope string, lines []BlameLineWithApproval, top int) CoverageReport {
+	ownership := BuildOwnershipReport(scope, lines, StatsByFile, 0)
+	stats := ownership.Total
+	report := CoverageReport{
+		Path:          scope,
+		Files:         len(ownership.Groups),
+		TotalLines:    stats.TotalLines,
+		ReviewedLines: stats.TotalLines - stats.UnreviewedLines,
+		Coverage:      percentOf(stats.TotalLines-stats.UnreviewedLines, stats.TotalLines),
+		Approvers:     stats.Approvers,
+	}
+
+	covered := 0
+	for _, approver := range stats.Approvers {
+		if covered*2 > report.ReviewedLines {
+			break
+		}
+		covered += approver.Lines
+		report.BusFactor++
+	}
+
+	report.SingleReviewers = []FileReviewerDependency{}
+	for _, fileStats := range ownership.Groups {
+		if len(fileStats.Approvers) == 0 {
+			continue
+		}
+		reviewed := fileStats.TotalLines - fileStats.UnreviewedLines
+		topApprover := fileStats.Approvers[0]
+		report.SingleReviewers = append(report.SingleReviewers, FileReviewerDependency{
+			File:          fileStats.Path,
+			TotalLines:    fileStats.TotalLines,
+			ReviewedLines: reviewed,
+			Approvers:     len(fileStats.Approvers),
+			TopApprover:   topApprover.Approver,
+			TopLines:      topApprover.Lines,
+			Share:         percentOf(topApprover.Lines, reviewed),
+		})
+	}
+	sort.Slice(report.SingleReviewers, func(i, j int) bool {
+		a, b := report.SingleReviewers[i], report.SingleReviewers[j]
+		if a.Share != b.Share {
+			return a.Share > b.Share
+		}
+		if a.ReviewedLines != b.ReviewedLines {
+			return a.ReviewedLines > b.ReviewedLines
+		}
+		return a.File < b.File
+	})
+	if top > 0 && len(report.SingleReviewers) > top {
+		report.SingleReviewers = report.SingleReviewers[:top]
+	}
+	return report
+}
+
+// Text renders the report as a summary followed by the approver
+// distribution and the files most dependent on a single reviewer
+func (r CoverageReport) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Review coverage of %s: %.1f%% (%d of %d lines in %d files)\n", r.Path, r.Coverage, r.ReviewedLines, r.TotalLines, r.Files)
+	fmt.Fprintf(&b, "Bus factor: %d\n", r.BusFactor)
+
+	if len(r.Approvers) > 0 {
+		width := len("Approver")
+		for _, approver := range r.Approvers {
+			width = max(width, len(approver.Approver))
+		}
+		fmt.Fprintf(&b, "\n%-*s  %6s  %6s\n", width, "Approver", "Lines", "Share")
+		for _, approver := range r.Approvers {
+			fmt.Fprintf(&b, "%-*s  %6d  %5.1f%%\n", width, approver.Approver, approver.Lines, approver.Percent)
+		}
+	}
+
+	if len(r.SingleReviewers) > 0 {
+		width := len("File")
+		for _, file := range r.SingleReviewers {
+			width = max(width, len(file.File))
+		}
+		fmt.Fprintf(&b, "\nFiles most dependent on a single reviewer:\n")
+		fmt.Fprintf(&b, "%-*s  %8s  %6s  %s\n", width, "File", "Reviewed", "Share", "Approver")
+		for _, file := range r.SingleReviewers {
+			fmt.Fprintf(&b, "%-*s  %8d  %5.1f%%  %s\n", width, file.File, file.ReviewedLines, file.Share, file.TopApprover)
+		}
+	}
+	return b.String()
+}
+
+// runCoverageReport implements report coverage, the repository-wide review
+// coverage report
+func runCoverageReport(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("report coverage", flag.ContinueOnError)
+	top := flags.Int("top", 10, "Number of files most dependent on a single reviewer to list (0 for all)")
+	jobs := flags.Int("jobs", 0, "Number of files blamed at once (default: one per CPU)")
+	format := flags.String("format", "text", "Report format: text or json")
+	configPath := flags.String("config", "", "Path to the config file")
+	includeVendored := flags.Bool("include-vendored", false, "Include vendored files")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported coverage format %q (expected text or json)", *format)
+	}
+	if *top < 0 {
+		return fmt.Errorf("-top must not be negative")
+	}
+	if *jobs < 0 {
+		return fmt.Errorf("-jobs must not be negative")
+	}
+
+	target := "."
+	if flags.NArg() > 0 {
+		target = flags.Arg(0)
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(target, *configPath, "")
+	if err != nil {
+		return err
+	}
+	// Without a path the whole repository is covered, wherever it is run
+	if flags.NArg() == 0 {
+		target = repoRoot
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{}, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+
+	lines, err := annotatePath(ctx, repoRoot, target, "", BlameOptions{Jobs: *jobs}, pipeline, *includeVendored)
+	if err != nil {
+		return err
+	}
+
+	report := BuildCoverageReport(displayPath(repoRoot, target), lines, *top)
+	if *format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(report.Text())
+	return nil
+}
@@ -0,0 +1,63 @@
+package reviewblame
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBuildCoverageReport(t *testing.T) {
+	line := func(file, approver string) BlameLineWithApproval {
+		return BlameLineWithApproval{BlameLine: BlameLine{Filename: file}, Approver: approver}
+	}
+	lines := []BlameLineWithApproval{
+		line("a.go", "alice"),
+		line("a.go", "alice"),
+		line("a.go", "bob"),
+		line("b.go", "carol"),
+		line("b.go", "carol"),
+		line("b.go", ""),
+		line("c.go", "alice"),
+		line("c.go", "bob"),
+		line("d.go", ""),
+	}
+
+	report := BuildCoverageReport(".", lines, 2)
+	if report.Files != 4 || report.TotalLines != 9 || report.ReviewedLines != 7 {
+		t.Errorf("unexpected totals %+v", report)
+	}
+	if report.BusFactor != 2 {
+		t.Errorf("expected a bus factor of 2, got %d", report.BusFactor)
+	}
+
+	wantText := "Review coverage of .: 77.8% (7 of 9 lines in 4 files)\n" +
+		"Bus factor: 2\n" +
+		"\n" +
+		"Approver   Lines   Share\n" +
+		"alice          3   33.3%\n" +
+		"bob            2   22.2%\n" +
+		"carol          2   22.2%\n" +
+		"\n" +
+		"Files most dependent on a single reviewer:\n" +
+		"File  Reviewed   Share  Approver\n" +
+		"b.go         2  100.0%  carol\n" +
+		"a.go         3   66.7%  alice\n"
+	if got := report.Text(); got != wantText {
+		t.Errorf("got:\n%s\nwant:\n%s", got, wantText)
+	}
+
+	if all := BuildCoverageReport(".", lines, 0); len(all.SingleReviewers) != 3 {
+		t.Errorf("expected the 3 reviewed files, got %+v", all.SingleReviewers)
+	}
+	if empty := BuildCoverageReport(".", nil, 0); empty.BusFactor != 0 || empty.Coverage != 0 {
+		t.Errorf("unexpected report of no lines %+v", empty)
+	}
+}
+
+func TestReportCoverageCommand(t *testing.T) {
+	// report coverage takes its own flags rather than those of report
+	err := runReport(context.Background(), []string{"coverage", "-format", "xml"}, "", "")
+	if err == nil || !strings.Contains(err.Error(), "unsupported coverage format") {
+		t.Errorf("expected report coverage to validate its format, got %v", err)
+	}
+}
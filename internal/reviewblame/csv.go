@@ -0,0 +1,125 @@
+package reviewblame
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns maps each CSV column name to the value it takes from a line
+var csvColumns = map[string]func(line BlameLineWithApproval) string{
+	"file":         func(line BlameLineWithApproval) string { return line.Filename },
+	"line":         func(line BlameLineWithApproval) string { return strconv.Itoa(line.LineNumber) },
+	"commit":       func(line BlameLineWithApproval) string { return line.CommitHash },
+	"author":       func(line BlameLineWithApproval) string { return line.Author },
+	"author_email": func(line BlameLineWithApproval) string { return line.AuthorEmail },
+	"author_date": func(line BlameLineWithApproval) string {
+		timestamp, err := strconv.ParseInt(line.Date, 10, 64)
+		if err != nil {
+			return line.Date
+		}
+		return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+	},
+	"repository": func(line BlameLineWithApproval) string { return line.Repository },
+	"pr": func(line BlameLineWithApproval) string {
+		if line.PRNumber == 0 {
+			return ""
+		}
+		return strconv.Itoa(line.PRNumber)
+	},
+	"pr_title":       func(line BlameLineWithApproval) string { return line.PRTitle },
+	"pr_labels":      func(line BlameLineWithApproval) string { return strings.Join(line.PRLabels, "; ") },
+	"approver":       func(line BlameLineWithApproval) string { return line.Approver },
+	"approver_email": func(line BlameLineWithApproval) string { return line.ApproverEmail },
+	"approvers":      csvApprovers,
+	"approval_date": func(line BlameLineWithApproval) string {
+		if line.ApprovalTime == nil {
+			return ""
+		}
+		return line.ApprovalTime.UTC().Format(time.RFC3339)
+	},
+	"review_state": func(line BlameLineWithApproval) string { return line.ReviewState() },
+	"content":      func(line BlameLineWithApproval) string { return line.Content },
+	"original_line": func(line BlameLineWithApproval) string {
+		if line.OriginalLineNumber == 0 {
+			return ""
+		}
+		return strconv.Itoa(line.OriginalLineNumber)
+	},
+	"original_file": func(line BlameLineWithApproval) string { return line.OriginalFilename },
+}
+
+// DefaultCSVColumns are the columns written when none are selected
+var DefaultCSVColumns = []string{"file", "line", "commit", "author", "pr", "approvers", "approval_date"}
+
+// csvApprovers joins the distinct approvers of a line's PR/MR with "; "
+func csvApprovers(line BlameLineWithApproval) string {
+	var names []string
+	for _, approver := range distinctApprovers(line) {
+		names = append(names, approver.Name)
+	}
+	return strings.Join(names, "; ")
+}
+
+// ParseCSVColumns parses a comma-separated list of CSV column names
+func ParseCSVColumns(spec string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := csvColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q (available: %s)", name, strings.Join(csvColumnNames(), ", "))
+		}
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no CSV columns selected")
+	}
+	return columns, nil
+}
+
+// csvColumnNames returns the known column names, defaults first
+func csvColumnNames() []string {
+	names := append([]string(nil), DefaultCSVColumns...)
+	return append(names, "author_email", "author_date", "repository", "pr_title", "pr_labels", "approver", "approver_email", "review_state", "content", "original_line", "original_file")
+}
+
+// formatCSV writes a header row and one row per line, with the columns of
+// opts.Columns or DefaultCSVColumns. Dates are RFC 3339 in UTC and lines
+// without a PR/MR or approval have empty cells, so the output can be filtered
+// in a spreadsheet and kept as audit evidence.
+func formatCSV(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+	values := make([]func(line BlameLineWithApproval) string, len(columns))
+	for i, name := range columns {
+		value, ok := csvColumns[name]
+		if !ok {
+			return fmt.Errorf("unknown CSV column %q", name)
+		}
+		values[i] = value
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	for _, line := range lines {
+		for i, value := range values {
+			row[i] = value(line)
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,93 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCSV(t *testing.T) {
+	approvedAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	approved := BlameLineWithApproval{
+		BlameLine: BlameLine{
+			CommitHash: "abc123", Filename: "src/main.go", LineNumber: 3,
+			Author: "Jane Doe", AuthorEmail: "jane@example.com", Date: "1700000000",
+			Content: `fmt.Println("a, b")`, OriginalLineNumber: 1, OriginalFilename: "src/old.go",
+		},
+		PRNumber:      42,
+		PRTitle:       "Add greeting",
+		Approver:      "bob",
+		ApproverEmail: "bob@example.com",
+		ApprovalTime:  &approvedAt,
+		Approvers:     []LineApprover{{Name: "alice"}, {Name: "bob"}, {Name: "alice"}},
+	}
+	unreviewed := BlameLineWithApproval{
+		BlameLine: BlameLine{CommitHash: "def456", Filename: "src/main.go", LineNumber: 4, Author: "Jane Doe"},
+	}
+	lines := []BlameLineWithApproval{approved, unreviewed}
+
+	tests := []struct {
+		name    string
+		columns []string
+		want    string
+	}{
+		{
+			name: "default columns",
+			want: "file,line,commit,author,pr,approvers,approval_date\n" +
+				"src/main.go,3,abc123,Jane Doe,42,alice; bob,2024-03-01T08:30:00Z\n" +
+				"src/main.go,4,def456,Jane Doe,,,\n",
+		},
+		{
+			name:    "selected columns",
+			columns: []string{"line", "approver_email", "author_date", "content"},
+			want: "line,approver_email,author_date,content\n" +
+				"3,bob@example.com,2023-11-14T22:13:20Z,\"fmt.Println(\"\"a, b\"\")\"\n" +
+				"4,,,\n",
+		},
+		{
+			name:    "original location",
+			columns: []string{"line", "original_line", "original_file"},
+			want:    "line,original_line,original_file\n3,1,src/old.go\n4,,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := formatCSV(&out, lines, FormatOptions{Columns: tt.columns}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("unexpected CSV:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVColumns(t *testing.T) {
+	columns, err := ParseCSVColumns(" file, pr ,approvers,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(columns, ",") != "file,pr,approvers" {
+		t.Errorf("unexpected columns %v", columns)
+	}
+
+	if _, err := ParseCSVColumns("file,reviewer"); err == nil || !strings.Contains(err.Error(), `"reviewer"`) {
+		t.Errorf("expected an error naming the unknown column, got %v", err)
+	}
+	if _, err := ParseCSVColumns(","); err == nil {
+		t.Error("expected an error for an empty column list")
+	}
+}
+
+func TestCSVFormatRegistered(t *testing.T) {
+	formatter, err := DefaultFormatters.Lookup("csv")
+	if err != nil {
+		t.Fatalf("csv format not registered: %v", err)
+	}
+	output := formatter.Format([]BlameLineWithApproval{{BlameLine: BlameLine{Filename: "a.go", LineNumber: 1}}}, FormatOptions{Columns: []string{"file", "line"}})
+	if output != "file,line\na.go,1\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+}
@@ -0,0 +1,76 @@
+package reviewblame
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DeletedFile locates the last revision of a file that no longer exists
+type DeletedFile struct {
+	// Path is the repository-relative path, slash separated
+	Path string
+	// DeletedIn is the commit that removed the file
+	DeletedIn string
+	// Subject is the subject line of DeletedIn
+	Subject string
+	// Revision is the last commit that still contains the file
+	Revision string
+}
+
+// FindDeletedFile finds the commit that deleted filePath and the last
+// revision that still contains it, searching the full history of HEAD
+func FindDeletedFile(repoRoot, filePath string) (*DeletedFile, error) {
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(repoRoot, absFilePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	// The most recent commit touching the path is the one that deleted it
+	cmd := exec.Command("git", "log", "--full-history", "-n", "1", "--format=%H%x00%P%x00%s", "--", relPath)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not search history of %s: %w", relPath, err)
+	}
+	fields := strings.SplitN(strings.TrimRight(string(output), "\n"), "\x00", 3)
+	if len(fields) != 3 {
+		return nil, &UntrackedFileError{Path: relPath}
+	}
+
+	deleted := &DeletedFile{Path: relPath, DeletedIn: fields[0], Subject: fields[2]}
+
+	// A merge may have deleted the file on any side, so use the first
+	// parent that still has it
+	for _, parent := range strings.Fields(fields[1]) {
+		if fileExistsAt(repoRoot, parent, relPath) {
+			deleted.Revision = parent
+			return deleted, nil
+		}
+	}
+	return nil, fmt.Errorf("%s was deleted in %s but no parent revision contains it", relPath, shortCommit(deleted.DeletedIn))
+}
+
+// fileExistsAt reports whether path (repository-relative) exists at rev
+func fileExistsAt(repoRoot, rev, path string) bool {
+	cmd := exec.Command("git", "cat-file", "-e", rev+":"+path)
+	cmd.Dir = repoRoot
+	return cmd.Run() == nil
+}
+
+// ReadFile returns the content of the file at its last revision
+func (d *DeletedFile) ReadFile(repoRoot string) ([]byte, error) {
+	return ReadFileAt(repoRoot, d.Path, d.Revision)
+}
+
+// Header describes which revision of the deleted file is being annotated
+func (d *DeletedFile) Header() string {
+	return fmt.Sprintf("%s was deleted in %s (%s); showing blame at %s\n",
+		d.Path, shortCommit(d.DeletedIn), d.Subject, shortCommit(d.Revision))
+}
@@ -0,0 +1,62 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDeletedFile(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+
+	if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "pkg", "old.go")
+	if err := os.WriteFile(filePath, []byte("package pkg\n\nfunc Old() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "pkg/old.go")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add old.go")
+	added := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	gitCommand(t, dir, "rm", "-q", "pkg/old.go")
+	gitCommand(t, dir, "commit", "-q", "-m", "Remove old.go (#4)")
+	removed := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	deleted, err := FindDeletedFile(dir, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted.Path != "pkg/old.go" || deleted.DeletedIn != removed || deleted.Revision != added {
+		t.Errorf("unexpected deleted file %+v", deleted)
+	}
+	if deleted.Subject != "Remove old.go (#4)" {
+		t.Errorf("unexpected subject %q", deleted.Subject)
+	}
+
+	header := deleted.Header()
+	if !strings.Contains(header, "deleted in "+shortCommit(removed)) || !strings.Contains(header, "blame at "+shortCommit(added)) {
+		t.Errorf("header does not name the revisions: %q", header)
+	}
+
+	lines, err := ExecuteGitBlameAt(context.Background(), dir, filePath, deleted.Revision, BlameOptions{})
+	if err != nil {
+		t.Fatalf("unexpected blame error: %v", err)
+	}
+	if len(lines) != 3 || lines[2].Content != "func Old() {}" || lines[0].CommitHash != added || lines[0].Filename != "pkg/old.go" {
+		t.Errorf("unexpected blame lines %+v", lines)
+	}
+
+	content, err := deleted.ReadFile(dir)
+	if err != nil || !strings.HasPrefix(string(content), "package pkg") {
+		t.Errorf("unexpected content %q, err %v", content, err)
+	}
+
+	if _, err := FindDeletedFile(dir, filepath.Join(dir, "never.go")); err == nil {
+		t.Error("expected an error for a path that never existed")
+	}
+}
@@ -0,0 +1,360 @@
+package reviewblame
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderPattern matches a unified diff hunk header, whose line counts
+// default to 1 when omitted
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// DiffLine is a line of a unified diff. Removed lines, which include the
+// old side of changed lines, know their file and line number before the
+// change, and added lines after it, so they can be blamed there.
+type DiffLine struct {
+	Text string
+	// OldFile and OldLine are set for removed lines, NewFile and NewLine
+	// for added lines; the files are relative to the repository root and
+	// slash separated
+	OldFile string
+	OldLine int
+	NewFile string
+	NewLine int
+}
+
+// diffLineKey identifies a line of a file before the change
+type diffLineKey struct {
+	file string
+	line int
+}
+
+// ParseUnifiedDiff reads a unified diff, as written by git diff or diff -u
+func ParseUnifiedDiff(r io.Reader) ([]DiffLine, error) {
+	var lines []DiffLine
+	var oldFile, newFile string
+	// oldLine and newLine are the next line numbers of each side; oldLeft
+	// and newLeft count the lines left in the current hunk
+	oldLine, newLine, oldLeft, newLeft := 0, 0, 0, 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := DiffLine{Text: scanner.Text()}
+		if oldLeft > 0 || newLeft > 0 {
+			prefix := byte(' ')
+			if line.Text != "" {
+				prefix = line.Text[0]
+			}
+			switch prefix {
+			case ' ':
+				oldLine++
+				newLine++
+				oldLeft--
+				newLeft--
+			case '-':
+				line.OldFile, line.OldLine = oldFile, oldLine
+				oldLine++
+				oldLeft--
+			case '+':
+				line.NewFile, line.NewLine = newFile, newLine
+				newLine++
+				newLeft--
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		if path, found := strings.CutPrefix(line.Text, "--- "); found {
+			oldFile = diffPath(path, "a/")
+		} else if path, found := strings.CutPrefix(line.Text, "+++ "); found {
+			newFile = diffPath(path, "b/")
+		} else if match := hunkHeaderPattern.FindStringSubmatch(line.Text); match != nil {
+			oldLine, _ = strconv.Atoi(match[1])
+			newLine, _ = strconv.Atoi(match[3])
+			oldLeft, newLeft = hunkLineCount(match[2]), hunkLineCount(match[4])
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// hunkLineCount parses the line count of a hunk header
+func hunkLineCount(count string) int {
+	if count == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(count)
+	return n
+}
+
+// diffPath returns the repository path of a "---" or "+++" header, without
+// its prefix ("a/" or "b/") or the timestamp diff -u appends, or "" for
+// /dev/null
+func diffPath(path, prefix string) string {
+	if quoted, err := strconv.QuotedPrefix(path); err == nil {
+		path = unquoteGitPath(quoted)
+	} else if name, _, found := strings.Cut(path, "\t"); found {
+		path = name
+	}
+	if path == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// annotateDiff blames the removed lines of a diff as of base, the revision
+// the diff applies to, and returns their enriched lines in diff order
+func annotateDiff(ctx context.Context, repoRoot, base string, diff []DiffLine, pipeline *EnrichmentPipeline) ([]BlameLineWithApproval, error) {
+	removed := make([]diffLineKey, 0, len(diff))
+	for _, line := range diff {
+		if line.OldFile != "" {
+			removed = append(removed, diffLineKey{line.OldFile, line.OldLine})
+		}
+	}
+	blameLines, err := blameDiffLines(ctx, repoRoot, base, removed)
+	if err != nil {
+		return nil, err
+	}
+	enriched, err := pipeline.Run(ctx, blameLines)
+	if err != nil {
+		return nil, err
+	}
+	return diffOrder(removed, enriched), nil
+}
+
+// blameDiffLines blames the given lines as of rev, or in the working tree
+// when rev is empty, one git blame per file
+func blameDiffLines(ctx context.Context, repoRoot, rev string, keys []diffLineKey) ([]BlameLine, error) {
+	numbers := make(map[string][]int)
+	var files []string
+	for _, key := range keys {
+		if numbers[key.file] == nil {
+			files = append(files, key.file)
+		}
+		numbers[key.file] = append(numbers[key.file], key.line)
+	}
+
+	var blameLines []BlameLine
+	for _, file := range files {
+		lines, err := ExecuteGitBlameAt(ctx, repoRoot, filepath.Join(repoRoot, filepath.FromSlash(file)), rev,
+			BlameOptions{LineRanges: lineRanges(numbers[file])})
+		if err != nil {
+			if rev == "" {
+				return nil, fmt.Errorf("could not blame %s: %w", file, err)
+			}
+			return nil, fmt.Errorf("could not blame %s at %s: %w", file, rev, err)
+		}
+		blameLines = append(blameLines, lines...)
+	}
+	return blameLines, nil
+}
+
+// diffOrder returns the enriched lines of keys, in the order of keys
+func diffOrder(keys []diffLineKey, enriched []BlameLineWithApproval) []BlameLineWithApproval {
+	byLine := make(map[diffLineKey]BlameLineWithApproval, len(enriched))
+	for _, line := range enriched {
+		byLine[diffLineKey{line.Filename, line.LineNumber}] = line
+	}
+	var lines []BlameLineWithApproval
+	for _, key := range keys {
+		if line, ok := byLine[key]; ok {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// lineRanges merges ascending line numbers into git blame -L ranges
+func lineRanges(numbers []int) []string {
+	var ranges []string
+	for i := 0; i < len(numbers); {
+		end := i
+		for end+1 < len(numbers) && numbers[end+1] == numbers[end]+1 {
+			end++
+		}
+		ranges = append(ranges, fmt.Sprintf("%d,%d", numbers[i], numbers[end]))
+		i = end + 1
+	}
+	return ranges
+}
+
+// formatAnnotatedDiff renders the diff with the approval of each removed
+// line in a column before it, followed by the approvers of the removed
+// lines
+func formatAnnotatedDiff(diff []DiffLine, lines []BlameLineWithApproval) string {
+	labels := make(map[diffLineKey]string, len(lines))
+	width := 0
+	for _, line := range lines {
+		label := NewEditorAnnotation(line).Text
+		labels[diffLineKey{line.Filename, line.LineNumber}] = label
+		width = max(width, len(label))
+	}
+
+	var b strings.Builder
+	for _, line := range diff {
+		label := ""
+		if line.OldFile != "" {
+			label = labels[diffLineKey{line.OldFile, line.OldLine}]
+		}
+		fmt.Fprintf(&b, "%-*s | %s\n", width, label, line.Text)
+	}
+
+	approved := make(map[string]int)
+	total, unreviewed := 0, 0
+	for _, line := range lines {
+		if line.Approver == "" {
+			unreviewed++
+			continue
+		}
+		approved[line.Approver]++
+		total++
+	}
+	if total > 0 {
+		fmt.Fprintf(&b, "\n%d removed or changed line(s) were approved by %s\n", total, formatApproverCounts(approved))
+	}
+	if unreviewed > 0 {
+		fmt.Fprintf(&b, "%d removed or changed line(s) were not approved\n", unreviewed)
+	}
+	return b.String()
+}
+
+// diffBase returns the revision the diff of a git diff revision argument
+// applies to: the left side of "<rev1>..<rev2>", the merge base of
+// "<rev1>...<rev2>", or the revision compared with the working tree
+func diffBase(ctx context.Context, repoRoot, revisions string) (string, error) {
+	if left, right, found := strings.Cut(revisions, "..."); found {
+		if left == "" {
+			left = "HEAD"
+		}
+		if right == "" {
+			right = "HEAD"
+		}
+		cmd := exec.CommandContext(ctx, "git", "merge-base", left, right)
+		cmd.Dir = repoRoot
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("no merge base of %s and %s: %w", left, right, err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+	if left, _, found := strings.Cut(revisions, ".."); found && left != "" {
+		return left, nil
+	} else if found {
+		return "HEAD", nil
+	}
+	return revisions, nil
+}
+
+// gitDiff runs git diff on the revisions and paths and parses its output,
+// with the default prefixes and no external diff or text conversion
+func gitDiff(ctx context.Context, repoRoot, revisions string, paths []string) ([]DiffLine, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--no-textconv", "--no-relative", "--src-prefix=a/", "--dst-prefix=b/", revisions, "--"}
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, absPath)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git diff %s: %s", revisions, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return ParseUnifiedDiff(strings.NewReader(string(output)))
+}
+
+// runDiff implements the diff subcommand
+func runDiff(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	base := flags.String("base", "HEAD", "Revision a diff read from stdin applies to")
+	format := flags.String("format", "text", "Output format: text or json")
+	configPath := flags.String("config", "", "Path to the config file")
+	offline := flags.Bool("offline", false, "Find PRs/MRs from merge commits without API calls")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported diff format %q (expected text or json)", *format)
+	}
+
+	const usage = "Usage: git-review-blame diff [<rev> | <rev1>..<rev2> | <rev1>...<rev2>] [-- <path>...]"
+	revisions, paths := "", []string(nil)
+	for i, arg := range flags.Args() {
+		if arg == "--" {
+			paths = flags.Args()[i+1:]
+			break
+		}
+		if revisions != "" {
+			return fmt.Errorf("diff takes one revision or range\n%s", usage)
+		}
+		revisions = arg
+	}
+	if revisions == "" && len(paths) > 0 {
+		return fmt.Errorf("paths need a revision or range to diff\n%s", usage)
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(".", *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+
+	// Without a revision the diff is read from stdin, e.g. a patch file
+	var diff []DiffLine
+	baseRevision := *base
+	if revisions == "" {
+		diff, err = ParseUnifiedDiff(os.Stdin)
+	} else {
+		if baseRevision, err = diffBase(ctx, repoRoot, revisions); err != nil {
+			return err
+		}
+		diff, err = gitDiff(ctx, repoRoot, revisions, paths)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read the diff: %w", err)
+	}
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{Offline: *offline, Revision: baseRevision}, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+	lines, err := annotateDiff(ctx, repoRoot, baseRevision, diff, pipeline)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		records := make([]AnnotationRecord, len(lines))
+		for i, line := range lines {
+			records[i] = NewAnnotationRecord(line)
+		}
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(formatAnnotatedDiff(diff, lines))
+	return nil
+}
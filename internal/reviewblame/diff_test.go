@@ -0,0 +1,151 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		diff  string
+		want  []string
+		added []string
+	}{
+		{
+			name: "git diff",
+			diff: "diff --git a/src/main.go b/src/main.go\nindex 1..2 100644\n--- a/src/main.go\n+++ b/src/main.go\n" +
+				"@@ -3,4 +3,3 @@ func main() {\n context\n-removed\n---- looks like a header\n+added\n context\n",
+			want:  []string{"src/main.go:4", "src/main.go:5"},
+			added: []string{"src/main.go:4"},
+		},
+		{
+			name:  "diff -u with timestamps and default counts",
+			diff:  "--- a.txt\t2024-01-01 00:00:00\n+++ a.txt\t2024-01-02 00:00:00\n@@ -2 +2 @@\n-old\n+new\n",
+			want:  []string{"a.txt:2"},
+			added: []string{"a.txt:2"},
+		},
+		{
+			name: "quoted path",
+			diff: "--- \"a/sp \\303\\251.txt\"\t\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-x\n-y\n",
+			want: []string{"sp é.txt:1", "sp é.txt:2"},
+		},
+		{
+			name: "new file and missing newline",
+			diff: "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1 @@\n+package main\n\\ No newline at end of file\n" +
+				"--- a/old.go\n+++ b/old.go\n@@ -1,2 +1,2 @@\n-a\n \n+b\n",
+			want:  []string{"old.go:1"},
+			added: []string{"new.go:1", "old.go:2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := ParseUnifiedDiff(strings.NewReader(tt.diff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != strings.Count(tt.diff, "\n") {
+				t.Errorf("expected every line of the diff, got %d", len(lines))
+			}
+			var removed, added []string
+			for _, line := range lines {
+				if line.OldFile != "" {
+					removed = append(removed, line.OldFile+":"+strconv.Itoa(line.OldLine))
+				}
+				if line.NewFile != "" {
+					added = append(added, line.NewFile+":"+strconv.Itoa(line.NewLine))
+				}
+			}
+			if !reflect.DeepEqual(removed, tt.want) {
+				t.Errorf("expected removed lines %q, got %q", tt.want, removed)
+			}
+			if !reflect.DeepEqual(added, tt.added) {
+				t.Errorf("expected added lines %q, got %q", tt.added, added)
+			}
+		})
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	got := lineRanges([]int{1, 2, 3, 7, 9, 10})
+	want := []string{"1,3", "7,7", "9,10"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestAnnotateDiff(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	file := filepath.Join(dir, "a.txt")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("one\ntwo\nthree\nfour\n")
+	gitCommand(t, dir, "add", "a.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add a (#3)\n\nReviewed-by: Alice <alice@example.com>")
+	write("one\n2\nthree\nfour\n")
+	gitCommand(t, dir, "commit", "-q", "-am", "Edit a")
+	gitCommand(t, dir, "checkout", "-q", "-b", "topic")
+	write("one\nTWO\nthree\n")
+	gitCommand(t, dir, "commit", "-q", "-am", "Rewrite a")
+
+	ctx := context.Background()
+	base, err := diffBase(ctx, dir, "main...topic")
+	if err != nil || base != gitCommand(t, dir, "rev-parse", "main") {
+		t.Fatalf("expected the merge base to be main, got %q, %v", base, err)
+	}
+	diff, err := gitDiff(ctx, dir, "main...topic", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := newOfflinePipeline(dir, &Config{}, Options{Revision: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := annotateDiff(ctx, dir, base, diff, pipeline)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 || lines[0].Content != "2" || lines[0].Approver != "" || lines[1].Content != "four" || lines[1].Approver != "Alice" {
+		t.Fatalf("expected the removed lines 2 and four, got %+v", lines)
+	}
+
+	output := formatAnnotatedDiff(diff, lines)
+	for _, want := range []string{
+		"unreviewed (no PR) | -2\n",
+		"Alice (#3)         | -four\n",
+		"                   | +TWO\n",
+		"1 removed or changed line(s) were approved by Alice (1)\n",
+		"1 removed or changed line(s) were not approved\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in:\n%s", want, output)
+		}
+	}
+}
+
+func TestDiffBase(t *testing.T) {
+	tests := []struct {
+		revisions string
+		want      string
+	}{
+		{"v1.0..v2.0", "v1.0"},
+		{"..topic", "HEAD"},
+		{"main", "main"},
+	}
+	for _, tt := range tests {
+		if got, err := diffBase(context.Background(), ".", tt.revisions); err != nil || got != tt.want {
+			t.Errorf("diffBase(%q) = %q, %v; want %q", tt.revisions, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DigestReport lists unreviewed lines introduced since a baseline snapshot
+type DigestReport struct {
+	Repository         string            `json:"repository"`
+	Path               string            `json:"path"`
+	GeneratedAt        time.Time         `json:"generated_at"`
+	CurrentCommit      string            `json:"current_commit"`
+	BaselineAt         *time.Time        `json:"baseline_at,omitempty"`
+	BaselineCommit     string            `json:"baseline_commit,omitempty"`
+	Coverage           float64           `json:"coverage"`
+	NewUnreviewedLines int               `json:"new_unreviewed_lines"`
+	Directories        []DirectoryDigest `json:"directories"`
+}
+
+// DirectoryDigest groups newly unreviewed lines of one directory
+type DirectoryDigest struct {
+	Directory          string         `json:"directory"`
+	NewUnreviewedLines int            `json:"new_unreviewed_lines"`
+	Lines              []SnapshotLine `json:"lines"`
+}
+
+// digestLineKey identifies a line independently of its line number, which
+// shifts whenever code above it changes
+func digestLineKey(line SnapshotLine) string {
+	return line.Filename + "\x00" + line.CommitHash + "\x00" + line.Content
+}
+
+// BuildDigest compares current against baseline and reports unreviewed lines
+// that were not already unreviewed in the baseline. A nil baseline yields an
+// empty report, since there is nothing to compare against yet.
+func BuildDigest(repository string, baseline, current *AnnotationSnapshot) DigestReport {
+	report := DigestReport{
+		Repository:    repository,
+		Path:          current.Path,
+		GeneratedAt:   current.CreatedAt,
+		CurrentCommit: current.Commit,
+		Directories:   []DirectoryDigest{},
+	}
+
+	reviewed := 0
+	for _, line := range current.Lines {
+		if line.Approver != "" {
+			reviewed++
+		}
+	}
+	if len(current.Lines) > 0 {
+		report.Coverage = float64(reviewed) * 100 / float64(len(current.Lines))
+	}
+
+	if baseline == nil {
+		return report
+	}
+	baselineAt := baseline.CreatedAt
+	report.BaselineAt = &baselineAt
+	report.BaselineCommit = baseline.Commit
+
+	// Count known unreviewed lines as a multiset so duplicated lines are matched one to one
+	known := make(map[string]int)
+	for _, line := range baseline.Lines {
+		if line.Approver == "" {
+			known[digestLineKey(line)]++
+		}
+	}
+
+	byDirectory := make(map[string]*DirectoryDigest)
+	for _, line := range current.Lines {
+		if line.Approver != "" {
+			continue
+		}
+		key := digestLineKey(line)
+		if known[key] > 0 {
+			known[key]--
+			continue
+		}
+
+		dir := path.Dir(line.Filename)
+		digest, ok := byDirectory[dir]
+		if !ok {
+			digest = &DirectoryDigest{Directory: dir}
+			byDirectory[dir] = digest
+		}
+		digest.Lines = append(digest.Lines, line)
+		digest.NewUnreviewedLines++
+		report.NewUnreviewedLines++
+	}
+
+	for _, digest := range byDirectory {
+		report.Directories = append(report.Directories, *digest)
+	}
+	sort.Slice(report.Directories, func(i, j int) bool {
+		if report.Directories[i].NewUnreviewedLines != report.Directories[j].NewUnreviewedLines {
+			return report.Directories[i].NewUnreviewedLines > report.Directories[j].NewUnreviewedLines
+		}
+		return report.Directories[i].Directory < report.Directories[j].Directory
+	})
+
+	return report
+}
+
+// Markdown renders the digest as a Markdown report
+func (r DigestReport) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Review digest for %s (%s)\n\n", r.Repository, r.Path)
+	fmt.Fprintf(&b, "Generated %s at commit `%s`. Current review coverage: **%.1f%%**.\n\n",
+		r.GeneratedAt.Format("2006-01-02 15:04"), shortCommit(r.CurrentCommit), r.Coverage)
+
+	if r.BaselineAt == nil {
+		b.WriteString("No earlier snapshot was found; this run is stored as the baseline for the next digest.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Compared with the snapshot of %s at commit `%s`: **%d** newly unreviewed line(s).\n",
+		r.BaselineAt.Format("2006-01-02 15:04"), shortCommit(r.BaselineCommit), r.NewUnreviewedLines)
+
+	if len(r.Directories) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("\n| Directory | New unreviewed lines |\n|---|---|\n")
+	for _, dir := range r.Directories {
+		fmt.Fprintf(&b, "| `%s` | %d |\n", dir.Directory, dir.NewUnreviewedLines)
+	}
+
+	for _, dir := range r.Directories {
+		fmt.Fprintf(&b, "\n## %s\n\n", dir.Directory)
+		for _, line := range dir.Lines {
+			fmt.Fprintf(&b, "- `%s:%d` (%s, commit `%s`)\n", line.Filename, line.LineNumber, line.Author, shortCommit(line.CommitHash))
+		}
+	}
+
+	return b.String()
+}
+
+// shortCommit abbreviates a commit hash to 8 characters
+func shortCommit(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
+
+// ParseSinceDuration parses durations such as "7d", "2w" or "36h"
+func ParseSinceDuration(value string) (time.Duration, error) {
+	if len(value) > 1 {
+		unit := value[len(value)-1]
+		if unit == 'd' || unit == 'w' {
+			n, err := strconv.Atoi(value[:len(value)-1])
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			days := n
+			if unit == 'w' {
+				days = n * 7
+			}
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+// runDigest implements the digest subcommand
+func runDigest(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("digest", flag.ContinueOnError)
+	since := flags.String("since", "7d", "Compare against the latest snapshot at least this old (e.g. 7d, 2w, 36h)")
+	format := flags.String("format", "markdown", "Report format: markdown or json")
+	noSave := flags.Bool("no-save", false, "Do not store the current state as a new snapshot")
+	configPath := flags.String("config", "", "Path to the config file")
+	includeVendored := flags.Bool("include-vendored", false, "Include vendored files")
+	anonymize := flags.Bool("anonymize", false, "Replace names, logins and emails with stable pseudonyms")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+
+	if *format != "markdown" && *format != "json" {
+		return fmt.Errorf("unsupported digest format %q (expected markdown or json)", *format)
+	}
+
+	window, err := ParseSinceDuration(*since)
+	if err != nil {
+		return err
+	}
+
+	target := "."
+	if flags.NArg() > 0 {
+		target = flags.Arg(0)
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(target, *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{Anonymize: *anonymize}, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+
+	lines, err := annotatePath(ctx, repoRoot, target, "", BlameOptions{}, pipeline, *includeVendored)
+	if err != nil {
+		return err
+	}
+
+	head, err := ResolveRevision(repoRoot, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	store, err := NewSnapshotStore(repoRoot)
+	if err != nil {
+		return fmt.Errorf("could not open snapshot store: %w", err)
+	}
+
+	now := time.Now()
+	scope := displayPath(repoRoot, target)
+	current := NewAnnotationSnapshot(scope, head, lines, now)
+
+	baseline, err := store.Baseline(scope, now.Add(-window))
+	if err != nil {
+		return fmt.Errorf("could not read snapshots: %w", err)
+	}
+
+	report := BuildDigest(fmt.Sprintf("%s/%s", repoInfo.Owner, repoInfo.Name), baseline, current)
+
+	if !*noSave {
+		if err := store.Save(current); err != nil {
+			return fmt.Errorf("could not save snapshot: %w", err)
+		}
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(report.Markdown())
+	return nil
+}
@@ -0,0 +1,101 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildDigest(t *testing.T) {
+	baseline := &AnnotationSnapshot{
+		CreatedAt: time.Unix(1700000000, 0),
+		Commit:    "1111111111",
+		Path:      ".",
+		Lines: []SnapshotLine{
+			{Filename: "pkg/a.go", LineNumber: 1, CommitHash: "aaaa", Content: "old unreviewed"},
+			{Filename: "pkg/a.go", LineNumber: 2, CommitHash: "bbbb", Content: "reviewed", Approver: "jane"},
+		},
+	}
+	current := &AnnotationSnapshot{
+		CreatedAt: time.Unix(1700600000, 0),
+		Commit:    "2222222222",
+		Path:      ".",
+		Lines: []SnapshotLine{
+			{Filename: "pkg/a.go", LineNumber: 1, CommitHash: "cccc", Content: "new line", Author: "bob"},
+			// Shifted down by the new line, but already unreviewed in the baseline
+			{Filename: "pkg/a.go", LineNumber: 2, CommitHash: "aaaa", Content: "old unreviewed"},
+			{Filename: "pkg/a.go", LineNumber: 3, CommitHash: "bbbb", Content: "reviewed", Approver: "jane"},
+			{Filename: "cmd/main.go", LineNumber: 1, CommitHash: "cccc", Content: "a"},
+			{Filename: "cmd/main.go", LineNumber: 2, CommitHash: "cccc", Content: "b"},
+		},
+	}
+
+	report := BuildDigest("owner/repo", baseline, current)
+
+	if report.NewUnreviewedLines != 3 {
+		t.Errorf("expected 3 new unreviewed lines, got %d", report.NewUnreviewedLines)
+	}
+	if report.Coverage != 20 {
+		t.Errorf("expected coverage 20, got %f", report.Coverage)
+	}
+	if len(report.Directories) != 2 {
+		t.Fatalf("expected 2 directories, got %+v", report.Directories)
+	}
+	if report.Directories[0].Directory != "cmd" || report.Directories[0].NewUnreviewedLines != 2 {
+		t.Errorf("expected cmd first with 2 lines, got %+v", report.Directories[0])
+	}
+	if report.Directories[1].Directory != "pkg" || report.Directories[1].Lines[0].Content != "new line" {
+		t.Errorf("expected pkg with the new line, got %+v", report.Directories[1])
+	}
+
+	markdown := report.Markdown()
+	for _, expected := range []string{"# Review digest for owner/repo (.)", "**3** newly unreviewed", "| `cmd` | 2 |", "- `pkg/a.go:1` (bob, commit `cccc`)"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in markdown, got:\n%s", expected, markdown)
+		}
+	}
+}
+
+func TestBuildDigestWithoutBaseline(t *testing.T) {
+	current := &AnnotationSnapshot{
+		CreatedAt: time.Unix(1700600000, 0),
+		Path:      ".",
+		Lines:     []SnapshotLine{{Filename: "a.go", CommitHash: "cccc"}},
+	}
+
+	report := BuildDigest("owner/repo", nil, current)
+
+	if report.BaselineAt != nil || report.NewUnreviewedLines != 0 {
+		t.Errorf("expected empty report without baseline, got %+v", report)
+	}
+	if !strings.Contains(report.Markdown(), "No earlier snapshot") {
+		t.Errorf("expected baseline note in markdown, got:\n%s", report.Markdown())
+	}
+}
+
+func TestParseSinceDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{input: "7d", expected: 7 * 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "36h", expected: 36 * time.Hour},
+		{input: "xd", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		result, err := ParseSinceDuration(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSinceDuration(%q): expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil || result != tt.expected {
+			t.Errorf("ParseSinceDuration(%q) = %v, %v; expected %v", tt.input, result, err, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discussionMarkerPrefix tags discussions created by this tool so re-runs can find them
+const discussionMarkerPrefix = "<!-- git-review-blame:"
+
+// GitLabDiffRefs identifies the diff version a positioned discussion refers to
+type GitLabDiffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	HeadSHA  string `json:"head_sha"`
+	StartSHA string `json:"start_sha"`
+}
+
+// GitLabPosition positions a discussion on a line of the MR diff
+type GitLabPosition struct {
+	BaseSHA      string `json:"base_sha"`
+	StartSHA     string `json:"start_sha"`
+	HeadSHA      string `json:"head_sha"`
+	PositionType string `json:"position_type"`
+	OldPath      string `json:"old_path,omitempty"`
+	NewPath      string `json:"new_path"`
+	NewLine      int    `json:"new_line"`
+}
+
+// GitLabNote is a single note of a GitLab discussion
+type GitLabNote struct {
+	ID         int        `json:"id"`
+	Body       string     `json:"body"`
+	Resolvable bool       `json:"resolvable"`
+	Resolved   bool       `json:"resolved"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+// GitLabDiscussion is a GitLab MR discussion thread
+type GitLabDiscussion struct {
+	ID    string       `json:"id"`
+	Notes []GitLabNote `json:"notes"`
+}
+
+// MergeRequestFileDiff is the change of one file by a merge request
+type MergeRequestFileDiff struct {
+	OldPath string
+	// Added are the numbers, in the new file, of the lines the merge
+	// request adds
+	Added map[int]bool
+}
+
+// GitLabAPIError is a GitLab API response with an unexpected status
+type GitLabAPIError struct {
+	StatusCode int
+	Status     string
+	// Message is the start of the response body, GitLab's explanation
+	Message string
+}
+
+// Error implements error
+func (e *GitLabAPIError) Error() string {
+	return fmt.Sprintf("GitLab API error: %d %s", e.StatusCode, e.Status)
+}
+
+// isPositionError reports whether err is GitLab rejecting the position of
+// a discussion, which it answers with 400 and the invalid position or line
+// code; other failures, such as authentication or rate limits, are not
+func isPositionError(err error) bool {
+	var apiErr *GitLabAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	return strings.Contains(apiErr.Message, "position") || strings.Contains(apiErr.Message, "line_code")
+}
+
+// DiscussionResult summarizes what PostMRDiscussions did
+type DiscussionResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+// projectAPIURL returns the API URL for a path under the given project
+func (c *GitLabClient) projectAPIURL(owner, repo, path string) string {
+	projectPath := url.PathEscape(fmt.Sprintf("%s/%s", owner, repo))
+	return fmt.Sprintf("%s/projects/%s%s", c.baseURL, projectPath, path)
+}
+
+// doJSON sends an optional JSON payload and decodes the JSON response into result
+func (c *GitLabClient) doJSON(ctx context.Context, method, apiURL string, payload, result interface{}, expectedStatus int) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	resp, err := c.makeRequestWithBody(ctx, method, apiURL, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &GitLabAPIError{StatusCode: resp.StatusCode, Status: resp.Status, Message: string(message)}
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(newResponseReader(resp.Body)).Decode(result)
+}
+
+// GetMergeRequestDiffRefs gets the latest diff refs of a merge request
+func (c *GitLabClient) GetMergeRequestDiffRefs(ctx context.Context, owner, repo string, mrIID int) (*GitLabDiffRefs, error) {
+	var mr struct {
+		DiffRefs GitLabDiffRefs `json:"diff_refs"`
+	}
+	apiURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d", mrIID))
+	if err := c.doJSON(ctx, "GET", apiURL, nil, &mr, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &mr.DiffRefs, nil
+}
+
+// GetMergeRequestDiffs returns the changes of a merge request by the new
+// path of each file. A file whose diff GitLab left out as too large has no
+// added lines.
+func (c *GitLabClient) GetMergeRequestDiffs(ctx context.Context, owner, repo string, mrIID int) (map[string]*MergeRequestFileDiff, error) {
+	files := make(map[string]*MergeRequestFileDiff)
+	apiURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/diffs", mrIID))
+	err := c.listPages(ctx, apiURL, func(dec *json.Decoder) error {
+		var diff struct {
+			OldPath string `json:"old_path"`
+			NewPath string `json:"new_path"`
+			Diff    string `json:"diff"`
+		}
+		if err := dec.Decode(&diff); err != nil {
+			return err
+		}
+		lines, err := ParseUnifiedDiff(strings.NewReader(diff.Diff))
+		if err != nil {
+			return err
+		}
+		file := &MergeRequestFileDiff{OldPath: diff.OldPath, Added: make(map[int]bool)}
+		for _, line := range lines {
+			if line.NewLine > 0 {
+				file.Added[line.NewLine] = true
+			}
+		}
+		files[diff.NewPath] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ListMergeRequestDiscussions lists the discussions of a merge request
+func (c *GitLabClient) ListMergeRequestDiscussions(ctx context.Context, owner, repo string, mrIID int) ([]GitLabDiscussion, error) {
+	var discussions []GitLabDiscussion
+	apiURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/discussions", mrIID))
+	err := c.listPages(ctx, apiURL, func(dec *json.Decoder) error {
+		var discussion GitLabDiscussion
+		if err := dec.Decode(&discussion); err != nil {
+			return err
+		}
+		discussions = append(discussions, discussion)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return discussions, nil
+}
+
+// CreateMergeRequestDiscussion starts a discussion, positioned on a diff line when position is set
+func (c *GitLabClient) CreateMergeRequestDiscussion(ctx context.Context, owner, repo string, mrIID int, body string, position *GitLabPosition) error {
+	payload := map[string]interface{}{"body": body}
+	if position != nil {
+		payload["position"] = position
+	}
+	apiURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/discussions", mrIID))
+	return c.doJSON(ctx, "POST", apiURL, payload, nil, http.StatusCreated)
+}
+
+// UpdateMergeRequestNote replaces the body of a note in a discussion
+func (c *GitLabClient) UpdateMergeRequestNote(ctx context.Context, owner, repo string, mrIID int, discussionID string, noteID int, body string) error {
+	apiURL := c.projectAPIURL(owner, repo,
+		fmt.Sprintf("/merge_requests/%d/discussions/%s/notes/%d", mrIID, discussionID, noteID))
+	return c.doJSON(ctx, "PUT", apiURL, map[string]string{"body": body}, nil, http.StatusOK)
+}
+
+// discussionMarker returns the hidden marker identifying the finding for a
+// range by its file, commit and lines, so findings added or removed
+// elsewhere do not change the markers of the others
+func discussionMarker(r UnreviewedRange) string {
+	return fmt.Sprintf("%s%s:%s:%d-%d -->", discussionMarkerPrefix, r.Filename, r.CommitHash, r.StartLine, r.EndLine)
+}
+
+// touchedRanges returns the parts of an unreviewed range the merge request
+// adds, split where it keeps lines it did not touch
+func touchedRanges(r UnreviewedRange, added map[int]bool) []UnreviewedRange {
+	var touched []UnreviewedRange
+	for line := r.StartLine; line <= r.EndLine; line++ {
+		if !added[line] {
+			continue
+		}
+		if n := len(touched); n > 0 && touched[n-1].EndLine == line-1 {
+			touched[n-1].EndLine = line
+			continue
+		}
+		part := r
+		part.StartLine, part.EndLine = line, line
+		touched = append(touched, part)
+	}
+	return touched
+}
+
+// discussionBody renders the discussion text for an unreviewed range
+func discussionBody(r UnreviewedRange) string {
+	lines := fmt.Sprintf("line %d", r.StartLine)
+	if r.EndLine != r.StartLine {
+		lines = fmt.Sprintf("lines %d-%d", r.StartLine, r.EndLine)
+	}
+	return fmt.Sprintf("%s\n**Unreviewed code touched** in `%s` (%s)\n\n%s",
+		discussionMarker(r), r.Filename, lines, r.Message())
+}
+
+// DetectMergeRequestIID reads the merge request IID from the GitLab CI environment
+func DetectMergeRequestIID() (int, error) {
+	value := os.Getenv("CI_MERGE_REQUEST_IID")
+	if value == "" {
+		return 0, errors.New("CI_MERGE_REQUEST_IID is not set; discussions can only be posted from merge request pipelines")
+	}
+	return strconv.Atoi(value)
+}
+
+// PostMRDiscussions posts one discussion per unreviewed range of lines the
+// merge request adds, positioned on its first line. Existing discussions
+// carrying the same marker are updated in place, and resolved ones are left
+// alone.
+func (c *GitLabClient) PostMRDiscussions(ctx context.Context, owner, repo string, mrIID int, lines []BlameLineWithApproval) (*DiscussionResult, error) {
+	diffRefs, err := c.GetMergeRequestDiffRefs(ctx, owner, repo, mrIID)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs, err := c.GetMergeRequestDiffs(ctx, owner, repo, mrIID)
+	if err != nil {
+		return nil, err
+	}
+
+	discussions, err := c.ListMergeRequestDiscussions(ctx, owner, repo, mrIID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]GitLabDiscussion)
+	for _, discussion := range discussions {
+		if len(discussion.Notes) == 0 {
+			continue
+		}
+		body := discussion.Notes[0].Body
+		if strings.HasPrefix(body, discussionMarkerPrefix) {
+			marker := body
+			if end := strings.Index(body, "-->"); end >= 0 {
+				marker = body[:end+len("-->")]
+			}
+			existing[marker] = discussion
+		}
+	}
+
+	var touched []UnreviewedRange
+	for _, r := range FindUnreviewedRanges(lines) {
+		if diff, ok := diffs[r.Filename]; ok {
+			touched = append(touched, touchedRanges(r, diff.Added)...)
+		}
+	}
+
+	result := &DiscussionResult{}
+	for _, r := range touched {
+		body := discussionBody(r)
+		if discussion, ok := existing[discussionMarker(r)]; ok {
+			note := discussion.Notes[0]
+			if note.Resolved || note.Body == body {
+				result.Unchanged++
+				continue
+			}
+			if err := c.UpdateMergeRequestNote(ctx, owner, repo, mrIID, discussion.ID, note.ID, body); err != nil {
+				return result, err
+			}
+			result.Updated++
+			continue
+		}
+
+		position := &GitLabPosition{
+			BaseSHA:      diffRefs.BaseSHA,
+			StartSHA:     diffRefs.StartSHA,
+			HeadSHA:      diffRefs.HeadSHA,
+			PositionType: "text",
+			OldPath:      diffs[r.Filename].OldPath,
+			NewPath:      r.Filename,
+			NewLine:      r.StartLine,
+		}
+		if err := c.CreateMergeRequestDiscussion(ctx, owner, repo, mrIID, body, position); err != nil {
+			// GitLab rejects positions when the diff changed since its refs
+			// were read; post an unpositioned thread instead so the finding
+			// is not lost. Any other failure would fail again.
+			if !isPositionError(err) {
+				return result, err
+			}
+			if err := c.CreateMergeRequestDiscussion(ctx, owner, repo, mrIID, body, nil); err != nil {
+				return result, err
+			}
+		}
+		result.Created++
+	}
+
+	return result, nil
+}
+
+// PublishMRDiscussions posts unreviewed-code findings to the current GitLab CI merge request
+func PublishMRDiscussions(ctx context.Context, repoInfo *RepoInfo, gitlabToken string, lines []BlameLineWithApproval) (*DiscussionResult, error) {
+	if repoInfo.Type != RepositoryTypeGitLab {
+		return nil, fmt.Errorf("discussions can only be posted to GitLab merge requests, not %s", repoInfo.Type)
+	}
+	if gitlabToken == "" {
+		return nil, ErrMissingGitLabToken
+	}
+
+	mrIID, err := DetectMergeRequestIID()
+	if err != nil {
+		return nil, err
+	}
+
+	return newGitLabClientForRepo(gitlabToken, repoInfo).PostMRDiscussions(ctx, repoInfo.Owner, repoInfo.Name, mrIID, lines)
+}
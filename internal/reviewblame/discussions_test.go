@@ -0,0 +1,186 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// positionRejection is GitLab's answer to a position outside the diff
+const positionRejection = `{"message":"400 Bad request - Note {:line_code=>[\"can't be blank\", \"must be a valid line code\"]}"}`
+
+// newDiscussionServer serves a merge request adding lines 1-3 of main.go
+// and untouched lines elsewhere, with the given discussions; create answers
+// discussion creations
+func newDiscussionServer(t *testing.T, discussions []GitLabDiscussion, create func(w http.ResponseWriter, payload map[string]interface{})) (*GitLabClient, *[]string) {
+	var updated []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "/projects/owner%2Frepo/merge_requests/5"
+		switch {
+		case r.Method == "GET" && r.URL.EscapedPath() == base:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"diff_refs": map[string]string{"base_sha": "b", "head_sha": "h", "start_sha": "s"},
+			})
+		case r.Method == "GET" && r.URL.EscapedPath() == base+"/diffs":
+			json.NewEncoder(w).Encode([]map[string]string{
+				{"old_path": "main.go", "new_path": "main.go", "diff": "@@ -1,2 +1,5 @@\n+one\n+two\n+three\n four\n-old\n+five\n"},
+				{"old_path": "other.go", "new_path": "other.go", "diff": "@@ -10,1 +10,1 @@\n-x\n+y\n"},
+			})
+		case r.Method == "GET" && r.URL.EscapedPath() == base+"/discussions":
+			json.NewEncoder(w).Encode(discussions)
+		case r.Method == "POST" && r.URL.EscapedPath() == base+"/discussions":
+			var payload map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			create(w, payload)
+		case r.Method == "PUT":
+			updated = append(updated, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+	return client, &updated
+}
+
+func TestPostMRDiscussions(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: 1, Author: "John"}},
+		{BlameLine: BlameLine{CommitHash: "bbbbbbbbbb", Filename: "main.go", LineNumber: 2, Author: "Bob"}},
+		{BlameLine: BlameLine{CommitHash: "cccccccccc", Filename: "main.go", LineNumber: 3, Author: "Eve"}},
+		{BlameLine: BlameLine{CommitHash: "dddddddddd", Filename: "untouched.go", LineNumber: 1, Author: "Eve"}},
+	}
+	ranges := FindUnreviewedRanges(lines)
+	discussions := []GitLabDiscussion{
+		{ID: "d1", Notes: []GitLabNote{{ID: 11, Body: discussionMarker(ranges[0]) + "\nold text"}}},
+		{ID: "d2", Notes: []GitLabNote{{ID: 12, Body: discussionMarker(ranges[2]) + "\nold text", Resolved: true}}},
+		{ID: "d3", Notes: []GitLabNote{{ID: 13, Body: "human comment"}}},
+	}
+
+	var created []map[string]interface{}
+	client, updated := newDiscussionServer(t, discussions, func(w http.ResponseWriter, payload map[string]interface{}) {
+		if _, positioned := payload["position"]; positioned {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(positionRejection))
+			return
+		}
+		created = append(created, payload)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	result, err := client.PostMRDiscussions(context.Background(), "owner", "repo", 5, lines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Created != 1 || result.Updated != 1 || result.Unchanged != 1 {
+		t.Errorf("expected 1 created, 1 updated, 1 unchanged, got %+v", result)
+	}
+	if len(*updated) != 1 || (*updated)[0] != "/projects/owner%2Frepo/merge_requests/5/discussions/d1/notes/11" {
+		t.Errorf("unexpected updates %v", *updated)
+	}
+	if len(created) != 1 || !strings.Contains(created[0]["body"].(string), "bbbbbbbb") {
+		t.Errorf("expected fallback discussion for commit bbbbbbbb, got %v", created)
+	}
+}
+
+func TestPostMRDiscussionsOnlyTouchedLines(t *testing.T) {
+	// Lines 1-5 of main.go are unreviewed, but the merge request only adds
+	// lines 1-3 and 5; line 4 and other.go are kept as they were
+	var lines []BlameLineWithApproval
+	for number := 1; number <= 5; number++ {
+		lines = append(lines, BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: number, Author: "John"}})
+	}
+	lines = append(lines, BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "bbbbbbbbbb", Filename: "other.go", LineNumber: 3, Author: "Bob"}})
+
+	var created []map[string]interface{}
+	client, _ := newDiscussionServer(t, nil, func(w http.ResponseWriter, payload map[string]interface{}) {
+		created = append(created, payload)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	result, err := client.PostMRDiscussions(context.Background(), "owner", "repo", 5, lines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Created != 2 || len(created) != 2 {
+		t.Fatalf("expected discussions for lines 1-3 and 5, got %+v: %v", result, created)
+	}
+	if body := created[0]["body"].(string); !strings.Contains(body, "(lines 1-3)") {
+		t.Errorf("expected the first discussion on lines 1-3, got %q", body)
+	}
+	if body := created[1]["body"].(string); !strings.Contains(body, "(line 5)") {
+		t.Errorf("expected the second discussion on line 5, got %q", body)
+	}
+	if position := created[1]["position"].(map[string]interface{}); position["new_line"] != float64(5) {
+		t.Errorf("expected the second discussion positioned on line 5, got %v", position)
+	}
+}
+
+func TestPostMRDiscussionsCreateFailure(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: 1, Author: "John"}},
+	}
+
+	attempts := 0
+	client, _ := newDiscussionServer(t, nil, func(w http.ResponseWriter, payload map[string]interface{}) {
+		attempts++
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	if _, err := client.PostMRDiscussions(context.Background(), "owner", "repo", 5, lines); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected the authentication error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected no unpositioned retry after an authentication error, got %d attempts", attempts)
+	}
+}
+
+func TestDiscussionBody(t *testing.T) {
+	r := UnreviewedRange{Filename: "main.go", CommitHash: "aaaaaaaaaaaa", Author: "John", StartLine: 3, EndLine: 5}
+
+	body := discussionBody(r)
+
+	if !strings.HasPrefix(body, "<!-- git-review-blame:main.go:aaaaaaaaaaaa:3-5 -->\n") {
+		t.Errorf("expected marker on first line, got %q", body)
+	}
+	if !strings.Contains(body, "(lines 3-5)") {
+		t.Errorf("expected line range in body, got %q", body)
+	}
+}
+
+func TestPostMRDiscussionsSeparatesRanges(t *testing.T) {
+	// One commit leaves two ranges in main.go, split by a reviewed line
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: 1, Author: "John"}},
+		{BlameLine: BlameLine{CommitHash: "bbbbbbbbbb", Filename: "main.go", LineNumber: 2, Author: "Bob"}, Approver: "alice"},
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Filename: "main.go", LineNumber: 3, Author: "John"}},
+	}
+	// The second range is already posted; a new finding before it must not
+	// re-key it
+	secondBody := discussionBody(FindUnreviewedRanges(lines)[1])
+
+	var created []string
+	client, _ := newDiscussionServer(t, []GitLabDiscussion{{ID: "d1", Notes: []GitLabNote{{ID: 11, Body: secondBody}}}}, func(w http.ResponseWriter, payload map[string]interface{}) {
+		created = append(created, payload["body"].(string))
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	result, err := client.PostMRDiscussions(context.Background(), "owner", "repo", 5, lines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Unchanged != 1 || result.Created != 1 {
+		t.Errorf("expected the first range to get its own discussion, got %+v", result)
+	}
+	if len(created) != 1 || !strings.Contains(created[0], "(line 1)") {
+		t.Errorf("expected a discussion for line 1, got %v", created)
+	}
+}
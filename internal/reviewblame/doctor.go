@@ -0,0 +1,498 @@
+package reviewblame
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// minimumGitVersion is the oldest git release whose blame and check-attr
+// options we rely on
+var minimumGitVersion = [2]int{2, 20}
+
+// DoctorResult is the outcome of one diagnostic check
+type DoctorResult struct {
+	Check  string
+	Passed bool
+	Detail string
+	// Hint explains how to fix a failed check
+	Hint string
+}
+
+// String formats the result as a pass/fail line, followed by the hint on failure
+func (r DoctorResult) String() string {
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	line := fmt.Sprintf("[%s] %s: %s", status, r.Check, r.Detail)
+	if !r.Passed && r.Hint != "" {
+		line += "\n       hint: " + r.Hint
+	}
+	return line
+}
+
+// Doctor diagnoses the environment of a repository: git, remote detection,
+// tokens, API and repository access and the snapshot store
+type Doctor struct {
+	path        string
+	githubToken string
+	gitlabToken string
+	// bitbucketToken and giteaToken are read from BITBUCKET_TOKEN and
+	// GITEA_TOKEN
+	bitbucketToken string
+	giteaToken     string
+	// newGitHubClient and newGitLabClient create the clients used for API
+	// checks; tests point them at fake servers
+	newGitHubClient func(token string, repoInfo *RepoInfo) *GitHubClient
+	newGitLabClient func(token string, repoInfo *RepoInfo) *GitLabClient
+}
+
+// NewDoctor creates a doctor for the repository containing path
+func NewDoctor(path, githubToken, gitlabToken string) *Doctor {
+	return &Doctor{
+		path:            path,
+		githubToken:     githubToken,
+		gitlabToken:     gitlabToken,
+		bitbucketToken:  os.Getenv(BitbucketTokenEnv),
+		giteaToken:      os.Getenv(GiteaTokenEnv),
+		newGitHubClient: newGitHubClientForRepo,
+		newGitLabClient: newGitLabClientForRepo,
+	}
+}
+
+// Run performs all checks. Checks that depend on a failed one are skipped.
+func (d *Doctor) Run(ctx context.Context) []DoctorResult {
+	results := []DoctorResult{checkGitVersion()}
+
+	repoRoot, err := FindGitRoot(d.path)
+	if err != nil {
+		return append(results, DoctorResult{
+			Check:  "repository",
+			Detail: fmt.Sprintf("%s is not inside a git repository", d.path),
+			Hint:   "run git-review-blame from a clone of the repository, or pass a path inside it",
+		})
+	}
+	results = append(results, DoctorResult{Check: "repository", Passed: true, Detail: repoRoot})
+
+	// The config file is checked on its own below
+	remote := DetectRemote(repoRoot)
+	config, err := LoadConfig(repoRoot, "")
+	if err != nil {
+		config = &Config{}
+	}
+	if config.Remote != "" {
+		remote = config.Remote
+	}
+	repoInfo, err := DetectRepoInfo(repoRoot, remote)
+	if err != nil {
+		detail := err.Error()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			detail = "the repository has no remote named " + remote
+		}
+		return append(results, DoctorResult{
+			Check:  "remote",
+			Detail: detail,
+			Hint:   "add a GitHub or GitLab remote named origin, e.g. git remote add origin https://github.com/owner/repo.git",
+		})
+	}
+	applyHostConfig(repoInfo, config.Hosts)
+	results = append(results, DoctorResult{
+		Check:  "remote",
+		Passed: true,
+		Detail: fmt.Sprintf("%s repository %s/%s on %s", repoInfo.Type, repoInfo.Owner, repoInfo.Name, repoInfo.Host),
+	})
+
+	results = append(results, checkConfig(repoRoot))
+
+	if repoInfo.Type == RepositoryTypePlugin {
+		// Plugins bring their own credentials
+		results = append(results, checkPlugin(repoInfo))
+		return append(results, checkSnapshotStore(repoRoot))
+	}
+
+	if repoInfo.Type == RepositoryTypeGerrit {
+		// Public Gerrit changes need no credentials
+		results = append(results, DoctorResult{Check: "token", Passed: true, Detail: "Gerrit changes are read anonymously unless GERRIT_USER and GERRIT_TOKEN are set"})
+		return append(results, checkSnapshotStore(repoRoot))
+	}
+
+	// GitHub and GitLab tokens may also come from gh, glab or a git
+	// credential helper
+	tokenSource := TokenSourceEnv
+	switch repoInfo.Type {
+	case RepositoryTypeGitHub:
+		d.githubToken, tokenSource = (&TokenResolver{}).Resolve(ctx, repoRoot, repoInfo, d.githubToken)
+	case RepositoryTypeGitLab:
+		d.gitlabToken, tokenSource = (&TokenResolver{}).Resolve(ctx, repoRoot, repoInfo, d.gitlabToken)
+	}
+
+	token, tokenVariable, cli := d.githubToken, "GITHUB_TOKEN", "gh"
+	switch repoInfo.Type {
+	case RepositoryTypeGitLab:
+		token, tokenVariable, cli = d.gitlabToken, "GITLAB_TOKEN", "glab"
+	case RepositoryTypeBitbucket:
+		token, tokenVariable = d.bitbucketToken, BitbucketTokenEnv
+	case RepositoryTypeGitea:
+		token, tokenVariable = d.giteaToken, GiteaTokenEnv
+	}
+	if token == "" {
+		hint := fmt.Sprintf("export %s with a token for %s (see API Tokens in the README)", tokenVariable, repoInfo.Host)
+		if repoInfo.Type == RepositoryTypeGitHub || repoInfo.Type == RepositoryTypeGitLab {
+			hint = fmt.Sprintf("export %s with a token for %s, or log in with %s auth login (see API Tokens in the README)", tokenVariable, repoInfo.Host, cli)
+		}
+		results = append(results, DoctorResult{
+			Check:  "token",
+			Detail: tokenVariable + " is not set; only PR/MR numbers from commit messages can be shown",
+			Hint:   hint,
+		})
+	} else {
+		detail := tokenVariable + " is set"
+		switch tokenSource {
+		case TokenSourceKeyring:
+			detail = "using the token stored with auth login"
+		case TokenSourceCLI:
+			detail = "using the token " + cli + " is logged in with"
+		case TokenSourceGitCredential:
+			detail = "using the git credential helper's password for " + repoInfo.Host
+		}
+		results = append(results, DoctorResult{Check: "token", Passed: true, Detail: detail})
+		switch repoInfo.Type {
+		case RepositoryTypeGitLab:
+			results = append(results, d.checkGitLabAPI(ctx, repoInfo)...)
+		case RepositoryTypeGitHub:
+			results = append(results, d.checkGitHubAPI(ctx, repoInfo)...)
+		}
+	}
+
+	return append(results, checkSnapshotStore(repoRoot))
+}
+
+// checkPlugin checks that the provider plugin of a repository can be run
+func checkPlugin(repoInfo *RepoInfo) DoctorResult {
+	result := DoctorResult{Check: "provider plugin"}
+	if repoInfo.Plugin == "" {
+		result.Detail = "no plugin is configured for " + repoInfo.Host
+		result.Hint = `name the plugin in the hosts entry of the config file, e.g. "plugin": "corp-review"`
+		return result
+	}
+	command := pluginCommand(repoInfo.Plugin)
+	path, err := exec.LookPath(command)
+	if err != nil {
+		result.Detail = command + " is not an executable on PATH"
+		result.Hint = "install the plugin, or set plugin to its path"
+		return result
+	}
+	result.Passed = true
+	result.Detail = path
+	return result
+}
+
+// checkGitVersion checks that git is installed and recent enough
+func checkGitVersion() DoctorResult {
+	result := DoctorResult{Check: "git version"}
+	output, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		result.Detail = "git is not installed or not on PATH"
+		result.Hint = "install git 2.20 or later"
+		return result
+	}
+
+	version := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(output)), "git version"))
+	result.Detail = version
+	major, minor, ok := parseGitVersion(version)
+	if !ok {
+		result.Passed = true
+		result.Detail += " (unrecognized version format, assuming it is recent enough)"
+		return result
+	}
+	if major > minimumGitVersion[0] || major == minimumGitVersion[0] && minor >= minimumGitVersion[1] {
+		result.Passed = true
+		return result
+	}
+	result.Hint = fmt.Sprintf("upgrade git to %d.%d or later", minimumGitVersion[0], minimumGitVersion[1])
+	return result
+}
+
+// parseGitVersion extracts the major and minor version from e.g. "2.39.5" or
+// "2.39.3 (Apple Git-146)"
+func parseGitVersion(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.Fields(version + " ")[0], ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// checkConfig checks that the config file, if any, loads
+func checkConfig(repoRoot string) DoctorResult {
+	result := DoctorResult{Check: "config"}
+	if _, err := LoadConfig(repoRoot, ""); err != nil {
+		result.Detail = err.Error()
+		result.Hint = "fix or remove " + DefaultConfigFile
+		return result
+	}
+	result.Passed = true
+	if _, err := os.Stat(filepath.Join(repoRoot, DefaultConfigFile)); err == nil {
+		result.Detail = DefaultConfigFile + " is valid"
+	} else {
+		result.Detail = "no " + DefaultConfigFile + ", using defaults"
+	}
+	return result
+}
+
+// connectionDetail describes how a request reached the server: proxy and TLS
+func connectionDetail(req *http.Request, resp *http.Response) string {
+	route := "direct"
+	if proxy, err := http.ProxyFromEnvironment(req); err == nil && proxy != nil {
+		route = "via proxy " + proxy.Redacted()
+	}
+	if resp == nil || resp.TLS == nil {
+		return route
+	}
+	detail := route + ", " + tls.VersionName(resp.TLS.Version)
+	if certificates := resp.TLS.PeerCertificates; len(certificates) > 0 {
+		detail += ", certificate issued by " + certificates[0].Issuer.CommonName
+	}
+	return detail
+}
+
+// reachabilityFailure explains a failed API request
+func reachabilityFailure(apiURL string, req *http.Request, err error) DoctorResult {
+	result := DoctorResult{
+		Check:  "API reachability",
+		Detail: fmt.Sprintf("%s (%s): %v", apiURL, connectionDetail(req, nil), err),
+		Hint:   "check network access to the API host and the HTTPS_PROXY/NO_PROXY variables",
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) {
+		result.Hint = "the server certificate is not trusted; point SSL_CERT_FILE at your organization's CA bundle"
+	}
+	return result
+}
+
+// authenticationFailure explains an API response that is not 200 OK
+func authenticationFailure(resp *http.Response, tokenVariable string) DoctorResult {
+	result := DoctorResult{Check: "authentication", Detail: "API returned " + resp.Status}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		result.Hint = tokenVariable + " is invalid or expired; create a new token"
+	case http.StatusForbidden:
+		result.Hint = "the token is valid but lacks access; it may need SSO authorization or more scopes"
+		if url := ssoAuthorizationURL(resp); url != "" {
+			result.Hint = "the organization requires SAML SSO; authorize the token at " + url
+		}
+	default:
+		result.Hint = "retry later; the API may be unavailable"
+	}
+	return result
+}
+
+// checkGitHubAPI checks reachability, authentication and token scopes on GitHub
+func (d *Doctor) checkGitHubAPI(ctx context.Context, repoInfo *RepoInfo) []DoctorResult {
+	client := d.newGitHubClient(d.githubToken, repoInfo)
+	apiURL := client.baseURL + "/user"
+	req, _ := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+
+	resp, err := client.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return []DoctorResult{reachabilityFailure(client.baseURL, req, err)}
+	}
+	defer resp.Body.Close()
+
+	results := []DoctorResult{{
+		Check:  "API reachability",
+		Passed: true,
+		Detail: fmt.Sprintf("%s (%s)", client.baseURL, connectionDetail(req, resp)),
+	}}
+	if resp.StatusCode != http.StatusOK {
+		return append(results, authenticationFailure(resp, "GITHUB_TOKEN"))
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	json.NewDecoder(newResponseReader(resp.Body)).Decode(&user)
+	results = append(results, DoctorResult{Check: "authentication", Passed: true, Detail: "authenticated as " + user.Login})
+
+	// Classic tokens report their scopes; fine-grained tokens and app tokens do not
+	scopesHeader, classic := resp.Header["X-Oauth-Scopes"]
+	if !classic {
+		results = append(results, DoctorResult{Check: "token scopes", Passed: true, Detail: "fine-grained or app token; make sure it can read pull requests and contents"})
+		return append(results, repositoryAccessResult(ctx, client, repoInfo))
+	}
+	scopes := splitScopes(strings.Join(scopesHeader, ","))
+	if scopes["repo"] || scopes["public_repo"] {
+		results = append(results, DoctorResult{Check: "token scopes", Passed: true, Detail: strings.Join(scopesHeader, ",")})
+		return append(results, repositoryAccessResult(ctx, client, repoInfo))
+	}
+	return append(results, DoctorResult{
+		Check:  "token scopes",
+		Detail: fmt.Sprintf("scopes %q grant no repository access", strings.Join(scopesHeader, ",")),
+		Hint:   "grant the repo scope (or public_repo for public repositories only)",
+	})
+}
+
+// repositoryAccessResult checks that the token can read the repository,
+// which also catches organizations enforcing SSO
+func repositoryAccessResult(ctx context.Context, checker RepositoryAccessChecker, repoInfo *RepoInfo) DoctorResult {
+	result := DoctorResult{Check: "repository access", Passed: true, Detail: fmt.Sprintf("%s/%s is readable", repoInfo.Owner, repoInfo.Name)}
+	var accessErr *AccessError
+	if err := checker.CheckRepositoryAccess(ctx, repoInfo.Owner, repoInfo.Name); errors.As(err, &accessErr) {
+		result.Passed, result.Detail, result.Hint = false, accessErr.Problem, accessErr.Hint
+	}
+	return result
+}
+
+// checkGitLabAPI checks reachability, authentication and token scopes on GitLab
+func (d *Doctor) checkGitLabAPI(ctx context.Context, repoInfo *RepoInfo) []DoctorResult {
+	client := d.newGitLabClient(d.gitlabToken, repoInfo)
+	apiURL := client.baseURL + "/personal_access_tokens/self"
+	req, _ := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+
+	resp, err := client.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return []DoctorResult{reachabilityFailure(client.baseURL, req, err)}
+	}
+	defer resp.Body.Close()
+
+	results := []DoctorResult{{
+		Check:  "API reachability",
+		Passed: true,
+		Detail: fmt.Sprintf("%s (%s)", client.baseURL, connectionDetail(req, resp)),
+	}}
+	if resp.StatusCode == http.StatusNotFound {
+		// Instances before GitLab 15.5, or project and group tokens
+		results = append(results, DoctorResult{Check: "authentication", Passed: true, Detail: "token accepted; its scopes cannot be inspected on this instance"})
+		return append(results, repositoryAccessResult(ctx, client, repoInfo))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return append(results, authenticationFailure(resp, "GITLAB_TOKEN"))
+	}
+
+	var token struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	json.NewDecoder(newResponseReader(resp.Body)).Decode(&token)
+	results = append(results, DoctorResult{Check: "authentication", Passed: true, Detail: fmt.Sprintf("token %q is active", token.Name)})
+
+	scopes := splitScopes(strings.Join(token.Scopes, ","))
+	if scopes["api"] || scopes["read_api"] {
+		results = append(results, DoctorResult{Check: "token scopes", Passed: true, Detail: strings.Join(token.Scopes, ",")})
+		return append(results, repositoryAccessResult(ctx, client, repoInfo))
+	}
+	return append(results, DoctorResult{
+		Check:  "token scopes",
+		Detail: fmt.Sprintf("scopes %q do not allow API reads", strings.Join(token.Scopes, ",")),
+		Hint:   "create a token with the read_api scope (api is needed for -post-discussions)",
+	})
+}
+
+// splitScopes parses a comma-separated scope list
+func splitScopes(list string) map[string]bool {
+	scopes := make(map[string]bool)
+	for _, scope := range strings.Split(list, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes[scope] = true
+		}
+	}
+	return scopes
+}
+
+// checkSnapshotStore checks that stored snapshots are readable and the store is writable
+func checkSnapshotStore(repoRoot string) DoctorResult {
+	result := DoctorResult{Check: "snapshot store"}
+	store, err := NewSnapshotStore(repoRoot)
+	if err != nil {
+		result.Detail = err.Error()
+		result.Hint = "make sure the git directory is accessible"
+		return result
+	}
+
+	entries, err := os.ReadDir(store.dir)
+	if os.IsNotExist(err) {
+		result.Passed = true
+		result.Detail = "no snapshots stored yet"
+		return result
+	}
+	if err != nil {
+		result.Detail = err.Error()
+		result.Hint = "check the permissions of " + store.dir
+		return result
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(store.dir, entry.Name()))
+		var snapshot AnnotationSnapshot
+		if err == nil {
+			err = json.Unmarshal(data, &snapshot)
+		}
+		if err != nil {
+			result.Detail = fmt.Sprintf("snapshot %s is unreadable: %v", entry.Name(), err)
+			result.Hint = "delete the file; digest will start a new baseline"
+			return result
+		}
+		count++
+	}
+
+	probe, err := os.CreateTemp(store.dir, ".doctor-*")
+	if err != nil {
+		result.Detail = fmt.Sprintf("%s is not writable: %v", store.dir, err)
+		result.Hint = "check the permissions of " + store.dir
+		return result
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	result.Passed = true
+	result.Detail = fmt.Sprintf("%d snapshot(s) in %s", count, store.dir)
+	return result
+}
+
+// runDoctor implements the doctor subcommand
+func runDoctor(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path := "."
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+
+	failed := 0
+	for _, result := range NewDoctor(path, githubToken, gitlabToken).Run(ctx) {
+		fmt.Println(result)
+		if !result.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
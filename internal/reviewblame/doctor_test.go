@@ -0,0 +1,217 @@
+package reviewblame
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		version      string
+		major, minor int
+		ok           bool
+	}{
+		{"2.39.5", 2, 39, true},
+		{"2.39.3 (Apple Git-146)", 2, 39, true},
+		{"2.45.1.windows.1", 2, 45, true},
+		{"unknown", 0, 0, false},
+	}
+	for _, tt := range tests {
+		major, minor, ok := parseGitVersion(tt.version)
+		if major != tt.major || minor != tt.minor || ok != tt.ok {
+			t.Errorf("parseGitVersion(%q) = %d, %d, %v", tt.version, major, minor, ok)
+		}
+	}
+}
+
+// newDoctorTestRepo creates a repository with an origin remote on host
+func newDoctorTestRepo(t *testing.T, remote string) string {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", remote)
+	return dir
+}
+
+// checkNames returns "check=PASS|FAIL" for each result
+func checkNames(results []DoctorResult) string {
+	var names []string
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		names = append(names, result.Check+"="+status)
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestDoctorGitHub(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		scopes     []string
+		repoStatus int
+		sso        string
+		want       string
+	}{
+		{"classic token", http.StatusOK, []string{"repo, read:org"}, http.StatusOK, "", "authentication=PASS, token scopes=PASS, repository access=PASS"},
+		{"missing repo scope", http.StatusOK, []string{"read:org"}, http.StatusOK, "", "authentication=PASS, token scopes=FAIL"},
+		{"fine-grained token", http.StatusOK, nil, http.StatusOK, "", "authentication=PASS, token scopes=PASS, repository access=PASS"},
+		{"bad token", http.StatusUnauthorized, nil, http.StatusOK, "", "authentication=FAIL"},
+		{"repository not visible", http.StatusOK, nil, http.StatusNotFound, "", "authentication=PASS, token scopes=PASS, repository access=FAIL"},
+		{"SSO not authorized", http.StatusOK, nil, http.StatusForbidden, "required; url=https://github.com/orgs/owner/sso?authorization_request=1", "authentication=PASS, token scopes=PASS, repository access=FAIL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer test-token" {
+					t.Errorf("unexpected token for %s", r.URL.Path)
+				}
+				for _, scopes := range tt.scopes {
+					w.Header().Add("X-OAuth-Scopes", scopes)
+				}
+				switch r.URL.Path {
+				case "/user":
+					w.WriteHeader(tt.status)
+					w.Write([]byte(`{"login": "alice"}`))
+				case "/repos/owner/repo":
+					if tt.sso != "" {
+						w.Header().Set("X-GitHub-SSO", tt.sso)
+					}
+					w.WriteHeader(tt.repoStatus)
+				default:
+					t.Errorf("unexpected request %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			doctor := NewDoctor(newDoctorTestRepo(t, "https://github.com/owner/repo.git"), "test-token", "")
+			doctor.newGitHubClient = func(token string, repoInfo *RepoInfo) *GitHubClient {
+				client := newGitHubClientForRepo(token, repoInfo)
+				client.baseURL = server.URL
+				return client
+			}
+
+			results := doctor.Run(context.Background())
+			want := "git version=PASS, repository=PASS, remote=PASS, config=PASS, token=PASS, API reachability=PASS, " +
+				tt.want + ", snapshot store=PASS"
+			if got := checkNames(results); got != want {
+				t.Errorf("got %s\nwant %s", got, want)
+			}
+			for _, result := range results {
+				if result.Check == "repository access" && tt.sso != "" && !strings.Contains(result.Hint, "https://github.com/orgs/owner/sso") {
+					t.Errorf("expected the SSO authorization URL, got %q", result.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDoctorGitLabScopes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/personal_access_tokens/self" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"name": "ci", "scopes": ["read_repository"]}`))
+	}))
+	defer server.Close()
+
+	doctor := NewDoctor(newDoctorTestRepo(t, "git@gitlab.example.com:group/project.git"), "", "test-token")
+	doctor.newGitLabClient = func(token string, repoInfo *RepoInfo) *GitLabClient {
+		if repoInfo.Host != "gitlab.example.com" {
+			t.Errorf("unexpected host %s", repoInfo.Host)
+		}
+		client := newGitLabClientForRepo(token, repoInfo)
+		client.baseURL = server.URL
+		return client
+	}
+
+	results := doctor.Run(context.Background())
+	if got := checkNames(results); !strings.Contains(got, "authentication=PASS, token scopes=FAIL") {
+		t.Errorf("unexpected results %s", got)
+	}
+	for _, result := range results {
+		if result.Check == "token scopes" && !strings.Contains(result.String(), "hint: create a token with the read_api scope") {
+			t.Errorf("expected a fix hint, got %q", result.String())
+		}
+	}
+}
+
+func TestDoctorFailures(t *testing.T) {
+	stubTokenCommands(t, nil)
+	dir := newDoctorTestRepo(t, "https://github.com/owner/repo.git")
+
+	store, err := NewSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(store.dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(store.dir, "1-src.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "git version=PASS, repository=PASS, remote=PASS, config=PASS, token=FAIL, snapshot store=FAIL"
+	if got := checkNames(NewDoctor(dir, "", "").Run(context.Background())); got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	if got := checkNames(NewDoctor(t.TempDir(), "", "").Run(context.Background())); got != "git version=PASS, repository=FAIL" {
+		t.Errorf("unexpected results outside a repository: %s", got)
+	}
+}
+
+func TestDoctorTokenFromCLI(t *testing.T) {
+	stubTokenCommands(t, map[string]string{"gh": "gh-token\n"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			t.Errorf("expected the gh token, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"login": "alice"}`))
+	}))
+	defer server.Close()
+
+	doctor := NewDoctor(newDoctorTestRepo(t, "https://github.com/owner/repo.git"), "", "")
+	doctor.newGitHubClient = func(token string, repoInfo *RepoInfo) *GitHubClient {
+		client := newGitHubClientForRepo(token, repoInfo)
+		client.baseURL = server.URL
+		return client
+	}
+	for _, result := range doctor.Run(context.Background()) {
+		if result.Check == "token" && (!result.Passed || result.Detail != "using the token gh is logged in with") {
+			t.Errorf("unexpected token result %q", result.String())
+		}
+	}
+}
+
+func TestDoctorPlugin(t *testing.T) {
+	dir := newDoctorTestRepo(t, "https://review.example.com/team/service.git")
+	config := `{"hosts": [{"host": "review.example.com", "provider": "plugin", "plugin": "corp-review"}]}`
+	if err := os.WriteFile(filepath.Join(dir, DefaultConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results := NewDoctor(dir, "", "").Run(context.Background())
+	if got, want := checkNames(results), "git version=PASS, repository=PASS, remote=PASS, config=PASS, provider plugin=FAIL, snapshot store=PASS"; got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	// Install the plugin on PATH under its command name
+	path, _ := writeFakePlugin(t, `{}`, `{}`)
+	installed := filepath.Join(filepath.Dir(path), pluginCommand("corp-review"))
+	if err := os.Rename(path, installed); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", filepath.Dir(installed)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	for _, result := range NewDoctor(dir, "", "").Run(context.Background()) {
+		if result.Check == "provider plugin" && (!result.Passed || result.Detail != installed) {
+			t.Errorf("unexpected plugin result %q", result.String())
+		}
+	}
+}
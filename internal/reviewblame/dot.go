@@ -0,0 +1,97 @@
+package reviewblame
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node IDs of the shared nodes for lines without a PR/MR or approver
+const (
+	dotNoPRNode       = "no-pr"
+	dotUnreviewedNode = "unreviewed"
+)
+
+// dotEdge is a weighted edge between two node IDs
+type dotEdge struct {
+	from, to string
+}
+
+// dotQuote quotes s as a Graphviz ID
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+}
+
+// formatDot renders a Graphviz digraph linking files to the PRs/MRs that
+// last touched their lines and PRs/MRs to their approvers. Edges carry the
+// number of lines, so an approver reached by heavy edges from many files
+// stands out as a single point of failure.
+func formatDot(lines []BlameLineWithApproval, opts FormatOptions) string {
+	labels := make(map[string]string)
+	shapes := make(map[string]string)
+	weights := make(map[dotEdge]int)
+	node := func(id, label, shape string) string {
+		labels[id] = label
+		shapes[id] = shape
+		return id
+	}
+
+	for _, line := range lines {
+		file := node("file:"+line.Filename, line.Filename, "box")
+		if line.PRNumber == 0 {
+			weights[dotEdge{file, node(dotNoPRNode, "no PR", "octagon")}]++
+			continue
+		}
+
+		prLabel := fmt.Sprintf("#%d", line.PRNumber)
+		if line.Repository != "" {
+			prLabel = line.Repository + prLabel
+		}
+		pr := node("pr:"+prLabel, prLabel, "ellipse")
+		weights[dotEdge{file, pr}]++
+
+		approver := node(dotUnreviewedNode, "unreviewed", "octagon")
+		if line.Approver != "" {
+			name := line.Approver
+			if opts.ShowEmail && line.ApproverEmail != "" {
+				name = line.ApproverEmail
+			}
+			approver = node("approver:"+name, name, "doublecircle")
+		}
+		weights[dotEdge{pr, approver}]++
+	}
+
+	ids := make([]string, 0, len(labels))
+	for id := range labels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	edges := make([]dotEdge, 0, len(weights))
+	for edge := range weights {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+
+	var b strings.Builder
+	b.WriteString("digraph review_ownership {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, id := range ids {
+		attributes := fmt.Sprintf("label=%s, shape=%s", dotQuote(labels[id]), shapes[id])
+		if id == dotNoPRNode || id == dotUnreviewedNode {
+			attributes += ", color=red"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(id), attributes)
+	}
+	for _, edge := range edges {
+		weight := weights[edge]
+		fmt.Fprintf(&b, "  %s -> %s [label=\"%d\", weight=%d];\n", dotQuote(edge.from), dotQuote(edge.to), weight, weight)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package reviewblame
+
+import (
+	"testing"
+)
+
+func TestFormatDot(t *testing.T) {
+	line := func(file string, pr int, approver string) BlameLineWithApproval {
+		return BlameLineWithApproval{BlameLine: BlameLine{Filename: file}, PRNumber: pr, Approver: approver}
+	}
+	lines := []BlameLineWithApproval{
+		line("a.go", 1, "alice"),
+		line("a.go", 1, "alice"),
+		line("a.go", 2, ""),
+		line("b.go", 1, "alice"),
+		line("b.go", 0, ""),
+	}
+	migrated := line("b.go", 1, "bob")
+	migrated.Repository = "old/repo"
+	lines = append(lines, migrated)
+
+	want := `digraph review_ownership {
+  rankdir=LR;
+  "approver:alice" [label="alice", shape=doublecircle];
+  "approver:bob" [label="bob", shape=doublecircle];
+  "file:a.go" [label="a.go", shape=box];
+  "file:b.go" [label="b.go", shape=box];
+  "no-pr" [label="no PR", shape=octagon, color=red];
+  "pr:#1" [label="#1", shape=ellipse];
+  "pr:#2" [label="#2", shape=ellipse];
+  "pr:old/repo#1" [label="old/repo#1", shape=ellipse];
+  "unreviewed" [label="unreviewed", shape=octagon, color=red];
+  "file:a.go" -> "pr:#1" [label="2", weight=2];
+  "file:a.go" -> "pr:#2" [label="1", weight=1];
+  "file:b.go" -> "no-pr" [label="1", weight=1];
+  "file:b.go" -> "pr:#1" [label="1", weight=1];
+  "file:b.go" -> "pr:old/repo#1" [label="1", weight=1];
+  "pr:#1" -> "approver:alice" [label="3", weight=3];
+  "pr:#2" -> "unreviewed" [label="1", weight=1];
+  "pr:old/repo#1" -> "approver:bob" [label="1", weight=1];
+}
+`
+	if got := formatDot(lines, FormatOptions{}); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDotQuote(t *testing.T) {
+	if got := dotQuote(`say "hi"\now`); got != `"say \"hi\"\\now"` {
+		t.Errorf("unexpected quoting %s", got)
+	}
+}
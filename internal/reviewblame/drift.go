@@ -0,0 +1,304 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DriftChange is a block of changed lines, some of them approved, with the
+// approvals of its lines before and after the change
+type DriftChange struct {
+	// File is the path after the change, or before it when the file was
+	// deleted or renamed
+	File string `json:"file"`
+	// OldFile is set when the file was renamed
+	OldFile string `json:"old_file,omitempty"`
+	// OldStart and OldEnd are the changed lines before the change; NewStart
+	// and NewEnd the lines after it, both 0 when the lines were removed
+	OldStart int             `json:"old_start"`
+	OldEnd   int             `json:"old_end"`
+	NewStart int             `json:"new_start"`
+	NewEnd   int             `json:"new_end"`
+	Before   []DriftApproval `json:"before"`
+	After    []DriftApproval `json:"after"`
+}
+
+// DriftApproval counts the lines of one side of a change sharing an approval
+type DriftApproval struct {
+	// Label is the approval as editor annotations show it, e.g. "alice (#42)"
+	Label       string `json:"label"`
+	Approver    string `json:"approver,omitempty"`
+	PRNumber    int    `json:"pr_number,omitempty"`
+	ReviewState string `json:"review_state"`
+	Lines       int    `json:"lines"`
+}
+
+// BuildDrift groups the removed and added lines of a diff into changes and
+// keeps the changes to approved lines. before and after are the enriched
+// removed and added lines, in any order.
+func BuildDrift(diff []DiffLine, before, after []BlameLineWithApproval) []DriftChange {
+	oldLines := make(map[diffLineKey]BlameLineWithApproval, len(before))
+	for _, line := range before {
+		oldLines[diffLineKey{line.Filename, line.LineNumber}] = line
+	}
+	newLines := make(map[diffLineKey]BlameLineWithApproval, len(after))
+	for _, line := range after {
+		newLines[diffLineKey{line.Filename, line.LineNumber}] = line
+	}
+
+	changes := []DriftChange{}
+	for i := 0; i < len(diff); {
+		if diff[i].OldFile == "" && diff[i].NewFile == "" {
+			i++
+			continue
+		}
+
+		// A change is a run of removed and added lines between context lines
+		var change DriftChange
+		var oldBlock, newBlock []BlameLineWithApproval
+		approved := false
+		for ; i < len(diff) && (diff[i].OldFile != "" || diff[i].NewFile != ""); i++ {
+			if line := diff[i]; line.OldFile != "" {
+				if change.OldStart == 0 {
+					change.OldStart = line.OldLine
+				}
+				change.OldEnd = line.OldLine
+				change.OldFile = line.OldFile
+				if blamed, ok := oldLines[diffLineKey{line.OldFile, line.OldLine}]; ok {
+					oldBlock = append(oldBlock, blamed)
+					approved = approved || blamed.Approver != ""
+				}
+			} else {
+				if change.NewStart == 0 {
+					change.NewStart = line.NewLine
+				}
+				change.NewEnd = line.NewLine
+				change.File = line.NewFile
+				if blamed, ok := newLines[diffLineKey{line.NewFile, line.NewLine}]; ok {
+					newBlock = append(newBlock, blamed)
+				}
+			}
+		}
+		if !approved {
+			continue
+		}
+
+		if change.File == "" {
+			change.File = change.OldFile
+		}
+		if change.OldFile == change.File {
+			change.OldFile = ""
+		}
+		change.Before = groupDriftApprovals(oldBlock)
+		change.After = groupDriftApprovals(newBlock)
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// groupDriftApprovals counts the lines of each approval, in order of first
+// appearance
+func groupDriftApprovals(lines []BlameLineWithApproval) []DriftApproval {
+	approvals := []DriftApproval{}
+	index := make(map[string]int)
+	for _, line := range lines {
+		label := NewEditorAnnotation(line).Text
+		if i, ok := index[label]; ok {
+			approvals[i].Lines++
+			continue
+		}
+		index[label] = len(approvals)
+		approvals = append(approvals, DriftApproval{
+			Label:       label,
+			Approver:    line.Approver,
+			PRNumber:    line.PRNumber,
+			ReviewState: line.ReviewState(),
+			Lines:       1,
+		})
+	}
+	return approvals
+}
+
+// formatDrift renders the changes, each with its approvals before and
+// after, followed by the approvers of both sides
+func formatDrift(changes []DriftChange) string {
+	var b strings.Builder
+	before := make(map[string]int)
+	after := make(map[string]int)
+	approvedLines := 0
+	for _, change := range changes {
+		oldFile := change.OldFile
+		if oldFile == "" {
+			oldFile = change.File
+		}
+		target := "removed"
+		if change.NewStart > 0 {
+			target = fmt.Sprintf("%s:%s", change.File, lineSpan(change.NewStart, change.NewEnd))
+		}
+		fmt.Fprintf(&b, "%s:%s -> %s\n", oldFile, lineSpan(change.OldStart, change.OldEnd), target)
+		fmt.Fprintf(&b, "  before: %s\n", formatDriftApprovals(change.Before))
+		if change.NewStart > 0 {
+			fmt.Fprintf(&b, "  after:  %s\n", formatDriftApprovals(change.After))
+		}
+
+		for _, approval := range change.Before {
+			if approval.Approver != "" {
+				before[approval.Approver] += approval.Lines
+				approvedLines += approval.Lines
+			}
+		}
+		for _, approval := range change.After {
+			if approval.Approver != "" {
+				after[approval.Approver] += approval.Lines
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		return "No approved lines changed\n"
+	}
+	fmt.Fprintf(&b, "\n%d approved line(s) changed in %d place(s)\n", approvedLines, len(changes))
+	fmt.Fprintf(&b, "Approved before by %s\n", formatApproverCounts(before))
+	if len(after) > 0 {
+		fmt.Fprintf(&b, "Approved after by %s\n", formatApproverCounts(after))
+	}
+	return b.String()
+}
+
+// lineSpan renders a line range as "10" or "10-12"
+func lineSpan(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d-%d", start, end)
+}
+
+// formatDriftApprovals renders the approvals of one side of a change
+func formatDriftApprovals(approvals []DriftApproval) string {
+	parts := make([]string, len(approvals))
+	for i, approval := range approvals {
+		parts[i] = fmt.Sprintf("%s: %d line(s)", approval.Label, approval.Lines)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// formatApproverCounts renders line counts per approver, most lines first
+func formatApproverCounts(counts map[string]int) string {
+	approvers := make([]string, 0, len(counts))
+	for approver := range counts {
+		approvers = append(approvers, approver)
+	}
+	sort.Slice(approvers, func(i, j int) bool {
+		if counts[approvers[i]] != counts[approvers[j]] {
+			return counts[approvers[i]] > counts[approvers[j]]
+		}
+		return approvers[i] < approvers[j]
+	})
+	parts := make([]string, len(approvers))
+	for i, approver := range approvers {
+		parts[i] = fmt.Sprintf("%s (%d)", approver, counts[approver])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// diffTarget returns the revision a git diff revision argument compares
+// with: the right side of a range, HEAD when it is omitted, or the working
+// tree ("") for a single revision
+func diffTarget(revisions string) string {
+	for _, separator := range []string{"...", ".."} {
+		if _, right, found := strings.Cut(revisions, separator); found {
+			if right == "" {
+				return "HEAD"
+			}
+			return right
+		}
+	}
+	return ""
+}
+
+// runDrift implements the drift subcommand
+func runDrift(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("drift", flag.ContinueOnError)
+	format := flags.String("format", "text", "Report format: text or json")
+	configPath := flags.String("config", "", "Path to the config file")
+	offline := flags.Bool("offline", false, "Find PRs/MRs from merge commits without API calls")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported drift format %q (expected text or json)", *format)
+	}
+
+	const usage = "Usage: git-review-blame drift <rev> | <rev1>..<rev2> | <rev1>...<rev2> [--] <path>..."
+	rest := flags.Args()
+	if len(rest) > 0 && rest[0] == "--" || len(rest) < 2 {
+		return fmt.Errorf("drift compares paths between two revisions\n%s", usage)
+	}
+	revisions, paths := rest[0], rest[1:]
+	if paths[0] == "--" {
+		paths = paths[1:]
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(paths[0], *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+
+	base, err := diffBase(ctx, repoRoot, revisions)
+	if err != nil {
+		return err
+	}
+	target := diffTarget(revisions)
+	diff, err := gitDiff(ctx, repoRoot, revisions, paths)
+	if err != nil {
+		return err
+	}
+
+	var removed, added []diffLineKey
+	for _, line := range diff {
+		if line.OldFile != "" {
+			removed = append(removed, diffLineKey{line.OldFile, line.OldLine})
+		} else if line.NewFile != "" {
+			added = append(added, diffLineKey{line.NewFile, line.NewLine})
+		}
+	}
+	oldLines, err := blameDiffLines(ctx, repoRoot, base, removed)
+	if err != nil {
+		return err
+	}
+	newLines, err := blameDiffLines(ctx, repoRoot, target, added)
+	if err != nil {
+		return err
+	}
+
+	// Both sides are enriched in one run, so their lookups are shared
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{Offline: *offline, Revision: target}, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+	enriched, err := pipeline.Run(ctx, append(oldLines, newLines...))
+	if err != nil {
+		return err
+	}
+	changes := BuildDrift(diff, enriched[:len(oldLines)], enriched[len(oldLines):])
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(formatDrift(changes))
+	return nil
+}
@@ -0,0 +1,114 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildDrift(t *testing.T) {
+	diff, err := ParseUnifiedDiff(strings.NewReader("--- a/old.go\n+++ b/new.go\n" +
+		"@@ -1,6 +1,5 @@\n-a\n-b\n+A\n c\n-d\n e\n-f\n+F\n+G\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	approved := func(file string, line int, approver string, pr int) BlameLineWithApproval {
+		return BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "abc123", Filename: file, LineNumber: line}, Approver: approver, PRNumber: pr}
+	}
+	before := []BlameLineWithApproval{
+		approved("old.go", 1, "alice", 1), approved("old.go", 2, "bob", 2),
+		approved("old.go", 4, "", 0),
+		approved("old.go", 6, "alice", 1),
+	}
+	after := []BlameLineWithApproval{
+		approved("new.go", 1, "carol", 3),
+		approved("new.go", 4, "", 0), approved("new.go", 5, "", 0),
+	}
+
+	changes := BuildDrift(diff, before, after)
+	want := []DriftChange{
+		{
+			File: "new.go", OldFile: "old.go", OldStart: 1, OldEnd: 2, NewStart: 1, NewEnd: 1,
+			Before: []DriftApproval{
+				{Label: "alice (#1)", Approver: "alice", PRNumber: 1, ReviewState: ReviewStateApproved, Lines: 1},
+				{Label: "bob (#2)", Approver: "bob", PRNumber: 2, ReviewState: ReviewStateApproved, Lines: 1},
+			},
+			After: []DriftApproval{{Label: "carol (#3)", Approver: "carol", PRNumber: 3, ReviewState: ReviewStateApproved, Lines: 1}},
+		},
+		// The removal of the unreviewed line d is not drift
+		{
+			File: "new.go", OldFile: "old.go", OldStart: 6, OldEnd: 6, NewStart: 4, NewEnd: 5,
+			Before: []DriftApproval{{Label: "alice (#1)", Approver: "alice", PRNumber: 1, ReviewState: ReviewStateApproved, Lines: 1}},
+			After:  []DriftApproval{{Label: "unreviewed (no PR)", ReviewState: ReviewStateNoPR, Lines: 2}},
+		},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("expected %+v, got %+v", want, changes)
+	}
+
+	output := formatDrift(changes)
+	for _, line := range []string{
+		"old.go:1-2 -> new.go:1\n",
+		"  before: alice (#1): 1 line(s); bob (#2): 1 line(s)\n",
+		"  after:  unreviewed (no PR): 2 line(s)\n",
+		"3 approved line(s) changed in 2 place(s)\n",
+		"Approved before by alice (2), bob (1)\n",
+		"Approved after by carol (1)\n",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected %q in:\n%s", line, output)
+		}
+	}
+	if got := formatDrift(nil); got != "No approved lines changed\n" {
+		t.Errorf("unexpected output without changes: %q", got)
+	}
+}
+
+func TestDriftBetweenRevisions(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "a.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add a (#3)\n\nReviewed-by: Alice <alice@example.com>")
+	if err := os.WriteFile(file, []byte("one\n2\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "commit", "-q", "-am", "Refactor a (#4)\n\nReviewed-by: Bob <bob@example.com>")
+
+	ctx := context.Background()
+	diff, err := gitDiff(ctx, dir, "HEAD~1..HEAD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffTarget("HEAD~1..HEAD") != "HEAD" || diffTarget("main...") != "HEAD" || diffTarget("main") != "" {
+		t.Error("unexpected diff targets")
+	}
+	oldLines, err := blameDiffLines(ctx, dir, "HEAD~1", []diffLineKey{{"a.txt", 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLines, err := blameDiffLines(ctx, dir, "HEAD", []diffLineKey{{"a.txt", 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := newOfflinePipeline(dir, &Config{}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enriched, err := pipeline.Run(ctx, append(oldLines, newLines...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := BuildDrift(diff, enriched[:1], enriched[1:])
+	if len(changes) != 1 || changes[0].Before[0].Approver != "Alice" || changes[0].After[0].Approver != "Bob" {
+		t.Errorf("expected line 2 to drift from Alice to Bob, got %+v", changes)
+	}
+}
@@ -0,0 +1,578 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Enricher is a pipeline stage that adds information to annotated lines.
+// Stages see the whole slice so they can batch and cache lookups; they run
+// in pipeline order, so later stages can use data set by earlier ones.
+type Enricher interface {
+	// Name identifies the stage for ordering and removal
+	Name() string
+
+	// Enrich updates lines in place. Lookup failures for individual lines
+	// should be tolerated; an error aborts the whole run.
+	Enrich(ctx context.Context, lines []BlameLineWithApproval) error
+}
+
+// EnrichmentPipeline runs a sequence of Enricher stages over blame lines
+type EnrichmentPipeline struct {
+	stages []Enricher
+}
+
+// NewEnrichmentPipeline creates a pipeline running the given stages in order
+func NewEnrichmentPipeline(stages ...Enricher) *EnrichmentPipeline {
+	return &EnrichmentPipeline{stages: stages}
+}
+
+// NewDefaultEnrichmentPipeline creates the standard pipeline: find the PR/MR
+// of each commit, then its approvals
+func NewDefaultEnrichmentPipeline(client ReviewClient, repoInfo *RepoInfo) *EnrichmentPipeline {
+	return NewEnrichmentPipeline(
+		NewPRLookupEnricher(client, repoInfo),
+		NewApprovalEnricher(client, repoInfo),
+	)
+}
+
+// Use appends a stage to the end of the pipeline
+func (p *EnrichmentPipeline) Use(stage Enricher) {
+	p.stages = append(p.stages, stage)
+}
+
+// InsertBefore inserts a stage before the stage with the given name
+func (p *EnrichmentPipeline) InsertBefore(name string, stage Enricher) error {
+	for i, existing := range p.stages {
+		if existing.Name() == name {
+			p.stages = append(p.stages[:i], append([]Enricher{stage}, p.stages[i:]...)...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no enrichment stage named %q", name)
+}
+
+// Remove removes the stage with the given name, reporting whether it existed
+func (p *EnrichmentPipeline) Remove(name string) bool {
+	for i, existing := range p.stages {
+		if existing.Name() == name {
+			p.stages = append(p.stages[:i], p.stages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Stages returns the names of the stages in execution order
+func (p *EnrichmentPipeline) Stages() []string {
+	names := make([]string, 0, len(p.stages))
+	for _, stage := range p.stages {
+		names = append(names, stage.Name())
+	}
+	return names
+}
+
+// Run wraps blame lines and passes them through every stage
+func (p *EnrichmentPipeline) Run(ctx context.Context, blameLines []BlameLine) ([]BlameLineWithApproval, error) {
+	lines := make([]BlameLineWithApproval, 0, len(blameLines))
+	for _, blameLine := range blameLines {
+		lines = append(lines, BlameLineWithApproval{BlameLine: blameLine})
+	}
+
+	for _, stage := range p.stages {
+		if err := stage.Enrich(ctx, lines); err != nil {
+			return nil, fmt.Errorf("enrichment stage %s failed: %w", stage.Name(), err)
+		}
+	}
+
+	return lines, nil
+}
+
+// RunStream is Run for output that should appear while lookups are still
+// running: the lines pass through the stages window hunks at a time, a hunk
+// being a run of consecutive lines from the same commit, and emit receives
+// each window in file order as soon as it is enriched. Stage caches carry
+// over between windows, so each commit and PR/MR is still looked up once;
+// batched lookups cover one window. All lines are returned for the checks
+// that need the whole file.
+func (p *EnrichmentPipeline) RunStream(ctx context.Context, blameLines []BlameLine, window int, emit func([]BlameLineWithApproval) error) ([]BlameLineWithApproval, error) {
+	lines := make([]BlameLineWithApproval, 0, len(blameLines))
+	for _, blameLine := range blameLines {
+		lines = append(lines, BlameLineWithApproval{BlameLine: blameLine})
+	}
+
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for hunks := 1; end < len(lines); end++ {
+			if lines[end].CommitHash != lines[end-1].CommitHash {
+				if hunks == window {
+					break
+				}
+				hunks++
+			}
+		}
+
+		for _, stage := range p.stages {
+			if err := stage.Enrich(ctx, lines[start:end]); err != nil {
+				return nil, fmt.Errorf("enrichment stage %s failed: %w", stage.Name(), err)
+			}
+		}
+		if err := emit(lines[start:end]); err != nil {
+			return nil, err
+		}
+		start = end
+	}
+
+	return lines, nil
+}
+
+// RunHunks is RunStream for blame output that arrives while git is still
+// running, such as the hunks of a BlameStream: each hunk is enriched
+// together with the ones already waiting, up to window, so lookups overlap
+// with git computing the rest of the file. emit, when not nil, receives the
+// lines in file order as soon as every line above them is enriched, so
+// lines of -L ranges that do not start at line 1 are only emitted at the
+// end. All lines are returned in file order.
+func (p *EnrichmentPipeline) RunHunks(ctx context.Context, hunks <-chan []BlameLine, window int, emit func([]BlameLineWithApproval) error) ([]BlameLineWithApproval, error) {
+	enriched := make(map[int]BlameLineWithApproval)
+	var lines []BlameLineWithApproval
+	next := 1
+	for hunk := range hunks {
+		var batch []BlameLineWithApproval
+		for _, blameLine := range hunk {
+			batch = append(batch, BlameLineWithApproval{BlameLine: blameLine})
+		}
+		// Take the hunks git has reported in the meantime
+		for waiting := 1; waiting < window; waiting++ {
+			more, ok := receiveReady(hunks)
+			if !ok {
+				break
+			}
+			for _, blameLine := range more {
+				batch = append(batch, BlameLineWithApproval{BlameLine: blameLine})
+			}
+		}
+
+		for _, stage := range p.stages {
+			if err := stage.Enrich(ctx, batch); err != nil {
+				return nil, fmt.Errorf("enrichment stage %s failed: %w", stage.Name(), err)
+			}
+		}
+		for _, line := range batch {
+			enriched[line.LineNumber] = line
+		}
+
+		var ready []BlameLineWithApproval
+		for line, ok := enriched[next]; ok; line, ok = enriched[next] {
+			ready = append(ready, line)
+			delete(enriched, next)
+			next++
+		}
+		if len(ready) > 0 {
+			lines = append(lines, ready...)
+			if emit != nil {
+				if err := emit(ready); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Lines after a gap between line ranges
+	var rest []BlameLineWithApproval
+	for _, line := range enriched {
+		rest = append(rest, line)
+	}
+	sort.Slice(rest, func(i, j int) bool { return rest[i].LineNumber < rest[j].LineNumber })
+	if len(rest) > 0 {
+		lines = append(lines, rest...)
+		if emit != nil {
+			if err := emit(rest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return lines, nil
+}
+
+// receiveReady receives a hunk from hunks without waiting; ok is false when
+// none is ready or hunks is closed
+func receiveReady(hunks <-chan []BlameLine) ([]BlameLine, bool) {
+	select {
+	case hunk, ok := <-hunks:
+		return hunk, ok
+	default:
+		return nil, false
+	}
+}
+
+// prKey identifies a PR/MR across repositories, for lines whose history was
+// migrated from another repository
+type prKey struct {
+	repository string
+	number     int
+}
+
+// lineRepository returns the owner and name of the repository holding the
+// line's PR/MR: its migration origin if set, otherwise the current repository
+func lineRepository(repoInfo *RepoInfo, line BlameLineWithApproval) (string, string) {
+	if owner, name, found := strings.Cut(line.Repository, "/"); found {
+		return owner, name
+	}
+	return repoInfo.Owner, repoInfo.Name
+}
+
+// PRNumberLookup is implemented by review clients that can fetch a PR/MR by
+// its number. The PR lookup stage uses it to resolve commits whose message
+// names their PR/MR, e.g. squash merges, without a per-commit search.
+type PRNumberLookup interface {
+	GetPRByNumber(ctx context.Context, owner, repo string, prNumber int) (*PullRequest, error)
+}
+
+// PRLookupEnricher sets the PR/MR number of each line from its commit, along
+// with the PR title, description, author, branch, labels and the issues its
+// description closes. The PR/MR a commit message names is fetched directly
+// when the client supports it, and used as the number of commits the API
+// finds no PR/MR for.
+type PRLookupEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// repoRoot is the local clone full commit messages are read from; only
+	// the blamed subject lines are searched when empty
+	repoRoot string
+	// cache maps commit hash to its PR (nil when none was found or the lookup failed)
+	cache map[string]*prLookupResult
+	// messagePRs maps commit hash to the PR/MR number its message names
+	// (0 when none)
+	messagePRs map[string]int
+	// byNumber caches the PRs fetched by number
+	byNumber map[prKey]*prLookupResult
+	// store keeps merged PRs across runs; nil without a persistent cache
+	store *SQLiteCache
+}
+
+// prLookupResult is the cached PR information of a commit
+type prLookupResult struct {
+	// failed is set when the lookup failed rather than found no PR
+	failed       bool
+	number       int
+	title        string
+	body         string
+	author       string
+	branch       string
+	labels       []string
+	linkedIssues []string
+	mergedAt     *time.Time
+}
+
+// NewPRLookupEnricher creates the PR lookup stage
+func NewPRLookupEnricher(client ReviewClient, repoInfo *RepoInfo) *PRLookupEnricher {
+	return &PRLookupEnricher{
+		client:     client,
+		repoInfo:   repoInfo,
+		cache:      make(map[string]*prLookupResult),
+		messagePRs: make(map[string]int),
+		byNumber:   make(map[prKey]*prLookupResult),
+	}
+}
+
+// Name implements Enricher
+func (e *PRLookupEnricher) Name() string {
+	return "pr-lookup"
+}
+
+// newPRLookupResult converts a found PR to its cached information
+func newPRLookupResult(pr *PullRequest) *prLookupResult {
+	return &prLookupResult{
+		number:       pr.Number,
+		title:        pr.Title,
+		body:         pr.Body,
+		author:       pr.User.Login,
+		branch:       pr.Head.Ref,
+		labels:       pr.LabelNames(),
+		linkedIssues: ExtractLinkedIssues(pr.Body),
+		mergedAt:     pr.MergedAt,
+	}
+}
+
+// readMessagePRs records the PR/MR numbers named by the messages of the
+// uncached commits of lines. Full messages are read from the clone in one
+// git call, since GitLab names the MR in the body; without a clone, or when
+// reading fails, the blamed subject lines are used.
+func (e *PRLookupEnricher) readMessagePRs(ctx context.Context, lines []BlameLineWithApproval) {
+	messages := make(map[string]string)
+	var commitHashes []string
+	for _, line := range lines {
+		commitHash := line.CommitHash
+		if _, exists := e.messagePRs[commitHash]; exists || isUncommitted(line.BlameLine) {
+			continue
+		}
+		if _, exists := messages[commitHash]; !exists {
+			messages[commitHash] = line.Summary
+			commitHashes = append(commitHashes, commitHash)
+		}
+	}
+	if len(commitHashes) > 0 && e.repoRoot != "" {
+		if full, err := ReadCommitMessages(ctx, e.repoRoot, commitHashes); err == nil {
+			for commitHash, message := range full {
+				messages[commitHash] = message
+			}
+		}
+	}
+	for commitHash, message := range messages {
+		e.messagePRs[commitHash] = ParsePRNumberFromMessage(message)
+	}
+}
+
+// lookupByNumber fetches the PR/MR a commit message names, or returns nil
+// when the fetch failed so the commit is searched for instead
+func (e *PRLookupEnricher) lookupByNumber(ctx context.Context, client PRNumberLookup, owner, name string, prNumber int) *prLookupResult {
+	key := prKey{owner + "/" + name, prNumber}
+	if result, exists := e.byNumber[key]; exists {
+		return result
+	}
+	pr, err := client.GetPRByNumber(ctx, owner, name, prNumber)
+	if err != nil || pr == nil {
+		return nil
+	}
+	result := newPRLookupResult(pr)
+	e.byNumber[key] = result
+	return result
+}
+
+// prefetch looks up the uncached commits of lines in batches, per
+// repository. Failures are only reported with -debug: the per-commit
+// lookups that follow retry whatever the batch missed.
+func (e *PRLookupEnricher) prefetch(ctx context.Context, batch CommitBatchLookup, lines []BlameLineWithApproval) {
+	var repositories []string
+	commits := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		commitHash := line.CommitHash
+		// Uncommitted lines have an all-zero hash and no PR
+		if _, exists := e.cache[commitHash]; exists || strings.Trim(commitHash, "0") == "" {
+			continue
+		}
+		owner, name := lineRepository(e.repoInfo, line)
+		repository := owner + "/" + name
+		if seen[repository+"@"+commitHash] {
+			continue
+		}
+		seen[repository+"@"+commitHash] = true
+		if commits[repository] == nil {
+			repositories = append(repositories, repository)
+		}
+		commits[repository] = append(commits[repository], commitHash)
+	}
+
+	for _, repository := range repositories {
+		owner, name, _ := strings.Cut(repository, "/")
+		if err := batch.PrefetchCommits(ctx, owner, name, commits[repository]); err != nil {
+			debugf("batch lookup of %d commit(s) in %s failed, looking them up one by one: %v", len(commits[repository]), repository, err)
+		}
+	}
+}
+
+// loadStored caches the PRs the store holds for the uncached commits of
+// lines
+func (e *PRLookupEnricher) loadStored(ctx context.Context, lines []BlameLineWithApproval) {
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		commitHash := line.CommitHash
+		if _, exists := e.cache[commitHash]; exists || seen[commitHash] || isUncommitted(line.BlameLine) {
+			continue
+		}
+		seen[commitHash] = true
+		owner, name := lineRepository(e.repoInfo, line)
+		result, err := e.store.CommitPR(ctx, e.repoInfo.Host, owner+"/"+name, commitHash)
+		if err != nil {
+			debugf("cache: %v", err)
+			continue
+		}
+		if result != nil {
+			e.cache[commitHash] = result
+		}
+	}
+}
+
+// Enrich implements Enricher
+func (e *PRLookupEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	if e.store != nil {
+		e.loadStored(ctx, lines)
+	}
+	e.readMessagePRs(ctx, lines)
+	// Batched clients look up many commits per request, which beats
+	// fetching each named PR
+	batch, batched := e.client.(CommitBatchLookup)
+	byNumber, _ := e.client.(PRNumberLookup)
+	if batched {
+		byNumber = nil
+		e.prefetch(ctx, batch, lines)
+	}
+	for i := range lines {
+		// Uncommitted lines have no PR to look up
+		if isUncommitted(lines[i].BlameLine) {
+			continue
+		}
+		commitHash := lines[i].CommitHash
+		result, exists := e.cache[commitHash]
+		if !exists {
+			owner, name := lineRepository(e.repoInfo, lines[i])
+			messagePR := e.messagePRs[commitHash]
+			if byNumber != nil && messagePR > 0 {
+				result = e.lookupByNumber(ctx, byNumber, owner, name, messagePR)
+			}
+			if result == nil {
+				pr, err := e.client.FindPRByCommit(ctx, owner, name, commitHash)
+				if ctx.Err() != nil {
+					// Interrupted, not failed: do not cache the lookup as missing
+					return ctx.Err()
+				}
+				if pr != nil && err == nil {
+					result = newPRLookupResult(pr)
+				} else if messagePR > 0 {
+					// The message still tells the PR/MR, e.g. of a commit
+					// the API does not associate with its squash merge
+					result = &prLookupResult{number: messagePR}
+				} else if err != nil {
+					recordLookupFailure(e.Name(), "commit "+shortCommit(commitHash), err)
+					result = &prLookupResult{failed: true}
+				}
+			}
+			// Cache failures too, to avoid repeated lookups
+			e.cache[commitHash] = result
+			if e.store != nil {
+				if err := e.store.StoreCommitPR(ctx, e.repoInfo.Host, owner+"/"+name, commitHash, result); err != nil {
+					debugf("cache: %v", err)
+				}
+			}
+		}
+		if result != nil {
+			result.apply(&lines[i])
+		}
+	}
+	return nil
+}
+
+// apply sets the PR information of a line
+func (r *prLookupResult) apply(line *BlameLineWithApproval) {
+	if r.failed {
+		line.LookupFailed = true
+	}
+	if r.number > 0 {
+		line.PRNumber = r.number
+		line.PRTitle = r.title
+		line.PRBody = r.body
+		line.PRAuthor = r.author
+		line.PRBranch = r.branch
+		line.PRLabels = r.labels
+		line.LinkedIssues = r.linkedIssues
+		line.PRMergedAt = r.mergedAt
+	}
+}
+
+// reviewApprovers converts the approvals of a PR/MR to its approvers
+func reviewApprovers(approvals []Review) []LineApprover {
+	var approvers []LineApprover
+	for _, approval := range approvals {
+		approvers = append(approvers, LineApprover{
+			Name:      approval.User.Login,
+			Email:     approval.User.Email,
+			Time:      approval.SubmittedAt,
+			URL:       approval.HTMLURL,
+			AvatarURL: approval.User.AvatarURL,
+		})
+	}
+	return approvers
+}
+
+// ApprovalEnricher sets the approver of each line from its PR/MR approvals
+type ApprovalEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// cache maps PR to its approvers, nil when there are none or the lookup
+	// failed; failed holds the PRs whose lookup failed
+	cache  map[prKey][]LineApprover
+	failed map[prKey]bool
+	// store keeps the approvers of merged PRs across runs; nil without a
+	// persistent cache
+	store *SQLiteCache
+}
+
+// NewApprovalEnricher creates the approvals stage
+func NewApprovalEnricher(client ReviewClient, repoInfo *RepoInfo) *ApprovalEnricher {
+	return &ApprovalEnricher{
+		client:   client,
+		repoInfo: repoInfo,
+		cache:    make(map[prKey][]LineApprover),
+		failed:   make(map[prKey]bool),
+	}
+}
+
+// Name implements Enricher
+func (e *ApprovalEnricher) Name() string {
+	return "approvals"
+}
+
+// Enrich implements Enricher
+func (e *ApprovalEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		prNumber := lines[i].PRNumber
+		if prNumber == 0 {
+			continue
+		}
+
+		key := prKey{lines[i].Repository, prNumber}
+		approvers, exists := e.cache[key]
+		// Approvals of merged PRs/MRs no longer change
+		owner, name := lineRepository(e.repoInfo, lines[i])
+		stored := e.store != nil && lines[i].PRMergedAt != nil
+		if !exists && stored {
+			var err error
+			approvers, exists, err = e.store.Approvers(ctx, e.repoInfo.Host, owner+"/"+name, prNumber)
+			if err != nil {
+				debugf("cache: %v", err)
+			}
+			if exists {
+				e.cache[key] = approvers
+			}
+		}
+		if !exists {
+			approvals, err := e.client.GetPRApprovals(ctx, owner, name, prNumber)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				approvers = reviewApprovers(approvals)
+				if stored {
+					if err := e.store.StoreApprovers(ctx, e.repoInfo.Host, owner+"/"+name, prNumber, approvers); err != nil {
+						debugf("cache: %v", err)
+					}
+				}
+			} else {
+				recordLookupFailure(e.Name(), prSubject(lines[i].Repository, prNumber), err)
+				e.failed[key] = true
+			}
+			e.cache[key] = approvers
+		}
+		if e.failed[key] {
+			lines[i].LookupFailed = true
+		}
+
+		if len(approvers) > 0 {
+			// Use the most recent approver; lines of a PR/MR share the list
+			lastApprover := approvers[len(approvers)-1]
+			lines[i].Approver = lastApprover.Name
+			lines[i].ApproverEmail = lastApprover.Email
+			lines[i].ApprovalTime = lastApprover.Time
+			lines[i].Approvers = approvers
+		}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeReviewClient is an in-memory ReviewClient that counts API calls
+type fakeReviewClient struct {
+	prs           map[string]int
+	bodies        map[int]string
+	approvals     map[int][]Review
+	findCalls     int
+	approvalCalls int
+}
+
+func (c *fakeReviewClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	c.findCalls++
+	number, ok := c.prs[commitHash]
+	if !ok {
+		return nil, nil
+	}
+	return &PullRequest{Number: number, Title: fmt.Sprintf("PR %d", number), Body: c.bodies[number]}, nil
+}
+
+func (c *fakeReviewClient) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	c.approvalCalls++
+	approvals, ok := c.approvals[prNumber]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return approvals, nil
+}
+
+func (c *fakeReviewClient) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newTestReview(login string, submittedAt time.Time) Review {
+	review := Review{State: "APPROVED", SubmittedAt: &submittedAt}
+	review.User.Login = login
+	return review
+}
+
+func TestDefaultEnrichmentPipeline(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	client := &fakeReviewClient{
+		prs: map[string]int{"aaaa": 1, "bbbb": 1, "cccc": 2},
+		approvals: map[int][]Review{
+			1: {newTestReview("alice", now), newTestReview("bob", now.Add(time.Hour))},
+		},
+	}
+	pipeline := NewDefaultEnrichmentPipeline(client, &RepoInfo{Owner: "owner", Name: "repo"})
+
+	blameLines := []BlameLine{
+		{CommitHash: "aaaa", LineNumber: 1},
+		{CommitHash: "bbbb", LineNumber: 2},
+		{CommitHash: "aaaa", LineNumber: 3},
+		{CommitHash: "cccc", LineNumber: 4},
+		{CommitHash: "dddd", LineNumber: 5},
+	}
+
+	lines, err := pipeline.Run(context.Background(), blameLines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d", len(lines))
+	}
+	for _, i := range []int{0, 1, 2} {
+		if lines[i].PRNumber != 1 || lines[i].Approver != "bob" {
+			t.Errorf("line %d: expected PR 1 approved by bob, got PR %d by %q", i+1, lines[i].PRNumber, lines[i].Approver)
+		}
+	}
+	if approvers := lines[0].Approvers; len(approvers) != 2 || approvers[0].Name != "alice" || !approvers[1].Time.Equal(now.Add(time.Hour)) {
+		t.Errorf("line 1: expected approvers alice then bob, got %+v", approvers)
+	}
+	if lines[3].PRNumber != 2 || lines[3].Approver != "" {
+		t.Errorf("line 4: expected PR 2 without approver, got %+v", lines[3])
+	}
+	if lines[4].PRNumber != 0 || lines[4].Approver != "" {
+		t.Errorf("line 5: expected no PR, got %+v", lines[4])
+	}
+
+	// One lookup per unique commit and one approvals call per unique PR
+	if client.findCalls != 4 {
+		t.Errorf("expected 4 PR lookups, got %d", client.findCalls)
+	}
+	if client.approvalCalls != 2 {
+		t.Errorf("expected 2 approval lookups, got %d", client.approvalCalls)
+	}
+
+	// Caches are kept across runs
+	if _, err := pipeline.Run(context.Background(), blameLines); err != nil {
+		t.Fatal(err)
+	}
+	if client.findCalls != 4 || client.approvalCalls != 2 {
+		t.Errorf("expected cached results on second run, got %d and %d calls", client.findCalls, client.approvalCalls)
+	}
+}
+
+func TestPRLookupEnricherLinkedIssues(t *testing.T) {
+	client := &fakeReviewClient{
+		prs:    map[string]int{"aaaa": 1, "bbbb": 2},
+		bodies: map[int]string{1: "Refactor the parser.\n\nFixes #10, closes owner/other#11"},
+	}
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaa"}},
+		{BlameLine: BlameLine{CommitHash: "bbbb"}},
+	}
+
+	if err := NewPRLookupEnricher(client, &RepoInfo{Owner: "owner", Name: "repo"}).Enrich(context.Background(), lines); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines[0].PRTitle != "PR 1" {
+		t.Errorf("expected PR title, got %q", lines[0].PRTitle)
+	}
+	if len(lines[0].LinkedIssues) != 2 || lines[0].LinkedIssues[0] != "#10" || lines[0].LinkedIssues[1] != "owner/other#11" {
+		t.Errorf("expected linked issues [#10 owner/other#11], got %v", lines[0].LinkedIssues)
+	}
+	if len(lines[1].LinkedIssues) != 0 {
+		t.Errorf("expected no linked issues, got %v", lines[1].LinkedIssues)
+	}
+}
+
+// fakeNumberClient is a fakeReviewClient that can also fetch PRs by number
+type fakeNumberClient struct {
+	fakeReviewClient
+	numberCalls int
+}
+
+func (c *fakeNumberClient) GetPRByNumber(ctx context.Context, owner, repo string, prNumber int) (*PullRequest, error) {
+	c.numberCalls++
+	if prNumber == 404 {
+		return nil, errors.New("not found")
+	}
+	return &PullRequest{Number: prNumber, Title: fmt.Sprintf("PR %d", prNumber)}, nil
+}
+
+func TestPRLookupEnricherMessagePRs(t *testing.T) {
+	lines := func() []BlameLineWithApproval {
+		return []BlameLineWithApproval{
+			{BlameLine: BlameLine{CommitHash: "aaaa", Summary: "Fix parser (#5)"}},
+			{BlameLine: BlameLine{CommitHash: "bbbb", Summary: "Fix lexer (#5)"}},
+			{BlameLine: BlameLine{CommitHash: "cccc", Summary: "Tweak docs"}},
+			{BlameLine: BlameLine{CommitHash: "dddd", Summary: "Merge pull request #404 from owner/gone"}},
+		}
+	}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	// PRs named by the message are fetched by number, once per PR
+	client := &fakeNumberClient{fakeReviewClient: fakeReviewClient{prs: map[string]int{"cccc": 6, "dddd": 7}}}
+	got := lines()
+	if err := NewPRLookupEnricher(client, repoInfo).Enrich(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got[0].PRNumber != 5 || got[0].PRTitle != "PR 5" || got[1].PRNumber != 5 || got[2].PRNumber != 6 || got[3].PRNumber != 7 {
+		t.Errorf("expected PRs 5, 5, 6, 7, got %d, %d, %d, %d", got[0].PRNumber, got[1].PRNumber, got[2].PRNumber, got[3].PRNumber)
+	}
+	if client.numberCalls != 2 || client.findCalls != 2 {
+		t.Errorf("expected 2 fetches by number and 2 commit searches, got %d and %d", client.numberCalls, client.findCalls)
+	}
+
+	// Without fetching by number, the message PR is the fallback
+	fallback := &fakeReviewClient{prs: map[string]int{"bbbb": 8}}
+	got = lines()
+	if err := NewPRLookupEnricher(fallback, repoInfo).Enrich(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got[0].PRNumber != 5 || got[0].PRTitle != "" || got[0].LookupFailed || got[1].PRNumber != 8 || got[2].PRNumber != 0 || got[3].PRNumber != 404 {
+		t.Errorf("expected PRs 5, 8, 0, 404, got %d, %d, %d, %d", got[0].PRNumber, got[1].PRNumber, got[2].PRNumber, got[3].PRNumber)
+	}
+}
+
+// namedEnricher is a stage that records the order stages run in
+type namedEnricher struct {
+	name string
+	log  *[]string
+	err  error
+}
+
+func (e namedEnricher) Name() string { return e.name }
+
+func (e namedEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	*e.log = append(*e.log, e.name)
+	return e.err
+}
+
+func TestEnrichmentPipelineOrdering(t *testing.T) {
+	var log []string
+	pipeline := NewEnrichmentPipeline(namedEnricher{name: "a", log: &log}, namedEnricher{name: "c", log: &log})
+
+	pipeline.Use(namedEnricher{name: "d", log: &log})
+	if err := pipeline.InsertBefore("c", namedEnricher{name: "b", log: &log}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := pipeline.InsertBefore("missing", namedEnricher{name: "x", log: &log}); err == nil {
+		t.Error("expected error inserting before unknown stage")
+	}
+	if !pipeline.Remove("d") || pipeline.Remove("d") {
+		t.Error("expected Remove to report whether the stage existed")
+	}
+
+	if _, err := pipeline.Run(context.Background(), []BlameLine{{CommitHash: "aaaa"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"a", "b", "c"}
+	if len(log) != len(expected) {
+		t.Fatalf("expected stages %v to run, got %v", expected, log)
+	}
+	for i := range expected {
+		if log[i] != expected[i] || pipeline.Stages()[i] != expected[i] {
+			t.Errorf("expected stage order %v, ran %v, listed %v", expected, log, pipeline.Stages())
+		}
+	}
+}
+
+func TestEnrichmentPipelineStageError(t *testing.T) {
+	var log []string
+	pipeline := NewEnrichmentPipeline(
+		namedEnricher{name: "broken", log: &log, err: errors.New("boom")},
+		namedEnricher{name: "after", log: &log},
+	)
+
+	if _, err := pipeline.Run(context.Background(), []BlameLine{{CommitHash: "aaaa"}}); err == nil {
+		t.Error("expected error from failing stage")
+	}
+	if len(log) != 1 {
+		t.Errorf("expected pipeline to stop after failing stage, ran %v", log)
+	}
+}
+
+func TestDefaultEnrichmentPipelineCanceled(t *testing.T) {
+	client := &fakeReviewClient{
+		prs:       map[string]int{"aaaa": 1},
+		approvals: map[int][]Review{1: {newTestReview("alice", time.Unix(1700000000, 0))}},
+	}
+	pipeline := NewDefaultEnrichmentPipeline(client, &RepoInfo{Owner: "owner", Name: "repo"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pipeline.Run(ctx, []BlameLine{{CommitHash: "aaaa"}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// An interrupted lookup must not be cached as a commit without a PR
+	lines, err := pipeline.Run(context.Background(), []BlameLine{{CommitHash: "aaaa"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines[0].PRNumber != 1 || lines[0].Approver != "alice" {
+		t.Errorf("expected PR 1 approved by alice, got %+v", lines[0])
+	}
+}
+
+// windowRecorder is a stage that records the commits of each slice it enriches
+type windowRecorder struct {
+	windows *[]string
+}
+
+func (e windowRecorder) Name() string { return "windows" }
+
+func (e windowRecorder) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	var commits []string
+	for _, line := range lines {
+		commits = append(commits, line.CommitHash)
+	}
+	*e.windows = append(*e.windows, strings.Join(commits, ","))
+	return nil
+}
+
+func TestEnrichmentPipelineRunStream(t *testing.T) {
+	client := &fakeReviewClient{
+		prs:       map[string]int{"aaaa": 1, "bbbb": 2, "cccc": 1},
+		approvals: map[int][]Review{1: {newTestReview("alice", time.Unix(1700000000, 0))}},
+	}
+	var windows []string
+	pipeline := NewDefaultEnrichmentPipeline(client, &RepoInfo{Owner: "owner", Name: "repo"})
+	pipeline.Use(windowRecorder{windows: &windows})
+
+	blameLines := []BlameLine{
+		{CommitHash: "aaaa", LineNumber: 1},
+		{CommitHash: "aaaa", LineNumber: 2},
+		{CommitHash: "bbbb", LineNumber: 3},
+		{CommitHash: "cccc", LineNumber: 4},
+		{CommitHash: "aaaa", LineNumber: 5},
+	}
+	var emitted []int
+	lines, err := pipeline.RunStream(context.Background(), blameLines, 2, func(window []BlameLineWithApproval) error {
+		for _, line := range window {
+			if line.PRNumber == 0 && line.CommitHash != "bbbb" {
+				t.Errorf("line %d emitted before it was enriched", line.LineNumber)
+			}
+			emitted = append(emitted, line.LineNumber)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedWindows := []string{"aaaa,aaaa,bbbb", "cccc,aaaa"}
+	if strings.Join(windows, " ") != strings.Join(expectedWindows, " ") {
+		t.Errorf("expected windows of two hunks %v, got %v", expectedWindows, windows)
+	}
+	if fmt.Sprint(emitted) != "[1 2 3 4 5]" {
+		t.Errorf("expected lines emitted in order, got %v", emitted)
+	}
+	if len(lines) != 5 || lines[4].Approver != "alice" {
+		t.Errorf("expected all enriched lines to be returned, got %+v", lines)
+	}
+	// Caches carry over between windows
+	if client.findCalls != 3 || client.approvalCalls != 2 {
+		t.Errorf("expected 3 commit and 2 approval lookups, got %d and %d", client.findCalls, client.approvalCalls)
+	}
+}
+
+func TestEnrichmentPipelineRunStreamEmitError(t *testing.T) {
+	var windows []string
+	pipeline := NewEnrichmentPipeline(windowRecorder{windows: &windows})
+
+	blameLines := []BlameLine{{CommitHash: "aaaa"}, {CommitHash: "bbbb"}}
+	_, err := pipeline.RunStream(context.Background(), blameLines, 1, func(window []BlameLineWithApproval) error {
+		return errors.New("broken pipe")
+	})
+	if err == nil || err.Error() != "broken pipe" {
+		t.Errorf("expected the emit error, got %v", err)
+	}
+	if len(windows) != 1 {
+		t.Errorf("expected the run to stop after the failed window, enriched %v", windows)
+	}
+}
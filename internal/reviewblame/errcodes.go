@@ -0,0 +1,113 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Exit codes of a failed run, distinct per kind of failure so scripts can
+// react without parsing messages
+const (
+	// ExitError is any failure without a more specific code, including
+	// CI gates such as -require-approval
+	ExitError = 1
+	// ExitNotGitRepository: the path is not inside a git repository
+	ExitNotGitRepository = 3
+	// ExitMissingToken: the command needs an API token and none was found
+	ExitMissingToken = 4
+	// ExitAuthFailure: the token is invalid or cannot read the repository
+	ExitAuthFailure = 5
+	// ExitRateLimited: lookups failed on rate limits (-fail-on-error)
+	ExitRateLimited = 6
+	// ExitFileNotTracked: git has no history of the file
+	ExitFileNotTracked = 7
+	// ExitLookupFailed: other lookups failed (-fail-on-error)
+	ExitLookupFailed = 8
+	// ExitInterrupted is the conventional status after Ctrl-C
+	ExitInterrupted = 130
+)
+
+// Error codes of the -json-errors object, one per exit code
+const (
+	ErrorCodeError            = "error"
+	ErrorCodeNotGitRepository = "not_git_repository"
+	ErrorCodeMissingToken     = "missing_token"
+	ErrorCodeAuthFailure      = "auth_failure"
+	ErrorCodeRateLimited      = "rate_limited"
+	ErrorCodeFileNotTracked   = "file_not_tracked"
+	ErrorCodeLookupFailed     = "lookup_failed"
+	ErrorCodeInterrupted      = "interrupted"
+)
+
+var (
+	// ErrLookupFailed is wrapped by -fail-on-error failures
+	ErrLookupFailed = errors.New("API lookups failed")
+	// ErrRateLimited is wrapped by -fail-on-error failures when lookups
+	// failed on rate limits
+	ErrRateLimited = errors.New("API rate limit exceeded")
+)
+
+// jsonErrors writes failures as a JSON object instead of a message
+// (-json-errors)
+var jsonErrors bool
+
+// UntrackedFileError is a file git has no history of
+type UntrackedFileError struct {
+	Path string
+	// Err is the failure of git blame, nil when the path never existed
+	Err error
+}
+
+// Error implements error
+func (e *UntrackedFileError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s does not exist and never existed in the history of HEAD", e.Path)
+	}
+	return "could not analyze file history. Please check if the file exists and is tracked by Git: " + e.Err.Error()
+}
+
+// Unwrap returns the failure of git blame
+func (e *UntrackedFileError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError returns the error code and exit code of a failed run
+func classifyError(ctx context.Context, err error) (string, int) {
+	var accessErr *AccessError
+	var untracked *UntrackedFileError
+	switch {
+	case ctx.Err() != nil:
+		return ErrorCodeInterrupted, ExitInterrupted
+	case errors.Is(err, ErrNotGitRepo):
+		return ErrorCodeNotGitRepository, ExitNotGitRepository
+	case errors.Is(err, ErrMissingGitHubToken), errors.Is(err, ErrMissingGitLabToken),
+		errors.Is(err, ErrMissingBitbucketToken), errors.Is(err, ErrMissingGiteaToken):
+		return ErrorCodeMissingToken, ExitMissingToken
+	case errors.As(err, &accessErr):
+		return ErrorCodeAuthFailure, ExitAuthFailure
+	case errors.As(err, &untracked):
+		return ErrorCodeFileNotTracked, ExitFileNotTracked
+	case errors.Is(err, ErrRateLimited):
+		return ErrorCodeRateLimited, ExitRateLimited
+	case errors.Is(err, ErrLookupFailed):
+		return ErrorCodeLookupFailed, ExitLookupFailed
+	}
+	return ErrorCodeError, ExitError
+}
+
+// writeErrorJSON writes a failed run as a one-line JSON object, e.g.
+// {"code":"missing_token","message":"...","exit_code":4}
+func writeErrorJSON(w io.Writer, code string, exitCode int, err error) {
+	message := "Interrupted"
+	if code != ErrorCodeInterrupted {
+		message = err.Error()
+	}
+	json.NewEncoder(w).Encode(struct {
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		ExitCode int    `json:"exit_code"`
+	}{code, message, exitCode})
+}
@@ -0,0 +1,49 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     string
+		exitCode int
+	}{
+		{"other", errors.New("boom"), ErrorCodeError, ExitError},
+		{"no repository", fmt.Errorf("this directory is not part of a Git repository: %w", ErrNotGitRepo), ErrorCodeNotGitRepository, ExitNotGitRepository},
+		{"no token", fmt.Errorf("authentication required: %w", ErrMissingGitLabToken), ErrorCodeMissingToken, ExitMissingToken},
+		{"access", fmt.Errorf("pipeline: %w", &AccessError{Problem: "p", Hint: "h"}), ErrorCodeAuthFailure, ExitAuthFailure},
+		{"untracked", &UntrackedFileError{Path: "a.go"}, ErrorCodeFileNotTracked, ExitFileNotTracked},
+		{"rate limited", errors.Join(errors.New("1 line(s) were not approved"), fmt.Errorf("2 API lookup(s) failed: %w", ErrRateLimited)), ErrorCodeRateLimited, ExitRateLimited},
+		{"lookups failed", fmt.Errorf("2 API lookup(s) failed: %w", ErrLookupFailed), ErrorCodeLookupFailed, ExitLookupFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, exitCode := classifyError(context.Background(), tt.err)
+			if code != tt.code || exitCode != tt.exitCode {
+				t.Errorf("expected %s (%d), got %s (%d)", tt.code, tt.exitCode, code, exitCode)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if code, exitCode := classifyError(ctx, context.Canceled); code != ErrorCodeInterrupted || exitCode != ExitInterrupted {
+		t.Errorf("expected an interrupted run, got %s (%d)", code, exitCode)
+	}
+}
+
+func TestWriteErrorJSON(t *testing.T) {
+	var buf bytes.Buffer
+	writeErrorJSON(&buf, ErrorCodeFileNotTracked, ExitFileNotTracked, &UntrackedFileError{Path: "a.go"})
+	want := `{"code":"file_not_tracked","message":"a.go does not exist and never existed in the history of HEAD","exit_code":7}` + "\n"
+	if buf.String() != want {
+		t.Errorf("expected %s, got %s", want, buf.String())
+	}
+}
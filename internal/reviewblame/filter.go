@@ -0,0 +1,80 @@
+package reviewblame
+
+import "strings"
+
+// LineFilter selects the printed lines by the people who approved or wrote
+// them, and by the labels of their PR/MR. Names and labels match
+// case-insensitively, people by name or email; a line must match one of the
+// approvers, when given, one of the authors, when given, and one of the
+// labels, when given.
+type LineFilter struct {
+	// Approvers are -approver values, matched against every approver of the
+	// line's PR/MR
+	Approvers []string
+	// Authors are -author values, matched against the commit author
+	Authors []string
+	// Labels are -label values, matched against the labels of the line's PR/MR
+	Labels []string
+}
+
+// IsEmpty reports whether the filter keeps every line
+func (f LineFilter) IsEmpty() bool {
+	return len(f.Approvers) == 0 && len(f.Authors) == 0 && len(f.Labels) == 0
+}
+
+// Match reports whether the filter keeps the line
+func (f LineFilter) Match(line BlameLineWithApproval) bool {
+	if len(f.Approvers) > 0 {
+		approved := false
+		for _, approver := range distinctApprovers(line) {
+			if matchesPerson(f.Approvers, approver.Name, approver.Email) {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			return false
+		}
+	}
+	if len(f.Authors) > 0 && !matchesPerson(f.Authors, line.Author, line.AuthorEmail) {
+		return false
+	}
+	return len(f.Labels) == 0 || hasLabel(f.Labels, line.PRLabels)
+}
+
+// Apply returns the lines the filter keeps, lines itself when it is empty
+func (f LineFilter) Apply(lines []BlameLineWithApproval) []BlameLineWithApproval {
+	if f.IsEmpty() {
+		return lines
+	}
+	var kept []BlameLineWithApproval
+	for _, line := range lines {
+		if f.Match(line) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// hasLabel reports whether one of labels is one of wanted
+func hasLabel(wanted, labels []string) bool {
+	for _, label := range labels {
+		for _, name := range wanted {
+			if strings.EqualFold(strings.TrimSpace(name), label) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesPerson reports whether name or email equals one of people
+func matchesPerson(people []string, name, email string) bool {
+	for _, person := range people {
+		person = strings.TrimSpace(person)
+		if strings.EqualFold(person, name) || email != "" && strings.EqualFold(person, email) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineFilter(t *testing.T) {
+	line := BlameLineWithApproval{
+		BlameLine: BlameLine{Author: "Jane Doe", AuthorEmail: "jane@example.com"},
+		Approver:  "bob",
+		Approvers: []LineApprover{{Name: "alice", Email: "alice@example.com"}, {Name: "bob"}},
+		PRLabels:  []string{"security-review", "backend"},
+	}
+	unapproved := BlameLineWithApproval{BlameLine: BlameLine{Author: "Jane Doe"}}
+
+	tests := []struct {
+		name   string
+		filter LineFilter
+		line   BlameLineWithApproval
+		want   bool
+	}{
+		{"no filter", LineFilter{}, unapproved, true},
+		{"last approver", LineFilter{Approvers: []string{"bob"}}, line, true},
+		{"earlier approver by email", LineFilter{Approvers: []string{"Alice@Example.com"}}, line, true},
+		{"one of several approvers", LineFilter{Approvers: []string{"carol", "ALICE"}}, line, true},
+		{"other approver", LineFilter{Approvers: []string{"carol"}}, line, false},
+		{"unapproved line", LineFilter{Approvers: []string{"alice"}}, unapproved, false},
+		{"author name", LineFilter{Authors: []string{"jane doe"}}, line, true},
+		{"author email", LineFilter{Authors: []string{"jane@example.com"}}, line, true},
+		{"other author", LineFilter{Authors: []string{"John"}}, line, false},
+		{"approver and author", LineFilter{Approvers: []string{"alice"}, Authors: []string{"Jane Doe"}}, line, true},
+		{"approver but other author", LineFilter{Approvers: []string{"alice"}, Authors: []string{"John"}}, line, false},
+		{"label", LineFilter{Labels: []string{"Security-Review"}}, line, true},
+		{"one of several labels", LineFilter{Labels: []string{"docs", "backend"}}, line, true},
+		{"other label", LineFilter{Labels: []string{"docs"}}, line, false},
+		{"line without labels", LineFilter{Labels: []string{"security-review"}}, unapproved, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.line); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	kept := LineFilter{Approvers: []string{"alice"}}.Apply([]BlameLineWithApproval{unapproved, line, unapproved})
+	if len(kept) != 1 || kept[0].Approver != "bob" {
+		t.Errorf("expected only the approved line, got %+v", kept)
+	}
+}
+
+func TestFormatPRLabels(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Author: "John", Date: "1609459200", LineNumber: 1, Content: "a"}, PRNumber: 3, PRLabels: []string{"security-review", "backend"}},
+		{BlameLine: BlameLine{CommitHash: "bbbbbbbbbb", Author: "John", Date: "1609459200", LineNumber: 2, Content: "b"}},
+	}
+	registry := NewFormatterRegistry()
+
+	human, _ := registry.Lookup("human")
+	output := strings.Split(human.Format(lines, FormatOptions{ShowLabels: true}), "\n")
+	if !strings.Contains(output[0], " security-review,backend 1) a") || !strings.Contains(output[1], "                        2) b") {
+		t.Errorf("expected a padded labels column, got %q", output)
+	}
+	if output := human.Format(lines, FormatOptions{}); strings.Contains(output, "backend") {
+		t.Errorf("expected no labels without ShowLabels, got %q", output)
+	}
+
+	porcelain, _ := registry.Lookup("porcelain")
+	if output := porcelain.Format(lines, FormatOptions{}); !strings.Contains(output, "\npr-labels security-review,backend\n") {
+		t.Errorf("expected a pr-labels line, got %q", output)
+	}
+
+	csv, _ := registry.Lookup("csv")
+	if output := csv.Format(lines, FormatOptions{Columns: []string{"line", "pr_labels"}}); output != "line,pr_labels\n1,security-review; backend\n2,\n" {
+		t.Errorf("unexpected CSV %q", output)
+	}
+}
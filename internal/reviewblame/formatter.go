@@ -0,0 +1,695 @@
+package reviewblame
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Formatter renders annotated blame lines in one output format
+type Formatter interface {
+	Format(lines []BlameLineWithApproval, opts FormatOptions) string
+}
+
+// FormatOptions are the display options passed to every formatter
+type FormatOptions struct {
+	ShowEmail  bool
+	NoColors   bool
+	ShowIssues bool
+	// ShowLabels adds the labels of each line's PR/MR to the human format
+	ShowLabels bool
+	// AllApprovers shows every approver of a line instead of the last one
+	AllApprovers bool
+	// GroupHunks shows the commit, approver and date of the human format only
+	// on the first line of each hunk
+	GroupHunks bool
+	// ColorBy colors the human format by approver, PR/MR or age with the
+	// colors of Theme (DefaultColorTheme when nil); "" or NoColors disables it
+	ColorBy string
+	Theme   *ColorTheme
+	// Hyperlinks makes PRs/MRs and approvers of the human format clickable
+	// in terminals that support OSC 8 hyperlinks; they need Repo
+	Hyperlinks bool
+	// Columns are the columns of the csv format, DefaultCSVColumns when empty
+	Columns []string
+	// Repo is the annotated repository, used to link PRs/MRs; nil when unknown
+	Repo *RepoInfo
+}
+
+// FormatterFunc adapts a plain function to the Formatter interface
+type FormatterFunc func(lines []BlameLineWithApproval, opts FormatOptions) string
+
+// Format implements Formatter
+func (f FormatterFunc) Format(lines []BlameLineWithApproval, opts FormatOptions) string {
+	return f(lines, opts)
+}
+
+// WriterFormatter is a Formatter that can also write its output directly,
+// without building it in memory first
+type WriterFormatter interface {
+	Formatter
+	WriteFormat(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error
+}
+
+// WriterFormatterFunc adapts a plain writing function to WriterFormatter
+type WriterFormatterFunc func(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error
+
+// Format implements Formatter
+func (f WriterFormatterFunc) Format(lines []BlameLineWithApproval, opts FormatOptions) string {
+	var result strings.Builder
+	f(&result, lines, opts)
+	return result.String()
+}
+
+// WriteFormat implements WriterFormatter
+func (f WriterFormatterFunc) WriteFormat(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+	return f(w, lines, opts)
+}
+
+// WriteFormatted writes lines to w in the given format, streaming them when
+// the formatter supports it
+func WriteFormatted(w io.Writer, formatter Formatter, lines []BlameLineWithApproval, opts FormatOptions) error {
+	if streaming, ok := formatter.(WriterFormatter); ok {
+		return streaming.WriteFormat(w, lines, opts)
+	}
+	_, err := io.WriteString(w, formatter.Format(lines, opts))
+	return err
+}
+
+// FormatterRegistry maps output format names to formatters
+type FormatterRegistry struct {
+	mu         sync.RWMutex
+	formatters map[string]Formatter
+}
+
+// NewFormatterRegistry creates a registry holding the built-in formats
+func NewFormatterRegistry() *FormatterRegistry {
+	return &FormatterRegistry{
+		formatters: map[string]Formatter{
+			"human": WriterFormatterFunc(func(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+				formatter := NewOutputFormatter(opts.ShowEmail, false, opts.NoColors)
+				formatter.ShowIssues = opts.ShowIssues
+				formatter.ShowLabels = opts.ShowLabels
+				formatter.AllApprovers = opts.AllApprovers
+				formatter.GroupHunks = opts.GroupHunks
+				formatter.ColorBy = opts.ColorBy
+				formatter.Theme = opts.Theme.withDefaults()
+				formatter.Hyperlinks = opts.Hyperlinks
+				formatter.Repo = opts.Repo
+				return formatter.WriteHuman(w, lines)
+			}),
+			"porcelain": WriterFormatterFunc(func(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+				formatter := NewOutputFormatter(opts.ShowEmail, true, opts.NoColors)
+				formatter.AllApprovers = opts.AllApprovers
+				return formatter.WritePorcelain(w, lines)
+			}),
+			"annotations": FormatterFunc(formatAnnotations),
+			"csv":         WriterFormatterFunc(formatCSV),
+			"dot":         FormatterFunc(formatDot),
+			"html":        WriterFormatterFunc(formatHTML),
+			"incremental": WriterFormatterFunc(formatIncremental),
+			"markdown":    WriterFormatterFunc(formatMarkdown),
+			"pr-summary":  WriterFormatterFunc(formatPRSummary),
+		},
+	}
+}
+
+// Register adds a formatter under name; names must be unique
+func (r *FormatterRegistry) Register(name string, formatter Formatter) error {
+	if name == "" || formatter == nil {
+		return fmt.Errorf("formatter name and implementation are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.formatters[name]; exists {
+		return fmt.Errorf("output format %q is already registered", name)
+	}
+	r.formatters[name] = formatter
+	return nil
+}
+
+// Lookup returns the formatter registered under name
+func (r *FormatterRegistry) Lookup(name string) (Formatter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	formatter, ok := r.formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q (available: %s)", name, strings.Join(r.namesLocked(), ", "))
+	}
+	return formatter, nil
+}
+
+// Names returns the registered format names in sorted order
+func (r *FormatterRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+func (r *FormatterRegistry) namesLocked() []string {
+	names := make([]string, 0, len(r.formatters))
+	for name := range r.formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultFormatters is the registry used by the command line
+var DefaultFormatters = NewFormatterRegistry()
+
+// RegisterFormatter adds a custom output format to DefaultFormatters
+func RegisterFormatter(name string, formatter Formatter) error {
+	return DefaultFormatters.Register(name, formatter)
+}
+
+// OutputFormatter handles formatting blame output for display
+type OutputFormatter struct {
+	ShowEmail bool
+	Porcelain bool
+	NoColors  bool
+	// ShowIssues adds a column with the issues closed by each line's PR/MR
+	ShowIssues bool
+	// ShowLabels adds a column with the labels of each line's PR/MR
+	ShowLabels bool
+	// AllApprovers lists every approver of a line's PR/MR instead of the last
+	AllApprovers bool
+	// GroupHunks blanks the commit, author, date, issue and label columns of lines
+	// continuing a hunk, a run of consecutive lines from the same commit
+	GroupHunks bool
+	// ColorBy colors the metadata of each line by approver, PR/MR or age
+	// with the colors of Theme, and dims unreviewed lines; "" disables it
+	ColorBy string
+	Theme   ColorTheme
+	// Hyperlinks links the commit to its PR/MR and the approver to their
+	// profile on Repo's host with OSC 8 escape sequences
+	Hyperlinks bool
+	Repo       *RepoInfo
+}
+
+// BlameLineWithApproval combines blame line with PR approval information
+type BlameLineWithApproval struct {
+	BlameLine
+	PRNumber      int
+	PRTitle       string
+	PRBody        string
+	PRAuthor      string
+	PRBranch      string
+	PRMergedAt    *time.Time
+	Approver      string
+	ApproverEmail string
+	ApprovalTime  *time.Time
+
+	// Approvers are all approvers of the PR/MR, oldest first; Approver is
+	// the last of them. The slice may be shared between lines.
+	Approvers []LineApprover
+
+	// ApproverTeam is the team of the approver, shown instead of them; only
+	// set with -show-team
+	ApproverTeam string
+
+	// Threads is the review thread status of the PR, nil when not fetched
+	Threads *ReviewThreadStatus
+
+	// ReviewRounds is the number of review iterations of the PR, 0 when not fetched
+	ReviewRounds int
+
+	// ApprovalRules is whether the PR/MR met its approval rules, nil when
+	// not fetched
+	ApprovalRules *ApprovalRuleStatus
+
+	// Objections are the reviewers who requested changes to the PR/MR or
+	// whose review was dismissed before it was merged, only fetched with
+	// -show-objections. The slice may be shared between lines.
+	Objections []Objection
+
+	// Repository is the "owner/name" the commit was imported from when it
+	// predates a configured migration, empty for the current repository
+	Repository string
+
+	// LinkedIssues are the issues the PR/MR description closes ("#12", "owner/repo#12")
+	LinkedIssues []string
+
+	// PRLabels are the labels of the PR/MR, its hashtags on Gerrit
+	PRLabels []string
+
+	// TrackerKey is the issue-tracker key (e.g. JIRA-1234) found in the PR/MR
+	// title or branch, and TrackerURL its link; see TrackerConfig
+	TrackerKey string
+	TrackerURL string
+
+	// Backport describes the mainline PR/MR a backport PR/MR was made from,
+	// nil when the line's PR/MR is not a detected backport
+	Backport *BackportOrigin
+
+	// ApproverIsOwner reports whether the approver was listed in the OWNERS
+	// files of the line's directory, nil when not checked or not applicable
+	ApproverIsOwner *bool
+
+	// LookupFailed is set when the line's PR/MR or its approvals could not be
+	// looked up, so whether it was reviewed is unknown (see ReviewState)
+	LookupFailed bool
+}
+
+// LineApprover is one approval of a line's PR/MR
+type LineApprover struct {
+	Name  string     `json:"name"`
+	Email string     `json:"email,omitempty"`
+	Time  *time.Time `json:"time,omitempty"`
+	// URL and AvatarURL link the approving review and the approver's avatar
+	// in reports; they are left out of records so snapshots stay comparable
+	URL       string `json:"-"`
+	AvatarURL string `json:"-"`
+}
+
+// distinctApprovers returns the approvers of a line's PR/MR, each once in
+// the order of their first approval, falling back to the single approver
+// when the full list was not fetched
+func distinctApprovers(line BlameLineWithApproval) []LineApprover {
+	if len(line.Approvers) == 0 {
+		if line.Approver == "" {
+			return nil
+		}
+		return []LineApprover{{Name: line.Approver, Email: line.ApproverEmail, Time: line.ApprovalTime}}
+	}
+	seen := make(map[string]bool, len(line.Approvers))
+	var approvers []LineApprover
+	for _, approver := range line.Approvers {
+		if !seen[approver.Name] {
+			seen[approver.Name] = true
+			approvers = append(approvers, approver)
+		}
+	}
+	return approvers
+}
+
+// FormatOutput formats the blame lines with approval information for display
+func (f *OutputFormatter) FormatOutput(lines []BlameLineWithApproval) string {
+	if f.Porcelain {
+		return f.formatPorcelain(lines)
+	}
+	return f.formatHuman(lines)
+}
+
+// formatHuman formats output in human-readable format similar to git blame
+func (f *OutputFormatter) formatHuman(lines []BlameLineWithApproval) string {
+	var result strings.Builder
+	f.WriteHuman(&result, lines)
+	return result.String()
+}
+
+// WriteHuman writes the human-readable format to w. The author and issue
+// columns are computed once, in the same pass that measures their widths,
+// and each line is assembled in a reused buffer.
+func (f *OutputFormatter) WriteHuman(w io.Writer, lines []BlameLineWithApproval) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	authors := make([]string, len(lines))
+	var issues []string
+	if f.ShowIssues {
+		issues = make([]string, len(lines))
+	}
+	maxAuthorWidth := 0
+	var labels []string
+	if f.ShowLabels {
+		labels = make([]string, len(lines))
+	}
+	maxIssuesWidth := 0
+	maxLabelsWidth := 0
+	maxLineNumber := 0
+	for i, line := range lines {
+		authors[i] = f.getHumanAuthorName(line)
+		maxAuthorWidth = max(maxAuthorWidth, utf8.RuneCountInString(authors[i]))
+		maxLineNumber = max(maxLineNumber, line.LineNumber)
+		if issues != nil {
+			issues[i] = formatIssues(line)
+			maxIssuesWidth = max(maxIssuesWidth, utf8.RuneCountInString(issues[i]))
+		}
+		if labels != nil {
+			labels[i] = strings.Join(line.PRLabels, ",")
+			maxLabelsWidth = max(maxLabelsWidth, utf8.RuneCountInString(labels[i]))
+		}
+	}
+
+	// Line numbers of -L ranges or partial hunks need not start at 1
+	maxLineNumWidth := len(strconv.Itoa(maxLineNumber))
+
+	out := bufio.NewWriter(w)
+	var buf, number, colored []byte
+	now := time.Now()
+	for i, line := range lines {
+		buf = buf[:0]
+		continuesHunk := f.GroupHunks && i > 0 && line.CommitHash == lines[i-1].CommitHash && line.LineNumber == lines[i-1].LineNumber+1
+		hyperlinks := f.Hyperlinks && !continuesHunk
+
+		// Commit hash (shortened to 8 chars), linked to the PR/MR
+		if url := f.pullRequestURL(line); hyperlinks && url != "" {
+			buf = appendHyperlink(buf, url, shortCommit(line.CommitHash))
+		} else {
+			buf = append(buf, shortCommit(line.CommitHash)...)
+		}
+
+		// Author name (approver if available, otherwise original author),
+		// linked to the approver's profile
+		buf = append(buf, " ("...)
+		if url := f.approverURL(line); hyperlinks && url != "" {
+			name := f.getAuthorName(line)
+			buf = appendHyperlink(buf, url, name)
+			buf = appendPadded(buf, authors[i][len(name):], maxAuthorWidth-utf8.RuneCountInString(name))
+		} else {
+			buf = appendPadded(buf, authors[i], maxAuthorWidth)
+		}
+
+		// Date (approval time if available, otherwise commit time)
+		buf = append(buf, ' ')
+		buf = f.appendDate(buf, line)
+		if issues != nil {
+			buf = append(buf, ' ')
+			buf = appendPadded(buf, issues[i], maxIssuesWidth)
+		}
+		if labels != nil {
+			buf = append(buf, ' ')
+			buf = appendPadded(buf, labels[i], maxLabelsWidth)
+		}
+		if continuesHunk {
+			// Keep the columns aligned by blanking the hunk's metadata
+			buf = appendSpaces(buf[:0], utf8.RuneCount(buf))
+		}
+
+		// Line number, right-aligned
+		buf = append(buf, ' ')
+		number = strconv.AppendInt(number[:0], int64(line.LineNumber), 10)
+		buf = appendSpaces(buf, maxLineNumWidth-len(number))
+		buf = append(buf, number...)
+
+		buf = append(buf, ") "...)
+		metadataEnd := len(buf)
+		buf = append(buf, line.Content...)
+		if color := f.lineColor(line, now); color != "" {
+			// Unreviewed lines are dimmed as a whole, others get their
+			// metadata colored
+			end := metadataEnd
+			if line.Approver == "" {
+				end = len(buf)
+			}
+			colored = appendColored(colored[:0], color, buf[:end])
+			colored = append(colored, buf[end:]...)
+			buf, colored = colored, buf
+		}
+		buf = append(buf, '\n')
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// pullRequestURL returns the web page of a line's PR/MR, or ""
+func (f *OutputFormatter) pullRequestURL(line BlameLineWithApproval) string {
+	if f.Repo == nil {
+		return ""
+	}
+	owner, name := lineRepository(f.Repo, line)
+	return f.Repo.PullRequestURL(owner, name, line.PRNumber)
+}
+
+// approverURL returns the profile page of the approver shown for a line, or
+// "" when several are shown or the approver is not known to be an account:
+// only approvers from the service's API come with an avatar, which
+// anonymization removes
+func (f *OutputFormatter) approverURL(line BlameLineWithApproval) string {
+	if f.Repo == nil || line.Approver == "" || line.ApproverTeam != "" || f.AllApprovers && len(line.Approvers) > 1 {
+		return ""
+	}
+	for _, approver := range line.Approvers {
+		if approver.Name == line.Approver && approver.AvatarURL != "" {
+			return f.Repo.UserURL(approver.Name)
+		}
+	}
+	return ""
+}
+
+// appendHyperlink appends text as an OSC 8 terminal hyperlink to url
+func appendHyperlink(buf []byte, url, text string) []byte {
+	buf = append(buf, "\033]8;;"...)
+	buf = append(buf, url...)
+	buf = append(buf, "\033\\"...)
+	buf = append(buf, text...)
+	return append(buf, "\033]8;;\033\\"...)
+}
+
+// lineColor returns the color of a line in the human format, "" when it
+// stays uncolored
+func (f *OutputFormatter) lineColor(line BlameLineWithApproval, now time.Time) string {
+	if f.ColorBy == "" || f.NoColors {
+		return ""
+	}
+	if line.Approver == "" {
+		return f.Theme.Unreviewed
+	}
+	return f.Theme.lineColor(f.ColorBy, line, now)
+}
+
+// appendPadded appends s left-aligned in a column of width runes, like %-*s
+func appendPadded(buf []byte, s string, width int) []byte {
+	buf = append(buf, s...)
+	return appendSpaces(buf, width-utf8.RuneCountInString(s))
+}
+
+// appendSpaces appends n spaces; n <= 0 appends nothing
+func appendSpaces(buf []byte, n int) []byte {
+	for ; n > 0; n-- {
+		buf = append(buf, ' ')
+	}
+	return buf
+}
+
+// formatPorcelain formats output in porcelain format for machine parsing
+func (f *OutputFormatter) formatPorcelain(lines []BlameLineWithApproval) string {
+	var result strings.Builder
+	f.WritePorcelain(&result, lines)
+	return result.String()
+}
+
+// WritePorcelain writes the porcelain format to w, assembling each entry in
+// a reused buffer
+func (f *OutputFormatter) WritePorcelain(w io.Writer, lines []BlameLineWithApproval) error {
+	out := bufio.NewWriter(w)
+	var buf []byte
+	field := func(key, value string) {
+		buf = append(buf, key...)
+		buf = append(buf, ' ')
+		buf = append(buf, value...)
+		buf = append(buf, '\n')
+	}
+	intField := func(key string, value int64) {
+		buf = append(buf, key...)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, value, 10)
+		buf = append(buf, '\n')
+	}
+
+	for _, line := range lines {
+		buf = buf[:0]
+
+		// Commit hash and line info: the line number in the commit that
+		// introduced the line, then in the final file
+		originalLine := line.OriginalLineNumber
+		if originalLine == 0 {
+			originalLine = line.LineNumber
+		}
+		buf = append(buf, line.CommitHash...)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(originalLine), 10)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(line.LineNumber), 10)
+		buf = append(buf, " 1\n"...)
+
+		// Author info (use approver if available)
+		if line.Approver != "" {
+			field("author", line.Approver)
+			if line.ApproverEmail != "" {
+				field("author-mail", "<"+line.ApproverEmail+">")
+			}
+			if line.ApprovalTime != nil {
+				intField("author-time", line.ApprovalTime.Unix())
+			}
+		} else {
+			// Fall back to original author
+			field("author", line.Author)
+			field("author-mail", "<"+line.AuthorEmail+">")
+			if timestamp, err := strconv.ParseInt(line.Date, 10, 64); err == nil {
+				intField("author-time", timestamp)
+			}
+		}
+
+		if f.AllApprovers {
+			for _, approver := range line.Approvers {
+				field("approver", approver.Name)
+				if approver.Email != "" {
+					field("approver-mail", "<"+approver.Email+">")
+				}
+				if approver.Time != nil {
+					intField("approver-time", approver.Time.Unix())
+				}
+			}
+		}
+
+		// Additional PR info
+		if line.ApproverTeam != "" {
+			field("approver-team", line.ApproverTeam)
+		}
+		if line.PRNumber > 0 {
+			intField("pr-number", int64(line.PRNumber))
+		}
+		field("review-state", line.ReviewState())
+		if line.Repository != "" {
+			field("pr-repository", line.Repository)
+		}
+		if len(line.LinkedIssues) > 0 {
+			field("linked-issues", strings.Join(line.LinkedIssues, " "))
+		}
+		if len(line.PRLabels) > 0 {
+			field("pr-labels", strings.Join(line.PRLabels, ","))
+		}
+		if line.TrackerKey != "" {
+			field("tracker-key", line.TrackerKey)
+			if line.TrackerURL != "" {
+				field("tracker-url", line.TrackerURL)
+			}
+		}
+		if line.Threads != nil {
+			intField("review-threads", int64(line.Threads.Total))
+			intField("unresolved-threads", int64(line.Threads.Unresolved()))
+		}
+		if line.ReviewRounds > 0 {
+			intField("review-rounds", int64(line.ReviewRounds))
+		}
+		if line.ApprovalRules != nil && !line.ApprovalRules.Satisfied() {
+			field("unmet-approval-rules", strings.Join(line.ApprovalRules.Unmet, ", "))
+		}
+		for _, objection := range line.Objections {
+			field("objection", objection.Name+" "+objection.State)
+		}
+		if line.ApproverIsOwner != nil {
+			field("approver-is-owner", strconv.FormatBool(*line.ApproverIsOwner))
+		}
+		if line.Backport != nil {
+			intField("original-pr-number", int64(line.Backport.PRNumber))
+			if line.Backport.Approver != "" {
+				field("original-approver", line.Backport.Approver)
+			}
+		}
+
+		field("filename", line.Filename)
+		if line.OriginalFilename != "" && line.OriginalFilename != line.Filename {
+			field("original-filename", line.OriginalFilename)
+		}
+		buf = append(buf, '\t')
+		buf = append(buf, line.Content...)
+		buf = append(buf, '\n')
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// getAuthorName returns the appropriate author name (approver preferred)
+func (f *OutputFormatter) getAuthorName(line BlameLineWithApproval) string {
+	if f.AllApprovers && len(line.Approvers) > 1 {
+		return f.approverList(line.Approvers)
+	}
+	if line.ApproverTeam != "" {
+		return line.ApproverTeam
+	}
+	if line.Approver != "" {
+		if f.ShowEmail && line.ApproverEmail != "" {
+			return line.ApproverEmail
+		}
+		return line.Approver
+	}
+
+	if f.ShowEmail && line.AuthorEmail != "" {
+		return line.AuthorEmail
+	}
+	return line.Author
+}
+
+// approverList joins the distinct approvers in the order they first approved
+func (f *OutputFormatter) approverList(approvers []LineApprover) string {
+	seen := make(map[string]bool, len(approvers))
+	names := make([]string, 0, len(approvers))
+	for _, approver := range approvers {
+		name := approver.Name
+		if f.ShowEmail && approver.Email != "" {
+			name = approver.Email
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// getHumanAuthorName returns the author column of the human format: the
+// author name, followed by the original PR/MR and its approver for backports
+func (f *OutputFormatter) getHumanAuthorName(line BlameLineWithApproval) string {
+	name := f.getAuthorName(line)
+	if origin := line.Backport; origin != nil && origin.PRNumber > 0 {
+		original := origin.Approver
+		if f.ShowEmail && origin.ApproverEmail != "" {
+			original = origin.ApproverEmail
+		}
+		name = fmt.Sprintf("%s <- %s#%d", name, original, origin.PRNumber)
+	}
+	if len(line.Objections) > 0 {
+		name += " (objected: " + objectorList(line.Objections) + ")"
+	}
+	// Lines without an approver show their commit author, marked with why;
+	// self-approved lines are marked too
+	if marker, ok := reviewStateMarkers[line.ReviewState()]; ok {
+		name += " " + marker
+	}
+	return name
+}
+
+// getDateString returns formatted date string (approval time preferred)
+func (f *OutputFormatter) getDateString(line BlameLineWithApproval) string {
+	return string(f.appendDate(nil, line))
+}
+
+// appendDate appends the date of getDateString to buf
+func (f *OutputFormatter) appendDate(buf []byte, line BlameLineWithApproval) []byte {
+	if line.ApprovalTime != nil {
+		return line.ApprovalTime.AppendFormat(buf, "2006-01-02 15:04:05")
+	}
+
+	// Try to parse original commit date
+	if timestamp, err := strconv.ParseInt(line.Date, 10, 64); err == nil {
+		return time.Unix(timestamp, 0).AppendFormat(buf, "2006-01-02 15:04:05")
+	}
+
+	return append(buf, line.Date...)
+}
+
+// NewOutputFormatter creates a new formatter with the given options
+func NewOutputFormatter(showEmail, porcelain, noColors bool) *OutputFormatter {
+	return &OutputFormatter{
+		ShowEmail: showEmail,
+		Porcelain: porcelain,
+		NoColors:  noColors,
+	}
+}
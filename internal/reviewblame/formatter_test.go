@@ -0,0 +1,539 @@
+package reviewblame
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewOutputFormatter(t *testing.T) {
+	formatter := NewOutputFormatter(true, false, true)
+	
+	if !formatter.ShowEmail {
+		t.Error("expected ShowEmail to be true")
+	}
+	if formatter.Porcelain {
+		t.Error("expected Porcelain to be false")
+	}
+	if !formatter.NoColors {
+		t.Error("expected NoColors to be true")
+	}
+}
+
+func TestFormatHuman(t *testing.T) {
+	approvalTime := time.Unix(1609632000, 0)
+	
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine: BlameLine{
+				CommitHash:  "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0",
+				Author:      "John Doe",
+				AuthorEmail: "john@example.com",
+				Date:        "1609459200",
+				LineNumber:  1,
+				Content:     "package main",
+			},
+			PRNumber:      123,
+			Approver:      "Jane Smith",
+			ApproverEmail: "jane@example.com", 
+			ApprovalTime:  &approvalTime,
+		},
+		{
+			BlameLine: BlameLine{
+				CommitHash:  "b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1",
+				Author:      "Bob Wilson", 
+				AuthorEmail: "bob@example.com",
+				Date:        "1609545600",
+				LineNumber:  2,
+				Content:     "import \"fmt\"",
+			},
+			// No PR info - should fall back to original author
+		},
+	}
+	
+	formatter := NewOutputFormatter(false, false, true)
+	output := formatter.FormatOutput(lines)
+	
+	// Check that output contains expected elements
+	if !strings.Contains(output, "a1b2c3d4") {
+		t.Error("expected shortened commit hash in output")
+	}
+	if !strings.Contains(output, "Jane Smith") {
+		t.Error("expected approver name in output")
+	}
+	if !strings.Contains(output, "Bob Wilson") {
+		t.Error("expected fallback to original author")
+	}
+	if !strings.Contains(output, "package main") {
+		t.Error("expected code content in output")
+	}
+	if !strings.Contains(output, "2021-01-03") {
+		t.Error("expected formatted approval time")
+	}
+	
+	// Check line numbers
+	if !strings.Contains(output, " 1) ") {
+		t.Error("expected line number 1 in output")
+	}
+	if !strings.Contains(output, " 2) ") {
+		t.Error("expected line number 2 in output")
+	}
+}
+
+func TestFormatHumanWithEmail(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine: BlameLine{
+				CommitHash:  "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0",
+				Author:      "John Doe",
+				AuthorEmail: "john@example.com",
+				Date:        "1609459200",
+				LineNumber:  1,
+				Content:     "package main",
+			},
+			Approver:      "Jane Smith",
+			ApproverEmail: "jane@example.com",
+		},
+	}
+	
+	formatter := NewOutputFormatter(true, false, true) // ShowEmail = true
+	output := formatter.FormatOutput(lines)
+	
+	if !strings.Contains(output, "jane@example.com") {
+		t.Error("expected approver email in output when ShowEmail=true")
+	}
+	if strings.Contains(output, "Jane Smith") {
+		t.Error("expected email instead of name when ShowEmail=true")
+	}
+}
+
+func TestFormatPorcelain(t *testing.T) {
+	approvalTime := time.Unix(1609632000, 0)
+	
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine: BlameLine{
+				CommitHash:  "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0",
+				Author:      "John Doe",
+				AuthorEmail: "john@example.com", 
+				Date:        "1609459200",
+				LineNumber:  1,
+				Content:     "package main",
+			},
+			PRNumber:      123,
+			Approver:      "Jane Smith",
+			ApproverEmail: "jane@example.com",
+			ApprovalTime:  &approvalTime,
+		},
+	}
+	
+	formatter := NewOutputFormatter(false, true, true) // Porcelain = true
+	output := formatter.FormatOutput(lines)
+	
+	expectedLines := []string{
+		"a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 1 1 1",
+		"author Jane Smith",
+		"author-mail <jane@example.com>",
+		"author-time 1609632000", 
+		"pr-number 123",
+		"\tpackage main",
+	}
+	
+	for _, expected := range expectedLines {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in porcelain output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestFormatPorcelainFallback(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine: BlameLine{
+				CommitHash:  "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0",
+				Author:      "John Doe",
+				AuthorEmail: "john@example.com",
+				Date:        "1609459200", 
+				LineNumber:  1,
+				Content:     "package main",
+			},
+			// No approver info - should use original author
+		},
+	}
+	
+	formatter := NewOutputFormatter(false, true, true)
+	output := formatter.FormatOutput(lines)
+	
+	expectedLines := []string{
+		"author John Doe",
+		"author-mail <john@example.com>",
+		"author-time 1609459200",
+	}
+	
+	for _, expected := range expectedLines {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in porcelain fallback output, got:\n%s", expected, output)
+		}
+	}
+	
+	// Should not contain PR info
+	if strings.Contains(output, "pr-number") {
+		t.Error("should not contain pr-number when no PR info available")
+	}
+}
+
+func TestGetAuthorName(t *testing.T) {
+	formatter := NewOutputFormatter(false, false, false)
+	
+	// Test with approver info
+	lineWithApprover := BlameLineWithApproval{
+		BlameLine: BlameLine{
+			Author:      "John Doe",
+			AuthorEmail: "john@example.com",
+		},
+		Approver:      "Jane Smith", 
+		ApproverEmail: "jane@example.com",
+	}
+	
+	name := formatter.getAuthorName(lineWithApprover)
+	if name != "Jane Smith" {
+		t.Errorf("expected 'Jane Smith', got '%s'", name)
+	}
+	
+	// Test fallback to original author
+	lineWithoutApprover := BlameLineWithApproval{
+		BlameLine: BlameLine{
+			Author:      "John Doe",
+			AuthorEmail: "john@example.com",
+		},
+	}
+	
+	name = formatter.getAuthorName(lineWithoutApprover)
+	if name != "John Doe" {
+		t.Errorf("expected 'John Doe', got '%s'", name)
+	}
+	
+	// Test with ShowEmail
+	formatter.ShowEmail = true
+	name = formatter.getAuthorName(lineWithApprover)
+	if name != "jane@example.com" {
+		t.Errorf("expected 'jane@example.com', got '%s'", name)
+	}
+}
+
+func TestGetDateString(t *testing.T) {
+	formatter := NewOutputFormatter(false, false, false)
+	
+	approvalTime := time.Unix(1609632000, 0)
+	
+	// Test with approval time
+	lineWithApproval := BlameLineWithApproval{
+		BlameLine: BlameLine{
+			Date: "1609459200",
+		},
+		ApprovalTime: &approvalTime,
+	}
+	
+	dateStr := formatter.getDateString(lineWithApproval)
+	if !strings.Contains(dateStr, "2021-01-03") {
+		t.Errorf("expected formatted approval time, got '%s'", dateStr)
+	}
+	
+	// Test fallback to commit date
+	lineWithoutApproval := BlameLineWithApproval{
+		BlameLine: BlameLine{
+			Date: "1609459200",
+		},
+	}
+	
+	dateStr = formatter.getDateString(lineWithoutApproval)
+	if !strings.Contains(dateStr, "2021-01-01") {
+		t.Errorf("expected formatted commit time, got '%s'", dateStr)
+	}
+}
+
+func TestFormatOutputEmpty(t *testing.T) {
+	formatter := NewOutputFormatter(false, false, false)
+	output := formatter.FormatOutput([]BlameLineWithApproval{})
+	
+	if output != "" {
+		t.Errorf("expected empty output for empty input, got '%s'", output)
+	}
+}
+func TestFormatterRegistryBuiltins(t *testing.T) {
+	registry := NewFormatterRegistry()
+
+	names := registry.Names()
+	if strings.Join(names, " ") != "annotations csv dot html human incremental markdown porcelain pr-summary" {
+		t.Errorf("expected built-in formats [annotations csv dot html human incremental markdown porcelain pr-summary], got %v", names)
+	}
+
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine: BlameLine{
+				CommitHash:  "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0",
+				Author:      "John Doe",
+				AuthorEmail: "john@example.com",
+				Date:        "1609459200",
+				LineNumber:  1,
+				Content:     "package main",
+			},
+		},
+	}
+
+	porcelain, err := registry.Lookup("porcelain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := NewOutputFormatter(false, true, false).FormatOutput(lines)
+	if output := porcelain.Format(lines, FormatOptions{}); output != expected {
+		t.Errorf("expected porcelain formatter to match OutputFormatter, got:\n%s", output)
+	}
+
+	human, err := registry.Lookup("human")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output := human.Format(lines, FormatOptions{ShowEmail: true}); !strings.Contains(output, "john@example.com") {
+		t.Errorf("expected ShowEmail to be honored, got:\n%s", output)
+	}
+}
+
+func TestFormatterRegistryRegister(t *testing.T) {
+	registry := NewFormatterRegistry()
+
+	custom := FormatterFunc(func(lines []BlameLineWithApproval, opts FormatOptions) string {
+		return "custom output"
+	})
+	if err := registry.Register("custom", custom); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := registry.Register("custom", custom); err == nil {
+		t.Error("expected error registering a duplicate name")
+	}
+	if err := registry.Register("", custom); err == nil {
+		t.Error("expected error registering an empty name")
+	}
+
+	formatter, err := registry.Lookup("custom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output := formatter.Format(nil, FormatOptions{}); output != "custom output" {
+		t.Errorf("expected custom output, got %q", output)
+	}
+
+	_, err = registry.Lookup("missing")
+	if err == nil || !strings.Contains(err.Error(), "available: annotations, csv, custom, dot, html, human, incremental, markdown, porcelain") {
+		t.Errorf("expected unknown format error listing available formats, got %v", err)
+	}
+}
+
+func TestWriteHumanAlignment(t *testing.T) {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine:    BlameLine{CommitHash: "a1b2c3d4e5f6", LineNumber: 9, Content: "first"},
+			Approver:     "Zoë",
+			ApprovalTime: &approvalTime,
+			LinkedIssues: []string{"#12"},
+		},
+		{
+			BlameLine:    BlameLine{CommitHash: "b1b2c3d4e5f6", LineNumber: 10, Content: "second"},
+			Approver:     "bob",
+			ApprovalTime: &approvalTime,
+		},
+	}
+
+	formatter := NewOutputFormatter(false, false, false)
+	formatter.ShowIssues = true
+	var output bytes.Buffer
+	if err := formatter.WriteHuman(&output, lines); err != nil {
+		t.Fatalf("WriteHuman failed: %v", err)
+	}
+
+	// Columns are measured and padded by runes, like fmt's %-*s, and line
+	// numbers are as wide as the largest one rather than the line count
+	want := "a1b2c3d4 (Zoë 2024-05-02 10:00:00 #12  9) first\n" +
+		"b1b2c3d4 (bob 2024-05-02 10:00:00     10) second\n"
+	if output.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", output.String(), want)
+	}
+}
+
+func TestWriteFormatted(t *testing.T) {
+	lines := []BlameLineWithApproval{{BlameLine: BlameLine{CommitHash: "a1b2c3d4", LineNumber: 1, Content: "x"}}}
+
+	var streamed bytes.Buffer
+	porcelain, _ := NewFormatterRegistry().Lookup("porcelain")
+	if err := WriteFormatted(&streamed, porcelain, lines, FormatOptions{}); err != nil {
+		t.Fatalf("WriteFormatted failed: %v", err)
+	}
+	if streamed.String() != porcelain.Format(lines, FormatOptions{}) {
+		t.Errorf("streamed output differs from Format:\n%s", streamed.String())
+	}
+
+	var plain bytes.Buffer
+	custom := FormatterFunc(func(lines []BlameLineWithApproval, opts FormatOptions) string { return "custom\n" })
+	if err := WriteFormatted(&plain, custom, lines, FormatOptions{}); err != nil || plain.String() != "custom\n" {
+		t.Errorf("expected custom output, got %q, %v", plain.String(), err)
+	}
+}
+
+func TestFormatAllApprovers(t *testing.T) {
+	first := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine: BlameLine{CommitHash: "a1b2c3d4e5f6", Author: "John Doe", Date: "1609459200", LineNumber: 1, Filename: "main.go", Content: "package main"},
+			PRNumber:  42,
+			Approver:  "bob", ApproverEmail: "bob@example.com", ApprovalTime: &last,
+			Approvers: []LineApprover{
+				{Name: "alice", Time: &first},
+				{Name: "bob", Email: "bob@example.com", Time: &last},
+				{Name: "alice", Time: &last},
+			},
+		},
+	}
+	registry := NewFormatterRegistry()
+
+	human, _ := registry.Lookup("human")
+	if got := human.Format(lines, FormatOptions{AllApprovers: true}); !strings.Contains(got, "(alice, bob 2024-05-02") {
+		t.Errorf("expected a comma-separated approver list, got %q", got)
+	}
+	if got := human.Format(lines, FormatOptions{}); !strings.Contains(got, "(bob 2024-05-02") {
+		t.Errorf("expected only the last approver by default, got %q", got)
+	}
+
+	porcelain, _ := registry.Lookup("porcelain")
+	got := porcelain.Format(lines, FormatOptions{AllApprovers: true})
+	want := "approver alice\napprover-time 1714554000\napprover bob\napprover-mail <bob@example.com>\napprover-time 1714644000\napprover alice\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("expected every approval in porcelain output, got:\n%s", got)
+	}
+	if strings.Contains(porcelain.Format(lines, FormatOptions{}), "approver ") {
+		t.Error("expected no approver fields by default")
+	}
+}
+
+// benchmarkLines returns n annotated lines resembling a large file
+func benchmarkLines(n int) []BlameLineWithApproval {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	lines := make([]BlameLineWithApproval, n)
+	for i := range lines {
+		lines[i] = BlameLineWithApproval{
+			BlameLine: BlameLine{
+				CommitHash: "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0",
+				Author:     "John Doe", AuthorEmail: "john@example.com", Date: "1609459200",
+				LineNumber: i + 1, Filename: "main.go",
+				Content: "	result = append(result, strings.Repeat(\"x\", 40))",
+			},
+			PRNumber:     42,
+			Approver:     "alice",
+			ApprovalTime: &approvalTime,
+		}
+	}
+	return lines
+}
+
+func BenchmarkWriteHuman(b *testing.B) {
+	lines := benchmarkLines(100000)
+	formatter := NewOutputFormatter(false, false, false)
+	b.ReportAllocs()
+	for b.Loop() {
+		formatter.WriteHuman(io.Discard, lines)
+	}
+}
+
+func BenchmarkWritePorcelain(b *testing.B) {
+	lines := benchmarkLines(100000)
+	formatter := NewOutputFormatter(false, true, false)
+	b.ReportAllocs()
+	for b.Loop() {
+		formatter.WritePorcelain(io.Discard, lines)
+	}
+}
+
+func TestWriteHumanGroupHunks(t *testing.T) {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	line := func(commit string, number int, content string) BlameLineWithApproval {
+		return BlameLineWithApproval{
+			BlameLine:    BlameLine{CommitHash: commit, LineNumber: number, Content: content},
+			Approver:     "alice",
+			ApprovalTime: &approvalTime,
+		}
+	}
+	lines := []BlameLineWithApproval{
+		line("a1b2c3d4e5f6", 1, "func main() {"),
+		line("a1b2c3d4e5f6", 2, "\trun()"),
+		line("b1b2c3d4e5f6", 3, "}"),
+		// Not consecutive: -L ranges start a new hunk
+		line("b1b2c3d4e5f6", 5, "// end"),
+	}
+
+	human, _ := NewFormatterRegistry().Lookup("human")
+	got := human.Format(lines, FormatOptions{GroupHunks: true})
+	want := "a1b2c3d4 (alice 2024-05-02 10:00:00 1) func main() {\n" +
+		"                                    2) \trun()\n" +
+		"b1b2c3d4 (alice 2024-05-02 10:00:00 3) }\n" +
+		"b1b2c3d4 (alice 2024-05-02 10:00:00 5) // end\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if strings.Count(human.Format(lines, FormatOptions{}), "a1b2c3d4") != 2 {
+		t.Error("expected every line to show its commit by default")
+	}
+}
+
+func TestWriteHumanHyperlinks(t *testing.T) {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine:    BlameLine{CommitHash: "a1b2c3d4e5f6", LineNumber: 1, Content: "first"},
+			PRNumber:     42,
+			Approver:     "alice",
+			ApprovalTime: &approvalTime,
+			Approvers:    []LineApprover{{Name: "alice", Time: &approvalTime, AvatarURL: "https://avatars.example.com/1"}},
+		},
+		{
+			// Trailer approvers are names, not accounts
+			BlameLine:    BlameLine{CommitHash: "b1b2c3d4e5f6", LineNumber: 2, Content: "second"},
+			Approver:     "Bob Smith",
+			ApprovalTime: &approvalTime,
+		},
+	}
+	repo := &RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com", Owner: "owner", Name: "repo"}
+	human, _ := NewFormatterRegistry().Lookup("human")
+
+	got := human.Format(lines, FormatOptions{Hyperlinks: true, Repo: repo})
+	want := "\033]8;;https://github.com/owner/repo/pull/42\033\\a1b2c3d4\033]8;;\033\\ (" +
+		"\033]8;;https://github.com/alice\033\\alice\033]8;;\033\\     2024-05-02 10:00:00 1) first\n" +
+		"b1b2c3d4 (Bob Smith 2024-05-02 10:00:00 2) second\n"
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+	if got := human.Format(lines, FormatOptions{Repo: repo}); strings.Contains(got, "\033]8") {
+		t.Errorf("expected no hyperlinks by default, got %q", got)
+	}
+}
+
+func TestFormatPorcelainOriginalLocation(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0", Filename: "new.go", LineNumber: 12, OriginalLineNumber: 3, OriginalFilename: "old.go", Content: "moved"}},
+		{BlameLine: BlameLine{CommitHash: "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0", Filename: "new.go", LineNumber: 13, OriginalLineNumber: 13, OriginalFilename: "new.go", Content: "kept"}},
+	}
+
+	output := NewOutputFormatter(false, true, true).FormatOutput(lines)
+	for _, expected := range []string{
+		"a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 3 12 1\n",
+		"filename new.go\noriginal-filename old.go\n\tmoved",
+		"a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 13 13 1\n",
+		"filename new.go\n\tkept",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in porcelain output, got:\n%s", expected, output)
+		}
+	}
+}
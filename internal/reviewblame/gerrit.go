@@ -0,0 +1,291 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Environment variables holding the Gerrit account and its HTTP password.
+// Without them changes are read anonymously.
+const (
+	GerritUserEnv  = "GERRIT_USER"
+	GerritTokenEnv = "GERRIT_TOKEN"
+)
+
+// changeIDPattern matches the Change-Id trailer added by Gerrit's commit-msg hook
+var changeIDPattern = regexp.MustCompile(`(?m)^Change-Id:\s*(I[0-9a-f]{40})\s*$`)
+
+// gerritXSSIPrefix is prepended by Gerrit to every JSON response
+const gerritXSSIPrefix = ")]}'"
+
+// gerritApprovalScore is the Code-Review vote that approves a change
+const gerritApprovalScore = 2
+
+// ParseChangeID returns the Change-Id trailer of a commit message, or ""
+func ParseChangeID(message string) string {
+	matches := changeIDPattern.FindAllStringSubmatch(message, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	// The last Change-Id wins, as in Gerrit
+	return matches[len(matches)-1][1]
+}
+
+// GerritClient handles Gerrit REST API interactions. Changes stand in for
+// pull requests and Code-Review +2 votes for approvals.
+type GerritClient struct {
+	user       string
+	token      string
+	httpClient *http.Client
+	baseURL    string
+	// repoRoot is the local clone whose commit messages hold Change-Ids;
+	// without it changes are looked up by commit only
+	repoRoot string
+}
+
+// NewGerritClient creates a new Gerrit API client for the server at host.
+// Requests are anonymous unless both user and token are set.
+func NewGerritClient(user, token, host string) *GerritClient {
+	return &GerritClient{
+		user:       user,
+		token:      token,
+		baseURL:    "https://" + host,
+		httpClient: newHTTPClient(30 * time.Second),
+	}
+}
+
+// newGerritClientForRepo creates a client for the server of repoInfo, with
+// credentials from GERRIT_USER and GERRIT_TOKEN
+func newGerritClientForRepo(repoRoot string, repoInfo *RepoInfo) *GerritClient {
+	client := NewGerritClient(os.Getenv(GerritUserEnv), os.Getenv(GerritTokenEnv), repoInfo.Host)
+	if repoInfo.APIURL != "" {
+		client.baseURL = repoInfo.APIURL
+	}
+	client.repoRoot = repoRoot
+	return client
+}
+
+// getJSON makes a request to the Gerrit API and decodes the response into
+// result. Authenticated requests go through the /a/ prefix.
+func (c *GerritClient) getJSON(ctx context.Context, path string, result interface{}) error {
+	base := c.baseURL
+	if c.user != "" && c.token != "" {
+		base += "/a"
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", base+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.user != "" && c.token != "" {
+		req.SetBasicAuth(c.user, c.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := doRequest(c.httpClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Gerrit API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	data, err := io.ReadAll(newResponseReader(resp.Body))
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimPrefix(data, []byte(gerritXSSIPrefix))
+	return json.Unmarshal(data, result)
+}
+
+// gerritTime is a Gerrit timestamp: UTC as "2006-01-02 15:04:05.000000000"
+type gerritTime struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *gerritTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse("2006-01-02 15:04:05.999999999", s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// GerritAccount is an account as returned by the Gerrit API
+type GerritAccount struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// login returns the username, or the name when usernames are not exposed
+func (a GerritAccount) login() string {
+	if a.Username != "" {
+		return a.Username
+	}
+	return a.Name
+}
+
+// GerritChange represents a change from the Gerrit API
+type GerritChange struct {
+	Number    int           `json:"_number"`
+	Subject   string        `json:"subject"`
+	Status    string        `json:"status"`
+	Owner     GerritAccount `json:"owner"`
+	Submitted *gerritTime   `json:"submitted"`
+	// Hashtags are Gerrit's counterpart of PR/MR labels
+	Hashtags []string `json:"hashtags"`
+	Labels   map[string]struct {
+		All []struct {
+			GerritAccount
+			Value int         `json:"value"`
+			Date  *gerritTime `json:"date"`
+		} `json:"all"`
+	} `json:"labels"`
+}
+
+// toPullRequest converts a Gerrit change to GitHub PR format
+func (c GerritChange) toPullRequest() *PullRequest {
+	pr := &PullRequest{
+		Number: c.Number,
+		Title:  c.Subject,
+		State:  c.Status,
+	}
+	pr.User.Login = c.Owner.login()
+	for _, hashtag := range c.Hashtags {
+		pr.Labels = append(pr.Labels, PRLabel{Name: hashtag})
+	}
+	if c.Submitted != nil {
+		pr.MergedAt = &c.Submitted.Time
+	}
+	return pr
+}
+
+// gerritChangeOptions requests the votes of each reviewer
+const gerritChangeOptions = "o=DETAILED_LABELS&o=DETAILED_ACCOUNTS"
+
+// changeID reads the Change-Id trailer of a local commit
+func (c *GerritClient) changeID(commitHash string) string {
+	if c.repoRoot == "" {
+		return ""
+	}
+	cmd := exec.Command("git", "log", "-1", "--format=%B", commitHash)
+	cmd.Dir = c.repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return ParseChangeID(string(output))
+}
+
+// FindPRByCommit finds the change of a commit through its Change-Id trailer,
+// or by the commit itself when it has none. A Change-Id shared by changes on
+// several branches resolves to a merged one.
+func (c *GerritClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	query := "commit:" + commitHash
+	if changeID := c.changeID(commitHash); changeID != "" {
+		query = "change:" + changeID
+	}
+
+	var changes []GerritChange
+	if err := c.getJSON(ctx, "/changes/?q="+url.QueryEscape(query), &changes); err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	found := changes[0]
+	for _, change := range changes {
+		if change.Status == "MERGED" {
+			found = change
+			break
+		}
+	}
+	return found.toPullRequest(), nil
+}
+
+// GetPRApprovals gets the users who voted Code-Review +2 on a change, in
+// the order they voted
+func (c *GerritClient) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	var change GerritChange
+	if err := c.getJSON(ctx, fmt.Sprintf("/changes/%d?%s", prNumber, gerritChangeOptions), &change); err != nil {
+		return nil, err
+	}
+
+	var approvals []Review
+	for _, vote := range change.Labels["Code-Review"].All {
+		if vote.Value < gerritApprovalScore {
+			continue
+		}
+		review := Review{State: "APPROVED"}
+		review.User.Login = vote.login()
+		review.User.Email = vote.Email
+		if vote.Date != nil {
+			review.SubmittedAt = &vote.Date.Time
+		}
+		approvals = append(approvals, review)
+	}
+	sort.SliceStable(approvals, func(i, j int) bool {
+		if approvals[i].SubmittedAt == nil || approvals[j].SubmittedAt == nil {
+			return approvals[j].SubmittedAt != nil
+		}
+		return approvals[i].SubmittedAt.Before(*approvals[j].SubmittedAt)
+	})
+
+	return approvals, nil
+}
+
+// GetPRApprovalInfo gets complete approval information for a commit
+func (c *GerritClient) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	pr, err := c.FindPRByCommit(ctx, owner, repo, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr == nil {
+		return nil, fmt.Errorf("no change found for commit %s", commitHash)
+	}
+
+	approvals, err := c.GetPRApprovals(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRApprovalInfo{
+		PR:        *pr,
+		Approvers: approvals,
+	}, nil
+}
+
+// usesGerrit reports whether an unrecognized self-hosted remote is a Gerrit
+// server, judged by a Change-Id trailer on HEAD
+func usesGerrit(repoRoot string, repoInfo *RepoInfo) bool {
+	if repoInfo.Type != RepositoryTypeGitLab || repoInfo.Host == "gitlab.com" || repoInfo.APIURL != "" {
+		return false
+	}
+	cmd := exec.Command("git", "log", "-1", "--format=%B", "HEAD")
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return ParseChangeID(string(output)) != ""
+}
@@ -0,0 +1,131 @@
+package reviewblame
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testChangeID = "I0123456789abcdef0123456789abcdef01234567"
+
+func TestParseChangeID(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"Fix parser\n\nChange-Id: " + testChangeID + "\n", testChangeID},
+		{"Fix parser\n\nChange-Id: Ideadbeef\n", ""},
+		{"Fix parser\n\nMentions Change-Id: " + testChangeID, ""},
+		{"Fix parser", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParseChangeID(tt.message); got != tt.expected {
+			t.Errorf("ParseChangeID(%q) = %q, want %q", tt.message, got, tt.expected)
+		}
+	}
+}
+
+func TestGerritGetPRApprovalInfo(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+	gitCommand(t, dir, "config", "user.email", "alice@example.com")
+	gitCommand(t, dir, "config", "user.name", "Alice")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main\n\nChange-Id: "+testChangeID)
+	commitHash := strings.TrimSpace(gitCommand(t, dir, "rev-parse", "HEAD"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "alice" || password != "secret" {
+			t.Errorf("expected basic auth as alice, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(gerritXSSIPrefix + "\n"))
+		switch r.URL.Path {
+		case "/a/changes/":
+			if r.URL.Query().Get("q") != "change:"+testChangeID {
+				t.Errorf("unexpected query %q", r.URL.Query().Get("q"))
+			}
+			w.Write([]byte(`[
+				{"_number": 40, "subject": "Add main", "status": "ABANDONED"},
+				{"_number": 41, "subject": "Add main", "status": "MERGED", "hashtags": ["security"],
+				 "owner": {"name": "Alice", "username": "alice"}, "submitted": "2024-03-02 10:00:00.000000000"}
+			]`))
+		case "/a/changes/41":
+			w.Write([]byte(`{"_number": 41, "labels": {"Code-Review": {"all": [
+				{"username": "carol", "email": "carol@example.com", "value": 2, "date": "2024-03-01 12:00:00.000000000"},
+				{"username": "dave", "value": 1, "date": "2024-03-01 08:00:00.000000000"},
+				{"name": "Bob Jones", "value": 2, "date": "2024-03-01 09:00:00.000000000"}
+			]}}}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGerritClient("alice", "secret", "review.example.com")
+	client.baseURL = server.URL
+	client.repoRoot = dir
+
+	info, err := client.GetPRApprovalInfo(context.Background(), "project", "repo", commitHash)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.PR.Number != 41 || info.PR.User.Login != "alice" || info.PR.MergedAt == nil || len(info.PR.Labels) != 1 || info.PR.Labels[0].Name != "security" ||
+		!info.PR.MergedAt.Equal(time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the merged change, got %+v", info.PR)
+	}
+	// dave only voted +1
+	if len(info.Approvers) != 2 || info.Approvers[0].User.Login != "Bob Jones" || info.Approvers[1].User.Email != "carol@example.com" {
+		t.Errorf("expected +2 votes by Bob Jones then carol, got %+v", info.Approvers)
+	}
+}
+
+func TestGerritFindPRByCommitAnonymous(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/changes/" || r.URL.Query().Get("q") != "commit:abc123" {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("expected an anonymous request")
+		}
+		w.Write([]byte(gerritXSSIPrefix + "\n[]"))
+	}))
+	defer server.Close()
+
+	client := NewGerritClient("", "", "review.example.com")
+	client.baseURL = server.URL
+
+	pr, err := client.FindPRByCommit(context.Background(), "project", "repo", "abc123")
+	if err != nil || pr != nil {
+		t.Errorf("expected no change, got %+v, %v", pr, err)
+	}
+}
+
+func TestUsesGerrit(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+	gitCommand(t, dir, "config", "user.email", "alice@example.com")
+	gitCommand(t, dir, "config", "user.name", "Alice")
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m", "Add main\n\nChange-Id: "+testChangeID)
+
+	if !usesGerrit(dir, &RepoInfo{Type: RepositoryTypeGitLab, Host: "review.example.com"}) {
+		t.Error("expected a self-hosted remote with Change-Id trailers to be Gerrit")
+	}
+	if usesGerrit(dir, &RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com"}) {
+		t.Error("expected GitHub remotes to stay GitHub")
+	}
+
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m", "No trailer")
+	if usesGerrit(dir, &RepoInfo{Type: RepositoryTypeGitLab, Host: "review.example.com"}) {
+		t.Error("expected a HEAD without Change-Id to stay GitLab")
+	}
+}
@@ -0,0 +1,828 @@
+package reviewblame
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrNotGitRepo = errors.New("not a git repository")
+
+// BlameLine represents a single line from git blame output
+type BlameLine struct {
+	CommitHash  string
+	Filename    string
+	Author      string
+	AuthorEmail string
+	Date        string
+	LineNumber  int
+	Content     string
+
+	// OriginalLineNumber is the line number in the commit that introduced the line
+	OriginalLineNumber int
+	// OriginalFilename is the repository-relative path of the file in the
+	// commit that introduced the line, which differs from Filename for lines
+	// of renamed files or lines moved or copied from other files
+	OriginalFilename string
+	// AuthorTimezone is the author's UTC offset, e.g. "+0200"
+	AuthorTimezone string
+	// Summary is the subject line of the commit
+	Summary string
+}
+
+// FindGitRoot finds the root directory of a git repository by walking up
+// the directory tree looking for a .git directory
+func FindGitRoot(startPath string) (string, error) {
+	// Convert to absolute path to handle relative paths consistently
+	absPath, err := filepath.Abs(startPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Start from the directory containing the file (if startPath is a file)
+	currentPath := absPath
+	if info, err := os.Stat(currentPath); err == nil && !info.IsDir() {
+		currentPath = filepath.Dir(currentPath)
+	}
+
+	// Walk up the directory tree
+	for {
+		gitPath := filepath.Join(currentPath, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			// Check if it's a directory (.git folder) or file (.git file for worktrees)
+			if info.IsDir() || info.Mode().IsRegular() {
+				return currentPath, nil
+			}
+		}
+
+		// Move up one directory
+		parentPath := filepath.Dir(currentPath)
+
+		// If we reached the root directory, stop
+		if parentPath == currentPath {
+			break
+		}
+
+		currentPath = parentPath
+	}
+
+	return "", ErrNotGitRepo
+}
+
+// BlameOptions are the git blame options passed through to control which
+// lines are annotated and which commits they are attributed to
+type BlameOptions struct {
+	// LineRanges are passed as -L options, in any syntax git blame accepts
+	// ("10,20", "/regex/,+5", ":funcname"); git blame merges overlapping
+	// ranges and reports the lines in file order. Empty means all lines.
+	LineRanges []string
+	// Porcelain selects --porcelain instead of --line-porcelain output
+	Porcelain bool
+	// DetectMoves attributes lines moved within the file to the commit that
+	// originally wrote them (-M)
+	DetectMoves bool
+	// CopyDetection is the number of -C options: 1 follows lines moved or
+	// copied from files modified in the same commit, 2 also from the files
+	// of the commit that created the file, 3 from files of any commit
+	CopyDetection int
+	// IgnoreWhitespace ignores whitespace changes when attributing lines (-w)
+	IgnoreWhitespace bool
+	// IgnoreRevsFiles list commits whose changes are attributed to the
+	// commits before them (--ignore-revs-file), in addition to the
+	// blame.ignoreRevsFile setting, which git blame applies by itself. A
+	// .git-blame-ignore-revs file is not applied unless it is named here or
+	// by that setting.
+	IgnoreRevsFiles []string
+	// Jobs is the number of files annotatePaths blames at once, one per CPU
+	// when 0
+	Jobs int
+	// Contents annotates the contents of this file instead of the working
+	// tree file, as git blame --contents does; "-" reads them from stdin.
+	// Lines that differ from the annotated revision are not committed yet.
+	Contents string
+	// Backend is BackendExec, the default when empty, or BackendGoGit
+	Backend string
+}
+
+// ExecuteGitBlame runs git blame on the specified file and returns the parsed output
+func ExecuteGitBlame(ctx context.Context, repoRoot, filePath string, opts BlameOptions) ([]BlameLine, error) {
+	return ExecuteGitBlameAt(ctx, repoRoot, filePath, "", opts)
+}
+
+// ExecuteGitBlameAt runs git blame on the specified file as of rev, or on the
+// working tree when rev is empty, and returns the parsed output
+func ExecuteGitBlameAt(ctx context.Context, repoRoot, filePath, rev string, opts BlameOptions) ([]BlameLine, error) {
+	switch opts.Backend {
+	case BackendGoGit:
+		return executeGoGitBlame(ctx, repoRoot, filePath, rev, opts)
+	case BackendIncremental:
+		stream, err := StartGitBlameStream(ctx, repoRoot, filePath, rev, opts)
+		if err != nil {
+			return nil, err
+		}
+		return stream.Collect()
+	}
+
+	// Add porcelain format for easier parsing
+	format := "--line-porcelain"
+	if opts.Porcelain {
+		format = "--porcelain"
+	}
+	args, relPath, err := gitBlameArgs(repoRoot, filePath, rev, opts, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute git blame, killing it when ctx is canceled
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoRoot
+	if opts.Contents == "-" {
+		cmd.Stdin = os.Stdin
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := parseGitBlameOutput(string(output))
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the repo-relative path so multi-file results stay attributable
+	for i := range lines {
+		lines[i].Filename = relPath
+	}
+
+	return lines, nil
+}
+
+// gitBlameArgs returns the git blame arguments for opts with the given
+// output format option, and the slash-separated path of filePath relative
+// to repoRoot
+func gitBlameArgs(repoRoot, filePath, rev string, opts BlameOptions, format string) ([]string, string, error) {
+	args := []string{"blame"}
+
+	// Add line ranges if specified
+	for _, lineRange := range opts.LineRanges {
+		args = append(args, "-L", lineRange)
+	}
+
+	// Follow moved and copied lines to the commits that wrote them
+	if opts.DetectMoves {
+		args = append(args, "-M")
+	}
+	for i := 0; i < opts.CopyDetection; i++ {
+		args = append(args, "-C")
+	}
+
+	// Skip whitespace-only and listed commits, so reformatting does not
+	// hide the commits that wrote the lines
+	if opts.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	for _, path := range opts.IgnoreRevsFiles {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, "--ignore-revs-file", absPath)
+	}
+
+	if opts.Contents != "" {
+		contents := opts.Contents
+		if contents != "-" {
+			absContents, err := filepath.Abs(contents)
+			if err != nil {
+				return nil, "", err
+			}
+			contents = absContents
+		}
+		args = append(args, "--contents", contents)
+	}
+
+	args = append(args, format)
+
+	// Convert filePath to absolute path first to handle relative paths correctly
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Add the file path (relative to repo root)
+	relPath, err := filepath.Rel(repoRoot, absFilePath)
+	if err != nil {
+		return nil, "", err
+	}
+	if rev != "" {
+		// git would read a rev starting with - as an option, such as
+		// --contents=<file>
+		if strings.HasPrefix(rev, "-") {
+			return nil, "", fmt.Errorf("invalid revision %q", rev)
+		}
+		args = append(args, rev)
+	}
+	args = append(args, "--", relPath)
+
+	return args, filepath.ToSlash(relPath), nil
+}
+
+// ResolveRevision resolves a revision expression (e.g. HEAD, v1.2.0) to a full commit hash
+func ResolveRevision(repoRoot, rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	cmd.Dir = repoRoot
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %s: %w", rev, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListTrackedFiles returns the absolute paths of all files tracked by git
+// under path, which may be a single file or a directory
+func ListTrackedFiles(repoRoot, path string) ([]string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "ls-files", "-z", "--", absPath)
+	cmd.Dir = repoRoot
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name == "" {
+			continue
+		}
+		files = append(files, filepath.Join(repoRoot, name))
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no tracked files found under %s", path)
+	}
+
+	return files, nil
+}
+
+// ListTrackedFilesAt returns the absolute paths of all files under paths in
+// the tree of rev, which may include files no longer in the working tree
+func ListTrackedFilesAt(repoRoot, rev string, paths []string) ([]string, error) {
+	args := []string{"ls-tree", "-r", "--name-only", "-z", rev, "--"}
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		relPath, err := filepath.Rel(repoRoot, absPath)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, filepath.ToSlash(relPath))
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoRoot
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name == "" {
+			continue
+		}
+		files = append(files, filepath.Join(repoRoot, name))
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found under %s at %s", strings.Join(paths, ", "), rev)
+	}
+
+	return files, nil
+}
+
+// ReadFileAt returns the content of a repository-relative path at rev
+func ReadFileAt(repoRoot, relPath, rev string) ([]byte, error) {
+	cmd := exec.Command("git", "show", rev+":"+relPath)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s at %s: %w", relPath, shortCommit(rev), err)
+	}
+	return output, nil
+}
+
+// ListChangedFiles returns the repository-relative paths of all files added,
+// modified or deleted between two commits. Renames count as a deletion and
+// an addition, so both paths are included.
+func ListChangedFiles(repoRoot, from, to string) (map[string]bool, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "--no-renames", "-z", from, to, "--")
+	cmd.Dir = repoRoot
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not list files changed since %s: %w", shortCommit(from), err)
+	}
+
+	changed := make(map[string]bool)
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name != "" {
+			changed[name] = true
+		}
+	}
+	return changed, nil
+}
+
+// parseGitBlameOutput parses the porcelain output from git blame. With
+// --porcelain git writes the commit headers only for the first line of each
+// commit, so the later lines of a commit take them from the first.
+func parseGitBlameOutput(output string) ([]BlameLine, error) {
+	var lines []BlameLine
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	// Lines of minified or generated files easily exceed the default 64KB
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var currentLine BlameLine
+	var lineNumber int
+	commits := make(map[string]BlameLine)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Skip empty lines
+		if line == "" {
+			continue
+		}
+
+		// Check if this is a commit hash line (starts with hash)
+		if len(line) >= 40 && isHexString(line[:40]) {
+			// If we have a previous line, save it
+			if currentLine.CommitHash != "" {
+				lines = append(lines, finishBlameLine(commits, lines, currentLine))
+			}
+
+			// Start new blame line; the header holds the line number in the
+			// original and in the final file, the latter differing from the
+			// position with -L, and for the first line of a group the number
+			// of lines in the group
+			parts := strings.Fields(line)
+			lineNumber++
+			if len(parts) >= 3 {
+				if finalLine, err := strconv.Atoi(parts[2]); err == nil {
+					lineNumber = finalLine
+				}
+			}
+			currentLine = BlameLine{
+				CommitHash: parts[0],
+				LineNumber: lineNumber,
+			}
+			if len(parts) >= 2 {
+				currentLine.OriginalLineNumber, _ = strconv.Atoi(parts[1])
+			}
+			continue
+		}
+
+		// Parse metadata fields
+		if strings.HasPrefix(line, "author ") {
+			currentLine.Author = line[7:]
+		} else if strings.HasPrefix(line, "author-mail ") {
+			email := strings.TrimSpace(line[12:])
+			// Remove < and > from email
+			if len(email) > 2 && email[0] == '<' && email[len(email)-1] == '>' {
+				email = email[1 : len(email)-1]
+			}
+			currentLine.AuthorEmail = email
+		} else if strings.HasPrefix(line, "author-time ") {
+			currentLine.Date = line[12:]
+		} else if strings.HasPrefix(line, "author-tz ") {
+			currentLine.AuthorTimezone = line[10:]
+		} else if strings.HasPrefix(line, "summary ") {
+			currentLine.Summary = line[8:]
+		} else if strings.HasPrefix(line, "filename ") {
+			currentLine.OriginalFilename = unquoteGitPath(line[9:])
+		} else if strings.HasPrefix(line, "\t") {
+			// This is the actual code line (starts with tab)
+			currentLine.Content = line[1:] // Remove the leading tab
+		}
+	}
+
+	// Don't forget the last line
+	if currentLine.CommitHash != "" {
+		lines = append(lines, finishBlameLine(commits, lines, currentLine))
+	}
+
+	return lines, scanner.Err()
+}
+
+// finishBlameLine fills the headers --porcelain left out of line: the
+// commit headers from the first line of the commit, and the filename, only
+// written for the first line of a group, from the previous line
+func finishBlameLine(commits map[string]BlameLine, previous []BlameLine, line BlameLine) BlameLine {
+	if line.Author == "" && line.AuthorEmail == "" && line.Date == "" {
+		if commit, ok := commits[line.CommitHash]; ok {
+			line.Author = commit.Author
+			line.AuthorEmail = commit.AuthorEmail
+			line.Date = commit.Date
+			line.AuthorTimezone = commit.AuthorTimezone
+			line.Summary = commit.Summary
+		}
+	} else {
+		commits[line.CommitHash] = line
+	}
+	if line.OriginalFilename == "" && len(previous) > 0 && previous[len(previous)-1].CommitHash == line.CommitHash {
+		line.OriginalFilename = previous[len(previous)-1].OriginalFilename
+	}
+	return line
+}
+
+// unquoteGitPath decodes a path git wrote as a C-style quoted string because
+// of special characters, and returns other paths unchanged
+func unquoteGitPath(path string) string {
+	if len(path) >= 2 && path[0] == '"' && path[len(path)-1] == '"' {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			return unquoted
+		}
+	}
+	return path
+}
+
+// isHexString checks if a string contains only hexadecimal characters
+func isHexString(s string) bool {
+	for _, r := range s {
+		if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
+
+// RepositoryType represents the type of git hosting service
+type RepositoryType int
+
+const (
+	RepositoryTypeGitHub RepositoryType = iota
+	RepositoryTypeGitLab
+	RepositoryTypeBitbucket
+	RepositoryTypeGitea
+	RepositoryTypeGerrit
+	// RepositoryTypePlugin is resolved by a provider plugin
+	RepositoryTypePlugin
+)
+
+func (rt RepositoryType) String() string {
+	switch rt {
+	case RepositoryTypeGitHub:
+		return "GitHub"
+	case RepositoryTypeGitLab:
+		return "GitLab"
+	case RepositoryTypeBitbucket:
+		return "Bitbucket"
+	case RepositoryTypeGitea:
+		return "Gitea"
+	case RepositoryTypeGerrit:
+		return "Gerrit"
+	case RepositoryTypePlugin:
+		return "Plugin"
+	default:
+		return "Unknown"
+	}
+}
+
+// RepoInfo contains repository owner, name, and type information
+type RepoInfo struct {
+	Owner string
+	Name  string
+	Type  RepositoryType
+	Host  string // For self-hosted GitLab instances
+	// APIURL overrides the API base URL derived from Host, e.g. for GitHub
+	// Enterprise Server; empty when not known
+	APIURL string
+	// Plugin is the provider plugin of RepositoryTypePlugin repositories
+	Plugin string
+}
+
+// PullRequestURL returns the web page of a PR/MR of the repository
+// owner/name on the repository's host, or "" when the host is not known
+func (r *RepoInfo) PullRequestURL(owner, name string, number int) string {
+	if r.Host == "" || number == 0 {
+		return ""
+	}
+	switch r.Type {
+	case RepositoryTypeGitLab:
+		return fmt.Sprintf("https://%s/%s/%s/-/merge_requests/%d", r.Host, owner, name, number)
+	case RepositoryTypeBitbucket:
+		return fmt.Sprintf("https://%s/%s/%s/pull-requests/%d", r.Host, owner, name, number)
+	case RepositoryTypeGitea:
+		return fmt.Sprintf("https://%s/%s/%s/pulls/%d", r.Host, owner, name, number)
+	case RepositoryTypeGerrit:
+		return fmt.Sprintf("https://%s/c/%s/%s/+/%d", r.Host, owner, name, number)
+	case RepositoryTypePlugin:
+		// The web pages of bespoke review systems are not known
+		return ""
+	default:
+		return fmt.Sprintf("https://%s/%s/%s/pull/%d", r.Host, owner, name, number)
+	}
+}
+
+// UserURL returns the profile page of an account on the repository's host,
+// or "" when the host is not known or has no profile pages by login
+func (r *RepoInfo) UserURL(login string) string {
+	if r.Host == "" || login == "" {
+		return ""
+	}
+	switch r.Type {
+	case RepositoryTypeGitHub, RepositoryTypeGitLab, RepositoryTypeGitea:
+		return fmt.Sprintf("https://%s/%s", r.Host, url.PathEscape(login))
+	default:
+		return ""
+	}
+}
+
+// DefaultRemote is the remote whose repository is queried when no other
+// remote is selected or detected
+const DefaultRemote = "origin"
+
+// UpstreamRemote is the conventional name of the remote of the repository a
+// fork was made from
+const UpstreamRemote = "upstream"
+
+// DetectRemote chooses the remote whose repository holds the PRs/MRs: the
+// remote the current branch tracks when that is not origin, else the
+// upstream remote of a fork, else origin. Forks usually keep origin for
+// the fork, which has no PRs/MRs of its own.
+func DetectRemote(repoRoot string) string {
+	cmd := exec.Command("git", "remote")
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return DefaultRemote
+	}
+	remotes := make(map[string]bool)
+	for _, name := range strings.Fields(string(output)) {
+		remotes[name] = true
+	}
+
+	cmd = exec.Command("git", "symbolic-ref", "-q", "--short", "HEAD")
+	cmd.Dir = repoRoot
+	if branch, err := cmd.Output(); err == nil {
+		cmd = exec.Command("git", "config", "--get", "branch."+strings.TrimSpace(string(branch))+".remote")
+		cmd.Dir = repoRoot
+		output, _ := cmd.Output()
+		// A branch tracking a local branch has "." as its remote, which is
+		// not one of the named remotes
+		if tracked := strings.TrimSpace(string(output)); remotes[tracked] && tracked != DefaultRemote {
+			return tracked
+		}
+	}
+	if remotes[UpstreamRemote] {
+		return UpstreamRemote
+	}
+	return DefaultRemote
+}
+
+// ExtractRepoInfo extracts owner and repository name from a git remote, or
+// from the remote DetectRemote chooses when remote is empty
+func ExtractRepoInfo(repoRoot, remote string) (*RepoInfo, error) {
+	if remote == "" {
+		remote = DetectRemote(repoRoot)
+	}
+	cmd := exec.Command("git", "remote", "get-url", remote)
+	cmd.Dir = repoRoot
+
+	output, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		// Without the git CLI, read the remote from the repository itself
+		remoteURL, goGitErr := goGitRemoteURL(repoRoot, remote)
+		if goGitErr != nil {
+			return nil, goGitErr
+		}
+		return parseRepositoryURL(remoteURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no remote named %s: %w", remote, err)
+	}
+
+	remoteURL := strings.TrimSpace(string(output))
+
+	return parseRepositoryURL(remoteURL)
+}
+
+// parseRepositoryURL extracts owner, repo name, and type from GitHub/GitLab URLs
+func parseRepositoryURL(url string) (*RepoInfo, error) {
+	url = strings.TrimSpace(url)
+
+	// GitHub SSH format: git@github.com:owner/repo.git
+	if strings.HasPrefix(url, "git@github.com:") {
+		path := strings.TrimPrefix(url, "git@github.com:")
+		repoInfo, err := parseRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = RepositoryTypeGitHub
+		repoInfo.Host = "github.com"
+		return repoInfo, nil
+	}
+
+	// GitHub HTTPS format: https://github.com/owner/repo.git
+	if strings.HasPrefix(url, "https://github.com/") {
+		path := strings.TrimPrefix(url, "https://github.com/")
+		repoInfo, err := parseRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = RepositoryTypeGitHub
+		repoInfo.Host = "github.com"
+		return repoInfo, nil
+	}
+
+	// GitHub HTTP format: http://github.com/owner/repo.git
+	if strings.HasPrefix(url, "http://github.com/") {
+		path := strings.TrimPrefix(url, "http://github.com/")
+		repoInfo, err := parseRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = RepositoryTypeGitHub
+		repoInfo.Host = "github.com"
+		return repoInfo, nil
+	}
+
+	// GitLab SSH format: git@gitlab.com:owner/repo.git
+	if strings.HasPrefix(url, "git@gitlab.com:") {
+		path := strings.TrimPrefix(url, "git@gitlab.com:")
+		repoInfo, err := parseRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = RepositoryTypeGitLab
+		repoInfo.Host = "gitlab.com"
+		return repoInfo, nil
+	}
+
+	// GitLab HTTPS format: https://gitlab.com/owner/repo.git
+	if strings.HasPrefix(url, "https://gitlab.com/") {
+		path := strings.TrimPrefix(url, "https://gitlab.com/")
+		repoInfo, err := parseRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = RepositoryTypeGitLab
+		repoInfo.Host = "gitlab.com"
+		return repoInfo, nil
+	}
+
+	// GitLab HTTP format: http://gitlab.com/owner/repo.git
+	if strings.HasPrefix(url, "http://gitlab.com/") {
+		path := strings.TrimPrefix(url, "http://gitlab.com/")
+		repoInfo, err := parseRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = RepositoryTypeGitLab
+		repoInfo.Host = "gitlab.com"
+		return repoInfo, nil
+	}
+
+	// SSH URL format: ssh://git@host:29418/owner/repo.git, common with Gerrit
+	if strings.HasPrefix(url, "ssh://") {
+		rest := strings.TrimPrefix(url, "ssh://")
+		slashIndex := strings.Index(rest, "/")
+		if slashIndex == -1 {
+			return nil, fmt.Errorf("invalid repository URL format: %s", url)
+		}
+
+		host := rest[:slashIndex]
+		if at := strings.LastIndex(host, "@"); at != -1 {
+			host = host[at+1:]
+		}
+		if colon := strings.Index(host, ":"); colon != -1 {
+			host = host[:colon]
+		}
+
+		repoInfo, err := parseRepoPath(rest[slashIndex+1:])
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = repositoryTypeForHost(host)
+		repoInfo.Host = host
+		return repoInfo, nil
+	}
+
+	// Self-hosted GitLab SSH format: git@gitlab.example.com:owner/repo.git
+	if strings.Contains(url, "@") && strings.Contains(url, ":") && !strings.HasPrefix(url, "http") {
+		parts := strings.SplitN(url, "@", 2)
+		if len(parts) == 2 {
+			hostAndPath := parts[1]
+			hostPathParts := strings.SplitN(hostAndPath, ":", 2)
+			if len(hostPathParts) == 2 {
+				host := hostPathParts[0]
+				path := hostPathParts[1]
+
+				repoInfo, err := parseRepoPath(path)
+				if err != nil {
+					return nil, err
+				}
+				repoInfo.Type = repositoryTypeForHost(host)
+				repoInfo.Host = host
+				return repoInfo, nil
+			}
+		}
+	}
+
+	// Self-hosted GitLab HTTPS format: https://gitlab.example.com/owner/repo.git
+	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+		// Extract rest after protocol
+		var rest string
+		if strings.HasPrefix(url, "https://") {
+			rest = strings.TrimPrefix(url, "https://")
+		} else {
+			rest = strings.TrimPrefix(url, "http://")
+		}
+
+		// Find first slash to separate host from path
+		slashIndex := strings.Index(rest, "/")
+		if slashIndex == -1 {
+			return nil, fmt.Errorf("invalid repository URL format: %s", url)
+		}
+
+		host := rest[:slashIndex]
+		path := rest[slashIndex+1:]
+		// Drop credentials, e.g. https://user@bitbucket.org/owner/repo.git
+		if at := strings.LastIndex(host, "@"); at != -1 {
+			host = host[at+1:]
+		}
+
+		repoInfo, err := parseRepoPath(path)
+		if err != nil {
+			return nil, err
+		}
+		repoInfo.Type = repositoryTypeForHost(host)
+		repoInfo.Host = host
+		return repoInfo, nil
+	}
+
+	return nil, fmt.Errorf("unsupported repository URL format: %s", url)
+}
+
+// repositoryTypeForHost returns the hosting service of a host that is not
+// github.com or gitlab.com; unknown hosts are assumed to be self-hosted GitLab
+// unless the config file's hosts, -provider or Change-Id trailers (Gerrit)
+// say otherwise
+func repositoryTypeForHost(host string) RepositoryType {
+	switch host {
+	case "github.com":
+		return RepositoryTypeGitHub
+	case "bitbucket.org":
+		return RepositoryTypeBitbucket
+	case "codeberg.org":
+		return RepositoryTypeGitea
+	}
+	return RepositoryTypeGitLab
+}
+
+// parseGitHubURL extracts owner and repo name from various GitHub URL formats (kept for backward compatibility)
+func parseGitHubURL(url string) (*RepoInfo, error) {
+	repoInfo, err := parseRepositoryURL(url)
+	if err != nil {
+		return nil, err
+	}
+	if repoInfo.Type != RepositoryTypeGitHub {
+		return nil, fmt.Errorf("not a GitHub repository: %s", url)
+	}
+	return repoInfo, nil
+}
+
+// parseRepoPath parses owner/repo from the path part of a GitHub URL
+func parseRepoPath(path string) (*RepoInfo, error) {
+	// Remove .git suffix if present
+	path = strings.TrimSuffix(path, ".git")
+
+	// Split by slash
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid repository path: %s", path)
+	}
+
+	// Take first two parts as owner/repo
+	return &RepoInfo{
+		Owner: parts[0],
+		Name:  parts[1],
+	}, nil
+}
@@ -0,0 +1,1083 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindGitRoot(t *testing.T) {
+	tests := []struct {
+		name        string
+		setupFunc   func(t *testing.T) (string, func())
+		expectError bool
+		errorType   error
+	}{
+		{
+			name: "finds git root in current directory",
+			setupFunc: func(t *testing.T) (string, func()) {
+				tempDir := t.TempDir()
+				gitDir := filepath.Join(tempDir, ".git")
+				if err := os.Mkdir(gitDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				return tempDir, func() {}
+			},
+			expectError: false,
+		},
+		{
+			name: "finds git root in parent directory", 
+			setupFunc: func(t *testing.T) (string, func()) {
+				tempDir := t.TempDir()
+				gitDir := filepath.Join(tempDir, ".git")
+				if err := os.Mkdir(gitDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				
+				subDir := filepath.Join(tempDir, "subdir")
+				if err := os.Mkdir(subDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				
+				return subDir, func() {}
+			},
+			expectError: false,
+		},
+		{
+			name: "finds git root with nested subdirectories",
+			setupFunc: func(t *testing.T) (string, func()) {
+				tempDir := t.TempDir()
+				gitDir := filepath.Join(tempDir, ".git")
+				if err := os.Mkdir(gitDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				
+				deepDir := filepath.Join(tempDir, "a", "b", "c")
+				if err := os.MkdirAll(deepDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				
+				return deepDir, func() {}
+			},
+			expectError: false,
+		},
+		{
+			name: "handles git worktree (.git file)",
+			setupFunc: func(t *testing.T) (string, func()) {
+				tempDir := t.TempDir()
+				gitFile := filepath.Join(tempDir, ".git")
+				if err := os.WriteFile(gitFile, []byte("gitdir: /path/to/git"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return tempDir, func() {}
+			},
+			expectError: false,
+		},
+		{
+			name: "returns error when no git repository found",
+			setupFunc: func(t *testing.T) (string, func()) {
+				tempDir := t.TempDir()
+				return tempDir, func() {}
+			},
+			expectError: true,
+			errorType:   ErrNotGitRepo,
+		},
+		{
+			name: "handles file path input",
+			setupFunc: func(t *testing.T) (string, func()) {
+				tempDir := t.TempDir()
+				gitDir := filepath.Join(tempDir, ".git")
+				if err := os.Mkdir(gitDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				
+				testFile := filepath.Join(tempDir, "test.txt")
+				if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				
+				return testFile, func() {}
+			},
+			expectError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testPath, cleanup := tt.setupFunc(t)
+			defer cleanup()
+
+			result, err := FindGitRoot(testPath)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+					return
+				}
+				if tt.errorType != nil && err != tt.errorType {
+					t.Errorf("expected error %v, got %v", tt.errorType, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Verify that the result contains a .git directory or file
+			gitPath := filepath.Join(result, ".git")
+			if _, err := os.Stat(gitPath); err != nil {
+				t.Errorf("result %s does not contain .git: %v", result, err)
+			}
+		})
+	}
+}
+
+func TestFindGitRootRealRepository(t *testing.T) {
+	// Test with the actual git repository of this project
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := FindGitRoot(wd)
+	if err != nil {
+		t.Skipf("skipping test in non-git environment: %v", err)
+		return
+	}
+
+	// Verify the result is a valid git root
+	gitPath := filepath.Join(root, ".git")
+	if _, err := os.Stat(gitPath); err != nil {
+		t.Errorf("result %s does not contain .git: %v", root, err)
+	}
+
+	// Verify it returns the same result when called with a file in the repo
+	thisFile := filepath.Join(wd, "git_test.go")
+	root2, err := FindGitRoot(thisFile)
+	if err != nil {
+		t.Errorf("failed to find git root from file path: %v", err)
+	}
+
+	if root != root2 {
+		t.Errorf("git root differs when called with directory vs file: %s vs %s", root, root2)
+	}
+}
+
+func TestParseGitBlameOutput(t *testing.T) {
+	// Sample porcelain output from git blame --line-porcelain
+	sampleOutput := `a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 1 1 1
+author John Doe
+author-mail <john.doe@example.com>
+author-time 1609459200
+	package main
+b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1 2 2 1
+author Jane Smith
+author-mail <jane.smith@example.com>
+author-time 1609545600
+	
+c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2 3 3 1
+author Bob Wilson
+author-mail <bob.wilson@example.com>  
+author-time 1609632000
+	import "fmt"`
+
+	expected := []BlameLine{
+		{
+			CommitHash:  "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0",
+			Author:      "John Doe",
+			AuthorEmail: "john.doe@example.com",
+			Date:        "1609459200",
+			LineNumber:  1,
+			Content:     "package main",
+		},
+		{
+			CommitHash:  "b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1",
+			Author:      "Jane Smith", 
+			AuthorEmail: "jane.smith@example.com",
+			Date:        "1609545600",
+			LineNumber:  2,
+			Content:     "",
+		},
+		{
+			CommitHash:  "c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2",
+			Author:      "Bob Wilson",
+			AuthorEmail: "bob.wilson@example.com",
+			Date:        "1609632000",
+			LineNumber:  3,
+			Content:     "import \"fmt\"",
+		},
+	}
+
+	result, err := parseGitBlameOutput(sampleOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(result))
+	}
+
+	for i, line := range result {
+		if line.CommitHash != expected[i].CommitHash {
+			t.Errorf("line %d: expected commit %s, got %s", i+1, expected[i].CommitHash, line.CommitHash)
+		}
+		if line.Author != expected[i].Author {
+			t.Errorf("line %d: expected author %s, got %s", i+1, expected[i].Author, line.Author)
+		}
+		if line.AuthorEmail != expected[i].AuthorEmail {
+			t.Errorf("line %d: expected email %s, got %s", i+1, expected[i].AuthorEmail, line.AuthorEmail)
+		}
+		if line.Date != expected[i].Date {
+			t.Errorf("line %d: expected date %s, got %s", i+1, expected[i].Date, line.Date)
+		}
+		if line.LineNumber != expected[i].LineNumber {
+			t.Errorf("line %d: expected line number %d, got %d", i+1, expected[i].LineNumber, line.LineNumber)
+		}
+		if line.Content != expected[i].Content {
+			t.Errorf("line %d: expected content %q, got %q", i+1, expected[i].Content, line.Content)
+		}
+	}
+}
+
+func TestIsHexString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"a1b2c3d4e5f6", true},
+		{"ABCDEF123456", true},
+		{"123456789abc", true},
+		{"g1b2c3d4e5f6", false},
+		{"12345G789abc", false},
+		{"", true}, // empty string should be true
+		{"xyz", false},
+		{"123", true},
+	}
+
+	for _, test := range tests {
+		result := isHexString(test.input)
+		if result != test.expected {
+			t.Errorf("isHexString(%q) = %t, expected %t", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestExecuteGitBlameIntegration(t *testing.T) {
+	// Skip if not in a git repository
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repoRoot, err := FindGitRoot(wd)
+	if err != nil {
+		t.Skipf("skipping integration test: not in git repository: %v", err)
+	}
+
+	// Test with this very file
+	thisFile := filepath.Join(wd, "git_test.go")
+	
+	lines, err := ExecuteGitBlame(context.Background(), repoRoot, thisFile, BlameOptions{})
+	if err != nil {
+		t.Fatalf("ExecuteGitBlame failed: %v", err)
+	}
+
+	if len(lines) == 0 {
+		t.Fatal("expected some blame lines, got none")
+	}
+
+	// Verify structure of first line
+	firstLine := lines[0]
+	if firstLine.CommitHash == "" {
+		t.Error("expected commit hash, got empty string")
+	}
+	if len(firstLine.CommitHash) != 40 {
+		t.Errorf("expected 40-char commit hash, got %d chars: %s", len(firstLine.CommitHash), firstLine.CommitHash)
+	}
+	if firstLine.LineNumber != 1 {
+		t.Errorf("expected first line number to be 1, got %d", firstLine.LineNumber)
+	}
+	if !isHexString(firstLine.CommitHash) {
+		t.Errorf("commit hash should be hex string: %s", firstLine.CommitHash)
+	}
+}
+
+func TestListTrackedFiles(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repoRoot, err := FindGitRoot(wd)
+	if err != nil {
+		t.Skipf("skipping integration test: not in git repository: %v", err)
+	}
+
+	files, err := ListTrackedFiles(repoRoot, filepath.Join(wd, "git.go"))
+	if err != nil {
+		t.Fatalf("ListTrackedFiles failed: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "git.go" {
+		t.Errorf("expected only git.go, got %v", files)
+	}
+
+	files, err = ListTrackedFiles(repoRoot, wd)
+	if err != nil {
+		t.Fatalf("ListTrackedFiles failed: %v", err)
+	}
+	if len(files) < 2 {
+		t.Errorf("expected multiple tracked files in directory, got %v", files)
+	}
+
+	if _, err := ListTrackedFiles(repoRoot, filepath.Join(wd, "does-not-exist.go")); err == nil {
+		t.Error("expected error for untracked path")
+	}
+}
+
+func TestParseRepositoryURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		expectOwner  string
+		expectRepo   string
+		expectType   RepositoryType
+		expectHost   string
+		expectError  bool
+	}{
+		// GitHub tests
+		{
+			name:        "GitHub SSH format",
+			url:         "git@github.com:owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitHub,
+			expectHost:  "github.com",
+			expectError: false,
+		},
+		{
+			name:        "GitHub HTTPS format",
+			url:         "https://github.com/owner/repo.git",
+			expectOwner: "owner", 
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitHub,
+			expectHost:  "github.com",
+			expectError: false,
+		},
+		{
+			name:        "GitHub HTTP format",
+			url:         "http://github.com/owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo", 
+			expectType:  RepositoryTypeGitHub,
+			expectHost:  "github.com",
+			expectError: false,
+		},
+		{
+			name:        "GitHub SSH without .git",
+			url:         "git@github.com:owner/repo",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitHub,
+			expectHost:  "github.com",
+			expectError: false,
+		},
+		{
+			name:        "GitHub HTTPS without .git",
+			url:         "https://github.com/owner/repo",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitHub,
+			expectHost:  "github.com",
+			expectError: false,
+		},
+		{
+			name:        "GitHub with subpath (should take first two)",
+			url:         "https://github.com/owner/repo/tree/main",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitHub,
+			expectHost:  "github.com",
+			expectError: false,
+		},
+		
+		// GitLab.com tests
+		{
+			name:        "GitLab SSH format",
+			url:         "git@gitlab.com:owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.com",
+			expectError: false,
+		},
+		{
+			name:        "GitLab HTTPS format", 
+			url:         "https://gitlab.com/owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.com",
+			expectError: false,
+		},
+		{
+			name:        "GitLab HTTP format",
+			url:         "http://gitlab.com/owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.com",
+			expectError: false,
+		},
+		{
+			name:        "GitLab SSH without .git",
+			url:         "git@gitlab.com:owner/repo",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.com",
+			expectError: false,
+		},
+		{
+			name:        "GitLab with subpath",
+			url:         "https://gitlab.com/owner/repo/-/tree/main",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.com",
+			expectError: false,
+		},
+		
+		// Self-hosted GitLab tests
+		{
+			name:        "Self-hosted GitLab SSH",
+			url:         "git@gitlab.example.com:owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo", 
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.example.com",
+			expectError: false,
+		},
+		{
+			name:        "Self-hosted GitLab HTTPS",
+			url:         "https://gitlab.example.com/owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.example.com",
+			expectError: false,
+		},
+		{
+			name:        "Self-hosted GitLab HTTP",
+			url:         "http://gitlab.internal.corp/owner/repo",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "gitlab.internal.corp",
+			expectError: false,
+		},
+
+		// SSH URL tests
+		{
+			name:        "Gerrit SSH URL with port",
+			url:         "ssh://alice@review.example.com:29418/platform/build.git",
+			expectOwner: "platform",
+			expectRepo:  "build",
+			expectType:  RepositoryTypeGitLab,
+			expectHost:  "review.example.com",
+			expectError: false,
+		},
+		{
+			name:        "GitHub SSH URL",
+			url:         "ssh://git@github.com/owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitHub,
+			expectHost:  "github.com",
+			expectError: false,
+		},
+
+		// Codeberg tests
+		{
+			name:        "Codeberg HTTPS",
+			url:         "https://codeberg.org/owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeGitea,
+			expectHost:  "codeberg.org",
+			expectError: false,
+		},
+
+		// Bitbucket Cloud tests
+		{
+			name:        "Bitbucket SSH",
+			url:         "git@bitbucket.org:workspace/repo.git",
+			expectOwner: "workspace",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeBitbucket,
+			expectHost:  "bitbucket.org",
+			expectError: false,
+		},
+		{
+			name:        "Bitbucket HTTPS with user",
+			url:         "https://alice@bitbucket.org/workspace/repo.git",
+			expectOwner: "workspace",
+			expectRepo:  "repo",
+			expectType:  RepositoryTypeBitbucket,
+			expectHost:  "bitbucket.org",
+			expectError: false,
+		},
+		
+		// Error cases
+		{
+			name:        "invalid path format",
+			url:         "git@github.com:justowner",
+			expectError: true,
+		},
+		{
+			name:        "empty URL",
+			url:         "",
+			expectError: true,
+		},
+		{
+			name:        "unsupported format",
+			url:         "ftp://example.com/owner/repo",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseRepositoryURL(tt.url)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if result.Owner != tt.expectOwner {
+				t.Errorf("expected owner %s, got %s", tt.expectOwner, result.Owner)
+			}
+
+			if result.Name != tt.expectRepo {
+				t.Errorf("expected repo %s, got %s", tt.expectRepo, result.Name)
+			}
+			
+			if result.Type != tt.expectType {
+				t.Errorf("expected type %s, got %s", tt.expectType, result.Type)
+			}
+			
+			if result.Host != tt.expectHost {
+				t.Errorf("expected host %s, got %s", tt.expectHost, result.Host)
+			}
+		})
+	}
+}
+
+func TestParseGitHubURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		expectOwner string
+		expectRepo  string
+		expectError bool
+	}{
+		{
+			name:        "GitHub SSH format",
+			url:         "git@github.com:owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectError: false,
+		},
+		{
+			name:        "GitHub HTTPS format",
+			url:         "https://github.com/owner/repo.git",
+			expectOwner: "owner", 
+			expectRepo:  "repo",
+			expectError: false,
+		},
+		{
+			name:        "GitLab URL should error",
+			url:         "https://gitlab.com/owner/repo.git",
+			expectError: true,
+		},
+		{
+			name:        "invalid path format",
+			url:         "git@github.com:justowner",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseGitHubURL(tt.url)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if result.Owner != tt.expectOwner {
+				t.Errorf("expected owner %s, got %s", tt.expectOwner, result.Owner)
+			}
+
+			if result.Name != tt.expectRepo {
+				t.Errorf("expected repo %s, got %s", tt.expectRepo, result.Name)
+			}
+		})
+	}
+}
+
+func TestParseRepoPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		expectOwner string
+		expectRepo  string
+		expectError bool
+	}{
+		{
+			name:        "basic owner/repo",
+			path:        "owner/repo",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectError: false,
+		},
+		{
+			name:        "with .git suffix",
+			path:        "owner/repo.git",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectError: false,
+		},
+		{
+			name:        "with additional path segments",
+			path:        "owner/repo/tree/main",
+			expectOwner: "owner",
+			expectRepo:  "repo",
+			expectError: false,
+		},
+		{
+			name:        "missing repo name",
+			path:        "owner",
+			expectError: true,
+		},
+		{
+			name:        "empty path",
+			path:        "",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseRepoPath(tt.path)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if result.Owner != tt.expectOwner {
+				t.Errorf("expected owner %s, got %s", tt.expectOwner, result.Owner)
+			}
+
+			if result.Name != tt.expectRepo {
+				t.Errorf("expected repo %s, got %s", tt.expectRepo, result.Name)
+			}
+		})
+	}
+}
+func TestParseGitBlameOutputLineRange(t *testing.T) {
+	// With -L, the final line number in each header is the line in the file
+	sampleOutput := `a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 3 12 1
+author John Doe
+	first
+a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 4 13
+author John Doe
+	second
+`
+	result, err := parseGitBlameOutput(sampleOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 2 || result[0].LineNumber != 12 || result[1].LineNumber != 13 {
+		t.Errorf("expected lines 12 and 13, got %+v", result)
+	}
+}
+
+func TestExecuteGitBlameLineRanges(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	content := "package main\n\nfunc first() {\n}\n\nfunc second() {\n\tprintln()\n}\n\nfunc third() {\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "main.go")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main")
+
+	// Overlapping and out-of-order ranges are merged into file order
+	lines, err := ExecuteGitBlame(context.Background(), dir, filepath.Join(dir, "main.go"), BlameOptions{LineRanges: []string{":first", "10,11", "1,1", "4,4"}})
+	if err != nil {
+		t.Fatalf("ExecuteGitBlame failed: %v", err)
+	}
+	var numbers []int
+	for _, line := range lines {
+		numbers = append(numbers, line.LineNumber)
+	}
+	if fmt.Sprint(numbers) != "[1 3 4 5 10 11]" {
+		t.Errorf("expected lines 1, 3-5 and 10-11, got %v", numbers)
+	}
+}
+
+func TestExecuteGitBlameCopyDetection(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, dir, "add", name)
+	}
+
+	moved := "func parseConfiguration(path string) (*Configuration, error) {\n\treturn loadConfigurationFromDisk(path)\n}\n"
+	write("a.go", "package main\n\n"+moved)
+	gitCommand(t, dir, "commit", "-q", "-m", "Add parser")
+	original := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	// Move the function to a new file in one commit
+	write("a.go", "package main\n")
+	write("b.go", "package main\n\n"+moved)
+	gitCommand(t, dir, "commit", "-q", "-m", "Move parser")
+	move := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	tests := []struct {
+		name string
+		opts BlameOptions
+		want string
+	}{
+		{"without copy detection", BlameOptions{}, move},
+		{"with -C", BlameOptions{CopyDetection: 1}, original},
+		{"with -CCC", BlameOptions{DetectMoves: true, CopyDetection: 3}, original},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := ExecuteGitBlame(context.Background(), dir, filepath.Join(dir, "b.go"), tt.opts)
+			if err != nil {
+				t.Fatalf("ExecuteGitBlame failed: %v", err)
+			}
+			if len(lines) != 5 || lines[2].CommitHash != tt.want {
+				t.Errorf("expected the function from %s, got %+v", tt.want, lines)
+			}
+			if lines[2].Filename != "b.go" {
+				t.Errorf("expected filename b.go, got %s", lines[2].Filename)
+			}
+		})
+	}
+}
+
+func TestExecuteGitBlameIgnoreRevs(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, dir, "add", name)
+	}
+
+	write("main.go", "func main() {\nprintln(\"hello\")\n}\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main")
+	original := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	write("main.go", "func main() {\n\tprintln(\"hello\")\n}\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Indent")
+	indent := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	write("main.go", "func main() {\n\tprintln('hello')\n}\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Reformat quotes")
+	reformat := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	blame := func(opts BlameOptions) string {
+		lines, err := ExecuteGitBlame(context.Background(), dir, filepath.Join(dir, "main.go"), opts)
+		if err != nil {
+			t.Fatalf("ExecuteGitBlame failed: %v", err)
+		}
+		return lines[1].CommitHash
+	}
+	listed := filepath.Join(t.TempDir(), "ignore-revs")
+	if err := os.WriteFile(listed, []byte("# formatting\n"+reformat+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := blame(BlameOptions{}); got != reformat {
+		t.Errorf("expected the reformat by default, got %s", got)
+	}
+	if got := blame(BlameOptions{IgnoreRevsFiles: []string{listed}}); got != indent {
+		t.Errorf("expected the indent with the reformat ignored, got %s", got)
+	}
+	if got := blame(BlameOptions{IgnoreRevsFiles: []string{listed}, IgnoreWhitespace: true}); got != original {
+		t.Errorf("expected the original commit ignoring whitespace and the reformat, got %s", got)
+	}
+
+	// A .git-blame-ignore-revs in the tree only applies when
+	// blame.ignoreRevsFile names it, as with git blame: a change must not be
+	// able to reassign its own lines
+	if err := os.WriteFile(filepath.Join(dir, ".git-blame-ignore-revs"), []byte(reformat+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := blame(BlameOptions{}); got != reformat {
+		t.Errorf("expected the unconfigured .git-blame-ignore-revs to be ignored, got %s", got)
+	}
+	gitCommand(t, dir, "config", "blame.ignoreRevsFile", ".git-blame-ignore-revs")
+	if got := blame(BlameOptions{}); got != indent {
+		t.Errorf("expected blame.ignoreRevsFile to be applied, got %s", got)
+	}
+}
+
+func TestPullRequestURL(t *testing.T) {
+	tests := []struct {
+		repo RepoInfo
+		want string
+	}{
+		{repo: RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com"}, want: "https://github.com/owner/repo/pull/5"},
+		{repo: RepoInfo{Type: RepositoryTypeGitLab, Host: "gitlab.example.com"}, want: "https://gitlab.example.com/owner/repo/-/merge_requests/5"},
+		{repo: RepoInfo{Type: RepositoryTypeBitbucket, Host: "bitbucket.org"}, want: "https://bitbucket.org/owner/repo/pull-requests/5"},
+		{repo: RepoInfo{Type: RepositoryTypeGitea, Host: "codeberg.org"}, want: "https://codeberg.org/owner/repo/pulls/5"},
+		{repo: RepoInfo{Type: RepositoryTypeGerrit, Host: "review.example.com"}, want: "https://review.example.com/c/owner/repo/+/5"},
+		{repo: RepoInfo{Type: RepositoryTypeGitHub}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.repo.PullRequestURL("owner", "repo", 5); got != tt.want {
+			t.Errorf("PullRequestURL() on %s = %q, want %q", tt.repo.Type, got, tt.want)
+		}
+	}
+}
+
+func TestUserURL(t *testing.T) {
+	tests := []struct {
+		repo RepoInfo
+		want string
+	}{
+		{repo: RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com"}, want: "https://github.com/alice"},
+		{repo: RepoInfo{Type: RepositoryTypeGitLab, Host: "gitlab.example.com"}, want: "https://gitlab.example.com/alice"},
+		{repo: RepoInfo{Type: RepositoryTypeGitea, Host: "codeberg.org"}, want: "https://codeberg.org/alice"},
+		{repo: RepoInfo{Type: RepositoryTypeGerrit, Host: "review.example.com"}, want: ""},
+		{repo: RepoInfo{Type: RepositoryTypeGitHub}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.repo.UserURL("alice"); got != tt.want {
+			t.Errorf("UserURL() on %s = %q, want %q", tt.repo.Type, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteGitBlameContents(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main")
+
+	contents := filepath.Join(t.TempDir(), "buffer.go")
+	if err := os.WriteFile(contents, []byte("package main\n\n// edited\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := ExecuteGitBlame(context.Background(), dir, path, BlameOptions{Contents: contents})
+	if err != nil {
+		t.Fatalf("ExecuteGitBlame failed: %v", err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("expected the 4 lines of the contents, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if uncommitted := i == 2; isUncommitted(line) != uncommitted || uncommitted && line.Author != "Not Committed Yet" {
+			t.Errorf("line %d: unexpected commit %s by %q", line.LineNumber, line.CommitHash, line.Author)
+		}
+	}
+
+	client := &fakeReviewClient{}
+	enriched, err := NewDefaultEnrichmentPipeline(client, &RepoInfo{Owner: "owner", Name: "repo"}).Run(context.Background(), lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.findCalls != 1 || enriched[2].Approver != "" || enriched[2].ReviewState() != ReviewStateUncommitted {
+		t.Errorf("expected no lookup for the uncommitted line, got %d lookups and %+v", client.findCalls, enriched[2])
+	}
+}
+
+func TestParseGitBlameOutputOriginalLocation(t *testing.T) {
+	// --porcelain only writes the commit headers for the first line of a
+	// commit and the filename for the first line of each group
+	sampleOutput := `a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 7 1 2
+author John Doe
+author-mail <john@example.com>
+author-time 1609459200
+author-tz +0100
+summary Move helpers
+filename "caf\303\251.go"
+	first
+a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 8 2
+	second
+b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1 3 3 1
+author Jane Smith
+author-mail <jane@example.com>
+author-time 1609632000
+author-tz +0000
+summary Add third
+previous a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 main.go
+filename main.go
+	third
+a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 20 4 1
+filename util.go
+	fourth
+`
+	result, err := parseGitBlameOutput(sampleOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		line, original int
+		file, author   string
+	}{
+		{1, 7, "café.go", "John Doe"},
+		{2, 8, "café.go", "John Doe"},
+		{3, 3, "main.go", "Jane Smith"},
+		{4, 20, "util.go", "John Doe"},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(result))
+	}
+	for i, want := range expected {
+		got := result[i]
+		if got.LineNumber != want.line || got.OriginalLineNumber != want.original || got.OriginalFilename != want.file || got.Author != want.author {
+			t.Errorf("line %d: expected %+v, got line %d, original %d in %q by %q", i+1, want, got.LineNumber, got.OriginalLineNumber, got.OriginalFilename, got.Author)
+		}
+	}
+	if result[1].Summary != "Move helpers" || result[3].AuthorTimezone != "+0100" {
+		t.Errorf("expected the commit headers to carry over, got %+v", result)
+	}
+}
+
+func TestExecuteGitBlameRenamedFile(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	content := "package main\n\nfunc main() {\n\tprintln(\"one\")\n\tprintln(\"two\")\n\tprintln(\"three\")\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "old.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "old.go")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add old")
+	gitCommand(t, dir, "mv", "old.go", "new.go")
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("// Package main\n"+content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "-A")
+	gitCommand(t, dir, "commit", "-q", "-m", "Rename")
+
+	for _, porcelain := range []bool{false, true} {
+		lines, err := ExecuteGitBlame(context.Background(), dir, filepath.Join(dir, "new.go"), BlameOptions{Porcelain: porcelain})
+		if err != nil {
+			t.Fatalf("ExecuteGitBlame failed: %v", err)
+		}
+		if len(lines) != 8 {
+			t.Fatalf("expected 8 lines, got %d", len(lines))
+		}
+		if lines[0].OriginalFilename != "new.go" || lines[1].OriginalFilename != "old.go" || lines[1].OriginalLineNumber != 1 || lines[1].LineNumber != 2 {
+			t.Errorf("porcelain %v: expected line 2 to come from old.go:1, got %+v", porcelain, lines)
+		}
+		if lines[1].Filename != "new.go" || lines[1].Author == "" {
+			t.Errorf("porcelain %v: expected new.go with its author, got %+v", porcelain, lines[1])
+		}
+	}
+}
+
+func TestDetectRemote(t *testing.T) {
+	tests := []struct {
+		name    string
+		remotes []string
+		tracks  string
+		want    string
+	}{
+		{"origin only", []string{"origin"}, "", "origin"},
+		{"fork", []string{"origin", "upstream"}, "origin", "upstream"},
+		{"tracked remote", []string{"origin", "upstream", "company"}, "company", "company"},
+		{"local tracking", []string{"origin"}, ".", "origin"},
+		{"no remotes", nil, "", "origin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			gitCommand(t, dir, "init", "-q", "-b", "main")
+			for _, remote := range tt.remotes {
+				gitCommand(t, dir, "remote", "add", remote, "https://github.com/"+remote+"/repo.git")
+			}
+			if tt.tracks != "" {
+				gitCommand(t, dir, "config", "branch.main.remote", tt.tracks)
+			}
+			if got := DetectRemote(dir); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExtractRepoInfoFromRemote(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/fork/repo.git")
+	gitCommand(t, dir, "remote", "add", "upstream", "https://github.com/owner/repo.git")
+
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"", "owner"},
+		{"upstream", "owner"},
+		{"origin", "fork"},
+	}
+	for _, tt := range tests {
+		repoInfo, err := ExtractRepoInfo(dir, tt.remote)
+		if err != nil {
+			t.Fatalf("ExtractRepoInfo(%q) failed: %v", tt.remote, err)
+		}
+		if repoInfo.Owner != tt.want {
+			t.Errorf("ExtractRepoInfo(%q): expected owner %s, got %s", tt.remote, tt.want, repoInfo.Owner)
+		}
+	}
+	if _, err := ExtractRepoInfo(dir, "missing"); err == nil || !strings.Contains(err.Error(), "no remote named missing") {
+		t.Errorf("expected an error for a missing remote, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// GiteaTokenEnv is the environment variable holding the Gitea or Forgejo
+// access token
+const GiteaTokenEnv = "GITEA_TOKEN"
+
+// giteaReviewsPageSize is the page size used when listing PR reviews
+const giteaReviewsPageSize = 50
+
+// GiteaClient handles Gitea and Forgejo API interactions, including Codeberg
+type GiteaClient struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewGiteaClient creates a new Gitea API client for the instance at host
+func NewGiteaClient(token, host string) *GiteaClient {
+	return &GiteaClient{
+		token:      token,
+		baseURL:    fmt.Sprintf("https://%s/api/v1", host),
+		httpClient: newHTTPClient(30 * time.Second),
+	}
+}
+
+// newGiteaClientForRepo creates a client for the API host of repoInfo
+func newGiteaClientForRepo(token string, repoInfo *RepoInfo) *GiteaClient {
+	client := NewGiteaClient(token, repoInfo.Host)
+	if repoInfo.APIURL != "" {
+		client.baseURL = repoInfo.APIURL
+	}
+	return client
+}
+
+// makeRequest makes an authenticated request to the Gitea API
+func (c *GiteaClient) makeRequest(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	return doRequest(c.httpClient, req)
+}
+
+// FindPRByCommit finds the merged pull request that introduced a specific
+// commit. Gitea answers 404 when no merged pull request contains it.
+func (c *GiteaClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s/pull", c.baseURL, owner, repo, commitHash)
+
+	resp, err := c.makeRequest(ctx, "GET", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Gitea API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	// Gitea pull requests use the GitHub field names
+	var pr PullRequest
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetPRByNumber fetches a pull request by its index
+func (c *GiteaClient) GetPRByNumber(ctx context.Context, owner, repo string, prNumber int) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, owner, repo, prNumber)
+
+	resp, err := c.makeRequest(ctx, "GET", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Gitea API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	var pr PullRequest
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetPRApprovals gets the approving reviews of a pull request, leaving out
+// reviews that were dismissed
+func (c *GiteaClient) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	var approvals []Review
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?limit=%d&page=%d", c.baseURL, owner, repo, prNumber, giteaReviewsPageSize, page)
+		resp, err := c.makeRequest(ctx, "GET", url)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("Gitea API error: %d %s", resp.StatusCode, resp.Status)
+		}
+		count := 0
+		err = decodeJSONArray(json.NewDecoder(newResponseReader(resp.Body)), func(dec *json.Decoder) error {
+			var review struct {
+				Review
+				Dismissed bool `json:"dismissed"`
+			}
+			if err := dec.Decode(&review); err != nil {
+				return err
+			}
+			count++
+			if review.State == "APPROVED" && !review.Dismissed {
+				approvals = append(approvals, review.Review)
+			}
+			return nil
+		})
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if count < giteaReviewsPageSize {
+			return approvals, nil
+		}
+	}
+}
+
+// GetPRApprovalInfo gets complete approval information for a commit
+func (c *GiteaClient) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	pr, err := c.FindPRByCommit(ctx, owner, repo, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr == nil {
+		return nil, fmt.Errorf("no pull request found for commit %s", commitHash)
+	}
+
+	approvals, err := c.GetPRApprovals(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRApprovalInfo{
+		PR:        *pr,
+		Approvers: approvals,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGiteaGetPRApprovalInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token test-token" {
+			t.Errorf("unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo/commits/abc123/pull":
+			w.Write([]byte(`{"number": 12, "title": "Add parser", "state": "closed",
+				"user": {"login": "alice"}, "merged_at": "2024-03-02T10:00:00Z", "head": {"ref": "feature/parser"}}`))
+		case "/repos/owner/repo/pulls/12/reviews":
+			var reviews []map[string]interface{}
+			if r.URL.Query().Get("page") == "1" {
+				for i := 0; i < giteaReviewsPageSize-2; i++ {
+					reviews = append(reviews, map[string]interface{}{"user": map[string]string{"login": fmt.Sprintf("user%d", i)}, "state": "COMMENT"})
+				}
+				reviews = append(reviews,
+					map[string]interface{}{"user": map[string]string{"login": "bob"}, "state": "APPROVED"},
+					map[string]interface{}{"user": map[string]string{"login": "dave"}, "state": "APPROVED", "dismissed": true},
+				)
+			} else {
+				reviews = append(reviews, map[string]interface{}{"user": map[string]string{"login": "carol"}, "state": "APPROVED"})
+			}
+			json.NewEncoder(w).Encode(reviews)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGiteaClient("test-token", "codeberg.org")
+	client.baseURL = server.URL
+
+	info, err := client.GetPRApprovalInfo(context.Background(), "owner", "repo", "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.PR.Number != 12 || info.PR.User.Login != "alice" || info.PR.MergedAt == nil {
+		t.Errorf("unexpected pull request %+v", info.PR)
+	}
+	// dave's approval was dismissed
+	if len(info.Approvers) != 2 || info.Approvers[0].User.Login != "bob" || info.Approvers[1].User.Login != "carol" {
+		t.Errorf("expected approvals by bob and carol, got %+v", info.Approvers)
+	}
+}
+
+func TestGiteaFindPRByCommitNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewGiteaClient("test-token", "codeberg.org")
+	client.baseURL = server.URL
+
+	pr, err := client.FindPRByCommit(context.Background(), "owner", "repo", "abc123")
+	if err != nil || pr != nil {
+		t.Errorf("expected no pull request, got %+v, %v", pr, err)
+	}
+}
+
+func TestClientFactoryGitea(t *testing.T) {
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo", Type: RepositoryTypeGitea, Host: "git.example.com"}
+
+	if _, err := (&ClientFactory{}).CreateClient(repoInfo, "github-token", "gitlab-token"); !errors.Is(err, ErrMissingGiteaToken) {
+		t.Errorf("expected ErrMissingGiteaToken, got %v", err)
+	}
+
+	client, err := (&ClientFactory{giteaToken: "gitea-token"}).CreateClient(repoInfo, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gitea, ok := client.(*GiteaClient); !ok || gitea.baseURL != "https://git.example.com/api/v1" {
+		t.Errorf("expected a Gitea client for git.example.com, got %#v", client)
+	}
+}
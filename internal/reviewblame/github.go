@@ -0,0 +1,235 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// GitHubClient handles GitHub API interactions
+type GitHubClient struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewGitHubClient creates a new GitHub API client
+func NewGitHubClient(token string) *GitHubClient {
+	return &GitHubClient{
+		token:      token,
+		baseURL:    "https://api.github.com",
+		httpClient: newHTTPClient(30 * time.Second),
+	}
+}
+
+// newGitHubClientForRepo creates a client for the API host of repoInfo
+func newGitHubClientForRepo(token string, repoInfo *RepoInfo) *GitHubClient {
+	client := NewGitHubClient(token)
+	if repoInfo.APIURL != "" {
+		client.baseURL = repoInfo.APIURL
+	}
+	return client
+}
+
+// makeRequest makes an authenticated request to the GitHub API
+func (c *GitHubClient) makeRequest(ctx context.Context, method, url string) (*http.Response, error) {
+	return c.makeRequestWithBody(ctx, method, url, nil)
+}
+
+// makeRequestWithBody makes an authenticated request with a JSON body to the GitHub API
+func (c *GitHubClient) makeRequestWithBody(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add authentication header
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doRequest(c.httpClient, req)
+}
+
+// PullRequest represents basic PR information from GitHub API
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	MergedAt *time.Time `json:"merged_at"`
+	Body     string     `json:"body"`
+	Head     struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Labels []PRLabel `json:"labels"`
+}
+
+// PRLabel is a label of a PR/MR
+type PRLabel struct {
+	Name string `json:"name"`
+}
+
+// LabelNames returns the names of the PR's labels
+func (pr *PullRequest) LabelNames() []string {
+	var names []string
+	for _, label := range pr.Labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+// Review represents a PR review from GitHub API
+type Review struct {
+	User struct {
+		Login     string `json:"login"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"user"`
+	State       string     `json:"state"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+	// HTMLURL is the web page of the review, empty when the service has none
+	HTMLURL string `json:"html_url"`
+}
+
+// PRApprovalInfo contains information about PR approvals
+type PRApprovalInfo struct {
+	PR        PullRequest
+	Approvers []Review
+}
+
+// FindPRByCommit finds the pull request that introduced a specific commit
+func (c *GitHubClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s/pulls", c.baseURL, owner, repo, commitHash)
+
+	resp, err := c.makeRequest(ctx, "GET", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	// Stop decoding once the chosen PR can no longer be replaced
+	var pr *PullRequest
+	err = decodeJSONArray(json.NewDecoder(newResponseReader(resp.Body)), func(dec *json.Decoder) error {
+		candidate := &PullRequest{}
+		if err := dec.Decode(candidate); err != nil {
+			return err
+		}
+		if preferCommitPR(pr, candidate) {
+			pr = candidate
+		}
+		if pr.MergedAt != nil {
+			return errStopDecoding
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// preferCommitPR reports whether candidate replaces chosen as the PR of a
+// commit, for PRs in the order GitHub lists them: the first merged PR wins,
+// otherwise the first one. The REST and GraphQL lookups share this rule so
+// a commit resolves to the same PR either way.
+func preferCommitPR(chosen, candidate *PullRequest) bool {
+	return chosen == nil || (chosen.MergedAt == nil && candidate.MergedAt != nil)
+}
+
+// GetPRApprovals gets all approvals for a specific pull request, reading
+// every page of its reviews
+func (c *GitHubClient) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews", c.baseURL, owner, repo, prNumber)
+
+	// Decode reviews one at a time, keeping only approvals, so PRs with
+	// hundreds of comment reviews are never held in memory at once
+	var approvals []Review
+	err := c.listPages(ctx, url, func(dec *json.Decoder) error {
+		var review Review
+		if err := dec.Decode(&review); err != nil {
+			return err
+		}
+		if review.State == "APPROVED" {
+			approvals = append(approvals, review)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+// GetPRApprovalInfo gets complete approval information for a commit
+func (c *GitHubClient) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	pr, err := c.FindPRByCommit(ctx, owner, repo, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr == nil {
+		return nil, fmt.Errorf("no pull request found for commit %s", commitHash)
+	}
+
+	approvals, err := c.GetPRApprovals(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRApprovalInfo{
+		PR:        *pr,
+		Approvers: approvals,
+	}, nil
+}
+
+// GitHubClientAdapter adapts GitHubClient to implement ReviewClient interface
+type GitHubClientAdapter struct {
+	client *GitHubClient
+	// commits maps "owner/repo@commit" to its PrefetchCommits result
+	commits map[string]*CommitPRInfo
+	// approvals maps "owner/repo#number" to the prefetched approvals of a PR
+	approvals map[string][]Review
+}
+
+// NewGitHubClientAdapter creates a new adapter for GitHubClient
+func NewGitHubClientAdapter(token string) ReviewClient {
+	return &GitHubClientAdapter{
+		client: NewGitHubClient(token),
+	}
+}
+
+// FindPRByCommit implements ReviewClient interface
+func (a *GitHubClientAdapter) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	if info, exists := a.prefetchedPR(owner, repo, commitHash); exists {
+		return info.PR, nil
+	}
+	return a.client.FindPRByCommit(ctx, owner, repo, commitHash)
+}
+
+// GetPRApprovals implements ReviewClient interface
+func (a *GitHubClientAdapter) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	if approvals, exists := a.prefetchedApprovals(owner, repo, prNumber); exists {
+		return approvals, nil
+	}
+	return a.client.GetPRApprovals(ctx, owner, repo, prNumber)
+}
+
+// GetPRApprovalInfo implements ReviewClient interface
+func (a *GitHubClientAdapter) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	return a.client.GetPRApprovalInfo(ctx, owner, repo, commitHash)
+}
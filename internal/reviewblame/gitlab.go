@@ -0,0 +1,307 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GitLabClient handles GitLab API interactions
+type GitLabClient struct {
+	token      string
+	httpClient *http.Client
+	baseURL    string
+	host       string
+}
+
+// NewGitLabClient creates a new GitLab API client
+func NewGitLabClient(token, host string) ReviewClient {
+	return newGitLabClient(token, host)
+}
+
+// newGitLabClient creates a new GitLab API client with access to GitLab-only endpoints
+func newGitLabClient(token, host string) *GitLabClient {
+	baseURL := fmt.Sprintf("https://%s/api/v4", host)
+	if host == "gitlab.com" {
+		baseURL = "https://gitlab.com/api/v4"
+	}
+
+	return &GitLabClient{
+		token:      token,
+		baseURL:    baseURL,
+		host:       host,
+		httpClient: newHTTPClient(30 * time.Second),
+	}
+}
+
+// newGitLabClientForRepo creates a client for the API host of repoInfo
+func newGitLabClientForRepo(token string, repoInfo *RepoInfo) *GitLabClient {
+	client := newGitLabClient(token, repoInfo.Host)
+	if repoInfo.APIURL != "" {
+		client.baseURL = repoInfo.APIURL
+	}
+	return client
+}
+
+// makeRequest makes an authenticated request to the GitLab API
+func (c *GitLabClient) makeRequest(ctx context.Context, method, apiURL string) (*http.Response, error) {
+	return c.makeRequestWithBody(ctx, method, apiURL, nil)
+}
+
+// makeRequestWithBody makes an authenticated request with a JSON body to the GitLab API
+func (c *GitLabClient) makeRequestWithBody(ctx context.Context, method, apiURL string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add authentication header
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doRequest(c.httpClient, req)
+}
+
+// GitLabMergeRequest represents basic MR information from GitLab API
+type GitLabMergeRequest struct {
+	IID          int        `json:"iid"`
+	Title        string     `json:"title"`
+	State        string     `json:"state"`
+	WebURL       string     `json:"web_url"`
+	Author       GitLabUser `json:"author"`
+	MergedAt     *time.Time `json:"merged_at"`
+	Description  string     `json:"description"`
+	SourceBranch string     `json:"source_branch"`
+	Labels       []string   `json:"labels"`
+}
+
+// toPullRequest converts a GitLab MR to GitHub PR format for compatibility
+func (mr *GitLabMergeRequest) toPullRequest() *PullRequest {
+	pr := &PullRequest{
+		Number: mr.IID,
+		Title:  mr.Title,
+		State:  mr.State,
+		User: struct {
+			Login string `json:"login"`
+		}{Login: mr.Author.Username},
+		MergedAt: mr.MergedAt,
+		Body:     mr.Description,
+	}
+	pr.Head.Ref = mr.SourceBranch
+	for _, label := range mr.Labels {
+		pr.Labels = append(pr.Labels, PRLabel{Name: label})
+	}
+	return pr
+}
+
+// GitLabUser represents a GitLab user
+type GitLabUser struct {
+	Name      string `json:"name"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// GitLabApproval represents a GitLab MR approval
+type GitLabApproval struct {
+	User      GitLabUser `json:"user"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// FindPRByCommit finds the merge request that introduced a specific commit
+func (c *GitLabClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	// Encode the project path
+	projectPath := url.PathEscape(fmt.Sprintf("%s/%s", owner, repo))
+	apiURL := fmt.Sprintf("%s/projects/%s/repository/commits/%s/merge_requests", c.baseURL, projectPath, commitHash)
+
+	resp, err := c.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitLab API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	// Only the first (most relevant) MR is needed, so stop decoding after it
+	var mr *GitLabMergeRequest
+	err = decodeJSONArray(json.NewDecoder(newResponseReader(resp.Body)), func(dec *json.Decoder) error {
+		mr = &GitLabMergeRequest{}
+		if err := dec.Decode(mr); err != nil {
+			return err
+		}
+		return errStopDecoding
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mr == nil {
+		return nil, nil
+	}
+	return mr.toPullRequest(), nil
+}
+
+// GetPRByNumber fetches a merge request by its IID
+func (c *GitLabClient) GetPRByNumber(ctx context.Context, owner, repo string, prNumber int) (*PullRequest, error) {
+	projectPath := url.PathEscape(fmt.Sprintf("%s/%s", owner, repo))
+	apiURL := fmt.Sprintf("%s/projects/%s/merge_requests/%d", c.baseURL, projectPath, prNumber)
+
+	resp, err := c.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitLab API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	var mr GitLabMergeRequest
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&mr); err != nil {
+		return nil, err
+	}
+	return mr.toPullRequest(), nil
+}
+
+// GetPRApprovals gets all approvals for a specific merge request
+func (c *GitLabClient) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	// Encode the project path
+	projectPath := url.PathEscape(fmt.Sprintf("%s/%s", owner, repo))
+	apiURL := fmt.Sprintf("%s/projects/%s/merge_requests/%d/approvals", c.baseURL, projectPath, prNumber)
+
+	resp, err := c.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitLab API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	// Stream the approved_by list of the approval response, converting GitLab
+	// approvals to GitHub review format, and ignore the fields after it
+	var reviews []Review
+	_, err = decodeJSONField(json.NewDecoder(newResponseReader(resp.Body)), "approved_by", func(dec *json.Decoder) error {
+		return decodeJSONArray(dec, func(dec *json.Decoder) error {
+			var approval GitLabApproval
+			if err := dec.Decode(&approval); err != nil {
+				return err
+			}
+			reviews = append(reviews, newGitLabApprovalReview(approval.User, approval.CreatedAt))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// GitLab clears approved_by when new pushes invalidate approvals, so a
+	// merged MR can report no approvers; recover them from the approval history
+	if len(reviews) == 0 {
+		if historical, err := c.GetHistoricalApprovals(ctx, owner, repo, prNumber); err == nil {
+			reviews = historical
+		}
+	}
+
+	return reviews, nil
+}
+
+// newGitLabApprovalReview converts a GitLab approval to GitHub review format
+func newGitLabApprovalReview(user GitLabUser, approvedAt *time.Time) Review {
+	review := Review{
+		State:       "APPROVED",
+		SubmittedAt: approvedAt,
+	}
+	review.User.Login = user.Username
+	review.User.Email = user.Email
+	review.User.AvatarURL = user.AvatarURL
+	return review
+}
+
+// GitLab system note bodies recording approval changes
+const (
+	gitLabApprovedNote   = "approved this merge request"
+	gitLabUnapprovedNote = "unapproved this merge request"
+)
+
+// GetHistoricalApprovals replays the approval system notes of a merge request
+// and returns the users whose approval stood when it was merged (or now, if
+// it is not merged), ordered by approval time
+func (c *GitLabClient) GetHistoricalApprovals(ctx context.Context, owner, repo string, mrIID int) ([]Review, error) {
+	var mr GitLabMergeRequest
+	if err := c.doJSON(ctx, "GET", c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d", mrIID)), nil, &mr, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	approvals := make(map[string]Review)
+	notesURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/notes?sort=asc&order_by=created_at", mrIID))
+	err := c.listPages(ctx, notesURL, func(dec *json.Decoder) error {
+		var note struct {
+			Body      string     `json:"body"`
+			System    bool       `json:"system"`
+			Author    GitLabUser `json:"author"`
+			CreatedAt time.Time  `json:"created_at"`
+		}
+		if err := dec.Decode(&note); err != nil {
+			return err
+		}
+		if !note.System || (mr.MergedAt != nil && note.CreatedAt.After(*mr.MergedAt)) {
+			return nil
+		}
+		username := note.Author.Username
+		switch strings.TrimSpace(note.Body) {
+		case gitLabApprovedNote:
+			approvedAt := note.CreatedAt
+			approvals[username] = newGitLabApprovalReview(note.Author, &approvedAt)
+		case gitLabUnapprovedNote:
+			delete(approvals, username)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reviews []Review
+	for _, review := range approvals {
+		reviews = append(reviews, review)
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		return reviews[i].SubmittedAt.Before(*reviews[j].SubmittedAt)
+	})
+
+	return reviews, nil
+}
+
+// GetPRApprovalInfo gets complete approval information for a commit
+func (c *GitLabClient) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	pr, err := c.FindPRByCommit(ctx, owner, repo, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr == nil {
+		return nil, fmt.Errorf("no merge request found for commit %s", commitHash)
+	}
+
+	approvals, err := c.GetPRApprovals(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRApprovalInfo{
+		PR:        *pr,
+		Approvers: approvals,
+	}, nil
+}
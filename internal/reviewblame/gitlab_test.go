@@ -0,0 +1,135 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetPRApprovalsFallsBackToHistory(t *testing.T) {
+	base := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	mergedAt := base.Add(5 * time.Hour)
+	note := func(hours int, username, body string, system bool) map[string]interface{} {
+		return map[string]interface{}{
+			"body":       body,
+			"system":     system,
+			"author":     map[string]string{"username": username},
+			"created_at": base.Add(time.Duration(hours) * time.Hour),
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/projects/owner%2Frepo/merge_requests/9"
+		switch r.URL.EscapedPath() {
+		case prefix + "/approvals":
+			// Approvals were reset by a push after the MR was approved
+			json.NewEncoder(w).Encode(map[string]interface{}{"approved_by": []interface{}{}})
+		case prefix:
+			json.NewEncoder(w).Encode(GitLabMergeRequest{IID: 9, MergedAt: &mergedAt})
+		case prefix + "/notes":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				note(1, "bob", "approved this merge request", true),
+				note(2, "carol", "approved this merge request", true),
+				note(3, "carol", "unapproved this merge request", true),
+				note(3, "dave", "approved this merge request", false),
+				note(4, "alice", "approved this merge request", true),
+				note(6, "erin", "approved this merge request", true),
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	reviews, err := client.GetPRApprovals(context.Background(), "owner", "repo", 9)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// carol withdrew, dave's note is a regular comment and erin approved after merge
+	if len(reviews) != 2 || reviews[0].User.Login != "bob" || reviews[1].User.Login != "alice" {
+		t.Fatalf("expected approvals by bob then alice, got %+v", reviews)
+	}
+	if !reviews[1].SubmittedAt.Equal(base.Add(4 * time.Hour)) {
+		t.Errorf("expected approval time from the system note, got %v", reviews[1].SubmittedAt)
+	}
+}
+
+func TestGetPRApprovalsCurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/owner%2Frepo/merge_requests/9/approvals" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"approved_by": []map[string]interface{}{
+				{"user": map[string]string{"username": "alice", "email": "alice@example.com"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	reviews, err := client.GetPRApprovals(context.Background(), "owner", "repo", 9)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].User.Login != "alice" || reviews[0].User.Email != "alice@example.com" {
+		t.Errorf("unexpected reviews %+v", reviews)
+	}
+}
+
+func TestGitLabFindPRByCommitLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"iid": 7, "title": "Harden login", "labels": []string{"security-review", "backend"}},
+		})
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	pr, err := client.FindPRByCommit(context.Background(), "owner", "repo", "aaaa")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels := pr.LabelNames(); len(labels) != 2 || labels[0] != "security-review" || labels[1] != "backend" {
+		t.Errorf("expected the MR labels, got %v", labels)
+	}
+}
+
+func TestGitLabGetPRByNumber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/group%2Fproject/merge_requests/45" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"iid": 45, "title": "Harden login", "source_branch": "login", "author": map[string]string{"username": "carol"},
+		})
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	pr, err := client.GetPRByNumber(context.Background(), "group", "project", 45)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.Number != 45 || pr.Title != "Harden login" || pr.Head.Ref != "login" || pr.User.Login != "carol" {
+		t.Errorf("unexpected merge request %+v", pr)
+	}
+	if _, err := client.GetPRByNumber(context.Background(), "group", "project", 46); err == nil {
+		t.Error("expected an error for a missing merge request")
+	}
+}
@@ -0,0 +1,66 @@
+package reviewblame
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchGlob reports whether a slash-separated repository path matches a
+// glob pattern. "**" matches any number of directories; a pattern without
+// a slash matches the file name in any directory, as in .gitignore.
+func MatchGlob(pattern, relPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(relPath))
+		return matched
+	}
+	return matchGlobSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(relPath, "/"))
+}
+
+// matchGlobSegments matches path segments against pattern segments
+func matchGlobSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchGlobSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// FilterGlobFiles keeps the absolute file paths whose path relative to the
+// repository root matches any of the glob patterns
+func FilterGlobFiles(repoRoot string, files, patterns []string) ([]string, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+
+	var matched []string
+	for _, file := range files {
+		relPath, err := filepath.Rel(repoRoot, file)
+		if err != nil {
+			return nil, err
+		}
+		for _, pattern := range patterns {
+			if MatchGlob(pattern, filepath.ToSlash(relPath)) {
+				matched = append(matched, file)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,172 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.go", path: "main.go", want: true},
+		{pattern: "*.go", path: "cmd/tool/main.go", want: true},
+		{pattern: "*.go", path: "main.go.orig", want: false},
+		{pattern: "src/*.go", path: "src/main.go", want: true},
+		{pattern: "src/*.go", path: "src/pkg/main.go", want: false},
+		{pattern: "src/**/*.go", path: "src/main.go", want: true},
+		{pattern: "src/**/*.go", path: "src/pkg/deep/main.go", want: true},
+		{pattern: "src/**/*.go", path: "lib/main.go", want: false},
+		{pattern: "**/testdata/**", path: "a/testdata/b/c.txt", want: true},
+		{pattern: "/docs/*.md", path: "docs/README.md", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilterGlobFiles(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	files := []string{
+		filepath.Join(root, "main.go"),
+		filepath.Join(root, "README.md"),
+		filepath.Join(root, "docs", "guide.md"),
+	}
+
+	matched, err := FilterGlobFiles(root, files, []string{"*.go", "docs/**"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matched) != 2 || matched[0] != files[0] || matched[1] != files[2] {
+		t.Errorf("unexpected files %v", matched)
+	}
+
+	if _, err := FilterGlobFiles(root, files, []string{"[a-"}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestAnnotatePathsSharesLookups(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	for name, content := range map[string]string{
+		"a.go":       "package a\n",
+		"b.go":       "package b\n",
+		"docs/x.md":  "# X\n",
+		"lib/c.go":   "package c\n",
+		"lib/c.json": "{}\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+	commit := strings.TrimSpace(gitCommand(t, dir, "rev-parse", "HEAD"))
+
+	client := &fakeReviewClient{prs: map[string]int{commit: 3}}
+	pipeline := NewDefaultEnrichmentPipeline(client, &RepoInfo{Owner: "owner", Name: "repo"})
+
+	paths := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "lib"), filepath.Join(dir, "a.go")}
+	lines, err := annotatePaths(context.Background(), dir, paths, "", []string{"*.go"}, BlameOptions{}, pipeline, false)
+	if err != nil {
+		t.Fatalf("annotatePaths failed: %v", err)
+	}
+
+	var files []string
+	for _, line := range lines {
+		files = append(files, line.Filename)
+		if line.PRNumber != 3 {
+			t.Errorf("%s: expected PR 3, got %d", line.Filename, line.PRNumber)
+		}
+	}
+	if strings.Join(files, " ") != "a.go lib/c.go" {
+		t.Errorf("expected a.go and lib/c.go once each, got %v", files)
+	}
+	if client.findCalls != 1 {
+		t.Errorf("expected one PR lookup shared by both files, got %d", client.findCalls)
+	}
+
+	if _, err := annotatePaths(context.Background(), dir, []string{dir}, "", []string{"*.rs"}, BlameOptions{}, pipeline, false); err == nil {
+		t.Error("expected an error when no file matches the globs")
+	}
+}
+
+func TestBlameFilesKeepsOrder(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	var files []string
+	for i := range 6 {
+		name := fmt.Sprintf("f%d.txt", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+
+	lines, err := blameFiles(context.Background(), dir, files, "", BlameOptions{Jobs: 3})
+	if err != nil {
+		t.Fatalf("blameFiles failed: %v", err)
+	}
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.Content)
+	}
+	if strings.Join(got, " ") != "f0.txt f1.txt f2.txt f3.txt f4.txt f5.txt" {
+		t.Errorf("expected the lines in file order, got %v", got)
+	}
+
+	_, err = blameFiles(context.Background(), dir, append(files, filepath.Join(dir, "missing.txt")), "", BlameOptions{Jobs: 2})
+	if err == nil || !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("expected the failing file in the error, got %v", err)
+	}
+}
+
+func TestWriteFilesGroupsHumanOutput(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaa", Filename: "a.go", LineNumber: 1, Author: "alice", Content: "package a"}},
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaa", Filename: "a.go", LineNumber: 2, Author: "alice", Content: ""}},
+		{BlameLine: BlameLine{CommitHash: "bbbbbbbb", Filename: "b.go", LineNumber: 1, Author: "bob", Content: "package b"}},
+	}
+	human, err := DefaultFormatters.Lookup("human")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := writeFiles(&out, human, lines, FormatOptions{NoColors: true}, true); err != nil {
+		t.Fatalf("writeFiles failed: %v", err)
+	}
+	output := out.String()
+	if !strings.HasPrefix(output, "==> a.go <==\n") || !strings.Contains(output, "\n\n==> b.go <==\n") || strings.Count(output, "==>") != 2 {
+		t.Errorf("expected a header per file, got:\n%s", output)
+	}
+
+	csv, err := DefaultFormatters.Lookup("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := writeFiles(&out, csv, lines, FormatOptions{Columns: []string{"file", "line"}}, false); err != nil {
+		t.Fatalf("writeFiles failed: %v", err)
+	}
+	if out.String() != "file,line\na.go,1\na.go,2\nb.go,1\n" {
+		t.Errorf("expected one CSV stream, got %q", out.String())
+	}
+}
@@ -0,0 +1,202 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// The blame backends BlameOptions.Backend selects
+const (
+	// BackendExec runs the git CLI; it is the default
+	BackendExec = "exec"
+	// BackendGoGit blames in-process with go-git, for containers and other
+	// environments without the git CLI
+	BackendGoGit = "go-git"
+	// BackendIncremental runs git blame --incremental, so the lookups of
+	// the first hunks overlap with git computing the rest of a large file
+	BackendIncremental = "incremental"
+)
+
+// ParseBackend validates a -backend value, "" selecting BackendExec
+func ParseBackend(name string) (string, error) {
+	switch name {
+	case "", BackendExec:
+		return BackendExec, nil
+	case BackendGoGit, BackendIncremental:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown blame backend %q (expected %s, %s or %s)", name, BackendExec, BackendIncremental, BackendGoGit)
+}
+
+// openGoGitRepository opens the repository at repoRoot with go-git,
+// including linked worktrees
+func openGoGitRepository(repoRoot string) (*git.Repository, error) {
+	repo, err := git.PlainOpenWithOptions(repoRoot, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", repoRoot, err)
+	}
+	return repo, nil
+}
+
+// executeGoGitBlame annotates filePath as of rev, or HEAD when rev is empty,
+// with go-git. go-git only follows lines through the file's own history, so
+// the options of git blame that change the attribution are rejected; line
+// ranges may be given as "start,end" or "start,+count". Like git blame at a
+// revision, uncommitted changes of the working tree are not annotated.
+func executeGoGitBlame(ctx context.Context, repoRoot, filePath, rev string, opts BlameOptions) ([]BlameLine, error) {
+	switch {
+	case opts.DetectMoves:
+		return nil, fmt.Errorf("-M is not supported by the %s backend", BackendGoGit)
+	case opts.CopyDetection > 0:
+		return nil, fmt.Errorf("-C is not supported by the %s backend", BackendGoGit)
+	case opts.IgnoreWhitespace:
+		return nil, fmt.Errorf("-w is not supported by the %s backend", BackendGoGit)
+	case len(opts.IgnoreRevsFiles) > 0:
+		return nil, fmt.Errorf("-ignore-revs-file is not supported by the %s backend", BackendGoGit)
+	case opts.Contents != "":
+		return nil, fmt.Errorf("-contents is not supported by the %s backend", BackendGoGit)
+	}
+
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(repoRoot, absFilePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	repo, err := openGoGitRepository(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %s: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result, err := git.Blame(commit, relPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not blame %s: %w", relPath, err)
+	}
+
+	keep, err := goGitLineRanges(opts.LineRanges, len(result.Lines))
+	if err != nil {
+		return nil, err
+	}
+
+	// Like git blame, report commit authors as .mailmap maps them
+	mailmap, err := ReadMailmap(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[plumbing.Hash]string)
+	var lines []BlameLine
+	for i, line := range result.Lines {
+		if keep != nil && !keep[i] {
+			continue
+		}
+		summary, ok := summaries[line.Hash]
+		if !ok {
+			if lineCommit, err := repo.CommitObject(line.Hash); err == nil {
+				summary = commitSummary(lineCommit)
+			}
+			summaries[line.Hash] = summary
+		}
+		author, authorEmail := mailmap.Resolve(line.AuthorName, line.Author)
+		lines = append(lines, BlameLine{
+			CommitHash:     line.Hash.String(),
+			Filename:       relPath,
+			Author:         author,
+			AuthorEmail:    authorEmail,
+			Date:           strconv.FormatInt(line.Date.Unix(), 10),
+			AuthorTimezone: line.Date.Format("-0700"),
+			LineNumber:     i + 1,
+			Content:        line.Text,
+			Summary:        summary,
+		})
+	}
+	return lines, nil
+}
+
+// commitSummary returns the subject line of a commit message
+func commitSummary(commit *object.Commit) string {
+	summary, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+	return summary
+}
+
+// goGitLineRanges returns which of the total lines the line ranges select,
+// or nil when there are none. Only the numeric forms of git blame -L are
+// supported; as with git blame, overlapping ranges are merged.
+func goGitLineRanges(lineRanges []string, total int) ([]bool, error) {
+	if len(lineRanges) == 0 {
+		return nil, nil
+	}
+	keep := make([]bool, total)
+	for _, lineRange := range lineRanges {
+		startText, endText, found := strings.Cut(lineRange, ",")
+		start, err := strconv.Atoi(startText)
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("line range %q is not supported by the %s backend (expected <start>,<end> or <start>,+<count>)", lineRange, BackendGoGit)
+		}
+		end := total
+		if found && endText != "" {
+			count, isCount := strings.CutPrefix(endText, "+")
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("line range %q is not supported by the %s backend (expected <start>,<end> or <start>,+<count>)", lineRange, BackendGoGit)
+			}
+			end = n
+			if isCount {
+				end = start + n - 1
+			}
+		}
+		if start > total {
+			return nil, fmt.Errorf("line range %q: file has only %d lines", lineRange, total)
+		}
+		if end < start {
+			start, end = end, start
+		}
+		for line := start; line <= min(end, total); line++ {
+			keep[line-1] = true
+		}
+	}
+	return keep, nil
+}
+
+// goGitRemoteURL returns the URL of a remote read with go-git, for when the
+// git CLI is not installed
+func goGitRemoteURL(repoRoot, name string) (string, error) {
+	repo, err := openGoGitRepository(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	remote, err := repo.Remote(name)
+	if err != nil {
+		return "", err
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", fmt.Errorf("remote %s has no URL", name)
+	}
+	return urls[0], nil
+}
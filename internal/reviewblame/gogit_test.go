@@ -0,0 +1,126 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", BackendExec, false},
+		{"exec", BackendExec, false},
+		{"go-git", BackendGoGit, false},
+		{"incremental", BackendIncremental, false},
+		{"libgit2", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBackend(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBackend(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestGoGitLineRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranges  []string
+		want    string
+		wantErr bool
+	}{
+		{name: "no ranges", want: "[]"},
+		{name: "start and end", ranges: []string{"2,3"}, want: "[2 3]"},
+		{name: "count", ranges: []string{"4,+2"}, want: "[4 5]"},
+		{name: "to the end", ranges: []string{"5"}, want: "[5 6]"},
+		{name: "reversed", ranges: []string{"3,1"}, want: "[1 2 3]"},
+		{name: "merged and clamped", ranges: []string{"5,9", "1,1", "5,5"}, want: "[1 5 6]"},
+		{name: "regex", ranges: []string{"/func/,+2"}, wantErr: true},
+		{name: "function", ranges: []string{":main"}, wantErr: true},
+		{name: "past the end", ranges: []string{"7,8"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, err := goGitLineRanges(tt.ranges, 6)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			var lines []int
+			for i, kept := range keep {
+				if kept {
+					lines = append(lines, i+1)
+				}
+			}
+			if fmt.Sprint(lines) != tt.want {
+				t.Errorf("expected lines %s, got %v", tt.want, lines)
+			}
+		})
+	}
+}
+
+func TestExecuteGoGitBlame(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "git@github.com:owner/repo.git")
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "src", "main.go")
+	if err := os.WriteFile(file, []byte("package main\n\nfunc main() {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "-A")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main\n\nWith a body")
+	if err := os.WriteFile(file, []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "commit", "-q", "-am", "Print")
+
+	ctx := context.Background()
+	want, err := ExecuteGitBlame(ctx, dir, file, BlameOptions{})
+	if err != nil {
+		t.Fatalf("exec backend failed: %v", err)
+	}
+	got, err := ExecuteGitBlame(ctx, dir, file, BlameOptions{Backend: BackendGoGit})
+	if err != nil {
+		t.Fatalf("go-git backend failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d lines, got %d", len(want), len(got))
+	}
+	for i := range want {
+		w, g := want[i], got[i]
+		if g.CommitHash != w.CommitHash || g.Filename != w.Filename || g.Author != w.Author || g.AuthorEmail != w.AuthorEmail ||
+			g.Date != w.Date || g.AuthorTimezone != w.AuthorTimezone || g.LineNumber != w.LineNumber || g.Content != w.Content || g.Summary != w.Summary {
+			t.Errorf("line %d: expected %+v, got %+v", i+1, w, g)
+		}
+	}
+
+	first := gitCommand(t, dir, "rev-parse", "HEAD~1")
+	lines, err := ExecuteGitBlameAt(ctx, dir, file, "HEAD~1", BlameOptions{Backend: BackendGoGit, LineRanges: []string{"3,+2"}})
+	if err != nil {
+		t.Fatalf("go-git backend failed at HEAD~1: %v", err)
+	}
+	if len(lines) != 2 || lines[0].LineNumber != 3 || lines[1].Content != "}" || lines[1].CommitHash != first || lines[0].Summary != "Add main" {
+		t.Errorf("expected lines 3-4 of the first commit, got %+v", lines)
+	}
+
+	if _, err := ExecuteGitBlame(ctx, dir, file, BlameOptions{Backend: BackendGoGit, DetectMoves: true}); err == nil || !strings.Contains(err.Error(), "-M") {
+		t.Errorf("expected -M to be rejected, got %v", err)
+	}
+
+	remoteURL, err := goGitRemoteURL(dir, "origin")
+	if err != nil || remoteURL != "git@github.com:owner/repo.git" {
+		t.Errorf("expected the origin URL, got %q, %v", remoteURL, err)
+	}
+}
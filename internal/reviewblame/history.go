@@ -0,0 +1,242 @@
+package reviewblame
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyFormat is the git log format of ExecuteGitLogRange: a NUL marking
+// the commit header, then unit-separated fields
+const historyFormat = "%x00%H%x1f%an%x1f%ae%x1f%at%x1f%s"
+
+// HistoryCommit is a commit that changed a line range, with the number of
+// lines it added to and removed from the range
+type HistoryCommit struct {
+	BlameLine
+	Added   int
+	Removed int
+}
+
+// ExecuteGitLogRange runs git log -L on the line range of a file, as of rev
+// or HEAD when rev is empty, and returns the commits that changed it, newest
+// first. lineRange takes any range git log -L accepts ("10,20",
+// "/regex/,+5", ":funcname").
+func ExecuteGitLogRange(ctx context.Context, repoRoot, filePath, lineRange, rev string) ([]HistoryCommit, error) {
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(repoRoot, absFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"log", "--format=" + historyFormat, "-L", lineRange + ":" + filepath.ToSlash(relPath)}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git log -L %s: %s", lineRange, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return parseGitLogRange(string(output), filepath.ToSlash(relPath))
+}
+
+// parseGitLogRange parses git log -L output in historyFormat, counting the
+// added and removed lines of each commit's patch
+func parseGitLogRange(output, filename string) ([]HistoryCommit, error) {
+	var commits []HistoryCommit
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header, found := strings.CutPrefix(line, "\x00"); found {
+			fields := strings.Split(header, "\x1f")
+			if len(fields) != 5 {
+				return nil, fmt.Errorf("unexpected git log header %q", header)
+			}
+			commits = append(commits, HistoryCommit{BlameLine: BlameLine{
+				CommitHash:  fields[0],
+				Filename:    filename,
+				Author:      fields[1],
+				AuthorEmail: fields[2],
+				Date:        fields[3],
+				Summary:     fields[4],
+			}})
+			continue
+		}
+		if len(commits) == 0 || strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		commit := &commits[len(commits)-1]
+		if strings.HasPrefix(line, "+") {
+			commit.Added++
+		} else if strings.HasPrefix(line, "-") {
+			commit.Removed++
+		}
+	}
+	return commits, scanner.Err()
+}
+
+// HistoryEntry is a commit of a line range with its PR/MR and approvers
+type HistoryEntry struct {
+	Commit      string     `json:"commit"`
+	Author      string     `json:"author"`
+	AuthorEmail string     `json:"author_email,omitempty"`
+	AuthorTime  *time.Time `json:"author_time,omitempty"`
+	Summary     string     `json:"summary"`
+	Added       int        `json:"added"`
+	Removed     int        `json:"removed"`
+	PRNumber    int        `json:"pr_number,omitempty"`
+	PRTitle     string     `json:"pr_title,omitempty"`
+	Approvers   []string   `json:"approvers,omitempty"`
+	ReviewState string     `json:"review_state"`
+}
+
+// BuildHistory combines the commits of a line range with their enriched
+// lines, one per commit in the same order
+func BuildHistory(commits []HistoryCommit, lines []BlameLineWithApproval) []HistoryEntry {
+	entries := make([]HistoryEntry, len(commits))
+	for i, commit := range commits {
+		line := lines[i]
+		entry := HistoryEntry{
+			Commit:      commit.CommitHash,
+			Author:      line.Author,
+			AuthorEmail: line.AuthorEmail,
+			Summary:     commit.Summary,
+			Added:       commit.Added,
+			Removed:     commit.Removed,
+			PRNumber:    line.PRNumber,
+			PRTitle:     line.PRTitle,
+			ReviewState: line.ReviewState(),
+		}
+		if timestamp, err := strconv.ParseInt(commit.Date, 10, 64); err == nil {
+			authorTime := time.Unix(timestamp, 0).UTC()
+			entry.AuthorTime = &authorTime
+		}
+		for _, approver := range distinctApprovers(line) {
+			entry.Approvers = append(entry.Approvers, approver.Name)
+		}
+		entries[i] = entry
+	}
+	return entries
+}
+
+// formatHistory renders the entries as a table, newest commit first
+func formatHistory(entries []HistoryEntry) string {
+	authors := make([]string, len(entries))
+	prs := make([]string, len(entries))
+	approvers := make([]string, len(entries))
+	authorWidth, prWidth, approverWidth := 0, 0, 0
+	for i, entry := range entries {
+		authors[i] = entry.Author
+		prs[i] = "-"
+		if entry.PRNumber > 0 {
+			prs[i] = fmt.Sprintf("#%d", entry.PRNumber)
+		}
+		approvers[i] = strings.Join(entry.Approvers, ", ")
+		if marker := reviewStateMarkers[entry.ReviewState]; marker != "" {
+			approvers[i] = strings.TrimSpace(approvers[i] + " " + marker)
+		}
+		authorWidth = max(authorWidth, len(authors[i]))
+		prWidth = max(prWidth, len(prs[i]))
+		approverWidth = max(approverWidth, len(approvers[i]))
+	}
+
+	var b strings.Builder
+	for i, entry := range entries {
+		date := ""
+		if entry.AuthorTime != nil {
+			date = entry.AuthorTime.Format("2006-01-02")
+		}
+		changes := fmt.Sprintf("+%d -%d", entry.Added, entry.Removed)
+		fmt.Fprintf(&b, "%s %s %-*s %-*s %-*s %-9s %s\n", shortCommit(entry.Commit), date,
+			authorWidth, authors[i], prWidth, prs[i], approverWidth, approvers[i], changes, entry.Summary)
+	}
+	return b.String()
+}
+
+// runHistory implements the history subcommand
+func runHistory(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	lineRange := flags.String("L", "", "Line range to follow, in any syntax git log -L accepts (\"10,20\", \"/regex/,+5\", \":funcname\")")
+	format := flags.String("format", "text", "Report format: text or json")
+	configPath := flags.String("config", "", "Path to the config file")
+	offline := flags.Bool("offline", false, "Find PRs/MRs from merge commits without API calls")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+
+	if *lineRange == "" {
+		return fmt.Errorf("-L is required\nUsage: git-review-blame history -L <start>,<end> [<rev>] [--] <file>")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported history format %q (expected text or json)", *format)
+	}
+	revision, paths, err := parseBlameArgs(flags.Args())
+	if err != nil {
+		return fmt.Errorf("%w\nUsage: git-review-blame history -L <start>,<end> [<rev>] [--] <file>", err)
+	}
+	if len(paths) != 1 {
+		return fmt.Errorf("history follows a line range of a single file")
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(paths[0], *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+
+	commits, err := ExecuteGitLogRange(ctx, repoRoot, paths[0], *lineRange, revision)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{Offline: *offline, Revision: revision}, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+	lines := make([]BlameLine, len(commits))
+	for i, commit := range commits {
+		lines[i] = commit.BlameLine
+	}
+	enriched, err := pipeline.Run(ctx, lines)
+	if err != nil {
+		return err
+	}
+
+	entries := BuildHistory(commits, enriched)
+	if *format == "json" {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "no commit changed %s:%s\n", displayPath(repoRoot, paths[0]), *lineRange)
+		return nil
+	}
+	fmt.Print(formatHistory(entries))
+	return nil
+}
@@ -0,0 +1,64 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteGitLogRange(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	path := filepath.Join(dir, "main.go")
+	commit := func(content, message string) string {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, dir, "add", ".")
+		gitCommand(t, dir, "commit", "-q", "-m", message)
+		return gitCommand(t, dir, "rev-parse", "HEAD")
+	}
+	first := commit("package main\n\nfunc main() {\n}\n", "Add main")
+	second := commit("package main\n\nfunc main() {\n\tprintln(1)\n}\n", "Print a number")
+	commit("package main\n\nfunc main() {\n\tprintln(1)\n}\n\nfunc other() {}\n", "Add other")
+
+	commits, err := ExecuteGitLogRange(context.Background(), dir, path, "3,5", "")
+	if err != nil {
+		t.Fatalf("ExecuteGitLogRange failed: %v", err)
+	}
+	if len(commits) != 2 || commits[0].CommitHash != second || commits[1].CommitHash != first {
+		t.Fatalf("expected the two commits touching main, newest first, got %+v", commits)
+	}
+	if commits[0].Summary != "Print a number" || commits[0].Author != "Test" || commits[0].Added != 1 || commits[0].Removed != 0 || commits[1].Added != 2 {
+		t.Errorf("unexpected commits %+v", commits)
+	}
+
+	if _, err := ExecuteGitLogRange(context.Background(), dir, path, "40,50", ""); err == nil || !strings.Contains(err.Error(), "git log -L 40,50") {
+		t.Errorf("expected git's error for a range past the end, got %v", err)
+	}
+
+	client := &fakeReviewClient{
+		prs:       map[string]int{second: 42},
+		approvals: map[int][]Review{42: {newTestReview("alice", time.Unix(1700000000, 0))}},
+	}
+	lines := make([]BlameLine, len(commits))
+	for i, commit := range commits {
+		lines[i] = commit.BlameLine
+	}
+	enriched, err := NewDefaultEnrichmentPipeline(client, &RepoInfo{Owner: "owner", Name: "repo"}).Run(context.Background(), lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := BuildHistory(commits, enriched)
+	date := time.Unix(0, 0).UTC()
+	entries[0].AuthorTime, entries[1].AuthorTime = &date, &date
+	want := shortCommit(second) + " 1970-01-01 Test #42 alice   +1 -0     Print a number\n" +
+		shortCommit(first) + " 1970-01-01 Test -   [no PR] +2 -0     Add main\n"
+	if got := formatHistory(entries); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
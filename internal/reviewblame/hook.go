@@ -0,0 +1,267 @@
+package reviewblame
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RefUpdate is a ref update of a push, as git passes it to hooks
+type RefUpdate struct {
+	// OldRev and NewRev are all zeros when the ref is created or deleted
+	OldRev string
+	NewRev string
+	Ref    string
+	// PrePush is set for the updates of a pre-push hook, whose commits
+	// already on a remote-tracking branch were pushed before
+	PrePush bool
+}
+
+// isZeroRev reports whether rev is the all-zero object name of a missing ref
+func isZeroRev(rev string) bool {
+	return rev != "" && strings.Trim(rev, "0") == ""
+}
+
+// ParseRefUpdates reads the ref updates of a pre-receive hook ("<old> <new>
+// <ref>") or a pre-push hook ("<local ref> <local sha> <remote ref> <remote
+// sha>"), one per line
+func ParseRefUpdates(r io.Reader) ([]RefUpdate, error) {
+	var updates []RefUpdate
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch len(fields) {
+		case 0:
+			continue
+		case 3:
+			updates = append(updates, RefUpdate{OldRev: fields[0], NewRev: fields[1], Ref: fields[2]})
+		case 4:
+			updates = append(updates, RefUpdate{OldRev: fields[3], NewRev: fields[1], Ref: fields[2], PrePush: true})
+		default:
+			return nil, fmt.Errorf("unexpected ref update %q (expected \"<old> <new> <ref>\" or \"<local ref> <local sha> <remote ref> <remote sha>\")", scanner.Text())
+		}
+	}
+	return updates, scanner.Err()
+}
+
+// pushedRange returns the git log arguments selecting the commits a ref
+// update adds: those reachable from the new revision but not from the old
+// one or, for a new ref, from any existing ref. Pre-push updates exclude
+// the remote-tracking branches instead, as the old revision may not have
+// been fetched.
+func pushedRange(update RefUpdate) []string {
+	switch {
+	case update.PrePush:
+		return []string{update.NewRev, "--not", "--remotes"}
+	case isZeroRev(update.OldRev):
+		return []string{update.NewRev, "--not", "--all"}
+	default:
+		return []string{update.NewRev, "^" + update.OldRev}
+	}
+}
+
+// PushedCommits returns the commits a ref update adds, none when the ref
+// is deleted
+func PushedCommits(ctx context.Context, repoRoot string, update RefUpdate) ([]string, error) {
+	if isZeroRev(update.NewRev) {
+		return nil, nil
+	}
+	output, err := hookGit(ctx, repoRoot, append([]string{"rev-list"}, pushedRange(update)...)...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+// pushedFiles returns the absolute paths of the files of the new revision
+// that the commits of a ref update changed. Merges count the changes they
+// bring to their first parent, so conflict resolutions are checked too.
+func pushedFiles(ctx context.Context, repoRoot string, update RefUpdate) ([]string, error) {
+	args := append([]string{"log", "-z", "--format=", "--name-only", "--no-renames", "--diff-merges=first-parent"}, pushedRange(update)...)
+	output, err := hookGit(ctx, repoRoot, args...)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool)
+	for _, name := range strings.Split(output, "\x00") {
+		if name = strings.TrimPrefix(name, "\n"); name != "" {
+			changed[name] = true
+		}
+	}
+
+	// Keep the files still in the new revision, skipping deleted files and
+	// submodules, which have no lines to blame
+	output, err = hookGit(ctx, repoRoot, "ls-tree", "-r", "-z", update.NewRev)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range strings.Split(output, "\x00") {
+		info, name, found := strings.Cut(entry, "\t")
+		// info is "<mode> <type> <object>"
+		if fields := strings.Fields(info); !found || !changed[name] || len(fields) < 2 || fields[1] != "blob" {
+			continue
+		}
+		files = append(files, filepath.Join(repoRoot, filepath.FromSlash(name)))
+	}
+	return files, nil
+}
+
+// annotatePush blames the files a ref update changed, as of its new
+// revision, and enriches the lines that came from the pushed commits with
+// the pipeline newPipeline creates for the new revision
+func annotatePush(ctx context.Context, repoRoot string, update RefUpdate, newPipeline func(revision string) (*EnrichmentPipeline, error)) ([]BlameLineWithApproval, error) {
+	commits, err := PushedCommits(ctx, repoRoot, update)
+	if err != nil || len(commits) == 0 {
+		return nil, err
+	}
+	pushed := make(map[string]bool, len(commits))
+	for _, commit := range commits {
+		pushed[commit] = true
+	}
+
+	files, err := pushedFiles(ctx, repoRoot, update)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	blameLines, err := blameFiles(ctx, repoRoot, files, update.NewRev, BlameOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var introduced []BlameLine
+	for _, line := range blameLines {
+		if pushed[line.CommitHash] {
+			introduced = append(introduced, line)
+		}
+	}
+	if len(introduced) == 0 {
+		return nil, nil
+	}
+
+	pipeline, err := newPipeline(update.NewRev)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.Run(ctx, introduced)
+}
+
+// hookGit runs git in the repository of a hook and returns its output
+func hookGit(ctx context.Context, repoRoot string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// hookRepositoryRoot returns the repository a hook runs in: its work tree,
+// or the repository itself when it is bare, as on a server
+func hookRepositoryRoot(ctx context.Context, dir string) (string, error) {
+	output, err := hookGit(ctx, dir, "rev-parse", "--is-bare-repository", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("this directory is not part of a Git repository: %w", err)
+	}
+	bare, gitDir, _ := strings.Cut(strings.TrimSuffix(output, "\n"), "\n")
+	if bare == "true" {
+		return gitDir, nil
+	}
+	return FindGitRoot(dir)
+}
+
+// runHook implements the hook subcommand: it reads the ref updates of a
+// pre-receive or pre-push hook from stdin, or of an update hook from its
+// arguments, and fails when the pushed commits introduce lines without an
+// approved PR/MR
+func runHook(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("hook", flag.ContinueOnError)
+	var refs stringsFlag
+	flags.Var(&refs, "ref", "Check only refs matching the pattern (e.g. 'refs/heads/main'); may be repeated")
+	forbidSelf := flags.Bool("forbid-self-approval", false, "Also reject lines approved only by their own commit or PR/MR author")
+	policyFile := flags.String("policy", "", "Also reject lines violating the Rego policy in the file")
+	configPath := flags.String("config", "", "Path to the config file")
+	offline := flags.Bool("offline", false, "Find PRs/MRs from merge commits without API calls")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+
+	var updates []RefUpdate
+	switch flags.NArg() {
+	case 0:
+		var err error
+		if updates, err = ParseRefUpdates(os.Stdin); err != nil {
+			return err
+		}
+	case 3:
+		// The update hook passes <ref> <old> <new> as arguments
+		updates = []RefUpdate{{Ref: flags.Arg(0), OldRev: flags.Arg(1), NewRev: flags.Arg(2)}}
+	default:
+		return fmt.Errorf("hook reads ref updates from stdin or takes <ref> <old> <new>\nUsage: git-review-blame hook [-ref <pattern>] [<ref> <old> <new>]")
+	}
+
+	repoRoot, err := hookRepositoryRoot(ctx, ".")
+	if err != nil {
+		return err
+	}
+	repoRoot, repoInfo, config, err := openRepositoryAt(repoRoot, *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+	opts := Options{
+		Offline:            *offline,
+		RequireApproval:    true,
+		ForbidSelfApproval: *forbidSelf,
+		PolicyFile:         *policyFile,
+	}
+	newPipeline := func(revision string) (*EnrichmentPipeline, error) {
+		pipelineOpts := opts
+		pipelineOpts.Revision = revision
+		return newEnrichmentPipeline(repoRoot, repoInfo, config, pipelineOpts, githubToken, gitlabToken)
+	}
+
+	var lines []BlameLineWithApproval
+	for _, update := range updates {
+		if len(refs) > 0 && !matchesAnyRef(refs, update.Ref) {
+			continue
+		}
+		pushed, err := annotatePush(ctx, repoRoot, update, newPipeline)
+		if err != nil {
+			return fmt.Errorf("could not check %s: %w", update.Ref, err)
+		}
+		lines = append(lines, pushed...)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	violations, err := evaluatePolicy(config, opts, lines)
+	if err != nil {
+		return err
+	}
+	return reportFailures(opts, lines, violations)
+}
+
+// matchesAnyRef reports whether ref matches any of the glob patterns
+func matchesAnyRef(patterns []string, ref string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, ref) {
+			return true
+		}
+	}
+	return false
+}
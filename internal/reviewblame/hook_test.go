@@ -0,0 +1,159 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRefUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []RefUpdate
+		wantErr bool
+	}{
+		{
+			name:  "pre-receive",
+			input: "1111 2222 refs/heads/main\n0000 3333 refs/heads/topic\n",
+			want: []RefUpdate{
+				{OldRev: "1111", NewRev: "2222", Ref: "refs/heads/main"},
+				{OldRev: "0000", NewRev: "3333", Ref: "refs/heads/topic"},
+			},
+		},
+		{
+			name:  "pre-push",
+			input: "refs/heads/topic 2222 refs/heads/main 1111\n",
+			want:  []RefUpdate{{OldRev: "1111", NewRev: "2222", Ref: "refs/heads/main", PrePush: true}},
+		},
+		{name: "nothing pushed", input: "\n"},
+		{name: "malformed", input: "1111 refs/heads/main\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRefUpdates(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPushedRange(t *testing.T) {
+	zero := strings.Repeat("0", 40)
+	tests := []struct {
+		update RefUpdate
+		want   string
+	}{
+		{RefUpdate{OldRev: "1111", NewRev: "2222"}, "2222 ^1111"},
+		{RefUpdate{OldRev: zero, NewRev: "2222"}, "2222 --not --all"},
+		{RefUpdate{OldRev: "1111", NewRev: "2222", PrePush: true}, "2222 --not --remotes"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(pushedRange(tt.update), " "); got != tt.want {
+			t.Errorf("pushedRange(%+v) = %q, want %q", tt.update, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotatePush(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.go", "package main\n\nfunc main() {\n}\n")
+	write("old.go", "package main\n")
+	gitCommand(t, dir, "add", "-A")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main (#1)\n\nReviewed-by: Alice <alice@example.com>")
+	oldRev := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	// A reviewed change, then a direct push that also deletes a file
+	write("main.go", "package main\n\nfunc main() {\n\thelper()\n}\n")
+	gitCommand(t, dir, "commit", "-q", "-am", "Call helper (#2)\n\nReviewed-by: Bob <bob@example.com>")
+	write("helper.go", "package main\n\nfunc helper() {\n}\n")
+	gitCommand(t, dir, "add", "helper.go")
+	gitCommand(t, dir, "rm", "-q", "old.go")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add helper")
+	newRev := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	root, err := hookRepositoryRoot(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPipeline := func(revision string) (*EnrichmentPipeline, error) {
+		return newOfflinePipeline(root, &Config{}, Options{Revision: revision})
+	}
+	lines, err := annotatePush(context.Background(), root, RefUpdate{OldRev: oldRev, NewRev: newRev, Ref: "refs/heads/main"}, newPipeline)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.Filename+":"+line.Content+":"+line.Approver)
+	}
+	want := []string{
+		"helper.go:package main:", "helper.go::", "helper.go:func helper() {:", "helper.go:}:",
+		"main.go:\thelper():Bob",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the pushed lines %q, got %q", want, got)
+	}
+
+	err = reportFailures(Options{RequireApproval: true}, lines, nil)
+	if err == nil || !strings.Contains(err.Error(), "4 line(s)") {
+		t.Errorf("expected the 4 unapproved lines to fail the push, got %v", err)
+	}
+
+	// Deleting a ref pushes nothing
+	lines, err = annotatePush(context.Background(), root, RefUpdate{OldRev: newRev, NewRev: strings.Repeat("0", 40), Ref: "refs/heads/main"}, newPipeline)
+	if err != nil || len(lines) != 0 {
+		t.Errorf("expected no lines for a deleted ref, got %d, %v", len(lines), err)
+	}
+}
+
+func TestHookRepositoryRoot(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "--bare")
+	root, err := hookRepositoryRoot(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := filepath.EvalSymlinks(dir)
+	if got, _ := filepath.EvalSymlinks(root); got != want {
+		t.Errorf("expected the bare repository %s, got %s", want, root)
+	}
+
+	if _, err := hookRepositoryRoot(context.Background(), t.TempDir()); err == nil {
+		t.Error("expected an error outside a repository")
+	}
+}
+
+func TestMatchesAnyRef(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		ref      string
+		want     bool
+	}{
+		{[]string{"refs/heads/main"}, "refs/heads/main", true},
+		{[]string{"main"}, "refs/heads/main", true},
+		{[]string{"refs/heads/release/*"}, "refs/heads/release/1.0", true},
+		{[]string{"refs/heads/main", "refs/heads/release/*"}, "refs/heads/topic", false},
+	}
+	for _, tt := range tests {
+		if got := matchesAnyRef(tt.patterns, tt.ref); got != tt.want {
+			t.Errorf("matchesAnyRef(%q, %q) = %v, want %v", tt.patterns, tt.ref, got, tt.want)
+		}
+	}
+}
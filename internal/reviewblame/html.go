@@ -0,0 +1,169 @@
+package reviewblame
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"strings"
+)
+
+// htmlLanguages maps file extensions to the highlight.js language used to
+// color the code column of the HTML report
+var htmlLanguages = map[string]string{
+	".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".css": "css", ".go": "go", ".html": "xml", ".xml": "xml", ".java": "java",
+	".js": "javascript", ".jsx": "javascript", ".ts": "typescript", ".tsx": "typescript",
+	".json": "json", ".kt": "kotlin", ".md": "markdown", ".php": "php", ".py": "python",
+	".rb": "ruby", ".rs": "rust", ".scala": "scala", ".sh": "bash", ".sql": "sql",
+	".swift": "swift", ".yaml": "yaml", ".yml": "yaml",
+}
+
+// htmlReport is the data rendered by htmlTemplate
+type htmlReport struct {
+	Files    []htmlFile
+	Stats    ReviewStats
+	Coverage string
+}
+
+// htmlFile is the table of one annotated file
+type htmlFile struct {
+	Name string
+	// Anchor is the name with characters other than letters, digits, "." and
+	// "-" replaced, used in the IDs of the file and its lines
+	Anchor   string
+	Language string
+	Rows     []htmlRow
+}
+
+// htmlRow is one line of a file. Commit details are only shown on the first
+// row of each run of lines from the same commit, as in git blame's output.
+type htmlRow struct {
+	Line         int
+	First        bool
+	Commit       string
+	Author       string
+	PRNumber     int
+	PRTitle      string
+	PRURL        string
+	Approvers    []htmlApprover
+	ApprovalDate string
+	Reviewed     bool
+	Content      string
+}
+
+// htmlApprover is an approver with the link to their review, or to the
+// PR/MR when the service has no review pages
+type htmlApprover struct {
+	Name      string
+	URL       string
+	AvatarURL string
+}
+
+// htmlTemplate renders the report as a single page; html/template escapes
+// names, titles and code, and refuses URLs with unsafe schemes. The code is
+// highlighted by highlight.js when it can be loaded and stays plain otherwise.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Review report: {{range $i, $f := .Files}}{{if $i}}, {{end}}{{$f.Name}}{{end}}</title>
+<link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/styles/github.min.css">
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; font-size: 13px; }
+td { padding: 0 8px; vertical-align: top; white-space: nowrap; }
+tr.first td { border-top: 1px solid #d0d7de; }
+td.num { color: #6e7781; text-align: right; user-select: none; }
+td.num a { color: inherit; text-decoration: none; }
+td.code { width: 100%; white-space: pre; font-family: ui-monospace, SFMono-Regular, Menlo, monospace; }
+td.code code.hljs { padding: 0; background: none; }
+tr.unreviewed td.num { background: #ffebe9; }
+img.avatar { width: 16px; height: 16px; border-radius: 50%; vertical-align: middle; }
+.muted { color: #6e7781; }
+</style>
+</head>
+<body>
+<h1>Review report</h1>
+<p>{{.Stats.ReviewedLines}} of {{.Stats.TotalLines}} lines reviewed ({{.Coverage}}).</p>
+{{range .Files}}{{$file := .}}
+<h2 id="{{.Anchor}}">{{.Name}}</h2>
+<table>
+{{range .Rows}}<tr id="{{$file.Anchor}}-L{{.Line}}" class="{{if .First}}first{{end}}{{if not .Reviewed}} unreviewed{{end}}">
+<td class="num"><a href="#{{$file.Anchor}}-L{{.Line}}">{{.Line}}</a></td>
+{{if .First}}<td><code title="{{.Author}}">{{.Commit}}</code></td>
+<td>{{if .PRNumber}}{{if .PRURL}}<a href="{{.PRURL}}"{{with .PRTitle}} title="{{.}}"{{end}}>#{{.PRNumber}}</a>{{else}}<span{{with .PRTitle}} title="{{.}}"{{end}}>#{{.PRNumber}}</span>{{end}}{{else}}<span class="muted">no PR/MR</span>{{end}}</td>
+<td>{{range $i, $a := .Approvers}}{{if $i}}, {{end}}{{if $a.AvatarURL}}<img class="avatar" src="{{$a.AvatarURL}}" alt=""> {{end}}{{if $a.URL}}<a href="{{$a.URL}}">{{$a.Name}}</a>{{else}}{{$a.Name}}{{end}}{{else}}<span class="muted">not approved</span>{{end}}</td>
+<td>{{.ApprovalDate}}</td>
+{{else}}<td></td><td></td><td></td><td></td>
+{{end}}<td class="code"><code class="language-{{$file.Language}}">{{.Content}}</code></td>
+</tr>
+{{end}}</table>
+{{end}}
+<script src="https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0/highlight.min.js"></script>
+<script>if (window.hljs) { hljs.highlightAll(); }</script>
+</body>
+</html>
+`))
+
+// formatHTML renders an HTML page with a table per file: line numbers, the
+// commit and author, a link to the PR/MR, the approvers with their avatars
+// and links to their reviews, and the code colored by language. Unreviewed
+// lines are highlighted. PR/MR links need opts.Repo.
+func formatHTML(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+	stats := ComputeReviewStats(lines)
+	report := htmlReport{Stats: stats, Coverage: fmt.Sprintf("%.1f%%", stats.Coverage())}
+	for i, line := range lines {
+		if i == 0 || line.Filename != lines[i-1].Filename {
+			language, ok := htmlLanguages[strings.ToLower(path.Ext(line.Filename))]
+			if !ok {
+				language = "plaintext"
+			}
+			report.Files = append(report.Files, htmlFile{Name: line.Filename, Anchor: htmlAnchor(line.Filename), Language: language})
+		}
+		file := &report.Files[len(report.Files)-1]
+		first := len(file.Rows) == 0 || line.CommitHash != lines[i-1].CommitHash
+		file.Rows = append(file.Rows, newHTMLRow(line, first, opts.Repo))
+	}
+	return htmlTemplate.Execute(w, report)
+}
+
+// htmlAnchor turns a file name into a fragment identifier, e.g. "src-main.go"
+func htmlAnchor(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// newHTMLRow builds the row of a line, linking its PR/MR on repo's host
+func newHTMLRow(line BlameLineWithApproval, first bool, repo *RepoInfo) htmlRow {
+	row := htmlRow{
+		Line:     line.LineNumber,
+		First:    first,
+		Commit:   shortCommit(line.CommitHash),
+		Author:   line.Author,
+		PRNumber: line.PRNumber,
+		PRTitle:  line.PRTitle,
+		Reviewed: line.Approver != "",
+		Content:  line.Content,
+	}
+	if repo != nil {
+		owner, name := lineRepository(repo, line)
+		row.PRURL = repo.PullRequestURL(owner, name, line.PRNumber)
+	}
+	if line.ApprovalTime != nil {
+		row.ApprovalDate = line.ApprovalTime.UTC().Format("2006-01-02")
+	}
+
+	for _, approver := range distinctApprovers(line) {
+		url := approver.URL
+		if url == "" {
+			url = row.PRURL
+		}
+		row.Approvers = append(row.Approvers, htmlApprover{Name: approver.Name, URL: url, AvatarURL: approver.AvatarURL})
+	}
+	return row
+}
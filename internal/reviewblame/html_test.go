@@ -0,0 +1,103 @@
+package reviewblame
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatHTML(t *testing.T) {
+	approvedAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	approvers := []LineApprover{
+		{Name: "alice", Time: &approvedAt, URL: "https://github.com/owner/repo/pull/42#pullrequestreview-1", AvatarURL: "https://avatars.example.com/alice"},
+		{Name: "bob", Time: &approvedAt},
+	}
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine:    BlameLine{CommitHash: "abcdef1234567890", Filename: "src/main.go", LineNumber: 1, Author: "Jane Doe", Content: `if a < b && c > "d" {`},
+			PRNumber:     42,
+			PRTitle:      "Add <greeting>",
+			Approver:     "bob",
+			ApprovalTime: &approvedAt,
+			Approvers:    approvers,
+		},
+		{
+			BlameLine:    BlameLine{CommitHash: "abcdef1234567890", Filename: "src/main.go", LineNumber: 2, Author: "Jane Doe", Content: "}"},
+			PRNumber:     42,
+			Approver:     "bob",
+			ApprovalTime: &approvedAt,
+			Approvers:    approvers,
+		},
+		{
+			BlameLine: BlameLine{CommitHash: "0123456789abcdef", Filename: "README", LineNumber: 1, Author: "John Roe", Content: "<script>alert(1)</script>"},
+			PRNumber:  7,
+		},
+	}
+
+	var out strings.Builder
+	if err := formatHTML(&out, lines, FormatOptions{Repo: &RepoInfo{Owner: "owner", Name: "repo", Type: RepositoryTypeGitHub, Host: "github.com"}}); err != nil {
+		t.Fatalf("formatHTML failed: %v", err)
+	}
+	output := out.String()
+
+	for _, want := range []string{
+		"2 of 3 lines reviewed (66.7%)",
+		`<h2 id="src-main.go">src/main.go</h2>`,
+		`<a href="https://github.com/owner/repo/pull/42" title="Add &lt;greeting&gt;">#42</a>`,
+		`<img class="avatar" src="https://avatars.example.com/alice" alt=""> <a href="https://github.com/owner/repo/pull/42#pullrequestreview-1">alice</a>`,
+		`, <a href="https://github.com/owner/repo/pull/42">bob</a>`,
+		"<td>2024-03-01</td>",
+		`<code class="language-go">if a &lt; b &amp;&amp; c &gt; &#34;d&#34; {</code>`,
+		`<code class="language-plaintext">&lt;script&gt;alert(1)&lt;/script&gt;</code>`,
+		`<a href="https://github.com/owner/repo/pull/7">#7</a>`,
+		`<span class="muted">not approved</span>`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "<code title=") != 2 {
+		t.Errorf("expected commit details once per run of lines, got:\n%s", output)
+	}
+	if !strings.Contains(output, `<tr id="README-L1" class="first unreviewed">`) || strings.Contains(output, `<tr id="src-main.go-L2" class="first`) {
+		t.Errorf("unexpected row classes:\n%s", output)
+	}
+}
+
+func TestFormatHTMLWithoutRepository(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "abc", Filename: "a.py", LineNumber: 1}, PRNumber: 3, Approver: "alice"},
+	}
+	var out strings.Builder
+	if err := formatHTML(&out, lines, FormatOptions{}); err != nil {
+		t.Fatalf("formatHTML failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "<span>#3</span>") || !strings.Contains(out.String(), "<td>alice</td>") {
+		t.Errorf("expected unlinked PR and approver, got:\n%s", out.String())
+	}
+}
+
+func TestWriteOutputToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	html, err := DefaultFormatters.Lookup("html")
+	if err != nil {
+		t.Fatalf("html format not registered: %v", err)
+	}
+	lines := []BlameLineWithApproval{{BlameLine: BlameLine{Filename: "a.go", LineNumber: 1, Content: "package a"}}}
+	err = writeOutput(path, func(w io.Writer) error {
+		return WriteFormatted(w, html, lines, FormatOptions{})
+	})
+	if err != nil {
+		t.Fatalf("writeOutput failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<!DOCTYPE html>") || !strings.Contains(string(data), "package a") {
+		t.Errorf("unexpected report:\n%s", data)
+	}
+}
@@ -0,0 +1,150 @@
+package reviewblame
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// httpCacheDir holds cached API responses for conditional requests; empty
+// disables the cache. main sets it, so tests never touch the disk cache.
+var httpCacheDir string
+
+// enableHTTPCache caches API responses under the user cache directory,
+// unless GIT_REVIEW_BLAME_NO_HTTP_CACHE is set
+func enableHTTPCache() {
+	if os.Getenv("GIT_REVIEW_BLAME_NO_HTTP_CACHE") != "" {
+		return
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return
+	}
+	httpCacheDir = filepath.Join(dir, "git-review-blame", "http")
+}
+
+// cachedResponse is a response stored with the validators to revalidate it
+type cachedResponse struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// cachingTransport revalidates GET requests against the responses of
+// earlier runs: it sends the stored ETag as If-None-Match and Last-Modified
+// as If-Modified-Since, and answers a 304 Not Modified with the stored
+// response. GitHub does not count 304s against the rate limit.
+type cachingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dir := httpCacheDir
+	if dir == "" || req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	path := filepath.Join(dir, httpCacheKey(req))
+	cached := readCachedResponse(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		debugf("%s %s served from the HTTP cache", req.Method, req.URL.Redacted())
+		// Keep the fresh rate limit headers of the 304
+		header := cached.Header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header = header
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+	case resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""):
+		body, err := io.ReadAll(newResponseReader(resp.Body))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		writeCachedResponse(path, &cachedResponse{Header: resp.Header, Body: body})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
+
+// httpCacheKey names the cache file of a request. The credentials and
+// Accept header are part of the key, since responses vary by them; the
+// key is a hash, so tokens are never written to disk.
+func httpCacheKey(req *http.Request) string {
+	hash := sha256.New()
+	for _, part := range []string{
+		req.URL.String(),
+		req.Header.Get("Accept"),
+		req.Header.Get("Authorization"),
+		req.Header.Get("PRIVATE-TOKEN"),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// readCachedResponse returns the response stored at path, or nil when there
+// is none or it cannot be read
+func readCachedResponse(path string) *cachedResponse {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+// writeCachedResponse stores a response at path. The cache is best effort:
+// failures only cost a full request on the next run.
+func writeCachedResponse(path string, cached *cachedResponse) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		debugf("cannot create the HTTP cache: %v", err)
+		return
+	}
+	// Write atomically so concurrent runs never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,73 @@
+package reviewblame
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachingTransport(t *testing.T) {
+	httpCacheDir = t.TempDir()
+	defer func() { httpCacheDir = "" }()
+
+	var full, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Remaining", "5000")
+		io.WriteString(w, `{"number": 1}`)
+	}))
+	defer server.Close()
+
+	client := newHTTPClient(0)
+	get := func(token string) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+"/repos/owner/repo/pulls/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		remaining   string
+		full        int
+		notModified int
+	}{
+		{"first request", "a", "5000", 1, 0},
+		{"revalidated", "a", "4999", 1, 1},
+		{"other token", "b", "5000", 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(tt.token)
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK || string(body) != `{"number": 1}` {
+				t.Errorf("expected the cached response, got %d %q", resp.StatusCode, body)
+			}
+			if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != tt.remaining {
+				t.Errorf("expected rate limit header %q, got %q", tt.remaining, remaining)
+			}
+			if full != tt.full || notModified != tt.notModified {
+				t.Errorf("expected %d full and %d conditional responses, got %d and %d", tt.full, tt.notModified, full, notModified)
+			}
+		})
+	}
+}
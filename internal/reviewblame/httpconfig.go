@@ -0,0 +1,130 @@
+package reviewblame
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HTTPConfig configures the connections to API hosts
+type HTTPConfig struct {
+	// Timeout limits each request, e.g. "60s" (default 30s)
+	Timeout string `json:"timeout"`
+	// Proxy is the URL of the proxy for all requests, overriding the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables
+	Proxy string `json:"proxy"`
+	// CACerts are PEM files of certificate authorities trusted in addition
+	// to the system's, e.g. an internal CA; paths are relative to the
+	// config file
+	CACerts []string `json:"ca_certs"`
+	// Proxy, CACerts and InsecureSkipVerify are only read from flags and
+	// the user config file, never from a repository's config file
+	// InsecureSkipVerify accepts any server certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+// validate checks the timeout and proxy URL
+func (h *HTTPConfig) validate() error {
+	if h.Timeout != "" {
+		if timeout, err := time.ParseDuration(h.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("http: invalid timeout %q", h.Timeout)
+		}
+	}
+	if h.Proxy != "" {
+		if proxy, err := url.Parse(h.Proxy); err != nil || proxy.Host == "" {
+			return fmt.Errorf("http: invalid proxy URL %q", h.Proxy)
+		}
+	}
+	return nil
+}
+
+// httpSettings holds the -timeout, -proxy, -ca-cert and
+// -insecure-skip-verify flags; they win over the config files
+var httpSettings HTTPConfig
+
+// httpTimeout overrides the request timeout of newHTTPClient when set
+var httpTimeout time.Duration
+
+// httpTransport sends all requests of newHTTPClient
+var httpTransport http.RoundTripper = http.DefaultTransport
+
+// configureHTTP applies the HTTP settings of the flags and of the config
+// file to the clients created afterwards
+func configureHTTP(fromFile *HTTPConfig) error {
+	settings := httpSettings
+	if fromFile != nil {
+		if settings.Timeout == "" {
+			settings.Timeout = fromFile.Timeout
+		}
+		if settings.Proxy == "" {
+			settings.Proxy = fromFile.Proxy
+		}
+		settings.CACerts = append(settings.CACerts, fromFile.CACerts...)
+		settings.InsecureSkipVerify = settings.InsecureSkipVerify || fromFile.InsecureSkipVerify
+	}
+	if err := settings.validate(); err != nil {
+		return err
+	}
+
+	if settings.Timeout != "" {
+		httpTimeout, _ = time.ParseDuration(settings.Timeout)
+	}
+	transport, err := newHTTPTransport(settings)
+	if err != nil {
+		return err
+	}
+	httpTransport = transport
+	return nil
+}
+
+// newHTTPTransport returns a transport using the proxy and certificates of
+// settings, or the default transport when they need none
+func newHTTPTransport(settings HTTPConfig) (http.RoundTripper, error) {
+	if settings.Proxy == "" && len(settings.CACerts) == 0 && !settings.InsecureSkipVerify {
+		return http.DefaultTransport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if settings.Proxy != "" {
+		proxy, err := url.Parse(settings.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", settings.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: settings.InsecureSkipVerify}
+	if len(settings.CACerts) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range settings.CACerts {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("could not read CA certificates: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no PEM certificates in %s", file)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// resolveCACerts makes the CA certificate paths of a config file relative
+// to the directory holding it
+func (h *HTTPConfig) resolveCACerts(configPath string) {
+	for i, file := range h.CACerts {
+		if !filepath.IsAbs(file) {
+			h.CACerts[i] = filepath.Join(filepath.Dir(configPath), file)
+		}
+	}
+}
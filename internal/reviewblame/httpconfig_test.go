@@ -0,0 +1,90 @@
+package reviewblame
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// resetHTTPSettings restores the default HTTP settings after a test
+func resetHTTPSettings(t *testing.T) {
+	t.Cleanup(func() {
+		httpSettings, httpTimeout, httpTransport = HTTPConfig{}, 0, http.DefaultTransport
+	})
+}
+
+func TestConfigureHTTPCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certificate, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config *HTTPConfig
+		ok     bool
+	}{
+		{"system certificates", nil, false},
+		{"custom CA", &HTTPConfig{CACerts: []string{caFile}}, true},
+		{"insecure", &HTTPConfig{InsecureSkipVerify: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetHTTPSettings(t)
+			if err := configureHTTP(tt.config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := newHTTPClient(time.Second).Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err == nil) != tt.ok {
+				t.Errorf("expected success %v, got %v", tt.ok, err)
+			}
+		})
+	}
+
+	resetHTTPSettings(t)
+	if err := configureHTTP(&HTTPConfig{CACerts: []string{filepath.Join(t.TempDir(), "missing.pem")}}); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
+
+func TestConfigureHTTPProxyAndTimeout(t *testing.T) {
+	resetHTTPSettings(t)
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	httpSettings.Timeout = "2m"
+	if err := configureHTTP(&HTTPConfig{Timeout: "10s", Proxy: proxy.URL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := newHTTPClient(30 * time.Second)
+	if client.Timeout != 2*time.Minute {
+		t.Errorf("expected the -timeout flag to win, got %s", client.Timeout)
+	}
+	resp, err := client.Get("http://api.example.invalid/user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://api.example.invalid/user" {
+		t.Errorf("expected the request to go through the proxy, got %q", proxied)
+	}
+
+	for _, config := range []*HTTPConfig{{Timeout: "soon"}, {Proxy: "not a url"}} {
+		if err := config.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", config)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// IdentityConfig folds several logins and emails into one canonical person,
+// e.g. contractor accounts, renamed users or service accounts acting for a human
+type IdentityConfig struct {
+	// Name is the canonical name or login shown in output
+	Name string `json:"name"`
+	// Email is the canonical email; when empty, emails are left unchanged
+	Email string `json:"email"`
+	// Aliases are the logins, author names and emails of this person
+	Aliases []string `json:"aliases"`
+}
+
+// validate checks that the identity has a canonical name
+func (i IdentityConfig) validate() error {
+	if i.Name == "" {
+		return fmt.Errorf("identity with aliases %v is missing \"name\"", i.Aliases)
+	}
+	return nil
+}
+
+// IdentityMap resolves logins, names and emails to canonical identities.
+// Matching is case-insensitive.
+type IdentityMap struct {
+	byAlias map[string]*IdentityConfig
+}
+
+// NewIdentityMap indexes identities by their name, email and aliases. An
+// alias claimed by two identities is an error.
+func NewIdentityMap(identities []IdentityConfig) (*IdentityMap, error) {
+	m := &IdentityMap{byAlias: make(map[string]*IdentityConfig)}
+	for i := range identities {
+		identity := &identities[i]
+		if err := identity.validate(); err != nil {
+			return nil, err
+		}
+
+		keys := append([]string{identity.Name, identity.Email}, identity.Aliases...)
+		for _, key := range keys {
+			key = strings.ToLower(strings.TrimSpace(key))
+			if key == "" {
+				continue
+			}
+			if other, exists := m.byAlias[key]; exists && other != identity {
+				return nil, fmt.Errorf("alias %q is used by both identity %q and %q", key, other.Name, identity.Name)
+			}
+			m.byAlias[key] = identity
+		}
+	}
+	return m, nil
+}
+
+// Resolve returns the canonical name and email of a person known by name
+// and email. The email is tried first since it is the more specific key;
+// unknown people are returned unchanged.
+func (m *IdentityMap) Resolve(name, email string) (string, string) {
+	identity := m.byAlias[strings.ToLower(email)]
+	if identity == nil {
+		identity = m.byAlias[strings.ToLower(name)]
+	}
+	if identity == nil {
+		return name, email
+	}
+	if identity.Email != "" {
+		email = identity.Email
+	}
+	return identity.Name, email
+}
+
+// IdentityEnricher replaces commit authors, approvers and PR/MR authors with
+// their canonical identities. It runs last so that stats, policies and
+// output all see the canonical identities.
+type IdentityEnricher struct {
+	identities *IdentityMap
+}
+
+// NewIdentityEnricher creates the identity mapping stage
+func NewIdentityEnricher(identities []IdentityConfig) (*IdentityEnricher, error) {
+	m, err := NewIdentityMap(identities)
+	if err != nil {
+		return nil, err
+	}
+	return &IdentityEnricher{identities: m}, nil
+}
+
+// Name implements Enricher
+func (e *IdentityEnricher) Name() string {
+	return "identities"
+}
+
+// Enrich implements Enricher
+func (e *IdentityEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		line := &lines[i]
+		line.Author, line.AuthorEmail = e.identities.Resolve(line.Author, line.AuthorEmail)
+		if line.Approver != "" {
+			line.Approver, line.ApproverEmail = e.identities.Resolve(line.Approver, line.ApproverEmail)
+		}
+		if line.PRAuthor != "" {
+			line.PRAuthor, _ = e.identities.Resolve(line.PRAuthor, "")
+		}
+		if len(line.Approvers) > 0 {
+			// Approver lists are shared between lines, so copy before changing
+			approvers := make([]LineApprover, len(line.Approvers))
+			for j, approver := range line.Approvers {
+				approver.Name, approver.Email = e.identities.Resolve(approver.Name, approver.Email)
+				approvers[j] = approver
+			}
+			line.Approvers = approvers
+		}
+		if len(line.Objections) > 0 {
+			// Objection lists are shared between lines, so copy before changing
+			objections := make([]Objection, len(line.Objections))
+			for j, objection := range line.Objections {
+				objection.Name, _ = e.identities.Resolve(objection.Name, "")
+				objections[j] = objection
+			}
+			line.Objections = objections
+		}
+		if line.Backport != nil && line.Backport.Approver != "" {
+			// Backport origins may be shared between lines, so copy before changing
+			origin := *line.Backport
+			origin.Approver, origin.ApproverEmail = e.identities.Resolve(origin.Approver, origin.ApproverEmail)
+			line.Backport = &origin
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package reviewblame
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIdentityMapResolve(t *testing.T) {
+	identities, err := NewIdentityMap([]IdentityConfig{
+		{Name: "alice", Email: "alice@example.com", Aliases: []string{"alice-contractor", "Alice Smith", "asmith@old-company.com"}},
+		{Name: "bob", Aliases: []string{"deploy-bot"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name, email         string
+		wantName, wantEmail string
+	}{
+		{"alice-contractor", "", "alice", "alice@example.com"},
+		{"Alice Smith", "asmith@gmail.com", "alice", "alice@example.com"},
+		{"Someone", "ASmith@old-company.com", "alice", "alice@example.com"},
+		{"alice", "alice@example.com", "alice", "alice@example.com"},
+		// Identities without a canonical email keep the original one
+		{"deploy-bot", "bot@example.com", "bob", "bot@example.com"},
+		{"carol", "carol@example.com", "carol", "carol@example.com"},
+		{"", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		name, email := identities.Resolve(tt.name, tt.email)
+		if name != tt.wantName || email != tt.wantEmail {
+			t.Errorf("Resolve(%q, %q) = %q, %q, want %q, %q", tt.name, tt.email, name, email, tt.wantName, tt.wantEmail)
+		}
+	}
+}
+
+func TestNewIdentityMapErrors(t *testing.T) {
+	if _, err := NewIdentityMap([]IdentityConfig{{Aliases: []string{"x"}}}); err == nil {
+		t.Error("expected an error for an identity without name")
+	}
+	if _, err := NewIdentityMap([]IdentityConfig{
+		{Name: "alice", Aliases: []string{"shared"}},
+		{Name: "bob", Aliases: []string{"Shared"}},
+	}); err == nil {
+		t.Error("expected an error for an alias used by two identities")
+	}
+}
+
+func TestIdentityEnricher(t *testing.T) {
+	enricher, err := NewIdentityEnricher([]IdentityConfig{
+		{Name: "alice", Email: "alice@example.com", Aliases: []string{"alice-contractor", "alice.old@example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	origin := &BackportOrigin{PRNumber: 3, Approver: "alice-contractor"}
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{Author: "Alice", AuthorEmail: "alice.old@example.com"}, Approver: "alice-contractor", PRAuthor: "alice-contractor", Backport: origin},
+		{BlameLine: BlameLine{Author: "Bob", AuthorEmail: "bob@example.com"}},
+	}
+	if err := enricher.Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if lines[0].Author != "alice" || lines[0].AuthorEmail != "alice@example.com" {
+		t.Errorf("author not folded: %q <%s>", lines[0].Author, lines[0].AuthorEmail)
+	}
+	if lines[0].Approver != "alice" || lines[0].ApproverEmail != "alice@example.com" || lines[0].PRAuthor != "alice" {
+		t.Errorf("approver or PR author not folded: %+v", lines[0])
+	}
+	if lines[0].Backport.Approver != "alice" || origin.Approver != "alice-contractor" {
+		t.Errorf("backport approver not folded on a copy: %+v, original %+v", lines[0].Backport, origin)
+	}
+	if lines[1].Author != "Bob" || lines[1].Approver != "" {
+		t.Errorf("unknown identity changed: %+v", lines[1])
+	}
+}
@@ -0,0 +1,101 @@
+package reviewblame
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// formatIncremental writes the format of git blame --incremental, which tig
+// blame and git gui blame read, with the approver in the author fields and
+// the commit author in the committer fields. Consecutive lines of a commit
+// form one entry; the commit header is only written for the first entry of
+// each commit, as git does, and summary is the PR/MR title when known.
+func formatIncremental(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+	out := bufio.NewWriter(w)
+	seen := make(map[string]bool)
+	var buf []byte
+	field := func(key, value string) {
+		buf = append(buf, key...)
+		buf = append(buf, ' ')
+		buf = append(buf, value...)
+		buf = append(buf, '\n')
+	}
+
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for end < len(lines) && continuesEntry(lines[end-1], lines[end]) {
+			end++
+		}
+		line := lines[start]
+
+		buf = buf[:0]
+		buf = append(buf, line.CommitHash...)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(line.OriginalLineNumber), 10)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(line.LineNumber), 10)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(end-start), 10)
+		buf = append(buf, '\n')
+
+		if !seen[line.CommitHash] {
+			seen[line.CommitHash] = true
+
+			authorTimezone := line.AuthorTimezone
+			if authorTimezone == "" {
+				authorTimezone = "+0000"
+			}
+			if line.Approver != "" {
+				field("author", line.Approver)
+				field("author-mail", "<"+line.ApproverEmail+">")
+				if line.ApprovalTime != nil {
+					field("author-time", strconv.FormatInt(line.ApprovalTime.Unix(), 10))
+					field("author-tz", line.ApprovalTime.Format("-0700"))
+				} else {
+					field("author-time", line.Date)
+					field("author-tz", authorTimezone)
+				}
+			} else {
+				field("author", line.Author)
+				field("author-mail", "<"+line.AuthorEmail+">")
+				field("author-time", line.Date)
+				field("author-tz", authorTimezone)
+			}
+			field("committer", line.Author)
+			field("committer-mail", "<"+line.AuthorEmail+">")
+			field("committer-time", line.Date)
+			field("committer-tz", authorTimezone)
+
+			summary := line.Summary
+			if line.PRTitle != "" {
+				summary = line.PRTitle
+			}
+			field("summary", strings.ReplaceAll(summary, "\n", " "))
+		}
+		// Like git, name the file in the commit that introduced the lines,
+		// which tig opens when blaming the parent commit
+		filename := line.Filename
+		if line.OriginalFilename != "" {
+			filename = line.OriginalFilename
+		}
+		field("filename", filename)
+
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+		start = end
+	}
+	return out.Flush()
+}
+
+// continuesEntry reports whether next directly follows prev in both the
+// final file and the commit that introduced them
+func continuesEntry(prev, next BlameLineWithApproval) bool {
+	return next.CommitHash == prev.CommitHash &&
+		next.Filename == prev.Filename &&
+		next.OriginalFilename == prev.OriginalFilename &&
+		next.LineNumber == prev.LineNumber+1 &&
+		next.OriginalLineNumber == prev.OriginalLineNumber+1
+}
@@ -0,0 +1,95 @@
+package reviewblame
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatIncremental(t *testing.T) {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.FixedZone("", 2*60*60))
+	reviewed := BlameLine{
+		CommitHash: "a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0", Filename: "main.go",
+		Author: "John Doe", AuthorEmail: "john@example.com", Date: "1609459200", AuthorTimezone: "-0500",
+		Summary: "Add main (#42)",
+	}
+	unreviewed := BlameLine{
+		CommitHash: "b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1", Filename: "main.go",
+		Author: "Jane Smith", AuthorEmail: "jane@example.com", Date: "1609545600", AuthorTimezone: "+0100",
+		Summary: "Fix typo",
+	}
+	line := func(blame BlameLine, original, final int) BlameLineWithApproval {
+		blame.OriginalLineNumber, blame.LineNumber = original, final
+		result := BlameLineWithApproval{BlameLine: blame}
+		if blame.CommitHash == reviewed.CommitHash {
+			result.PRNumber, result.PRTitle = 42, "Add main"
+			result.Approver, result.ApproverEmail, result.ApprovalTime = "alice", "alice@example.com", &approvalTime
+		}
+		return result
+	}
+	lines := []BlameLineWithApproval{
+		line(reviewed, 1, 1),
+		line(reviewed, 2, 2),
+		line(unreviewed, 3, 3),
+		line(reviewed, 3, 4),
+	}
+
+	want := `a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 1 1 2
+author alice
+author-mail <alice@example.com>
+author-time 1714636800
+author-tz +0200
+committer John Doe
+committer-mail <john@example.com>
+committer-time 1609459200
+committer-tz -0500
+summary Add main
+filename main.go
+b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1 3 3 1
+author Jane Smith
+author-mail <jane@example.com>
+author-time 1609545600
+author-tz +0100
+committer Jane Smith
+committer-mail <jane@example.com>
+committer-time 1609545600
+committer-tz +0100
+summary Fix typo
+filename main.go
+a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0 3 4 1
+filename main.go
+`
+	var output bytes.Buffer
+	if err := formatIncremental(&output, lines, FormatOptions{}); err != nil {
+		t.Fatalf("formatIncremental failed: %v", err)
+	}
+	if output.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", output.String(), want)
+	}
+}
+
+func TestParseBlameArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		revision string
+		paths    string
+		wantErr  bool
+	}{
+		{args: []string{"cli.go"}, paths: "cli.go"},
+		{args: []string{"--", "cli.go"}, paths: "cli.go"},
+		{args: []string{"HEAD~1", "cli.go"}, revision: "HEAD~1", paths: "cli.go"},
+		{args: []string{"a1b2c3d4", "--", "cli.go"}, revision: "a1b2c3d4", paths: "cli.go"},
+		{args: []string{"cli.go", "git.go"}, paths: "cli.go git.go"},
+		{args: []string{"HEAD", "cli.go", "git.go"}, revision: "HEAD", paths: "cli.go git.go"},
+		{args: []string{"HEAD", "--", "cli.go", "missing.go"}, revision: "HEAD", paths: "cli.go missing.go"},
+		{args: []string{"HEAD", "--"}, wantErr: true},
+		{args: []string{"a", "b", "--", "cli.go"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		revision, paths, err := parseBlameArgs(tt.args)
+		if (err != nil) != tt.wantErr || revision != tt.revision || strings.Join(paths, " ") != tt.paths {
+			t.Errorf("parseBlameArgs(%s) = %q, %q, %v", strings.Join(tt.args, " "), revision, paths, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package reviewblame
+
+import (
+	"regexp"
+	"strings"
+)
+
+// issueReference matches a single issue reference: "#12", "owner/repo#12",
+// "group/sub/project#12" or an issue URL on GitHub or GitLab
+const issueReference = `(?:[\w.\-]+(?:/[\w.\-]+)+)?#\d+|https?://\S+?/issues/\d+`
+
+// closingKeywordPattern matches GitHub and GitLab closing keywords followed
+// by one issue reference, or a GitLab-style list ("Closes #1, #2 and #3")
+var closingKeywordPattern = regexp.MustCompile(
+	`(?i)\b(?:close[sd]?|closing|fix(?:e[sd])?|fixing|resolve[sd]?|resolving|implement(?:s|ed|ing)?)\b:?\s+` +
+		`((?:` + issueReference + `)(?:\s*(?:,|\band\b)\s*(?:` + issueReference + `))*)`)
+
+var (
+	issueReferencePattern = regexp.MustCompile(issueReference)
+	// issueURLPattern extracts the project path and number from an issue URL
+	issueURLPattern = regexp.MustCompile(`^https?://[^/]+/(.+?)(?:/-)?/issues/(\d+)$`)
+)
+
+// ExtractLinkedIssues returns the issues a PR/MR description closes, as
+// "#N" for the same repository or "owner/repo#N" otherwise, without
+// duplicates and in order of appearance
+func ExtractLinkedIssues(description string) []string {
+	var issues []string
+	seen := make(map[string]bool)
+
+	for _, match := range closingKeywordPattern.FindAllStringSubmatch(description, -1) {
+		for _, reference := range issueReferencePattern.FindAllString(match[1], -1) {
+			if url := issueURLPattern.FindStringSubmatch(reference); url != nil {
+				reference = url[1] + "#" + url[2]
+			}
+			if !seen[reference] {
+				seen[reference] = true
+				issues = append(issues, reference)
+			}
+		}
+	}
+
+	return issues
+}
+
+// formatIssues joins the tracker key and linked issues of a line for display
+func formatIssues(line BlameLineWithApproval) string {
+	issues := line.LinkedIssues
+	if line.TrackerKey != "" {
+		issues = append([]string{line.TrackerKey}, issues...)
+	}
+	return strings.Join(issues, ",")
+}
@@ -0,0 +1,58 @@
+package reviewblame
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractLinkedIssues(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		expected    []string
+	}{
+		{"github fixes", "This change Fixes #12 and improves logging", []string{"#12"}},
+		{"multiple keywords", "closes #1\nresolves: #2\nfixed owner/repo#3", []string{"#1", "#2", "owner/repo#3"}},
+		{"gitlab list", "Closes #4, #5 and group/sub/project#6", []string{"#4", "#5", "group/sub/project#6"}},
+		{"gitlab implements", "Implements #7", []string{"#7"}},
+		{"issue urls", "Fixes https://github.com/owner/repo/issues/8 and closes https://gitlab.com/group/project/-/issues/9",
+			[]string{"owner/repo#8", "group/project#9"}},
+		{"duplicates", "Fixes #10. Also closes #10", []string{"#10"}},
+		{"mention without keyword", "Related to #11, see #12", nil},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractLinkedIssues(tt.description); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatHumanShowIssues(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaaaaaaaa", Author: "John", Date: "1609459200", LineNumber: 1, Content: "a"}, LinkedIssues: []string{"#12", "#13"}},
+		{BlameLine: BlameLine{CommitHash: "bbbbbbbbbb", Author: "John", Date: "1609459200", LineNumber: 2, Content: "b"}},
+	}
+
+	formatter, err := NewFormatterRegistry().Lookup("human")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := formatter.Format(lines, FormatOptions{ShowIssues: true})
+	outputLines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if !strings.Contains(outputLines[0], " #12,#13 1) a") {
+		t.Errorf("expected issues column, got %q", outputLines[0])
+	}
+	if !strings.Contains(outputLines[1], "        2) b") {
+		t.Errorf("expected padded empty issues column, got %q", outputLines[1])
+	}
+
+	if output := formatter.Format(lines, FormatOptions{}); strings.Contains(output, "#12") {
+		t.Errorf("expected no issues without ShowIssues, got %q", output)
+	}
+}
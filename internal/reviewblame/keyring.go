@@ -0,0 +1,39 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keyringService is the service name tokens are stored under in the OS
+// credential store, with the host as the account
+const keyringService = "git-review-blame"
+
+// ErrTokenNotStored is returned by Keyring.Get when the host has no token
+var ErrTokenNotStored = errors.New("no token stored")
+
+// Keyring stores API tokens by host in the OS credential store: the macOS
+// keychain, the Secret Service (GNOME Keyring, KWallet) through libsecret,
+// or the Windows Credential Manager
+type Keyring interface {
+	Get(ctx context.Context, host string) (string, error)
+	Set(ctx context.Context, host, token string) error
+	Delete(ctx context.Context, host string) error
+}
+
+// systemKeyring is the credential store of the OS; tests replace it
+var systemKeyring Keyring = newSystemKeyring()
+
+// validateToken rejects empty tokens and tokens with whitespace or quotes,
+// which are pasting mistakes rather than token characters
+func validateToken(token string) error {
+	if token == "" {
+		return fmt.Errorf("no token given")
+	}
+	if strings.ContainsAny(token, " \t\r\n\"'\\") {
+		return fmt.Errorf("the token contains whitespace or quotes")
+	}
+	return nil
+}
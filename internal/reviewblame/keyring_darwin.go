@@ -0,0 +1,61 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// macKeychain stores tokens as generic passwords in the login keychain
+// with the security tool
+type macKeychain struct{}
+
+// newSystemKeyring returns the macOS keychain
+func newSystemKeyring() Keyring {
+	return macKeychain{}
+}
+
+// securityItemNotFound is the exit status of security when no item matches
+const securityItemNotFound = 44
+
+// Get implements Keyring
+func (macKeychain) Get(ctx context.Context, host string) (string, error) {
+	output, err := runTokenCommand(ctx, "", "", "security", "find-generic-password", "-s", keyringService, "-a", host, "-w")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return "", ErrTokenNotStored
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read the keychain: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// Set implements Keyring. The command is passed on standard input to
+// security's interactive mode so the token does not show up in the
+// process list.
+func (macKeychain) Set(ctx context.Context, host, token string) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+	command := fmt.Sprintf("add-generic-password -U -s %q -a %q -w %q\n", keyringService, host, token)
+	if _, err := runTokenCommand(ctx, "", command, "security", "-i"); err != nil {
+		return fmt.Errorf("could not write to the keychain: %w", err)
+	}
+	return nil
+}
+
+// Delete implements Keyring
+func (macKeychain) Delete(ctx context.Context, host string) error {
+	_, err := runTokenCommand(ctx, "", "", "security", "delete-generic-password", "-s", keyringService, "-a", host)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return ErrTokenNotStored
+	}
+	if err != nil {
+		return fmt.Errorf("could not write to the keychain: %w", err)
+	}
+	return nil
+}
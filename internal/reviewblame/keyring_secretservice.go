@@ -0,0 +1,57 @@
+//go:build !darwin && !windows
+
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// secretServiceKeyring stores tokens in the Secret Service (GNOME Keyring,
+// KWallet) with libsecret's secret-tool
+type secretServiceKeyring struct{}
+
+// newSystemKeyring returns the Secret Service keyring
+func newSystemKeyring() Keyring {
+	return secretServiceKeyring{}
+}
+
+// Get implements Keyring
+func (secretServiceKeyring) Get(ctx context.Context, host string) (string, error) {
+	output, err := runTokenCommand(ctx, "", "", "secret-tool", "lookup", "service", keyringService, "host", host)
+	var exitErr *exec.ExitError
+	// secret-tool exits with status 1 and prints nothing when no item matches
+	if output == "" && (err == nil || errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return "", ErrTokenNotStored
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read the keyring (is secret-tool from libsecret installed?): %w", err)
+	}
+	return output, nil
+}
+
+// Set implements Keyring; secret-tool reads the token from standard input
+func (secretServiceKeyring) Set(ctx context.Context, host, token string) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+	_, err := runTokenCommand(ctx, "", token, "secret-tool", "store", "--label", "git-review-blame token for "+host, "service", keyringService, "host", host)
+	if err != nil {
+		return fmt.Errorf("could not write to the keyring (is secret-tool from libsecret installed?): %w", err)
+	}
+	return nil
+}
+
+// Delete implements Keyring
+func (k secretServiceKeyring) Delete(ctx context.Context, host string) error {
+	// secret-tool clear succeeds whether or not an item matched
+	if _, err := k.Get(ctx, host); err != nil {
+		return err
+	}
+	if _, err := runTokenCommand(ctx, "", "", "secret-tool", "clear", "service", keyringService, "host", host); err != nil {
+		return fmt.Errorf("could not write to the keyring: %w", err)
+	}
+	return nil
+}
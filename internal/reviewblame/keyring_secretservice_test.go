@@ -0,0 +1,57 @@
+//go:build !darwin && !windows
+
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestSecretServiceKeyring(t *testing.T) {
+	ctx := context.Background()
+	var stdins []string
+	stored := ""
+	original := runTokenCommand
+	runTokenCommand = func(ctx context.Context, dir, stdin, name string, args ...string) (string, error) {
+		if name != "secret-tool" || args[len(args)-4] != "service" || args[len(args)-3] != keyringService || args[len(args)-1] != "gitlab.example.com" {
+			t.Errorf("unexpected command %s %v", name, args)
+		}
+		stdins = append(stdins, stdin)
+		switch args[0] {
+		case "store":
+			stored = stdin
+		case "lookup":
+			if stored == "" {
+				// secret-tool's status when no item matches
+				return "", exec.Command("false").Run()
+			}
+			return stored, nil
+		case "clear":
+			stored = ""
+		}
+		return "", nil
+	}
+	defer func() { runTokenCommand = original }()
+
+	keyring := secretServiceKeyring{}
+	if _, err := keyring.Get(ctx, "gitlab.example.com"); !errors.Is(err, ErrTokenNotStored) {
+		t.Errorf("expected ErrTokenNotStored, got %v", err)
+	}
+	if err := keyring.Set(ctx, "gitlab.example.com", "glpat-secret"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if stdins[1] != "glpat-secret" {
+		t.Errorf("expected the token on standard input, got %q", stdins[1])
+	}
+	if token, err := keyring.Get(ctx, "gitlab.example.com"); err != nil || token != "glpat-secret" {
+		t.Errorf("Get() = %q, %v", token, err)
+	}
+	if err := keyring.Delete(ctx, "gitlab.example.com"); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if err := keyring.Delete(ctx, "gitlab.example.com"); !errors.Is(err, ErrTokenNotStored) {
+		t.Errorf("expected ErrTokenNotStored deleting twice, got %v", err)
+	}
+}
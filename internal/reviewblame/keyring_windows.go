@@ -0,0 +1,115 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Credential Manager functions and constants of advapi32.dll
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// winCredential is the CREDENTIALW structure
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// windowsCredentialManager stores tokens as generic credentials named
+// "git-review-blame:<host>" in the Windows Credential Manager
+type windowsCredentialManager struct{}
+
+// newSystemKeyring returns the Windows Credential Manager
+func newSystemKeyring() Keyring {
+	return windowsCredentialManager{}
+}
+
+// credentialTarget returns the target name of a host's credential
+func credentialTarget(host string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(keyringService + ":" + host)
+}
+
+// Get implements Keyring
+func (windowsCredentialManager) Get(ctx context.Context, host string) (string, error) {
+	target, err := credentialTarget(host)
+	if err != nil {
+		return "", err
+	}
+	var credential *winCredential
+	ok, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&credential)))
+	if ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", ErrTokenNotStored
+		}
+		return "", fmt.Errorf("could not read the Credential Manager: %w", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(credential)))
+	return string(unsafe.Slice(credential.CredentialBlob, credential.CredentialBlobSize)), nil
+}
+
+// Set implements Keyring
+func (windowsCredentialManager) Set(ctx context.Context, host, token string) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+	target, err := credentialTarget(host)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(host)
+	if err != nil {
+		return err
+	}
+	blob := []byte(token)
+	credential := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if ok, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&credential)), 0); ok == 0 {
+		return fmt.Errorf("could not write to the Credential Manager: %w", err)
+	}
+	return nil
+}
+
+// Delete implements Keyring
+func (windowsCredentialManager) Delete(ctx context.Context, host string) error {
+	target, err := credentialTarget(host)
+	if err != nil {
+		return err
+	}
+	if ok, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return ErrTokenNotStored
+		}
+		return fmt.Errorf("could not write to the Credential Manager: %w", err)
+	}
+	return nil
+}
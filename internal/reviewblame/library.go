@@ -0,0 +1,53 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Annotate blames paths and looks up the PR/MR and approvers of every line,
+// as the blame command does for opts, without printing the lines. The paths
+// must be in one repository; directories annotate every tracked file under
+// them. The API token is opts.Token, else GITHUB_TOKEN or GITLAB_TOKEN,
+// else discovered by TokenResolver. configure, when not nil, may change the
+// stages of the pipeline before it runs.
+func Annotate(ctx context.Context, paths []string, opts Options, configure func(*EnrichmentPipeline) error) ([]BlameLineWithApproval, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths to annotate")
+	}
+	repoRoot, repoInfo, config, err := OpenRepository(paths[0], opts.ConfigPath, opts.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProvider(repoInfo, opts.Provider); err != nil {
+		return nil, err
+	}
+	githubToken, gitlabToken := resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{Explicit: opts.Token, Source: opts.TokenSource}, os.Getenv("GITHUB_TOKEN"), os.Getenv("GITLAB_TOKEN"))
+
+	blame := opts.blameOptions()
+	if len(blame.LineRanges) > 0 && (len(paths) > 1 || len(opts.Globs) > 0 || isDirectoryPath(repoRoot, paths[0], opts.Revision)) {
+		return nil, fmt.Errorf("line ranges annotate a single file and cannot be combined with directories, several paths or globs")
+	}
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, opts, githubToken, gitlabToken)
+	if err != nil {
+		return nil, err
+	}
+	if configure != nil {
+		if err := configure(pipeline); err != nil {
+			return nil, err
+		}
+	}
+	lines, err := annotatePaths(ctx, repoRoot, paths, opts.Revision, opts.Globs, blame, pipeline, opts.IncludeVendored)
+	if err != nil {
+		return nil, err
+	}
+	return opts.Filter.Apply(lines), nil
+}
+
+// NewReviewClient creates the API client of the review system of repoInfo,
+// the repository at repoRoot. The tokens authenticate GitHub and GitLab;
+// the other systems read theirs from the environment.
+func NewReviewClient(repoRoot string, repoInfo *RepoInfo, githubToken, gitlabToken string) (ReviewClient, error) {
+	return createReviewClient(repoRoot, repoInfo, githubToken, gitlabToken)
+}
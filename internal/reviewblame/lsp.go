@@ -0,0 +1,370 @@
+package reviewblame
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// JSON-RPC error codes the language server answers with
+const (
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+// lspRequest is a JSON-RPC request, or a notification when it has no ID
+type lspRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// lspResponse is a JSON-RPC response; Result is "null" for empty results
+type lspResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+// lspError is the error of a JSON-RPC response
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// lspPosition is a zero-based line and UTF-16 character offset
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspRange is a range of a text document, end exclusive
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+// lspTextDocument identifies a text document by URI
+type lspTextDocument struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+// lspInlayHint is an inlay hint shown after a line
+type lspInlayHint struct {
+	Position    lspPosition `json:"position"`
+	Label       string      `json:"label"`
+	Tooltip     string      `json:"tooltip,omitempty"`
+	PaddingLeft bool        `json:"paddingLeft"`
+}
+
+// lspDocument is an open text document with its annotated lines, which
+// are computed on first use and dropped when the document changes
+type lspDocument struct {
+	text      string
+	lines     []BlameLineWithApproval
+	annotated bool
+}
+
+// LanguageServer provides approval inlay hints and hovers over the Language
+// Server Protocol. It keeps the text of open documents, so lines edited
+// but not saved are annotated as not committed yet, and annotates them with
+// a BlameServer whose lookups stay warm between documents.
+type LanguageServer struct {
+	blame     *BlameServer
+	documents map[string]*lspDocument
+	// log receives the errors that are not reported to the client, such as
+	// files outside a repository
+	log      io.Writer
+	shutdown bool
+}
+
+// NewLanguageServer creates a language server annotating with blame
+func NewLanguageServer(blame *BlameServer, log io.Writer) *LanguageServer {
+	return &LanguageServer{blame: blame, documents: make(map[string]*lspDocument), log: log}
+}
+
+// Serve reads requests from r and writes responses to w until the client
+// sends exit or closes r. Requests are answered in order.
+func (s *LanguageServer) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		body, err := readLSPMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var request lspRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return fmt.Errorf("invalid language server message: %w", err)
+		}
+		if request.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(ctx, request)
+		if len(request.ID) == 0 {
+			// Notifications have no response
+			continue
+		}
+		response := lspResponse{JSONRPC: "2.0", ID: request.ID, Error: rpcErr}
+		if rpcErr == nil {
+			if response.Result, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+		if err := writeLSPMessage(w, response); err != nil {
+			return err
+		}
+	}
+}
+
+// handle runs a request or notification and returns its result
+func (s *LanguageServer) handle(ctx context.Context, request lspRequest) (any, *lspError) {
+	var params struct {
+		TextDocument   lspTextDocument `json:"textDocument"`
+		Position       lspPosition     `json:"position"`
+		Range          lspRange        `json:"range"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	if len(request.Params) > 0 {
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+	}
+	uri := params.TextDocument.URI
+
+	switch request.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				// Full document sync: every change sends the whole text
+				"textDocumentSync":  map[string]any{"openClose": true, "change": 1, "save": true},
+				"hoverProvider":     true,
+				"inlayHintProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "git-review-blame", "version": Version},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		s.documents[uri] = &lspDocument{text: params.TextDocument.Text}
+	case "textDocument/didChange":
+		if document := s.documents[uri]; document != nil && len(params.ContentChanges) > 0 {
+			*document = lspDocument{text: params.ContentChanges[len(params.ContentChanges)-1].Text}
+		}
+	case "textDocument/didSave":
+		// Saving may follow a commit or amend, so annotate again
+		if document := s.documents[uri]; document != nil {
+			*document = lspDocument{text: document.text}
+		}
+	case "textDocument/didClose":
+		delete(s.documents, uri)
+	case "textDocument/hover":
+		for _, line := range s.annotate(ctx, uri) {
+			if line.LineNumber == params.Position.Line+1 {
+				return map[string]any{
+					"contents": map[string]string{"kind": "plaintext", "value": NewEditorAnnotation(line).Hover},
+				}, nil
+			}
+		}
+		return nil, nil
+	case "textDocument/inlayHint":
+		hints := []lspInlayHint{}
+		for _, line := range s.annotate(ctx, uri) {
+			if line.LineNumber-1 < params.Range.Start.Line || line.LineNumber-1 > params.Range.End.Line {
+				continue
+			}
+			hints = append(hints, lspInlayHint{
+				Position:    lspPosition{Line: line.LineNumber - 1, Character: utf16Length(line.Content)},
+				Label:       lspHintLabel(line),
+				Tooltip:     NewEditorAnnotation(line).Hover,
+				PaddingLeft: true,
+			})
+		}
+		return hints, nil
+	default:
+		if len(request.ID) > 0 {
+			return nil, &lspError{Code: lspMethodNotFound, Message: "unsupported method " + request.Method}
+		}
+	}
+	return nil, nil
+}
+
+// annotate returns the annotated lines of a document, annotating its
+// unsaved text when it is open. Failures are logged and leave the document
+// without annotations until it changes.
+func (s *LanguageServer) annotate(ctx context.Context, uri string) []BlameLineWithApproval {
+	document := s.documents[uri]
+	if document != nil && document.annotated {
+		return document.lines
+	}
+	lines, err := s.blameDocument(ctx, uri, document)
+	if err != nil {
+		fmt.Fprintf(s.log, "git-review-blame: %s: %v\n", uri, err)
+	}
+	if document != nil {
+		document.lines, document.annotated = lines, true
+	}
+	return lines
+}
+
+// blameDocument annotates the file of uri, with the text of document in
+// place of the saved file when it is open
+func (s *LanguageServer) blameDocument(ctx context.Context, uri string, document *lspDocument) ([]BlameLineWithApproval, error) {
+	path, err := lspURIPath(uri)
+	if err != nil {
+		return nil, err
+	}
+	var contents string
+	if document != nil {
+		if contents, err = writeLSPContents(document.text); err != nil {
+			return nil, err
+		}
+		defer os.Remove(contents)
+	}
+	_, lines, err := s.blame.Blame(ctx, BlameRequest{Repo: filepath.Dir(path), File: path}, contents)
+	return lines, err
+}
+
+// writeLSPContents writes the text of a document to a temporary file for
+// git blame --contents
+func writeLSPContents(text string) (string, error) {
+	file, err := os.CreateTemp("", "git-review-blame-*")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(text); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// lspHintLabel is the inlay hint of a line, e.g. "approved by alice in #123
+// on 2024-02-01"
+func lspHintLabel(line BlameLineWithApproval) string {
+	if line.Approver == "" || isUncommitted(line.BlameLine) {
+		return NewEditorAnnotation(line).Text
+	}
+	label := "approved by " + line.Approver
+	if line.PRNumber > 0 {
+		label += fmt.Sprintf(" in #%d", line.PRNumber)
+	}
+	if line.ApprovalTime != nil {
+		label += " on " + line.ApprovalTime.Format("2006-01-02")
+	}
+	if line.SelfApproved() {
+		label += " (self-approved)"
+	}
+	return label
+}
+
+// lspURIPath returns the file path of a file:// URI
+func lspURIPath(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme %q", parsed.Scheme)
+	}
+	path := parsed.Path
+	// file:///C:/src has the path /C:/src on Windows
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// utf16Length returns the length of s in UTF-16 code units, the unit of
+// LSP character offsets
+func utf16Length(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// readLSPMessage reads the body of a message framed with a Content-Length
+// header
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("invalid language server message header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeLSPMessage writes value as a message framed with a Content-Length
+// header
+func writeLSPMessage(w io.Writer, value any) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// runLSP implements the lsp subcommand
+func runLSP(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	cacheTTL := flags.Duration("cache-ttl", 10*time.Minute, "How long the lookups of a repository are reused before approvals are fetched again (0 for the lifetime of the server)")
+	configPath := flags.String("config", "", "Path to the config file")
+	offline := flags.Bool("offline", false, "Find PRs/MRs from merge commits without API calls")
+	flags.Bool("stdio", true, "Talk over stdin and stdout; accepted as editors pass it, and the only transport")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+	if *cacheTTL < 0 {
+		return fmt.Errorf("-cache-ttl must not be negative")
+	}
+
+	blame := NewBlameServer(githubToken, gitlabToken)
+	blame.ConfigPath = *configPath
+	blame.Offline = *offline
+	blame.CacheTTL = *cacheTTL
+	return NewLanguageServer(blame, os.Stderr).Serve(ctx, os.Stdin, os.Stdout)
+}
@@ -0,0 +1,128 @@
+package reviewblame
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLanguageServer(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main\n\nfunc main() {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "main.go")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main (#12)\n\nReviewed-by: Alice <alice@example.com>")
+	uri := "file://" + filepath.ToSlash(file)
+
+	var input bytes.Buffer
+	send := func(message string) {
+		if err := writeLSPMessage(&input, json.RawMessage(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send(`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"processId": null, "capabilities": {}}}`)
+	send(`{"jsonrpc": "2.0", "method": "initialized", "params": {}}`)
+	// The open buffer has an unsaved line at the top
+	send(`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "` + uri + `", "languageId": "go", "version": 1, "text": "// héllo\npackage main\n\nfunc main() {\n}\n"}}}`)
+	send(`{"jsonrpc": "2.0", "id": 2, "method": "textDocument/inlayHint", "params": {"textDocument": {"uri": "` + uri + `"}, "range": {"start": {"line": 0, "character": 0}, "end": {"line": 1, "character": 0}}}}`)
+	send(`{"jsonrpc": "2.0", "id": 3, "method": "textDocument/hover", "params": {"textDocument": {"uri": "` + uri + `"}, "position": {"line": 1, "character": 3}}}`)
+	send(`{"jsonrpc": "2.0", "id": 4, "method": "textDocument/hover", "params": {"textDocument": {"uri": "` + uri + `"}, "position": {"line": 40, "character": 0}}}`)
+	send(`{"jsonrpc": "2.0", "id": 5, "method": "textDocument/definition", "params": {}}`)
+	send(`{"jsonrpc": "2.0", "id": 6, "method": "shutdown"}`)
+	send(`{"jsonrpc": "2.0", "method": "exit"}`)
+
+	blame := NewBlameServer("", "")
+	blame.Offline = true
+	var output, log bytes.Buffer
+	if err := NewLanguageServer(blame, &log).Serve(context.Background(), &input, &output); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	responses := make(map[string]lspResponse)
+	reader := bufio.NewReader(&output)
+	for {
+		body, err := readLSPMessage(reader)
+		if err != nil {
+			break
+		}
+		var response lspResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		responses[string(response.ID)] = response
+	}
+	if len(responses) != 6 {
+		t.Fatalf("expected 6 responses, got %d: %v", len(responses), responses)
+	}
+
+	if !strings.Contains(string(responses["1"].Result), `"inlayHintProvider":true`) {
+		t.Errorf("expected inlay hint support, got %s", responses["1"].Result)
+	}
+
+	var hints []lspInlayHint
+	if err := json.Unmarshal(responses["2"].Result, &hints); err != nil {
+		t.Fatal(err)
+	}
+	if len(hints) != 2 {
+		t.Fatalf("expected hints for lines 1 and 2, got %+v", hints)
+	}
+	if hints[0].Label != "not committed yet" || hints[0].Position != (lspPosition{Line: 0, Character: 8}) {
+		t.Errorf("expected the unsaved line to be marked after its 8 UTF-16 units, got %+v", hints[0])
+	}
+	if hints[1].Label != "approved by Alice in #12" || hints[1].Position != (lspPosition{Line: 1, Character: 12}) {
+		t.Errorf("unexpected hint for line 2: %+v", hints[1])
+	}
+
+	if !strings.Contains(string(responses["3"].Result), "Approved by Alice") {
+		t.Errorf("expected the hover of line 2, got %s", responses["3"].Result)
+	}
+	if string(responses["4"].Result) != "null" {
+		t.Errorf("expected no hover past the end, got %s", responses["4"].Result)
+	}
+	if responses["5"].Error == nil || responses["5"].Error.Code != lspMethodNotFound {
+		t.Errorf("expected an unsupported method error, got %+v", responses["5"])
+	}
+	if string(responses["6"].Result) != "null" {
+		t.Errorf("expected shutdown to answer null, got %s", responses["6"].Result)
+	}
+	if log.Len() > 0 {
+		t.Errorf("unexpected log output %q", log.String())
+	}
+}
+
+func TestLanguageServerExitWithoutShutdown(t *testing.T) {
+	var input bytes.Buffer
+	writeLSPMessage(&input, json.RawMessage(`{"jsonrpc": "2.0", "method": "exit"}`))
+	err := NewLanguageServer(NewBlameServer("", ""), &bytes.Buffer{}).Serve(context.Background(), &input, &bytes.Buffer{})
+	if err == nil {
+		t.Error("expected an error for exit without shutdown")
+	}
+}
+
+func TestLSPURIPath(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{"file:///home/dev/src/main.go", filepath.FromSlash("/home/dev/src/main.go"), false},
+		{"file:///home/dev/my%20project/main.go", filepath.FromSlash("/home/dev/my project/main.go"), false},
+		{"file:///C:/src/main.go", filepath.FromSlash("C:/src/main.go"), false},
+		{"untitled:Untitled-1", "", true},
+	}
+	for _, tt := range tests {
+		got, err := lspURIPath(tt.uri)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("lspURIPath(%q) = %q, %v; want %q", tt.uri, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// MailmapFileName is the mailmap file looked up at the repository root
+const MailmapFileName = ".mailmap"
+
+// MailmapEnricher applies the repository's .mailmap to approvers and backport
+// approvers that have an email, so they match the canonical identities git
+// blame already reports for commit authors.
+type MailmapEnricher struct {
+	repoRoot string
+	// cache maps "Name <email>" to its mapped name and email
+	cache map[string][2]string
+	// mailmap is the parsed .mailmap, used instead of git check-mailmap once
+	// git turned out not to be installed
+	mailmap *Mailmap
+}
+
+// NewMailmapEnricher creates the mailmap stage
+func NewMailmapEnricher(repoRoot string) *MailmapEnricher {
+	return &MailmapEnricher{
+		repoRoot: repoRoot,
+		cache:    make(map[string][2]string),
+	}
+}
+
+// hasMailmap reports whether the repository has a .mailmap file at its root
+func hasMailmap(repoRoot string) bool {
+	_, err := os.Stat(filepath.Join(repoRoot, MailmapFileName))
+	return err == nil
+}
+
+// Name implements Enricher
+func (e *MailmapEnricher) Name() string {
+	return "mailmap"
+}
+
+// resolve returns the mailmapped name and email of a contact. Contacts
+// without an email cannot be matched and are returned unchanged.
+func (e *MailmapEnricher) resolve(name, email string) (string, string, error) {
+	if email == "" {
+		return name, email, nil
+	}
+
+	contact := fmt.Sprintf("%s <%s>", name, email)
+	if mapped, exists := e.cache[contact]; exists {
+		return mapped[0], mapped[1], nil
+	}
+	if e.mailmap != nil {
+		name, email = e.mailmap.Resolve(name, email)
+		return name, email, nil
+	}
+
+	cmd := exec.Command("git", "check-mailmap", contact)
+	cmd.Dir = e.repoRoot
+	output, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		// Without git, e.g. with the go-git backend, read the file itself
+		mailmap, err := ReadMailmap(e.repoRoot)
+		if err != nil {
+			return "", "", err
+		}
+		e.mailmap = mailmap
+		name, email = mailmap.Resolve(name, email)
+		return name, email, nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("could not apply .mailmap to %s: %w", contact, err)
+	}
+
+	mappedName, mappedEmail := name, email
+	result := strings.TrimSpace(string(output))
+	if open := strings.LastIndex(result, " <"); open >= 0 && strings.HasSuffix(result, ">") {
+		mappedName = result[:open]
+		mappedEmail = result[open+2 : len(result)-1]
+	}
+
+	e.cache[contact] = [2]string{mappedName, mappedEmail}
+	return mappedName, mappedEmail, nil
+}
+
+// Enrich implements Enricher
+func (e *MailmapEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		line := &lines[i]
+		name, email, err := e.resolve(line.Approver, line.ApproverEmail)
+		if err != nil {
+			return err
+		}
+		line.Approver, line.ApproverEmail = name, email
+
+		if len(line.Approvers) > 0 {
+			// Approver lists are shared between lines, so copy before changing
+			approvers := make([]LineApprover, len(line.Approvers))
+			for j, approver := range line.Approvers {
+				approver.Name, approver.Email, err = e.resolve(approver.Name, approver.Email)
+				if err != nil {
+					return err
+				}
+				approvers[j] = approver
+			}
+			line.Approvers = approvers
+		}
+
+		if line.Backport != nil && line.Backport.ApproverEmail != "" {
+			name, email, err := e.resolve(line.Backport.Approver, line.Backport.ApproverEmail)
+			if err != nil {
+				return err
+			}
+			// Backport origins may be shared between lines, so copy before changing
+			origin := *line.Backport
+			origin.Approver, origin.ApproverEmail = name, email
+			line.Backport = &origin
+		}
+	}
+	return nil
+}
+
+// Mailmap is a parsed .mailmap file, for applying it without git
+type Mailmap struct {
+	entries map[mailmapKey]mailmapEntry
+}
+
+// mailmapKey identifies the identity an entry replaces: a lower-case commit
+// email, and optionally a lower-case commit name
+type mailmapKey struct {
+	email string
+	name  string
+}
+
+// mailmapEntry is the proper name and email of an identity; either may be
+// empty to keep the original
+type mailmapEntry struct {
+	name  string
+	email string
+}
+
+// ParseMailmap parses the gitmailmap format, with one of these forms per line:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+//
+// and "#" comments. Emails and names are matched case-insensitively.
+func ParseMailmap(content string) *Mailmap {
+	mailmap := &Mailmap{entries: make(map[mailmapKey]mailmapEntry)}
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		// Split the line into the names before each <email>
+		var names, emails []string
+		rest := line
+		for {
+			open := strings.Index(rest, "<")
+			if open < 0 {
+				break
+			}
+			end := strings.Index(rest[open:], ">")
+			if end < 0 {
+				break
+			}
+			names = append(names, strings.TrimSpace(rest[:open]))
+			emails = append(emails, strings.TrimSpace(rest[open+1:open+end]))
+			rest = rest[open+end+1:]
+		}
+
+		switch len(emails) {
+		case 1:
+			// Proper Name <commit@email>
+			if names[0] != "" {
+				mailmap.entries[mailmapKey{email: strings.ToLower(emails[0])}] = mailmapEntry{name: names[0]}
+			}
+		case 2:
+			key := mailmapKey{email: strings.ToLower(emails[1]), name: strings.ToLower(names[1])}
+			mailmap.entries[key] = mailmapEntry{name: names[0], email: emails[0]}
+		}
+	}
+	return mailmap
+}
+
+// ReadMailmap reads the .mailmap at the repository root; a repository
+// without one gets an empty mailmap
+func ReadMailmap(repoRoot string) (*Mailmap, error) {
+	data, err := os.ReadFile(filepath.Join(repoRoot, MailmapFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return ParseMailmap(""), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseMailmap(string(data)), nil
+}
+
+// Resolve returns the canonical name and email of a commit identity,
+// preferring an entry for both its name and email over one for its email
+func (m *Mailmap) Resolve(name, email string) (string, string) {
+	entry, found := m.entries[mailmapKey{email: strings.ToLower(email), name: strings.ToLower(name)}]
+	if !found {
+		entry, found = m.entries[mailmapKey{email: strings.ToLower(email)}]
+	}
+	if !found {
+		return name, email
+	}
+	if entry.name != "" {
+		name = entry.name
+	}
+	if entry.email != "" {
+		email = entry.email
+	}
+	return name, email
+}
@@ -0,0 +1,94 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMailmap(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "file.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+
+	mailmap := "Test Person <person@example.com> <test@example.com>\n" +
+		"Alice <alice@example.com> <alice@old-company.com>\n"
+	if err := os.WriteFile(filepath.Join(dir, MailmapFileName), []byte(mailmap), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !hasMailmap(dir) {
+		t.Fatal("expected .mailmap to be detected")
+	}
+
+	// git blame applies the mailmap to commit authors
+	blameLines, err := ExecuteGitBlame(context.Background(), dir, filepath.Join(dir, "file.txt"), BlameOptions{})
+	if err != nil {
+		t.Fatalf("unexpected blame error: %v", err)
+	}
+	if blameLines[0].Author != "Test Person" || blameLines[0].AuthorEmail != "person@example.com" {
+		t.Errorf("expected mailmapped author, got %q <%s>", blameLines[0].Author, blameLines[0].AuthorEmail)
+	}
+	goGitLines, err := ExecuteGitBlame(context.Background(), dir, filepath.Join(dir, "file.txt"), BlameOptions{Backend: BackendGoGit})
+	if err != nil {
+		t.Fatalf("unexpected go-git blame error: %v", err)
+	}
+	if goGitLines[0].Author != "Test Person" || goGitLines[0].AuthorEmail != "person@example.com" {
+		t.Errorf("expected the go-git backend to mailmap the author, got %q <%s>", goGitLines[0].Author, goGitLines[0].AuthorEmail)
+	}
+
+	lines := []BlameLineWithApproval{
+		{Approver: "alice-gl", ApproverEmail: "alice@old-company.com", Backport: &BackportOrigin{PRNumber: 2, Approver: "alice-gl", ApproverEmail: "alice@old-company.com"}},
+		{Approver: "bob", ApproverEmail: "bob@example.com"},
+		{Approver: "carol"},
+	}
+	if err := NewMailmapEnricher(dir).Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if lines[0].Approver != "Alice" || lines[0].ApproverEmail != "alice@example.com" {
+		t.Errorf("expected mailmapped approver, got %q <%s>", lines[0].Approver, lines[0].ApproverEmail)
+	}
+	if lines[0].Backport.Approver != "Alice" {
+		t.Errorf("expected mailmapped backport approver, got %+v", lines[0].Backport)
+	}
+	if lines[1].Approver != "bob" || lines[1].ApproverEmail != "bob@example.com" {
+		t.Errorf("unmapped approver changed: %+v", lines[1])
+	}
+	if lines[2].Approver != "carol" || lines[2].ApproverEmail != "" {
+		t.Errorf("approver without email changed: %+v", lines[2])
+	}
+}
+
+func TestParseMailmap(t *testing.T) {
+	mailmap := ParseMailmap(`# Canonical identities
+Jane Doe <jane@example.com>
+<joe@example.com> <joe@old.example.com>
+Bob <bob@example.com> <robert@example.com>
+Carol <carol@example.com> Carol Work <shared@example.com>
+Dave <dave@example.com> <shared@example.com> # everyone else on the shared address
+`)
+	tests := []struct {
+		name, email         string
+		wantName, wantEmail string
+	}{
+		{"jdoe", "JANE@example.com", "Jane Doe", "JANE@example.com"},
+		{"Joe", "joe@old.example.com", "Joe", "joe@example.com"},
+		{"Robert", "robert@example.com", "Bob", "bob@example.com"},
+		{"carol work", "shared@example.com", "Carol", "carol@example.com"},
+		{"Someone", "shared@example.com", "Dave", "dave@example.com"},
+		{"Eve", "eve@example.com", "Eve", "eve@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, email := mailmap.Resolve(tt.name, tt.email)
+			if name != tt.wantName || email != tt.wantEmail {
+				t.Errorf("expected %s <%s>, got %s <%s>", tt.wantName, tt.wantEmail, name, email)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package reviewblame
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// markdownEscaper escapes the characters that would end a table cell or be
+// read as Markdown syntax in names such as "dependabot[bot]"
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;", ">", "&gt;",
+)
+
+// formatMarkdown writes a Markdown table per file with a row per hunk, a run
+// of consecutive lines from the same commit: the line range, the commit, a
+// link to the PR/MR, the approvers linked to their reviews and the approval
+// date, for pasting into design docs and audit tickets. PR/MR links need
+// opts.Repo.
+func formatMarkdown(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+	for start := 0; start < len(lines); {
+		line := lines[start]
+		if start == 0 || line.Filename != lines[start-1].Filename {
+			separator := "\n"
+			if start == 0 {
+				separator = ""
+			}
+			if _, err := fmt.Fprintf(w, "%s### %s\n\n| Lines | Commit | PR/MR | Approvers | Approved |\n|---|---|---|---|---|\n", separator, markdownEscaper.Replace(line.Filename)); err != nil {
+				return err
+			}
+		}
+
+		end := start + 1
+		for end < len(lines) &&
+			lines[end].Filename == line.Filename &&
+			lines[end].CommitHash == line.CommitHash &&
+			lines[end].LineNumber == lines[end-1].LineNumber+1 {
+			end++
+		}
+		if _, err := io.WriteString(w, markdownHunkRow(line, lines[end-1].LineNumber, opts.Repo)); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// markdownHunkRow renders the table row of a hunk starting with line and
+// ending at endLine
+func markdownHunkRow(line BlameLineWithApproval, endLine int, repo *RepoInfo) string {
+	lineRange := strconv.Itoa(line.LineNumber)
+	if endLine != line.LineNumber {
+		lineRange += "-" + strconv.Itoa(endLine)
+	}
+
+	var prURL string
+	if repo != nil {
+		owner, name := lineRepository(repo, line)
+		prURL = repo.PullRequestURL(owner, name, line.PRNumber)
+	}
+	pr := "no PR/MR"
+	switch {
+	case line.PRNumber != 0 && prURL != "":
+		pr = fmt.Sprintf("[#%d](%s)", line.PRNumber, prURL)
+	case line.PRNumber != 0:
+		pr = fmt.Sprintf("#%d", line.PRNumber)
+	}
+
+	var approvers []string
+	for _, approver := range distinctApprovers(line) {
+		name := markdownEscaper.Replace(approver.Name)
+		if approver.URL != "" {
+			name = fmt.Sprintf("[%s](%s)", name, approver.URL)
+		}
+		approvers = append(approvers, name)
+	}
+	approved := "*not approved*"
+	if len(approvers) > 0 {
+		approved = strings.Join(approvers, ", ")
+	}
+
+	var date string
+	if line.ApprovalTime != nil {
+		date = line.ApprovalTime.UTC().Format("2006-01-02")
+	}
+	return fmt.Sprintf("| %s | `%s` | %s | %s | %s |\n", lineRange, shortCommit(line.CommitHash), pr, approved, date)
+}
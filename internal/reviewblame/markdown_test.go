@@ -0,0 +1,61 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	approvedAt := time.Date(2024, 3, 1, 23, 30, 0, 0, time.FixedZone("PST", -8*3600))
+	approvers := []LineApprover{
+		{Name: "alice", URL: "https://github.com/owner/repo/pull/42#pullrequestreview-1"},
+		{Name: "dependabot[bot]"},
+		{Name: "alice"},
+	}
+	reviewed := func(number int) BlameLineWithApproval {
+		return BlameLineWithApproval{
+			BlameLine:    BlameLine{CommitHash: "abcdef1234567890", Filename: "src/main.go", LineNumber: number},
+			PRNumber:     42,
+			Approver:     "alice",
+			ApprovalTime: &approvedAt,
+			Approvers:    approvers,
+		}
+	}
+	lines := []BlameLineWithApproval{
+		reviewed(1), reviewed(2), reviewed(3),
+		{BlameLine: BlameLine{CommitHash: "0123456789abcdef", Filename: "src/main.go", LineNumber: 4}},
+		// Same commit again, but not adjacent to the first hunk
+		reviewed(5),
+		{BlameLine: BlameLine{CommitHash: "fedcba9876543210", Filename: "lib/util_test.go", LineNumber: 7}, PRNumber: 3, Repository: "old/repo"},
+	}
+
+	var out strings.Builder
+	if err := formatMarkdown(&out, lines, FormatOptions{Repo: &RepoInfo{Owner: "owner", Name: "repo", Type: RepositoryTypeGitLab, Host: "gitlab.com"}}); err != nil {
+		t.Fatalf("formatMarkdown failed: %v", err)
+	}
+	want := "### src/main.go\n\n" +
+		"| Lines | Commit | PR/MR | Approvers | Approved |\n|---|---|---|---|---|\n" +
+		"| 1-3 | `abcdef12` | [#42](https://gitlab.com/owner/repo/-/merge_requests/42) | [alice](https://github.com/owner/repo/pull/42#pullrequestreview-1), dependabot\\[bot\\] | 2024-03-02 |\n" +
+		"| 4 | `01234567` | no PR/MR | *not approved* |  |\n" +
+		"| 5 | `abcdef12` | [#42](https://gitlab.com/owner/repo/-/merge_requests/42) | [alice](https://github.com/owner/repo/pull/42#pullrequestreview-1), dependabot\\[bot\\] | 2024-03-02 |\n" +
+		"\n### lib/util\\_test.go\n\n" +
+		"| Lines | Commit | PR/MR | Approvers | Approved |\n|---|---|---|---|---|\n" +
+		"| 7 | `fedcba98` | [#3](https://gitlab.com/old/repo/-/merge_requests/3) | *not approved* |  |\n"
+	if out.String() != want {
+		t.Errorf("unexpected Markdown:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestFormatMarkdownWithoutRepository(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "abc", Filename: "a.go", LineNumber: 1}, PRNumber: 3, Approver: "bob"},
+	}
+	formatter, err := DefaultFormatters.Lookup("markdown")
+	if err != nil {
+		t.Fatalf("markdown format not registered: %v", err)
+	}
+	if output := formatter.Format(lines, FormatOptions{}); !strings.HasSuffix(output, "| 1 | `abc` | #3 | bob |  |\n") {
+		t.Errorf("expected an unlinked PR and approver, got:\n%s", output)
+	}
+}
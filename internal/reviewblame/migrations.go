@@ -0,0 +1,127 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MigrationConfig records that history before a commit was imported from
+// another repository (a transfer, split or re-import), so PRs of older
+// commits are looked up there
+type MigrationConfig struct {
+	// Before is the first commit made in the current repository; every
+	// commit it descends from came from Repository
+	Before string `json:"before"`
+	// Repository is the original "owner/name" on the same host
+	Repository string `json:"repository"`
+}
+
+// validate checks that a migration entry is complete
+func (m MigrationConfig) validate() error {
+	if m.Before == "" {
+		return fmt.Errorf("migration to %q is missing \"before\"", m.Repository)
+	}
+	owner, name, found := strings.Cut(m.Repository, "/")
+	if !found || owner == "" || name == "" {
+		return fmt.Errorf("migration repository %q must be of the form owner/name", m.Repository)
+	}
+	return nil
+}
+
+// resolvedMigration is a migration with its boundary commit resolved
+type resolvedMigration struct {
+	before     string
+	repository string
+	// depth is the number of commits reachable from before, used to find
+	// the earliest matching migration
+	depth int
+}
+
+// MigrationEnricher sets the originating repository of lines whose commit
+// predates a configured migration. It must run before the PR lookup stages.
+type MigrationEnricher struct {
+	repoRoot   string
+	migrations []MigrationConfig
+	resolved   []resolvedMigration
+	// cache maps commit hash to its originating repository ("" for the current one)
+	cache map[string]string
+}
+
+// NewMigrationEnricher creates the repository migration stage
+func NewMigrationEnricher(repoRoot string, migrations []MigrationConfig) *MigrationEnricher {
+	return &MigrationEnricher{
+		repoRoot:   repoRoot,
+		migrations: migrations,
+		cache:      make(map[string]string),
+	}
+}
+
+// Name implements Enricher
+func (e *MigrationEnricher) Name() string {
+	return "repository-migrations"
+}
+
+// resolve resolves the boundary commits once
+func (e *MigrationEnricher) resolve() error {
+	if e.resolved != nil || len(e.migrations) == 0 {
+		return nil
+	}
+
+	e.resolved = make([]resolvedMigration, 0, len(e.migrations))
+	for _, migration := range e.migrations {
+		before, err := ResolveRevision(e.repoRoot, migration.Before)
+		if err != nil {
+			return fmt.Errorf("migration to %s: %w", migration.Repository, err)
+		}
+
+		cmd := exec.Command("git", "rev-list", "--count", before)
+		cmd.Dir = e.repoRoot
+		output, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("migration to %s: %w", migration.Repository, err)
+		}
+		depth, err := strconv.Atoi(strings.TrimSpace(string(output)))
+		if err != nil {
+			return err
+		}
+
+		e.resolved = append(e.resolved, resolvedMigration{before: before, repository: migration.Repository, depth: depth})
+	}
+	return nil
+}
+
+// originOf returns the repository a commit came from, or "" for the current one.
+// When a commit predates several migrations, the earliest one applies.
+func (e *MigrationEnricher) originOf(commitHash string) string {
+	origin, depth := "", 0
+	for _, migration := range e.resolved {
+		if commitHash == migration.before || !isAncestor(e.repoRoot, commitHash, migration.before) {
+			continue
+		}
+		if origin == "" || migration.depth < depth {
+			origin, depth = migration.repository, migration.depth
+		}
+	}
+	return origin
+}
+
+// Enrich implements Enricher
+func (e *MigrationEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	if err := e.resolve(); err != nil {
+		return err
+	}
+
+	for i := range lines {
+		commitHash := lines[i].CommitHash
+		origin, exists := e.cache[commitHash]
+		if !exists {
+			origin = e.originOf(commitHash)
+			e.cache[commitHash] = origin
+		}
+		lines[i].Repository = origin
+	}
+	return nil
+}
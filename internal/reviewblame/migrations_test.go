@@ -0,0 +1,81 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordingReviewClient is a fakeReviewClient that records which repository
+// each PR lookup was made against
+type recordingReviewClient struct {
+	fakeReviewClient
+	lookups map[string]string
+}
+
+func (c *recordingReviewClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	c.lookups[commitHash] = owner + "/" + repo
+	return c.fakeReviewClient.FindPRByCommit(context.Background(), owner, repo, commitHash)
+}
+
+func TestMigrationEnricher(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+
+	var commits []string
+	for _, content := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, dir, "add", "file.txt")
+		gitCommand(t, dir, "commit", "-q", "-m", "commit "+content)
+		commits = append(commits, gitCommand(t, dir, "rev-parse", "HEAD"))
+	}
+
+	// commits[0] came from first-org/repo, commits[1] from second-org/repo,
+	// and the current repository starts at commits[2]
+	migrations := []MigrationConfig{
+		{Before: commits[2], Repository: "second-org/repo"},
+		{Before: commits[1], Repository: "first-org/repo"},
+	}
+
+	client := &recordingReviewClient{lookups: make(map[string]string)}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+	pipeline := NewDefaultEnrichmentPipeline(client, repoInfo)
+	if err := pipeline.InsertBefore("pr-lookup", NewMigrationEnricher(dir, migrations)); err != nil {
+		t.Fatal(err)
+	}
+
+	var blameLines []BlameLine
+	for _, commit := range commits {
+		blameLines = append(blameLines, BlameLine{CommitHash: commit})
+	}
+	lines, err := pipeline.Run(context.Background(), blameLines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"first-org/repo", "second-org/repo", "", ""}
+	for i, line := range lines {
+		if line.Repository != expected[i] {
+			t.Errorf("commit %d: expected repository %q, got %q", i, expected[i], line.Repository)
+		}
+	}
+	if lookup := client.lookups[commits[0]]; lookup != "first-org/repo" {
+		t.Errorf("expected PR lookup in first-org/repo, got %s", lookup)
+	}
+	if lookup := client.lookups[commits[3]]; lookup != "owner/repo" {
+		t.Errorf("expected PR lookup in owner/repo, got %s", lookup)
+	}
+}
+
+func TestMigrationEnricherUnknownCommit(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+
+	enricher := NewMigrationEnricher(dir, []MigrationConfig{{Before: "does-not-exist", Repository: "old/repo"}})
+	if err := enricher.Enrich(context.Background(), nil); err == nil {
+		t.Error("expected error for unresolvable migration commit")
+	}
+}
@@ -0,0 +1,185 @@
+package reviewblame
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// notebookPreviewWidth is the maximum length of a cell's code preview
+const notebookPreviewWidth = 60
+
+// NotebookCell is a cell of a Jupyter notebook and the lines of the raw
+// .ipynb JSON it spans
+type NotebookCell struct {
+	// Index is the 1-based position of the cell in the notebook
+	Index     int
+	Type      string
+	Source    string
+	StartLine int
+	EndLine   int
+}
+
+// Preview returns the first non-blank source line, shortened for display
+func (c NotebookCell) Preview() string {
+	for _, line := range strings.Split(c.Source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if len(line) > notebookPreviewWidth {
+			line = line[:notebookPreviewWidth-3] + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+// isNotebook reports whether a file is a Jupyter notebook
+func isNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// ParseNotebookCells finds the cells of a notebook and the raw JSON line
+// range each one occupies, so blame lines can be attributed to cells
+func ParseNotebookCells(content []byte) ([]NotebookCell, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("notebook is not a JSON object")
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key != "cells" {
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return nil, fmt.Errorf("notebook cells are not an array")
+		}
+
+		var cells []NotebookCell
+		for decoder.More() {
+			start := decoder.InputOffset()
+			var cell struct {
+				CellType string          `json:"cell_type"`
+				Source   json.RawMessage `json:"source"`
+			}
+			if err := decoder.Decode(&cell); err != nil {
+				return nil, fmt.Errorf("invalid notebook cell: %w", err)
+			}
+			end := decoder.InputOffset()
+
+			cells = append(cells, NotebookCell{
+				Index:     len(cells) + 1,
+				Type:      cell.CellType,
+				Source:    notebookSource(cell.Source),
+				StartLine: lineAtOffset(content, skipSeparators(content, start)),
+				EndLine:   lineAtOffset(content, end-1),
+			})
+		}
+		return cells, nil
+	}
+
+	return nil, fmt.Errorf("notebook has no cells")
+}
+
+// notebookSource joins a cell source, which is either a string or a list of strings
+func notebookSource(raw json.RawMessage) string {
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err == nil {
+		return strings.Join(lines, "")
+	}
+	var source string
+	json.Unmarshal(raw, &source)
+	return source
+}
+
+// skipSeparators advances offset past whitespace and commas between array elements
+func skipSeparators(content []byte, offset int64) int64 {
+	for offset < int64(len(content)) && strings.IndexByte(" \t\r\n,", content[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// lineAtOffset returns the 1-based line number of a byte offset
+func lineAtOffset(content []byte, offset int64) int {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	return bytes.Count(content[:offset], []byte("\n")) + 1
+}
+
+// NotebookCellSummary aggregates the annotated lines of one notebook cell
+type NotebookCellSummary struct {
+	Cell          NotebookCell
+	Lines         int
+	ReviewedLines int
+	// Changes lists who owns the cell's lines, as "#PR (approver)",
+	// "#PR (unreviewed)" or "no PR (author)", in order of appearance
+	Changes []string
+}
+
+// SummarizeNotebookCells attributes annotated lines to the cells containing them
+func SummarizeNotebookCells(cells []NotebookCell, lines []BlameLineWithApproval) []NotebookCellSummary {
+	summaries := make([]NotebookCellSummary, len(cells))
+	seen := make([]map[string]bool, len(cells))
+	for i, cell := range cells {
+		summaries[i].Cell = cell
+		seen[i] = make(map[string]bool)
+	}
+
+	for _, line := range lines {
+		for i, cell := range cells {
+			if line.LineNumber < cell.StartLine || line.LineNumber > cell.EndLine {
+				continue
+			}
+
+			summaries[i].Lines++
+			if line.Approver != "" {
+				summaries[i].ReviewedLines++
+			}
+
+			change := fmt.Sprintf("no PR (%s)", line.Author)
+			if line.PRNumber > 0 {
+				approver := line.Approver
+				if approver == "" {
+					approver = "unreviewed"
+				}
+				change = fmt.Sprintf("#%d (%s)", line.PRNumber, approver)
+			}
+			if !seen[i][change] {
+				seen[i][change] = true
+				summaries[i].Changes = append(summaries[i].Changes, change)
+			}
+			break
+		}
+	}
+
+	return summaries
+}
+
+// FormatNotebookCells renders cell summaries for notebook authors
+func FormatNotebookCells(summaries []NotebookCellSummary) string {
+	var result strings.Builder
+	for _, summary := range summaries {
+		if summary.Lines == 0 {
+			continue
+		}
+		fmt.Fprintf(&result, "Cell %d [%s] %s\n", summary.Cell.Index, summary.Cell.Type, summary.Cell.Preview())
+		fmt.Fprintf(&result, "    %d lines, %d reviewed: %s\n",
+			summary.Lines, summary.ReviewedLines, strings.Join(summary.Changes, ", "))
+	}
+	return result.String()
+}
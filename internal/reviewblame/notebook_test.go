@@ -0,0 +1,109 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": [
+    "# Analysis\n",
+    "Some notes"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "metadata": {},
+   "outputs": [],
+   "source": "\nimport pandas as pd\ndf = pd.read_csv('data.csv')"
+  }
+ ],
+ "metadata": {
+  "kernelspec": {"name": "python3"}
+ },
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func TestParseNotebookCells(t *testing.T) {
+	cells, err := ParseNotebookCells([]byte(testNotebook))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cells) != 2 {
+		t.Fatalf("expected 2 cells, got %d", len(cells))
+	}
+
+	expected := []struct {
+		cellType   string
+		start, end int
+		preview    string
+	}{
+		{"markdown", 3, 10, "# Analysis"},
+		{"code", 11, 17, "import pandas as pd"},
+	}
+	for i, want := range expected {
+		cell := cells[i]
+		if cell.Index != i+1 || cell.Type != want.cellType {
+			t.Errorf("cell %d: unexpected index/type %d/%s", i, cell.Index, cell.Type)
+		}
+		if cell.StartLine != want.start || cell.EndLine != want.end {
+			t.Errorf("cell %d: expected lines %d-%d, got %d-%d", i, want.start, want.end, cell.StartLine, cell.EndLine)
+		}
+		if cell.Preview() != want.preview {
+			t.Errorf("cell %d: expected preview %q, got %q", i, want.preview, cell.Preview())
+		}
+	}
+}
+
+func TestParseNotebookCellsInvalid(t *testing.T) {
+	for _, content := range []string{`[]`, `{"metadata": {}}`, `{"cells": {}}`} {
+		if _, err := ParseNotebookCells([]byte(content)); err == nil {
+			t.Errorf("expected error for %s", content)
+		}
+	}
+}
+
+func TestSummarizeNotebookCells(t *testing.T) {
+	cells, err := ParseNotebookCells([]byte(testNotebook))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []BlameLineWithApproval
+	for n := 1; n <= 23; n++ {
+		line := BlameLineWithApproval{BlameLine: BlameLine{LineNumber: n, Author: "John"}}
+		switch {
+		case n >= 3 && n <= 10:
+			line.PRNumber, line.Approver = 12, "alice"
+		case n >= 11 && n <= 15:
+			line.PRNumber = 15
+		}
+		lines = append(lines, line)
+	}
+
+	summaries := SummarizeNotebookCells(cells, lines)
+	if summaries[0].Lines != 8 || summaries[0].ReviewedLines != 8 {
+		t.Errorf("unexpected first cell counts %+v", summaries[0])
+	}
+	if summaries[1].Lines != 7 || summaries[1].ReviewedLines != 0 {
+		t.Errorf("unexpected second cell counts %+v", summaries[1])
+	}
+
+	output := FormatNotebookCells(summaries)
+	for _, expected := range []string{
+		"Cell 1 [markdown] # Analysis\n    8 lines, 8 reviewed: #12 (alice)\n",
+		"Cell 2 [code] import pandas as pd\n    7 lines, 0 reviewed: #15 (unreviewed), no PR (John)\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package reviewblame
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// topOffendersLimit is how many authors of unreviewed lines a summary lists
+const topOffendersLimit = 5
+
+// RunSummary is the digest of a run sent to notification webhooks
+type RunSummary struct {
+	Repository   string
+	Scope        string
+	Stats        ReviewStats
+	TopOffenders []PersonCount
+	// Base is the revision new lines are counted against; NewUnreviewed is
+	// only set with a base
+	Base          string
+	NewUnreviewed int
+}
+
+// BuildRunSummary summarizes annotated lines for a repository path
+func BuildRunSummary(repoInfo *RepoInfo, scope string, lines []BlameLineWithApproval) RunSummary {
+	return RunSummary{
+		Repository:   fmt.Sprintf("%s/%s", repoInfo.Owner, repoInfo.Name),
+		Scope:        scope,
+		Stats:        ComputeReviewStats(lines),
+		TopOffenders: TopUnreviewedAuthors(lines, topOffendersLimit),
+	}
+}
+
+// CountNewUnreviewed sets the summary's new unreviewed lines: the
+// unreviewed lines whose commit is not reachable from base, such as the
+// commits of a pull request or uncommitted changes
+func (s *RunSummary) CountNewUnreviewed(repoRoot, base string, lines []BlameLineWithApproval) {
+	introduced := make(map[string]bool)
+	s.Base, s.NewUnreviewed = base, 0
+	for _, line := range lines {
+		if line.Approver != "" {
+			continue
+		}
+		isNew, ok := introduced[line.CommitHash]
+		if !ok {
+			isNew = !isAncestor(repoRoot, line.CommitHash, base)
+			introduced[line.CommitHash] = isNew
+		}
+		if isNew {
+			s.NewUnreviewed++
+		}
+	}
+}
+
+// Title returns the one-line headline of the summary
+func (s RunSummary) Title() string {
+	return fmt.Sprintf("Review coverage for %s (%s): %.1f%%", s.Repository, s.Scope, s.Stats.Coverage())
+}
+
+// Text renders the summary body as Markdown understood by Slack and Teams
+func (s RunSummary) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d* of *%d* lines reviewed, *%d* unreviewed", s.Stats.ReviewedLines, s.Stats.TotalLines, s.Stats.UnreviewedLines())
+	if s.Base != "" {
+		fmt.Fprintf(&b, " (*%d* new since %s)", s.NewUnreviewed, s.Base)
+	}
+	if len(s.TopOffenders) > 0 {
+		b.WriteString("\nTop authors of unreviewed lines:")
+		for _, offender := range s.TopOffenders {
+			fmt.Fprintf(&b, "\n• %s: %d", offender.Name, offender.Lines)
+		}
+	}
+	return b.String()
+}
+
+// validateNotificationType rejects webhook types webhookPayload cannot build
+func validateNotificationType(webhookType string) error {
+	_, err := webhookPayload(webhookType, RunSummary{})
+	return err
+}
+
+// webhookPayload builds the JSON payload for the given webhook type
+func webhookPayload(webhookType string, summary RunSummary) (interface{}, error) {
+	switch webhookType {
+	case "slack":
+		return map[string]string{
+			"text": "*" + summary.Title() + "*\n" + summary.Text(),
+		}, nil
+	case "teams":
+		// Legacy connector card, accepted by Teams incoming webhooks
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "http://schema.org/extensions",
+			"summary":  summary.Title(),
+			"title":    summary.Title(),
+			"text":     strings.ReplaceAll(summary.Text(), "\n", "\n\n"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported notification type %q (expected slack or teams)", webhookType)
+	}
+}
+
+// Notifier posts run summaries to incoming webhooks
+type Notifier struct {
+	httpClient *http.Client
+}
+
+// NewNotifier creates a new webhook notifier
+func NewNotifier() *Notifier {
+	return &Notifier{
+		httpClient: newHTTPClient(30 * time.Second),
+	}
+}
+
+// Send posts the summary to a single webhook
+func (n *Notifier) Send(config NotificationConfig, summary RunSummary) error {
+	if config.WebhookURL == "" {
+		return fmt.Errorf("%s notification has no webhook_url", config.Type)
+	}
+
+	payload, err := webhookPayload(config.Type, summary)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.httpClient.Post(config.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s webhook error: %d %s", config.Type, resp.StatusCode, resp.Status)
+	}
+
+	return nil
+}
+
+// SendAll posts the summary to every configured webhook, stopping at the first error
+func (n *Notifier) SendAll(configs []NotificationConfig, summary RunSummary) error {
+	for _, config := range configs {
+		if err := n.Send(config, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testRunSummary() RunSummary {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{Author: "bob"}},
+		{BlameLine: BlameLine{Author: "bob"}},
+		{BlameLine: BlameLine{Author: "alice"}},
+		{BlameLine: BlameLine{Author: "alice"}, Approver: "jane"},
+	}
+	return BuildRunSummary(&RepoInfo{Owner: "owner", Name: "repo"}, "src", lines)
+}
+
+func TestRunSummaryText(t *testing.T) {
+	summary := testRunSummary()
+
+	if summary.Title() != "Review coverage for owner/repo (src): 25.0%" {
+		t.Errorf("unexpected title %q", summary.Title())
+	}
+
+	text := summary.Text()
+	for _, expected := range []string{"*1* of *4* lines reviewed, *3* unreviewed", "• bob: 2", "• alice: 1"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in summary text, got:\n%s", expected, text)
+		}
+	}
+}
+
+func TestRunSummaryCountNewUnreviewed(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("one\n"), 0644)
+	gitCommand(t, dir, "add", "file.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+	base := gitCommand(t, dir, "rev-parse", "HEAD")
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m", "Add feature")
+	feature := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: base, Author: "bob"}},
+		{BlameLine: BlameLine{CommitHash: feature, Author: "alice"}},
+		{BlameLine: BlameLine{CommitHash: feature, Author: "alice"}, Approver: "jane"},
+		{BlameLine: BlameLine{CommitHash: strings.Repeat("0", 40), Author: "Not Committed Yet"}},
+	}
+	summary := BuildRunSummary(&RepoInfo{Owner: "owner", Name: "repo"}, "src", lines)
+	if text := summary.Text(); strings.Contains(text, "new since") {
+		t.Errorf("expected no new line count without a base, got:\n%s", text)
+	}
+
+	summary.CountNewUnreviewed(dir, "main~1", lines)
+	if summary.NewUnreviewed != 2 {
+		t.Errorf("expected the feature and uncommitted lines to be new, got %d", summary.NewUnreviewed)
+	}
+	if text := summary.Text(); !strings.Contains(text, "*3* unreviewed (*2* new since main~1)") {
+		t.Errorf("unexpected summary text:\n%s", text)
+	}
+}
+
+func TestNotifierSend(t *testing.T) {
+	tests := []struct {
+		webhookType string
+		expectKey   string
+	}{
+		{webhookType: "slack", expectKey: "text"},
+		{webhookType: "teams", expectKey: "@type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.webhookType, func(t *testing.T) {
+			var payload map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("expected JSON content type, got %s", r.Header.Get("Content-Type"))
+				}
+				json.NewDecoder(r.Body).Decode(&payload)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			err := NewNotifier().Send(NotificationConfig{Type: tt.webhookType, WebhookURL: server.URL}, testRunSummary())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := payload[tt.expectKey]; !ok {
+				t.Errorf("expected %q in payload, got %v", tt.expectKey, payload)
+			}
+		})
+	}
+}
+
+func TestNotifierSendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier()
+	summary := testRunSummary()
+
+	if err := notifier.Send(NotificationConfig{Type: "slack", WebhookURL: server.URL}, summary); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+	if err := notifier.Send(NotificationConfig{Type: "discord", WebhookURL: server.URL}, summary); err == nil {
+		t.Error("expected error for unsupported webhook type")
+	}
+	if err := notifier.Send(NotificationConfig{Type: "slack"}, summary); err == nil {
+		t.Error("expected error for missing webhook URL")
+	}
+}
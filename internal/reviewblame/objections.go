@@ -0,0 +1,226 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Objection states
+const (
+	// ObjectionChangesRequested is a review requesting changes
+	ObjectionChangesRequested = "changes-requested"
+	// ObjectionDismissed is a review that was dismissed, e.g. to merge
+	// despite its change request
+	ObjectionDismissed = "dismissed"
+)
+
+// Objection is a reviewer who objected to a PR/MR before it was merged
+type Objection struct {
+	Name string `json:"name"`
+	// State is ObjectionChangesRequested or ObjectionDismissed
+	State string     `json:"state"`
+	Time  *time.Time `json:"time,omitempty"`
+	// URL links the review in reports; it is left out of records
+	URL string `json:"-"`
+}
+
+// ObjectionProvider is implemented by review clients that can list the
+// reviewers who requested changes to a PR/MR or whose review was dismissed
+type ObjectionProvider interface {
+	GetPRObjections(ctx context.Context, owner, repo string, prNumber int) ([]Objection, error)
+}
+
+// addObjection records an objection of a reviewer, keeping one per reviewer
+// in the order they first objected, with the state of their latest objection
+func addObjection(objections []Objection, objection Objection) []Objection {
+	for i := range objections {
+		if objections[i].Name == objection.Name {
+			objections[i] = objection
+			return objections
+		}
+	}
+	return append(objections, objection)
+}
+
+// GetPRObjections lists the reviewers who requested changes to a pull
+// request or whose review was dismissed before it was merged
+func (c *GitHubClient) GetPRObjections(ctx context.Context, owner, repo string, prNumber int) ([]Objection, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, owner, repo, prNumber)
+	resp, err := c.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+	var pr PullRequest
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&pr); err != nil {
+		return nil, err
+	}
+
+	var objections []Objection
+	err = c.listPages(ctx, apiURL+"/reviews", func(dec *json.Decoder) error {
+		var review Review
+		if err := dec.Decode(&review); err != nil {
+			return err
+		}
+		if pr.MergedAt != nil && review.SubmittedAt != nil && review.SubmittedAt.After(*pr.MergedAt) {
+			return nil
+		}
+		objection := Objection{Name: review.User.Login, Time: review.SubmittedAt, URL: review.HTMLURL}
+		switch review.State {
+		case "CHANGES_REQUESTED":
+			objection.State = ObjectionChangesRequested
+		case "DISMISSED":
+			objection.State = ObjectionDismissed
+		default:
+			return nil
+		}
+		objections = addObjection(objections, objection)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objections, nil
+}
+
+// GetPRObjections implements ObjectionProvider
+func (a *GitHubClientAdapter) GetPRObjections(ctx context.Context, owner, repo string, prNumber int) ([]Objection, error) {
+	return a.client.GetPRObjections(ctx, owner, repo, prNumber)
+}
+
+// GetPRObjections lists the reviewers of a merge request who requested
+// changes. GitLab records only each reviewer's current state, without a
+// time, and has no dismissed reviews.
+func (c *GitLabClient) GetPRObjections(ctx context.Context, owner, repo string, mrIID int) ([]Objection, error) {
+	var reviewers []struct {
+		User  GitLabUser `json:"user"`
+		State string     `json:"state"`
+	}
+	apiURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/reviewers", mrIID))
+	if err := c.doJSON(ctx, "GET", apiURL, nil, &reviewers, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var objections []Objection
+	for _, reviewer := range reviewers {
+		if reviewer.State == "requested_changes" {
+			objections = append(objections, Objection{Name: reviewer.User.Username, State: ObjectionChangesRequested})
+		}
+	}
+	return objections, nil
+}
+
+// ObjectionEnricher sets the objections of each line's PR/MR. It is a no-op
+// for clients that do not implement ObjectionProvider.
+type ObjectionEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// cache maps PR to its objections (nil when there are none or the
+	// lookup failed)
+	cache map[prKey][]Objection
+}
+
+// NewObjectionEnricher creates the objections stage
+func NewObjectionEnricher(client ReviewClient, repoInfo *RepoInfo) *ObjectionEnricher {
+	return &ObjectionEnricher{
+		client:   client,
+		repoInfo: repoInfo,
+		cache:    make(map[prKey][]Objection),
+	}
+}
+
+// Name implements Enricher
+func (e *ObjectionEnricher) Name() string {
+	return "objections"
+}
+
+// Enrich implements Enricher
+func (e *ObjectionEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	provider, ok := e.client.(ObjectionProvider)
+	if !ok {
+		return nil
+	}
+
+	for i := range lines {
+		prNumber := lines[i].PRNumber
+		if prNumber == 0 {
+			continue
+		}
+
+		key := prKey{lines[i].Repository, prNumber}
+		objections, exists := e.cache[key]
+		if !exists {
+			owner, name := lineRepository(e.repoInfo, lines[i])
+			fetched, err := provider.GetPRObjections(ctx, owner, name, prNumber)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				objections = fetched
+			} else {
+				recordLookupFailure(e.Name(), prSubject(lines[i].Repository, prNumber), err)
+			}
+			e.cache[key] = objections
+		}
+		lines[i].Objections = objections
+	}
+	return nil
+}
+
+// objectorList joins the names of the objectors, marking dismissed reviews
+func objectorList(objections []Objection) string {
+	names := make([]string, 0, len(objections))
+	for _, objection := range objections {
+		if objection.State == ObjectionDismissed {
+			names = append(names, objection.Name+" (dismissed)")
+		} else {
+			names = append(names, objection.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// ObjectionSummaries returns one line per PR/MR of the lines that had
+// objections, ordered by PR number, e.g. "#12: bob requested changes,
+// carol's review was dismissed"
+func ObjectionSummaries(lines []BlameLineWithApproval) []string {
+	var keys []prKey
+	objections := make(map[prKey][]Objection)
+	for _, line := range lines {
+		key := prKey{line.Repository, line.PRNumber}
+		if len(line.Objections) == 0 || objections[key] != nil {
+			continue
+		}
+		keys = append(keys, key)
+		objections[key] = line.Objections
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].repository != keys[j].repository {
+			return keys[i].repository < keys[j].repository
+		}
+		return keys[i].number < keys[j].number
+	})
+
+	summaries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var parts []string
+		for _, objection := range objections[key] {
+			if objection.State == ObjectionDismissed {
+				parts = append(parts, objection.Name+"'s review was dismissed")
+			} else {
+				parts = append(parts, objection.Name+" requested changes")
+			}
+		}
+		summaries = append(summaries, fmt.Sprintf("%s#%d: %s", key.repository, key.number, strings.Join(parts, ", ")))
+	}
+	return summaries
+}
@@ -0,0 +1,141 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitHubGetPRObjections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/pulls/3":
+			json.NewEncoder(w).Encode(map[string]interface{}{"number": 3, "merged_at": "2024-01-10T00:00:00Z"})
+		case "/repos/owner/repo/pulls/3/reviews":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"user": map[string]string{"login": "bob"}, "state": "CHANGES_REQUESTED", "submitted_at": "2024-01-02T00:00:00Z"},
+				{"user": map[string]string{"login": "alice"}, "state": "APPROVED", "submitted_at": "2024-01-03T00:00:00Z"},
+				{"user": map[string]string{"login": "carol"}, "state": "CHANGES_REQUESTED", "submitted_at": "2024-01-04T00:00:00Z"},
+				{"user": map[string]string{"login": "bob"}, "state": "DISMISSED", "submitted_at": "2024-01-05T00:00:00Z",
+					"html_url": "https://github.com/owner/repo/pull/3#pullrequestreview-5"},
+				{"user": map[string]string{"login": "dave"}, "state": "CHANGES_REQUESTED", "submitted_at": "2024-01-11T00:00:00Z"},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	objections, err := client.GetPRObjections(context.Background(), "owner", "repo", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dismissed := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	requested := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	want := []Objection{
+		{Name: "bob", State: ObjectionDismissed, Time: &dismissed, URL: "https://github.com/owner/repo/pull/3#pullrequestreview-5"},
+		{Name: "carol", State: ObjectionChangesRequested, Time: &requested},
+	}
+	if !reflect.DeepEqual(objections, want) {
+		t.Errorf("expected %+v, got %+v", want, objections)
+	}
+}
+
+func TestGitLabGetPRObjections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/owner%2Frepo/merge_requests/4/reviewers" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"user": map[string]string{"username": "alice"}, "state": "approved"},
+			{"user": map[string]string{"username": "bob"}, "state": "requested_changes"},
+			{"user": map[string]string{"username": "carol"}, "state": "unreviewed"},
+		})
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	objections, err := client.GetPRObjections(context.Background(), "owner", "repo", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []Objection{{Name: "bob", State: ObjectionChangesRequested}}; !reflect.DeepEqual(objections, want) {
+		t.Errorf("expected %+v, got %+v", want, objections)
+	}
+}
+
+// fakeObjectionClient is a fakeReviewClient that also reports objections
+type fakeObjectionClient struct {
+	fakeReviewClient
+	objections     map[int][]Objection
+	objectionCalls int
+}
+
+func (c *fakeObjectionClient) GetPRObjections(ctx context.Context, owner, repo string, prNumber int) ([]Objection, error) {
+	c.objectionCalls++
+	return c.objections[prNumber], nil
+}
+
+func TestObjectionEnricher(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	client := &fakeObjectionClient{
+		fakeReviewClient: fakeReviewClient{
+			prs:       map[string]int{"aaa": 1, "bbb": 2},
+			approvals: map[int][]Review{1: {newTestReview("alice", now)}, 2: {newTestReview("alice", now)}},
+		},
+		objections: map[int][]Objection{
+			1: {{Name: "bob", State: ObjectionChangesRequested}, {Name: "carol", State: ObjectionDismissed}},
+		},
+	}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	pipeline := NewDefaultEnrichmentPipeline(client, repoInfo)
+	pipeline.Use(NewObjectionEnricher(client, repoInfo))
+	lines, err := pipeline.Run(context.Background(), []BlameLine{
+		{CommitHash: "aaa", LineNumber: 1, Author: "dev"},
+		{CommitHash: "aaa", LineNumber: 2, Author: "dev"},
+		{CommitHash: "bbb", LineNumber: 3, Author: "dev"},
+		{CommitHash: "ccc", LineNumber: 4, Author: "dev"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if client.objectionCalls != 2 {
+		t.Errorf("expected 2 objection lookups (cached per PR), got %d", client.objectionCalls)
+	}
+	if len(lines[1].Objections) != 2 || len(lines[2].Objections) != 0 {
+		t.Errorf("expected objections on the lines of #1 only, got %+v", lines)
+	}
+
+	summaries := ObjectionSummaries(lines)
+	expected := []string{"#1: bob requested changes, carol's review was dismissed"}
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("expected %q, got %q", expected, summaries)
+	}
+
+	formatter := NewOutputFormatter(false, false, true)
+	if name := formatter.getHumanAuthorName(lines[0]); name != "alice (objected: bob, carol (dismissed))" {
+		t.Errorf("unexpected author column %q", name)
+	}
+	porcelain := formatter.formatPorcelain(lines[:1])
+	if !strings.Contains(porcelain, "objection bob changes-requested\nobjection carol dismissed\n") {
+		t.Errorf("expected objection fields in porcelain output, got:\n%s", porcelain)
+	}
+	if record := NewAnnotationRecord(lines[0]); len(record.Objections) != 2 {
+		t.Errorf("expected the objections in the annotation record, got %+v", record.Objections)
+	}
+}
@@ -0,0 +1,151 @@
+package reviewblame
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// prMessagePatterns match PR/MR references left in commit messages by the
+// hosting service, most specific first
+var prMessagePatterns = []*regexp.Regexp{
+	// GitHub merge commits: "Merge pull request #123 from owner/branch"
+	regexp.MustCompile(`^Merge pull request #(\d+)`),
+	// GitLab merge commits: "See merge request group/project!123" in the body
+	regexp.MustCompile(`(?m)^See merge request \S*!(\d+)`),
+	// Bitbucket merge commits: "Merged in feature/x (pull request #123)"
+	regexp.MustCompile(`^Merged in \S+ \(pull request #(\d+)\)`),
+	// Gitea and Forgejo merge commits: "Merge pull request 'Title' (#123) from branch into main"
+	regexp.MustCompile(`^Merge pull request '.*' \(#(\d+)\) from `),
+	// Squash merges: "Fix parser (#123)" on GitHub, "Fix parser (!123)" on GitLab
+	regexp.MustCompile(`(?m)\A[^\n]*\([#!](\d+)\)\s*$`),
+}
+
+// ParsePRNumberFromMessage extracts the PR/MR number a commit message refers
+// to, or returns 0 when the message has no recognized reference
+func ParsePRNumberFromMessage(message string) int {
+	for _, pattern := range prMessagePatterns {
+		if match := pattern.FindStringSubmatch(message); match != nil {
+			if number, err := strconv.Atoi(match[1]); err == nil {
+				return number
+			}
+		}
+	}
+	return 0
+}
+
+// FindPRNumberOffline finds the PR/MR that brought a commit into revision
+// (HEAD when empty) using only local history: the commit's own message
+// (squash merges), then the messages of the merge commits that brought it
+// in, oldest first.
+func FindPRNumberOffline(repoRoot, commitHash, revision string) (int, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%B", commitHash)
+	cmd.Dir = repoRoot
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, err
+	}
+	if number := ParsePRNumberFromMessage(string(output)); number > 0 {
+		return number, nil
+	}
+
+	if revision == "" {
+		revision = "HEAD"
+	}
+	cmd = exec.Command("git", "log", "--ancestry-path", "--merges", "--reverse",
+		"--format=%H%x00%B%x1e", commitHash+".."+revision)
+	cmd.Dir = repoRoot
+
+	output, err = cmd.Output()
+	if err != nil {
+		return 0, err
+	}
+	for _, record := range strings.Split(string(output), "\x1e") {
+		mergeHash, message, found := strings.Cut(strings.TrimSpace(record), "\x00")
+		if !found {
+			continue
+		}
+		number := ParsePRNumberFromMessage(message)
+		if number == 0 {
+			continue
+		}
+		// A merge only brought the commit in if its mainline parent did not
+		// already contain it
+		if !isAncestor(repoRoot, commitHash, mergeHash+"^1") {
+			return number, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// ReadCommitMessages reads the full messages of commits in one git call,
+// keyed by commit hash
+func ReadCommitMessages(ctx context.Context, repoRoot string, commitHashes []string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "log", "--no-walk=unsorted", "--stdin", "--format=%H%x00%B%x1e")
+	cmd.Dir = repoRoot
+	cmd.Stdin = strings.NewReader(strings.Join(commitHashes, "\n") + "\n")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	messages := make(map[string]string, len(commitHashes))
+	for _, record := range strings.Split(string(output), "\x1e") {
+		if commitHash, message, found := strings.Cut(strings.TrimSpace(record), "\x00"); found {
+			messages[commitHash] = message
+		}
+	}
+	return messages, nil
+}
+
+// isAncestor reports whether commit is reachable from rev
+func isAncestor(repoRoot, commit, rev string) bool {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", commit, rev)
+	cmd.Dir = repoRoot
+	return cmd.Run() == nil
+}
+
+// OfflinePRLookupEnricher sets the PR/MR number of each line from local
+// commit messages, without any API access
+type OfflinePRLookupEnricher struct {
+	repoRoot string
+	// revision is the annotated revision, whose merges are searched; HEAD
+	// when empty
+	revision string
+	// cache maps commit hash to PR number (0 when none was found)
+	cache map[string]int
+}
+
+// NewOfflinePRLookupEnricher creates the offline PR lookup stage
+func NewOfflinePRLookupEnricher(repoRoot string) *OfflinePRLookupEnricher {
+	return &OfflinePRLookupEnricher{
+		repoRoot: repoRoot,
+		cache:    make(map[string]int),
+	}
+}
+
+// Name implements Enricher
+func (e *OfflinePRLookupEnricher) Name() string {
+	return "offline-pr-lookup"
+}
+
+// Enrich implements Enricher
+func (e *OfflinePRLookupEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		commitHash := lines[i].CommitHash
+		prNumber, exists := e.cache[commitHash]
+		if !exists {
+			// Uncommitted lines and unreadable history simply yield no PR
+			prNumber, _ = FindPRNumberOffline(e.repoRoot, commitHash, e.revision)
+			e.cache[commitHash] = prNumber
+		}
+		if prNumber > 0 && lines[i].PRNumber == 0 {
+			lines[i].PRNumber = prNumber
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePRNumberFromMessage(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected int
+	}{
+		{"Merge pull request #42 from owner/feature\n\nAdd feature", 42},
+		{"Merge branch 'feature' into 'main'\n\nAdd feature\n\nSee merge request group/project!17", 17},
+		{"Merged in feature/parser (pull request #31)\n\nFix parser", 31},
+		{"Merge pull request 'Fix parser' (#44) from feature/parser into main", 44},
+		{"Fix parser crash (#123)", 123},
+		{"Fix parser crash (!56)\n\nDetails", 56},
+		{"Fix parser crash\n\nRelated to (#99)", 0},
+		{"Bump version to 1.2.3", 0},
+		{"Merge branch 'main' into feature", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := ParsePRNumberFromMessage(tt.message); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+// gitCommand runs git in dir and returns its trimmed output
+func gitCommand(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestOfflinePRLookupEnricher(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, dir, "add", "file.txt")
+	}
+
+	write("one\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+	initial := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	write("one\ntwo\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add line two (#7)")
+	squashed := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	gitCommand(t, dir, "checkout", "-q", "-b", "feature")
+	write("one\ntwo\nthree\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add line three")
+	feature := gitCommand(t, dir, "rev-parse", "HEAD")
+	gitCommand(t, dir, "checkout", "-q", "main")
+	gitCommand(t, dir, "merge", "-q", "--no-ff", "-m", "Merge pull request #12 from owner/feature", "feature")
+
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: initial}},
+		{BlameLine: BlameLine{CommitHash: squashed}},
+		{BlameLine: BlameLine{CommitHash: feature}},
+	}
+	if err := NewOfflinePRLookupEnricher(dir).Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if lines[0].PRNumber != 0 || lines[1].PRNumber != 7 || lines[2].PRNumber != 12 {
+		t.Errorf("expected PRs 0, 7, 12, got %d, %d, %d", lines[0].PRNumber, lines[1].PRNumber, lines[2].PRNumber)
+	}
+}
+
+func TestOfflinePipelineAtRevision(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCommand(t, dir, "add", name)
+	}
+
+	write("file.txt", "one\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+	gitCommand(t, dir, "branch", "release")
+
+	// A commit pushed to main directly, tagged before it reaches release
+	write("file.txt", "one\ntwo\n")
+	write("old.txt", "old\n")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add line two")
+	gitCommand(t, dir, "tag", "v1.0.0")
+
+	gitCommand(t, dir, "checkout", "-q", "release")
+	gitCommand(t, dir, "merge", "-q", "--no-ff", "-m", "Merge pull request #20 from owner/main", "main")
+	gitCommand(t, dir, "rm", "-q", "old.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Remove old file")
+
+	annotate := func(revision string) map[string][]int {
+		pipeline, err := newOfflinePipeline(dir, &Config{}, Options{Revision: revision})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, err := annotatePath(context.Background(), dir, dir, revision, BlameOptions{}, pipeline, false)
+		if err != nil {
+			t.Fatalf("annotatePath(context.Background(), %q) failed: %v", revision, err)
+		}
+		prs := make(map[string][]int)
+		for _, line := range lines {
+			prs[line.Filename] = append(prs[line.Filename], line.PRNumber)
+		}
+		return prs
+	}
+
+	// At the tag, the commit was not yet merged and old.txt still existed
+	if prs := annotate("v1.0.0"); fmt.Sprint(prs) != "map[file.txt:[0 0] old.txt:[0]]" {
+		t.Errorf("unexpected PRs at v1.0.0: %v", prs)
+	}
+	if prs := annotate(""); fmt.Sprint(prs) != "map[file.txt:[0 20]]" {
+		t.Errorf("unexpected PRs in the working tree: %v", prs)
+	}
+}
+
+func TestOfflinePipelineMigrations(t *testing.T) {
+	config := &Config{Migrations: []MigrationConfig{{Before: "abc123", Repository: "old/repo"}}}
+	pipeline, err := newOfflinePipeline(t.TempDir(), config, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stages := strings.Join(pipeline.Stages(), ",")
+	if !strings.HasPrefix(stages, "repository-migrations,offline-pr-lookup,") {
+		t.Errorf("expected migrations before the offline lookup, got %s", stages)
+	}
+}
+
+func TestReadCommitMessages(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m", "Initial commit")
+	initial := gitCommand(t, dir, "rev-parse", "HEAD")
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m", "Merge branch 'login' into 'main'\n\nHarden login\n\nSee merge request group/project!45")
+	merge := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	messages, err := ReadCommitMessages(context.Background(), dir, []string{merge, initial})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 2 || messages[initial] != "Initial commit" {
+		t.Errorf("expected both messages, got %q", messages)
+	}
+	if number := ParsePRNumberFromMessage(messages[merge]); number != 45 {
+		t.Errorf("expected MR 45 from the message body, got %d", number)
+	}
+}
@@ -0,0 +1,211 @@
+package reviewblame
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// OwnersFileName is the per-directory owners file name (Chromium/Bazel style)
+const OwnersFileName = "OWNERS"
+
+// maxOwnersIncludeDepth bounds file:// include chains
+const maxOwnersIncludeDepth = 5
+
+// OwnersPerFileRule adds owners for files in the directory matching a glob
+type OwnersPerFileRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// OwnersFile is a parsed OWNERS file
+type OwnersFile struct {
+	Owners []string
+	// Everyone is set by a "*" entry
+	Everyone bool
+	// NoParent is set by "set noparent": owners of parent directories do not apply
+	NoParent bool
+	PerFile  []OwnersPerFileRule
+	// Includes are repository-relative paths of other OWNERS files (file://path)
+	Includes []string
+}
+
+// ParseOwnersFile parses the Chromium OWNERS format: one owner (email or
+// username) per line, "*", "set noparent", "per-file glob=owner[,owner]",
+// "file://path/to/OWNERS" includes and "#" comments
+func ParseOwnersFile(content string) OwnersFile {
+	var owners OwnersFile
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if hash := strings.Index(line, "#"); hash >= 0 {
+			line = line[:hash]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "":
+		case line == "*":
+			owners.Everyone = true
+		case line == "set noparent":
+			owners.NoParent = true
+		case strings.HasPrefix(line, "file://"):
+			owners.Includes = append(owners.Includes, strings.TrimPrefix(strings.TrimPrefix(line, "file://"), "/"))
+		case strings.HasPrefix(line, "per-file "):
+			pattern, list, found := strings.Cut(strings.TrimPrefix(line, "per-file "), "=")
+			if !found {
+				continue
+			}
+			rule := OwnersPerFileRule{Pattern: strings.TrimSpace(pattern)}
+			for _, owner := range strings.Split(list, ",") {
+				if owner = strings.TrimSpace(owner); owner != "" {
+					rule.Owners = append(rule.Owners, owner)
+				}
+			}
+			owners.PerFile = append(owners.PerFile, rule)
+		default:
+			owners.Owners = append(owners.Owners, line)
+		}
+	}
+
+	return owners
+}
+
+// OwnersResolver finds the owners of files as of a given commit
+type OwnersResolver struct {
+	repoRoot string
+	// cache maps "rev:path" to the parsed OWNERS file (nil when absent)
+	cache map[string]*OwnersFile
+}
+
+// NewOwnersResolver creates a resolver reading OWNERS files from git history
+func NewOwnersResolver(repoRoot string) *OwnersResolver {
+	return &OwnersResolver{
+		repoRoot: repoRoot,
+		cache:    make(map[string]*OwnersFile),
+	}
+}
+
+// readOwnersFile reads and parses the OWNERS file at a repository path as of rev
+func (r *OwnersResolver) readOwnersFile(rev, filePath string) *OwnersFile {
+	key := rev + ":" + filePath
+	if owners, exists := r.cache[key]; exists {
+		return owners
+	}
+
+	var owners *OwnersFile
+	cmd := exec.Command("git", "show", key)
+	cmd.Dir = r.repoRoot
+	if output, err := cmd.Output(); err == nil {
+		parsed := ParseOwnersFile(string(output))
+		owners = &parsed
+	}
+	r.cache[key] = owners
+	return owners
+}
+
+// Owners returns the owners of filename (repository-relative, slash form) as
+// of rev, walking OWNERS files from its directory up to the repository root.
+// found is false when no OWNERS file applies to the file.
+func (r *OwnersResolver) Owners(rev, filename string) (owners []string, everyone bool, found bool) {
+	base := path.Base(filename)
+	for dir := path.Dir(filename); ; dir = path.Dir(dir) {
+		ownersPath := OwnersFileName
+		if dir != "." {
+			ownersPath = dir + "/" + OwnersFileName
+		}
+
+		if file := r.readOwnersFile(rev, ownersPath); file != nil {
+			found = true
+			collected, all := r.collect(rev, *file, base, 0)
+			owners = append(owners, collected...)
+			everyone = everyone || all
+			if file.NoParent {
+				break
+			}
+		}
+
+		if dir == "." {
+			break
+		}
+	}
+	return owners, everyone, found
+}
+
+// collect returns the owners an OWNERS file grants for a file with the given base name
+func (r *OwnersResolver) collect(rev string, file OwnersFile, base string, depth int) ([]string, bool) {
+	owners := append([]string(nil), file.Owners...)
+	everyone := file.Everyone
+
+	for _, rule := range file.PerFile {
+		if matched, _ := path.Match(rule.Pattern, base); matched {
+			for _, owner := range rule.Owners {
+				if owner == "*" {
+					everyone = true
+				} else {
+					owners = append(owners, owner)
+				}
+			}
+		}
+	}
+
+	if depth < maxOwnersIncludeDepth {
+		for _, include := range file.Includes {
+			if included := r.readOwnersFile(rev, include); included != nil {
+				more, all := r.collect(rev, *included, base, depth+1)
+				owners = append(owners, more...)
+				everyone = everyone || all
+			}
+		}
+	}
+
+	return owners, everyone
+}
+
+// isOwner reports whether a user, identified by login or email, is in owners
+func isOwner(owners []string, login, email string) bool {
+	for _, owner := range owners {
+		owner = strings.TrimPrefix(owner, "@")
+		if (login != "" && strings.EqualFold(owner, login)) || (email != "" && strings.EqualFold(owner, email)) {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnersEnricher records whether each line's approver owned the file's
+// directory, according to the OWNERS files at the line's commit
+type OwnersEnricher struct {
+	resolver *OwnersResolver
+}
+
+// NewOwnersEnricher creates the OWNERS stage
+func NewOwnersEnricher(repoRoot string) *OwnersEnricher {
+	return &OwnersEnricher{resolver: NewOwnersResolver(repoRoot)}
+}
+
+// Name implements Enricher
+func (e *OwnersEnricher) Name() string {
+	return "owners"
+}
+
+// Enrich implements Enricher
+func (e *OwnersEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		line := &lines[i]
+		if line.Approver == "" {
+			continue
+		}
+
+		owners, everyone, found := e.resolver.Owners(line.CommitHash, line.Filename)
+		if !found {
+			continue
+		}
+		approverIsOwner := everyone || isOwner(owners, line.Approver, line.ApproverEmail)
+		line.ApproverIsOwner = &approverIsOwner
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseOwnersFile(t *testing.T) {
+	content := `# Owners of the payments module
+alice@example.com
+bob  # team lead
+set noparent
+per-file *.sql=dba@example.com, carol
+per-file BUILD=*
+file://build/OWNERS
+`
+	owners := ParseOwnersFile(content)
+
+	if !reflect.DeepEqual(owners.Owners, []string{"alice@example.com", "bob"}) {
+		t.Errorf("unexpected owners %v", owners.Owners)
+	}
+	if !owners.NoParent || owners.Everyone {
+		t.Errorf("expected noparent without everyone, got %+v", owners)
+	}
+	if len(owners.PerFile) != 2 || owners.PerFile[0].Pattern != "*.sql" ||
+		!reflect.DeepEqual(owners.PerFile[0].Owners, []string{"dba@example.com", "carol"}) {
+		t.Errorf("unexpected per-file rules %+v", owners.PerFile)
+	}
+	if !reflect.DeepEqual(owners.Includes, []string{"build/OWNERS"}) {
+		t.Errorf("unexpected includes %v", owners.Includes)
+	}
+}
+
+func TestOwnersEnricher(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+
+	files := map[string]string{
+		"OWNERS":           "root-owner\n",
+		"lib/OWNERS":       "lib-owner@example.com\nper-file *.sql=dba\n",
+		"secure/OWNERS":    "set noparent\nfile://shared/OWNERS\n",
+		"shared/OWNERS":    "security-owner\n",
+		"lib/code.go":      "package lib\n",
+		"lib/schema.sql":   "CREATE TABLE t;\n",
+		"secure/crypto.go": "package secure\n",
+		"docs/readme.txt":  "docs\n",
+	}
+	for name, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add owners")
+	commit := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	// Later ownership changes must not affect lines from the earlier commit
+	if err := os.WriteFile(filepath.Join(dir, "lib", "OWNERS"), []byte("someone-else\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "commit", "-q", "-am", "Change owners")
+
+	line := func(filename, approver, email string) BlameLineWithApproval {
+		return BlameLineWithApproval{
+			BlameLine:     BlameLine{CommitHash: commit, Filename: filename},
+			Approver:      approver,
+			ApproverEmail: email,
+		}
+	}
+	lines := []BlameLineWithApproval{
+		line("lib/code.go", "lib-owner", "lib-owner@example.com"),
+		line("lib/code.go", "root-owner", ""),
+		line("lib/code.go", "dba", ""),
+		line("lib/schema.sql", "dba", ""),
+		line("secure/crypto.go", "root-owner", ""),
+		line("secure/crypto.go", "security-owner", ""),
+		line("docs/readme.txt", "random", ""),
+		line("docs/readme.txt", "", ""),
+	}
+	if err := NewOwnersEnricher(dir).Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{true, true, false, true, false, true, false, nil}
+	for i, want := range expected {
+		got := lines[i].ApproverIsOwner
+		if want == nil {
+			if got != nil {
+				t.Errorf("line %d: expected no owner check, got %v", i, *got)
+			}
+			continue
+		}
+		if got == nil || *got != want.(bool) {
+			t.Errorf("line %d (%s by %s): expected %v, got %v", i, lines[i].Filename, lines[i].Approver, want, got)
+		}
+	}
+}
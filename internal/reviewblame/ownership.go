@@ -0,0 +1,246 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Groupings of the stats subcommand
+const (
+	StatsByTotal = "total"
+	StatsByFile  = "file"
+	StatsByDir   = "dir"
+)
+
+// ApproverShare is the number of surviving lines an approver approved
+type ApproverShare struct {
+	Approver string  `json:"approver"`
+	Lines    int     `json:"lines"`
+	Percent  float64 `json:"percent"`
+}
+
+// PRShare is the number of surviving lines a PR/MR introduced
+type PRShare struct {
+	Repository string  `json:"repository,omitempty"`
+	Number     int     `json:"number"`
+	Title      string  `json:"title,omitempty"`
+	Lines      int     `json:"lines"`
+	Percent    float64 `json:"percent"`
+}
+
+// Ref returns "#42", or "owner/name#42" for a PR/MR of a migrated repository
+func (p PRShare) Ref() string {
+	return fmt.Sprintf("%s#%d", p.Repository, p.Number)
+}
+
+// OwnershipStats summarizes who approved the lines of a file, a directory or
+// the whole annotated tree
+type OwnershipStats struct {
+	Path            string          `json:"path"`
+	TotalLines      int             `json:"total_lines"`
+	UnreviewedLines int             `json:"unreviewed_lines"`
+	Approvers       []ApproverShare `json:"approvers"`
+	TopPRs          []PRShare       `json:"top_prs"`
+}
+
+// OwnershipReport is the result of the stats subcommand: the stats of the
+// annotated tree, and of each file or directory when grouped
+type OwnershipReport struct {
+	Total  OwnershipStats   `json:"total"`
+	Groups []OwnershipStats `json:"groups,omitempty"`
+}
+
+// BuildOwnershipReport aggregates annotated lines into approver shares and
+// the top PRs/MRs by surviving lines, keeping up to top PRs/MRs (all when 0).
+// by is StatsByTotal, StatsByFile or StatsByDir.
+func BuildOwnershipReport(scope string, lines []BlameLineWithApproval, by string, top int) OwnershipReport {
+	report := OwnershipReport{Total: buildOwnershipStats(scope, lines, top)}
+	if by == StatsByTotal {
+		return report
+	}
+
+	groups := make(map[string][]BlameLineWithApproval)
+	for _, line := range lines {
+		key := line.Filename
+		if by == StatsByDir {
+			key = path.Dir(line.Filename)
+		}
+		groups[key] = append(groups[key], line)
+	}
+	for key, groupLines := range groups {
+		report.Groups = append(report.Groups, buildOwnershipStats(key, groupLines, top))
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Path < report.Groups[j].Path
+	})
+	return report
+}
+
+// buildOwnershipStats counts the lines of one file, directory or tree
+func buildOwnershipStats(name string, lines []BlameLineWithApproval, top int) OwnershipStats {
+	stats := OwnershipStats{Path: name, TotalLines: len(lines), Approvers: []ApproverShare{}, TopPRs: []PRShare{}}
+	approvers := make(map[string]int)
+	prs := make(map[string]*PRShare)
+	for _, line := range lines {
+		switch {
+		case line.Approver == "":
+			stats.UnreviewedLines++
+		case line.ApproverTeam != "":
+			// With -show-team, approvals count for the approver's team
+			approvers[line.ApproverTeam]++
+		default:
+			approvers[line.Approver]++
+		}
+		if line.PRNumber == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%s#%d", line.Repository, line.PRNumber)
+		pr := prs[key]
+		if pr == nil {
+			pr = &PRShare{Repository: line.Repository, Number: line.PRNumber, Title: line.PRTitle}
+			prs[key] = pr
+		}
+		pr.Lines++
+	}
+
+	for _, count := range topCounts(approvers, 0) {
+		stats.Approvers = append(stats.Approvers, ApproverShare{
+			Approver: count.Name,
+			Lines:    count.Lines,
+			Percent:  percentOf(count.Lines, stats.TotalLines),
+		})
+	}
+	for _, pr := range prs {
+		pr.Percent = percentOf(pr.Lines, stats.TotalLines)
+		stats.TopPRs = append(stats.TopPRs, *pr)
+	}
+	sort.Slice(stats.TopPRs, func(i, j int) bool {
+		a, b := stats.TopPRs[i], stats.TopPRs[j]
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		return a.Number < b.Number
+	})
+	if top > 0 && len(stats.TopPRs) > top {
+		stats.TopPRs = stats.TopPRs[:top]
+	}
+	return stats
+}
+
+// percentOf returns count as a percentage of total (0-100)
+func percentOf(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) * 100 / float64(total)
+}
+
+// Text renders the stats of the tree followed by those of each group
+func (r OwnershipReport) Text() string {
+	var b strings.Builder
+	r.Total.writeText(&b)
+	for _, group := range r.Groups {
+		b.WriteString("\n")
+		group.writeText(&b)
+	}
+	return b.String()
+}
+
+// writeText renders the stats as a heading and approver and PR/MR tables
+func (s OwnershipStats) writeText(b *strings.Builder) {
+	fmt.Fprintf(b, "%s: %d lines, %d unreviewed (%.1f%%)\n", s.Path, s.TotalLines,
+		s.UnreviewedLines, percentOf(s.UnreviewedLines, s.TotalLines))
+
+	if len(s.Approvers) > 0 {
+		width := 0
+		for _, approver := range s.Approvers {
+			width = max(width, len(approver.Approver))
+		}
+		b.WriteString("  Approvers:\n")
+		for _, approver := range s.Approvers {
+			fmt.Fprintf(b, "    %-*s  %6d  %5.1f%%\n", width, approver.Approver, approver.Lines, approver.Percent)
+		}
+	}
+
+	if len(s.TopPRs) > 0 {
+		width := 0
+		for _, pr := range s.TopPRs {
+			width = max(width, len(pr.Ref()))
+		}
+		b.WriteString("  Top PRs/MRs:\n")
+		for _, pr := range s.TopPRs {
+			fmt.Fprintf(b, "    %-*s  %6d  %5.1f%%  %s\n", width, pr.Ref(), pr.Lines, pr.Percent, pr.Title)
+		}
+	}
+}
+
+// runStats implements the stats subcommand
+func runStats(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	by := flags.String("by", StatsByTotal, "Also report each file or directory: total, file or dir")
+	top := flags.Int("top", 10, "Number of PRs/MRs to list by surviving lines (0 for all)")
+	format := flags.String("format", "text", "Report format: text or json")
+	configPath := flags.String("config", "", "Path to the config file")
+	includeVendored := flags.Bool("include-vendored", false, "Include vendored files")
+	offline := flags.Bool("offline", false, "Find PRs/MRs from merge commits without API calls; approvers are not known")
+	showTeam := flags.Bool("show-team", false, "Count approvals for the approver's team instead of the approver")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+
+	if *by != StatsByTotal && *by != StatsByFile && *by != StatsByDir {
+		return fmt.Errorf("unsupported stats grouping %q (expected total, file or dir)", *by)
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported stats format %q (expected text or json)", *format)
+	}
+	if *top < 0 {
+		return fmt.Errorf("-top must not be negative")
+	}
+
+	target := "."
+	if flags.NArg() > 0 {
+		target = flags.Arg(0)
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(target, *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{Offline: *offline, ShowTeam: *showTeam}, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+
+	lines, err := annotatePath(ctx, repoRoot, target, "", BlameOptions{}, pipeline, *includeVendored)
+	if err != nil {
+		return err
+	}
+
+	report := BuildOwnershipReport(displayPath(repoRoot, target), lines, *by, *top)
+	if *format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(report.Text())
+	return nil
+}
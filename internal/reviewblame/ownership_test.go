@@ -0,0 +1,54 @@
+package reviewblame
+
+import "testing"
+
+func TestBuildOwnershipReport(t *testing.T) {
+	line := func(file, approver string, pr int, title string) BlameLineWithApproval {
+		return BlameLineWithApproval{BlameLine: BlameLine{Filename: file}, Approver: approver, PRNumber: pr, PRTitle: title}
+	}
+	lines := []BlameLineWithApproval{
+		line("src/b.go", "alice", 42, "Add parser"),
+		line("src/b.go", "alice", 42, "Add parser"),
+		line("src/b.go", "bob", 7, "Fix typo"),
+		line("src/b.go", "", 9, "Unreviewed change"),
+		line("cmd/a.go", "bob", 7, "Fix typo"),
+		line("cmd/a.go", "", 0, ""),
+	}
+
+	report := BuildOwnershipReport("src", lines, StatsByTotal, 2)
+	if report.Total.TotalLines != 6 || report.Total.UnreviewedLines != 2 || report.Groups != nil {
+		t.Errorf("unexpected report %+v", report)
+	}
+	wantText := "src: 6 lines, 2 unreviewed (33.3%)\n" +
+		"  Approvers:\n" +
+		"    alice       2   33.3%\n" +
+		"    bob         2   33.3%\n" +
+		"  Top PRs/MRs:\n" +
+		"    #7        2   33.3%  Fix typo\n" +
+		"    #42       2   33.3%  Add parser\n"
+	if got := report.Text(); got != wantText {
+		t.Errorf("got:\n%s\nwant:\n%s", got, wantText)
+	}
+
+	tests := []struct {
+		by    string
+		paths []string
+	}{
+		{StatsByFile, []string{"cmd/a.go", "src/b.go"}},
+		{StatsByDir, []string{"cmd", "src"}},
+	}
+	for _, tt := range tests {
+		report := BuildOwnershipReport("src", lines, tt.by, 0)
+		if len(report.Groups) != len(tt.paths) {
+			t.Fatalf("-by %s: expected %d groups, got %+v", tt.by, len(tt.paths), report.Groups)
+		}
+		for i, group := range report.Groups {
+			if group.Path != tt.paths[i] {
+				t.Errorf("-by %s: group %d is %q, want %q", tt.by, i, group.Path, tt.paths[i])
+			}
+		}
+		if b := report.Groups[1]; b.TotalLines != 4 || b.UnreviewedLines != 1 || len(b.TopPRs) != 3 || b.Approvers[0].Approver != "alice" || b.Approvers[0].Percent != 50 {
+			t.Errorf("-by %s: unexpected stats %+v", tt.by, b)
+		}
+	}
+}
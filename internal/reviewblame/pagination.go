@@ -0,0 +1,119 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// listPageSize is the page size requested from list endpoints, the maximum
+// both GitHub and GitLab allow
+const listPageSize = 100
+
+// withPageSize returns apiURL with a per_page parameter of listPageSize,
+// unless it already sets one
+func withPageSize(apiURL string) string {
+	parsed, err := url.Parse(apiURL)
+	if err != nil || parsed.Query().Has("per_page") {
+		return apiURL
+	}
+	query := parsed.Query()
+	query.Set("per_page", strconv.Itoa(listPageSize))
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// nextPageLink returns the rel="next" URL of a Link header, or "" on the
+// last page
+func nextPageLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(link), ";")
+		if !found || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+	return ""
+}
+
+// decodeJSONPage streams the elements of one page of a list, reporting
+// whether element stopped decoding early so no further pages are needed
+func decodeJSONPage(resp *http.Response, element func(dec *json.Decoder) error) (stopped bool, err error) {
+	err = decodeJSONArray(json.NewDecoder(newResponseReader(resp.Body)), func(dec *json.Decoder) error {
+		err := element(dec)
+		if errors.Is(err, errStopDecoding) {
+			stopped = true
+		}
+		return err
+	})
+	return stopped, err
+}
+
+// listPages streams the elements of every page of a GitHub list endpoint,
+// following the Link header. element may return errStopDecoding to skip the
+// remaining elements and pages.
+func (c *GitHubClient) listPages(ctx context.Context, apiURL string, element func(dec *json.Decoder) error) error {
+	for pageURL := withPageSize(apiURL); pageURL != ""; {
+		resp, err := c.makeRequest(ctx, "GET", pageURL)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+		}
+		stopped, err := decodeJSONPage(resp, element)
+		resp.Body.Close()
+		if err != nil || stopped {
+			return err
+		}
+
+		pageURL = nextPageLink(resp.Header.Get("Link"))
+	}
+	return nil
+}
+
+// listPages streams the elements of every page of a GitLab list endpoint,
+// requesting the page named by the X-Next-Page header until it is empty.
+// element may return errStopDecoding to skip the remaining elements and pages.
+func (c *GitLabClient) listPages(ctx context.Context, apiURL string, element func(dec *json.Decoder) error) error {
+	parsed, err := url.Parse(withPageSize(apiURL))
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := c.makeRequest(ctx, "GET", parsed.String())
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("GitLab API error: %d %s", resp.StatusCode, resp.Status)
+		}
+		stopped, err := decodeJSONPage(resp, element)
+		resp.Body.Close()
+		if err != nil || stopped {
+			return err
+		}
+
+		nextPage := resp.Header.Get("X-Next-Page")
+		if nextPage == "" {
+			return nil
+		}
+		query := parsed.Query()
+		query.Set("page", nextPage)
+		parsed.RawQuery = query.Encode()
+	}
+}
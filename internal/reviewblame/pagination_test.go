@@ -0,0 +1,153 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNextPageLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "next and last",
+			header: `<https://api.github.com/repositories/1/pulls/2/reviews?page=2>; rel="next", <https://api.github.com/repositories/1/pulls/2/reviews?page=5>; rel="last"`,
+			want:   "https://api.github.com/repositories/1/pulls/2/reviews?page=2",
+		},
+		{
+			name:   "last page",
+			header: `<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=4>; rel="prev"`,
+			want:   "",
+		},
+		{name: "no header", header: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPageLink(tt.header); got != tt.want {
+				t.Errorf("nextPageLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPageSize(t *testing.T) {
+	if got := withPageSize("https://gitlab.com/api/v4/projects/a%2Fb/merge_requests/1/notes?sort=asc"); got != "https://gitlab.com/api/v4/projects/a%2Fb/merge_requests/1/notes?per_page=100&sort=asc" {
+		t.Errorf("unexpected URL %q", got)
+	}
+	if got := withPageSize("https://api.github.com/x?per_page=10"); got != "https://api.github.com/x?per_page=10" {
+		t.Errorf("expected an explicit page size to be kept, got %q", got)
+	}
+}
+
+func TestGitHubGetPRApprovalsPaginates(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("per_page") != "100" {
+			t.Errorf("expected per_page=100, got %q", r.URL.RawQuery)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var reviews []map[string]interface{}
+		if page == 0 {
+			for i := 0; i < listPageSize; i++ {
+				reviews = append(reviews, map[string]interface{}{"state": "COMMENTED", "user": map[string]string{"login": "commenter"}})
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?per_page=100&page=2>; rel="next", <http://%s%s?per_page=100&page=2>; rel="last"`, r.Host, r.URL.Path, r.Host, r.URL.Path))
+		} else {
+			reviews = append(reviews, map[string]interface{}{"state": "APPROVED", "user": map[string]string{"login": "late-approver"}})
+		}
+		json.NewEncoder(w).Encode(reviews)
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	approvals, err := client.GetPRApprovals(context.Background(), "owner", "repo", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+	if len(approvals) != 1 || approvals[0].User.Login != "late-approver" {
+		t.Errorf("expected the approval on the second page, got %+v", approvals)
+	}
+}
+
+func TestGitLabListPages(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/owner%2Frepo/merge_requests/1/discussions" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+		}
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		switch page {
+		case "":
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"id": "d1", "notes": []}]`))
+		case "2":
+			w.Header().Set("X-Next-Page", "3")
+			w.Write([]byte(`[{"id": "d2", "notes": []}]`))
+		default:
+			w.Header().Set("X-Next-Page", "")
+			w.Write([]byte(`[{"id": "d3", "notes": []}]`))
+		}
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	discussions, err := client.ListMergeRequestDiscussions(context.Background(), "owner", "repo", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pages) != 3 || pages[2] != "3" {
+		t.Errorf("expected pages 1 to 3, got %q", pages)
+	}
+	if len(discussions) != 3 || discussions[2].ID != "d3" {
+		t.Errorf("expected 3 discussions, got %+v", discussions)
+	}
+}
+
+func TestListPagesStopsEarly(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Next-Page", "2")
+		w.Write([]byte(`[1, 2, 3]`))
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	var seen []int
+	err := client.listPages(context.Background(), server.URL+"/items", func(dec *json.Decoder) error {
+		var item int
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		seen = append(seen, item)
+		if item == 2 {
+			return errStopDecoding
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 1 || len(seen) != 2 {
+		t.Errorf("expected to stop after item 2 of the first page, got %v in %d requests", seen, requests)
+	}
+}
@@ -0,0 +1,171 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// PluginCommandPrefix is prepended to plugin names without a path, so
+// "corp-review" runs git-review-blame-provider-corp-review from PATH, as git
+// runs git-credential-<name> for credential helpers
+const PluginCommandPrefix = "git-review-blame-provider-"
+
+// pluginCommand returns the executable of a plugin name or path. Paths are
+// only accepted from the user config file (see loadRepositoryConfig).
+func pluginCommand(plugin string) string {
+	if strings.ContainsAny(plugin, `/\`) {
+		return plugin
+	}
+	return PluginCommandPrefix + plugin
+}
+
+// pluginRequest is written to the plugin's stdin. Operations are:
+//
+//	find-pr    {"host", "owner", "repo", "commit"} -> {"pr": {...} or null}
+//	approvals  {"host", "owner", "repo", "pr"}     -> {"approvals": [...]}
+type pluginRequest struct {
+	Host   string `json:"host"`
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Commit string `json:"commit,omitempty"`
+	PR     int    `json:"pr,omitempty"`
+}
+
+// pluginPR is a PR/MR in a plugin response
+type pluginPR struct {
+	Number   int        `json:"number"`
+	Title    string     `json:"title"`
+	Body     string     `json:"body"`
+	Author   string     `json:"author"`
+	Branch   string     `json:"branch"`
+	Labels   []string   `json:"labels"`
+	MergedAt *time.Time `json:"merged_at"`
+}
+
+// pluginApproval is an approval in a plugin response
+type pluginApproval struct {
+	Login       string     `json:"login"`
+	Email       string     `json:"email"`
+	AvatarURL   string     `json:"avatar_url"`
+	URL         string     `json:"url"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+}
+
+// pluginResponse is read from the plugin's stdout
+type pluginResponse struct {
+	PR        *pluginPR        `json:"pr"`
+	Approvals []pluginApproval `json:"approvals"`
+	// Error reports a failed lookup, as does a non-zero exit status
+	Error string `json:"error"`
+}
+
+// PluginClient resolves PRs/MRs and approvals with an external executable
+// speaking JSON over stdin and stdout, for review systems without a built-in
+// client. The plugin runs once per lookup, in the repository, with the
+// operation as its argument and the environment of this process, so it can
+// read its own credentials.
+type PluginClient struct {
+	command  string
+	repoRoot string
+	host     string
+}
+
+// newPluginClientForRepo creates a client running the plugin of repoInfo
+func newPluginClientForRepo(repoRoot string, repoInfo *RepoInfo) *PluginClient {
+	return &PluginClient{command: pluginCommand(repoInfo.Plugin), repoRoot: repoRoot, host: repoInfo.Host}
+}
+
+// call runs the plugin with an operation and decodes its response
+func (c *PluginClient) call(ctx context.Context, operation string, request pluginRequest) (*pluginResponse, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, c.command, operation)
+	cmd.Dir = c.repoRoot
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return nil, fmt.Errorf("provider plugin %s %s: %s", c.command, operation, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("provider plugin %s %s: %w", c.command, operation, err)
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("provider plugin %s %s: invalid response: %w", c.command, operation, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("provider plugin %s %s: %s", c.command, operation, response.Error)
+	}
+	return &response, nil
+}
+
+// FindPRByCommit asks the plugin for the PR/MR of a commit
+func (c *PluginClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	response, err := c.call(ctx, "find-pr", pluginRequest{Host: c.host, Owner: owner, Repo: repo, Commit: commitHash})
+	if err != nil || response.PR == nil || response.PR.Number == 0 {
+		return nil, err
+	}
+	pr := &PullRequest{
+		Number:   response.PR.Number,
+		Title:    response.PR.Title,
+		Body:     response.PR.Body,
+		MergedAt: response.PR.MergedAt,
+	}
+	pr.User.Login = response.PR.Author
+	pr.Head.Ref = response.PR.Branch
+	for _, label := range response.PR.Labels {
+		pr.Labels = append(pr.Labels, PRLabel{Name: label})
+	}
+	return pr, nil
+}
+
+// GetPRApprovals asks the plugin for the approvals of a PR/MR
+func (c *PluginClient) GetPRApprovals(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	response, err := c.call(ctx, "approvals", pluginRequest{Host: c.host, Owner: owner, Repo: repo, PR: prNumber})
+	if err != nil {
+		return nil, err
+	}
+	var reviews []Review
+	for _, approval := range response.Approvals {
+		review := Review{State: "APPROVED", SubmittedAt: approval.SubmittedAt, HTMLURL: approval.URL}
+		review.User.Login = approval.Login
+		review.User.Email = approval.Email
+		review.User.AvatarURL = approval.AvatarURL
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
+
+// GetPRApprovalInfo gets complete approval information for a commit
+func (c *PluginClient) GetPRApprovalInfo(ctx context.Context, owner, repo, commitHash string) (*PRApprovalInfo, error) {
+	pr, err := c.FindPRByCommit(ctx, owner, repo, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr == nil {
+		return nil, fmt.Errorf("no pull request found for commit %s", commitHash)
+	}
+
+	approvals, err := c.GetPRApprovals(ctx, owner, repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRApprovalInfo{
+		PR:        *pr,
+		Approvers: approvals,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakePlugin writes a provider plugin answering find-pr and approvals
+// with the given responses and recording its stdin
+func writeFakePlugin(t *testing.T, findPR, approvals string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	stdin := filepath.Join(dir, "stdin")
+	script := "#!/bin/sh\ncat > " + stdin + "\ncase \"$1\" in\n" +
+		"find-pr) cat <<'EOF'\n" + findPR + "\nEOF\n;;\n" +
+		"approvals) cat <<'EOF'\n" + approvals + "\nEOF\n;;\n" +
+		"*) echo \"unknown operation $1\" >&2; exit 2;;\nesac\n"
+	path := filepath.Join(dir, "git-review-blame-provider-fake")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, stdin
+}
+
+func TestPluginClient(t *testing.T) {
+	path, stdin := writeFakePlugin(t,
+		`{"pr": {"number": 42, "title": "Add cache", "author": "carol", "branch": "cache", "labels": ["perf"], "merged_at": "2024-05-01T12:00:00Z"}}`,
+		`{"approvals": [{"login": "alice", "email": "alice@example.com", "url": "https://review.example.com/42#1", "submitted_at": "2024-05-01T10:00:00Z"}]}`)
+	client, err := (&ClientFactory{}).CreateClient(&RepoInfo{Type: RepositoryTypePlugin, Host: "review.example.com", Plugin: path}, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	pr, err := client.FindPRByCommit(ctx, "team", "service", "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.Number != 42 || pr.Title != "Add cache" || pr.User.Login != "carol" || pr.Head.Ref != "cache" || pr.MergedAt == nil || len(pr.LabelNames()) != 1 {
+		t.Errorf("unexpected PR %+v", pr)
+	}
+	request, _ := os.ReadFile(stdin)
+	if want := `{"host":"review.example.com","owner":"team","repo":"service","commit":"abc123"}`; string(request) != want {
+		t.Errorf("expected request %s, got %s", want, request)
+	}
+
+	approvals, err := client.GetPRApprovals(ctx, "team", "service", 42)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(approvals) != 1 || approvals[0].User.Login != "alice" || approvals[0].User.Email != "alice@example.com" || approvals[0].HTMLURL == "" || approvals[0].State != "APPROVED" {
+		t.Errorf("unexpected approvals %+v", approvals)
+	}
+	request, _ = os.ReadFile(stdin)
+	if !strings.Contains(string(request), `"pr":42`) {
+		t.Errorf("expected the PR number in the request, got %s", request)
+	}
+}
+
+func TestPluginClientErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		findPR    string
+		wantPR    bool
+		wantError string
+	}{
+		{"no PR", `{"pr": null}`, false, ""},
+		{"reported error", `{"error": "commit not indexed yet"}`, false, "commit not indexed yet"},
+		{"invalid response", `not json`, false, "invalid response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := writeFakePlugin(t, tt.findPR, `{}`)
+			client := newPluginClientForRepo("", &RepoInfo{Plugin: path})
+			pr, err := client.FindPRByCommit(context.Background(), "team", "service", "abc123")
+			if tt.wantError == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantError != "" && (err == nil || !strings.Contains(err.Error(), tt.wantError)) {
+				t.Errorf("expected error %q, got %v", tt.wantError, err)
+			}
+			if (pr != nil) != tt.wantPR {
+				t.Errorf("unexpected PR %+v", pr)
+			}
+		})
+	}
+
+	// A failing plugin's stderr is the error
+	path, _ := writeFakePlugin(t, `{}`, `{}`)
+	client := newPluginClientForRepo("", &RepoInfo{Plugin: path})
+	if _, err := client.call(context.Background(), "rounds", pluginRequest{}); err == nil || !strings.Contains(err.Error(), "unknown operation rounds") {
+		t.Errorf("expected the plugin's stderr, got %v", err)
+	}
+
+	if _, err := (&ClientFactory{}).CreateClient(&RepoInfo{Type: RepositoryTypePlugin}, "", ""); err != ErrMissingPlugin {
+		t.Errorf("expected ErrMissingPlugin, got %v", err)
+	}
+}
+
+func TestPluginCommand(t *testing.T) {
+	tests := []struct {
+		plugin string
+		want   string
+	}{
+		{"corp-review", "git-review-blame-provider-corp-review"},
+		{"/opt/review/plugin", "/opt/review/plugin"},
+		{"./bin/plugin", "./bin/plugin"},
+	}
+	for _, tt := range tests {
+		if got := pluginCommand(tt.plugin); got != tt.want {
+			t.Errorf("pluginCommand(%q) = %q, want %q", tt.plugin, got, tt.want)
+		}
+	}
+}
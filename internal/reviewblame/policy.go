@@ -0,0 +1,259 @@
+package reviewblame
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultPolicyQuery is the Rego query evaluated when the config does not set one
+const DefaultPolicyQuery = "data.git_review_blame.violations"
+
+// OPABinaryEnv names the environment variable overriding the opa executable
+const OPABinaryEnv = "GIT_REVIEW_BLAME_OPA"
+
+// PolicyConfig delegates line compliance decisions to a Rego policy
+type PolicyConfig struct {
+	// Files are the .rego files (or directories) passed to opa as data
+	Files []string `json:"files"`
+	// Query must evaluate to a collection of violations (see PolicyViolation)
+	Query string `json:"query"`
+	// OPABinary is the opa executable to run: $GIT_REVIEW_BLAME_OPA, or "opa"
+	// from PATH. It is never read from a config file, which a repository can
+	// commit.
+	OPABinary string `json:"-"`
+}
+
+// resolveFiles makes the policy files of a config file relative to root,
+// the directory holding it, and rejects files outside root, so a
+// repository's config cannot feed arbitrary files on the machine to opa
+func (p *PolicyConfig) resolveFiles(root string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	for i, file := range p.Files {
+		if filepath.IsAbs(file) {
+			return fmt.Errorf("policy file %s must be relative to %s", file, root)
+		}
+		path := filepath.Join(root, file)
+		if !isWithin(root, path) {
+			return fmt.Errorf("policy file %s is outside %s", file, root)
+		}
+		// A symlink committed in the repository must not lead out of it either
+		if resolved, err := filepath.EvalSymlinks(path); err == nil && !isWithin(realRoot, resolved) {
+			return fmt.Errorf("policy file %s links outside %s", file, root)
+		}
+		p.Files[i] = path
+	}
+	return nil
+}
+
+// isWithin reports whether path is root or lies below it
+func isWithin(root, path string) bool {
+	relPath, err := filepath.Rel(root, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// AnnotationRecord is the JSON representation of an annotated line, used as policy input
+type AnnotationRecord struct {
+	File          string     `json:"file"`
+	Line          int        `json:"line"`
+	Commit        string     `json:"commit"`
+	Author        string     `json:"author"`
+	AuthorEmail   string     `json:"author_email"`
+	AuthorTime    string     `json:"author_time"`
+	PRNumber      int        `json:"pr_number"`
+	Approver      string     `json:"approver"`
+	ApproverEmail string     `json:"approver_email"`
+	ApprovalTime  *time.Time `json:"approval_time"`
+	// ReviewState is one of the ReviewState values, e.g. "no-pr"
+	ReviewState string `json:"review_state,omitempty"`
+	// Approvers lists every approver of the PR/MR, oldest first
+	Approvers []LineApprover `json:"approvers,omitempty"`
+	// ApproverTeam is only set when approver teams were resolved
+	ApproverTeam string `json:"approver_team,omitempty"`
+	Content      string `json:"content"`
+	// ReviewThreads and UnresolvedThreads are only set when thread status was fetched
+	ReviewThreads     *int `json:"review_threads,omitempty"`
+	UnresolvedThreads *int `json:"unresolved_threads,omitempty"`
+	// ReviewRounds is only set when review rounds were fetched
+	ReviewRounds int `json:"review_rounds,omitempty"`
+	// UnmetApprovalRules is only set when approval rules were fetched and
+	// some were not met
+	UnmetApprovalRules []string `json:"unmet_approval_rules,omitempty"`
+	// Objections are only set when objections were fetched and there were some
+	Objections []Objection `json:"objections,omitempty"`
+	// Repository is set for commits imported from another repository
+	Repository string `json:"repository,omitempty"`
+	// PRTitle, LinkedIssues, PRLabels and the tracker fields describe the line's PR/MR
+	PRTitle      string   `json:"pr_title,omitempty"`
+	LinkedIssues []string `json:"linked_issues,omitempty"`
+	PRLabels     []string `json:"pr_labels,omitempty"`
+	TrackerKey   string   `json:"tracker_key,omitempty"`
+	TrackerURL   string   `json:"tracker_url,omitempty"`
+	// ApproverIsOwner is only set when OWNERS files were checked
+	ApproverIsOwner *bool `json:"approver_is_owner,omitempty"`
+	// Backport is only set when the line's PR/MR is a detected backport
+	Backport *BackportRecord `json:"backport,omitempty"`
+	// OriginalLine and OriginalFile locate the line in the commit that
+	// introduced it
+	OriginalLine int    `json:"original_line,omitempty"`
+	OriginalFile string `json:"original_file,omitempty"`
+}
+
+// BackportRecord is the JSON representation of a backport's original PR/MR
+type BackportRecord struct {
+	OriginalPRNumber int        `json:"original_pr_number"`
+	OriginalApprover string     `json:"original_approver"`
+	ApprovalTime     *time.Time `json:"original_approval_time"`
+}
+
+// NewAnnotationRecord converts an annotated line into its record form
+func NewAnnotationRecord(line BlameLineWithApproval) AnnotationRecord {
+	record := AnnotationRecord{
+		File:            line.Filename,
+		Line:            line.LineNumber,
+		Commit:          line.CommitHash,
+		Author:          line.Author,
+		AuthorEmail:     line.AuthorEmail,
+		AuthorTime:      line.Date,
+		PRNumber:        line.PRNumber,
+		Approver:        line.Approver,
+		ApproverEmail:   line.ApproverEmail,
+		ApprovalTime:    line.ApprovalTime,
+		ReviewState:     line.ReviewState(),
+		Approvers:       line.Approvers,
+		ApproverTeam:    line.ApproverTeam,
+		Content:         line.Content,
+		ReviewRounds:    line.ReviewRounds,
+		Objections:      line.Objections,
+		Repository:      line.Repository,
+		ApproverIsOwner: line.ApproverIsOwner,
+		PRTitle:         line.PRTitle,
+		LinkedIssues:    line.LinkedIssues,
+		PRLabels:        line.PRLabels,
+		TrackerKey:      line.TrackerKey,
+		TrackerURL:      line.TrackerURL,
+		OriginalLine:    line.OriginalLineNumber,
+		OriginalFile:    line.OriginalFilename,
+	}
+	if line.Backport != nil {
+		record.Backport = &BackportRecord{
+			OriginalPRNumber: line.Backport.PRNumber,
+			OriginalApprover: line.Backport.Approver,
+			ApprovalTime:     line.Backport.ApprovalTime,
+		}
+	}
+	if line.Threads != nil {
+		total, unresolved := line.Threads.Total, line.Threads.Unresolved()
+		record.ReviewThreads = &total
+		record.UnresolvedThreads = &unresolved
+	}
+	if line.ApprovalRules != nil {
+		record.UnmetApprovalRules = line.ApprovalRules.Unmet
+	}
+	return record
+}
+
+// PolicyViolation is a line the policy declared non-compliant
+type PolicyViolation struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("%s:%d: %s", v.File, v.Line, v.Message)
+}
+
+// PolicyEvaluator evaluates Rego policies with the opa CLI
+type PolicyEvaluator struct {
+	config PolicyConfig
+}
+
+// NewPolicyEvaluator creates an evaluator, filling in defaults for unset fields
+func NewPolicyEvaluator(config PolicyConfig) *PolicyEvaluator {
+	if config.Query == "" {
+		config.Query = DefaultPolicyQuery
+	}
+	if config.OPABinary == "" {
+		config.OPABinary = os.Getenv(OPABinaryEnv)
+	}
+	if config.OPABinary == "" {
+		config.OPABinary = "opa"
+	}
+	return &PolicyEvaluator{config: config}
+}
+
+// Evaluate runs the policy query with {"lines": [...records]} as input and
+// returns the violations it reports
+func (e *PolicyEvaluator) Evaluate(lines []BlameLineWithApproval) ([]PolicyViolation, error) {
+	if len(e.config.Files) == 0 {
+		return nil, fmt.Errorf("no policy files configured")
+	}
+
+	records := make([]AnnotationRecord, 0, len(lines))
+	for _, line := range lines {
+		records = append(records, NewAnnotationRecord(line))
+	}
+	input, err := json.Marshal(map[string]interface{}{"lines": records})
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, file := range e.config.Files {
+		args = append(args, "--data", file)
+	}
+	args = append(args, e.config.Query)
+
+	cmd := exec.Command(e.config.OPABinary, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("opa eval failed: %s", msg)
+		}
+		return nil, fmt.Errorf("opa eval failed: %w", err)
+	}
+
+	return parseOPAResult(output)
+}
+
+// parseOPAResult extracts violations from `opa eval --format json` output.
+// The query value may be an array or set of violation objects; an undefined
+// result means no violations.
+func parseOPAResult(output []byte) ([]PolicyViolation, error) {
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("could not parse opa output: %w", err)
+	}
+
+	var violations []PolicyViolation
+	for _, r := range result.Result {
+		for _, expr := range r.Expressions {
+			var values []PolicyViolation
+			if err := json.Unmarshal(expr.Value, &values); err != nil {
+				return nil, fmt.Errorf("policy query must return a collection of {file, line, message} objects: %w", err)
+			}
+			violations = append(violations, values...)
+		}
+	}
+
+	return violations, nil
+}
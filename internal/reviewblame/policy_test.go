@@ -0,0 +1,167 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakeOPA writes a script standing in for the opa binary that records
+// its arguments and stdin and prints the given output
+func writeFakeOPA(t *testing.T, output string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat > " + filepath.Join(dir, "stdin") + "\ncat <<'EOF'\n" + output + "\nEOF\n"
+	path := filepath.Join(dir, "opa")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, dir
+}
+
+func TestPolicyEvaluatorEvaluate(t *testing.T) {
+	opa, dir := writeFakeOPA(t, `{"result":[{"expressions":[{"value":[{"file":"main.go","line":2,"message":"unreviewed change to payments"}]}]}]}`)
+
+	evaluator := NewPolicyEvaluator(PolicyConfig{Files: []string{"policy.rego"}, OPABinary: opa})
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{Filename: "main.go", LineNumber: 2, CommitHash: "aaaa"}},
+	}
+
+	violations, err := evaluator.Evaluate(lines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(violations) != 1 || violations[0].String() != "main.go:2: unreviewed change to payments" {
+		t.Errorf("unexpected violations %+v", violations)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if !strings.Contains(string(args), "--data policy.rego "+DefaultPolicyQuery) {
+		t.Errorf("unexpected opa arguments %q", args)
+	}
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	if !strings.Contains(string(stdin), `"lines":[{"file":"main.go","line":2,"commit":"aaaa"`) {
+		t.Errorf("unexpected policy input %s", stdin)
+	}
+}
+
+func TestPolicyEvaluatorErrors(t *testing.T) {
+	if _, err := NewPolicyEvaluator(PolicyConfig{}).Evaluate(nil); err == nil {
+		t.Error("expected error without policy files")
+	}
+
+	evaluator := NewPolicyEvaluator(PolicyConfig{Files: []string{"p.rego"}, OPABinary: filepath.Join(t.TempDir(), "missing-opa")})
+	if _, err := evaluator.Evaluate(nil); err == nil {
+		t.Error("expected error when opa cannot be run")
+	}
+}
+
+func TestParseOPAResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected int
+		wantErr  bool
+	}{
+		{name: "undefined result", output: `{}`, expected: 0},
+		{name: "empty set", output: `{"result":[{"expressions":[{"value":[]}]}]}`, expected: 0},
+		{name: "two violations", output: `{"result":[{"expressions":[{"value":[{"file":"a","line":1,"message":"x"},{"file":"b","line":2,"message":"y"}]}]}]}`, expected: 2},
+		{name: "boolean value", output: `{"result":[{"expressions":[{"value":true}]}]}`, wantErr: true},
+		{name: "invalid JSON", output: `nope`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := parseOPAResult([]byte(tt.output))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(violations) != tt.expected {
+				t.Errorf("expected %d violations, got %d", tt.expected, len(violations))
+			}
+		})
+	}
+}
+
+func TestReportFailuresRequireApproval(t *testing.T) {
+	line := func(number, pr int, approver string) BlameLineWithApproval {
+		return BlameLineWithApproval{
+			BlameLine: BlameLine{CommitHash: "abc12345def", Filename: "main.go", LineNumber: number, Author: "jane"},
+			PRNumber:  pr,
+			Approver:  approver,
+		}
+	}
+	lines := []BlameLineWithApproval{line(1, 1, "bob"), line(2, 2, ""), line(3, 2, ""), line(4, 0, "")}
+	violations := []PolicyViolation{{File: "main.go", Line: 1, Message: "self-approved"}}
+
+	if err := reportFailures(Options{}, lines, nil); err != nil {
+		t.Errorf("expected unapproved lines to pass without -require-approval, got %v", err)
+	}
+	if err := reportFailures(Options{RequireApproval: true}, lines[:1], nil); err != nil {
+		t.Errorf("expected approved lines to pass, got %v", err)
+	}
+
+	err := reportFailures(Options{RequireApproval: true}, lines, nil)
+	if err == nil || !strings.Contains(err.Error(), "3 line(s) were not approved") {
+		t.Errorf("expected 3 unapproved lines, got %v", err)
+	}
+	err = reportFailures(Options{RequireApproval: true}, lines, violations)
+	if err == nil || !strings.Contains(err.Error(), "violate the review policy") || !strings.Contains(err.Error(), "not approved") {
+		t.Errorf("expected both policy and approval failures, got %v", err)
+	}
+}
+
+func TestPolicyEvaluatorBinaryFromEnv(t *testing.T) {
+	opa, _ := writeFakeOPA(t, `{"result":[]}`)
+	t.Setenv(OPABinaryEnv, opa)
+	if evaluator := NewPolicyEvaluator(PolicyConfig{}); evaluator.config.OPABinary != opa {
+		t.Errorf("expected %s, got %s", opa, evaluator.config.OPABinary)
+	}
+
+	// A config file cannot name the binary
+	var config PolicyConfig
+	if err := json.Unmarshal([]byte(`{"opa_binary": "/tmp/evil"}`), &config); err != nil || config.OPABinary != "" {
+		t.Errorf("expected opa_binary to be ignored, got %q, %v", config.OPABinary, err)
+	}
+}
+
+func TestPolicyConfigResolveFiles(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(outside, []byte("secret"), 0600)
+	if err := os.Symlink(outside, filepath.Join(root, "link.rego")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		wantErr bool
+	}{
+		{"policy/review.rego", false},
+		{"policy/../review.rego", false},
+		{"../review.rego", true},
+		{outside, true},
+		{"link.rego", true},
+	}
+	for _, tt := range tests {
+		config := PolicyConfig{Files: []string{tt.file}}
+		err := config.resolveFiles(root)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.file, err)
+			continue
+		}
+		if err == nil && !strings.HasPrefix(config.Files[0], root) {
+			t.Errorf("%s: expected a path under %s, got %s", tt.file, root, config.Files[0])
+		}
+	}
+}
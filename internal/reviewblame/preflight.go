@@ -0,0 +1,160 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AccessError is a token that cannot read the repository, with how to fix it
+type AccessError struct {
+	Problem string
+	Hint    string
+}
+
+// Error implements error
+func (e *AccessError) Error() string {
+	return e.Problem + "; " + e.Hint
+}
+
+// RepositoryAccessChecker is implemented by review clients that can check
+// that their token can read the repository before any lookup
+type RepositoryAccessChecker interface {
+	CheckRepositoryAccess(ctx context.Context, owner, repo string) error
+}
+
+// ssoAuthorizationURL returns the URL where a token is authorized for an
+// organization enforcing SAML SSO, from GitHub's
+// "X-GitHub-SSO: required; url=..." header, or "" when SSO is not the problem
+func ssoAuthorizationURL(resp *http.Response) string {
+	sso := resp.Header.Get("X-GitHub-SSO")
+	if !strings.HasPrefix(sso, "required") {
+		return ""
+	}
+	if _, url, found := strings.Cut(sso, "url="); found {
+		return strings.TrimSpace(url)
+	}
+	return "the organization's SSO page"
+}
+
+// CheckRepositoryAccess checks that the token can read a repository and
+// explains why it cannot: an invalid token, missing SSO authorization or
+// scopes, or a repository the token cannot see. Other failures, such as an
+// unreachable API, are left to the lookups.
+func (c *GitHubClient) CheckRepositoryAccess(ctx context.Context, owner, repo string) error {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("%s/repos/%s/%s", c.baseURL, owner, repo))
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return &AccessError{
+			Problem: "the GitHub token is invalid or expired",
+			Hint:    "create a new token at https://github.com/settings/tokens or log in with gh auth login",
+		}
+	case http.StatusForbidden:
+		if isRateLimited(resp) {
+			return nil
+		}
+		if url := ssoAuthorizationURL(resp); url != "" {
+			return &AccessError{
+				Problem: fmt.Sprintf("the %s organization requires SAML SSO and the GitHub token is not authorized for it", owner),
+				Hint:    "authorize the token at " + url,
+			}
+		}
+		return &AccessError{
+			Problem: fmt.Sprintf("the GitHub token may not read %s/%s", owner, repo),
+			Hint:    "grant it the repo scope, or read access to pull requests and contents for a fine-grained token",
+		}
+	case http.StatusNotFound:
+		// GitHub hides private repositories the token cannot read
+		hint := "check the remote, and that the token's repository access includes it"
+		if scopes, classic := resp.Header["X-Oauth-Scopes"]; classic {
+			granted := splitScopes(strings.Join(scopes, ","))
+			if !granted["repo"] {
+				hint = "if it is private, grant the token the repo scope"
+			}
+		}
+		return &AccessError{
+			Problem: fmt.Sprintf("repository %s/%s was not found or the GitHub token cannot see it", owner, repo),
+			Hint:    hint,
+		}
+	}
+	return nil
+}
+
+// CheckRepositoryAccess implements RepositoryAccessChecker
+func (a *GitHubClientAdapter) CheckRepositoryAccess(ctx context.Context, owner, repo string) error {
+	return a.client.CheckRepositoryAccess(ctx, owner, repo)
+}
+
+// CheckRepositoryAccess checks that the token can read a project, as the
+// GitHub client does
+func (c *GitLabClient) CheckRepositoryAccess(ctx context.Context, owner, repo string) error {
+	resp, err := c.makeRequest(ctx, "GET", c.projectAPIURL(owner, repo, ""))
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return &AccessError{
+			Problem: "the GitLab token is invalid, expired or revoked",
+			Hint:    "create a new token with the read_api scope or log in with glab auth login",
+		}
+	case http.StatusForbidden:
+		if isRateLimited(resp) {
+			return nil
+		}
+		return &AccessError{
+			Problem: "the GitLab token's scopes do not allow API reads",
+			Hint:    "create a token with the read_api scope (api is needed for -post-discussions)",
+		}
+	case http.StatusNotFound:
+		return &AccessError{
+			Problem: fmt.Sprintf("project %s/%s was not found or the GitLab token cannot see it", owner, repo),
+			Hint:    "check the remote, and that the token's user is a member of the project (at least Reporter for private projects)",
+		}
+	}
+	return nil
+}
+
+// PreflightEnricher checks once, before any lookup, that the token can
+// read the repository, so a token problem fails the run with its fix
+// instead of degrading every line to its commit author. It is a no-op for
+// clients that do not implement RepositoryAccessChecker.
+type PreflightEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	checked  bool
+}
+
+// NewPreflightEnricher creates the preflight stage
+func NewPreflightEnricher(client ReviewClient, repoInfo *RepoInfo) *PreflightEnricher {
+	return &PreflightEnricher{client: client, repoInfo: repoInfo}
+}
+
+// Name implements Enricher
+func (e *PreflightEnricher) Name() string {
+	return "preflight"
+}
+
+// Enrich implements Enricher
+func (e *PreflightEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	checker, ok := e.client.(RepositoryAccessChecker)
+	if e.checked || !ok {
+		return nil
+	}
+	if err := checker.CheckRepositoryAccess(ctx, e.repoInfo.Owner, e.repoInfo.Name); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	e.checked = true
+	return nil
+}
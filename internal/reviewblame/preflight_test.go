@@ -0,0 +1,101 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitHubCheckRepositoryAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		want   string
+	}{
+		{"readable", http.StatusOK, nil, ""},
+		{"invalid token", http.StatusUnauthorized, nil, "the GitHub token is invalid or expired"},
+		{"sso", http.StatusForbidden, http.Header{"X-Github-Sso": {"required; url=https://github.com/orgs/owner/sso?authorization_request=1"}}, "authorize the token at https://github.com/orgs/owner/sso?authorization_request=1"},
+		{"forbidden", http.StatusForbidden, nil, "the GitHub token may not read owner/repo"},
+		{"missing repo scope", http.StatusNotFound, http.Header{"X-Oauth-Scopes": {"read:org"}}, "grant the token the repo scope"},
+		{"not visible", http.StatusNotFound, nil, "repository owner/repo was not found or the GitHub token cannot see it"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/owner/repo" {
+					t.Errorf("unexpected request %s", r.URL.Path)
+				}
+				for name, values := range tt.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := NewGitHubClient("test-token")
+			client.baseURL = server.URL
+			err := client.CheckRepositoryAccess(context.Background(), "owner", "repo")
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var accessErr *AccessError
+			if !errors.As(err, &accessErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an access error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestGitLabCheckRepositoryAccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/group%2Fproject" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+	err := client.CheckRepositoryAccess(context.Background(), "group", "project")
+	if err == nil || !strings.Contains(err.Error(), "project group/project was not found") {
+		t.Errorf("expected an access error, got %v", err)
+	}
+}
+
+// fakeAccessClient is a fakeReviewClient whose token cannot read the
+// repository
+type fakeAccessClient struct {
+	fakeReviewClient
+	checks int
+}
+
+func (c *fakeAccessClient) CheckRepositoryAccess(ctx context.Context, owner, repo string) error {
+	c.checks++
+	return &AccessError{Problem: "the GitHub token is invalid or expired", Hint: "create a new token"}
+}
+
+func TestPreflightEnricher(t *testing.T) {
+	client := &fakeAccessClient{fakeReviewClient: fakeReviewClient{prs: map[string]int{"aaa": 1}}}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+	pipeline := NewEnrichmentPipeline(NewPreflightEnricher(client, repoInfo), NewPRLookupEnricher(client, repoInfo))
+
+	_, err := pipeline.Run(context.Background(), []BlameLine{{CommitHash: "aaa", LineNumber: 1}})
+	if err == nil || !strings.Contains(err.Error(), "invalid or expired; create a new token") {
+		t.Errorf("expected the access error, got %v", err)
+	}
+
+	// Clients that cannot check access skip the stage
+	lines, err := NewEnrichmentPipeline(NewPreflightEnricher(&client.fakeReviewClient, repoInfo), NewPRLookupEnricher(&client.fakeReviewClient, repoInfo)).
+		Run(context.Background(), []BlameLine{{CommitHash: "aaa", LineNumber: 1}})
+	if err != nil || lines[0].PRNumber != 1 {
+		t.Errorf("expected the lookup to run, got %v and %+v", err, lines)
+	}
+}
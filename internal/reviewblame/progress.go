@@ -0,0 +1,171 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// streamWindow is the number of hunks -stream and -progress enrich at a
+// time: small enough for the first lines to appear quickly, large enough to
+// keep batching commit lookups
+const streamWindow = 8
+
+// streamFormats are the formats -stream can write a window at a time; the
+// others need every line before they can write anything, e.g. the CSV header
+// or the HTML coverage summary
+var streamFormats = map[string]bool{
+	"human":       true,
+	"porcelain":   true,
+	"incremental": true,
+	"annotations": true,
+}
+
+// spinnerFrames are drawn in turn by Progress
+const spinnerFrames = `|/-\`
+
+// Progress draws a spinner with the number of resolved lines on a terminal,
+// redrawn on every change and at a fixed interval while lookups are slow
+type Progress struct {
+	w     io.Writer
+	total int
+
+	mu    sync.Mutex
+	done  int
+	frame int
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// StartProgress draws a spinner for total lines on w until Stop is called
+func StartProgress(w io.Writer, total int, interval time.Duration) *Progress {
+	p := &Progress{w: w, total: total, stop: make(chan struct{}), stopped: make(chan struct{})}
+	p.draw()
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.draw()
+			}
+		}
+	}()
+	return p
+}
+
+// Add counts n more resolved lines
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	p.done += n
+	p.mu.Unlock()
+	p.draw()
+}
+
+// Print runs write with the spinner cleared, so output written to the same
+// terminal is not mixed with it
+func (p *Progress) Print(write func() error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLocked()
+	err := write()
+	p.drawLocked()
+	return err
+}
+
+// Stop stops the spinner and clears its line
+func (p *Progress) Stop() {
+	close(p.stop)
+	<-p.stopped
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLocked()
+}
+
+func (p *Progress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.drawLocked()
+}
+
+func (p *Progress) drawLocked() {
+	fmt.Fprintf(p.w, "\r%c Resolving approvals: %d/%d lines", spinnerFrames[p.frame%len(spinnerFrames)], p.done, p.total)
+	p.frame++
+}
+
+func (p *Progress) clearLocked() {
+	fmt.Fprint(p.w, "\r\033[K")
+}
+
+// isTerminal reports whether file is a terminal rather than a file or pipe
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// enrichLines runs pipeline over blameLines. With -stream, write receives
+// each window of lines as soon as it is enriched; with -progress, a spinner
+// on stderr counts the resolved lines, unless stderr is not a terminal.
+// write is nil without -stream.
+func enrichLines(ctx context.Context, pipeline *EnrichmentPipeline, blameLines []BlameLine, opts Options, write func([]BlameLineWithApproval) error) ([]BlameLineWithApproval, error) {
+	var progress *Progress
+	if opts.Progress && isTerminal(os.Stderr) {
+		progress = StartProgress(os.Stderr, len(blameLines), 100*time.Millisecond)
+		defer progress.Stop()
+	}
+	if write == nil && progress == nil {
+		return pipeline.Run(ctx, blameLines)
+	}
+
+	return pipeline.RunStream(ctx, blameLines, streamWindow, func(window []BlameLineWithApproval) error {
+		if progress == nil {
+			return write(window)
+		}
+		progress.Add(len(window))
+		if write == nil {
+			return nil
+		}
+		return progress.Print(func() error { return write(window) })
+	})
+}
+
+// enrichStream is enrichLines for the hunks of a git blame --incremental
+// stream, which are enriched while git is still running. The spinner counts
+// the lines of the whole file, as the lines in -L ranges are not known
+// before git reports them.
+func enrichStream(ctx context.Context, pipeline *EnrichmentPipeline, stream *BlameStream, opts Options, write func([]BlameLineWithApproval) error) ([]BlameLineWithApproval, error) {
+	var progress *Progress
+	if opts.Progress && isTerminal(os.Stderr) {
+		progress = StartProgress(os.Stderr, stream.Lines, 100*time.Millisecond)
+		defer progress.Stop()
+	}
+
+	lines, err := pipeline.RunHunks(ctx, stream.Hunks, streamWindow, func(window []BlameLineWithApproval) error {
+		if progress != nil {
+			progress.Add(len(window))
+		}
+		switch {
+		case write == nil:
+			return nil
+		case progress == nil:
+			return write(window)
+		default:
+			return progress.Print(func() error { return write(window) })
+		}
+	})
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	if err := stream.Wait(); err != nil {
+		return nil, &UntrackedFileError{Err: err}
+	}
+	return lines, nil
+}
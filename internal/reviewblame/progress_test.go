@@ -0,0 +1,92 @@
+package reviewblame
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a strings.Builder safe for the spinner's goroutine
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestProgress(t *testing.T) {
+	var stderr, stdout lockedBuffer
+	progress := StartProgress(&stderr, 10, time.Hour)
+	progress.Add(4)
+	err := progress.Print(func() error {
+		_, err := stdout.Write([]byte("line 1\n"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	progress.Add(6)
+	progress.Stop()
+
+	output := stderr.String()
+	for _, expected := range []string{"\r| Resolving approvals: 0/10 lines", "\r/ Resolving approvals: 4/10 lines", "Resolving approvals: 10/10 lines"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in the spinner output, got %q", expected, output)
+		}
+	}
+	if !strings.HasSuffix(output, "\r\033[K") {
+		t.Errorf("expected Stop to clear the spinner line, got %q", output)
+	}
+	if strings.Count(output, "\r\033[K") != 2 {
+		t.Errorf("expected the spinner to be cleared around printed output and on Stop, got %q", output)
+	}
+	if stdout.String() != "line 1\n" {
+		t.Errorf("expected the printed output, got %q", stdout.String())
+	}
+}
+
+func TestEnrichLinesStream(t *testing.T) {
+	var windows []string
+	pipeline := NewEnrichmentPipeline(windowRecorder{windows: &windows})
+	blameLines := make([]BlameLine, 0, streamWindow+1)
+	for i := 0; i <= streamWindow; i++ {
+		blameLines = append(blameLines, BlameLine{CommitHash: strings.Repeat(string(rune('a'+i)), 4), LineNumber: i + 1})
+	}
+
+	var written int
+	lines, err := enrichLines(context.Background(), pipeline, blameLines, Options{Stream: true}, func(window []BlameLineWithApproval) error {
+		written += len(window)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 2 || written != len(blameLines) || len(lines) != len(blameLines) {
+		t.Errorf("expected %d lines written in 2 windows, wrote %d in %v", len(blameLines), written, windows)
+	}
+
+	// Without -stream, and -progress on a non-terminal, the lines are enriched in one run
+	if isTerminal(os.Stderr) {
+		t.Skip("stderr is a terminal")
+	}
+	windows = nil
+	if _, err := enrichLines(context.Background(), pipeline, blameLines, Options{Progress: true}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 1 {
+		t.Errorf("expected a single run, enriched %v", windows)
+	}
+}
@@ -0,0 +1,347 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// BranchProtection is the pull request review a branch currently requires,
+// combined from its classic protection rule and the rulesets that target it
+type BranchProtection struct {
+	RequiredApprovals       int  `json:"required_approvals"`
+	RequireCodeOwnerReviews bool `json:"require_code_owner_reviews"`
+	// DismissStaleReviews discards approvals of commits older than the head
+	// of the pull request
+	DismissStaleReviews bool `json:"dismiss_stale_reviews"`
+}
+
+// merge requires the stricter setting of p and other
+func (p *BranchProtection) merge(other BranchProtection) {
+	p.RequiredApprovals = max(p.RequiredApprovals, other.RequiredApprovals)
+	p.RequireCodeOwnerReviews = p.RequireCodeOwnerReviews || other.RequireCodeOwnerReviews
+	p.DismissStaleReviews = p.DismissStaleReviews || other.DismissStaleReviews
+}
+
+// PullRequestReviewState is what checking a PR against branch protection
+// needs: its base branch and whose reviews approve it
+type PullRequestReviewState struct {
+	BaseBranch string
+	HeadSHA    string
+	// Approvers are the logins whose latest review approves the PR
+	Approvers []string
+	// CurrentApprovers are the Approvers who approved the head commit, the
+	// approvals left when stale reviews are dismissed
+	CurrentApprovers []string
+}
+
+// BranchProtectionProvider is implemented by review clients that can read
+// branch protection, the reviews of a PR and the members of the teams
+// CODEOWNERS names
+type BranchProtectionProvider interface {
+	GetBranchProtection(ctx context.Context, owner, repo, branch string) (*BranchProtection, error)
+	GetPullRequestReviewState(ctx context.Context, owner, repo string, prNumber int) (*PullRequestReviewState, error)
+	TeamMemberLister
+}
+
+// GetBranchProtection reads the required reviews of a branch from its
+// classic protection rule, which needs admin access and is treated as
+// missing on 404, and from the rulesets that apply to it
+func (c *GitHubClient) GetBranchProtection(ctx context.Context, owner, repo, branch string) (*BranchProtection, error) {
+	protection := &BranchProtection{}
+
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/branches/%s/protection/required_pull_request_reviews", c.baseURL, owner, repo, url.PathEscape(branch))
+	resp, err := c.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var reviews struct {
+			RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+			RequireCodeOwnerReviews      bool `json:"require_code_owner_reviews"`
+			DismissStaleReviews          bool `json:"dismiss_stale_reviews"`
+		}
+		if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&reviews); err != nil {
+			return nil, err
+		}
+		protection.merge(BranchProtection{
+			RequiredApprovals:       reviews.RequiredApprovingReviewCount,
+			RequireCodeOwnerReviews: reviews.RequireCodeOwnerReviews,
+			DismissStaleReviews:     reviews.DismissStaleReviews,
+		})
+	case http.StatusNotFound:
+		// Not protected, or not visible to the token
+	default:
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	rulesURL := fmt.Sprintf("%s/repos/%s/%s/rules/branches/%s", c.baseURL, owner, repo, url.PathEscape(branch))
+	err = c.listPages(ctx, rulesURL, func(dec *json.Decoder) error {
+		var rule struct {
+			Type       string `json:"type"`
+			Parameters struct {
+				RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+				RequireCodeOwnerReview       bool `json:"require_code_owner_review"`
+				DismissStaleReviewsOnPush    bool `json:"dismiss_stale_reviews_on_push"`
+			} `json:"parameters"`
+		}
+		if err := dec.Decode(&rule); err != nil {
+			return err
+		}
+		if rule.Type == "pull_request" {
+			protection.merge(BranchProtection{
+				RequiredApprovals:       rule.Parameters.RequiredApprovingReviewCount,
+				RequireCodeOwnerReviews: rule.Parameters.RequireCodeOwnerReview,
+				DismissStaleReviews:     rule.Parameters.DismissStaleReviewsOnPush,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return protection, nil
+}
+
+// GetPullRequestReviewState gets the base branch and head commit of a pull
+// request and who approves it. A reviewer's latest approval, change request
+// or dismissal decides; comments do not.
+func (c *GitHubClient) GetPullRequestReviewState(ctx context.Context, owner, repo string, prNumber int) (*PullRequestReviewState, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, owner, repo, prNumber)
+	resp, err := c.makeRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+	}
+
+	var pr struct {
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := json.NewDecoder(newResponseReader(resp.Body)).Decode(&pr); err != nil {
+		return nil, err
+	}
+
+	type latestReview struct {
+		state    string
+		commitID string
+	}
+	var reviewers []string
+	latest := make(map[string]latestReview)
+	err = c.listPages(ctx, apiURL+"/reviews", func(dec *json.Decoder) error {
+		var review struct {
+			User struct {
+				Login string `json:"login"`
+			} `json:"user"`
+			State    string `json:"state"`
+			CommitID string `json:"commit_id"`
+		}
+		if err := dec.Decode(&review); err != nil {
+			return err
+		}
+		switch review.State {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			if _, seen := latest[review.User.Login]; !seen {
+				reviewers = append(reviewers, review.User.Login)
+			}
+			latest[review.User.Login] = latestReview{review.State, review.CommitID}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state := &PullRequestReviewState{BaseBranch: pr.Base.Ref, HeadSHA: pr.Head.SHA}
+	for _, login := range reviewers {
+		review := latest[login]
+		if review.state != "APPROVED" {
+			continue
+		}
+		state.Approvers = append(state.Approvers, login)
+		if review.commitID == pr.Head.SHA {
+			state.CurrentApprovers = append(state.CurrentApprovers, login)
+		}
+	}
+	return state, nil
+}
+
+// GetBranchProtection implements BranchProtectionProvider
+func (a *GitHubClientAdapter) GetBranchProtection(ctx context.Context, owner, repo, branch string) (*BranchProtection, error) {
+	return a.client.GetBranchProtection(ctx, owner, repo, branch)
+}
+
+// GetPullRequestReviewState implements BranchProtectionProvider
+func (a *GitHubClientAdapter) GetPullRequestReviewState(ctx context.Context, owner, repo string, prNumber int) (*PullRequestReviewState, error) {
+	return a.client.GetPullRequestReviewState(ctx, owner, repo, prNumber)
+}
+
+// ListTeamMembers implements TeamMemberLister
+func (a *GitHubClientAdapter) ListTeamMembers(ctx context.Context, org, slug string) ([]string, error) {
+	return a.client.ListTeamMembers(ctx, org, slug)
+}
+
+// BranchProtectionEnricher checks each line's PR against the review
+// requirements its base branch has now: the required approvals, counting
+// only approvals of the head commit when stale reviews are dismissed, and
+// an approval from a code owner of the line's file as of HEAD when code
+// owner reviews are required. Unmet requirements are recorded as approval
+// rules, so lines are marked rules-unmet. It is a no-op for clients that
+// do not implement BranchProtectionProvider.
+type BranchProtectionEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// codeOwners finds the code owners of files as of HEAD
+	codeOwners *codeOwnersResolver
+	// protections maps "owner/name:branch" to its protection (nil when the
+	// lookup failed)
+	protections map[string]*BranchProtection
+	// reviews maps PR to its review state (nil when the lookup failed)
+	reviews map[prKey]*PullRequestReviewState
+	// cache maps PR and file to the rule status
+	cache map[branchProtectionKey]*ApprovalRuleStatus
+}
+
+// branchProtectionKey identifies the status of a file's lines from one PR,
+// since the code owners differ between files
+type branchProtectionKey struct {
+	pr   prKey
+	file string
+}
+
+// NewBranchProtectionEnricher creates the branch protection stage
+func NewBranchProtectionEnricher(client ReviewClient, repoInfo *RepoInfo, repoRoot string) *BranchProtectionEnricher {
+	return &BranchProtectionEnricher{
+		client:      client,
+		repoInfo:    repoInfo,
+		codeOwners:  newCodeOwnersResolver(repoRoot),
+		protections: make(map[string]*BranchProtection),
+		reviews:     make(map[prKey]*PullRequestReviewState),
+		cache:       make(map[branchProtectionKey]*ApprovalRuleStatus),
+	}
+}
+
+// Name implements Enricher
+func (e *BranchProtectionEnricher) Name() string {
+	return "branch-protection"
+}
+
+// Enrich implements Enricher
+func (e *BranchProtectionEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	provider, ok := e.client.(BranchProtectionProvider)
+	if !ok {
+		return nil
+	}
+
+	for i := range lines {
+		prNumber := lines[i].PRNumber
+		if prNumber == 0 {
+			continue
+		}
+
+		key := branchProtectionKey{prKey{lines[i].Repository, prNumber}, lines[i].Filename}
+		status, exists := e.cache[key]
+		if !exists {
+			var err error
+			status, err = e.evaluate(ctx, provider, lines[i])
+			if err != nil {
+				return err
+			}
+			e.cache[key] = status
+		}
+		if status != nil {
+			lines[i].ApprovalRules = status
+		}
+	}
+	return nil
+}
+
+// evaluate checks the PR of line against the protection of its base
+// branch. It returns nil when either could not be read and an error only
+// when ctx is done.
+func (e *BranchProtectionEnricher) evaluate(ctx context.Context, provider BranchProtectionProvider, line BlameLineWithApproval) (*ApprovalRuleStatus, error) {
+	owner, name := lineRepository(e.repoInfo, line)
+	pr := prKey{line.Repository, line.PRNumber}
+
+	state, exists := e.reviews[pr]
+	if !exists {
+		var err error
+		state, err = provider.GetPullRequestReviewState(ctx, owner, name, line.PRNumber)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			state = nil
+		}
+		e.reviews[pr] = state
+	}
+	if state == nil {
+		return nil, nil
+	}
+
+	branch := owner + "/" + name + ":" + state.BaseBranch
+	protection, exists := e.protections[branch]
+	if !exists {
+		var err error
+		protection, err = provider.GetBranchProtection(ctx, owner, name, state.BaseBranch)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			fmt.Fprintf(warningOutput, "warning: could not read the branch protection of %s: %v\n", branch, err)
+			protection = nil
+		}
+		e.protections[branch] = protection
+	}
+	if protection == nil {
+		return nil, nil
+	}
+
+	approvers := state.Approvers
+	if protection.DismissStaleReviews {
+		approvers = state.CurrentApprovers
+	}
+
+	var rules []ApprovalRule
+	if protection.RequiredApprovals > 0 {
+		rules = append(rules, ApprovalRule{
+			Name:       "required reviews on " + state.BaseBranch,
+			Required:   protection.RequiredApprovals,
+			ApprovedBy: approvers,
+		})
+	}
+	// CODEOWNERS is read from this checkout, so it does not apply to PRs
+	// of other repositories
+	if protection.RequireCodeOwnerReviews && line.Repository == "" {
+		rule, owners, err := e.codeOwners.Owners(ctx, provider, line.Filename)
+		if err != nil {
+			return nil, err
+		}
+		if len(owners) > 0 {
+			rules = append(rules, ApprovalRule{
+				Name:              rule.Pattern,
+				Type:              "code_owner",
+				Required:          1,
+				EligibleApprovers: owners,
+				ApprovedBy:        approvers,
+			})
+		}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return evaluateApprovalRules(rules, nil), nil
+}
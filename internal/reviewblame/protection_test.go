@@ -0,0 +1,204 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGitHubGetBranchProtection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/repos/owner/repo/branches/release%2F1.0/protection/required_pull_request_reviews":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"required_approving_review_count": 1,
+				"require_code_owner_reviews":      true,
+				"dismiss_stale_reviews":           false,
+			})
+		case "/repos/owner/repo/rules/branches/release%2F1.0":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"type": "deletion"},
+				{"type": "pull_request", "parameters": map[string]interface{}{
+					"required_approving_review_count": 2,
+					"require_code_owner_review":       false,
+					"dismiss_stale_reviews_on_push":   true,
+				}},
+			})
+		case "/repos/owner/repo/branches/main/protection/required_pull_request_reviews":
+			w.WriteHeader(http.StatusNotFound)
+		case "/repos/owner/repo/rules/branches/main":
+			json.NewEncoder(w).Encode([]interface{}{})
+		default:
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	tests := []struct {
+		branch string
+		want   BranchProtection
+	}{
+		{"release/1.0", BranchProtection{RequiredApprovals: 2, RequireCodeOwnerReviews: true, DismissStaleReviews: true}},
+		{"main", BranchProtection{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			protection, err := client.GetBranchProtection(context.Background(), "owner", "repo", tt.branch)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *protection != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, *protection)
+			}
+		})
+	}
+}
+
+func TestGitHubGetPullRequestReviewState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/pulls/7":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"base": map[string]string{"ref": "main"},
+				"head": map[string]string{"sha": "head"},
+			})
+		case "/repos/owner/repo/pulls/7/reviews":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"user": map[string]string{"login": "alice"}, "state": "APPROVED", "commit_id": "old"},
+				{"user": map[string]string{"login": "bob"}, "state": "CHANGES_REQUESTED", "commit_id": "old"},
+				{"user": map[string]string{"login": "carol"}, "state": "APPROVED", "commit_id": "old"},
+				{"user": map[string]string{"login": "bob"}, "state": "APPROVED", "commit_id": "head"},
+				{"user": map[string]string{"login": "carol"}, "state": "DISMISSED", "commit_id": "old"},
+				{"user": map[string]string{"login": "alice"}, "state": "COMMENTED", "commit_id": "head"},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	state, err := client.GetPullRequestReviewState(context.Background(), "owner", "repo", 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &PullRequestReviewState{
+		BaseBranch:       "main",
+		HeadSHA:          "head",
+		Approvers:        []string{"alice", "bob"},
+		CurrentApprovers: []string{"bob"},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("expected %+v, got %+v", want, state)
+	}
+}
+
+// fakeProtectionClient is a fakeReviewClient that also reports branch
+// protection, review states and team members
+type fakeProtectionClient struct {
+	fakeReviewClient
+	protections     map[string]*BranchProtection
+	states          map[int]*PullRequestReviewState
+	teams           map[string][]string
+	protectionCalls int
+	stateCalls      int
+}
+
+func (c *fakeProtectionClient) GetBranchProtection(ctx context.Context, owner, repo, branch string) (*BranchProtection, error) {
+	c.protectionCalls++
+	return c.protections[branch], nil
+}
+
+func (c *fakeProtectionClient) GetPullRequestReviewState(ctx context.Context, owner, repo string, prNumber int) (*PullRequestReviewState, error) {
+	c.stateCalls++
+	return c.states[prNumber], nil
+}
+
+func (c *fakeProtectionClient) ListTeamMembers(ctx context.Context, org, slug string) ([]string, error) {
+	return c.teams[org+"/"+slug], nil
+}
+
+func TestBranchProtectionEnricher(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	codeOwners := "* @alice\n/api/ @acme/api\ndocs/\n"
+	if err := os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte(codeOwners), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "Add CODEOWNERS")
+
+	now := time.Unix(1700000000, 0)
+	client := &fakeProtectionClient{
+		fakeReviewClient: fakeReviewClient{
+			prs: map[string]int{"aaa": 1, "bbb": 2, "ccc": 3},
+			approvals: map[int][]Review{
+				1: {newTestReview("alice", now), newTestReview("bob", now)},
+				2: {newTestReview("alice", now), newTestReview("bob", now)},
+				3: {newTestReview("bob", now)},
+			},
+		},
+		protections: map[string]*BranchProtection{
+			"main":    {RequiredApprovals: 2, RequireCodeOwnerReviews: true, DismissStaleReviews: true},
+			"release": {},
+		},
+		states: map[int]*PullRequestReviewState{
+			1: {BaseBranch: "main", Approvers: []string{"alice", "bob"}, CurrentApprovers: []string{"alice", "bob"}},
+			2: {BaseBranch: "main", Approvers: []string{"alice", "bob"}, CurrentApprovers: []string{"alice"}},
+			3: {BaseBranch: "release", Approvers: []string{"bob"}, CurrentApprovers: []string{"bob"}},
+		},
+		teams: map[string][]string{"acme/api": {"carol"}},
+	}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	pipeline := NewDefaultEnrichmentPipeline(client, repoInfo)
+	pipeline.Use(NewBranchProtectionEnricher(client, repoInfo, dir))
+	lines, err := pipeline.Run(context.Background(), []BlameLine{
+		{CommitHash: "aaa", LineNumber: 1, Filename: "main.go"},
+		{CommitHash: "aaa", LineNumber: 2, Filename: "api/handler.go"},
+		{CommitHash: "aaa", LineNumber: 3, Filename: "docs/readme.md"},
+		{CommitHash: "bbb", LineNumber: 4, Filename: "api/handler.go"},
+		{CommitHash: "ccc", LineNumber: 5, Filename: "api/handler.go"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if client.stateCalls != 3 || client.protectionCalls != 2 {
+		t.Errorf("expected 3 review state and 2 protection lookups, got %d and %d", client.stateCalls, client.protectionCalls)
+	}
+	want := []string{ReviewStateApproved, ReviewStateRulesUnmet, ReviewStateApproved, ReviewStateRulesUnmet, ReviewStateApproved}
+	for i, line := range lines {
+		if line.ReviewState() != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i+1, want[i], line.ReviewState())
+		}
+	}
+	if lines[4].ApprovalRules != nil {
+		t.Errorf("expected no rules for an unprotected branch, got %+v", lines[4].ApprovalRules)
+	}
+
+	warnings := UnmetApprovalRuleWarnings(lines)
+	expected := []string{
+		"#1 was approved without meeting its approval rules: code owners of /api/ (0 of 1 approvals)",
+		"#2 was approved without meeting its approval rules: required reviews on main (1 of 2 approvals), code owners of /api/ (0 of 1 approvals)",
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("expected %q, got %q", expected, warnings)
+	}
+}
@@ -0,0 +1,88 @@
+package reviewblame
+
+import (
+	"fmt"
+	"strings"
+)
+
+// providerNames maps the names accepted by -provider and the hosts config
+// to repository types
+var providerNames = map[string]RepositoryType{
+	"github":    RepositoryTypeGitHub,
+	"gitlab":    RepositoryTypeGitLab,
+	"bitbucket": RepositoryTypeBitbucket,
+	"gitea":     RepositoryTypeGitea,
+	"forgejo":   RepositoryTypeGitea,
+	"gerrit":    RepositoryTypeGerrit,
+	"plugin":    RepositoryTypePlugin,
+}
+
+// parseProvider returns the repository type of a provider name
+func parseProvider(name string) (RepositoryType, error) {
+	repoType, ok := providerNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown provider %q (expected github, gitlab, bitbucket, gitea, forgejo, gerrit or plugin)", name)
+	}
+	return repoType, nil
+}
+
+// HostConfig names the hosting service of a self-hosted instance that cannot
+// be recognized from its host name alone
+type HostConfig struct {
+	// Host is the host name of the remote, e.g. "git.example.com"
+	Host string `json:"host"`
+	// Provider is github, gitlab, bitbucket, gitea, forgejo, gerrit or
+	// plugin
+	Provider string `json:"provider"`
+	// APIURL overrides the API base URL derived from the host
+	APIURL string `json:"api_url"`
+	// Plugin is the provider plugin of the plugin provider: a name, run as
+	// git-review-blame-provider-<name> from PATH, or a path
+	Plugin string `json:"plugin"`
+}
+
+// validate checks that the host has a name and a known provider
+func (h HostConfig) validate() error {
+	if h.Host == "" {
+		return fmt.Errorf("host is missing a host name")
+	}
+	repoType, err := parseProvider(h.Provider)
+	if err != nil {
+		return fmt.Errorf("host %q: %w", h.Host, err)
+	}
+	if (repoType == RepositoryTypePlugin) != (h.Plugin != "") {
+		return fmt.Errorf("host %q: plugin must be set for the plugin provider, and only for it", h.Host)
+	}
+	return nil
+}
+
+// applyHostConfig sets the provider and API URL of repoInfo from the entry
+// for its host, and reports whether there is one
+func applyHostConfig(repoInfo *RepoInfo, hosts []HostConfig) bool {
+	for _, host := range hosts {
+		if !strings.EqualFold(host.Host, repoInfo.Host) {
+			continue
+		}
+		repoInfo.Type, _ = parseProvider(host.Provider)
+		if host.APIURL != "" {
+			repoInfo.APIURL = strings.TrimSuffix(host.APIURL, "/")
+		}
+		repoInfo.Plugin = host.Plugin
+		return true
+	}
+	return false
+}
+
+// applyProvider overrides the detected provider of repoInfo with name, as
+// given by -provider; an empty name keeps the detected provider
+func applyProvider(repoInfo *RepoInfo, name string) error {
+	if name == "" {
+		return nil
+	}
+	repoType, err := parseProvider(name)
+	if err != nil {
+		return err
+	}
+	repoInfo.Type = repoType
+	return nil
+}
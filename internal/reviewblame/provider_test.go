@@ -0,0 +1,42 @@
+package reviewblame
+
+import "testing"
+
+func TestApplyHostConfig(t *testing.T) {
+	hosts := []HostConfig{
+		{Host: "git.example.com", Provider: "gitea", APIURL: "https://git.example.com/gitea/api/v1/"},
+		{Host: "code.example.com", Provider: "github"},
+		{Host: "review.example.com", Provider: "plugin", Plugin: "corp-review"},
+	}
+
+	repoInfo := &RepoInfo{Type: RepositoryTypeGitLab, Host: "Git.Example.com"}
+	applyHostConfig(repoInfo, hosts)
+	if repoInfo.Type != RepositoryTypeGitea || repoInfo.APIURL != "https://git.example.com/gitea/api/v1" {
+		t.Errorf("expected Gitea with the configured API URL, got %s %q", repoInfo.Type, repoInfo.APIURL)
+	}
+
+	repoInfo = &RepoInfo{Type: RepositoryTypeGitLab, Host: "review.example.com"}
+	applyHostConfig(repoInfo, hosts)
+	if repoInfo.Type != RepositoryTypePlugin || repoInfo.Plugin != "corp-review" {
+		t.Errorf("expected the corp-review plugin, got %s %q", repoInfo.Type, repoInfo.Plugin)
+	}
+
+	repoInfo = &RepoInfo{Type: RepositoryTypeGitLab, Host: "gitlab.example.com"}
+	applyHostConfig(repoInfo, hosts)
+	if repoInfo.Type != RepositoryTypeGitLab {
+		t.Errorf("expected an unlisted host to keep its type, got %s", repoInfo.Type)
+	}
+}
+
+func TestApplyProvider(t *testing.T) {
+	repoInfo := &RepoInfo{Type: RepositoryTypeGitLab}
+	if err := applyProvider(repoInfo, ""); err != nil || repoInfo.Type != RepositoryTypeGitLab {
+		t.Errorf("expected an empty provider to keep the type, got %s, %v", repoInfo.Type, err)
+	}
+	if err := applyProvider(repoInfo, "Forgejo"); err != nil || repoInfo.Type != RepositoryTypeGitea {
+		t.Errorf("expected Gitea, got %s, %v", repoInfo.Type, err)
+	}
+	if err := applyProvider(repoInfo, "svn"); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
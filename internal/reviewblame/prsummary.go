@@ -0,0 +1,119 @@
+package reviewblame
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GroupByModes are the values -group-by accepts, with the format each selects
+var GroupByModes = map[string]string{
+	"pr": "pr-summary",
+}
+
+// PRGroup is the lines of a file that still originate from one PR/MR
+type PRGroup struct {
+	// Repository is set for PRs/MRs of a repository the commits were
+	// imported from; Number is 0 for the lines without a PR/MR
+	Repository string
+	Number     int
+	Title      string
+	Approvers  []string
+	State      string
+	Lines      int
+	// Ranges are the runs of consecutive line numbers, in file order
+	Ranges []LineSpan
+}
+
+// LineSpan is a run of consecutive line numbers
+type LineSpan struct {
+	Start, End int
+}
+
+// String renders the span as "10-14", or "10" for a single line
+func (s LineSpan) String() string {
+	if s.Start == s.End {
+		return strconv.Itoa(s.Start)
+	}
+	return fmt.Sprintf("%d-%d", s.Start, s.End)
+}
+
+// GroupLinesByPR aggregates lines by PR/MR, in the order each PR/MR first
+// appears; the lines without a PR/MR form one group at the end
+func GroupLinesByPR(lines []BlameLineWithApproval) []PRGroup {
+	var groups []*PRGroup
+	var noPR *PRGroup
+	byKey := make(map[string]*PRGroup)
+	approvers := make(map[*PRGroup]map[string]bool)
+	for _, line := range lines {
+		key := fmt.Sprintf("%s#%d", line.Repository, line.PRNumber)
+		group := byKey[key]
+		if group == nil {
+			group = &PRGroup{Repository: line.Repository, Number: line.PRNumber, Title: line.PRTitle, State: line.ReviewState()}
+			byKey[key] = group
+			approvers[group] = make(map[string]bool)
+			if line.PRNumber == 0 {
+				noPR = group
+			} else {
+				groups = append(groups, group)
+			}
+		}
+
+		group.Lines++
+		if n := len(group.Ranges); n > 0 && group.Ranges[n-1].End+1 == line.LineNumber {
+			group.Ranges[n-1].End = line.LineNumber
+		} else {
+			group.Ranges = append(group.Ranges, LineSpan{Start: line.LineNumber, End: line.LineNumber})
+		}
+		for _, approver := range distinctApprovers(line) {
+			if !approvers[group][approver.Name] {
+				approvers[group][approver.Name] = true
+				group.Approvers = append(group.Approvers, approver.Name)
+			}
+		}
+	}
+	if noPR != nil {
+		groups = append(groups, noPR)
+	}
+
+	result := make([]PRGroup, len(groups))
+	for i, group := range groups {
+		result[i] = *group
+	}
+	return result
+}
+
+// formatPRSummary writes the lines grouped by PR/MR: the PR/MR and its
+// title, its approvers, and the line ranges that still originate from it
+func formatPRSummary(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+	out := bufio.NewWriter(w)
+	for _, group := range GroupLinesByPR(lines) {
+		if group.Number == 0 {
+			fmt.Fprintln(out, "No PR/MR")
+		} else {
+			fmt.Fprintf(out, "%s#%d %s\n", group.Repository, group.Number, group.Title)
+		}
+
+		approvers := strings.Join(group.Approvers, ", ")
+		if approvers == "" {
+			approvers = "none"
+		}
+		if marker := reviewStateMarkers[group.State]; marker != "" && group.Number > 0 {
+			approvers += " " + marker
+		}
+		fmt.Fprintf(out, "    Approvers: %s\n", approvers)
+
+		ranges := make([]string, len(group.Ranges))
+		for i, span := range group.Ranges {
+			ranges[i] = span.String()
+		}
+		noun := "lines"
+		if group.Lines == 1 {
+			noun = "line"
+		}
+		fmt.Fprintf(out, "    Lines: %s (%d %s)\n", strings.Join(ranges, ", "), group.Lines, noun)
+	}
+	return out.Flush()
+}
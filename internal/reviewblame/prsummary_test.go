@@ -0,0 +1,45 @@
+package reviewblame
+
+import "testing"
+
+func TestFormatPRSummary(t *testing.T) {
+	line := func(number, pr int, title string, approvers ...string) BlameLineWithApproval {
+		l := BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "aaaa", LineNumber: number}, PRNumber: pr, PRTitle: title}
+		for _, name := range approvers {
+			l.Approvers = append(l.Approvers, LineApprover{Name: name})
+			l.Approver = name
+		}
+		return l
+	}
+	lines := []BlameLineWithApproval{
+		line(1, 42, "Add parser", "alice"),
+		line(2, 42, "Add parser", "alice", "bob"),
+		line(3, 51, "Fix typo"),
+		line(4, 0, ""),
+		line(5, 42, "Add parser", "alice"),
+		line(6, 42, "Add parser", "alice"),
+		line(7, 0, ""),
+	}
+
+	groups := GroupLinesByPR(lines)
+	if len(groups) != 3 || groups[0].Number != 42 || groups[1].Number != 51 || groups[2].Number != 0 {
+		t.Fatalf("expected PRs 42, 51 and no PR in order, got %+v", groups)
+	}
+
+	formatter, err := NewFormatterRegistry().Lookup("pr-summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#42 Add parser\n" +
+		"    Approvers: alice, bob\n" +
+		"    Lines: 1-2, 5-6 (4 lines)\n" +
+		"#51 Fix typo\n" +
+		"    Approvers: none [unapproved]\n" +
+		"    Lines: 3 (1 line)\n" +
+		"No PR/MR\n" +
+		"    Approvers: none\n" +
+		"    Lines: 4, 7 (2 lines)\n"
+	if got := formatter.Format(lines, FormatOptions{}); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRateLimitRetries is how often a rate-limited request is retried
+	maxRateLimitRetries = 5
+	// maxRateLimitWait is the longest single wait for a limit to reset;
+	// longer waits give up and degrade the output instead
+	maxRateLimitWait = 5 * time.Minute
+	// rateLimitBackoff is the first backoff when the server does not say
+	// when to retry; it doubles with each retry
+	rateLimitBackoff = time.Second
+)
+
+// warningOutput receives warnings about rate limiting
+var warningOutput io.Writer = os.Stderr
+
+// rateLimitSleep waits for d or until ctx is canceled; tests replace it
+var rateLimitSleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimits records requests that stayed rate limited after retrying
+var rateLimits struct {
+	sync.Mutex
+	exhausted int
+	// reset is when the latest exhausted limit resets, zero when unknown
+	reset time.Time
+}
+
+// isRateLimited reports whether resp rejected the request for exceeding a
+// rate limit. GitHub answers 403 with an exhausted X-RateLimit-Remaining or
+// a Retry-After header (secondary limits); GitLab and others answer 429.
+func isRateLimited(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// rateLimitReset returns when the server allows the next request, from
+// Retry-After (seconds or an HTTP date) or the X-RateLimit-Reset (GitHub) or
+// RateLimit-Reset (GitLab) epoch seconds, or the zero time when not given
+func rateLimitReset(resp *http.Response, now time.Time) time.Time {
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second)
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return date
+		}
+	}
+	for _, header := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		if epoch, err := strconv.ParseInt(resp.Header.Get(header), 10, 64); err == nil {
+			return time.Unix(epoch, 0)
+		}
+	}
+	return time.Time{}
+}
+
+// rateLimitDelay returns how long to wait before retry number attempt
+// (counting from 0): until the limit resets when the server says so,
+// otherwise an exponential backoff
+func rateLimitDelay(resp *http.Response, attempt int, now time.Time) time.Duration {
+	if reset := rateLimitReset(resp, now); !reset.IsZero() {
+		if delay := reset.Sub(now); delay > 0 {
+			return delay
+		}
+		return 0
+	}
+	return rateLimitBackoff << attempt
+}
+
+// doRequest sends req with client, waiting and retrying while the server
+// reports a rate limit. When the limit persists, or would take longer than
+// maxRateLimitWait to reset, the rate-limited response is returned for the
+// caller to handle as an error, and the failure is recorded for
+// RateLimitWarning. Idempotent requests failing with transient errors are
+// retried by retryPolicy, and the failures that remain are recorded for
+// TransientErrorWarning.
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.GetBody != nil
+	for attempt, transientAttempt := 0, 0; ; {
+		resp, err := client.Do(req)
+		var delay time.Duration
+		switch {
+		case req.Context().Err() != nil:
+			return resp, err
+		case isTransient(resp, err) && isIdempotent(req):
+			if transientAttempt == retryPolicy.Attempts || !replayable {
+				recordTransientFailure()
+				return resp, err
+			}
+			delay = retryPolicy.delay(transientAttempt)
+			transientAttempt++
+			if err != nil {
+				fmt.Fprintf(warningOutput, "warning: %s request failed (%v), retrying in %s\n", req.URL.Host, err, delay.Round(time.Millisecond))
+			} else {
+				resp.Body.Close()
+				fmt.Fprintf(warningOutput, "warning: %s answered %s, retrying in %s\n", req.URL.Host, resp.Status, delay.Round(time.Millisecond))
+			}
+		case err != nil || !isRateLimited(resp):
+			return resp, err
+		default:
+			now := time.Now()
+			delay = rateLimitDelay(resp, attempt, now)
+			if attempt == maxRateLimitRetries || delay > maxRateLimitWait || !replayable {
+				recordRateLimitExhausted(rateLimitReset(resp, now))
+				return resp, nil
+			}
+			attempt++
+			resp.Body.Close()
+			fmt.Fprintf(warningOutput, "warning: %s rate limit reached, retrying in %s\n", req.URL.Host, delay.Round(time.Second))
+		}
+		if err := rateLimitSleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		req = retry
+	}
+}
+
+// recordRateLimitExhausted records a request that stayed rate limited
+func recordRateLimitExhausted(reset time.Time) {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	rateLimits.exhausted++
+	if reset.After(rateLimits.reset) {
+		rateLimits.reset = reset
+	}
+}
+
+// RateLimitWarning describes the requests that failed on rate limits, whose
+// lines fall back to commit authors, or returns "" when there were none
+func RateLimitWarning() string {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	if rateLimits.exhausted == 0 {
+		return ""
+	}
+	warning := fmt.Sprintf("API rate limit exceeded for %d request(s); affected lines show commit authors instead of approvers", rateLimits.exhausted)
+	if !rateLimits.reset.IsZero() {
+		warning += fmt.Sprintf(". The limit resets at %s", rateLimits.reset.Local().Format(time.Kitchen))
+	}
+	return warning
+}
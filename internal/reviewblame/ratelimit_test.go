@@ -0,0 +1,206 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubRateLimitSleep records waits instead of sleeping, and resets the
+// recorded rate limit failures
+func stubRateLimitSleep(t *testing.T) *[]time.Duration {
+	var waits []time.Duration
+	sleep, output := rateLimitSleep, warningOutput
+	rateLimitSleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	warningOutput = io.Discard
+	t.Cleanup(func() {
+		rateLimitSleep, warningOutput = sleep, output
+		rateLimits.exhausted, rateLimits.reset = 0, time.Time{}
+	})
+	return &waits
+}
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		want   bool
+	}{
+		{name: "too many requests", status: 429, want: true},
+		{name: "primary limit", status: 403, header: http.Header{"X-Ratelimit-Remaining": {"0"}}, want: true},
+		{name: "secondary limit", status: 403, header: http.Header{"Retry-After": {"60"}}, want: true},
+		{name: "forbidden", status: 403, header: http.Header{"X-Ratelimit-Remaining": {"4999"}}, want: false},
+		{name: "ok", status: 200, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: tt.header}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			if got := isRateLimited(resp); got != tt.want {
+				t.Errorf("isRateLimited() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		header  http.Header
+		attempt int
+		want    time.Duration
+	}{
+		{name: "retry-after seconds", header: http.Header{"Retry-After": {"30"}}, want: 30 * time.Second},
+		{name: "retry-after date", header: http.Header{"Retry-After": {now.Add(time.Minute).UTC().Format(http.TimeFormat)}}, want: time.Minute},
+		{name: "github reset", header: http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Unix()+90, 10)}}, want: 90 * time.Second},
+		{name: "gitlab reset", header: http.Header{"Ratelimit-Reset": {strconv.FormatInt(now.Unix()+5, 10)}}, want: 5 * time.Second},
+		{name: "reset passed", header: http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Unix()-5, 10)}}, want: 0},
+		{name: "backoff", header: http.Header{}, attempt: 3, want: 8 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: 429, Header: tt.header}
+			if got := rateLimitDelay(resp, tt.attempt, now); got != tt.want {
+				t.Errorf("rateLimitDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubClientRetriesRateLimit(t *testing.T) {
+	waits := stubRateLimitSleep(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()+2, 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`[{"number": 7, "state": "closed"}]`))
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	pr, err := client.FindPRByCommit(context.Background(), "owner", "repo", "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr == nil || pr.Number != 7 {
+		t.Errorf("expected PR 7 after retrying, got %+v", pr)
+	}
+	if len(*waits) != 2 || (*waits)[0] <= 0 || (*waits)[0] > 2*time.Second {
+		t.Errorf("expected 2 waits until the reset, got %v", *waits)
+	}
+	if warning := RateLimitWarning(); warning != "" {
+		t.Errorf("expected no warning after recovering, got %q", warning)
+	}
+}
+
+func TestRateLimitExhausted(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		wantWaits int
+		wantReset bool
+	}{
+		{name: "retries exhausted", header: http.Header{}, wantWaits: maxRateLimitRetries},
+		{name: "reset too far away", header: http.Header{"Retry-After": {"3600"}}, wantWaits: 0, wantReset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := stubRateLimitSleep(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, values := range tt.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer server.Close()
+
+			client := newGitLabClient("test-token", "gitlab.com")
+			client.baseURL = server.URL
+
+			if _, err := client.GetPRApprovals(context.Background(), "owner", "repo", 1); err == nil {
+				t.Error("expected error for rate-limited request")
+			}
+			if len(*waits) != tt.wantWaits {
+				t.Errorf("expected %d waits, got %v", tt.wantWaits, *waits)
+			}
+			warning := RateLimitWarning()
+			if !strings.Contains(warning, "exceeded for 1 request(s)") || strings.Contains(warning, "resets at") != tt.wantReset {
+				t.Errorf("unexpected warning %q", warning)
+			}
+		})
+	}
+}
+
+func TestRateLimitRetryResendsBody(t *testing.T) {
+	stubRateLimitSleep(t)
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("POST", server.URL, bytes.NewReader([]byte(`{"query": "q"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := doRequest(newHTTPClient(time.Second), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || len(bodies) != 2 || bodies[1] != `{"query": "q"}` {
+		t.Errorf("expected the body to be sent again, got status %d and bodies %q", resp.StatusCode, bodies)
+	}
+}
+
+func TestRateLimitWaitCanceled(t *testing.T) {
+	waits := stubRateLimitSleep(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rateLimitSleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		cancel()
+		return ctx.Err()
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if _, err := doRequest(newHTTPClient(time.Second), req); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(*waits) != 1 {
+		t.Errorf("expected to stop after the first wait, got %v", *waits)
+	}
+}
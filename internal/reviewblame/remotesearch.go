@@ -0,0 +1,117 @@
+package reviewblame
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// RemoteSearchEnricher looks up the lines the PR lookup stage found no PR/MR
+// for in the repositories of the other remotes, e.g. the fork next to
+// upstream or a mirror. Lines found there take that repository, so their
+// approvals are fetched from it too. It runs between the pr-lookup and
+// approvals stages.
+type RemoteSearchEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// repositories are the "owner/name" of the other remotes, in the order
+	// they are searched
+	repositories []string
+	// cache maps commit hash to where its PR was found (nil when no
+	// repository has one)
+	cache map[string]*remoteSearchResult
+}
+
+// remoteSearchResult is the cached PR of a commit found on another remote
+type remoteSearchResult struct {
+	repository string
+	pr         *prLookupResult
+}
+
+// NewRemoteSearchEnricher creates the remote search stage
+func NewRemoteSearchEnricher(client ReviewClient, repoInfo *RepoInfo, repositories []string) *RemoteSearchEnricher {
+	return &RemoteSearchEnricher{
+		client:       client,
+		repoInfo:     repoInfo,
+		repositories: repositories,
+		cache:        make(map[string]*remoteSearchResult),
+	}
+}
+
+// Name implements Enricher
+func (e *RemoteSearchEnricher) Name() string {
+	return "remote-search"
+}
+
+// Enrich implements Enricher
+func (e *RemoteSearchEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		if lines[i].PRNumber > 0 || isUncommitted(lines[i].BlameLine) {
+			continue
+		}
+		commitHash := lines[i].CommitHash
+		result, exists := e.cache[commitHash]
+		if !exists {
+			owner, name := lineRepository(e.repoInfo, lines[i])
+			searched := owner + "/" + name
+			for _, repository := range e.repositories {
+				if repository == searched {
+					continue
+				}
+				owner, name, _ := strings.Cut(repository, "/")
+				pr, err := e.client.FindPRByCommit(ctx, owner, name, commitHash)
+				if ctx.Err() != nil {
+					// Interrupted, not failed: do not cache the search as missing
+					return ctx.Err()
+				}
+				if err == nil && pr != nil {
+					result = &remoteSearchResult{repository: repository, pr: newPRLookupResult(pr)}
+					break
+				}
+			}
+			e.cache[commitHash] = result
+		}
+		if result != nil {
+			// The PR/MR is known now, even if the primary lookup failed
+			lines[i].LookupFailed = false
+			lines[i].Repository = result.repository
+			result.pr.apply(&lines[i])
+		}
+	}
+	return nil
+}
+
+// remoteRepositories returns the "owner/name" of the remotes of repoRoot
+// that are on the host of repoInfo, other than repoInfo itself, in the
+// order git lists them. Remotes on other hosts need other clients and are
+// left out.
+func remoteRepositories(repoRoot string, repoInfo *RepoInfo) []string {
+	cmd := exec.Command("git", "remote")
+	cmd.Dir = repoRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	current := repoInfo.Owner + "/" + repoInfo.Name
+	seen := map[string]bool{current: true}
+	var repositories []string
+	for _, remote := range strings.Fields(string(output)) {
+		cmd := exec.Command("git", "remote", "get-url", remote)
+		cmd.Dir = repoRoot
+		remoteURL, err := cmd.Output()
+		if err != nil {
+			continue
+		}
+		info, err := parseRepositoryURL(strings.TrimSpace(string(remoteURL)))
+		if err != nil || !strings.EqualFold(info.Host, repoInfo.Host) {
+			continue
+		}
+		repository := info.Owner + "/" + info.Name
+		if !seen[repository] {
+			seen[repository] = true
+			repositories = append(repositories, repository)
+		}
+	}
+	return repositories
+}
@@ -0,0 +1,87 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// repositoryReviewClient is a fakeReviewClient whose PRs depend on the
+// repository, keyed by "owner/name@commit"
+type repositoryReviewClient struct {
+	fakeReviewClient
+	repositoryPRs map[string]int
+	searched      []string
+}
+
+func (c *repositoryReviewClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	key := owner + "/" + repo + "@" + commitHash
+	c.searched = append(c.searched, key)
+	if owner == "broken" {
+		return nil, errors.New("unavailable")
+	}
+	number, ok := c.repositoryPRs[key]
+	if !ok {
+		return nil, nil
+	}
+	return &PullRequest{Number: number, Title: "Mirrored"}, nil
+}
+
+func TestRemoteSearchEnricher(t *testing.T) {
+	client := &repositoryReviewClient{repositoryPRs: map[string]int{
+		"owner/repo@aaaa": 1,
+		"fork/repo@bbbb":  2,
+	}}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+	pipeline := NewEnrichmentPipeline(
+		NewPRLookupEnricher(client, repoInfo),
+		NewRemoteSearchEnricher(client, repoInfo, []string{"broken/repo", "owner/repo", "fork/repo"}),
+	)
+
+	lines, err := pipeline.Run(context.Background(), []BlameLine{
+		{CommitHash: "aaaa", LineNumber: 1},
+		{CommitHash: "bbbb", LineNumber: 2},
+		{CommitHash: "bbbb", LineNumber: 3},
+		{CommitHash: "cccc", LineNumber: 4},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if lines[0].PRNumber != 1 || lines[0].Repository != "" {
+		t.Errorf("line 1: expected PR 1 of the primary repository, got #%d of %q", lines[0].PRNumber, lines[0].Repository)
+	}
+	for _, line := range lines[1:3] {
+		if line.PRNumber != 2 || line.Repository != "fork/repo" || line.PRTitle != "Mirrored" {
+			t.Errorf("line %d: expected PR 2 of fork/repo, got #%d of %q", line.LineNumber, line.PRNumber, line.Repository)
+		}
+	}
+	if lines[3].PRNumber != 0 || lines[3].Repository != "" {
+		t.Errorf("line 4: expected no PR, got #%d of %q", lines[3].PRNumber, lines[3].Repository)
+	}
+
+	// Each commit is searched once per repository, skipping the primary one
+	want := []string{
+		"owner/repo@aaaa", "owner/repo@bbbb", "owner/repo@cccc",
+		"broken/repo@bbbb", "fork/repo@bbbb",
+		"broken/repo@cccc", "fork/repo@cccc",
+	}
+	if !reflect.DeepEqual(client.searched, want) {
+		t.Errorf("expected searches %q, got %q", want, client.searched)
+	}
+}
+
+func TestRemoteRepositories(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "git@github.com:fork/repo.git")
+	gitCommand(t, dir, "remote", "add", "upstream", "https://github.com/owner/repo.git")
+	gitCommand(t, dir, "remote", "add", "mirror", "https://gitlab.com/owner/repo.git")
+	gitCommand(t, dir, "remote", "add", "same", "https://github.com/fork/repo")
+
+	got := remoteRepositories(dir, &RepoInfo{Owner: "owner", Name: "repo", Host: "github.com"})
+	if want := []string{"fork/repo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
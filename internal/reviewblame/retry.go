@@ -0,0 +1,96 @@
+package reviewblame
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy is how requests failing with transient errors are retried:
+// server errors (500, 502, 503, 504), reset connections and timeouts
+type RetryPolicy struct {
+	// Attempts is how often a request is retried; 0 disables retries
+	Attempts int
+	// Backoff is the longest wait before the first retry; it doubles with
+	// each retry, up to MaxBackoff, and each wait is a random part of it
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// retryPolicy applies to every API request; -retries and -retry-backoff
+// change it
+var retryPolicy = RetryPolicy{Attempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// transientFailures counts requests that still failed transiently after
+// retrying
+var transientFailures struct {
+	sync.Mutex
+	count int
+}
+
+// isTransient reports whether a request failed in a way that may succeed
+// when retried
+func isTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout() ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, io.EOF)
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isIdempotent reports whether a request may be sent again without
+// repeating its effect; POSTs, e.g. posting a discussion, are never retried
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// delay returns how long to wait before retry number attempt (counting
+// from 0): a random duration up to the exponential backoff ("full jitter"),
+// so clients failing together do not retry together
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.Backoff << attempt
+	if backoff > p.MaxBackoff || backoff <= 0 {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+// recordTransientFailure records a request that failed transiently after
+// all retries
+func recordTransientFailure() {
+	transientFailures.Lock()
+	defer transientFailures.Unlock()
+	transientFailures.count++
+}
+
+// TransientErrorWarning describes the requests that failed on transient
+// errors after retrying, whose lines fall back to commit authors, or
+// returns "" when there were none
+func TransientErrorWarning() string {
+	transientFailures.Lock()
+	defer transientFailures.Unlock()
+	if transientFailures.count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d API request(s) failed after %d retries on server or network errors; affected lines show commit authors instead of approvers", transientFailures.count, retryPolicy.Attempts)
+}
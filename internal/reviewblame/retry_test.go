@@ -0,0 +1,70 @@
+package reviewblame
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoRequestRetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		want     int
+		requests int
+		failed   int
+	}{
+		{"recovers", "GET", []int{503, 502, 200}, 200, 3, 0},
+		{"gives up", "GET", []int{500, 500, 500, 500, 500}, 500, 4, 1},
+		{"client error", "GET", []int{404}, 404, 1, 0},
+		{"post", "POST", []int{503, 200}, 503, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := stubRateLimitSleep(t)
+			t.Cleanup(func() { transientFailures.count = 0 })
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := doRequest(newHTTPClient(time.Second), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want || requests != tt.requests || len(*waits) != tt.requests-1 {
+				t.Errorf("expected %d after %d requests, got %d after %d requests and %d waits", tt.want, tt.requests, resp.StatusCode, requests, len(*waits))
+			}
+			if transientFailures.count != tt.failed {
+				t.Errorf("expected %d recorded failures, got %d", tt.failed, transientFailures.count)
+			}
+		})
+	}
+
+	transientFailures.count = 2
+	defer func() { transientFailures.count = 0 }()
+	if warning := TransientErrorWarning(); !strings.HasPrefix(warning, "2 API request(s) failed after 3 retries") {
+		t.Errorf("unexpected warning %q", warning)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if delay := policy.delay(attempt); delay <= 0 || delay > max {
+				t.Fatalf("attempt %d: expected a delay up to %s, got %s", attempt, max, delay)
+			}
+		}
+	}
+}
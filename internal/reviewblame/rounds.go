@@ -0,0 +1,193 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ReviewRoundProvider is implemented by review clients that can count the
+// review iterations (review, new commits, re-review) a PR/MR went through
+type ReviewRoundProvider interface {
+	GetReviewRounds(ctx context.Context, owner, repo string, prNumber int) (int, error)
+}
+
+// GetReviewRounds counts the review rounds of a pull request. Every submitted
+// review records the head commit it was made against, so a round is a run of
+// consecutive reviews of the same head commit.
+func (c *GitHubClient) GetReviewRounds(ctx context.Context, owner, repo string, prNumber int) (int, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews", c.baseURL, owner, repo, prNumber)
+
+	rounds := 0
+	lastCommit := ""
+	err := c.listPages(ctx, url, func(dec *json.Decoder) error {
+		var review struct {
+			State    string `json:"state"`
+			CommitID string `json:"commit_id"`
+		}
+		if err := dec.Decode(&review); err != nil {
+			return err
+		}
+		if review.State == "PENDING" {
+			return nil
+		}
+		if rounds == 0 || review.CommitID != lastCommit {
+			rounds++
+			lastCommit = review.CommitID
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rounds, nil
+}
+
+// GetReviewRounds implements ReviewRoundProvider
+func (a *GitHubClientAdapter) GetReviewRounds(ctx context.Context, owner, repo string, prNumber int) (int, error) {
+	return a.client.GetReviewRounds(ctx, owner, repo, prNumber)
+}
+
+// gitLabMRVersion is a diff version of a merge request, created on every push
+type gitLabMRVersion struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// gitLabReviewNote is the subset of a discussion note needed to count review rounds
+type gitLabReviewNote struct {
+	System    bool       `json:"system"`
+	CreatedAt time.Time  `json:"created_at"`
+	Author    GitLabUser `json:"author"`
+}
+
+// GetReviewRounds counts the review rounds of a merge request: the number of
+// diff versions that received at least one comment from someone other than
+// the MR author before the next push
+func (c *GitLabClient) GetReviewRounds(ctx context.Context, owner, repo string, mrIID int) (int, error) {
+	var mr GitLabMergeRequest
+	if err := c.doJSON(ctx, "GET", c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d", mrIID)), nil, &mr, http.StatusOK); err != nil {
+		return 0, err
+	}
+
+	var versions []gitLabMRVersion
+	err := c.listPages(ctx, c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/versions", mrIID)), func(dec *json.Decoder) error {
+		var version gitLabMRVersion
+		if err := dec.Decode(&version); err != nil {
+			return err
+		}
+		versions = append(versions, version)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var reviewTimes []time.Time
+	err = c.listPages(ctx, c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/discussions", mrIID)), func(dec *json.Decoder) error {
+		var discussion struct {
+			Notes []gitLabReviewNote `json:"notes"`
+		}
+		if err := dec.Decode(&discussion); err != nil {
+			return err
+		}
+		for _, note := range discussion.Notes {
+			if !note.System && note.Author.Username != mr.Author.Username {
+				reviewTimes = append(reviewTimes, note.CreatedAt)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return countReviewRounds(versions, reviewTimes), nil
+}
+
+// countReviewRounds counts the versions during whose lifetime at least one
+// review happened. Reviews before the first version count towards it.
+func countReviewRounds(versions []gitLabMRVersion, reviewTimes []time.Time) int {
+	if len(reviewTimes) == 0 {
+		return 0
+	}
+	if len(versions) == 0 {
+		return 1
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].CreatedAt.Before(versions[j].CreatedAt)
+	})
+
+	reviewed := make(map[int]bool)
+	for _, reviewTime := range reviewTimes {
+		// Find the latest version created before the review
+		index := sort.Search(len(versions), func(i int) bool {
+			return versions[i].CreatedAt.After(reviewTime)
+		}) - 1
+		if index < 0 {
+			index = 0
+		}
+		reviewed[index] = true
+	}
+	return len(reviewed)
+}
+
+// ReviewRoundEnricher sets the review round count of each line's PR/MR. It
+// is a no-op for clients that do not implement ReviewRoundProvider.
+type ReviewRoundEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// cache maps PR to its round count (0 when the lookup failed)
+	cache map[prKey]int
+}
+
+// NewReviewRoundEnricher creates the review round stage
+func NewReviewRoundEnricher(client ReviewClient, repoInfo *RepoInfo) *ReviewRoundEnricher {
+	return &ReviewRoundEnricher{
+		client:   client,
+		repoInfo: repoInfo,
+		cache:    make(map[prKey]int),
+	}
+}
+
+// Name implements Enricher
+func (e *ReviewRoundEnricher) Name() string {
+	return "review-rounds"
+}
+
+// Enrich implements Enricher
+func (e *ReviewRoundEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	provider, ok := e.client.(ReviewRoundProvider)
+	if !ok {
+		return nil
+	}
+
+	for i := range lines {
+		prNumber := lines[i].PRNumber
+		if prNumber == 0 {
+			continue
+		}
+
+		key := prKey{lines[i].Repository, prNumber}
+		rounds, exists := e.cache[key]
+		if !exists {
+			owner, name := lineRepository(e.repoInfo, lines[i])
+			fetched, err := provider.GetReviewRounds(ctx, owner, name, prNumber)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				rounds = fetched
+			} else {
+				recordLookupFailure(e.Name(), prSubject(lines[i].Repository, prNumber), err)
+			}
+			e.cache[key] = rounds
+		}
+		lines[i].ReviewRounds = rounds
+	}
+	return nil
+}
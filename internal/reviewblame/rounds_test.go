@@ -0,0 +1,148 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGitHubGetReviewRounds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/pulls/4/reviews" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode([]map[string]string{
+			{"state": "CHANGES_REQUESTED", "commit_id": "c1"},
+			{"state": "COMMENTED", "commit_id": "c1"},
+			{"state": "COMMENTED", "commit_id": "c2"},
+			{"state": "PENDING", "commit_id": "c3"},
+			{"state": "APPROVED", "commit_id": "c4"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	rounds, err := client.GetReviewRounds(context.Background(), "owner", "repo", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rounds != 3 {
+		t.Errorf("expected 3 rounds, got %d", rounds)
+	}
+}
+
+func TestCountReviewRounds(t *testing.T) {
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	versions := []gitLabMRVersion{
+		{ID: 3, CreatedAt: at(10)},
+		{ID: 1, CreatedAt: at(0)},
+		{ID: 2, CreatedAt: at(5)},
+	}
+
+	tests := []struct {
+		name     string
+		versions []gitLabMRVersion
+		reviews  []time.Time
+		expected int
+	}{
+		{"no reviews", versions, nil, 0},
+		{"rubber stamp", versions, []time.Time{at(11)}, 1},
+		{"every version reviewed", versions, []time.Time{at(1), at(2), at(6), at(12)}, 3},
+		{"skipped version", versions, []time.Time{at(1), at(11)}, 2},
+		{"no versions", nil, []time.Time{at(1)}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countReviewRounds(tt.versions, tt.reviews); got != tt.expected {
+				t.Errorf("expected %d rounds, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGitLabGetReviewRounds(t *testing.T) {
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/projects/owner%2Frepo/merge_requests/8"
+		switch r.URL.EscapedPath() {
+		case prefix:
+			json.NewEncoder(w).Encode(GitLabMergeRequest{IID: 8, Author: GitLabUser{Username: "author"}})
+		case prefix + "/versions":
+			json.NewEncoder(w).Encode([]gitLabMRVersion{
+				{ID: 2, CreatedAt: base.Add(2 * time.Hour)},
+				{ID: 1, CreatedAt: base},
+			})
+		case prefix + "/discussions":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"notes": []map[string]interface{}{
+					{"system": false, "created_at": base.Add(time.Hour), "author": map[string]string{"username": "reviewer"}},
+					{"system": false, "created_at": base.Add(90 * time.Minute), "author": map[string]string{"username": "author"}},
+				}},
+				{"notes": []map[string]interface{}{
+					{"system": true, "created_at": base.Add(3 * time.Hour), "author": map[string]string{"username": "reviewer"}},
+				}},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	rounds, err := client.GetReviewRounds(context.Background(), "owner", "repo", 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rounds != 1 {
+		t.Errorf("expected 1 round (author replies and system notes ignored), got %d", rounds)
+	}
+}
+
+// fakeRoundClient is a fakeReviewClient that also counts review rounds
+type fakeRoundClient struct {
+	fakeReviewClient
+	rounds     map[int]int
+	roundCalls int
+}
+
+func (c *fakeRoundClient) GetReviewRounds(ctx context.Context, owner, repo string, prNumber int) (int, error) {
+	c.roundCalls++
+	return c.rounds[prNumber], nil
+}
+
+func TestReviewRoundEnricher(t *testing.T) {
+	client := &fakeRoundClient{
+		fakeReviewClient: fakeReviewClient{prs: map[string]int{"aaa": 1}},
+		rounds:           map[int]int{1: 5},
+	}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	pipeline := NewDefaultEnrichmentPipeline(client, repoInfo)
+	pipeline.Use(NewReviewRoundEnricher(client, repoInfo))
+
+	lines, err := pipeline.Run(context.Background(), []BlameLine{
+		{CommitHash: "aaa", LineNumber: 1},
+		{CommitHash: "aaa", LineNumber: 2},
+		{CommitHash: "bbb", LineNumber: 3},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if client.roundCalls != 1 {
+		t.Errorf("expected 1 round lookup, got %d", client.roundCalls)
+	}
+	if lines[0].ReviewRounds != 5 || lines[1].ReviewRounds != 5 || lines[2].ReviewRounds != 0 {
+		t.Errorf("unexpected rounds %d, %d, %d", lines[0].ReviewRounds, lines[1].ReviewRounds, lines[2].ReviewRounds)
+	}
+}
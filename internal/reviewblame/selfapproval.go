@@ -0,0 +1,86 @@
+package reviewblame
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SelfApproved reports whether every approver of the line's PR/MR is one of
+// its authors: the PR/MR author, or the commit author by name or email.
+// Such lines had no independent review.
+func (l BlameLineWithApproval) SelfApproved() bool {
+	approvers := distinctApprovers(l)
+	if len(approvers) == 0 {
+		return false
+	}
+	for _, approver := range approvers {
+		if !l.isAuthor(approver) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAuthor reports whether approver wrote the line's PR/MR or commit
+func (l BlameLineWithApproval) isAuthor(approver LineApprover) bool {
+	if l.PRAuthor != "" && strings.EqualFold(approver.Name, l.PRAuthor) {
+		return true
+	}
+	if approver.Email != "" && strings.EqualFold(approver.Email, l.AuthorEmail) {
+		return true
+	}
+	return l.Author != "" && strings.EqualFold(approver.Name, l.Author)
+}
+
+// SelfApprovedRange is a run of consecutive self-approved lines of one file
+// and commit
+type SelfApprovedRange struct {
+	Filename   string
+	CommitHash string
+	Author     string
+	Approver   string
+	PRNumber   int
+	StartLine  int
+	EndLine    int
+}
+
+// FindSelfApprovedRanges groups consecutive self-approved lines of the same
+// file and commit into ranges, in input order
+func FindSelfApprovedRanges(lines []BlameLineWithApproval) []SelfApprovedRange {
+	var ranges []SelfApprovedRange
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if !line.SelfApproved() {
+			continue
+		}
+
+		end := i
+		for end+1 < len(lines) &&
+			lines[end+1].Filename == line.Filename &&
+			lines[end+1].CommitHash == line.CommitHash &&
+			lines[end+1].LineNumber == lines[end].LineNumber+1 &&
+			lines[end+1].SelfApproved() {
+			end++
+		}
+
+		ranges = append(ranges, SelfApprovedRange{
+			Filename:   line.Filename,
+			CommitHash: line.CommitHash,
+			Author:     line.Author,
+			Approver:   line.Approver,
+			PRNumber:   line.PRNumber,
+			StartLine:  line.LineNumber,
+			EndLine:    lines[end].LineNumber,
+		})
+		i = end
+	}
+	return ranges
+}
+
+// Message explains why the range is considered self-approved
+func (r SelfApprovedRange) Message() string {
+	if r.PRNumber > 0 {
+		return fmt.Sprintf("Commit %s by %s was approved in #%d only by its author %s.", shortCommit(r.CommitHash), r.Author, r.PRNumber, r.Approver)
+	}
+	return fmt.Sprintf("Commit %s by %s was reviewed only by its author %s.", shortCommit(r.CommitHash), r.Author, r.Approver)
+}
@@ -0,0 +1,90 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfApproved(t *testing.T) {
+	approvalTime := time.Unix(1700000000, 0)
+	line := func(approvers ...LineApprover) BlameLineWithApproval {
+		l := BlameLineWithApproval{
+			BlameLine: BlameLine{CommitHash: "aaaa", Author: "Jane Doe", AuthorEmail: "jane@example.com"},
+			PRNumber:  7,
+			PRAuthor:  "jdoe",
+			Approvers: approvers,
+		}
+		if len(approvers) > 0 {
+			l.Approver = approvers[len(approvers)-1].Name
+			l.ApprovalTime = &approvalTime
+		}
+		return l
+	}
+
+	tests := []struct {
+		name string
+		line BlameLineWithApproval
+		want bool
+	}{
+		{"approved by the PR author", line(LineApprover{Name: "JDoe"}), true},
+		{"approved by the commit author's email", line(LineApprover{Name: "jane", Email: "Jane@example.com"}), true},
+		{"approved by the commit author's name", line(LineApprover{Name: "Jane Doe"}), true},
+		{"also approved by someone else", line(LineApprover{Name: "jdoe"}, LineApprover{Name: "alice"}), false},
+		{"approved by someone else", line(LineApprover{Name: "alice", Email: "alice@example.com"}), false},
+		{"not approved", line(), false},
+	}
+	for _, tt := range tests {
+		if got := tt.line.SelfApproved(); got != tt.want {
+			t.Errorf("%s: SelfApproved() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	self := line(LineApprover{Name: "jdoe"})
+	if self.ReviewState() != ReviewStateSelfApproved {
+		t.Errorf("expected the self-approved review state, got %q", self.ReviewState())
+	}
+	human, _ := NewFormatterRegistry().Lookup("human")
+	if got := human.Format([]BlameLineWithApproval{self}, FormatOptions{}); !strings.Contains(got, "(jdoe [self-approved]") {
+		t.Errorf("expected a self-approved marker, got %q", got)
+	}
+	if annotation := NewEditorAnnotation(self); annotation.Severity != AnnotationSeverityWarning {
+		t.Errorf("expected self-approved lines to be warnings, got %q", annotation.Severity)
+	}
+}
+
+func TestFindSelfApprovedRanges(t *testing.T) {
+	self := []LineApprover{{Name: "jane"}}
+	other := []LineApprover{{Name: "alice"}}
+	newLine := func(commit string, number int, approvers []LineApprover) BlameLineWithApproval {
+		return BlameLineWithApproval{
+			BlameLine: BlameLine{CommitHash: commit, Author: "jane", Filename: "main.go", LineNumber: number},
+			PRNumber:  7,
+			Approver:  approvers[0].Name,
+			Approvers: approvers,
+		}
+	}
+	lines := []BlameLineWithApproval{
+		newLine("a1b2c3d4e5f6", 1, self),
+		newLine("a1b2c3d4e5f6", 2, self),
+		newLine("b1b2c3d4e5f6", 3, other),
+		newLine("a1b2c3d4e5f6", 4, self),
+	}
+
+	ranges := FindSelfApprovedRanges(lines)
+	if len(ranges) != 2 || ranges[0].StartLine != 1 || ranges[0].EndLine != 2 || ranges[1].StartLine != 4 {
+		t.Fatalf("expected ranges 1-2 and 4, got %+v", ranges)
+	}
+	want := "Commit a1b2c3d4 by jane was approved in #7 only by its author jane."
+	if got := ranges[0].Message(); got != want {
+		t.Errorf("Message() = %q, want %q", got, want)
+	}
+
+	err := reportFailures(Options{ForbidSelfApproval: true}, lines, nil)
+	if err == nil || !strings.Contains(err.Error(), "3 line(s) were approved only by their own author") {
+		t.Errorf("expected -forbid-self-approval to fail on 3 lines, got %v", err)
+	}
+	if err := reportFailures(Options{}, lines, nil); err != nil {
+		t.Errorf("expected self-approval to be allowed by default, got %v", err)
+	}
+}
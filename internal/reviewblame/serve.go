@@ -0,0 +1,407 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBlameRequestBytes caps the body of a blame request
+const maxBlameRequestBytes = 1 << 20
+
+// BlameRequest is the body of POST /blame
+type BlameRequest struct {
+	// Repo is a path inside the repository; relative paths are resolved
+	// against the server's working directory
+	Repo string `json:"repo"`
+	// File is the file to annotate, relative to the repository root
+	File string `json:"file"`
+	// Range is an optional line range in any syntax git blame -L accepts
+	Range string `json:"range,omitempty"`
+	// Rev annotates the file as of a revision instead of the working tree
+	Rev string `json:"rev,omitempty"`
+}
+
+// BlameResponse is the response of POST /blame: the lines in the policy
+// input representation and, for editor plugins, their annotations
+type BlameResponse struct {
+	File        string             `json:"file"`
+	Lines       []AnnotationRecord `json:"lines"`
+	Annotations []EditorAnnotation `json:"annotations"`
+}
+
+// BlameServer answers blame requests over HTTP. It keeps one enrichment
+// pipeline per repository, so the commits, PRs/MRs and approvals looked up
+// for one request are cached for the next ones until the pipeline is older
+// than CacheTTL.
+type BlameServer struct {
+	// ConfigPath, Offline and Backend apply to every repository
+	ConfigPath string
+	Offline    bool
+	Backend    string
+	// CacheTTL is how long a repository's lookups are reused; 0 keeps them
+	// for the lifetime of the server
+	CacheTTL time.Duration
+	// Warmer serves POST /webhook of WebhookHandler, verified with
+	// WebhookSecret
+	Warmer        *CacheWarmer
+	WebhookSecret string
+	// Roots, when set, are the directories whose repositories are served;
+	// others are answered 403
+	Roots []string
+	// AllowedHosts are host names accepted in the Host header of blame
+	// requests besides localhost and IP addresses
+	AllowedHosts []string
+
+	githubToken string
+	gitlabToken string
+	now         func() time.Time
+
+	mu    sync.Mutex
+	repos map[string]*servedRepository
+}
+
+// servedRepository is a repository with its warm pipeline
+type servedRepository struct {
+	// mu serializes the requests of the repository, as the pipeline stages
+	// and their caches are not safe for concurrent use
+	mu       sync.Mutex
+	root     string
+	pipeline *EnrichmentPipeline
+	created  time.Time
+}
+
+// NewBlameServer creates a server using the given API tokens
+func NewBlameServer(githubToken, gitlabToken string) *BlameServer {
+	return &BlameServer{
+		githubToken: githubToken,
+		gitlabToken: gitlabToken,
+		now:         time.Now,
+		repos:       make(map[string]*servedRepository),
+	}
+}
+
+// Handler returns the HTTP API: POST /blame and GET /healthz
+func (s *BlameServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /blame", s.localOnly(s.handleBlame))
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	return mux
+}
+
+// WebhookHandler returns the webhook API: POST /webhook and GET /healthz.
+// It is served on its own listener, which can be exposed to GitHub or
+// GitLab without exposing the unauthenticated blame API.
+func (s *BlameServer) WebhookHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", s.handleWebhook)
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	return mux
+}
+
+// handleHealthz implements GET /healthz
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// localOnly rejects requests from web browsers. A page on any site can make
+// the browser post to 127.0.0.1, directly or by rebinding its own host name
+// to it; editor plugins and CI jobs send neither an Origin header nor a
+// host name of another site.
+func (s *BlameServer) localOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			writeJSONError(w, http.StatusForbidden, fmt.Errorf("requests from web pages (Origin %s) are not accepted", origin))
+			return
+		}
+		host := r.Host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			host = name
+		}
+		host = strings.Trim(host, "[]")
+		if !strings.EqualFold(host, "localhost") && net.ParseIP(host) == nil && !containsFold(s.AllowedHosts, host) {
+			writeJSONError(w, http.StatusForbidden, fmt.Errorf("host %s is not accepted; add it with -allow-host", host))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// handleBlame implements POST /blame
+func (s *BlameServer) handleBlame(w http.ResponseWriter, r *http.Request) {
+	var request BlameRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBlameRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	file, lines, err := s.Blame(r.Context(), request, "")
+	if err != nil {
+		status := http.StatusInternalServerError
+		var requestErr *blameRequestError
+		if errors.As(err, &requestErr) {
+			status = requestErr.status
+		}
+		writeJSONError(w, status, err)
+		return
+	}
+
+	response := BlameResponse{
+		File:        file,
+		Lines:       make([]AnnotationRecord, len(lines)),
+		Annotations: make([]EditorAnnotation, len(lines)),
+	}
+	for i, line := range lines {
+		response.Lines[i] = NewAnnotationRecord(line)
+		response.Annotations[i] = NewEditorAnnotation(line)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// blameRequestError is an error caused by a blame request rather than the
+// server, with the HTTP status it is answered with
+type blameRequestError struct {
+	status int
+	err    error
+}
+
+// Error implements error
+func (e *blameRequestError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *blameRequestError) Unwrap() error {
+	return e.err
+}
+
+// Blame annotates the requested file with the warm pipeline of its
+// repository, and returns its path relative to the repository root.
+// contents, when not empty, is a file with the contents to annotate in
+// place of the working tree file, as with -contents.
+func (s *BlameServer) Blame(ctx context.Context, request BlameRequest, contents string) (string, []BlameLineWithApproval, error) {
+	if request.Repo == "" || request.File == "" {
+		return "", nil, &blameRequestError{http.StatusBadRequest, errors.New("repo and file are required")}
+	}
+	if strings.HasPrefix(request.Rev, "-") {
+		return "", nil, &blameRequestError{http.StatusBadRequest, fmt.Errorf("rev %q is not a revision", request.Rev)}
+	}
+
+	repo, err := s.repository(ctx, request.Repo)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, errRepositoryNotServed) {
+			status = http.StatusForbidden
+		}
+		return "", nil, &blameRequestError{status, err}
+	}
+
+	filePath := request.File
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(repo.root, filepath.FromSlash(filePath))
+	}
+	if !isWithin(repo.root, filePath) {
+		return "", nil, &blameRequestError{http.StatusBadRequest, fmt.Errorf("%s is outside the repository", request.File)}
+	}
+	opts := BlameOptions{Backend: s.Backend, Contents: contents}
+	if request.Range != "" {
+		opts.LineRanges = []string{request.Range}
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	blameLines, err := ExecuteGitBlameAt(ctx, repo.root, filePath, request.Rev, opts)
+	if err != nil {
+		return "", nil, &blameRequestError{http.StatusUnprocessableEntity, fmt.Errorf("could not blame %s: %w", request.File, err)}
+	}
+	lines, err := repo.pipeline.Run(ctx, blameLines)
+	if err != nil {
+		return "", nil, err
+	}
+	return displayPath(repo.root, filePath), lines, nil
+}
+
+// errRepositoryNotServed is returned for repositories outside the roots of
+// the server
+var errRepositoryNotServed = errors.New("repository is not served")
+
+// repository returns the served repository containing path, opening it and
+// creating its pipeline on first use or when its lookups have expired
+func (s *BlameServer) repository(ctx context.Context, path string) (*servedRepository, error) {
+	repoRoot, err := FindGitRoot(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not part of a Git repository: %w", path, err)
+	}
+	if !s.serves(repoRoot) {
+		return nil, fmt.Errorf("%s: %w; serve it with -root", repoRoot, errRepositoryNotServed)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repos[repoRoot]
+	if repo != nil && (s.CacheTTL == 0 || s.now().Sub(repo.created) < s.CacheTTL) {
+		return repo, nil
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(repoRoot, s.ConfigPath, "")
+	if err != nil {
+		return nil, err
+	}
+	githubToken, gitlabToken := resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, s.githubToken, s.gitlabToken)
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{Offline: s.Offline}, githubToken, gitlabToken)
+	if err != nil {
+		return nil, err
+	}
+	repo = &servedRepository{root: repoRoot, pipeline: pipeline, created: s.now()}
+	s.repos[repoRoot] = repo
+	return repo, nil
+}
+
+// serves reports whether the repository at repoRoot lies in one of the roots
+func (s *BlameServer) serves(repoRoot string) bool {
+	if len(s.Roots) == 0 {
+		return true
+	}
+	for _, root := range s.Roots {
+		if isWithin(root, repoRoot) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSON writes value as the JSON response body with the given status
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeJSONError writes err as a JSON error response
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// runServe implements the serve subcommand
+func runServe(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:7465", "Address to listen on; keep it on loopback, the API reads the repositories under -root")
+	cacheTTL := flags.Duration("cache-ttl", 10*time.Minute, "How long the lookups of a repository are reused before approvals are fetched again (0 for the lifetime of the server)")
+	configPath := flags.String("config", "", "Path to the config file")
+	offline := flags.Bool("offline", false, "Find PRs/MRs from merge commits without API calls")
+	backend := flags.String("backend", BackendExec, "Blame backend: exec, incremental or go-git")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	webhookAddr := flags.String("webhook-addr", "", "Also listen on the address for GitHub and GitLab webhooks at POST /webhook, and cache merged PRs/MRs and their approvers")
+	cachePath := flags.String("cache-path", "", "Database file of the persistent cache (default: in the user cache directory)")
+	var roots, allowedHosts stringsFlag
+	flags.Var(&roots, "root", "Serve the repositories in the directory; may be repeated (default: the working directory)")
+	flags.Var(&allowedHosts, "allow-host", "Accept blame requests addressed to the host name besides localhost and IP addresses; may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("serve takes no arguments\nUsage: git-review-blame serve [-addr <host:port>] [-webhook-addr <host:port>]")
+	}
+	if *cacheTTL < 0 {
+		return fmt.Errorf("-cache-ttl must not be negative")
+	}
+	backendName, err := ParseBackend(*backend)
+	if err != nil {
+		return err
+	}
+
+	server := NewBlameServer(githubToken, gitlabToken)
+	server.ConfigPath = *configPath
+	server.Offline = *offline
+	server.Backend = backendName
+	server.CacheTTL = *cacheTTL
+	server.AllowedHosts = allowedHosts
+	if len(roots) == 0 {
+		roots = stringsFlag{"."}
+	}
+	for _, root := range roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		// Repository roots are found with symlinks resolved
+		if resolved, err := filepath.EvalSymlinks(absRoot); err == nil {
+			absRoot = resolved
+		}
+		server.Roots = append(server.Roots, absRoot)
+	}
+	if *webhookAddr != "" {
+		server.WebhookSecret = os.Getenv(WebhookSecretEnv)
+		if server.WebhookSecret == "" {
+			return fmt.Errorf("-webhook-addr needs the webhook secret in %s", WebhookSecretEnv)
+		}
+		// Blame requests read what the webhooks store
+		cacheSettings = CacheConfig{Backend: CacheBackendSQLite, Path: *cachePath}
+		store, err := openCache(nil)
+		if err != nil {
+			return err
+		}
+		server.Warmer = NewCacheWarmer(store, githubToken, gitlabToken)
+	} else {
+		cacheSettings.Path = *cachePath
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "listening on http://%s\n", listener.Addr())
+	if server.Warmer == nil {
+		return serveHTTP(ctx, listener, server.Handler())
+	}
+
+	webhookListener, err := net.Listen("tcp", *webhookAddr)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "listening for webhooks on http://%s\n", webhookListener.Addr())
+	// Either server failing stops the other
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 2)
+	go func() { errs <- serveHTTP(ctx, listener, server.Handler()) }()
+	go func() { errs <- serveHTTP(ctx, webhookListener, server.WebhookHandler()) }()
+	err = <-errs
+	cancel()
+	<-errs
+	return err
+}
+
+// serveHTTP serves handler on listener until ctx is done
+func serveHTTP(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
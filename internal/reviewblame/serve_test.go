@@ -0,0 +1,181 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlameServer(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n\nfunc main() {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "-A")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main (#12)\n\nReviewed-by: Alice <alice@example.com>")
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	server := NewBlameServer("", "")
+	server.Offline = true
+	server.CacheTTL = time.Minute
+	server.now = func() time.Time { return now }
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	post := func(body string) (int, map[string]json.RawMessage) {
+		t.Helper()
+		resp, err := http.Post(httpServer.URL+"/blame", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+		return resp.StatusCode, decoded
+	}
+
+	status, body := post(`{"repo": "` + filepath.Join(dir, "src") + `", "file": "src/main.go", "range": "3,4"}`)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body["error"])
+	}
+	var lines []AnnotationRecord
+	var annotations []EditorAnnotation
+	if err := json.Unmarshal(body["lines"], &lines); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(body["annotations"], &annotations); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0].Line != 3 || lines[0].PRNumber != 12 || lines[0].Approver != "Alice" || lines[1].Content != "}" {
+		t.Errorf("unexpected lines %+v", lines)
+	}
+	if len(annotations) != 2 || annotations[0].Text != "Alice (#12)" || annotations[0].File != "src/main.go" {
+		t.Errorf("unexpected annotations %+v", annotations)
+	}
+
+	// The pipeline is reused until it expires
+	root, err := FindGitRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := server.repos[root].pipeline
+	if status, _ := post(`{"repo": "` + dir + `", "file": "src/main.go"}`); status != http.StatusOK || server.repos[root].pipeline != first {
+		t.Errorf("expected the cached pipeline to be reused, got status %d", status)
+	}
+	now = now.Add(2 * time.Minute)
+	if status, _ := post(`{"repo": "` + dir + `", "file": "src/main.go"}`); status != http.StatusOK || server.repos[root].pipeline == first {
+		t.Errorf("expected an expired pipeline to be replaced, got status %d", status)
+	}
+
+	errorTests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"malformed", `{"repo": `, http.StatusBadRequest},
+		{"unknown field", `{"repo": "` + dir + `", "file": "src/main.go", "lines": "1,2"}`, http.StatusBadRequest},
+		{"missing file", `{"repo": "` + dir + `"}`, http.StatusBadRequest},
+		{"outside the repository", `{"repo": "` + dir + `", "file": "../secret.go"}`, http.StatusBadRequest},
+		{"not a repository", `{"repo": "` + t.TempDir() + `", "file": "main.go"}`, http.StatusNotFound},
+		{"untracked file", `{"repo": "` + dir + `", "file": "src/missing.go"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := post(tt.body)
+			if status != tt.status || len(body["error"]) == 0 {
+				t.Errorf("expected status %d with an error, got %d: %v", tt.status, status, body)
+			}
+		})
+	}
+
+	resp, err := http.Get(httpServer.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected /healthz to answer 200, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get(httpServer.URL + "/blame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET /blame to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestBlameServerRejectsUntrustedRequests(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "add", "-A")
+	gitCommand(t, dir, "commit", "-q", "-m", "Add main")
+	root, err := FindGitRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewBlameServer("", "")
+	server.Offline = true
+	server.Roots = []string{root}
+	server.AllowedHosts = []string{"blame.internal"}
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	outside := t.TempDir()
+	gitCommand(t, outside, "init", "-q", "-b", "main")
+
+	tests := []struct {
+		name    string
+		body    string
+		headers map[string]string
+		status  int
+	}{
+		{"served", `{"repo": "` + dir + `", "file": "main.go"}`, nil, http.StatusOK},
+		{"allowed host name", `{"repo": "` + dir + `", "file": "main.go"}`, map[string]string{"Host": "blame.internal:7465"}, http.StatusOK},
+		{"option as rev", `{"repo": "` + dir + `", "file": "main.go", "rev": "--contents=/etc/passwd"}`, nil, http.StatusBadRequest},
+		{"web page", `{"repo": "` + dir + `", "file": "main.go"}`, map[string]string{"Origin": "https://attacker.example.com"}, http.StatusForbidden},
+		{"rebound host name", `{"repo": "` + dir + `", "file": "main.go"}`, map[string]string{"Host": "attacker.example.com:7465"}, http.StatusForbidden},
+		{"outside the roots", `{"repo": "` + outside + `", "file": "main.go"}`, nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", httpServer.URL+"/blame", strings.NewReader(tt.body))
+			for name, value := range tt.headers {
+				if name == "Host" {
+					req.Host = value
+				}
+				req.Header.Set(name, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	if _, _, err := gitBlameArgs(root, filepath.Join(root, "main.go"), "--output=/tmp/x", BlameOptions{}, "--porcelain"); err == nil {
+		t.Error("expected gitBlameArgs to reject a rev starting with -")
+	}
+}
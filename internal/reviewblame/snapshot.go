@@ -0,0 +1,155 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotDirName is the directory inside the git dir where snapshots are kept
+const snapshotDirName = "git-review-blame/snapshots"
+
+// AnnotationSnapshot records the annotation state of a path at a point in time
+type AnnotationSnapshot struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Commit    string         `json:"commit"`
+	Path      string         `json:"path"`
+	Lines     []SnapshotLine `json:"lines"`
+}
+
+// SnapshotLine is the persisted subset of a BlameLineWithApproval
+type SnapshotLine struct {
+	Filename   string `json:"filename"`
+	LineNumber int    `json:"line"`
+	CommitHash string `json:"commit"`
+	Author     string `json:"author"`
+	Content    string `json:"content"`
+	PRNumber   int    `json:"pr,omitempty"`
+	Approver   string `json:"approver,omitempty"`
+}
+
+// NewAnnotationSnapshot captures annotated lines for path at the given commit
+func NewAnnotationSnapshot(path, commit string, lines []BlameLineWithApproval, now time.Time) *AnnotationSnapshot {
+	snapshot := &AnnotationSnapshot{
+		CreatedAt: now.UTC(),
+		Commit:    commit,
+		Path:      path,
+		Lines:     make([]SnapshotLine, 0, len(lines)),
+	}
+	for _, line := range lines {
+		snapshot.Lines = append(snapshot.Lines, SnapshotLine{
+			Filename:   line.Filename,
+			LineNumber: line.LineNumber,
+			CommitHash: line.CommitHash,
+			Author:     line.Author,
+			Content:    line.Content,
+			PRNumber:   line.PRNumber,
+			Approver:   line.Approver,
+		})
+	}
+	return snapshot
+}
+
+// SnapshotStore keeps annotation snapshots as JSON files inside the git directory
+type SnapshotStore struct {
+	dir string
+}
+
+// NewSnapshotStore opens the snapshot store of the repository at repoRoot
+func NewSnapshotStore(repoRoot string) (*SnapshotStore, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
+	cmd.Dir = repoRoot
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	gitDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repoRoot, gitDir)
+	}
+
+	return &SnapshotStore{dir: filepath.Join(gitDir, filepath.FromSlash(snapshotDirName))}, nil
+}
+
+// snapshotFileName returns a file name that sorts chronologically and is unique per path
+func snapshotFileName(snapshot *AnnotationSnapshot) string {
+	scope := strings.NewReplacer("/", "_", "\\", "_", ".", "_").Replace(snapshot.Path)
+	return fmt.Sprintf("%d-%s.json", snapshot.CreatedAt.Unix(), scope)
+}
+
+// Save writes a snapshot to the store
+func (s *SnapshotStore) Save(snapshot *AnnotationSnapshot) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(s.dir, snapshotFileName(snapshot)), data, 0644)
+}
+
+// List returns all stored snapshots of path, oldest first
+func (s *SnapshotStore) List(path string) ([]*AnnotationSnapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snapshots []*AnnotationSnapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var snapshot AnnotationSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("corrupt snapshot %s: %w", entry.Name(), err)
+		}
+		if snapshot.Path == path {
+			snapshots = append(snapshots, &snapshot)
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+
+	return snapshots, nil
+}
+
+// Baseline returns the most recent snapshot of path taken at or before cutoff,
+// falling back to the oldest snapshot when all are newer. It returns nil when
+// no snapshot of path exists.
+func (s *SnapshotStore) Baseline(path string, cutoff time.Time) (*AnnotationSnapshot, error) {
+	snapshots, err := s.List(path)
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+
+	baseline := snapshots[0]
+	for _, snapshot := range snapshots {
+		if snapshot.CreatedAt.After(cutoff) {
+			break
+		}
+		baseline = snapshot
+	}
+	return baseline, nil
+}
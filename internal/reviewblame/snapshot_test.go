@@ -0,0 +1,64 @@
+package reviewblame
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	dir := t.TempDir()
+	if err := exec.Command("git", "init", "-q", dir).Run(); err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+
+	store, err := NewSnapshotStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if baseline, err := store.Baseline("src", time.Now()); err != nil || baseline != nil {
+		t.Fatalf("expected no baseline in empty store, got %v, %v", baseline, err)
+	}
+
+	day := 24 * time.Hour
+	now := time.Unix(1700000000, 0)
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "src/a.go", LineNumber: 1, Content: "x"}, Approver: "jane"},
+	}
+	for _, age := range []time.Duration{10 * day, 8 * day, 1 * day} {
+		if err := store.Save(NewAnnotationSnapshot("src", "c", lines, now.Add(-age))); err != nil {
+			t.Fatalf("unexpected error saving snapshot: %v", err)
+		}
+	}
+	if err := store.Save(NewAnnotationSnapshot("other", "c", lines, now)); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := store.List("src")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("expected 3 snapshots for src, got %d", len(snapshots))
+	}
+	if snapshots[0].Lines[0].Approver != "jane" {
+		t.Errorf("expected snapshot lines to round-trip, got %+v", snapshots[0].Lines)
+	}
+
+	baseline, err := store.Baseline("src", now.Add(-7*day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !baseline.CreatedAt.Equal(now.Add(-8 * day)) {
+		t.Errorf("expected 8 day old baseline, got %v", baseline.CreatedAt)
+	}
+
+	baseline, err = store.Baseline("src", now.Add(-30*day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !baseline.CreatedAt.Equal(now.Add(-10 * day)) {
+		t.Errorf("expected oldest snapshot as fallback baseline, got %v", baseline.CreatedAt)
+	}
+}
@@ -0,0 +1,422 @@
+package reviewblame
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// CacheConfig selects the persistent cache of PR/MR lookups
+type CacheConfig struct {
+	// Backend is "sqlite", or empty for no persistent cache
+	Backend string `json:"backend"`
+	// Path is the database file (default: DefaultCachePath); relative
+	// paths are relative to the user config file
+	Path string `json:"path"`
+}
+
+// validate checks the backend
+func (c *CacheConfig) validate() error {
+	switch c.Backend {
+	case "", CacheBackendSQLite:
+		return nil
+	}
+	return fmt.Errorf("cache: unknown backend %q (expected %s)", c.Backend, CacheBackendSQLite)
+}
+
+// CacheBackendSQLite stores lookups in a SQLite database
+const CacheBackendSQLite = "sqlite"
+
+// cacheSchema creates the tables of the cache. Commits map to the merged
+// PR/MR they came from, and PRs/MRs hold their details and approvers; both
+// are keyed by host and repository, so one database serves every clone.
+const cacheSchema = `
+CREATE TABLE IF NOT EXISTS commits (
+	host        TEXT NOT NULL,
+	repository  TEXT NOT NULL,
+	commit_hash TEXT NOT NULL,
+	pr_number   INTEGER NOT NULL,
+	fetched_at  INTEGER NOT NULL,
+	PRIMARY KEY (host, repository, commit_hash)
+);
+CREATE INDEX IF NOT EXISTS commits_by_pr ON commits (host, repository, pr_number);
+CREATE TABLE IF NOT EXISTS pull_requests (
+	host       TEXT NOT NULL,
+	repository TEXT NOT NULL,
+	number     INTEGER NOT NULL,
+	details    TEXT NOT NULL,
+	approvers  TEXT,
+	fetched_at INTEGER NOT NULL,
+	PRIMARY KEY (host, repository, number)
+);
+`
+
+// DefaultCachePath returns the database file shared by all repositories,
+// under the user cache directory
+func DefaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "git-review-blame", "cache.db"), nil
+}
+
+// SQLiteCache persists the PRs/MRs of commits and their approvers across
+// runs, repositories and the processes sharing the database, e.g. serve
+// and CI jobs. Only merged PRs/MRs are stored: their commits and approvals
+// no longer change.
+type SQLiteCache struct {
+	db   *sql.DB
+	path string
+}
+
+// OpenSQLiteCache opens or creates the cache database at path
+func OpenSQLiteCache(path string) (*SQLiteCache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("could not create the cache directory: %w", err)
+	}
+	// WAL lets readers proceed while another process writes; writers wait
+	// for each other up to the busy timeout
+	dsn := "file:" + path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the cache %s: %w", path, err)
+	}
+	if _, err := db.Exec(cacheSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not open the cache %s: %w", path, err)
+	}
+	return &SQLiteCache{db: db, path: path}, nil
+}
+
+// cacheSettings holds the -cache and -cache-path flags, which override the
+// cache of the config file
+var cacheSettings CacheConfig
+
+// openCaches are the caches opened by this process, by path; runs of serve
+// share one database handle
+var openCaches struct {
+	sync.Mutex
+	byPath map[string]*SQLiteCache
+}
+
+// openCache opens the cache selected by the flags or else the config file,
+// or returns nil when none is selected. Caches stay open until the process
+// exits.
+func openCache(fromFile *CacheConfig) (*SQLiteCache, error) {
+	settings := cacheSettings
+	if fromFile != nil {
+		if settings.Backend == "" {
+			settings.Backend = fromFile.Backend
+		}
+		if settings.Path == "" {
+			settings.Path = fromFile.Path
+		}
+	}
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	if settings.Backend == "" {
+		return nil, nil
+	}
+	path := settings.Path
+	if path == "" {
+		defaultPath, err := DefaultCachePath()
+		if err != nil {
+			return nil, fmt.Errorf("could not locate the cache: %w", err)
+		}
+		path = defaultPath
+	}
+
+	openCaches.Lock()
+	defer openCaches.Unlock()
+	if cache, exists := openCaches.byPath[path]; exists {
+		return cache, nil
+	}
+	cache, err := OpenSQLiteCache(path)
+	if err != nil {
+		return nil, err
+	}
+	if openCaches.byPath == nil {
+		openCaches.byPath = make(map[string]*SQLiteCache)
+	}
+	openCaches.byPath[path] = cache
+	return cache, nil
+}
+
+// Close closes the database
+func (c *SQLiteCache) Close() error {
+	return c.db.Close()
+}
+
+// cachedPR is the stored form of a prLookupResult
+type cachedPR struct {
+	Number       int        `json:"number"`
+	Title        string     `json:"title"`
+	Body         string     `json:"body"`
+	Author       string     `json:"author"`
+	Branch       string     `json:"branch"`
+	Labels       []string   `json:"labels,omitempty"`
+	LinkedIssues []string   `json:"linked_issues,omitempty"`
+	MergedAt     *time.Time `json:"merged_at"`
+}
+
+// cachedApprover is the stored form of a LineApprover, with its links
+type cachedApprover struct {
+	Name      string     `json:"name"`
+	Email     string     `json:"email,omitempty"`
+	Time      *time.Time `json:"time,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	AvatarURL string     `json:"avatar_url,omitempty"`
+}
+
+// CommitPR returns the merged PR/MR of a commit, or nil when it is not
+// cached
+func (c *SQLiteCache) CommitPR(ctx context.Context, host, repository, commitHash string) (*prLookupResult, error) {
+	var details string
+	err := c.db.QueryRowContext(ctx, `
+		SELECT p.details FROM commits c
+		JOIN pull_requests p ON p.host = c.host AND p.repository = c.repository AND p.number = c.pr_number
+		WHERE c.host = ? AND c.repository = ? AND c.commit_hash = ?`,
+		host, repository, commitHash).Scan(&details)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pr cachedPR
+	if err := json.Unmarshal([]byte(details), &pr); err != nil {
+		return nil, err
+	}
+	return &prLookupResult{
+		number:       pr.Number,
+		title:        pr.Title,
+		body:         pr.Body,
+		author:       pr.Author,
+		branch:       pr.Branch,
+		labels:       pr.Labels,
+		linkedIssues: pr.LinkedIssues,
+		mergedAt:     pr.MergedAt,
+	}, nil
+}
+
+// StoreCommitPR records the PR/MR of a commit if it was merged
+func (c *SQLiteCache) StoreCommitPR(ctx context.Context, host, repository, commitHash string, result *prLookupResult) error {
+	if result == nil || result.failed || result.number == 0 || result.mergedAt == nil {
+		return nil
+	}
+	details, err := json.Marshal(cachedPR{
+		Number:       result.number,
+		Title:        result.title,
+		Body:         result.body,
+		Author:       result.author,
+		Branch:       result.branch,
+		Labels:       result.labels,
+		LinkedIssues: result.linkedIssues,
+		MergedAt:     result.mergedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := time.Now().Unix()
+	// Keep the stored approvers of a PR already known from another commit
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO pull_requests (host, repository, number, details, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (host, repository, number) DO UPDATE SET details = excluded.details, fetched_at = excluded.fetched_at`,
+		host, repository, result.number, string(details), now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO commits (host, repository, commit_hash, pr_number, fetched_at) VALUES (?, ?, ?, ?, ?)`,
+		host, repository, commitHash, result.number, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Approvers returns the approvers of a PR/MR and whether they are cached;
+// a cached PR/MR may have no approvers
+func (c *SQLiteCache) Approvers(ctx context.Context, host, repository string, number int) ([]LineApprover, bool, error) {
+	var stored sql.NullString
+	err := c.db.QueryRowContext(ctx, `
+		SELECT approvers FROM pull_requests WHERE host = ? AND repository = ? AND number = ?`,
+		host, repository, number).Scan(&stored)
+	if err == sql.ErrNoRows || err == nil && !stored.Valid {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var cached []cachedApprover
+	if err := json.Unmarshal([]byte(stored.String), &cached); err != nil {
+		return nil, false, err
+	}
+	var approvers []LineApprover
+	for _, approver := range cached {
+		approvers = append(approvers, LineApprover(approver))
+	}
+	return approvers, true, nil
+}
+
+// StoreApprovers records the approvers of a PR/MR already stored as merged
+func (c *SQLiteCache) StoreApprovers(ctx context.Context, host, repository string, number int, approvers []LineApprover) error {
+	cached := make([]cachedApprover, 0, len(approvers))
+	for _, approver := range approvers {
+		cached = append(cached, cachedApprover(approver))
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	_, err = c.db.ExecContext(ctx, `
+		UPDATE pull_requests SET approvers = ?, fetched_at = ? WHERE host = ? AND repository = ? AND number = ?`,
+		string(data), time.Now().Unix(), host, repository, number)
+	return err
+}
+
+// CacheStats are the cached lookups of one repository
+type CacheStats struct {
+	Host         string
+	Repository   string
+	Commits      int
+	PullRequests int
+	// Approved counts the PRs/MRs whose approvers are cached
+	Approved int
+	Updated  time.Time
+}
+
+// Stats returns the cached lookups per repository, ordered by host and
+// repository
+func (c *SQLiteCache) Stats(ctx context.Context) ([]CacheStats, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT p.host, p.repository,
+			(SELECT COUNT(*) FROM commits c WHERE c.host = p.host AND c.repository = p.repository),
+			COUNT(*), COUNT(p.approvers), MAX(p.fetched_at)
+		FROM pull_requests p
+		GROUP BY p.host, p.repository
+		ORDER BY p.host, p.repository`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []CacheStats
+	for rows.Next() {
+		var entry CacheStats
+		var updated int64
+		if err := rows.Scan(&entry.Host, &entry.Repository, &entry.Commits, &entry.PullRequests, &entry.Approved, &updated); err != nil {
+			return nil, err
+		}
+		entry.Updated = time.Unix(updated, 0)
+		stats = append(stats, entry)
+	}
+	return stats, rows.Err()
+}
+
+// Clear removes the cached lookups of the repository owner/name on host, or
+// of all repositories when host is empty
+func (c *SQLiteCache) Clear(ctx context.Context, host, repository string) error {
+	for _, table := range []string{"commits", "pull_requests"} {
+		query := "DELETE FROM " + table
+		var args []interface{}
+		if host != "" {
+			query += " WHERE host = ? AND repository = ?"
+			args = append(args, host, repository)
+		}
+		if _, err := c.db.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const cacheUsage = "usage: git-review-blame cache stats|clear [-path <file>] [-repo <host>/<owner>/<name>]"
+
+// runCache implements the cache subcommand
+func runCache(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	return runCacheCommand(ctx, args, os.Stdout)
+}
+
+// runCacheCommand shows the cached lookups per repository, or removes those
+// of one repository or all of them
+func runCacheCommand(ctx context.Context, args []string, output io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(cacheUsage)
+	}
+	flags := flag.NewFlagSet("cache "+args[0], flag.ContinueOnError)
+	pathFlag := flags.String("path", "", "Database file of the cache (default: in the user cache directory)")
+	repoFlag := flags.String("repo", "", "Clear only the repository, e.g. github.com/owner/name")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	path := *pathFlag
+	if path == "" {
+		defaultPath, err := DefaultCachePath()
+		if err != nil {
+			return fmt.Errorf("could not locate the cache: %w", err)
+		}
+		path = defaultPath
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no cache at %s; enable it with -cache sqlite", path)
+	}
+	cache, err := OpenSQLiteCache(path)
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	switch args[0] {
+	case "stats":
+		stats, err := cache.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "Cache: %s\n", cache.path)
+		if len(stats) == 0 {
+			fmt.Fprintln(output, "No cached lookups")
+		}
+		for _, entry := range stats {
+			fmt.Fprintf(output, "%s/%s: %d commits, %d PRs/MRs (%d with approvers), updated %s\n",
+				entry.Host, entry.Repository, entry.Commits, entry.PullRequests, entry.Approved, entry.Updated.Format("2006-01-02 15:04"))
+		}
+	case "clear":
+		var host, repository string
+		if *repoFlag != "" {
+			var found bool
+			host, repository, found = strings.Cut(*repoFlag, "/")
+			if !found || !strings.Contains(repository, "/") {
+				return fmt.Errorf("-repo must be <host>/<owner>/<name>, got %q", *repoFlag)
+			}
+		}
+		if err := cache.Clear(ctx, host, repository); err != nil {
+			return err
+		}
+		if host == "" {
+			fmt.Fprintf(output, "Cleared %s\n", cache.path)
+		} else {
+			fmt.Fprintf(output, "Cleared %s from %s\n", *repoFlag, cache.path)
+		}
+	default:
+		return fmt.Errorf("unknown cache command %q\n%s", args[0], cacheUsage)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mergedReviewClient reports the PRs of fakeReviewClient as merged
+type mergedReviewClient struct {
+	*fakeReviewClient
+	mergedAt time.Time
+}
+
+func (c *mergedReviewClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	pr, err := c.fakeReviewClient.FindPRByCommit(ctx, owner, repo, commitHash)
+	if pr != nil {
+		pr.MergedAt = &c.mergedAt
+	}
+	return pr, err
+}
+
+func TestSQLiteCache(t *testing.T) {
+	ctx := context.Background()
+	cache, err := OpenSQLiteCache(filepath.Join(t.TempDir(), "cache", "cache.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	mergedAt := time.Unix(1700000000, 0).UTC()
+	results := []struct {
+		commitHash string
+		result     *prLookupResult
+		stored     bool
+	}{
+		{"aaaa", &prLookupResult{number: 1, title: "Add cache", labels: []string{"perf"}, mergedAt: &mergedAt}, true},
+		{"bbbb", &prLookupResult{number: 1, title: "Add cache", mergedAt: &mergedAt}, true},
+		{"cccc", &prLookupResult{number: 2, title: "Still open"}, false},
+		{"dddd", &prLookupResult{failed: true}, false},
+	}
+	for _, test := range results {
+		if err := cache.StoreCommitPR(ctx, "github.com", "owner/repo", test.commitHash, test.result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := cache.CommitPR(ctx, "github.com", "owner/repo", test.commitHash)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if (got != nil) != test.stored {
+			t.Errorf("%s: expected stored=%v, got %+v", test.commitHash, test.stored, got)
+		}
+		if got != nil && (got.number != test.result.number || got.title != test.result.title || !got.mergedAt.Equal(mergedAt)) {
+			t.Errorf("%s: expected %+v, got %+v", test.commitHash, test.result, got)
+		}
+	}
+	if got, _ := cache.CommitPR(ctx, "gitlab.com", "owner/repo", "aaaa"); got != nil {
+		t.Errorf("expected lookups to be keyed by host, got %+v", got)
+	}
+
+	if _, found, _ := cache.Approvers(ctx, "github.com", "owner/repo", 1); found {
+		t.Error("expected no approvers before they are stored")
+	}
+	approvers := []LineApprover{{Name: "alice", Time: &mergedAt, URL: "https://github.com/owner/repo/pull/1#review"}}
+	if err := cache.StoreApprovers(ctx, "github.com", "owner/repo", 1, approvers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, found, err := cache.Approvers(ctx, "github.com", "owner/repo", 1)
+	if err != nil || !found || len(got) != 1 || got[0].Name != "alice" || got[0].URL != approvers[0].URL {
+		t.Errorf("expected the stored approvers, got %+v, %v, %v", got, found, err)
+	}
+
+	stats, err := cache.Stats(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Commits != 2 || stats[0].PullRequests != 1 || stats[0].Approved != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if err := cache.Clear(ctx, "github.com", "owner/repo"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats, _ := cache.Stats(ctx); len(stats) != 0 {
+		t.Errorf("expected an empty cache, got %+v", stats)
+	}
+}
+
+func TestSQLiteCacheEnrichers(t *testing.T) {
+	cache, err := OpenSQLiteCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo", Host: "github.com"}
+	newPipeline := func(client ReviewClient) *EnrichmentPipeline {
+		lookup := NewPRLookupEnricher(client, repoInfo)
+		lookup.store = cache
+		approvals := NewApprovalEnricher(client, repoInfo)
+		approvals.store = cache
+		return NewEnrichmentPipeline(lookup, approvals)
+	}
+	blameLines := []BlameLine{{CommitHash: "aaaa", LineNumber: 1}, {CommitHash: "bbbb", LineNumber: 2}}
+
+	now := time.Unix(1700000000, 0)
+	warm := &mergedReviewClient{&fakeReviewClient{
+		prs:       map[string]int{"aaaa": 1, "bbbb": 1},
+		approvals: map[int][]Review{1: {newTestReview("alice", now)}},
+	}, now}
+	if _, err := newPipeline(warm).Run(context.Background(), blameLines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A later run answers merged PRs from the cache
+	cold := &fakeReviewClient{}
+	lines, err := newPipeline(cold).Run(context.Background(), blameLines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cold.findCalls != 0 || cold.approvalCalls != 0 {
+		t.Errorf("expected no API lookups, got %d and %d", cold.findCalls, cold.approvalCalls)
+	}
+	for _, line := range lines {
+		if line.PRNumber != 1 || line.Approver != "alice" || line.PRMergedAt == nil {
+			t.Errorf("expected the cached PR and approver, got %+v", line)
+		}
+	}
+}
+
+func TestCacheCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	cache, err := OpenSQLiteCache(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mergedAt := time.Unix(1700000000, 0)
+	cache.StoreCommitPR(context.Background(), "github.com", "owner/repo", "aaaa", &prLookupResult{number: 1, mergedAt: &mergedAt})
+	cache.Close()
+
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{[]string{"stats", "-path", path}, "github.com/owner/repo: 1 commits, 1 PRs/MRs (0 with approvers)", ""},
+		{[]string{"clear", "-path", path, "-repo", "owner/repo"}, "", "-repo must be <host>/<owner>/<name>"},
+		{[]string{"clear", "-path", path, "-repo", "github.com/owner/repo"}, "Cleared github.com/owner/repo", ""},
+		{[]string{"stats", "-path", path}, "No cached lookups", ""},
+		{[]string{"stats", "-path", filepath.Join(t.TempDir(), "missing.db")}, "", "no cache at"},
+		{[]string{"vacuum", "-path", path}, "", "unknown cache command"},
+		{nil, "", "usage:"},
+	}
+	for _, test := range tests {
+		var output bytes.Buffer
+		err := runCacheCommand(context.Background(), test.args, &output)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%v: expected error %q, got %v", test.args, test.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.args, err)
+		}
+		if !strings.Contains(output.String(), test.want) {
+			t.Errorf("%v: expected %q in %q", test.args, test.want, output.String())
+		}
+	}
+}
+
+func TestOpenCacheSettings(t *testing.T) {
+	defer func() { cacheSettings = CacheConfig{} }()
+	if cache, err := openCache(nil); cache != nil || err != nil {
+		t.Errorf("expected no cache by default, got %v, %v", cache, err)
+	}
+	if _, err := openCache(&CacheConfig{Backend: "redis"}); err == nil {
+		t.Error("expected an unknown backend to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "cache.db")
+	cacheSettings.Backend = CacheBackendSQLite
+	first, err := openCache(&CacheConfig{Path: path})
+	if err != nil || first == nil || first.path != path {
+		t.Fatalf("expected the flag to enable the configured file, got %v, %v", first, err)
+	}
+	second, _ := openCache(&CacheConfig{Path: path})
+	if second != first {
+		t.Error("expected runs to share the open cache")
+	}
+}
@@ -0,0 +1,53 @@
+package reviewblame
+
+// Review states of a line, reported by ReviewState
+const (
+	// ReviewStateApproved: the line's PR/MR, or its commit's trailers, name an approver
+	ReviewStateApproved = "approved"
+	// ReviewStateSelfApproved: every approver is an author of the commit or PR/MR
+	ReviewStateSelfApproved = "self-approved"
+	// ReviewStateRulesUnmet: the PR/MR was approved, but not as its approval
+	// rules require (see -approval-rules)
+	ReviewStateRulesUnmet = "rules-unmet"
+	// ReviewStateUnapproved: the line's PR/MR was merged without approvals
+	ReviewStateUnapproved = "unapproved"
+	// ReviewStateNoPR: no PR/MR was found for the commit, e.g. a direct push
+	ReviewStateNoPR = "no-pr"
+	// ReviewStateLookupFailed: the PR/MR or its approvals could not be looked up
+	ReviewStateLookupFailed = "lookup-failed"
+	// ReviewStateUncommitted: a local change, not committed yet
+	ReviewStateUncommitted = "uncommitted"
+)
+
+// reviewStateMarkers are the markers the human format shows after the
+// author of lines that were not approved, and after the approver of lines
+// that were not independently approved
+var reviewStateMarkers = map[string]string{
+	ReviewStateSelfApproved: "[self-approved]",
+	ReviewStateRulesUnmet:   "[rules unmet]",
+	ReviewStateUnapproved:   "[unapproved]",
+	ReviewStateNoPR:         "[no PR]",
+	ReviewStateLookupFailed: "[lookup failed]",
+}
+
+// ReviewState tells why a line has an approver or not, so lines pushed
+// without a PR/MR, PRs/MRs merged without approval and failed lookups are
+// not all shown as their commit author
+func (l BlameLineWithApproval) ReviewState() string {
+	switch {
+	case isUncommitted(l.BlameLine):
+		return ReviewStateUncommitted
+	case l.Approver != "" && l.SelfApproved():
+		return ReviewStateSelfApproved
+	case l.Approver != "" && l.ApprovalRules != nil && !l.ApprovalRules.Satisfied():
+		return ReviewStateRulesUnmet
+	case l.Approver != "":
+		return ReviewStateApproved
+	case l.LookupFailed:
+		return ReviewStateLookupFailed
+	case l.PRNumber == 0:
+		return ReviewStateNoPR
+	default:
+		return ReviewStateUnapproved
+	}
+}
@@ -0,0 +1,98 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingPRClient fails every PR lookup
+type failingPRClient struct {
+	fakeReviewClient
+}
+
+func (c *failingPRClient) FindPRByCommit(ctx context.Context, owner, repo, commitHash string) (*PullRequest, error) {
+	return nil, errors.New("GitHub API error: 502 Bad Gateway")
+}
+
+func TestReviewState(t *testing.T) {
+	approvalTime := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		line BlameLineWithApproval
+		want string
+	}{
+		{"approved", BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "aaaa"}, PRNumber: 1, Approver: "alice", ApprovalTime: &approvalTime}, ReviewStateApproved},
+		{"trailer approval without PR", BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "aaaa"}, Approver: "Alice"}, ReviewStateApproved},
+		{"merged without approval", BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "aaaa"}, PRNumber: 1}, ReviewStateUnapproved},
+		{"direct push", BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "aaaa"}}, ReviewStateNoPR},
+		{"lookup failed", BlameLineWithApproval{BlameLine: BlameLine{CommitHash: "aaaa"}, LookupFailed: true}, ReviewStateLookupFailed},
+		{"uncommitted", BlameLineWithApproval{BlameLine: BlameLine{CommitHash: strings.Repeat("0", 40)}}, ReviewStateUncommitted},
+	}
+
+	for _, tt := range tests {
+		if got := tt.line.ReviewState(); got != tt.want {
+			t.Errorf("%s: ReviewState() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnrichersRecordLookupFailures(t *testing.T) {
+	repo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	pipeline := NewDefaultEnrichmentPipeline(&failingPRClient{}, repo)
+	lines, err := pipeline.Run(context.Background(), []BlameLine{{CommitHash: "aaaa"}, {CommitHash: "aaaa"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, line := range lines {
+		if line.ReviewState() != ReviewStateLookupFailed {
+			t.Errorf("expected a failed PR lookup to be recorded on every line of the commit, got %q", line.ReviewState())
+		}
+	}
+
+	// PR 2 has no recorded approvals, so the fake client fails its lookup
+	client := &fakeReviewClient{prs: map[string]int{"aaaa": 2, "bbbb": 3}, approvals: map[int][]Review{3: nil}}
+	lines, err = NewDefaultEnrichmentPipeline(client, repo).Run(context.Background(), []BlameLine{{CommitHash: "aaaa"}, {CommitHash: "bbbb"}, {CommitHash: "cccc"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{ReviewStateLookupFailed, ReviewStateUnapproved, ReviewStateNoPR}
+	for i, line := range lines {
+		if line.ReviewState() != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i+1, want[i], line.ReviewState())
+		}
+	}
+}
+
+func TestReviewStateOutput(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "a1b2c3d4e5f6", Author: "bob", Date: "1700000000", LineNumber: 1, Filename: "main.go"}, PRNumber: 7},
+		{BlameLine: BlameLine{CommitHash: "b1b2c3d4e5f6", Author: "carol", Date: "1700000000", LineNumber: 2, Filename: "main.go"}, LookupFailed: true},
+	}
+	registry := NewFormatterRegistry()
+
+	human, _ := registry.Lookup("human")
+	got := human.Format(lines, FormatOptions{})
+	if !strings.Contains(got, "(bob [unapproved]") || !strings.Contains(got, "(carol [lookup failed]") {
+		t.Errorf("expected review state markers in human output, got:\n%s", got)
+	}
+
+	porcelain, _ := registry.Lookup("porcelain")
+	got = porcelain.Format(lines, FormatOptions{})
+	if !strings.Contains(got, "review-state unapproved\n") || !strings.Contains(got, "review-state lookup-failed\n") {
+		t.Errorf("expected review-state fields in porcelain output, got:\n%s", got)
+	}
+
+	annotations, _ := registry.Lookup("annotations")
+	got = annotations.Format(lines, FormatOptions{})
+	if !strings.Contains(got, `"state":"unapproved"`) || !strings.Contains(got, `"text":"review unknown (no PR)"`) {
+		t.Errorf("expected review states in annotations, got:\n%s", got)
+	}
+
+	if record := NewAnnotationRecord(lines[1]); record.ReviewState != ReviewStateLookupFailed {
+		t.Errorf("expected the review state in the annotation record, got %q", record.ReviewState)
+	}
+}
@@ -0,0 +1,126 @@
+package reviewblame
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ReviewStats summarizes how many blamed lines trace back to an approved PR/MR
+type ReviewStats struct {
+	TotalLines    int
+	ReviewedLines int
+}
+
+// ComputeReviewStats counts reviewed lines, i.e. lines with at least one approver
+func ComputeReviewStats(lines []BlameLineWithApproval) ReviewStats {
+	stats := ReviewStats{TotalLines: len(lines)}
+	for _, line := range lines {
+		if line.Approver != "" {
+			stats.ReviewedLines++
+		}
+	}
+	return stats
+}
+
+// UnreviewedLines returns the number of lines without any approver
+func (s ReviewStats) UnreviewedLines() int {
+	return s.TotalLines - s.ReviewedLines
+}
+
+// Coverage returns the percentage of reviewed lines (0-100)
+func (s ReviewStats) Coverage() float64 {
+	if s.TotalLines == 0 {
+		return 0
+	}
+	return float64(s.ReviewedLines) * 100 / float64(s.TotalLines)
+}
+
+// UnreviewedRange is a run of consecutive unreviewed lines from the same file and commit
+type UnreviewedRange struct {
+	Filename   string
+	CommitHash string
+	Author     string
+	PRNumber   int
+	StartLine  int
+	EndLine    int
+}
+
+// FindUnreviewedRanges groups consecutive unreviewed lines of the same file
+// and commit into ranges, in input order
+func FindUnreviewedRanges(lines []BlameLineWithApproval) []UnreviewedRange {
+	var ranges []UnreviewedRange
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line.Approver != "" {
+			continue
+		}
+
+		end := i
+		for end+1 < len(lines) &&
+			lines[end+1].Approver == "" &&
+			lines[end+1].Filename == line.Filename &&
+			lines[end+1].CommitHash == line.CommitHash &&
+			lines[end+1].LineNumber == lines[end].LineNumber+1 {
+			end++
+		}
+
+		ranges = append(ranges, UnreviewedRange{
+			Filename:   line.Filename,
+			CommitHash: line.CommitHash,
+			Author:     line.Author,
+			PRNumber:   line.PRNumber,
+			StartLine:  line.LineNumber,
+			EndLine:    lines[end].LineNumber,
+		})
+		i = end
+	}
+	return ranges
+}
+
+// Message explains why the range is considered unreviewed
+func (r UnreviewedRange) Message() string {
+	shortHash := r.CommitHash
+	if len(shortHash) > 8 {
+		shortHash = shortHash[:8]
+	}
+	if r.PRNumber > 0 {
+		return fmt.Sprintf("Commit %s by %s was merged in #%d without approvals.", shortHash, r.Author, r.PRNumber)
+	}
+	return fmt.Sprintf("Commit %s by %s is not associated with any pull request.", shortHash, r.Author)
+}
+
+// PersonCount is a number of lines attributed to one person
+type PersonCount struct {
+	Name  string
+	Lines int
+}
+
+// TopUnreviewedAuthors returns up to n commit authors with the most
+// unreviewed lines, most lines first
+func TopUnreviewedAuthors(lines []BlameLineWithApproval, n int) []PersonCount {
+	counts := make(map[string]int)
+	for _, line := range lines {
+		if line.Approver == "" {
+			counts[line.Author]++
+		}
+	}
+	return topCounts(counts, n)
+}
+
+// topCounts sorts counts by descending line count, then name, and keeps n entries
+func topCounts(counts map[string]int, n int) []PersonCount {
+	result := make([]PersonCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, PersonCount{Name: name, Lines: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Lines != result[j].Lines {
+			return result[i].Lines > result[j].Lines
+		}
+		return result[i].Name < result[j].Name
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
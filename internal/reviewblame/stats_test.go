@@ -0,0 +1,85 @@
+package reviewblame
+
+import "testing"
+
+func TestComputeReviewStats(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{Approver: "alice"},
+		{Approver: "bob"},
+		{Approver: "alice"},
+		{},
+	}
+
+	stats := ComputeReviewStats(lines)
+
+	if stats.TotalLines != 4 {
+		t.Errorf("expected 4 total lines, got %d", stats.TotalLines)
+	}
+	if stats.ReviewedLines != 3 {
+		t.Errorf("expected 3 reviewed lines, got %d", stats.ReviewedLines)
+	}
+	if stats.UnreviewedLines() != 1 {
+		t.Errorf("expected 1 unreviewed line, got %d", stats.UnreviewedLines())
+	}
+	if stats.Coverage() != 75 {
+		t.Errorf("expected coverage 75, got %f", stats.Coverage())
+	}
+}
+
+func TestReviewStatsCoverageEmpty(t *testing.T) {
+	stats := ComputeReviewStats(nil)
+
+	if stats.Coverage() != 0 {
+		t.Errorf("expected coverage 0 for no lines, got %f", stats.Coverage())
+	}
+}
+
+func TestFindUnreviewedRanges(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "main.go", LineNumber: 1}},
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "main.go", LineNumber: 2}},
+		{BlameLine: BlameLine{CommitHash: "bbbb", Filename: "main.go", LineNumber: 3}},
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "main.go", LineNumber: 4}, Approver: "jane"},
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "main.go", LineNumber: 5}},
+		{BlameLine: BlameLine{CommitHash: "aaaa", Filename: "git.go", LineNumber: 6}},
+	}
+
+	ranges := FindUnreviewedRanges(lines)
+
+	expected := []UnreviewedRange{
+		{Filename: "main.go", CommitHash: "aaaa", StartLine: 1, EndLine: 2},
+		{Filename: "main.go", CommitHash: "bbbb", StartLine: 3, EndLine: 3},
+		{Filename: "main.go", CommitHash: "aaaa", StartLine: 5, EndLine: 5},
+		{Filename: "git.go", CommitHash: "aaaa", StartLine: 6, EndLine: 6},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("expected %d ranges, got %d: %+v", len(expected), len(ranges), ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Errorf("range %d: expected %+v, got %+v", i, expected[i], ranges[i])
+		}
+	}
+}
+
+func TestTopUnreviewedAuthors(t *testing.T) {
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{Author: "bob"}},
+		{BlameLine: BlameLine{Author: "alice"}},
+		{BlameLine: BlameLine{Author: "bob"}},
+		{BlameLine: BlameLine{Author: "carol"}},
+		{BlameLine: BlameLine{Author: "alice"}, Approver: "jane"},
+	}
+
+	top := TopUnreviewedAuthors(lines, 2)
+
+	expected := []PersonCount{{Name: "bob", Lines: 2}, {Name: "alice", Lines: 1}}
+	if len(top) != len(expected) {
+		t.Fatalf("expected %d authors, got %+v", len(expected), top)
+	}
+	for i := range expected {
+		if top[i] != expected[i] {
+			t.Errorf("position %d: expected %+v, got %+v", i, expected[i], top[i])
+		}
+	}
+}
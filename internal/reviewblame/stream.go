@@ -0,0 +1,103 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxResponseBytes caps the size of a single API response body so a
+// misbehaving server cannot exhaust memory
+const maxResponseBytes = 64 << 20
+
+// ErrResponseTooLarge is returned when an API response exceeds maxResponseBytes
+var ErrResponseTooLarge = fmt.Errorf("API response exceeds %d bytes", maxResponseBytes)
+
+// errStopDecoding is returned by element callbacks to end decoding early
+var errStopDecoding = errors.New("stop decoding")
+
+// limitedReader fails with ErrResponseTooLarge instead of silently truncating
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+// newResponseReader wraps a response body with the response size limit
+func newResponseReader(body io.Reader) io.Reader {
+	return &limitedReader{r: body, remaining: maxResponseBytes}
+}
+
+// Read implements io.Reader
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// expectDelim reads the next token and checks that it is the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("unexpected JSON token %v, expected %v", token, delim)
+	}
+	return nil
+}
+
+// decodeJSONArray streams the elements of a JSON array, calling element for
+// each one with the decoder positioned at it. element must decode exactly one
+// value, and may return errStopDecoding to skip the rest of the array. A null
+// array has no elements.
+func decodeJSONArray(dec *json.Decoder, element func(dec *json.Decoder) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("unexpected JSON token %v, expected [", token)
+	}
+	for dec.More() {
+		if err := element(dec); err != nil {
+			if errors.Is(err, errStopDecoding) {
+				return nil
+			}
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// decodeJSONField streams a JSON object, decoding the value of field with
+// value and stopping there; other fields are skipped. found is false when
+// the object has no such field.
+func decodeJSONField(dec *json.Decoder, field string, value func(dec *json.Decoder) error) (found bool, err error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return false, err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if key, ok := token.(string); ok && key == field {
+			return true, value(dec)
+		}
+		var skipped json.RawMessage
+		if err := dec.Decode(&skipped); err != nil {
+			return false, err
+		}
+	}
+	return false, expectDelim(dec, '}')
+}
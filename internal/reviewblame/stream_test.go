@@ -0,0 +1,101 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseReaderLimit(t *testing.T) {
+	body := strings.NewReader(strings.Repeat("x", maxResponseBytes+1))
+	if _, err := io.Copy(io.Discard, newResponseReader(body)); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+
+	body = strings.NewReader(strings.Repeat("x", 1024))
+	if n, err := io.Copy(io.Discard, newResponseReader(body)); err != nil || n != 1024 {
+		t.Errorf("expected 1024 bytes without error, got %d, %v", n, err)
+	}
+}
+
+func TestDecodeJSONArray(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		stop  int
+		want  []int
+		fails bool
+	}{
+		{"all elements", `[1, 2, 3]`, 0, []int{1, 2, 3}, false},
+		{"null", `null`, 0, nil, false},
+		{"early stop ignores the rest", `[1, 2, this is not JSON`, 2, []int{1, 2}, false},
+		{"not an array", `{"a": 1}`, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			err := decodeJSONArray(json.NewDecoder(strings.NewReader(tt.input)), func(dec *json.Decoder) error {
+				var value int
+				if err := dec.Decode(&value); err != nil {
+					return err
+				}
+				got = append(got, value)
+				if value == tt.stop {
+					return errStopDecoding
+				}
+				return nil
+			})
+			if (err != nil) != tt.fails {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if !tt.fails && len(got) != len(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPRByCommitStopsAfterMergedPR(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Everything after the first merged PR is never decoded
+		w.Write([]byte(`[{"number": 7, "title": "First", "merged_at": "2024-05-01T12:00:00Z"}, {"number": 8, ` + strings.Repeat(" ", 4096) + `garbage`))
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	pr, err := client.FindPRByCommit(context.Background(), "owner", "repo", "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr == nil || pr.Number != 7 || pr.Title != "First" {
+		t.Errorf("unexpected PR %+v", pr)
+	}
+}
+
+func TestGitLabApprovalsSkipsOtherFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 1, "suggested_approvers": [{"username": "x"}], ` +
+			`"approved_by": [{"user": {"username": "alice"}}, {"user": {"username": "bob"}}], ` +
+			`"approvers": garbage`))
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	reviews, err := client.GetPRApprovals(context.Background(), "owner", "repo", 9)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reviews) != 2 || reviews[0].User.Login != "alice" || reviews[1].User.Login != "bob" {
+		t.Errorf("unexpected reviews %+v", reviews)
+	}
+}
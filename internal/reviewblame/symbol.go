@@ -0,0 +1,185 @@
+package reviewblame
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SymbolRange is the line span of a declaration in a source file
+type SymbolRange struct {
+	// Name is the declared name; methods are named Receiver.Method
+	Name string
+	// Kind is func, method, type, var or const
+	Kind string
+	// StartLine and EndLine include the doc comment
+	StartLine int
+	EndLine   int
+}
+
+// LineRange returns the span in git blame -L syntax
+func (s SymbolRange) LineRange() string {
+	return fmt.Sprintf("%d,%d", s.StartLine, s.EndLine)
+}
+
+// FindSymbol resolves name to the line range of its top-level declaration
+// in content. A method can be given as Receiver.Method or, when only one
+// receiver declares it, by its bare name. Only Go files are supported.
+func FindSymbol(filename string, content []byte, name string) (*SymbolRange, error) {
+	if filepath.Ext(filename) != ".go" {
+		return nil, fmt.Errorf("cannot resolve symbol %s: symbol lookup is only supported for Go files", name)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+
+	span := func(name, kind string, doc *ast.CommentGroup, node ast.Node) SymbolRange {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return SymbolRange{Name: name, Kind: kind, StartLine: fset.Position(start).Line, EndLine: fset.Position(node.End()).Line}
+	}
+
+	var symbols []SymbolRange
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				symbols = append(symbols, span(receiverName(decl.Recv.List[0].Type)+"."+decl.Name.Name, "method", decl.Doc, decl))
+			} else {
+				symbols = append(symbols, span(decl.Name.Name, "func", decl.Doc, decl))
+			}
+		case *ast.GenDecl:
+			kind := decl.Tok.String()
+			for _, spec := range decl.Specs {
+				// An ungrouped declaration spans its keyword and doc comment
+				var doc *ast.CommentGroup
+				var node ast.Node = spec
+				if !decl.Lparen.IsValid() {
+					doc, node = decl.Doc, decl
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if doc == nil {
+						doc = spec.Doc
+					}
+					symbols = append(symbols, span(spec.Name.Name, kind, doc, node))
+				case *ast.ValueSpec:
+					if doc == nil {
+						doc = spec.Doc
+					}
+					for _, ident := range spec.Names {
+						symbols = append(symbols, span(ident.Name, kind, doc, node))
+					}
+				}
+			}
+		}
+	}
+
+	for _, symbol := range symbols {
+		if symbol.Name == name {
+			return &symbol, nil
+		}
+	}
+
+	var methods []SymbolRange
+	for _, symbol := range symbols {
+		if symbol.Kind == "method" && strings.HasSuffix(symbol.Name, "."+name) {
+			methods = append(methods, symbol)
+		}
+	}
+	switch len(methods) {
+	case 0:
+		return nil, fmt.Errorf("symbol %s is not declared in %s", name, filename)
+	case 1:
+		return &methods[0], nil
+	}
+	candidates := make([]string, len(methods))
+	for i, method := range methods {
+		candidates[i] = method.Name
+	}
+	return nil, fmt.Errorf("symbol %s is ambiguous in %s; use one of %s", name, filename, strings.Join(candidates, ", "))
+}
+
+// receiverName returns the type name of a method receiver, without pointer
+// and type parameters
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// SymbolSummary lists the PRs/MRs and approvers responsible for a symbol
+type SymbolSummary struct {
+	Symbol        SymbolRange
+	Lines         int
+	ReviewedLines int
+	PRNumbers     []int
+	Approvers     []string
+}
+
+// SummarizeSymbol collects the distinct PRs/MRs and approvers of the lines
+// within the symbol's range
+func SummarizeSymbol(symbol SymbolRange, lines []BlameLineWithApproval) SymbolSummary {
+	summary := SymbolSummary{Symbol: symbol}
+	prs := make(map[int]bool)
+	approvers := make(map[string]bool)
+	for _, line := range lines {
+		if line.LineNumber < symbol.StartLine || line.LineNumber > symbol.EndLine {
+			continue
+		}
+		summary.Lines++
+		if line.PRNumber > 0 && !prs[line.PRNumber] {
+			prs[line.PRNumber] = true
+			summary.PRNumbers = append(summary.PRNumbers, line.PRNumber)
+		}
+		if line.Approver != "" {
+			summary.ReviewedLines++
+			if !approvers[line.Approver] {
+				approvers[line.Approver] = true
+				summary.Approvers = append(summary.Approvers, line.Approver)
+			}
+		}
+	}
+	sort.Ints(summary.PRNumbers)
+	sort.Strings(summary.Approvers)
+	return summary
+}
+
+// String renders the summary for the end of the blame output
+func (s SymbolSummary) String() string {
+	prs := make([]string, len(s.PRNumbers))
+	for i, number := range s.PRNumbers {
+		prs[i] = fmt.Sprintf("#%d", number)
+	}
+	if len(prs) == 0 {
+		prs = []string{"none"}
+	}
+	approvers := s.Approvers
+	if len(approvers) == 0 {
+		approvers = []string{"none"}
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "%s %s (lines %d-%d): %d lines, %d reviewed\n",
+		s.Symbol.Kind, s.Symbol.Name, s.Symbol.StartLine, s.Symbol.EndLine, s.Lines, s.ReviewedLines)
+	fmt.Fprintf(&result, "    PRs: %s\n", strings.Join(prs, ", "))
+	fmt.Fprintf(&result, "    Approvers: %s\n", strings.Join(approvers, ", "))
+	return result.String()
+}
@@ -0,0 +1,91 @@
+package reviewblame
+
+import (
+	"strings"
+	"testing"
+)
+
+const symbolTestSource = `package server
+
+// Server handles requests
+type Server struct {
+	name string
+}
+
+// Handle serves one request
+func (s *Server) Handle() error {
+	return nil
+}
+
+func (c Client[T]) Close() {}
+
+func (s *Server) Close() {}
+
+const (
+	// Timeout is the request timeout
+	Timeout = 10
+
+	Retries = 3
+)
+
+var defaultServer = &Server{
+	name: "default",
+}
+`
+
+func TestFindSymbol(t *testing.T) {
+	tests := []struct {
+		name    string
+		symbol  string
+		want    SymbolRange
+		wantErr string
+	}{
+		{name: "type with doc", symbol: "Server", want: SymbolRange{Name: "Server", Kind: "type", StartLine: 3, EndLine: 6}},
+		{name: "qualified method", symbol: "Server.Handle", want: SymbolRange{Name: "Server.Handle", Kind: "method", StartLine: 8, EndLine: 11}},
+		{name: "unique bare method", symbol: "Handle", want: SymbolRange{Name: "Server.Handle", Kind: "method", StartLine: 8, EndLine: 11}},
+		{name: "generic receiver", symbol: "Client.Close", want: SymbolRange{Name: "Client.Close", Kind: "method", StartLine: 13, EndLine: 13}},
+		{name: "grouped const", symbol: "Timeout", want: SymbolRange{Name: "Timeout", Kind: "const", StartLine: 18, EndLine: 19}},
+		{name: "multi-line var", symbol: "defaultServer", want: SymbolRange{Name: "defaultServer", Kind: "var", StartLine: 24, EndLine: 26}},
+		{name: "ambiguous method", symbol: "Close", wantErr: "use one of Client.Close, Server.Close"},
+		{name: "missing", symbol: "Missing", wantErr: "not declared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindSymbol("server.go", []byte(symbolTestSource), tt.symbol)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindSymbol failed: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FindSymbol("server.py", []byte("def handle(): pass\n"), "handle"); err == nil || !strings.Contains(err.Error(), "only supported for Go files") {
+		t.Errorf("expected an unsupported language error, got %v", err)
+	}
+}
+
+func TestSummarizeSymbol(t *testing.T) {
+	symbol := SymbolRange{Name: "Server.Handle", Kind: "method", StartLine: 2, EndLine: 4}
+	lines := []BlameLineWithApproval{
+		{BlameLine: BlameLine{LineNumber: 1}, PRNumber: 9, Approver: "outside"},
+		{BlameLine: BlameLine{LineNumber: 2}, PRNumber: 7, Approver: "bob"},
+		{BlameLine: BlameLine{LineNumber: 3}, PRNumber: 3, Approver: "alice"},
+		{BlameLine: BlameLine{LineNumber: 4}, PRNumber: 7, Approver: "bob"},
+	}
+
+	want := "method Server.Handle (lines 2-4): 3 lines, 3 reviewed\n" +
+		"    PRs: #3, #7\n" +
+		"    Approvers: alice, bob\n"
+	if got := SummarizeSymbol(symbol, lines).String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
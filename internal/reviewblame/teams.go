@@ -0,0 +1,326 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// TeamConfig defines a team whose review share can be reported. Members
+// come from the config list, a GitHub team, a GitLab group, or a mix.
+type TeamConfig struct {
+	// Name identifies the team on the command line
+	Name string `json:"name"`
+	// Members lists logins (or identity names) of team members
+	Members []string `json:"members"`
+	// GitHubTeam is an organization team as "org/team-slug"
+	GitHubTeam string `json:"github_team"`
+	// GitLabGroup is the full path of a GitLab group, e.g. "company/platform"
+	GitLabGroup string `json:"gitlab_group"`
+}
+
+// validate checks that the team has a name and at least one member source
+func (t TeamConfig) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("team is missing a name")
+	}
+	if len(t.Members) == 0 && t.GitHubTeam == "" && t.GitLabGroup == "" {
+		return fmt.Errorf("team %q needs members, a github_team or a gitlab_group", t.Name)
+	}
+	if t.GitHubTeam != "" && strings.Count(t.GitHubTeam, "/") != 1 {
+		return fmt.Errorf("team %q: github_team must be \"org/team-slug\", got %q", t.Name, t.GitHubTeam)
+	}
+	return nil
+}
+
+// ListTeamMembers returns the logins of all members of an organization team,
+// including members of child teams
+func (c *GitHubClient) ListTeamMembers(ctx context.Context, org, slug string) ([]string, error) {
+	var logins []string
+	url := fmt.Sprintf("%s/orgs/%s/teams/%s/members", c.baseURL, org, slug)
+	err := c.listPages(ctx, url, func(dec *json.Decoder) error {
+		var member struct {
+			Login string `json:"login"`
+		}
+		if err := dec.Decode(&member); err != nil {
+			return err
+		}
+		logins = append(logins, member.Login)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return logins, nil
+}
+
+// ListGroupMembers returns the usernames of all members of a group,
+// including members inherited from parent groups
+func (c *GitLabClient) ListGroupMembers(ctx context.Context, group string) ([]string, error) {
+	var usernames []string
+	apiURL := fmt.Sprintf("%s/groups/%s/members/all", c.baseURL, url.PathEscape(group))
+	err := c.listPages(ctx, apiURL, func(dec *json.Decoder) error {
+		var member struct {
+			Username string `json:"username"`
+		}
+		if err := dec.Decode(&member); err != nil {
+			return err
+		}
+		usernames = append(usernames, member.Username)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
+// findTeam returns the configured team called name. An unconfigured name
+// containing a slash is taken as a GitHub team slug ("org/team") or a
+// GitLab group path, depending on where the repository is hosted.
+func findTeam(config *Config, repoInfo *RepoInfo, name string) (TeamConfig, error) {
+	for _, team := range config.Teams {
+		if team.Name == name {
+			return team, nil
+		}
+	}
+	if !strings.Contains(name, "/") {
+		return TeamConfig{}, fmt.Errorf("team %q is not defined in the config file; pass a GitHub team as org/team or a GitLab group path", name)
+	}
+	if repoInfo.Type == RepositoryTypeGitLab {
+		return TeamConfig{Name: name, GitLabGroup: name}, nil
+	}
+	team := TeamConfig{Name: name, GitHubTeam: name}
+	return team, team.validate()
+}
+
+// ResolveTeamMembers collects the members of a team from all its sources
+func ResolveTeamMembers(ctx context.Context, team TeamConfig, repoInfo *RepoInfo, githubToken, gitlabToken string) ([]string, error) {
+	members := append([]string(nil), team.Members...)
+
+	if team.GitHubTeam != "" {
+		if githubToken == "" {
+			return nil, ErrMissingGitHubToken
+		}
+		org, slug, _ := strings.Cut(team.GitHubTeam, "/")
+		logins, err := newGitHubClientForRepo(githubToken, repoInfo).ListTeamMembers(ctx, org, slug)
+		if err != nil {
+			return nil, fmt.Errorf("could not list members of GitHub team %s: %w", team.GitHubTeam, err)
+		}
+		members = append(members, logins...)
+	}
+
+	if team.GitLabGroup != "" {
+		if gitlabToken == "" {
+			return nil, ErrMissingGitLabToken
+		}
+		usernames, err := newGitLabClientForRepo(gitlabToken, repoInfo).ListGroupMembers(ctx, team.GitLabGroup)
+		if err != nil {
+			return nil, fmt.Errorf("could not list members of GitLab group %s: %w", team.GitLabGroup, err)
+		}
+		members = append(members, usernames...)
+	}
+
+	return members, nil
+}
+
+// TeamMembership decides whether an approver belongs to a team. Approvers
+// and members are compared case-insensitively by their canonical identity,
+// so a member listed by login matches an approver shown under their name.
+type TeamMembership struct {
+	members    map[string]bool
+	identities *IdentityMap
+}
+
+// NewTeamMembership indexes members, resolving them through identities
+func NewTeamMembership(members []string, identities *IdentityMap) *TeamMembership {
+	m := &TeamMembership{members: make(map[string]bool), identities: identities}
+	for _, member := range members {
+		m.members[m.key(member)] = true
+	}
+	return m
+}
+
+// key returns the canonical, lower-case form of a person
+func (m *TeamMembership) key(person string) string {
+	if m.identities != nil {
+		person, _ = m.identities.Resolve(person, "")
+	}
+	return strings.ToLower(strings.TrimSpace(person))
+}
+
+// Contains reports whether the approver is a team member
+func (m *TeamMembership) Contains(approver string) bool {
+	return m.members[m.key(approver)]
+}
+
+// Size returns the number of distinct members
+func (m *TeamMembership) Size() int {
+	return len(m.members)
+}
+
+// TeamCoverage counts the lines of a file (or of all files) by who approved them
+type TeamCoverage struct {
+	File         string `json:"file,omitempty"`
+	TotalLines   int    `json:"total_lines"`
+	TeamLines    int    `json:"team_lines"`
+	OutsideLines int    `json:"outside_lines"`
+}
+
+// UnreviewedLines returns the number of lines without any approver
+func (c TeamCoverage) UnreviewedLines() int {
+	return c.TotalLines - c.TeamLines - c.OutsideLines
+}
+
+// share returns count as a percentage of all lines (0-100)
+func (c TeamCoverage) share(count int) float64 {
+	if c.TotalLines == 0 {
+		return 0
+	}
+	return float64(count) * 100 / float64(c.TotalLines)
+}
+
+// TeamCoverageReport reports the share of lines approved by a team
+type TeamCoverageReport struct {
+	Team    string         `json:"team"`
+	Members int            `json:"members"`
+	Total   TeamCoverage   `json:"total"`
+	Files   []TeamCoverage `json:"files"`
+}
+
+// BuildTeamCoverageReport splits the lines of each file into those approved
+// by team members, by outsiders, and unreviewed ones
+func BuildTeamCoverageReport(team string, membership *TeamMembership, lines []BlameLineWithApproval) TeamCoverageReport {
+	report := TeamCoverageReport{Team: team, Members: membership.Size()}
+	byFile := make(map[string]*TeamCoverage)
+	for _, line := range lines {
+		file := byFile[line.Filename]
+		if file == nil {
+			file = &TeamCoverage{File: line.Filename}
+			byFile[line.Filename] = file
+		}
+
+		file.TotalLines++
+		report.Total.TotalLines++
+		switch {
+		case line.Approver == "":
+		case membership.Contains(line.Approver):
+			file.TeamLines++
+			report.Total.TeamLines++
+		default:
+			file.OutsideLines++
+			report.Total.OutsideLines++
+		}
+	}
+
+	for _, file := range byFile {
+		report.Files = append(report.Files, *file)
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].File < report.Files[j].File
+	})
+	return report
+}
+
+// Text renders the report as a table with one row per file and a total row
+func (r TeamCoverageReport) Text() string {
+	width := len("Total")
+	for _, file := range r.Files {
+		width = max(width, len(file.File))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Team %s (%d members)\n\n", r.Team, r.Members)
+	row := func(name string, c TeamCoverage) {
+		fmt.Fprintf(&b, "%-*s  %6d  %5.1f%%  %5.1f%%  %5.1f%%\n", width, name, c.TotalLines,
+			c.share(c.TeamLines), c.share(c.OutsideLines), c.share(c.UnreviewedLines()))
+	}
+	fmt.Fprintf(&b, "%-*s  %6s  %6s  %6s  %6s\n", width, "File", "Lines", "Team", "Other", "None")
+	for _, file := range r.Files {
+		row(file.File, file)
+	}
+	row("Total", r.Total)
+	return b.String()
+}
+
+// runTeamCoverage implements the team-coverage subcommand
+func runTeamCoverage(ctx context.Context, args []string, githubToken, gitlabToken string) error {
+	flags := flag.NewFlagSet("team-coverage", flag.ContinueOnError)
+	teamName := flags.String("team", "", "Team from the config file, GitHub team as org/team, or GitLab group path")
+	format := flags.String("format", "text", "Report format: text or json")
+	configPath := flags.String("config", "", "Path to the config file")
+	includeVendored := flags.Bool("include-vendored", false, "Include vendored files")
+	debug := flags.Bool("debug", false, "Log API requests and the run ID to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *debug {
+		enableDebugLogging()
+	}
+
+	if *teamName == "" {
+		return fmt.Errorf("-team is required")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unsupported team coverage format %q (expected text or json)", *format)
+	}
+
+	target := "."
+	if flags.NArg() > 0 {
+		target = flags.Arg(0)
+	}
+
+	repoRoot, repoInfo, config, err := OpenRepository(target, *configPath, "")
+	if err != nil {
+		return err
+	}
+	githubToken, gitlabToken = resolveTokens(ctx, repoRoot, repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+
+	team, err := findTeam(config, repoInfo, *teamName)
+	if err != nil {
+		return err
+	}
+	members, err := ResolveTeamMembers(ctx, team, repoInfo, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+	identities, err := NewIdentityMap(config.Identities)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := newEnrichmentPipeline(repoRoot, repoInfo, config, Options{}, githubToken, gitlabToken)
+	if err != nil {
+		return err
+	}
+	for _, stage := range pipeline.Stages() {
+		if stage == "offline-pr-lookup" {
+			return fmt.Errorf("team coverage needs API access to find approvers")
+		}
+	}
+	// .mailmap names approvers by their commit name, which team member
+	// lists do not use
+	pipeline.Remove("mailmap")
+
+	lines, err := annotatePath(ctx, repoRoot, target, "", BlameOptions{}, pipeline, *includeVendored)
+	if err != nil {
+		return err
+	}
+
+	report := BuildTeamCoverageReport(team.Name, NewTeamMembership(members, identities), lines)
+	if *format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(report.Text())
+	return nil
+}
@@ -0,0 +1,116 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListTeamMembersPaginates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/acme/teams/platform/members" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		var members []map[string]string
+		if r.URL.Query().Get("page") == "" {
+			for i := 0; i < listPageSize; i++ {
+				members = append(members, map[string]string{"login": fmt.Sprintf("user%d", i)})
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?per_page=100&page=2>; rel="next"`, r.Host, r.URL.Path))
+		} else {
+			members = append(members, map[string]string{"login": "last"})
+		}
+		json.NewEncoder(w).Encode(members)
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+	logins, err := client.ListTeamMembers(context.Background(), "acme", "platform")
+	if err != nil {
+		t.Fatalf("ListTeamMembers failed: %v", err)
+	}
+	if len(logins) != listPageSize+1 || logins[len(logins)-1] != "last" {
+		t.Errorf("expected %d members ending with last, got %d", listPageSize+1, len(logins))
+	}
+}
+
+func TestListGroupMembers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/groups/company%2Fplatform/members/all" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+		}
+		w.Write([]byte(`[{"username": "alice"}, {"username": "bob"}]`))
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+	usernames, err := client.ListGroupMembers(context.Background(), "company/platform")
+	if err != nil {
+		t.Fatalf("ListGroupMembers failed: %v", err)
+	}
+	if strings.Join(usernames, ",") != "alice,bob" {
+		t.Errorf("unexpected members %v", usernames)
+	}
+}
+
+func TestFindTeam(t *testing.T) {
+	config := &Config{Teams: []TeamConfig{{Name: "platform", Members: []string{"alice"}}}}
+	github := &RepoInfo{Type: RepositoryTypeGitHub}
+	gitlab := &RepoInfo{Type: RepositoryTypeGitLab}
+
+	if team, err := findTeam(config, github, "platform"); err != nil || len(team.Members) != 1 {
+		t.Errorf("expected the configured team, got %+v, %v", team, err)
+	}
+	if team, err := findTeam(config, github, "acme/sre"); err != nil || team.GitHubTeam != "acme/sre" {
+		t.Errorf("expected a GitHub team, got %+v, %v", team, err)
+	}
+	if team, err := findTeam(config, gitlab, "company/infra/sre"); err != nil || team.GitLabGroup != "company/infra/sre" {
+		t.Errorf("expected a GitLab group, got %+v, %v", team, err)
+	}
+	if _, err := findTeam(config, github, "unknown"); err == nil {
+		t.Error("expected an error for an unknown team")
+	}
+}
+
+func TestBuildTeamCoverageReport(t *testing.T) {
+	identities, err := NewIdentityMap([]IdentityConfig{{Name: "Alice Smith", Aliases: []string{"alice"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	membership := NewTeamMembership([]string{"alice", "Bob"}, identities)
+
+	line := func(file, approver string) BlameLineWithApproval {
+		return BlameLineWithApproval{BlameLine: BlameLine{Filename: file}, Approver: approver}
+	}
+	lines := []BlameLineWithApproval{
+		line("b.go", "Alice Smith"),
+		line("b.go", "bob"),
+		line("b.go", "carol"),
+		line("b.go", ""),
+		line("a.go", "carol"),
+	}
+
+	report := BuildTeamCoverageReport("platform", membership, lines)
+	want := TeamCoverage{TotalLines: 5, TeamLines: 2, OutsideLines: 2}
+	if report.Total != want || report.Members != 2 {
+		t.Errorf("unexpected total %+v with %d members", report.Total, report.Members)
+	}
+	if len(report.Files) != 2 || report.Files[0].File != "a.go" || report.Files[1].TeamLines != 2 {
+		t.Errorf("unexpected files %+v", report.Files)
+	}
+
+	wantText := "Team platform (2 members)\n\n" +
+		"File    Lines    Team   Other    None\n" +
+		"a.go        1    0.0%  100.0%    0.0%\n" +
+		"b.go        4   50.0%   25.0%   25.0%\n" +
+		"Total       5   40.0%   40.0%   20.0%\n"
+	if got := report.Text(); got != wantText {
+		t.Errorf("got:\n%s\nwant:\n%s", got, wantText)
+	}
+}
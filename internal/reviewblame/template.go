@@ -0,0 +1,75 @@
+package reviewblame
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateLine is the data of a -format template: the fields of
+// BlameLineWithApproval plus a few derived ones
+type templateLine struct {
+	BlameLineWithApproval
+	// Hash is the full commit hash and ShortHash its first 8 characters
+	Hash      string
+	ShortHash string
+	// PRURL is the web page of the line's PR/MR, "" without one
+	PRURL string
+}
+
+// templateFuncs are the functions available to -format templates
+var templateFuncs = template.FuncMap{
+	// date formats an approval time as YYYY-MM-DD, "" when there is none
+	"date": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format("2006-01-02")
+	},
+	"join": strings.Join,
+}
+
+// isTemplateFormat reports whether a -format value is a template rather
+// than the name of a format
+func isTemplateFormat(format string) bool {
+	return strings.Contains(format, "{{")
+}
+
+// NewTemplateFormatter creates a formatter writing each line with a Go
+// text/template, followed by a newline, e.g.
+// '{{.ShortHash}} {{.PRNumber}} {{.Approver}} {{.Content}}'
+func NewTemplateFormatter(text string) (WriterFormatter, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -format template: %w", err)
+	}
+	return WriterFormatterFunc(func(w io.Writer, lines []BlameLineWithApproval, opts FormatOptions) error {
+		out := bufio.NewWriter(w)
+		for _, line := range lines {
+			data := templateLine{BlameLineWithApproval: line, Hash: line.CommitHash, ShortHash: shortCommit(line.CommitHash)}
+			if opts.Repo != nil {
+				owner, name := lineRepository(opts.Repo, line)
+				data.PRURL = opts.Repo.PullRequestURL(owner, name, line.PRNumber)
+			}
+			if err := tmpl.Execute(out, data); err != nil {
+				return fmt.Errorf("-format template: %w", err)
+			}
+			if err := out.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		return out.Flush()
+	}), nil
+}
+
+// LookupFormatter returns the formatter of a -format value: a template when
+// it contains "{{", otherwise a format of DefaultFormatters
+func LookupFormatter(format string) (Formatter, error) {
+	if isTemplateFormat(format) {
+		return NewTemplateFormatter(format)
+	}
+	return DefaultFormatters.Lookup(format)
+}
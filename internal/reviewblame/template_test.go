@@ -0,0 +1,66 @@
+package reviewblame
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	approvalTime := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	lines := []BlameLineWithApproval{
+		{
+			BlameLine:    BlameLine{CommitHash: "a1b2c3d4e5f6", LineNumber: 1, Filename: "main.go", Content: "package main"},
+			PRNumber:     42,
+			Approver:     "alice",
+			ApprovalTime: &approvalTime,
+			Approvers:    []LineApprover{{Name: "alice"}, {Name: "bob"}},
+		},
+		{BlameLine: BlameLine{CommitHash: "b1b2c3d4e5f6", LineNumber: 2, Filename: "main.go"}},
+	}
+	repo := &RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com", Owner: "owner", Name: "repo"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"fields", "{{.Hash}} {{.PRNumber}} {{.Approver}} {{.Content}}", "a1b2c3d4e5f6 42 alice package main\nb1b2c3d4e5f6 0  \n"},
+		{"derived fields", "{{.Filename}}:{{.LineNumber}} {{.ShortHash}} {{.PRURL}}", "main.go:1 a1b2c3d4 https://github.com/owner/repo/pull/42\nmain.go:2 b1b2c3d4 \n"},
+		{"functions", "{{date .ApprovalTime}}|{{range $i, $a := .Approvers}}{{if $i}},{{end}}{{$a.Name}}{{end}}", "2024-05-02|alice,bob\n|\n"},
+		{"conditionals", "{{if .Approver}}{{.Approver}}{{else}}UNREVIEWED{{end}}", "alice\nUNREVIEWED\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := LookupFormatter(tt.template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := formatter.Format(lines, FormatOptions{Repo: repo}); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFormatterErrors(t *testing.T) {
+	if _, err := NewTemplateFormatter("{{.Hash"); err == nil || !strings.Contains(err.Error(), "invalid -format template") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+
+	formatter, err := NewTemplateFormatter("{{.Missing}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var output bytes.Buffer
+	err = formatter.WriteFormat(&output, []BlameLineWithApproval{{}}, FormatOptions{})
+	if err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("expected an error naming the unknown field, got %v", err)
+	}
+
+	if _, err := LookupFormatter("human"); err != nil {
+		t.Errorf("expected format names to be looked up, got %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReviewThreadStatus summarizes the review threads of a PR/MR
+type ReviewThreadStatus struct {
+	// Total is the number of resolvable review threads
+	Total int
+	// Resolved is the number of threads resolved before the PR/MR was merged
+	// (or resolved so far, for PRs that are not merged)
+	Resolved int
+}
+
+// Unresolved returns the number of threads that were left unresolved
+func (s ReviewThreadStatus) Unresolved() int {
+	return s.Total - s.Resolved
+}
+
+// MergedWithUnresolvedThreads reports whether review discussion was left open
+func (s ReviewThreadStatus) MergedWithUnresolvedThreads() bool {
+	return s.Unresolved() > 0
+}
+
+// ReviewThreadProvider is implemented by review clients that can report
+// review thread resolution status
+type ReviewThreadProvider interface {
+	GetReviewThreadStatus(ctx context.Context, owner, repo string, prNumber int) (*ReviewThreadStatus, error)
+}
+
+// reviewThreadsQuery pages through the review threads of a pull request
+const reviewThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes { isResolved }
+      }
+    }
+  }
+}`
+
+// graphqlURL returns the GraphQL endpoint next to the REST base URL.
+// GitHub Enterprise Server serves REST under /api/v3 and GraphQL at
+// /api/graphql, while api.github.com serves both at the root.
+func (c *GitHubClient) graphqlURL() string {
+	if base, ok := strings.CutSuffix(strings.TrimSuffix(c.baseURL, "/"), "/api/v3"); ok {
+		return base + "/api/graphql"
+	}
+	return c.baseURL + "/graphql"
+}
+
+// GetReviewThreadStatus counts the review threads of a pull request and how
+// many are resolved. GitHub does not expose when a thread was resolved, so
+// this reflects the current state of the threads.
+func (c *GitHubClient) GetReviewThreadStatus(ctx context.Context, owner, repo string, prNumber int) (*ReviewThreadStatus, error) {
+	status := &ReviewThreadStatus{}
+	var after *string
+
+	for {
+		payload, err := json.Marshal(map[string]interface{}{
+			"query": reviewThreadsQuery,
+			"variables": map[string]interface{}{
+				"owner":  owner,
+				"repo":   repo,
+				"number": prNumber,
+				"after":  after,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.makeRequestWithBody(ctx, "POST", c.graphqlURL(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Data struct {
+				Repository struct {
+					PullRequest *struct {
+						ReviewThreads struct {
+							PageInfo struct {
+								HasNextPage bool   `json:"hasNextPage"`
+								EndCursor   string `json:"endCursor"`
+							} `json:"pageInfo"`
+							Nodes []struct {
+								IsResolved bool `json:"isResolved"`
+							} `json:"nodes"`
+						} `json:"reviewThreads"`
+					} `json:"pullRequest"`
+				} `json:"repository"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode, resp.Status)
+		}
+		err = json.NewDecoder(newResponseReader(resp.Body)).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("GitHub GraphQL error: %s", result.Errors[0].Message)
+		}
+
+		pr := result.Data.Repository.PullRequest
+		if pr == nil {
+			return nil, fmt.Errorf("pull request #%d not found", prNumber)
+		}
+
+		for _, thread := range pr.ReviewThreads.Nodes {
+			status.Total++
+			if thread.IsResolved {
+				status.Resolved++
+			}
+		}
+
+		if !pr.ReviewThreads.PageInfo.HasNextPage {
+			return status, nil
+		}
+		cursor := pr.ReviewThreads.PageInfo.EndCursor
+		after = &cursor
+	}
+}
+
+// GetReviewThreadStatus implements ReviewThreadProvider
+func (a *GitHubClientAdapter) GetReviewThreadStatus(ctx context.Context, owner, repo string, prNumber int) (*ReviewThreadStatus, error) {
+	return a.client.GetReviewThreadStatus(ctx, owner, repo, prNumber)
+}
+
+// GetReviewThreadStatus counts the resolvable discussions of a merge request
+// and how many were resolved before it was merged
+func (c *GitLabClient) GetReviewThreadStatus(ctx context.Context, owner, repo string, mrIID int) (*ReviewThreadStatus, error) {
+	var mr GitLabMergeRequest
+	apiURL := c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d", mrIID))
+	if err := c.doJSON(ctx, "GET", apiURL, nil, &mr, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	discussions, err := c.ListMergeRequestDiscussions(ctx, owner, repo, mrIID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ReviewThreadStatus{}
+	for _, discussion := range discussions {
+		if len(discussion.Notes) == 0 || !discussion.Notes[0].Resolvable {
+			continue
+		}
+		status.Total++
+		if discussionResolvedBy(discussion, mr.MergedAt) {
+			status.Resolved++
+		}
+	}
+	return status, nil
+}
+
+// discussionResolvedBy reports whether every resolvable note of a discussion
+// was resolved, and resolved no later than mergedAt when that is known
+func discussionResolvedBy(discussion GitLabDiscussion, mergedAt *time.Time) bool {
+	for _, note := range discussion.Notes {
+		if !note.Resolvable {
+			continue
+		}
+		if !note.Resolved {
+			return false
+		}
+		if mergedAt != nil && note.ResolvedAt != nil && note.ResolvedAt.After(*mergedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// ThreadEnricher sets the review thread status of each line's PR/MR. It is a
+// no-op for clients that do not implement ReviewThreadProvider.
+type ThreadEnricher struct {
+	client   ReviewClient
+	repoInfo *RepoInfo
+	// cache maps PR to its thread status (nil when the lookup failed)
+	cache map[prKey]*ReviewThreadStatus
+}
+
+// NewThreadEnricher creates the review thread stage
+func NewThreadEnricher(client ReviewClient, repoInfo *RepoInfo) *ThreadEnricher {
+	return &ThreadEnricher{
+		client:   client,
+		repoInfo: repoInfo,
+		cache:    make(map[prKey]*ReviewThreadStatus),
+	}
+}
+
+// Name implements Enricher
+func (e *ThreadEnricher) Name() string {
+	return "review-threads"
+}
+
+// Enrich implements Enricher
+func (e *ThreadEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	provider, ok := e.client.(ReviewThreadProvider)
+	if !ok {
+		return nil
+	}
+
+	for i := range lines {
+		prNumber := lines[i].PRNumber
+		if prNumber == 0 {
+			continue
+		}
+
+		key := prKey{lines[i].Repository, prNumber}
+		status, exists := e.cache[key]
+		if !exists {
+			owner, name := lineRepository(e.repoInfo, lines[i])
+			fetched, err := provider.GetReviewThreadStatus(ctx, owner, name, prNumber)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				status = fetched
+			} else {
+				recordLookupFailure(e.Name(), prSubject(lines[i].Repository, prNumber), err)
+			}
+			e.cache[key] = status
+		}
+		lines[i].Threads = status
+	}
+	return nil
+}
+
+// UnresolvedThreadWarnings returns one message per PR/MR of the lines that
+// was merged with unresolved review threads, ordered by PR number
+func UnresolvedThreadWarnings(lines []BlameLineWithApproval) []string {
+	var keys []prKey
+	statuses := make(map[prKey]*ReviewThreadStatus)
+	for _, line := range lines {
+		key := prKey{line.Repository, line.PRNumber}
+		if line.Threads == nil || !line.Threads.MergedWithUnresolvedThreads() || statuses[key] != nil {
+			continue
+		}
+		keys = append(keys, key)
+		statuses[key] = line.Threads
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].repository != keys[j].repository {
+			return keys[i].repository < keys[j].repository
+		}
+		return keys[i].number < keys[j].number
+	})
+
+	warnings := make([]string, 0, len(keys))
+	for _, key := range keys {
+		status := statuses[key]
+		warnings = append(warnings, fmt.Sprintf("%s#%d merged with unresolved threads (%d of %d unresolved)",
+			key.repository, key.number, status.Unresolved(), status.Total))
+	}
+	return warnings
+}
@@ -0,0 +1,191 @@
+package reviewblame
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGitHubGetReviewThreadStatus(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/graphql" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var payload struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		requests++
+
+		hasNext, nodes := true, []map[string]bool{{"isResolved": true}, {"isResolved": false}}
+		if payload.Variables["after"] == "cursor1" {
+			hasNext, nodes = false, []map[string]bool{{"isResolved": true}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"repository": map[string]interface{}{
+					"pullRequest": map[string]interface{}{
+						"reviewThreads": map[string]interface{}{
+							"pageInfo": map[string]interface{}{"hasNextPage": hasNext, "endCursor": "cursor1"},
+							"nodes":    nodes,
+						},
+					},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	status, err := client.GetReviewThreadStatus(context.Background(), "owner", "repo", 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 paginated requests, got %d", requests)
+	}
+	if status.Total != 3 || status.Resolved != 2 || status.Unresolved() != 1 {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestGitHubGraphQLURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.github.com", "https://api.github.com/graphql"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/graphql"},
+	}
+	for _, tt := range tests {
+		client := NewGitHubClient("test-token")
+		client.baseURL = tt.baseURL
+		if got := client.graphqlURL(); got != tt.want {
+			t.Errorf("graphqlURL() for %s = %s, want %s", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubGetReviewThreadStatusGraphQLError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"message": "Could not resolve to a PullRequest"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient("test-token")
+	client.baseURL = server.URL
+
+	if _, err := client.GetReviewThreadStatus(context.Background(), "owner", "repo", 7); err == nil {
+		t.Error("expected error for GraphQL error response")
+	}
+}
+
+func TestGitLabGetReviewThreadStatus(t *testing.T) {
+	mergedAt := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	before := mergedAt.Add(-time.Hour)
+	after := mergedAt.Add(time.Hour)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "/projects/owner%2Frepo/merge_requests/3"
+		switch r.URL.EscapedPath() {
+		case base:
+			json.NewEncoder(w).Encode(GitLabMergeRequest{IID: 3, MergedAt: &mergedAt})
+		case base + "/discussions":
+			json.NewEncoder(w).Encode([]GitLabDiscussion{
+				{ID: "resolved", Notes: []GitLabNote{{Resolvable: true, Resolved: true, ResolvedAt: &before}}},
+				{ID: "resolved-late", Notes: []GitLabNote{{Resolvable: true, Resolved: true, ResolvedAt: &after}}},
+				{ID: "open", Notes: []GitLabNote{{Resolvable: true}}},
+				{ID: "system", Notes: []GitLabNote{{Body: "added 1 commit"}}},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newGitLabClient("test-token", "gitlab.com")
+	client.baseURL = server.URL
+
+	status, err := client.GetReviewThreadStatus(context.Background(), "owner", "repo", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Total != 3 || status.Resolved != 1 {
+		t.Errorf("expected 1 of 3 threads resolved before merge, got %+v", status)
+	}
+}
+
+// fakeThreadClient is a fakeReviewClient that also reports review threads
+type fakeThreadClient struct {
+	fakeReviewClient
+	threads     map[int]*ReviewThreadStatus
+	threadCalls int
+}
+
+func (c *fakeThreadClient) GetReviewThreadStatus(ctx context.Context, owner, repo string, prNumber int) (*ReviewThreadStatus, error) {
+	c.threadCalls++
+	return c.threads[prNumber], nil
+}
+
+func TestThreadEnricher(t *testing.T) {
+	client := &fakeThreadClient{
+		fakeReviewClient: fakeReviewClient{prs: map[string]int{"aaa": 1, "bbb": 2}},
+		threads: map[int]*ReviewThreadStatus{
+			1: {Total: 2, Resolved: 2},
+			2: {Total: 3, Resolved: 1},
+		},
+	}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	pipeline := NewDefaultEnrichmentPipeline(client, repoInfo)
+	pipeline.Use(NewThreadEnricher(client, repoInfo))
+
+	lines, err := pipeline.Run(context.Background(), []BlameLine{
+		{CommitHash: "aaa", LineNumber: 1},
+		{CommitHash: "bbb", LineNumber: 2},
+		{CommitHash: "bbb", LineNumber: 3},
+		{CommitHash: "ccc", LineNumber: 4},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if client.threadCalls != 2 {
+		t.Errorf("expected 2 thread lookups (cached per PR), got %d", client.threadCalls)
+	}
+	if lines[0].Threads == nil || lines[0].Threads.MergedWithUnresolvedThreads() {
+		t.Errorf("expected PR #1 to have all threads resolved, got %+v", lines[0].Threads)
+	}
+	if lines[3].Threads != nil {
+		t.Errorf("expected no thread status for a line without PR, got %+v", lines[3].Threads)
+	}
+
+	warnings := UnresolvedThreadWarnings(lines)
+	expected := "#2 merged with unresolved threads (2 of 3 unresolved)"
+	if len(warnings) != 1 || warnings[0] != expected {
+		t.Errorf("expected [%q], got %q", expected, warnings)
+	}
+}
+
+func TestThreadEnricherWithoutProvider(t *testing.T) {
+	client := &fakeReviewClient{prs: map[string]int{"aaa": 1}}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+
+	lines := []BlameLineWithApproval{{BlameLine: BlameLine{CommitHash: "aaa"}, PRNumber: 1}}
+	if err := NewThreadEnricher(client, repoInfo).Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines[0].Threads != nil {
+		t.Errorf("expected no thread status, got %+v", lines[0].Threads)
+	}
+}
@@ -0,0 +1,139 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Token sources, reported by TokenResolver.Resolve and selected with
+// -token-source
+const (
+	TokenSourceFlag          = "flag"
+	TokenSourceEnv           = "env"
+	TokenSourceKeyring       = "keyring"
+	TokenSourceCLI           = "cli"
+	TokenSourceGitCredential = "git-credential"
+)
+
+// tokenSources are the sources -token-source accepts, in the order they are
+// tried when none is selected
+var tokenSources = []string{TokenSourceEnv, TokenSourceKeyring, TokenSourceCLI, TokenSourceGitCredential}
+
+// runTokenCommand runs a program that prints a token, in dir with stdin as
+// its input, and returns its output; tests replace it
+var runTokenCommand = func(ctx context.Context, dir, stdin, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin)
+	// Never prompt: a credential helper without a stored credential would
+	// otherwise ask for a username and password on the terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=true")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// TokenResolver finds the API token of a GitHub or GitLab repository. The
+// token passed with -token wins; otherwise the environment variable, the
+// token stored with "auth login", the token the gh or glab CLI is logged in
+// with, and the password of a git credential helper for the host are tried
+// in that order.
+type TokenResolver struct {
+	// Explicit is the token passed with -token
+	Explicit string
+	// Source restricts discovery to one of tokenSources; all are tried when empty
+	Source string
+}
+
+// ParseTokenSource checks a -token-source value
+func ParseTokenSource(source string) (string, error) {
+	for _, known := range tokenSources {
+		if source == known {
+			return source, nil
+		}
+	}
+	return "", fmt.Errorf("unknown token source %q (expected %s)", source, strings.Join(tokenSources, ", "))
+}
+
+// Resolve returns the token for repoInfo and the source it came from, or two
+// empty strings when no source has one. envToken is the token of the
+// host's environment variable.
+func (r *TokenResolver) Resolve(ctx context.Context, repoRoot string, repoInfo *RepoInfo, envToken string) (token, source string) {
+	if r.Explicit != "" {
+		return r.Explicit, TokenSourceFlag
+	}
+	for _, source := range tokenSources {
+		if r.Source != "" && r.Source != source {
+			continue
+		}
+		var token string
+		switch source {
+		case TokenSourceEnv:
+			token = envToken
+		case TokenSourceKeyring:
+			token, _ = systemKeyring.Get(ctx, repoInfo.Host)
+		case TokenSourceCLI:
+			token = cliToken(ctx, repoRoot, repoInfo)
+		case TokenSourceGitCredential:
+			token = gitCredentialToken(ctx, repoRoot, repoInfo.Host)
+		}
+		if token != "" {
+			debugf("using the %s token for %s from %s", repoInfo.Type, repoInfo.Host, source)
+			return token, source
+		}
+	}
+	return "", ""
+}
+
+// cliToken returns the token gh or glab is logged in with for the host, or
+// "" when the CLI is not installed or not logged in
+func cliToken(ctx context.Context, repoRoot string, repoInfo *RepoInfo) string {
+	var output string
+	var err error
+	switch repoInfo.Type {
+	case RepositoryTypeGitHub:
+		output, err = runTokenCommand(ctx, repoRoot, "", "gh", "auth", "token", "--hostname", repoInfo.Host)
+	case RepositoryTypeGitLab:
+		output, err = runTokenCommand(ctx, repoRoot, "", "glab", "config", "get", "token", "--host", repoInfo.Host)
+	default:
+		return ""
+	}
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+// gitCredentialToken returns the password "git credential fill" finds for
+// https://host, or "" when no credential helper has one
+func gitCredentialToken(ctx context.Context, repoRoot, host string) string {
+	output, err := runTokenCommand(ctx, repoRoot, fmt.Sprintf("protocol=https\nhost=%s\n\n", host), "git", "credential", "fill")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if password, found := strings.CutPrefix(line, "password="); found {
+			return password
+		}
+	}
+	return ""
+}
+
+// resolveTokens returns the GitHub and GitLab tokens of a run with the one
+// of the repository's host resolved by resolver
+func resolveTokens(ctx context.Context, repoRoot string, repoInfo *RepoInfo, resolver *TokenResolver, githubToken, gitlabToken string) (string, string) {
+	switch repoInfo.Type {
+	case RepositoryTypeGitHub:
+		githubToken, _ = resolver.Resolve(ctx, repoRoot, repoInfo, githubToken)
+	case RepositoryTypeGitLab:
+		gitlabToken, _ = resolver.Resolve(ctx, repoRoot, repoInfo, gitlabToken)
+	}
+	return githubToken, gitlabToken
+}
@@ -0,0 +1,191 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// memoryKeyring is a Keyring holding tokens by host in memory
+type memoryKeyring map[string]string
+
+// Get implements Keyring
+func (k memoryKeyring) Get(ctx context.Context, host string) (string, error) {
+	token, ok := k[host]
+	if !ok {
+		return "", ErrTokenNotStored
+	}
+	return token, nil
+}
+
+// Set implements Keyring
+func (k memoryKeyring) Set(ctx context.Context, host, token string) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+	k[host] = token
+	return nil
+}
+
+// Delete implements Keyring
+func (k memoryKeyring) Delete(ctx context.Context, host string) error {
+	if _, ok := k[host]; !ok {
+		return ErrTokenNotStored
+	}
+	delete(k, host)
+	return nil
+}
+
+// stubTokenCommands replaces runTokenCommand with outputs keyed by program
+// name, where programs without an output fail as if not installed, and the
+// system keyring with an empty memoryKeyring
+func stubTokenCommands(t *testing.T, outputs map[string]string) *[]string {
+	t.Helper()
+	var calls []string
+	originalKeyring := systemKeyring
+	systemKeyring = memoryKeyring{}
+	t.Cleanup(func() { systemKeyring = originalKeyring })
+	original := runTokenCommand
+	runTokenCommand = func(ctx context.Context, dir, stdin, name string, args ...string) (string, error) {
+		calls = append(calls, strings.TrimSpace(name+" "+strings.Join(args, " ")+" "+strings.ReplaceAll(stdin, "\n", " ")))
+		output, ok := outputs[name]
+		if !ok {
+			return "", errors.New("executable file not found in $PATH")
+		}
+		return output, nil
+	}
+	t.Cleanup(func() { runTokenCommand = original })
+	return &calls
+}
+
+func TestTokenResolverChain(t *testing.T) {
+	github := &RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com"}
+	gitlab := &RepoInfo{Type: RepositoryTypeGitLab, Host: "gitlab.example.com"}
+
+	tests := []struct {
+		name       string
+		resolver   TokenResolver
+		repo       *RepoInfo
+		envToken   string
+		stored     string
+		outputs    map[string]string
+		wantToken  string
+		wantSource string
+		wantCalls  string
+	}{
+		{
+			name:       "flag wins",
+			resolver:   TokenResolver{Explicit: "flag-token"},
+			repo:       github,
+			envToken:   "env-token",
+			wantToken:  "flag-token",
+			wantSource: TokenSourceFlag,
+		},
+		{
+			name:       "env before cli",
+			repo:       github,
+			envToken:   "env-token",
+			outputs:    map[string]string{"gh": "gh-token\n"},
+			wantToken:  "env-token",
+			wantSource: TokenSourceEnv,
+		},
+		{
+			name:       "keyring before cli",
+			repo:       github,
+			stored:     "keyring-token",
+			outputs:    map[string]string{"gh": "gh-token\n"},
+			wantToken:  "keyring-token",
+			wantSource: TokenSourceKeyring,
+		},
+		{
+			name:       "gh",
+			repo:       github,
+			outputs:    map[string]string{"gh": "gh-token\n", "git": "password=git-token\n"},
+			wantToken:  "gh-token",
+			wantSource: TokenSourceCLI,
+			wantCalls:  "gh auth token --hostname github.com",
+		},
+		{
+			name:       "glab",
+			repo:       gitlab,
+			outputs:    map[string]string{"glab": "glab-token\n"},
+			wantToken:  "glab-token",
+			wantSource: TokenSourceCLI,
+			wantCalls:  "glab config get token --host gitlab.example.com",
+		},
+		{
+			name:       "git credential helper",
+			repo:       gitlab,
+			outputs:    map[string]string{"git": "protocol=https\nhost=gitlab.example.com\nusername=oauth2\npassword=git-token\n"},
+			wantToken:  "git-token",
+			wantSource: TokenSourceGitCredential,
+			wantCalls:  "glab config get token --host gitlab.example.com; git credential fill protocol=https host=gitlab.example.com",
+		},
+		{
+			name:       "source override skips env and cli",
+			resolver:   TokenResolver{Source: TokenSourceGitCredential},
+			repo:       github,
+			envToken:   "env-token",
+			outputs:    map[string]string{"gh": "gh-token\n", "git": "password=git-token\n"},
+			wantToken:  "git-token",
+			wantSource: TokenSourceGitCredential,
+			wantCalls:  "git credential fill protocol=https host=github.com",
+		},
+		{
+			name:      "nothing found",
+			resolver:  TokenResolver{Source: TokenSourceEnv},
+			repo:      github,
+			outputs:   map[string]string{"gh": "gh-token\n"},
+			wantToken: "",
+		},
+		{
+			name:      "empty credential",
+			repo:      github,
+			outputs:   map[string]string{"git": "username=\npassword=\n"},
+			wantToken: "",
+			wantCalls: "gh auth token --hostname github.com; git credential fill protocol=https host=github.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := stubTokenCommands(t, tt.outputs)
+			if tt.stored != "" {
+				systemKeyring.Set(context.Background(), tt.repo.Host, tt.stored)
+			}
+			token, source := tt.resolver.Resolve(context.Background(), t.TempDir(), tt.repo, tt.envToken)
+			if token != tt.wantToken || source != tt.wantSource {
+				t.Errorf("Resolve() = %q from %q, want %q from %q", token, source, tt.wantToken, tt.wantSource)
+			}
+			if tt.wantCalls != "" && strings.Join(*calls, "; ") != tt.wantCalls {
+				t.Errorf("unexpected commands %q, want %q", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestResolveTokens(t *testing.T) {
+	stubTokenCommands(t, map[string]string{"gh": "gh-token\n", "glab": "glab-token\n"})
+
+	github, gitlab := resolveTokens(context.Background(), t.TempDir(), &RepoInfo{Type: RepositoryTypeGitHub, Host: "github.com"}, &TokenResolver{}, "", "gitlab-env")
+	if github != "gh-token" || gitlab != "gitlab-env" {
+		t.Errorf("expected only the GitHub token to be discovered, got %q and %q", github, gitlab)
+	}
+
+	github, gitlab = resolveTokens(context.Background(), t.TempDir(), &RepoInfo{Type: RepositoryTypeBitbucket, Host: "bitbucket.org"}, &TokenResolver{}, "", "")
+	if github != "" || gitlab != "" {
+		t.Errorf("expected no discovery for Bitbucket, got %q and %q", github, gitlab)
+	}
+}
+
+func TestParseTokenSource(t *testing.T) {
+	for _, source := range []string{"env", "keyring", "cli", "git-credential"} {
+		if _, err := ParseTokenSource(source); err != nil {
+			t.Errorf("ParseTokenSource(%q) failed: %v", source, err)
+		}
+	}
+	if _, err := ParseTokenSource("vault"); err == nil || !strings.Contains(err.Error(), "env, keyring, cli, git-credential") {
+		t.Errorf("expected an error listing the sources, got %v", err)
+	}
+}
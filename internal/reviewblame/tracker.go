@@ -0,0 +1,97 @@
+package reviewblame
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TrackerConfig extracts issue-tracker keys (e.g. JIRA-1234) from PR/MR
+// titles and branch names
+type TrackerConfig struct {
+	// Name identifies the tracker in messages, e.g. "jira"
+	Name string `json:"name"`
+	// Pattern is a regular expression matching a key; when it has a
+	// capture group, the first group is used as the key
+	Pattern string `json:"pattern"`
+	// URLTemplate builds the issue link; "{key}" is replaced by the key
+	URLTemplate string `json:"url_template"`
+}
+
+// compile validates the tracker and compiles its pattern
+func (t TrackerConfig) compile() (*regexp.Regexp, error) {
+	if t.Pattern == "" {
+		return nil, fmt.Errorf("tracker %q is missing \"pattern\"", t.Name)
+	}
+	pattern, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("tracker %q has an invalid pattern: %w", t.Name, err)
+	}
+	return pattern, nil
+}
+
+// compiledTracker is a tracker with its pattern compiled
+type compiledTracker struct {
+	config  TrackerConfig
+	pattern *regexp.Regexp
+}
+
+// match returns the first key found in text
+func (t compiledTracker) match(text string) string {
+	match := t.pattern.FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+	if len(match) > 1 && match[1] != "" {
+		return match[1]
+	}
+	return match[0]
+}
+
+// TrackerEnricher sets the tracker key and link of each line from its PR/MR
+// title, falling back to the PR/MR branch name. Trackers are tried in order.
+// It must run after the PR lookup stage.
+type TrackerEnricher struct {
+	trackers []compiledTracker
+}
+
+// NewTrackerEnricher creates the issue tracker stage
+func NewTrackerEnricher(trackers []TrackerConfig) (*TrackerEnricher, error) {
+	enricher := &TrackerEnricher{}
+	for _, tracker := range trackers {
+		pattern, err := tracker.compile()
+		if err != nil {
+			return nil, err
+		}
+		enricher.trackers = append(enricher.trackers, compiledTracker{config: tracker, pattern: pattern})
+	}
+	return enricher, nil
+}
+
+// Name implements Enricher
+func (e *TrackerEnricher) Name() string {
+	return "issue-tracker"
+}
+
+// Enrich implements Enricher
+func (e *TrackerEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		line := &lines[i]
+		if line.PRNumber == 0 {
+			continue
+		}
+
+	search:
+		for _, text := range []string{line.PRTitle, line.PRBranch} {
+			for _, tracker := range e.trackers {
+				if key := tracker.match(text); key != "" {
+					line.TrackerKey = key
+					line.TrackerURL = strings.ReplaceAll(tracker.config.URLTemplate, "{key}", key)
+					break search
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package reviewblame
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTrackerEnricher(t *testing.T) {
+	enricher, err := NewTrackerEnricher([]TrackerConfig{
+		{Name: "jira", Pattern: `\b([A-Z][A-Z0-9]+-\d+)\b`, URLTemplate: "https://jira.example.com/browse/{key}"},
+		{Name: "linear", Pattern: `(?i)\blin-(\d+)`, URLTemplate: "https://linear.app/acme/issue/LIN-{key}"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := []BlameLineWithApproval{
+		{PRNumber: 1, PRTitle: "PAY-42: Handle refunds", PRBranch: "feature/PAY-41"},
+		{PRNumber: 2, PRTitle: "Handle refunds", PRBranch: "feature/OPS-7-refunds"},
+		{PRNumber: 3, PRTitle: "Fix lin-99 crash"},
+		{PRNumber: 4, PRTitle: "Bump dependencies", PRBranch: "deps"},
+		{PRTitle: "ABC-1 without PR"},
+	}
+	if err := enricher.Enrich(context.Background(), lines); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct{ key, url string }{
+		{"PAY-42", "https://jira.example.com/browse/PAY-42"},
+		{"OPS-7", "https://jira.example.com/browse/OPS-7"},
+		{"99", "https://linear.app/acme/issue/LIN-99"},
+		{"", ""},
+		{"", ""},
+	}
+	for i, want := range expected {
+		if lines[i].TrackerKey != want.key || lines[i].TrackerURL != want.url {
+			t.Errorf("line %d: expected %q %q, got %q %q", i, want.key, want.url, lines[i].TrackerKey, lines[i].TrackerURL)
+		}
+	}
+}
+
+func TestNewTrackerEnricherInvalid(t *testing.T) {
+	for _, tracker := range []TrackerConfig{
+		{Name: "empty"},
+		{Name: "broken", Pattern: "([A-Z"},
+	} {
+		if _, err := NewTrackerEnricher([]TrackerConfig{tracker}); err == nil {
+			t.Errorf("expected error for tracker %q", tracker.Name)
+		}
+	}
+}
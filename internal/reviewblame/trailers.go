@@ -0,0 +1,94 @@
+package reviewblame
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// reviewTrailerPattern matches the Reviewed-by and Acked-by trailers of
+// mailing-list workflows, e.g. "Reviewed-by: Jane Doe <jane@example.com>"
+var reviewTrailerPattern = regexp.MustCompile(`(?mi)^(?:Reviewed-by|Acked-by):[ \t]*(.+?)[ \t]*$`)
+
+// TrailerReviewer is a person named in a review trailer
+type TrailerReviewer struct {
+	Name  string
+	Email string
+}
+
+// ParseReviewTrailers returns the reviewers named in the Reviewed-by and
+// Acked-by trailers of a commit message, in the order they appear
+func ParseReviewTrailers(message string) []TrailerReviewer {
+	var reviewers []TrailerReviewer
+	for _, match := range reviewTrailerPattern.FindAllStringSubmatch(message, -1) {
+		name, email := match[1], ""
+		if open := strings.LastIndex(name, "<"); open != -1 && strings.HasSuffix(name, ">") {
+			email = name[open+1 : len(name)-1]
+			name = strings.TrimSpace(name[:open])
+		}
+		if name == "" {
+			name = email
+		}
+		reviewers = append(reviewers, TrailerReviewer{Name: name, Email: email})
+	}
+	return reviewers
+}
+
+// TrailerApprovalEnricher sets the approver of lines that have none from
+// the review trailers of their commit. It runs after the API stages, so it
+// fills in for a missing token or an unreachable API, and on its own in
+// -trailers-only mode.
+type TrailerApprovalEnricher struct {
+	repoRoot string
+	// cache maps commit hash to its trailer reviewers (nil when none)
+	cache map[string][]LineApprover
+}
+
+// NewTrailerApprovalEnricher creates the trailer approvals stage
+func NewTrailerApprovalEnricher(repoRoot string) *TrailerApprovalEnricher {
+	return &TrailerApprovalEnricher{
+		repoRoot: repoRoot,
+		cache:    make(map[string][]LineApprover),
+	}
+}
+
+// Name implements Enricher
+func (e *TrailerApprovalEnricher) Name() string {
+	return "trailer-approvals"
+}
+
+// reviewers returns the reviewers named in the trailers of a commit
+func (e *TrailerApprovalEnricher) reviewers(commitHash string) []LineApprover {
+	approvers, exists := e.cache[commitHash]
+	if exists {
+		return approvers
+	}
+
+	cmd := exec.Command("git", "show", "-s", "--format=%B", commitHash)
+	cmd.Dir = e.repoRoot
+	// Uncommitted lines and unreadable history simply yield no reviewer
+	if output, err := cmd.Output(); err == nil {
+		for _, reviewer := range ParseReviewTrailers(string(output)) {
+			approvers = append(approvers, LineApprover{Name: reviewer.Name, Email: reviewer.Email})
+		}
+	}
+	e.cache[commitHash] = approvers
+	return approvers
+}
+
+// Enrich implements Enricher
+func (e *TrailerApprovalEnricher) Enrich(ctx context.Context, lines []BlameLineWithApproval) error {
+	for i := range lines {
+		if lines[i].Approver != "" || isUncommitted(lines[i].BlameLine) {
+			continue
+		}
+		if approvers := e.reviewers(lines[i].CommitHash); len(approvers) > 0 {
+			last := approvers[len(approvers)-1]
+			lines[i].Approver = last.Name
+			lines[i].ApproverEmail = last.Email
+			lines[i].Approvers = approvers
+		}
+	}
+	return nil
+}
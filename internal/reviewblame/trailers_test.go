@@ -0,0 +1,77 @@
+package reviewblame
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseReviewTrailers(t *testing.T) {
+	message := "Fix parser\n\nSigned-off-by: Alice <alice@example.com>\n" +
+		"Reviewed-by: Bob Jones <bob@example.com>\n" +
+		"acked-by: carol@example.com\n" +
+		"Acked-by: <dave@example.com>\n"
+
+	reviewers := ParseReviewTrailers(message)
+	expected := []TrailerReviewer{
+		{Name: "Bob Jones", Email: "bob@example.com"},
+		{Name: "carol@example.com"},
+		{Name: "dave@example.com", Email: "dave@example.com"},
+	}
+	if len(reviewers) != len(expected) {
+		t.Fatalf("expected %d reviewers, got %+v", len(expected), reviewers)
+	}
+	for i := range expected {
+		if reviewers[i] != expected[i] {
+			t.Errorf("reviewer %d: expected %+v, got %+v", i, expected[i], reviewers[i])
+		}
+	}
+
+	if reviewers := ParseReviewTrailers("Fix parser\n\nReviewed by the team"); len(reviewers) != 0 {
+		t.Errorf("expected no reviewers, got %+v", reviewers)
+	}
+}
+
+func TestTrailerApprovalEnricher(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q")
+	gitCommand(t, dir, "config", "user.email", "alice@example.com")
+	gitCommand(t, dir, "config", "user.name", "Alice")
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m",
+		"Fix parser\n\nReviewed-by: Bob Jones <bob@example.com>\nAcked-by: Carol <carol@example.com>")
+	reviewed := strings.TrimSpace(gitCommand(t, dir, "rev-parse", "HEAD"))
+	gitCommand(t, dir, "commit", "-q", "--allow-empty", "-m", "Unreviewed change")
+	unreviewed := strings.TrimSpace(gitCommand(t, dir, "rev-parse", "HEAD"))
+
+	line := func(commitHash, approver string) BlameLineWithApproval {
+		return BlameLineWithApproval{BlameLine: BlameLine{CommitHash: commitHash}, Approver: approver}
+	}
+	lines := []BlameLineWithApproval{
+		line(reviewed, ""),
+		line(reviewed, "dave"),
+		line(unreviewed, ""),
+	}
+
+	if err := NewTrailerApprovalEnricher(dir).Enrich(context.Background(), lines); err != nil {
+		t.Fatalf("Enrich failed: %v", err)
+	}
+	if lines[0].Approver != "Carol" || lines[0].ApproverEmail != "carol@example.com" {
+		t.Errorf("expected the last trailer reviewer, got %q <%s>", lines[0].Approver, lines[0].ApproverEmail)
+	}
+	if lines[1].Approver != "dave" {
+		t.Errorf("expected the API approver to be kept, got %q", lines[1].Approver)
+	}
+	if lines[2].Approver != "" {
+		t.Errorf("expected no approver without trailers, got %q", lines[2].Approver)
+	}
+}
+
+func TestTrailersOnlyPipeline(t *testing.T) {
+	pipeline, err := newEnrichmentPipeline(t.TempDir(), &RepoInfo{Type: RepositoryTypeGitHub}, &Config{}, Options{TrailersOnly: true}, "github-token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stages := pipeline.Stages(); len(stages) != 1 || stages[0] != "trailer-approvals" {
+		t.Errorf("expected only the trailer stage, got %v", stages)
+	}
+}
@@ -0,0 +1,88 @@
+package reviewblame
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Version is the release version, set at build time with
+// -ldflags "-X git-blame-reviewer/internal/reviewblame.Version=v1.2.3"
+var Version = "dev"
+
+// RunIDHeader carries the per-run correlation ID on every outgoing request
+const RunIDHeader = "X-Request-ID"
+
+// runID identifies all requests of one invocation, so API administrators can
+// correlate our traffic with a user's debug log
+var runID = newRunID()
+
+// debugOutput receives debug logs; nil disables them
+var debugOutput io.Writer
+
+// newRunID returns a random 16-character hex ID
+func newRunID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// userAgent returns the User-Agent sent on all requests
+func userAgent() string {
+	return "git-review-blame/" + Version
+}
+
+// enableDebugLogging turns on debug logs to stderr
+func enableDebugLogging() {
+	debugOutput = os.Stderr
+	debugf("%s starting", userAgent())
+}
+
+// debugf writes a debug log line tagged with the run ID when debug logging is enabled
+func debugf(format string, args ...interface{}) {
+	if debugOutput == nil {
+		return
+	}
+	fmt.Fprintf(debugOutput, "debug: [run %s] %s\n", runID, fmt.Sprintf(format, args...))
+}
+
+// identifyingTransport sets the User-Agent and run ID headers on each request
+// and logs requests when debug logging is enabled
+type identifyingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *identifyingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set(RunIDHeader, runID)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		debugf("%s %s failed after %s: %v", req.Method, req.URL.Redacted(), time.Since(start).Round(time.Millisecond), err)
+		return nil, err
+	}
+	debugf("%s %s -> %d (%s)", req.Method, req.URL.Redacted(), resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	return resp, nil
+}
+
+// newHTTPClient creates the HTTP client used for all outgoing requests,
+// with the timeout and transport of configureHTTP when set
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if httpTimeout > 0 {
+		timeout = httpTimeout
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &cachingTransport{base: &identifyingTransport{base: httpTransport}},
+	}
+}
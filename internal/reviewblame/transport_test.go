@@ -0,0 +1,50 @@
+package reviewblame
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestsCarryUserAgentAndRunID(t *testing.T) {
+	var userAgents, runIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		runIDs = append(runIDs, r.Header.Get(RunIDHeader))
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var debug bytes.Buffer
+	debugOutput = &debug
+	defer func() { debugOutput = nil }()
+
+	github := NewGitHubClient("test-token")
+	github.baseURL = server.URL
+	gitlab := newGitLabClient("test-token", "gitlab.com")
+	gitlab.baseURL = server.URL
+
+	if _, err := github.FindPRByCommit(context.Background(), "owner", "repo", "abc123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := gitlab.FindPRByCommit(context.Background(), "owner", "repo", "abc123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range userAgents {
+		if userAgents[i] != "git-review-blame/"+Version {
+			t.Errorf("request %d: unexpected User-Agent %q", i, userAgents[i])
+		}
+		if runIDs[i] != runID || len(runID) != 16 {
+			t.Errorf("request %d: unexpected run ID %q (run %q)", i, runIDs[i], runID)
+		}
+	}
+
+	logs := debug.String()
+	if strings.Count(logs, "[run "+runID+"] GET ") != 2 || !strings.Contains(logs, "/commits/abc123/pulls -> 200") {
+		t.Errorf("unexpected debug log:\n%s", logs)
+	}
+}
@@ -0,0 +1,108 @@
+package reviewblame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UserConfigEnv names the environment variable overriding the user config file
+const UserConfigEnv = "GIT_REVIEW_BLAME_USER_CONFIG"
+
+// UserConfig holds the settings read from the user's own config file only.
+// A repository's config file is written by whoever can commit to it, so
+// settings deciding where tokens are sent, or which programs run, are not
+// taken from it.
+type UserConfig struct {
+	// Hosts are tried before the hosts of the repository's config file and
+	// may set api_url and plugin paths, relative to the user config file
+	Hosts []HostConfig `json:"hosts"`
+	// HTTP may set the proxy and certificates; its timeout wins over the
+	// repository's
+	HTTP *HTTPConfig `json:"http"`
+	// Cache selects the persistent cache; a path is relative to the user
+	// config file
+	Cache *CacheConfig `json:"cache"`
+}
+
+// UserConfigPath returns the path of the user config file:
+// $GIT_REVIEW_BLAME_USER_CONFIG, or git-review-blame/config.json in the
+// user config directory (~/.config on Linux)
+func UserConfigPath() (string, error) {
+	if path := os.Getenv(UserConfigEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "git-review-blame", "config.json"), nil
+}
+
+// LoadUserConfig reads the user config file. A missing file, or a missing
+// user config directory, yields an empty UserConfig.
+func LoadUserConfig() (*UserConfig, error) {
+	path, err := UserConfigPath()
+	if err != nil {
+		return &UserConfig{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &UserConfig{}, nil
+		}
+		return nil, err
+	}
+
+	var config UserConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid user config file %s: %w", path, err)
+	}
+
+	for i, host := range config.Hosts {
+		if err := host.validate(); err != nil {
+			return nil, fmt.Errorf("invalid user config file %s: %w", path, err)
+		}
+		if strings.ContainsAny(host.Plugin, `/\`) && !filepath.IsAbs(host.Plugin) {
+			config.Hosts[i].Plugin = filepath.Join(filepath.Dir(path), host.Plugin)
+		}
+	}
+
+	if config.HTTP != nil {
+		if err := config.HTTP.validate(); err != nil {
+			return nil, fmt.Errorf("invalid user config file %s: %w", path, err)
+		}
+		config.HTTP.resolveCACerts(path)
+	}
+
+	if config.Cache != nil {
+		if err := config.Cache.validate(); err != nil {
+			return nil, fmt.Errorf("invalid user config file %s: %w", path, err)
+		}
+		if config.Cache.Path != "" && !filepath.IsAbs(config.Cache.Path) {
+			config.Cache.Path = filepath.Join(filepath.Dir(path), config.Cache.Path)
+		}
+	}
+
+	return &config, nil
+}
+
+// apply adds the user settings to a repository's config; the first entry for
+// a host wins, so the user's hosts come first
+func (u *UserConfig) apply(config *Config) {
+	if len(u.Hosts) > 0 {
+		config.Hosts = append(append([]HostConfig(nil), u.Hosts...), config.Hosts...)
+	}
+	if u.HTTP != nil {
+		settings := *u.HTTP
+		if settings.Timeout == "" && config.HTTP != nil {
+			settings.Timeout = config.HTTP.Timeout
+		}
+		config.HTTP = &settings
+	}
+	config.Cache = u.Cache
+}
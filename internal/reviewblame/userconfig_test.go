@@ -0,0 +1,141 @@
+package reviewblame
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigUserHosts(t *testing.T) {
+	repoRoot := t.TempDir()
+	userConfig := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(UserConfigEnv, userConfig)
+
+	// Without a user config file, the repository's hosts are used as they are
+	config, err := LoadConfig(repoRoot, "")
+	if err != nil || len(config.Hosts) != 0 {
+		t.Fatalf("expected an empty config, got %+v, %v", config, err)
+	}
+
+	os.WriteFile(userConfig, []byte(`{"hosts": [{"host": "code.example.com", "provider": "github", "api_url": "https://code.example.com/api/v3"}]}`), 0644)
+	os.WriteFile(filepath.Join(repoRoot, DefaultConfigFile), []byte(`{"hosts": [{"host": "code.example.com", "provider": "gitea"}, {"host": "git.example.com", "provider": "forgejo"}]}`), 0644)
+	config, err = LoadConfig(repoRoot, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repoInfo := &RepoInfo{Host: "code.example.com"}
+	if !applyHostConfig(repoInfo, config.Hosts) || repoInfo.Type != RepositoryTypeGitHub || repoInfo.APIURL != "https://code.example.com/api/v3" {
+		t.Errorf("expected the user's entry to win, got %+v", repoInfo)
+	}
+	repoInfo = &RepoInfo{Host: "git.example.com"}
+	if !applyHostConfig(repoInfo, config.Hosts) || repoInfo.Type != RepositoryTypeGitea {
+		t.Errorf("expected the repository's entry for other hosts, got %+v", repoInfo)
+	}
+
+	os.WriteFile(userConfig, []byte(`{"hosts": [{"host": "code.example.com"}]}`), 0644)
+	if _, err := LoadConfig(repoRoot, ""); err == nil || !strings.Contains(err.Error(), "invalid user config file") {
+		t.Errorf("expected an invalid user config to fail, got %v", err)
+	}
+}
+
+func TestLoadConfigRejectsRepositoryAPIURL(t *testing.T) {
+	t.Setenv(UserConfigEnv, filepath.Join(t.TempDir(), "config.json"))
+	repoRoot := t.TempDir()
+	os.WriteFile(filepath.Join(repoRoot, DefaultConfigFile), []byte(`{"hosts": [{"host": "github.com", "provider": "github", "api_url": "https://attacker.example.com"}]}`), 0644)
+
+	if _, err := LoadConfig(repoRoot, ""); err == nil || !strings.Contains(err.Error(), "api_url") {
+		t.Errorf("expected a repository api_url to be rejected, got %v", err)
+	}
+}
+
+func TestLoadConfigPluginPaths(t *testing.T) {
+	userDir := t.TempDir()
+	userConfig := filepath.Join(userDir, "config.json")
+	t.Setenv(UserConfigEnv, userConfig)
+	repoRoot := t.TempDir()
+
+	for _, plugin := range []string{"./hooks/provider", "/tmp/provider", `tools\\provider.exe`} {
+		os.WriteFile(filepath.Join(repoRoot, DefaultConfigFile), []byte(`{"hosts": [{"host": "review.example.com", "provider": "plugin", "plugin": "`+plugin+`"}]}`), 0644)
+		if _, err := LoadConfig(repoRoot, ""); err == nil || !strings.Contains(err.Error(), "user config file") {
+			t.Errorf("expected the repository plugin path %q to be rejected, got %v", plugin, err)
+		}
+	}
+
+	// A plugin name is still read from the repository
+	os.WriteFile(filepath.Join(repoRoot, DefaultConfigFile), []byte(`{"hosts": [{"host": "review.example.com", "provider": "plugin", "plugin": "corp-review"}]}`), 0644)
+	config, err := LoadConfig(repoRoot, "")
+	if err != nil || len(config.Hosts) != 1 || config.Hosts[0].Plugin != "corp-review" {
+		t.Fatalf("expected the plugin name to be kept, got %+v, %v", config, err)
+	}
+
+	// Paths from the user config file are relative to it
+	os.WriteFile(userConfig, []byte(`{"hosts": [{"host": "review.example.com", "provider": "plugin", "plugin": "bin/provider"}]}`), 0644)
+	config, err = LoadConfig(repoRoot, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repoInfo := &RepoInfo{Host: "review.example.com"}
+	if !applyHostConfig(repoInfo, config.Hosts) || repoInfo.Plugin != filepath.Join(userDir, "bin", "provider") {
+		t.Errorf("expected the user's plugin path, got %+v", repoInfo)
+	}
+}
+
+func TestLoadConfigUserCache(t *testing.T) {
+	userDir := t.TempDir()
+	userConfig := filepath.Join(userDir, "config.json")
+	t.Setenv(UserConfigEnv, userConfig)
+	repoRoot := t.TempDir()
+
+	// A repository could ship a database of forged approvals
+	os.WriteFile(filepath.Join(repoRoot, DefaultConfigFile), []byte(`{"cache": {"backend": "sqlite", "path": "approvals.db"}}`), 0644)
+	if _, err := LoadConfig(repoRoot, ""); err == nil || !strings.Contains(err.Error(), "user config file") {
+		t.Errorf("expected a repository cache to be rejected, got %v", err)
+	}
+	os.Remove(filepath.Join(repoRoot, DefaultConfigFile))
+
+	os.WriteFile(userConfig, []byte(`{"cache": {"backend": "sqlite", "path": "cache.db"}}`), 0644)
+	config, err := LoadConfig(repoRoot, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Cache == nil || config.Cache.Backend != "sqlite" || config.Cache.Path != filepath.Join(userDir, "cache.db") {
+		t.Errorf("expected the user's cache relative to the user config file, got %+v", config.Cache)
+	}
+
+	os.WriteFile(userConfig, []byte(`{"cache": {"backend": "redis"}}`), 0644)
+	if _, err := LoadConfig(repoRoot, ""); err == nil || !strings.Contains(err.Error(), "invalid user config file") {
+		t.Errorf("expected an unknown backend to fail, got %v", err)
+	}
+}
+
+func TestLoadConfigUserHTTP(t *testing.T) {
+	userDir := t.TempDir()
+	t.Setenv(UserConfigEnv, filepath.Join(userDir, "config.json"))
+	repoRoot := t.TempDir()
+
+	tests := []string{
+		`{"http": {"proxy": "http://attacker.example.com:3128"}}`,
+		`{"http": {"ca_certs": ["attacker.pem"]}}`,
+		`{"http": {"insecure_skip_verify": true}}`,
+	}
+	for _, content := range tests {
+		os.WriteFile(filepath.Join(repoRoot, DefaultConfigFile), []byte(content), 0644)
+		if _, err := LoadConfig(repoRoot, ""); err == nil || !strings.Contains(err.Error(), "user config file") {
+			t.Errorf("%s: expected the repository's setting to be rejected, got %v", content, err)
+		}
+	}
+
+	os.WriteFile(filepath.Join(repoRoot, DefaultConfigFile), []byte(`{"http": {"timeout": "2m"}}`), 0644)
+	os.WriteFile(filepath.Join(userDir, "config.json"), []byte(`{"http": {"proxy": "http://proxy.corp.example:3128", "ca_certs": ["corp-root.pem"]}}`), 0644)
+	config, err := LoadConfig(repoRoot, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.HTTP == nil || config.HTTP.Timeout != "2m" || config.HTTP.Proxy != "http://proxy.corp.example:3128" {
+		t.Errorf("expected the user's proxy with the repository's timeout, got %+v", config.HTTP)
+	}
+	if len(config.HTTP.CACerts) != 1 || config.HTTP.CACerts[0] != filepath.Join(userDir, "corp-root.pem") {
+		t.Errorf("expected CA certificates relative to the user config file, got %v", config.HTTP.CACerts)
+	}
+}
@@ -0,0 +1,89 @@
+package reviewblame
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// vendorDirectories are directory names that hold third-party code by convention
+var vendorDirectories = map[string]bool{
+	"vendor":           true,
+	"vendors":          true,
+	"third_party":      true,
+	"third-party":      true,
+	"thirdparty":       true,
+	"node_modules":     true,
+	"bower_components": true,
+	"Pods":             true,
+	"Carthage":         true,
+}
+
+// inVendorDirectory reports whether a slash-separated repository path lies
+// inside a conventional vendor directory
+func inVendorDirectory(relPath string) bool {
+	segments := strings.Split(relPath, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if vendorDirectories[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// vendoredAttributes returns the linguist-vendored attribute value ("set",
+// "unset", "unspecified", "false", ...) of each repository path
+func vendoredAttributes(repoRoot string, relPaths []string) (map[string]string, error) {
+	cmd := exec.Command("git", "check-attr", "-z", "--stdin", "linguist-vendored")
+	cmd.Dir = repoRoot
+	cmd.Stdin = strings.NewReader(strings.Join(relPaths, "\x00") + "\x00")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not read linguist-vendored attributes: %w", err)
+	}
+
+	// Output is a sequence of NUL-terminated <path> <attribute> <value> triples
+	fields := strings.Split(string(output), "\x00")
+	values := make(map[string]string, len(relPaths))
+	for i := 0; i+2 < len(fields); i += 3 {
+		values[fields[i]] = fields[i+2]
+	}
+	return values, nil
+}
+
+// FilterVendoredFiles removes vendored files from absolute file paths. A file
+// is vendored when .gitattributes marks it linguist-vendored, or when it is
+// inside a conventional vendor directory and not marked linguist-vendored=false,
+// as GitHub Linguist does. It returns the remaining files and the number removed.
+func FilterVendoredFiles(repoRoot string, files []string) ([]string, int, error) {
+	relPaths := make([]string, len(files))
+	for i, file := range files {
+		relPath, err := filepath.Rel(repoRoot, file)
+		if err != nil {
+			return nil, 0, err
+		}
+		relPaths[i] = filepath.ToSlash(relPath)
+	}
+
+	attributes, err := vendoredAttributes(repoRoot, relPaths)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var kept []string
+	for i, file := range files {
+		vendored := inVendorDirectory(relPaths[i])
+		switch attributes[relPaths[i]] {
+		case "set", "true":
+			vendored = true
+		case "unset", "false":
+			vendored = false
+		}
+		if !vendored {
+			kept = append(kept, file)
+		}
+	}
+	return kept, len(files) - len(kept), nil
+}
@@ -0,0 +1,72 @@
+package reviewblame
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInVendorDirectory(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"vendor/github.com/pkg/errors/errors.go", true},
+		{"web/node_modules/react/index.js", true},
+		{"src/third_party/zlib/zlib.h", true},
+		{"vendor.go", false},
+		{"src/vendors.txt", false},
+		{"cmd/main.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := inVendorDirectory(tt.path); got != tt.want {
+			t.Errorf("inVendorDirectory(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilterVendoredFiles(t *testing.T) {
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+
+	files := map[string]string{
+		"main.go":               "package main\n",
+		"vendor/lib/lib.go":     "package lib\n",
+		"vendor/ours/ours.go":   "package ours\n",
+		"web/node_modules/a.js": "a\n",
+		"generated/api.pb.go":   "package generated\n",
+		".gitattributes":        "generated/** linguist-vendored\nvendor/ours/** linguist-vendored=false\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCommand(t, dir, "add", ".")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+
+	tracked, err := ListTrackedFiles(dir, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kept, excluded, err := FilterVendoredFiles(dir, tracked)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, ".gitattributes"),
+		filepath.Join(dir, "main.go"),
+		filepath.Join(dir, "vendor", "ours", "ours.go"),
+	}
+	if !reflect.DeepEqual(kept, want) || excluded != 3 {
+		t.Errorf("got %v (%d excluded), want %v (3 excluded)", kept, excluded, want)
+	}
+}
@@ -0,0 +1,63 @@
+package reviewblame
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxLookupWarnings is how many failed lookups are listed in the summary;
+// the rest are only counted
+const maxLookupWarnings = 10
+
+// lookupFailures records API lookups that failed, whose lines were marked
+// [lookup failed] or lost part of their information
+var lookupFailures struct {
+	sync.Mutex
+	count    int
+	warnings []string
+}
+
+// recordLookupFailure records a failed lookup of subject, e.g. "commit
+// abc1234" or "#12", by the named stage
+func recordLookupFailure(stage, subject string, err error) {
+	lookupFailures.Lock()
+	defer lookupFailures.Unlock()
+	lookupFailures.count++
+	if len(lookupFailures.warnings) < maxLookupWarnings {
+		lookupFailures.warnings = append(lookupFailures.warnings, fmt.Sprintf("%s (%s): %v", subject, stage, err))
+	}
+}
+
+// LookupFailureCount returns the number of failed lookups
+func LookupFailureCount() int {
+	lookupFailures.Lock()
+	defer lookupFailures.Unlock()
+	return lookupFailures.count
+}
+
+// LookupWarnings describes the failed lookups, at most maxLookupWarnings
+// of them followed by a count of the rest, or returns nil when there were
+// none
+func LookupWarnings() []string {
+	lookupFailures.Lock()
+	defer lookupFailures.Unlock()
+	if lookupFailures.count == 0 {
+		return nil
+	}
+	warnings := []string{fmt.Sprintf("%d API lookup(s) failed; their lines are incomplete or marked [lookup failed]:", lookupFailures.count)}
+	for _, warning := range lookupFailures.warnings {
+		warnings = append(warnings, "  "+warning)
+	}
+	if more := lookupFailures.count - len(lookupFailures.warnings); more > 0 {
+		warnings = append(warnings, fmt.Sprintf("  and %d more", more))
+	}
+	return warnings
+}
+
+// prSubject names a PR/MR in lookup warnings
+func prSubject(repository string, number int) string {
+	if repository != "" {
+		return fmt.Sprintf("%s#%d", repository, number)
+	}
+	return fmt.Sprintf("#%d", number)
+}
@@ -0,0 +1,65 @@
+package reviewblame
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetLookupFailures clears the recorded lookup failures before and after
+// a test
+func resetLookupFailures(t *testing.T) {
+	lookupFailures.count, lookupFailures.warnings = 0, nil
+	t.Cleanup(func() {
+		lookupFailures.count, lookupFailures.warnings = 0, nil
+	})
+}
+
+func TestLookupWarnings(t *testing.T) {
+	resetLookupFailures(t)
+	client := &fakeReviewClient{
+		prs:       map[string]int{"aaa": 1, "bbb": 2},
+		approvals: map[int][]Review{1: {newTestReview("alice", time.Unix(1700000000, 0))}},
+	}
+	repoInfo := &RepoInfo{Owner: "owner", Name: "repo"}
+	lines, err := NewDefaultEnrichmentPipeline(client, repoInfo).Run(context.Background(), []BlameLine{
+		{CommitHash: "aaa", LineNumber: 1},
+		{CommitHash: "bbb", LineNumber: 2},
+		{CommitHash: "bbb", LineNumber: 3},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !lines[1].LookupFailed || !lines[2].LookupFailed {
+		t.Errorf("expected the lines of #2 to be marked, got %+v", lines)
+	}
+
+	want := []string{
+		"1 API lookup(s) failed; their lines are incomplete or marked [lookup failed]:",
+		"  #2 (approvals): not found",
+	}
+	if warnings := LookupWarnings(); !reflect.DeepEqual(warnings, want) {
+		t.Errorf("expected %q, got %q", want, warnings)
+	}
+
+	if err := reportFailures(Options{}, lines, nil); err != nil {
+		t.Errorf("expected failures to only warn, got %v", err)
+	}
+	if err := reportFailures(Options{FailOnError: true}, lines, nil); err == nil || !strings.Contains(err.Error(), "1 API lookup(s) failed") {
+		t.Errorf("expected -fail-on-error to fail the run, got %v", err)
+	}
+}
+
+func TestLookupWarningsLimit(t *testing.T) {
+	resetLookupFailures(t)
+	for i := 0; i < maxLookupWarnings+3; i++ {
+		recordLookupFailure("pr-lookup", "commit abcd1234", errors.New("GitHub API error: 502"))
+	}
+	warnings := LookupWarnings()
+	if len(warnings) != maxLookupWarnings+2 || warnings[len(warnings)-1] != "  and 3 more" {
+		t.Errorf("expected %d listed failures and a count of the rest, got %q", maxLookupWarnings, warnings)
+	}
+}
@@ -0,0 +1,322 @@
+package reviewblame
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxWebhookBytes caps the body of a webhook delivery; GitHub sends at most
+// 25 MB, but PR events are far smaller
+const maxWebhookBytes = 5 << 20
+
+// WebhookSecretEnv names the environment variable holding the secret that
+// webhook deliveries are verified with
+const WebhookSecretEnv = "GIT_REVIEW_BLAME_WEBHOOK_SECRET"
+
+// PRCommitLister is implemented by review clients that can list the commits
+// of a PR/MR. The cache warmer uses it to map every commit of a merged PR/MR
+// to it.
+type PRCommitLister interface {
+	ListPRCommits(ctx context.Context, owner, repo string, prNumber int) ([]string, error)
+}
+
+// ListPRCommits lists the commit hashes of a pull request
+func (c *GitHubClient) ListPRCommits(ctx context.Context, owner, repo string, prNumber int) ([]string, error) {
+	var commits []string
+	err := c.listPages(ctx, fmt.Sprintf("%s/repos/%s/%s/pulls/%d/commits", c.baseURL, owner, repo, prNumber), func(dec *json.Decoder) error {
+		var commit struct {
+			SHA string `json:"sha"`
+		}
+		if err := dec.Decode(&commit); err != nil {
+			return err
+		}
+		commits = append(commits, commit.SHA)
+		return nil
+	})
+	return commits, err
+}
+
+// ListPRCommits implements PRCommitLister
+func (a *GitHubClientAdapter) ListPRCommits(ctx context.Context, owner, repo string, prNumber int) ([]string, error) {
+	return a.client.ListPRCommits(ctx, owner, repo, prNumber)
+}
+
+// ListPRCommits lists the commit hashes of a merge request
+func (c *GitLabClient) ListPRCommits(ctx context.Context, owner, repo string, mrIID int) ([]string, error) {
+	var commits []string
+	err := c.listPages(ctx, c.projectAPIURL(owner, repo, fmt.Sprintf("/merge_requests/%d/commits", mrIID)), func(dec *json.Decoder) error {
+		var commit struct {
+			ID string `json:"id"`
+		}
+		if err := dec.Decode(&commit); err != nil {
+			return err
+		}
+		commits = append(commits, commit.ID)
+		return nil
+	})
+	return commits, err
+}
+
+// WebhookEvent is a webhook delivery that changes the cached lookups of a
+// PR/MR: it was merged, or received an approval
+type WebhookEvent struct {
+	RepoInfo *RepoInfo
+	Number   int
+	// PR is the PR from the payload; nil when the payload lacks details,
+	// as GitLab's does, and it is fetched instead
+	PR *PullRequest
+	// Commits are the merge or squash commits named by the payload, which
+	// the PR/MR's commit list does not include
+	Commits []string
+}
+
+// githubWebhookPayload is the subset of pull_request and
+// pull_request_review events the cache warmer reads
+type githubWebhookPayload struct {
+	Action      string `json:"action"`
+	PullRequest *struct {
+		PullRequest
+		Merged         bool   `json:"merged"`
+		MergeCommitSHA string `json:"merge_commit_sha"`
+	} `json:"pull_request"`
+	Review *struct {
+		State string `json:"state"`
+	} `json:"review"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+		// URL is the repository's API URL, which tells the API base URL
+		// of GitHub Enterprise Server
+		URL string `json:"url"`
+	} `json:"repository"`
+}
+
+// parseGitHubWebhook returns the event of a GitHub delivery, or nil when it
+// does not change a merged PR: a PR that was merged, or an approval of one
+func parseGitHubWebhook(eventType string, body []byte) (*WebhookEvent, error) {
+	if eventType != "pull_request" && eventType != "pull_request_review" {
+		return nil, nil
+	}
+	var payload githubWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	pr := payload.PullRequest
+	if pr == nil || pr.MergedAt == nil {
+		return nil, nil
+	}
+	switch {
+	case eventType == "pull_request" && payload.Action == "closed" && pr.Merged:
+	case eventType == "pull_request_review" && payload.Action == "submitted" && payload.Review != nil && strings.EqualFold(payload.Review.State, "approved"):
+	default:
+		return nil, nil
+	}
+
+	owner, name, found := strings.Cut(payload.Repository.FullName, "/")
+	webURL, err := url.Parse(payload.Repository.HTMLURL)
+	if !found || err != nil || webURL.Host == "" {
+		return nil, fmt.Errorf("invalid %s payload: no repository", eventType)
+	}
+	repoInfo := &RepoInfo{Owner: owner, Name: name, Type: RepositoryTypeGitHub, Host: webURL.Host}
+	repoInfo.APIURL = strings.TrimSuffix(payload.Repository.URL, "/repos/"+payload.Repository.FullName)
+
+	event := &WebhookEvent{RepoInfo: repoInfo, Number: pr.Number, PR: &pr.PullRequest}
+	if pr.MergeCommitSHA != "" {
+		event.Commits = []string{pr.MergeCommitSHA}
+	}
+	return event, nil
+}
+
+// gitlabWebhookPayload is the subset of merge request events the cache
+// warmer reads
+type gitlabWebhookPayload struct {
+	ObjectKind string `json:"object_kind"`
+	Project    struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID             int    `json:"iid"`
+		Action          string `json:"action"`
+		State           string `json:"state"`
+		MergeCommitSHA  string `json:"merge_commit_sha"`
+		SquashCommitSHA string `json:"squash_commit_sha"`
+	} `json:"object_attributes"`
+}
+
+// parseGitLabWebhook returns the event of a GitLab delivery, or nil when it
+// does not change a merged MR: an MR that was merged, or an approval of one
+func parseGitLabWebhook(eventType string, body []byte) (*WebhookEvent, error) {
+	if eventType != "Merge Request Hook" {
+		return nil, nil
+	}
+	var payload gitlabWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	attributes := payload.ObjectAttributes
+	if attributes.State != "merged" || (attributes.Action != "merge" && attributes.Action != "approved") {
+		return nil, nil
+	}
+
+	path := payload.Project.PathWithNamespace
+	separator := strings.LastIndex(path, "/")
+	webURL, err := url.Parse(payload.Project.WebURL)
+	if separator < 0 || err != nil || webURL.Host == "" {
+		return nil, fmt.Errorf("invalid %s payload: no project", eventType)
+	}
+	repoInfo := &RepoInfo{Owner: path[:separator], Name: path[separator+1:], Type: RepositoryTypeGitLab, Host: webURL.Host}
+
+	event := &WebhookEvent{RepoInfo: repoInfo, Number: attributes.IID}
+	for _, commit := range []string{attributes.MergeCommitSHA, attributes.SquashCommitSHA} {
+		if commit != "" {
+			event.Commits = append(event.Commits, commit)
+		}
+	}
+	return event, nil
+}
+
+// verifyGitHubSignature checks the X-Hub-Signature-256 header of a delivery
+// against the HMAC of its body
+func verifyGitHubSignature(secret string, body []byte, signature string) bool {
+	digest, found := strings.CutPrefix(signature, "sha256=")
+	if !found {
+		return false
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// CacheWarmer stores the commits and approvers of merged PRs/MRs announced
+// by webhooks, so later blames of those commits need no API request
+type CacheWarmer struct {
+	store *SQLiteCache
+	// newClient creates the review client of a repository; tests replace it
+	newClient func(ctx context.Context, repoInfo *RepoInfo) (ReviewClient, error)
+}
+
+// NewCacheWarmer creates a warmer storing into store, using the given API
+// tokens or those of the token sources
+func NewCacheWarmer(store *SQLiteCache, githubToken, gitlabToken string) *CacheWarmer {
+	return &CacheWarmer{
+		store: store,
+		newClient: func(ctx context.Context, repoInfo *RepoInfo) (ReviewClient, error) {
+			github, gitlab := resolveTokens(ctx, "", repoInfo, &TokenResolver{}, githubToken, gitlabToken)
+			return createReviewClient("", repoInfo, github, gitlab)
+		},
+	}
+}
+
+// Warm stores the PR/MR of an event for each of its commits, along with its
+// approvers, and returns the number of commits stored. PRs/MRs that are not
+// merged are skipped.
+func (w *CacheWarmer) Warm(ctx context.Context, event *WebhookEvent) (int, error) {
+	client, err := w.newClient(ctx, event.RepoInfo)
+	if err != nil {
+		return 0, err
+	}
+	owner, name := event.RepoInfo.Owner, event.RepoInfo.Name
+	subject := prSubject(owner+"/"+name, event.Number)
+
+	pr := event.PR
+	if pr == nil {
+		byNumber, ok := client.(PRNumberLookup)
+		if !ok {
+			return 0, fmt.Errorf("%s: the client cannot fetch PRs/MRs by number", subject)
+		}
+		if pr, err = byNumber.GetPRByNumber(ctx, owner, name, event.Number); err != nil {
+			return 0, fmt.Errorf("%s: %w", subject, err)
+		}
+	}
+	if pr.MergedAt == nil {
+		return 0, nil
+	}
+
+	commits := append([]string(nil), event.Commits...)
+	lister, ok := client.(PRCommitLister)
+	if !ok {
+		return 0, fmt.Errorf("%s: the client cannot list the commits of PRs/MRs", subject)
+	}
+	prCommits, err := lister.ListPRCommits(ctx, owner, name, event.Number)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", subject, err)
+	}
+	commits = append(commits, prCommits...)
+
+	host, repository := event.RepoInfo.Host, owner+"/"+name
+	result := newPRLookupResult(pr)
+	for _, commit := range commits {
+		if err := w.store.StoreCommitPR(ctx, host, repository, commit, result); err != nil {
+			return 0, err
+		}
+	}
+
+	approvals, err := client.GetPRApprovals(ctx, owner, name, event.Number)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", subject, err)
+	}
+	if err := w.store.StoreApprovers(ctx, host, repository, event.Number, reviewApprovers(approvals)); err != nil {
+		return 0, err
+	}
+	debugf("webhook: cached %s for %d commits", subject, len(commits))
+	return len(commits), nil
+}
+
+// handleWebhook implements POST /webhook: it verifies a GitHub or GitLab
+// delivery with the webhook secret and warms the cache with its PR/MR
+func (s *BlameServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	var event *WebhookEvent
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		if !verifyGitHubSignature(s.WebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("invalid X-Hub-Signature-256"))
+			return
+		}
+		event, err = parseGitHubWebhook(r.Header.Get("X-GitHub-Event"), body)
+	case r.Header.Get("X-Gitlab-Event") != "":
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(s.WebhookSecret)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("invalid X-Gitlab-Token"))
+			return
+		}
+		event, err = parseGitLabWebhook(r.Header.Get("X-Gitlab-Event"), body)
+	default:
+		writeJSONError(w, http.StatusBadRequest, errors.New("not a GitHub or GitLab webhook delivery"))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if event == nil {
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ignored"})
+		return
+	}
+
+	commits, err := s.Warmer.Warm(r.Context(), event)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "cached", "commits": commits})
+}
@@ -0,0 +1,247 @@
+package reviewblame
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// commitListingClient is a fakeReviewClient that lists PR commits and
+// fetches PRs by number as merged
+type commitListingClient struct {
+	*fakeReviewClient
+	commits  map[int][]string
+	mergedAt time.Time
+}
+
+func (c *commitListingClient) ListPRCommits(ctx context.Context, owner, repo string, prNumber int) ([]string, error) {
+	return c.commits[prNumber], nil
+}
+
+func (c *commitListingClient) GetPRByNumber(ctx context.Context, owner, repo string, prNumber int) (*PullRequest, error) {
+	return &PullRequest{Number: prNumber, Title: "Fetched", MergedAt: &c.mergedAt}, nil
+}
+
+const githubMergedPayload = `{
+	"action": "closed",
+	"pull_request": {"number": 7, "title": "Add cache", "merged": true, "merged_at": "2024-05-01T12:00:00Z", "merge_commit_sha": "mmmm", "user": {"login": "carol"}},
+	"repository": {"full_name": "owner/repo", "html_url": "https://github.com/owner/repo", "url": "https://api.github.com/repos/owner/repo"}
+}`
+
+func TestParseGitHubWebhook(t *testing.T) {
+	tests := []struct {
+		name        string
+		eventType   string
+		body        string
+		wantNumber  int
+		wantAPIURL  string
+		wantCommits []string
+	}{
+		{"merged", "pull_request", githubMergedPayload, 7, "https://api.github.com", []string{"mmmm"}},
+		{"closed without merging", "pull_request", `{"action": "closed", "pull_request": {"number": 7, "merged": false}, "repository": {"full_name": "owner/repo", "html_url": "https://github.com/owner/repo"}}`, 0, "", nil},
+		{"approval after merge", "pull_request_review", `{
+			"action": "submitted", "review": {"state": "approved"},
+			"pull_request": {"number": 8, "merged_at": "2024-05-01T12:00:00Z"},
+			"repository": {"full_name": "owner/repo", "html_url": "https://ghe.example.com/owner/repo", "url": "https://ghe.example.com/api/v3/repos/owner/repo"}
+		}`, 8, "https://ghe.example.com/api/v3", nil},
+		{"comment", "pull_request_review", `{"action": "submitted", "review": {"state": "commented"}, "pull_request": {"number": 8, "merged_at": "2024-05-01T12:00:00Z"}, "repository": {"full_name": "owner/repo", "html_url": "https://github.com/owner/repo"}}`, 0, "", nil},
+		{"approval of an open PR", "pull_request_review", `{"action": "submitted", "review": {"state": "approved"}, "pull_request": {"number": 8}, "repository": {"full_name": "owner/repo", "html_url": "https://github.com/owner/repo"}}`, 0, "", nil},
+		{"other event", "push", `{}`, 0, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := parseGitHubWebhook(tt.eventType, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNumber == 0 {
+				if event != nil {
+					t.Errorf("expected the delivery to be ignored, got %+v", event)
+				}
+				return
+			}
+			if event == nil || event.Number != tt.wantNumber || event.RepoInfo.Owner != "owner" || event.RepoInfo.Name != "repo" {
+				t.Fatalf("expected #%d of owner/repo, got %+v", tt.wantNumber, event)
+			}
+			if event.RepoInfo.APIURL != tt.wantAPIURL {
+				t.Errorf("expected API URL %q, got %q", tt.wantAPIURL, event.RepoInfo.APIURL)
+			}
+			if strings.Join(event.Commits, ",") != strings.Join(tt.wantCommits, ",") {
+				t.Errorf("expected commits %v, got %v", tt.wantCommits, event.Commits)
+			}
+		})
+	}
+
+	if _, err := parseGitHubWebhook("pull_request", []byte(`{`)); err == nil {
+		t.Error("expected an invalid payload to fail")
+	}
+}
+
+func TestParseGitLabWebhook(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantNumber int
+	}{
+		{"merged", `{"object_kind": "merge_request", "project": {"path_with_namespace": "group/sub/repo", "web_url": "https://gitlab.example.com/group/sub/repo"}, "object_attributes": {"iid": 3, "action": "merge", "state": "merged", "merge_commit_sha": "mmmm"}}`, 3},
+		{"approved after merge", `{"object_kind": "merge_request", "project": {"path_with_namespace": "group/sub/repo", "web_url": "https://gitlab.example.com/group/sub/repo"}, "object_attributes": {"iid": 4, "action": "approved", "state": "merged"}}`, 4},
+		{"approved while open", `{"object_kind": "merge_request", "project": {"path_with_namespace": "group/sub/repo", "web_url": "https://gitlab.example.com/group/sub/repo"}, "object_attributes": {"iid": 4, "action": "approved", "state": "opened"}}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := parseGitLabWebhook("Merge Request Hook", []byte(tt.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNumber == 0 {
+				if event != nil {
+					t.Errorf("expected the delivery to be ignored, got %+v", event)
+				}
+				return
+			}
+			if event == nil || event.Number != tt.wantNumber || event.PR != nil {
+				t.Fatalf("expected !%d without details, got %+v", tt.wantNumber, event)
+			}
+			if info := event.RepoInfo; info.Owner != "group/sub" || info.Name != "repo" || info.Host != "gitlab.example.com" {
+				t.Errorf("unexpected repository %+v", info)
+			}
+		})
+	}
+
+	if event, err := parseGitLabWebhook("Push Hook", []byte(`{}`)); event != nil || err != nil {
+		t.Errorf("expected push events to be ignored, got %+v, %v", event, err)
+	}
+}
+
+// signGitHubDelivery returns the X-Hub-Signature-256 of body
+func signGitHubDelivery(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	body := []byte(`{"action": "closed"}`)
+	if !verifyGitHubSignature("secret", body, signGitHubDelivery("secret", string(body))) {
+		t.Error("expected a valid signature to verify")
+	}
+	for _, signature := range []string{"", "sha256=zz", signGitHubDelivery("other", string(body)), strings.TrimPrefix(signGitHubDelivery("secret", string(body)), "sha256=")} {
+		if verifyGitHubSignature("secret", body, signature) {
+			t.Errorf("expected %q to be rejected", signature)
+		}
+	}
+}
+
+func TestWebhookWarmsCache(t *testing.T) {
+	store, err := OpenSQLiteCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Unix(1700000000, 0)
+	client := &commitListingClient{
+		fakeReviewClient: &fakeReviewClient{approvals: map[int][]Review{
+			7: {newTestReview("alice", now)},
+			3: {newTestReview("bob", now)},
+		}},
+		commits:  map[int][]string{7: {"aaaa", "bbbb"}, 3: {"cccc"}},
+		mergedAt: now,
+	}
+	server := NewBlameServer("", "")
+	server.WebhookSecret = "secret"
+	server.Warmer = &CacheWarmer{store: store, newClient: func(ctx context.Context, repoInfo *RepoInfo) (ReviewClient, error) {
+		return client, nil
+	}}
+	httpServer := httptest.NewServer(server.WebhookHandler())
+	defer httpServer.Close()
+
+	deliver := func(headers map[string]string, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", httpServer.URL+"/webhook", strings.NewReader(body))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{"github merge", map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": signGitHubDelivery("secret", githubMergedPayload)}, githubMergedPayload, http.StatusOK},
+		{"bad signature", map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": signGitHubDelivery("other", githubMergedPayload)}, githubMergedPayload, http.StatusUnauthorized},
+		{"ignored event", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": signGitHubDelivery("secret", "{}")}, "{}", http.StatusAccepted},
+		{"gitlab merge", map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "secret"}, `{"project": {"path_with_namespace": "group/repo", "web_url": "https://gitlab.com/group/repo"}, "object_attributes": {"iid": 3, "action": "merge", "state": "merged"}}`, http.StatusOK},
+		{"bad gitlab token", map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "guess"}, `{}`, http.StatusUnauthorized},
+		{"unknown sender", nil, `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if status := deliver(tt.headers, tt.body); status != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, status)
+		}
+	}
+
+	// The blame API is not exposed with the webhooks, nor the webhooks
+	// with the blame API
+	resp, err := http.Post(httpServer.URL+"/blame", "application/json", strings.NewReader(`{"repo": ".", "file": "main.go"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected no /blame on the webhook listener, got %d", resp.StatusCode)
+	}
+	blameServer := httptest.NewServer(server.Handler())
+	defer blameServer.Close()
+	resp, err = http.Post(blameServer.URL+"/webhook", "application/json", strings.NewReader(githubMergedPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected no /webhook on the blame listener, got %d", resp.StatusCode)
+	}
+
+	// Blames of the merged commits are answered from the cache
+	cold := &fakeReviewClient{}
+	lookup := NewPRLookupEnricher(cold, &RepoInfo{Owner: "owner", Name: "repo", Host: "github.com"})
+	lookup.store = store
+	approvals := NewApprovalEnricher(cold, &RepoInfo{Owner: "owner", Name: "repo", Host: "github.com"})
+	approvals.store = store
+	lines, err := NewEnrichmentPipeline(lookup, approvals).Run(context.Background(), []BlameLine{
+		{CommitHash: "aaaa", LineNumber: 1},
+		{CommitHash: "mmmm", LineNumber: 2},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cold.findCalls != 0 || cold.approvalCalls != 0 {
+		t.Errorf("expected no API lookups, got %d and %d", cold.findCalls, cold.approvalCalls)
+	}
+	for _, line := range lines {
+		if line.PRNumber != 7 || line.PRTitle != "Add cache" || line.Approver != "alice" {
+			t.Errorf("expected #7 approved by alice, got %+v", line)
+		}
+	}
+
+	if stats, _ := store.Stats(context.Background()); len(stats) != 2 || stats[0].Host != "github.com" || stats[1].Repository != "group/repo" || stats[1].Commits != 1 {
+		t.Errorf("unexpected cache contents %+v", stats)
+	}
+}
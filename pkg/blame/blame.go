@@ -0,0 +1,143 @@
+// Package blame finds who approved each line of a file: it runs git blame,
+// looks up the pull or merge request of each line's commit and its
+// approvers, as the git-review-blame command does.
+//
+//	lines, err := blame.Blame(ctx, blame.Options{Paths: []string{"main.go"}})
+//	if err != nil {
+//		return err
+//	}
+//	for _, line := range lines {
+//		fmt.Println(line.LineNumber, line.PRNumber, line.Approver)
+//	}
+//
+// Lines are annotated by a Pipeline of Enricher stages: the PR/MR lookup,
+// the approvals and the stages selected by Options. Options.Configure adds
+// stages of your own, e.g. for another data source, or reorders and removes
+// the built-in ones. The review system is detected from the repository's
+// remote (see package providers), and the lines can be rendered with
+// package format.
+package blame
+
+import (
+	"context"
+
+	"git-blame-reviewer/internal/reviewblame"
+)
+
+// LineWithApproval is a blamed line with the PR/MR that introduced it and
+// its approvers. PRNumber is 0 when no PR/MR was found and Approver is
+// empty when it was not approved; LookupFailed is set when the API lookup
+// failed, so whether the line was reviewed is unknown.
+type LineWithApproval = reviewblame.BlameLineWithApproval
+
+// Line is the git blame information of a line
+type Line = reviewblame.BlameLine
+
+// Approver is one approval of a line's PR/MR
+type Approver = reviewblame.LineApprover
+
+// Enricher is a pipeline stage that adds information to annotated lines.
+// Stages see every line of a run so they can batch and cache lookups, and
+// run in pipeline order, so later stages can use data set by earlier ones.
+// Lookup failures of single lines should be tolerated; an error from Enrich
+// aborts the run.
+type Enricher = reviewblame.Enricher
+
+// Pipeline runs a sequence of Enricher stages over blamed lines. Use
+// appends a stage, InsertBefore and Remove change the order by stage name,
+// Stages lists the names and Run annotates lines blamed elsewhere.
+type Pipeline = reviewblame.EnrichmentPipeline
+
+// NewPipeline creates a pipeline running the given stages in order
+func NewPipeline(stages ...Enricher) *Pipeline {
+	return reviewblame.NewEnrichmentPipeline(stages...)
+}
+
+// Names of the built-in stages, for Pipeline.InsertBefore and Remove.
+// StagePRLookup and StageApprovals run with API access,
+// StageOfflinePRLookup instead of them with Offline; StageTrailerApprovals
+// fills in approvers from commit trailers in every mode.
+const (
+	StagePRLookup         = "pr-lookup"
+	StageOfflinePRLookup  = "offline-pr-lookup"
+	StageApprovals        = "approvals"
+	StageTrailerApprovals = "trailer-approvals"
+)
+
+// Approver policies pick the approval shown as a line's Approver
+const (
+	ApproverPolicyFirst              = reviewblame.ApproverPolicyFirst
+	ApproverPolicyLast               = reviewblame.ApproverPolicyLast
+	ApproverPolicyLastBeforeMerge    = reviewblame.ApproverPolicyLastBeforeMerge
+	ApproverPolicyCodeOwnerPreferred = reviewblame.ApproverPolicyCodeOwnerPreferred
+)
+
+// Options select the lines Blame annotates and how their approvals are
+// looked up. Only Paths is required.
+type Options struct {
+	// Paths are the files and directories to annotate, all in one
+	// repository; a directory annotates every tracked file under it
+	Paths []string
+	// Revision annotates the files as of a commit instead of the working tree
+	Revision string
+	// LineRanges restrict a single file to git blame -L ranges, e.g. "10,20"
+	LineRanges []string
+	// Globs keep the files of Paths matching any of the patterns, relative
+	// to the repository root
+	Globs []string
+	// IncludeVendored keeps vendored files when annotating a directory
+	IncludeVendored bool
+
+	// DetectMoves and IgnoreWhitespace are git blame's -M and -w options
+	DetectMoves      bool
+	IgnoreWhitespace bool
+
+	// ConfigPath is the config file, .git-review-blame.json at the
+	// repository root when empty
+	ConfigPath string
+	// Remote selects the remote whose repository holds the PRs/MRs
+	Remote string
+	// Provider overrides the review system detected from the remote, e.g.
+	// "gitlab"
+	Provider string
+	// Token is the API token. When empty, GITHUB_TOKEN or GITLAB_TOKEN is
+	// used, else the token of "git-review-blame auth login", of the gh or
+	// glab CLI, or of a git credential helper for the host.
+	Token string
+
+	// Offline maps lines to PRs/MRs from commit messages without any API
+	// access; TrailersOnly takes approvers from Reviewed-by/Acked-by
+	// trailers only
+	Offline      bool
+	TrailersOnly bool
+	// ApproverPolicy is one of the ApproverPolicy constants,
+	// ApproverPolicyLast when empty
+	ApproverPolicy string
+
+	// Configure, when set, is called with the pipeline built for the other
+	// options before it runs, to add, reorder or remove stages; an error
+	// aborts Blame
+	Configure func(*Pipeline) error
+}
+
+// Blame annotates the lines of opts.Paths with the PR/MR and approvers of
+// their commits, in the order of the files and their lines. Without an API
+// token it falls back to offline lookups and warns on stderr. A failed
+// lookup does not fail Blame; its lines have LookupFailed set.
+func Blame(ctx context.Context, opts Options) ([]LineWithApproval, error) {
+	return reviewblame.Annotate(ctx, opts.Paths, reviewblame.Options{
+		Revision:         opts.Revision,
+		LineRanges:       opts.LineRanges,
+		Globs:            opts.Globs,
+		IncludeVendored:  opts.IncludeVendored,
+		DetectMoves:      opts.DetectMoves,
+		IgnoreWhitespace: opts.IgnoreWhitespace,
+		ConfigPath:       opts.ConfigPath,
+		Remote:           opts.Remote,
+		Provider:         opts.Provider,
+		Token:            opts.Token,
+		Offline:          opts.Offline,
+		TrailersOnly:     opts.TrailersOnly,
+		ApproverPolicy:   opts.ApproverPolicy,
+	}, opts.Configure)
+}
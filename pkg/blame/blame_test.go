@@ -0,0 +1,82 @@
+package blame
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// gitCommand runs git in dir and fails the test on errors
+func gitCommand(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+func TestBlame(t *testing.T) {
+	t.Setenv("GIT_REVIEW_BLAME_USER_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	os.WriteFile(file, []byte("one\n"), 0644)
+	gitCommand(t, dir, "add", "file.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+	os.WriteFile(file, []byte("one\ntwo\n"), 0644)
+	gitCommand(t, dir, "commit", "-q", "-a", "-m", "Add line two (#7)\n\nReviewed-by: Alice <alice@example.com>")
+
+	lines, err := Blame(context.Background(), Options{Paths: []string{file}, Offline: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].PRNumber != 0 || lines[0].Approver != "" {
+		t.Errorf("expected the first line without a PR, got %+v", lines[0])
+	}
+	if lines[1].Content != "two" || lines[1].PRNumber != 7 || lines[1].Approver != "Alice" {
+		t.Errorf("expected the second line approved by Alice in #7, got %+v", lines[1])
+	}
+
+	lines, err = Blame(context.Background(), Options{Paths: []string{dir}, LineRanges: []string{"2,2"}, Offline: true})
+	if err == nil {
+		t.Errorf("expected line ranges of a directory to fail, got %d lines", len(lines))
+	}
+	if _, err := Blame(context.Background(), Options{Offline: true}); err == nil {
+		t.Error("expected Blame without paths to fail")
+	}
+}
+
+func TestBlameConfigure(t *testing.T) {
+	t.Setenv("GIT_REVIEW_BLAME_USER_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	dir := t.TempDir()
+	gitCommand(t, dir, "init", "-q", "-b", "main")
+	gitCommand(t, dir, "remote", "add", "origin", "https://github.com/owner/repo.git")
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("one\n"), 0644)
+	gitCommand(t, dir, "add", "file.txt")
+	gitCommand(t, dir, "commit", "-q", "-m", "Initial commit")
+
+	var stages []string
+	_, err := Blame(context.Background(), Options{Paths: []string{dir}, Offline: true, Configure: func(pipeline *Pipeline) error {
+		stages = pipeline.Stages()
+		return errors.New("stop")
+	}})
+	if err == nil || err.Error() != "stop" {
+		t.Errorf("expected the error of Configure, got %v", err)
+	}
+	if !slices.Contains(stages, StageOfflinePRLookup) || !slices.Contains(stages, StageTrailerApprovals) {
+		t.Errorf("expected the offline stages, got %v", stages)
+	}
+}
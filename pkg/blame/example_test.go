@@ -0,0 +1,75 @@
+package blame_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"git-blame-reviewer/pkg/blame"
+)
+
+// ticketEnricher is a stage of another tool: it takes the ticket of each
+// line from the subject of its commit
+type ticketEnricher struct{}
+
+var ticketPattern = regexp.MustCompile(`[A-Z]+-[0-9]+`)
+
+func (ticketEnricher) Name() string { return "tickets" }
+
+func (ticketEnricher) Enrich(ctx context.Context, lines []blame.LineWithApproval) error {
+	for i := range lines {
+		lines[i].TrackerKey = ticketPattern.FindString(lines[i].Summary)
+	}
+	return nil
+}
+
+// exampleRepository creates a repository with one reviewed commit
+func exampleRepository() (string, func()) {
+	dir, err := os.MkdirTemp("", "blame-example")
+	if err != nil {
+		panic(err)
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			panic(fmt.Sprintf("git %v: %v\n%s", args, err, output))
+		}
+	}
+	git("init", "-q", "-b", "main")
+	git("remote", "add", "origin", "https://github.com/owner/repo.git")
+	os.WriteFile(filepath.Join(dir, "retry.go"), []byte("package retry\n"), 0644)
+	git("add", "retry.go")
+	git("commit", "-q", "-m", "OPS-42 Add retries (#7)\n\nReviewed-by: Alice <alice@example.com>")
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func ExampleBlame_enricher() {
+	dir, cleanup := exampleRepository()
+	defer cleanup()
+	os.Setenv("GIT_REVIEW_BLAME_USER_CONFIG", filepath.Join(dir, "no-user-config.json"))
+	defer os.Unsetenv("GIT_REVIEW_BLAME_USER_CONFIG")
+
+	lines, err := blame.Blame(context.Background(), blame.Options{
+		Paths:   []string{filepath.Join(dir, "retry.go")},
+		Offline: true,
+		Configure: func(pipeline *blame.Pipeline) error {
+			// Run before the trailer approvals, after the PR/MR lookup
+			return pipeline.InsertBefore(blame.StageTrailerApprovals, ticketEnricher{})
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, line := range lines {
+		fmt.Printf("%s: #%d approved by %s, ticket %s\n", line.Content, line.PRNumber, line.Approver, line.TrackerKey)
+	}
+	// Output: package retry: #7 approved by Alice, ticket OPS-42
+}
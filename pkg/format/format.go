@@ -0,0 +1,56 @@
+// Package format renders lines annotated by package blame in the output
+// formats of git-review-blame, such as human, porcelain, csv, markdown and
+// html, or with a Go text/template: as with the -format flag, a format
+// containing "{{" is a template executed for each line, followed by a
+// newline, e.g. "{{.LineNumber}} {{.Approver}}".
+//
+//	formatter, err := format.Lookup("csv")
+//	if err != nil {
+//		return err
+//	}
+//	return format.Write(os.Stdout, formatter, lines, format.Options{})
+package format
+
+import (
+	"io"
+
+	"git-blame-reviewer/internal/reviewblame"
+	"git-blame-reviewer/pkg/blame"
+)
+
+// Formatter renders annotated lines in one output format
+type Formatter = reviewblame.Formatter
+
+// Func adapts a plain function to the Formatter interface
+type Func = reviewblame.FormatterFunc
+
+// WriterFormatter is a Formatter that streams its output to a writer
+// instead of building it in memory; Write prefers it
+type WriterFormatter = reviewblame.WriterFormatter
+
+// WriterFunc adapts a plain streaming function to WriterFormatter
+type WriterFunc = reviewblame.WriterFormatterFunc
+
+// Options are the display options passed to every formatter
+type Options = reviewblame.FormatOptions
+
+// Lookup returns the formatter of a format name, or of a template when name
+// contains "{{"
+func Lookup(name string) (Formatter, error) {
+	return reviewblame.LookupFormatter(name)
+}
+
+// Register adds a formatter under name, which must not be taken
+func Register(name string, formatter Formatter) error {
+	return reviewblame.RegisterFormatter(name, formatter)
+}
+
+// Names returns the registered format names, sorted
+func Names() []string {
+	return reviewblame.DefaultFormatters.Names()
+}
+
+// Write renders lines to w, streaming them with formatters that support it
+func Write(w io.Writer, formatter Formatter, lines []blame.LineWithApproval, opts Options) error {
+	return reviewblame.WriteFormatted(w, formatter, lines, opts)
+}
@@ -0,0 +1,56 @@
+package format
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"git-blame-reviewer/pkg/blame"
+)
+
+func TestWrite(t *testing.T) {
+	lines := []blame.LineWithApproval{
+		{BlameLine: blame.Line{CommitHash: "a1b2c3d4e5f6", Filename: "main.go", LineNumber: 1, Author: "jane", Content: "package main"}, PRNumber: 7, Approver: "alice"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "file,line,commit,author,pr,approvers,approval_date\nmain.go,1,a1b2c3d4e5f6,jane,7,alice,\n"},
+		{"{{.Approver}} #{{.PRNumber}}", "alice #7\n"},
+	}
+	for _, tt := range tests {
+		formatter, err := Lookup(tt.format)
+		if err != nil {
+			t.Fatalf("Lookup(%q) failed: %v", tt.format, err)
+		}
+		var output bytes.Buffer
+		if err := Write(&output, formatter, lines, Options{NoColors: true}); err != nil {
+			t.Fatalf("Write(%q) failed: %v", tt.format, err)
+		}
+		if output.String() != tt.want {
+			t.Errorf("expected %q output %q, got %q", tt.format, tt.want, output.String())
+		}
+	}
+
+	if _, err := Lookup("unknown"); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	count := Func(func(lines []blame.LineWithApproval, opts Options) string {
+		return strings.Repeat(".", len(lines))
+	})
+	if err := Register("dots", count); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(Names(), "dots") || !slices.Contains(Names(), "human") {
+		t.Errorf("expected the registered and built-in formats, got %v", Names())
+	}
+	if err := Register("dots", count); err == nil {
+		t.Error("expected a taken name to fail")
+	}
+}
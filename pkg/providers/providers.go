@@ -0,0 +1,61 @@
+// Package providers detects the code review system of a git repository
+// from its remote and creates the API client that looks up the pull or
+// merge request of a commit and its approvals. GitHub, GitLab, Bitbucket
+// Cloud, Gitea and Forgejo, Gerrit and provider plugins are supported.
+package providers
+
+import (
+	"git-blame-reviewer/internal/reviewblame"
+)
+
+// Repository is a repository on a review system, detected from a git remote
+type Repository = reviewblame.RepoInfo
+
+// Type is the review system of a repository
+type Type = reviewblame.RepositoryType
+
+// The review systems
+const (
+	GitHub    = reviewblame.RepositoryTypeGitHub
+	GitLab    = reviewblame.RepositoryTypeGitLab
+	Bitbucket = reviewblame.RepositoryTypeBitbucket
+	Gitea     = reviewblame.RepositoryTypeGitea
+	Gerrit    = reviewblame.RepositoryTypeGerrit
+	// Plugin repositories are looked up by a provider plugin
+	Plugin = reviewblame.RepositoryTypePlugin
+)
+
+// Client looks up the PR/MR of a commit and its approvals on a review system
+type Client = reviewblame.ReviewClient
+
+// PullRequest, Review and ApprovalInfo are the results of Client
+type (
+	PullRequest  = reviewblame.PullRequest
+	Review       = reviewblame.Review
+	ApprovalInfo = reviewblame.PRApprovalInfo
+)
+
+// Errors of NewClient when the review system needs a token that was not
+// given or found in the environment
+var (
+	ErrMissingGitHubToken    = reviewblame.ErrMissingGitHubToken
+	ErrMissingGitLabToken    = reviewblame.ErrMissingGitLabToken
+	ErrMissingBitbucketToken = reviewblame.ErrMissingBitbucketToken
+	ErrMissingGiteaToken     = reviewblame.ErrMissingGiteaToken
+)
+
+// Detect finds the git repository containing path and detects its review
+// system from remote, else from the repository's config file, else from
+// the branch's remote, upstream or origin. It returns the repository root
+// and the repository.
+func Detect(path, remote string) (string, *Repository, error) {
+	repoRoot, repo, _, err := reviewblame.OpenRepository(path, "", remote)
+	return repoRoot, repo, err
+}
+
+// NewClient creates the API client of repo, the repository at repoRoot.
+// The tokens authenticate GitHub and GitLab; Bitbucket and Gitea read
+// BITBUCKET_TOKEN and GITEA_TOKEN, and the errors above report a missing one.
+func NewClient(repoRoot string, repo *Repository, githubToken, gitlabToken string) (Client, error) {
+	return reviewblame.NewReviewClient(repoRoot, repo, githubToken, gitlabToken)
+}
@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	t.Setenv("GIT_REVIEW_BLAME_USER_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"remote", "add", "origin", "https://github.com/owner/repo.git"},
+		{"remote", "add", "upstream", "git@gitlab.com:group/project.git"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	tests := []struct {
+		remote string
+		typ    Type
+		owner  string
+		name   string
+	}{
+		{"origin", GitHub, "owner", "repo"},
+		{"upstream", GitLab, "group", "project"},
+	}
+	for _, tt := range tests {
+		repoRoot, repo, err := Detect(dir, tt.remote)
+		if err != nil {
+			t.Fatalf("Detect(%q) failed: %v", tt.remote, err)
+		}
+		if repo.Type != tt.typ || repo.Owner != tt.owner || repo.Name != tt.name {
+			t.Errorf("Detect(%q) = %+v", tt.remote, repo)
+		}
+		if resolved, _ := filepath.EvalSymlinks(dir); repoRoot != dir && repoRoot != resolved {
+			t.Errorf("expected the repository root %s, got %s", dir, repoRoot)
+		}
+	}
+
+	if _, _, err := Detect(t.TempDir(), ""); err == nil {
+		t.Error("expected a directory outside a repository to fail")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	repo := &Repository{Owner: "owner", Name: "repo", Type: GitHub, Host: "github.com"}
+	if _, err := NewClient(t.TempDir(), repo, "", ""); !errors.Is(err, ErrMissingGitHubToken) {
+		t.Errorf("expected ErrMissingGitHubToken, got %v", err)
+	}
+	client, err := NewClient(t.TempDir(), repo, "token", "")
+	if err != nil || client == nil {
+		t.Errorf("expected a GitHub client, got %v, %v", client, err)
+	}
+}